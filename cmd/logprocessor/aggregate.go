@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/cluster"
+	"github.com/interview/junior-go-challenge/internal/report"
+	"github.com/interview/junior-go-challenge/internal/server"
+)
+
+// aggregateOptions holds the settings of the aggregate subcommand
+type aggregateOptions struct {
+	addr string
+	top  int
+}
+
+// newAggregateFlags defines the flags of the aggregate subcommand on opts
+func newAggregateFlags(opts *aggregateOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	fs.StringVar(&opts.addr, "addr", "127.0.0.1:9100", "Address to accept reports from workers and serve the cluster-wide summary on; other than loopback only with a token")
+	fs.IntVar(&opts.top, "top", 10, "Number of message patterns to print on exit")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s aggregate [flags]\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Merges the summaries workers run with -aggregator push periodically, and serves the")
+		fmt.Fprintln(fs.Output(), "cluster-wide /summary, /metrics, /patterns and /workers until interrupted.")
+		fmt.Fprintf(fs.Output(), "Workers and readers must present the token in $%s if it is set; it is required to listen beyond loopback.\n", cluster.TokenEnv)
+		printExamples(fs.Output(), []example{
+			{"Run the aggregator for workers on other hosts, with the token set", "aggregate -addr :9100"},
+			{"Run one of the workers", "-gelf-udp-addr :12201 -section patterns -aggregator http://aggregator:9100"},
+		})
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runAggregate implements the aggregate subcommand
+func runAggregate(args []string) error {
+	var opts aggregateOptions
+	fs := newAggregateFlags(&opts)
+	fs.Parse(args)
+
+	token := os.Getenv(cluster.TokenEnv)
+	if token == "" && !loopback(opts.addr) {
+		return fmt.Errorf("-addr %s accepts reports from other hosts; set $%s or listen on 127.0.0.1", opts.addr, cluster.TokenEnv)
+	}
+	aggregator := cluster.NewAggregator(token)
+	srv := server.New()
+	srv.Use(cluster.RequireToken(token))
+	srv.SetSummaryFunc(aggregator.Summary)
+	for _, path := range []string{cluster.PushPath, "/patterns", "/workers"} {
+		srv.Handle(path, aggregator.Handler())
+	}
+	if err := srv.Start(opts.addr); err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		srv.Shutdown(ctx)
+		cancel()
+	}()
+	srv.SetStatus(server.StatusRunning)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("Aggregating worker reports on %s...\n", opts.addr)
+	<-ctx.Done()
+	srv.SetStatus(server.StatusStopping)
+
+	fmt.Printf("\nMerged reports of %d workers:\n\n", len(aggregator.Workers()))
+	report.WriteText(os.Stdout, aggregator.Summary())
+	if patterns := aggregator.Patterns(opts.top); len(patterns) > 0 {
+		fmt.Println("\nTop Message Patterns:")
+		for _, pc := range patterns {
+			fmt.Printf("  %d: %s\n", pc.Count, pc.Pattern)
+		}
+	}
+	return nil
+}
+
+// startPushing reports the summary, and the pattern table of -section
+// patterns, to -aggregator every -push-interval. The returned function
+// stops reporting after a final report of the finished counts.
+func (a *app) startPushing() func() {
+	if a.cfg.aggregator == "" {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := a.clock.NewTicker(a.cfg.pushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				a.push()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		a.push()
+	}
+}
+
+// push sends one report to the aggregator. Failures are only printed: the
+// next report carries the same counts.
+func (a *app) push() {
+	a.mu.Lock()
+	proc, patterns := a.current, a.patterns
+	a.mu.Unlock()
+	if proc == nil {
+		return
+	}
+	r := &cluster.Report{Worker: a.cfg.workerName, Sent: a.clock.Now(), Summary: proc.GetSummary()}
+	if patterns != nil {
+		r.Patterns = patterns.Table()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := cluster.Push(ctx, a.cfg.aggregator, os.Getenv(cluster.TokenEnv), r); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+}
+
+// patternSection returns the pattern section among sections, if any
+func patternSection(sections []analyzer.Section) *analyzer.PatternSection {
+	for _, s := range sections {
+		if p, ok := analyzer.Unwrap(s).(*analyzer.PatternSection); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// loopback reports whether addr only accepts connections from this host
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/interview/junior-go-challenge/internal/backfill"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/report"
+)
+
+// backfillOptions holds the settings of the backfill subcommand
+type backfillOptions struct {
+	cfg     backfill.Config
+	format  string
+	outputs stringList
+}
+
+// newBackfillFlags defines the flags of the backfill subcommand on opts
+func newBackfillFlags(opts *backfillOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	fs.StringVar(&opts.cfg.InputDir, "dir", "./sample-data", "Directory containing the archive")
+	fs.StringVar(&opts.format, "format", "json", fmt.Sprintf("Input log format %v", parser.Formats()))
+	fs.StringVar(&opts.cfg.Pattern, "pattern", "", "Glob selecting input files (default depends on -format)")
+	fs.StringVar(&opts.cfg.OutputDir, "out", "", "Directory for partitions, per-day summaries and progress (required)")
+	fs.Var(&opts.outputs, "output", "Forward each day's entries to a sink URL, e.g. gelf+udp://graylog:12201 (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s backfill -out DIR [flags]\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Processes an archive one day at a time. Re-run with the same -out to resume.")
+		printExamples(fs.Output(), []example{
+			{"Backfill a year of load balancer logs", "backfill -dir /archive/alb -format alb -out ./backfill"},
+			{"Forward every day to Graylog as well", "backfill -dir /archive -out ./backfill -output gelf+tcp://graylog:12201"},
+		})
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runBackfill implements the backfill subcommand
+func runBackfill(args []string) error {
+	var opts backfillOptions
+	fs := newBackfillFlags(&opts)
+	fs.Parse(args)
+	cfg, format := opts.cfg, opts.format
+
+	if cfg.OutputDir == "" {
+		fs.Usage()
+		return fmt.Errorf("-out is required")
+	}
+	var err error
+	if cfg.Parser, err = parser.ForFormat(format); err != nil {
+		return err
+	}
+	if cfg.Pattern == "" {
+		cfg.Pattern = parser.DefaultPattern(format)
+	}
+	cfg.Outputs = opts.outputs
+
+	b := backfill.New(cfg)
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		fmt.Println("\nStopping backfill...")
+		b.Stop()
+	}()
+
+	fmt.Printf("Backfilling %s into %s...\n", cfg.InputDir, cfg.OutputDir)
+	summary, err := b.Run(func(day string, s *models.LogSummary) {
+		fmt.Printf("  %s: %d entries\n", day, s.TotalEntries)
+	})
+	if errors.Is(err, backfill.ErrInterrupted) {
+		return fmt.Errorf("%w; run again with -out %s to resume", err, cfg.OutputDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	report.WriteText(os.Stdout, summary)
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !chaos
+
+package main
+
+import "flag"
+
+// registerChaosFlag leaves -chaos undefined; build with -tags chaos to
+// inject faults
+func registerChaosFlag(fs *flag.FlagSet, cfg *options) {}
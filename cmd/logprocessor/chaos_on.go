@@ -0,0 +1,11 @@
+//go:build chaos
+
+package main
+
+import "flag"
+
+// registerChaosFlag defines -chaos. It is only compiled into builds with the
+// chaos tag, so production binaries cannot inject faults by mistake.
+func registerChaosFlag(fs *flag.FlagSet, cfg *options) {
+	fs.StringVar(&cfg.chaos, "chaos", "", "Inject faults at these rates to test degradation, e.g. parse-error=0.01,slow-sink=0.05,sink-delay=200ms,sink-error=0.01,panic=0.001,seed=1")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/partition"
+)
+
+// startCompaction bounds the memory of long-running processing: every
+// -compact-interval, partitions that ended more than -compact-after ago are
+// moved to -partition-dir, and hourly summaries there are rolled up into
+// daily ones once their day ended more than -rollup-after ago. It runs until
+// the returned function is called.
+func (a *app) startCompaction() func() {
+	if a.cfg.compactEvery <= 0 || a.partitions == nil {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := a.clock.NewTicker(a.cfg.compactEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				a.compact(a.clock.Now())
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// compact performs one compaction as of now
+func (a *app) compact(now time.Time) {
+	if _, err := a.partitions.Compact(a.cfg.partitionDir, now.Add(-a.cfg.compactAfter)); err != nil {
+		fmt.Printf("Error compacting summaries: %v\n", err)
+	}
+	if _, err := partition.Rollup(a.cfg.partitionDir, now.Add(-a.cfg.rollupAfter)); err != nil {
+		fmt.Printf("Error rolling up summaries: %v\n", err)
+	}
+}
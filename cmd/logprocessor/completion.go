@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/charset"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+)
+
+// completeCommand is the hidden subcommand the completion scripts call to
+// get the candidates for the word being completed
+const completeCommand = "__complete"
+
+// example is a command line shown in the help of a command
+type example struct {
+	description string
+	args        string
+}
+
+// printExamples writes the examples section of a command's help
+func printExamples(w io.Writer, examples []example) {
+	fmt.Fprintln(w, "\nExamples:")
+	for _, e := range examples {
+		fmt.Fprintf(w, "  # %s\n  %s %s\n\n", e.description, filepath.Base(os.Args[0]), e.args)
+	}
+}
+
+// completionScripts load completions into each shell. They pass the words
+// of the command line up to the cursor to __complete and fall back to file
+// names when it has no candidates.
+var completionScripts = map[string]string{
+	"bash": `_logprocessor() {
+    local IFS=$'\n'
+    COMPREPLY=($(logprocessor __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _logprocessor logprocessor
+`,
+	"zsh": `#compdef logprocessor
+_logprocessor() {
+    local -a candidates
+    candidates=("${(@f)$(logprocessor __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n ${candidates[1]} ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _logprocessor logprocessor
+`,
+	"fish": `function __logprocessor_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    logprocessor __complete $tokens[2..-1] 2>/dev/null
+end
+complete -c logprocessor -a '(__logprocessor_complete)'
+`,
+}
+
+// runCompletion implements the completion subcommand
+func runCompletion(args []string) error {
+	if len(args) != 1 || completionScripts[args[0]] == "" {
+		fmt.Fprintf(os.Stderr, "Usage: %s completion bash|zsh|fish\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Prints a script that completes subcommands, flags and their values.")
+		printExamples(os.Stderr, []example{
+			{"Load completions into the current bash session", "completion bash > /tmp/lp.bash && source /tmp/lp.bash"},
+			{"Install them for zsh", "completion zsh > \"${fpath[1]}/_logprocessor\""},
+			{"Install them for fish", "completion fish > ~/.config/fish/completions/logprocessor.fish"},
+		})
+		return fmt.Errorf("expected a shell: bash, zsh or fish")
+	}
+	fmt.Print(completionScripts[args[0]])
+	return nil
+}
+
+// runComplete prints the completion candidates for a command line, one per
+// line. The last argument is the word being completed.
+func runComplete(args []string) error {
+	for _, candidate := range complete(args) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// subcommands are the subcommands offered as the first word
+var subcommands = []string{"aggregate", "backfill", "completion", "coordinate", "decrypt", "generate", "replay", "rules"}
+
+// complete returns the candidates for the last of words, the arguments
+// typed so far
+func complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	var fs *flag.FlagSet
+	args := words
+	switch words[0] {
+	case "backfill":
+		fs, args = newBackfillFlags(&backfillOptions{}), words[1:]
+	case "rules":
+		if len(words) == 2 {
+			return withPrefix([]string{"test"}, current)
+		}
+		fs, args = newRulesFlags(&rulesOptions{}), words[2:]
+	case "decrypt":
+		fs, args = newDecryptFlags(&decryptOptions{}), words[1:]
+	case "coordinate":
+		fs, args = newCoordinateFlags(&coordinateOptions{}), words[1:]
+	case "aggregate":
+		fs, args = newAggregateFlags(&aggregateOptions{}), words[1:]
+	case "generate":
+		fs, args = newGenerateFlags(&generateOptions{}), words[1:]
+	case "replay":
+		fs, args = newReplayFlags(&replayOptions{}), words[1:]
+	case "completion":
+		if len(words) == 2 {
+			return withPrefix(sortedScripts(), current)
+		}
+		return nil
+	default:
+		fs = newFlagSet(&options{})
+		if len(words) == 1 && !strings.HasPrefix(current, "-") {
+			return withPrefix(subcommands, current)
+		}
+	}
+
+	// The value of a flag given as -flag=value or after -flag
+	if name, value, ok := strings.Cut(strings.TrimLeft(current, "-"), "="); ok && strings.HasPrefix(current, "-") {
+		dashes := current[:len(current)-len(strings.TrimLeft(current, "-"))]
+		var candidates []string
+		for _, v := range withPrefix(flagValues(name, args), value) {
+			candidates = append(candidates, dashes+name+"="+v)
+		}
+		return candidates
+	}
+	if len(args) >= 2 {
+		if name, ok := takesValue(fs, args[len(args)-2]); ok {
+			return withPrefix(flagValues(name, args), current)
+		}
+	}
+	if strings.HasPrefix(current, "-") {
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) {
+			names = append(names, "-"+f.Name)
+		})
+		return withPrefix(names, current)
+	}
+	return nil
+}
+
+// takesValue returns the name of the flag word if it is one that expects a
+// value in the next word
+func takesValue(fs *flag.FlagSet, word string) (string, bool) {
+	if !strings.HasPrefix(word, "-") || strings.Contains(word, "=") {
+		return "", false
+	}
+	name := strings.TrimLeft(word, "-")
+	f := fs.Lookup(name)
+	if f == nil {
+		return "", false
+	}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return "", false
+	}
+	return name, true
+}
+
+// flagValues returns the known values of a flag. args are the words typed
+// so far, which may name a state file to read service names from.
+func flagValues(name string, args []string) []string {
+	switch name {
+	case "format":
+		return parser.Formats()
+	case "section":
+		return analyzer.SectionNames()
+	case "encoding":
+		return charset.Names()
+	case "source-names":
+		return []string{"relative", "base", "absolute"}
+	case "normalize-messages":
+		return []string{"nfc", "fold", "nfc,fold"}
+	case "normalize-locale":
+		return []string{"tr", "az"}
+	case "analyzer":
+		return []string{"locks", "actor"}
+	case "stall-action":
+		return []string{"exit", "restart"}
+	case "in-use":
+		return []string{"process", "skip", "wait", "prefix"}
+	case "partition-by":
+		return []string{"day", "hour"}
+	case "schedule-mode":
+		return []string{"replace", "merge"}
+	case "service":
+		return stateServices(args)
+	case "min-level", "default-level", "priority-level":
+		var levels []string
+		for _, level := range models.Levels() {
+			levels = append(levels, string(level))
+		}
+		return levels
+	}
+	return nil
+}
+
+// stateServices returns the services in the analyzer state file named by
+// -state-in or -state-out in args, if any
+func stateServices(args []string) []string {
+	for i := len(args) - 2; i >= 0; i-- {
+		arg := strings.TrimLeft(args[i], "-")
+		var path string
+		if name, value, ok := strings.Cut(arg, "="); ok && (name == "state-in" || name == "state-out") {
+			path = value
+		} else if arg == "state-in" || arg == "state-out" {
+			path = args[i+1]
+		}
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var state analyzer.State
+		if err := json.Unmarshal(data, &state); err != nil || state.Summary == nil {
+			return nil
+		}
+		services := make([]string, 0, len(state.Summary.ByService))
+		for service := range state.Summary.ByService {
+			services = append(services, service)
+		}
+		sort.Strings(services)
+		return services
+	}
+	return nil
+}
+
+// withPrefix returns the candidates starting with prefix
+func withPrefix(candidates []string, prefix string) []string {
+	var matched []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matched = append(matched, c)
+		}
+	}
+	return matched
+}
+
+// sortedScripts returns the shells completion scripts exist for
+func sortedScripts() []string {
+	shells := make([]string, 0, len(completionScripts))
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	return shells
+}
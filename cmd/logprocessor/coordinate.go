@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/cluster"
+	"github.com/interview/junior-go-challenge/internal/partition"
+	"github.com/interview/junior-go-challenge/internal/report"
+)
+
+// coordinateOptions holds the settings of the coordinate subcommand
+type coordinateOptions struct {
+	addr     string
+	shards   int
+	stateOut string
+	timeout  time.Duration
+}
+
+// newCoordinateFlags defines the flags of the coordinate subcommand on opts
+func newCoordinateFlags(opts *coordinateOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("coordinate", flag.ExitOnError)
+	fs.StringVar(&opts.addr, "addr", ":9000", "Address to accept partial summaries from workers on")
+	fs.IntVar(&opts.shards, "shards", 0, "Number of workers (shards) to wait for (required)")
+	fs.StringVar(&opts.stateOut, "state-out", "", "Write the merged analyzer state as JSON to this file")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "Give up if not every shard has reported within this time (0 waits indefinitely)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s coordinate -shards N [flags]\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Merges the partial summaries of workers run with -shard and -coordinator into one summary.")
+		fmt.Fprintf(fs.Output(), "Workers must present the token in $%s if it is set.\n", cluster.TokenEnv)
+		printExamples(fs.Output(), []example{
+			{"Wait for 8 workers", "coordinate -shards 8 -state-out archive.json"},
+			{"Run one of the workers", "-dir /archive -shard 3/8 -coordinator http://coordinator:9000"},
+		})
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runCoordinate implements the coordinate subcommand
+func runCoordinate(args []string) error {
+	var opts coordinateOptions
+	fs := newCoordinateFlags(&opts)
+	fs.Parse(args)
+	if opts.shards < 1 {
+		fs.Usage()
+		return fmt.Errorf("-shards is required")
+	}
+
+	coordinator := cluster.NewCoordinator(opts.shards, os.Getenv(cluster.TokenEnv))
+	listener, err := net.Listen("tcp", opts.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", opts.addr, err)
+	}
+	server := &http.Server{Handler: coordinator.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		server.Shutdown(ctx)
+		cancel()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	fmt.Printf("Waiting for %d shards on %s...\n", opts.shards, listener.Addr())
+	merged := analyzer.NewLogAnalyzer()
+	if err := coordinator.Wait(ctx, merged); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return fmt.Errorf("interrupted: %w", err)
+		}
+		return err
+	}
+
+	if opts.stateOut != "" {
+		data, err := json.MarshalIndent(merged.Export(), "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode analyzer state: %w", err)
+		}
+		if err := partition.WriteFile(opts.stateOut, append(data, '\n')); err != nil {
+			return fmt.Errorf("failed to save analyzer state: %w", err)
+		}
+	}
+
+	fmt.Println()
+	report.WriteText(os.Stdout, merged.GetSummary())
+	return nil
+}
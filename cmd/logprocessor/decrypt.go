@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/interview/junior-go-challenge/internal/crypt"
+)
+
+// decryptOptions holds the settings of the decrypt subcommand
+type decryptOptions struct {
+	keyCommand string
+}
+
+// newDecryptFlags defines the flags of the decrypt subcommand on opts
+func newDecryptFlags(opts *decryptOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	fs.StringVar(&opts.keyCommand, "encryption-key-command", "", "Command printing the encryption key, e.g. a KMS decrypt call (default $"+crypt.KeyEnv+")")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s decrypt [flags] FILE...\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Writes the decrypted contents of state, checkpoint and export files written with -encrypt to stdout.")
+		printExamples(fs.Output(), []example{
+			{"Inspect an encrypted state file", "decrypt state.json | jq .summary"},
+			{"Read an encrypted merge with the key from KMS", "decrypt -encryption-key-command 'aws kms decrypt --ciphertext-blob fileb://key.enc --query Plaintext --output text' merged.ndjson"},
+		})
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runDecrypt implements the decrypt subcommand
+func runDecrypt(args []string) error {
+	var opts decryptOptions
+	fs := newDecryptFlags(&opts)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("expected the files to decrypt")
+	}
+
+	c, err := loadCipher(opts.keyCommand)
+	if err != nil {
+		return err
+	}
+	for _, path := range fs.Args() {
+		if err := decryptFile(c, path, os.Stdout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptFile writes the decrypted contents of the file at path to w. Files
+// sealed whole and encrypted streams are both accepted.
+func decryptFile(c *crypt.Cipher, path string, w io.Writer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 16)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if crypt.Sealed(head[:n]) {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if data, err = c.Open(data); err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", path, err)
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	if _, err := io.Copy(w, c.NewReader(file)); err != nil {
+		return fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadCipher reads the encryption key from the output of keyCommand if it
+// is set, or else from the environment
+func loadCipher(keyCommand string) (*crypt.Cipher, error) {
+	if keyCommand != "" {
+		return crypt.KeyFromCommand(keyCommand)
+	}
+	key := os.Getenv(crypt.KeyEnv)
+	if key == "" {
+		return nil, fmt.Errorf("no encryption key: set $%s or -encryption-key-command", crypt.KeyEnv)
+	}
+	return crypt.ParseKey(key)
+}
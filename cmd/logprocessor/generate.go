@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/generate"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// generateOptions holds the settings of the generate subcommand
+type generateOptions struct {
+	out      string
+	files    int
+	entries  int
+	services string
+	levels   string
+	rate     float64
+	start    string
+	format   string
+	corrupt  float64
+	seed     int64
+	follow   bool
+}
+
+// newGenerateFlags defines the flags of the generate subcommand on opts
+func newGenerateFlags(opts *generateOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	fs.StringVar(&opts.out, "out", "-", "Directory to write the log files to, or - for stdout")
+	fs.IntVar(&opts.files, "files", 1, "Number of files to write to -out")
+	fs.IntVar(&opts.entries, "entries", 1000, "Number of entries per file (with -follow, in total; 0 for no limit)")
+	fs.StringVar(&opts.services, "services", "api,auth,db,web", "Comma-separated services emitting the entries")
+	fs.StringVar(&opts.levels, "levels", "", "Relative weights of the levels, e.g. INFO=90,ERROR=10 (default DEBUG=10,INFO=70,WARNING=12,ERROR=7,FATAL=1)")
+	fs.Float64Var(&opts.rate, "rate", 10, "Average entries per second, spacing the timestamps")
+	fs.StringVar(&opts.start, "start", "", "RFC 3339 timestamp of the first entry (default such that the last entry is about now)")
+	fs.StringVar(&opts.format, "format", "json", fmt.Sprintf("Format of the entries %v", generate.Formats))
+	fs.Float64Var(&opts.corrupt, "corrupt", 0, "Fraction of lines to damage (truncated, not JSON, invalid UTF-8, bad timestamps), between 0 and 1")
+	fs.Int64Var(&opts.seed, "seed", 1, "Random seed; the same seed and flags give the same entries")
+	fs.BoolVar(&opts.follow, "follow", false, "Write entries in real time at -rate, stamped with the current time, until interrupted")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s generate [flags]\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Writes synthetic log files for benchmarking the processor and testing rules and alerts.")
+		printExamples(fs.Output(), []example{
+			{"Write ten files of 100000 entries, 1% of them damaged", "generate -out ./bench -files 10 -entries 100000 -corrupt 0.01"},
+			{"Write an error burst to test -alert thresholds against", "generate -out ./burst -levels INFO=50,ERROR=50 -rate 100"},
+			{"Append 500 entries a second to ./live/generated.json until interrupted", "generate -out ./live -follow -rate 500 -entries 0"},
+		})
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runGenerate implements the generate subcommand
+func runGenerate(args []string) error {
+	var opts generateOptions
+	fs := newGenerateFlags(&opts)
+	fs.Parse(args)
+
+	cfg := generate.Config{
+		Rate:    opts.rate,
+		Format:  opts.format,
+		Corrupt: opts.corrupt,
+		Seed:    opts.seed,
+	}
+	for _, service := range strings.Split(opts.services, ",") {
+		if service = strings.TrimSpace(service); service != "" {
+			cfg.Services = append(cfg.Services, service)
+		}
+	}
+	if opts.levels != "" {
+		levels, err := parseLevelWeights(opts.levels)
+		if err != nil {
+			return err
+		}
+		cfg.Levels = levels
+	}
+	if opts.files < 1 || opts.entries < 0 {
+		return fmt.Errorf("-files must be at least 1 and -entries not negative")
+	}
+	if opts.follow && opts.files != 1 {
+		return fmt.Errorf("-follow writes a single stream and cannot be combined with -files")
+	}
+	switch {
+	case opts.start != "":
+		start, err := time.Parse(time.RFC3339, opts.start)
+		if err != nil {
+			return fmt.Errorf("invalid -start: %w", err)
+		}
+		cfg.Start = start
+	case opts.rate > 0:
+		span := time.Duration(float64(opts.files*opts.entries) / opts.rate * float64(time.Second))
+		cfg.Start = time.Now().Add(-span).Truncate(time.Second)
+	}
+
+	g, err := generate.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	if opts.follow {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		out := os.Stdout
+		if opts.out != "-" {
+			if err := os.MkdirAll(opts.out, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			file, err := os.OpenFile(filepath.Join(opts.out, "generated.json"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to open output file: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+		return g.Stream(ctx, out, opts.entries)
+	}
+
+	if opts.out == "-" {
+		return g.Write(os.Stdout, opts.files*opts.entries)
+	}
+	if err := os.MkdirAll(opts.out, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for i := 1; i <= opts.files; i++ {
+		path := filepath.Join(opts.out, fmt.Sprintf("generated-%03d.json", i))
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		err = g.Write(file, opts.entries)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d entries to %d files in %s\n", opts.files*opts.entries, opts.files, opts.out)
+	return nil
+}
+
+// parseLevelWeights parses weights such as INFO=90,ERROR=10
+func parseLevelWeights(s string) (map[models.LogLevel]float64, error) {
+	weights := make(map[models.LogLevel]float64)
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(part, "=")
+		level, known := models.ParseLevel(name)
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || !known || err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid level weight %q, expected LEVEL=WEIGHT", part)
+		}
+		weights[level] = weight
+	}
+	return weights, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/interview/junior-go-challenge/internal/manifest"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/partition"
+	"github.com/interview/junior-go-challenge/internal/processor"
+)
+
+// secretFlags are left out of run manifests
+var secretFlags = []string{"pagerduty-routing-key", "opsgenie-api-key", "encryption-key-command"}
+
+// newManifest starts the manifest of a run if -manifest is set
+func (a *app) newManifest() *manifest.Manifest {
+	if a.cfg.manifest == "" {
+		return nil
+	}
+	m := manifest.New(a.cfg.inputDir, a.cfg.flags, secretFlags...)
+	var files []string
+	if a.cfg.configFile != "" {
+		files = append(files, a.cfg.configFile)
+	}
+	if err := m.HashConfig(files...); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+	return m
+}
+
+// writeManifest completes the manifest of a run with the files proc read
+// and writes it to -manifest, replacing the previous run's
+func (a *app) writeManifest(m *manifest.Manifest, proc *processor.LogProcessor, err error) {
+	if m == nil {
+		return
+	}
+	var summary *models.LogSummary
+	if proc != nil {
+		for _, r := range proc.FileResults() {
+			m.AddFile(r.Path, r.Status, r.Error, r.Entries, r.Duration)
+			m.SetHash(r.Path, r.SHA256, r.Bytes)
+		}
+		for _, s := range proc.SkippedPaths() {
+			m.AddSkipped(s.Path, s.Reason)
+		}
+		summary = proc.GetSummary()
+	}
+	for _, p := range a.stats.phases {
+		m.AddPhase(p.name, p.time)
+	}
+	m.Finish(summary, err)
+	if err := partition.WriteJSON(a.cfg.manifest, m); err != nil {
+		fmt.Printf("Error writing run manifest: %v\n", err)
+	}
+}
+
+// verifyInput checks the files proc read against the hashes recorded in
+// the -verify-input manifest, prints the chain of custody and records it in
+// m, if set. It fails if any file does not match.
+func (a *app) verifyInput(m *manifest.Manifest, proc *processor.LogProcessor) error {
+	var files []manifest.File
+	for _, r := range proc.FileResults() {
+		files = append(files, manifest.File{Path: r.Path, Status: r.Status, SHA256: r.SHA256, Bytes: r.Bytes})
+	}
+	custody, err := manifest.Verify(a.cfg.verifyInput, a.cfg.inputDir, files)
+	if err != nil {
+		return err
+	}
+	if m != nil {
+		m.Custody = custody
+	}
+
+	fmt.Printf("\nChain of Custody (against %s, sha256 %s):\n", custody.Manifest, custody.ManifestSHA256)
+	hashed := 0
+	for _, f := range files {
+		if f.SHA256 != "" {
+			hashed++
+			fmt.Printf("  %s  %s  %d bytes\n", f.SHA256, f.Path, f.Bytes)
+		}
+	}
+	for _, d := range custody.Discrepancies {
+		switch d.Problem {
+		case manifest.ProblemModified:
+			fmt.Printf("  MODIFIED %s: expected %s, got %s\n", d.Path, d.Expected, d.Actual)
+		case manifest.ProblemMissing:
+			fmt.Printf("  MISSING %s: expected %s\n", d.Path, d.Expected)
+		default:
+			fmt.Printf("  UNEXPECTED %s: not in the manifest\n", d.Path)
+		}
+	}
+	if !custody.Verified {
+		return fmt.Errorf("input verification failed: %d discrepancies with %s", len(custody.Discrepancies), custody.Manifest)
+	}
+	fmt.Printf("Verified: %d input files match %s\n", hashed, custody.Manifest)
+	return nil
+}
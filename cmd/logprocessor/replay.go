@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/human"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/replay"
+	"github.com/interview/junior-go-challenge/internal/sink"
+)
+
+// replayOptions holds the settings of the replay subcommand
+type replayOptions struct {
+	cfg     replay.Config
+	format  string
+	outputs stringList
+}
+
+// newReplayFlags defines the flags of the replay subcommand on opts
+func newReplayFlags(opts *replayOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.StringVar(&opts.cfg.InputDir, "dir", "./sample-data", "Directory containing the captured logs")
+	fs.StringVar(&opts.format, "format", "json", fmt.Sprintf("Input log format %v", parser.Formats()))
+	fs.StringVar(&opts.cfg.Pattern, "pattern", "", "Glob selecting input files (default depends on -format)")
+	fs.Var(&opts.outputs, "output", "Send the entries to a sink URL, e.g. gelf+udp://graylog:12201 (repeatable, required)")
+	fs.Float64Var(&opts.cfg.Speed, "speed", 1, "Multiple of the original pace, e.g. 10 for ten times as fast (0 for as fast as possible)")
+	human.DurationVar(fs, &opts.cfg.MaxGap, "max-gap", 0, "Shorten quiet periods in the logs to at most this long (0 keeps them)")
+	fs.BoolVar(&opts.cfg.Retime, "retime", false, "Stamp entries with the time they are sent instead of their original timestamps")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s replay -output URL [flags]\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Sends captured logs to sinks in timestamp order, at the pace they were logged at.")
+		printExamples(fs.Output(), []example{
+			{"Replay yesterday's traffic to a staging Graylog at ten times the speed", "replay -dir /archive/2023-01-01 -output gelf+tcp://graylog-staging:12201 -speed 10"},
+			{"Replay as current traffic, skipping quiet nights", "replay -dir ./captured -output gelf+udp://localhost:12201 -retime -max-gap 1m"},
+		})
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runReplay implements the replay subcommand
+func runReplay(args []string) error {
+	var opts replayOptions
+	fs := newReplayFlags(&opts)
+	fs.Parse(args)
+	cfg := opts.cfg
+
+	if len(opts.outputs) == 0 {
+		fs.Usage()
+		return fmt.Errorf("-output is required")
+	}
+	if cfg.Speed < 0 || cfg.MaxGap < 0 {
+		return fmt.Errorf("-speed and -max-gap must not be negative")
+	}
+	var err error
+	if cfg.Parser, err = parser.ForFormat(opts.format); err != nil {
+		return err
+	}
+	if cfg.Pattern == "" {
+		cfg.Pattern = parser.DefaultPattern(opts.format)
+	}
+
+	var sinks []sink.Sink
+	defer func() {
+		for _, s := range sinks {
+			if err := s.Close(); err != nil {
+				fmt.Printf("Error closing output: %v\n", err)
+			}
+		}
+	}()
+	for _, url := range opts.outputs {
+		s, err := sink.Open(url)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, s)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("Replaying %s...\n", cfg.InputDir)
+	stats, err := replay.New(cfg, sinks...).Run(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Replayed %d entries spanning %s in %s", stats.Entries, stats.LogSpan.Round(time.Second), stats.Elapsed.Round(time.Millisecond))
+	if stats.Failed > 0 {
+		fmt.Printf(", %d of them rejected by an output", stats.Failed)
+	}
+	if stats.MaxLag > time.Second {
+		fmt.Printf(", falling up to %s behind", stats.MaxLag.Round(time.Millisecond))
+	}
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/interview/junior-go-challenge/internal/processor"
+	"github.com/interview/junior-go-challenge/internal/retention"
+)
+
+// enforceRetention deletes or archives the input files proc processed
+// completely that are older than -retention-age. Files that were skipped,
+// damaged, failed or only partly delivered are kept for another attempt.
+func (a *app) enforceRetention(proc *processor.LogProcessor) error {
+	if a.cfg.retentionAge <= 0 {
+		return nil
+	}
+	var files []string
+	for _, r := range proc.FileResults() {
+		if r.Status == processor.FileOK {
+			files = append(files, r.Path)
+		}
+	}
+
+	var opts []retention.Option
+	if a.cfg.retentionArchive != "" {
+		opts = append(opts, retention.WithArchive(a.cfg.retentionArchive))
+	}
+	if a.cfg.retentionDryRun {
+		opts = append(opts, retention.WithDryRun())
+	}
+	if a.cfg.retentionAudit != "" {
+		audit, err := os.OpenFile(a.cfg.retentionAudit, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open retention audit log: %w", err)
+		}
+		defer audit.Close()
+		opts = append(opts, retention.WithAudit(audit))
+	}
+
+	actions, err := retention.New(a.cfg.retentionAge, opts...).Enforce(files)
+	for _, action := range actions {
+		switch {
+		case action.Error != "":
+		case action.DryRun && action.Action == retention.ActionArchive:
+			fmt.Printf("Would archive %s to %s\n", action.Path, action.Target)
+		case action.DryRun:
+			fmt.Printf("Would delete %s\n", action.Path)
+		case action.Action == retention.ActionArchive:
+			fmt.Printf("Archived %s to %s\n", action.Path, action.Target)
+		default:
+			fmt.Printf("Deleted %s\n", action.Path)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("retention failed: %w", err)
+	}
+	return nil
+}
+
+// checkRetention validates the -retention flags
+func checkRetention(cfg options) error {
+	if cfg.retentionAge <= 0 {
+		if cfg.retentionArchive != "" || cfg.retentionDryRun || cfg.retentionAudit != "" {
+			return fmt.Errorf("-retention-archive, -retention-dry-run and -retention-audit need -retention-age")
+		}
+		return nil
+	}
+	if cfg.listening() {
+		return fmt.Errorf("-retention-age applies to input files and cannot be combined with network listeners")
+	}
+	if cfg.retentionArchive != "" && filepath.Clean(cfg.retentionArchive) == filepath.Clean(cfg.inputDir) {
+		return fmt.Errorf("-retention-archive must differ from the input directory")
+	}
+	return nil
+}
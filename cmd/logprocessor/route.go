@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/interview/junior-go-challenge/internal/config"
+	"github.com/interview/junior-go-challenge/internal/rules"
+	"github.com/interview/junior-go-challenge/internal/sink"
+)
+
+// countOnly is the route output that counts entries without forwarding them
+const countOnly = "count"
+
+// route is a validated route from the config file
+type route struct {
+	name   string
+	match  rules.Matcher
+	output string
+}
+
+// newRoutes validates the configured routes
+func newRoutes(configs []config.RouteConfig) ([]route, error) {
+	var routes []route
+	for i, rc := range configs {
+		if rc.Output == "" {
+			return nil, fmt.Errorf("route %d has no output", i+1)
+		}
+		match, err := rules.ParseMatch(rc.Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match of route %d: %w", i+1, err)
+		}
+		name := rc.Name
+		if name == "" {
+			name = rc.Output
+		}
+		routes = append(routes, route{name: name, match: match, output: rc.Output})
+	}
+	return routes, nil
+}
+
+// newRouter opens the outputs of the routes for one run and remembers the
+// router so its counts can be reported
+func (a *app) newRouter() (*sink.Router, error) {
+	var routes []sink.Route
+	for _, r := range a.routes {
+		sr := sink.Route{Name: r.name, Match: r.match}
+		if r.output != countOnly {
+			s, err := sink.Open(r.output)
+			if err != nil {
+				for _, opened := range routes {
+					if opened.Sink != nil {
+						opened.Sink.Close()
+					}
+				}
+				return nil, fmt.Errorf("failed to open output of route %s: %w", r.name, err)
+			}
+			sr.Sink = a.wrapOutput(s)
+		}
+		routes = append(routes, sr)
+	}
+
+	router := sink.NewRouter(routes...)
+	a.mu.Lock()
+	a.router = router
+	a.mu.Unlock()
+	return router, nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/config"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/processor"
+	"github.com/interview/junior-go-challenge/internal/rules"
+)
+
+// rulesOptions holds the settings of the rules subcommand
+type rulesOptions struct {
+	rulesFile  string
+	inputDir   string
+	format     string
+	pattern    string
+	configFile string
+}
+
+// newRulesFlags defines the flags of the rules test subcommand on opts
+func newRulesFlags(opts *rulesOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("rules test", flag.ExitOnError)
+	fs.StringVar(&opts.rulesFile, "rules", "", "YAML file with the rules to evaluate (required)")
+	fs.StringVar(&opts.inputDir, "dir", "./sample-data", "Directory with sample log files")
+	fs.StringVar(&opts.format, "format", "json", fmt.Sprintf("Input log format %v", parser.Formats()))
+	fs.StringVar(&opts.pattern, "pattern", "", "Glob selecting input files (default depends on -format)")
+	fs.StringVar(&opts.configFile, "config", "", "JSON config file whose levels the rules may use")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s rules test -rules FILE [flags]\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Evaluates alert rules against sample logs and checks their expect settings.")
+		printExamples(fs.Output(), []example{
+			{"Check rules against the sample data", "rules test -rules rules.yaml"},
+			{"Replay an incident's logs in CI", "rules test -rules alerts/prod.yaml -dir testdata/incident -format gcp"},
+		})
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runRules implements the rules subcommand
+func runRules(args []string) error {
+	var opts rulesOptions
+	fs := newRulesFlags(&opts)
+	if len(args) == 0 || args[0] != "test" {
+		fs.Usage()
+		return fmt.Errorf("unknown rules command, expected \"rules test\"")
+	}
+	fs.Parse(args[1:])
+	rulesFile, inputDir, format, pattern := opts.rulesFile, opts.inputDir, opts.format, opts.pattern
+
+	if rulesFile == "" {
+		fs.Usage()
+		return fmt.Errorf("-rules is required")
+	}
+	// Rules may compare levels defined in the config
+	if opts.configFile != "" {
+		settings, err := config.Load(opts.configFile)
+		if err != nil {
+			return err
+		}
+		if err := settings.RegisterLevels(); err != nil {
+			return err
+		}
+	}
+	ruleSet, err := rules.Load(rulesFile)
+	if err != nil {
+		return err
+	}
+	p, err := parser.ForFormat(format)
+	if err != nil {
+		return err
+	}
+	if pattern == "" {
+		pattern = parser.DefaultPattern(format)
+	}
+
+	// Rules see the entries in timestamp order, as in a live stream
+	section := newRuleSection(ruleSet)
+	proc := processor.NewLogProcessor(inputDir,
+		processor.WithParser(p),
+		processor.WithPattern(pattern),
+		processor.WithSection(section))
+	proc.Use(processor.NormalizeLevel(), processor.InferLevel(models.INFO))
+
+	fmt.Printf("Evaluating %d rules against %s...\n", len(ruleSet), inputDir)
+	if err := proc.Start(); err != nil {
+		return fmt.Errorf("processing failed: %w", err)
+	}
+	fmt.Println()
+	section.WriteText(os.Stdout)
+
+	if failed := section.failedExpectations(); len(failed) > 0 {
+		return fmt.Errorf("%d rules did not behave as expected: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// ruleSection evaluates rules as an ordered analysis section and records
+// their firings
+type ruleSection struct {
+	rules   []*rules.Rule
+	engine  *rules.Engine
+	firings map[*rules.Rule][]rules.Firing
+}
+
+var _ analyzer.OrderedSection = (*ruleSection)(nil)
+
+func newRuleSection(ruleSet []*rules.Rule) *ruleSection {
+	return &ruleSection{
+		rules:   ruleSet,
+		engine:  rules.NewEngine(ruleSet),
+		firings: make(map[*rules.Rule][]rules.Firing),
+	}
+}
+
+func (s *ruleSection) Name() string {
+	return "Alert Rules"
+}
+
+func (s *ruleSection) ObservesInOrder() {}
+
+func (s *ruleSection) Observe(entry models.LogEntry) {
+	for _, f := range s.engine.Observe(entry) {
+		s.firings[f.Rule] = append(s.firings[f.Rule], f)
+	}
+}
+
+func (s *ruleSection) WriteText(w io.Writer) error {
+	for _, rule := range s.rules {
+		firings := s.firings[rule]
+		status := "did not fire"
+		if len(firings) > 0 {
+			status = fmt.Sprintf("fired %d times, actions: %s", len(firings), strings.Join(rule.Actions, ", "))
+			if len(firings) == 1 {
+				status = "fired once, actions: " + strings.Join(rule.Actions, ", ")
+			}
+		}
+		if s.failed(rule) {
+			status += fmt.Sprintf(" (FAIL: expected %s)", rule.Expect)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", rule.Name, status); err != nil {
+			return err
+		}
+		for _, f := range firings {
+			_, err := fmt.Fprintf(w, "  %s: %d matches, reached by %s: %s\n",
+				f.Time.Format("2006-01-02 15:04:05"), f.Count, f.Entry.Service, f.Entry.Message)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// failed reports whether a rule did not meet its expectation
+func (s *ruleSection) failed(rule *rules.Rule) bool {
+	fired := len(s.firings[rule]) > 0
+	return (rule.Expect == rules.ExpectFire && !fired) || (rule.Expect == rules.ExpectQuiet && fired)
+}
+
+// failedExpectations returns the names of the rules that failed
+func (s *ruleSection) failedExpectations() []string {
+	var failed []string
+	for _, rule := range s.rules {
+		if s.failed(rule) {
+			failed = append(failed, rule.Name)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,1512 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/alert"
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/avro"
+	"github.com/interview/junior-go-challenge/internal/bloom"
+	"github.com/interview/junior-go-challenge/internal/chaos"
+	"github.com/interview/junior-go-challenge/internal/charset"
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/cluster"
+	"github.com/interview/junior-go-challenge/internal/config"
+	"github.com/interview/junior-go-challenge/internal/crypt"
+	"github.com/interview/junior-go-challenge/internal/daemon"
+	"github.com/interview/junior-go-challenge/internal/input"
+	"github.com/interview/junior-go-challenge/internal/issue"
+	"github.com/interview/junior-go-challenge/internal/logmetric"
+	"github.com/interview/junior-go-challenge/internal/mergesort"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/partition"
+	"github.com/interview/junior-go-challenge/internal/privacy"
+	"github.com/interview/junior-go-challenge/internal/processor"
+	"github.com/interview/junior-go-challenge/internal/recent"
+	"github.com/interview/junior-go-challenge/internal/release"
+	"github.com/interview/junior-go-challenge/internal/report"
+	"github.com/interview/junior-go-challenge/internal/rules"
+	"github.com/interview/junior-go-challenge/internal/schedule"
+	"github.com/interview/junior-go-challenge/internal/server"
+	"github.com/interview/junior-go-challenge/internal/sink"
+	"github.com/interview/junior-go-challenge/internal/statsd"
+	"github.com/interview/junior-go-challenge/internal/tenant"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// app holds the long-lived collaborators shared by every processing run
+type app struct {
+	cfg      options
+	settings *config.Config // from -config, may be nil
+	counters []*analyzer.Counter
+	routes   []route
+	parser   parser.Parser
+	mapping  *parser.FieldMapping // from json_fields in -config, may be nil
+	inUse    processor.InUsePolicy
+	naming   processor.SourceNames
+	form     textnorm.Form   // from -normalize-messages and -normalize-locale
+	minLevel models.LogLevel // from -min-level, may be empty
+	priority models.LogLevel // from -priority-level, may be empty
+	cipher   *crypt.Cipher   // from -encrypt, may be nil
+	shard    *cluster.Shard  // from -shard, may be nil
+	mailer   *report.Mailer
+	monitor  *alert.Monitor
+	metrics  *statsd.Client
+	// anonymizer anonymizes the summaries printed and emailed, if set
+	anonymizer *privacy.Anonymizer
+	// converter turns entries into the metrics configured in -config
+	converter *logmetric.Converter
+	// tenants are the tenants configured in -config, if any
+	tenants *tenant.Registry
+	health  *server.Server
+	// activity counts the latest entries for -slack-command
+	activity *recent.Activity
+	// partitions holds per-period summaries when -partition-by is set
+	partitions *partition.Summaries
+	// sections are the additional analyses reported after the summary
+	sections []analyzer.Section
+	// errorHistory holds the errors of earlier runs, if -error-history is set
+	errorHistory *bloom.Filter
+	// filers file new errors in -github-issues and -jira-url
+	filers []*issue.Filer
+	// releases is the timeline of -release and -releases, if given
+	releases *release.Timeline
+	// samples are the -sample rules of output sinks
+	samples []sink.SampleRule
+	// signingKey signs the entries forwarded to output sinks, if set
+	signingKey []byte
+	// annotatePath names the files of -annotate-dir, if -annotate-path is set
+	annotatePath *sink.PathTemplate
+	// chaos injects faults, if -chaos is set
+	chaos *chaos.Injector
+	// minWorkers and maxWorkers bound the worker pool, from -workers
+	minWorkers, maxWorkers int
+	// clock paces scheduled runs, snapshots, compaction, pushes, alerting
+	// and tenant quotas
+	clock clock.Clock
+
+	// stopping is closed on SIGINT/SIGTERM
+	stopping chan struct{}
+	// reload receives SIGHUP requests
+	reload chan struct{}
+	// removePIDFile cleans up the PID file, if one was written
+	removePIDFile func()
+
+	mu      sync.Mutex
+	current *processor.LogProcessor
+	// patterns is the pattern section of the latest run, if any
+	patterns *analyzer.PatternSection
+	// scores is the health section of the latest run, if any
+	scores *analyzer.HealthSection
+	// router is the entry router of the latest run, if routes are configured
+	router *sink.Router
+
+	// stats times the current batch run
+	stats *runStats
+}
+
+func newApp(cfg options) (*app, error) {
+	a := &app{
+		cfg:      cfg,
+		clock:    clock.System,
+		stopping: make(chan struct{}),
+		reload:   make(chan struct{}, 1),
+	}
+
+	var err error
+	if cfg.configFile != "" {
+		if a.settings, err = config.Load(cfg.configFile); err != nil {
+			return nil, err
+		}
+		if err := a.settings.RegisterLevels(); err != nil {
+			return nil, err
+		}
+		for _, c := range a.settings.Counters {
+			counter, err := analyzer.NewCounter(c.Name, c.Match, c.Label)
+			if err != nil {
+				return nil, err
+			}
+			a.counters = append(a.counters, counter)
+		}
+		if a.routes, err = newRoutes(a.settings.Routes); err != nil {
+			return nil, err
+		}
+		if len(a.settings.JSONFields) > 0 {
+			if a.mapping, err = parser.NewFieldMapping(a.settings.JSONFields); err != nil {
+				return nil, fmt.Errorf("invalid json_fields in config: %w", err)
+			}
+		}
+	}
+
+	if a.parser, err = parser.ForFormat(cfg.format); err != nil {
+		return nil, err
+	}
+	// Only files are wrapped in envelopes, not network input
+	filesParser := a.jsonParser()
+	if cfg.jsonEnvelope != "" {
+		if cfg.format != "json" && cfg.format != "auto" {
+			return nil, fmt.Errorf("-json-envelope requires -format json or auto")
+		}
+		if filesParser.Envelope, err = parser.ParseEnvelope(cfg.jsonEnvelope); err != nil {
+			return nil, fmt.Errorf("invalid -json-envelope: %w", err)
+		}
+	}
+	switch cfg.format {
+	case "json":
+		a.parser = filesParser
+	case "auto":
+		a.parser = parser.AutoParser{JSON: filesParser}
+	}
+	if a.cfg.pattern == "" {
+		a.cfg.pattern = parser.DefaultPattern(cfg.format)
+	}
+	if a.naming, err = processor.ParseSourceNames(cfg.sourceNames); err != nil {
+		return nil, err
+	}
+	if a.form, err = textnorm.ParseForm(cfg.normalize); err != nil {
+		return nil, err
+	}
+	if cfg.locale != "" {
+		if a.form&textnorm.Fold == 0 {
+			return nil, fmt.Errorf("-normalize-locale needs -normalize-messages fold")
+		}
+		locale, err := textnorm.LocaleForm(cfg.locale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -normalize-locale: %w", err)
+		}
+		a.form |= locale
+	}
+	if a.inUse, err = processor.ParseInUsePolicy(cfg.inUse); err != nil {
+		return nil, err
+	}
+	if cfg.encrypt {
+		if a.cipher, err = loadCipher(cfg.keyCommand); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.minLevel != "" {
+		var ok bool
+		if a.minLevel, ok = models.ParseLevel(cfg.minLevel); !ok {
+			return nil, fmt.Errorf("unknown -min-level %q (known: %v)", cfg.minLevel, models.Levels())
+		}
+	}
+	if cfg.priority != "" {
+		var ok bool
+		if a.priority, ok = models.ParseLevel(cfg.priority); !ok {
+			return nil, fmt.Errorf("unknown -priority-level %q (known: %v)", cfg.priority, models.Levels())
+		}
+	}
+	if a.minWorkers, a.maxWorkers, err = parseWorkers(cfg.workers); err != nil {
+		return nil, err
+	}
+	if cfg.queueSize < 1 {
+		return nil, fmt.Errorf("-queue-size must be positive")
+	}
+	if cfg.analyzerKind != "locks" && cfg.analyzerKind != "actor" {
+		return nil, fmt.Errorf("invalid -analyzer %q, expected locks or actor", cfg.analyzerKind)
+	}
+	if cfg.cardinality < 0 {
+		return nil, fmt.Errorf("-max-cardinality must not be negative")
+	}
+	if cfg.stallAction != "exit" && cfg.stallAction != "restart" {
+		return nil, fmt.Errorf("unknown -stall-action %q (known: exit, restart)", cfg.stallAction)
+	}
+	if cfg.readers < 1 || cfg.parsers < 1 {
+		return nil, fmt.Errorf("-readers and -parsers must be positive")
+	}
+	if !charset.Supported(cfg.encoding) {
+		return nil, fmt.Errorf("unsupported encoding %q (supported: %v)", cfg.encoding, charset.Names())
+	}
+	if cfg.chaos != "" {
+		faults, err := chaos.ParseConfig(cfg.chaos)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -chaos: %w", err)
+		}
+		a.chaos = chaos.New(faults)
+		a.parser = a.chaos.Parser(a.parser)
+	}
+
+	if contains(cfg.sections, "new-errors") && cfg.errorHistory == "" {
+		return nil, fmt.Errorf("-section new-errors needs -error-history")
+	}
+	if cfg.errorHistory != "" {
+		if a.errorHistory, err = bloom.Load(cfg.errorHistory, errorHistoryCapacity, errorHistoryFalsePositives); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.filers, err = newFilers(&cfg); err != nil {
+		return nil, err
+	}
+	if len(a.filers) > 0 && !contains(cfg.sections, "new-errors") {
+		return nil, fmt.Errorf("-github-issues and -jira-url file the errors of -section new-errors, which they need")
+	}
+
+	if a.releases, err = loadReleases(cfg.releases, cfg.releaseFile); err != nil {
+		return nil, err
+	}
+	if a.releases != nil && !contains(cfg.sections, "new-errors") && !contains(cfg.sections, "storms") && !contains(cfg.sections, "gaps") {
+		return nil, fmt.Errorf("-release and -releases annotate -section new-errors, storms and gaps, and need one of them")
+	}
+
+	for _, s := range cfg.samples {
+		rule, err := sink.ParseSampleRule(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -sample: %w", err)
+		}
+		a.samples = append(a.samples, rule)
+	}
+
+	if cfg.signEntries {
+		if a.signingKey = []byte(os.Getenv("LOGPROCESSOR_SIGNING_KEY")); len(a.signingKey) == 0 {
+			return nil, fmt.Errorf("-sign-entries needs the key in LOGPROCESSOR_SIGNING_KEY")
+		}
+	}
+
+	if cfg.emailTo != "" {
+		a.mailer, err = report.NewMailer(cfg.smtpAddr, cfg.smtpUser, os.Getenv("SMTP_PASSWORD"),
+			cfg.emailFrom, strings.Split(cfg.emailTo, ","), cfg.emailSubject)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	policy := privacy.Policy{MinCount: cfg.privacyMin, Epsilon: cfg.privacyEpsilon}
+	if cfg.privacyMin < 0 || cfg.privacyEpsilon < 0 {
+		return nil, fmt.Errorf("-privacy-min-count and -privacy-epsilon must not be negative")
+	}
+	if policy.Enabled() {
+		if len(cfg.sections) > 0 {
+			return nil, fmt.Errorf("-section reports are not anonymized and cannot be combined with -privacy-min-count or -privacy-epsilon")
+		}
+		a.anonymizer = privacy.NewAnonymizer(policy)
+	}
+
+	if a.monitor, err = newMonitor(cfg.thresholds, cfg.pagerDutyKey, cfg.opsgenieKey, a.clock); err != nil {
+		return nil, err
+	}
+
+	if cfg.statsdAddr != "" {
+		statsdOpts := []statsd.Option{statsd.WithPrefix(cfg.statsdPrefix)}
+		if cfg.dogStatsD {
+			statsdOpts = append(statsdOpts, statsd.WithDogStatsD())
+		}
+		if a.metrics, err = statsd.New(cfg.statsdAddr, statsdOpts...); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.settings != nil && len(a.settings.Metrics) > 0 {
+		if a.converter, err = newConverter(a.settings.Metrics, a.metrics); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.settings != nil && len(a.settings.Tenants) > 0 {
+		if a.tenants, err = newTenants(a.settings.Tenants, a.clock); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.sections, err = a.newSections(); err != nil {
+		return nil, err
+	}
+
+	if cfg.partitionBy != "" {
+		period, err := partition.ParsePeriod(cfg.partitionBy)
+		if err != nil {
+			return nil, err
+		}
+		a.partitions = partition.NewSummaries(period)
+	}
+	if cfg.compactEvery > 0 {
+		if a.partitions == nil {
+			return nil, fmt.Errorf("-compact-interval needs -partition-by")
+		}
+		// A run that reprocesses the same input would count it twice
+		if !cfg.listening() && (cfg.schedule == "" || cfg.scheduleMode != "merge") {
+			return nil, fmt.Errorf("-compact-interval needs network listeners or -schedule-mode merge")
+		}
+	}
+
+	// Each run rewrites the annotated files, so entries received over the
+	// network before a reload would be lost
+	if cfg.annotateDir != "" && cfg.listening() {
+		return nil, fmt.Errorf("-annotate-dir cannot be combined with network listeners")
+	}
+	if cfg.annotateDir != "" && filepath.Clean(cfg.annotateDir) == filepath.Clean(cfg.inputDir) {
+		return nil, fmt.Errorf("-annotate-dir must differ from the input directory")
+	}
+	if cfg.annotatePath != "" {
+		if cfg.annotateDir == "" {
+			return nil, fmt.Errorf("-annotate-path needs -annotate-dir")
+		}
+		p, err := sink.ParsePathTemplate(cfg.annotatePath)
+		if err != nil {
+			return nil, err
+		}
+		a.annotatePath = p
+	}
+	if (cfg.annotateShards != 0 || cfg.annotateShard != "") && cfg.annotateDir == "" {
+		return nil, fmt.Errorf("-annotate-shards and -annotate-shard-by need -annotate-dir")
+	}
+	if cfg.annotateShards < 0 {
+		return nil, fmt.Errorf("-annotate-shards must not be negative")
+	}
+	if cfg.annotateShard != "" {
+		if _, err := rules.Field(cfg.annotateShard); err != nil {
+			return nil, fmt.Errorf("invalid -annotate-shard-by: %w", err)
+		}
+	}
+
+	if cfg.natsURL != "" && (cfg.natsStream == "" || cfg.natsConsumer == "") {
+		return nil, fmt.Errorf("-nats-url needs -nats-stream and -nats-consumer")
+	}
+	if cfg.amqpURL != "" {
+		if cfg.amqpQueue == "" {
+			return nil, fmt.Errorf("-amqp-url needs -amqp-queue")
+		}
+		if cfg.amqpPrefetch < 1 || cfg.amqpPrefetch > 65535 {
+			return nil, fmt.Errorf("-amqp-prefetch must be from 1 to 65535")
+		}
+	}
+	if cfg.redisURL != "" {
+		if cfg.redisStream == "" || cfg.redisGroup == "" {
+			return nil, fmt.Errorf("-redis-url needs -redis-stream and -redis-group")
+		}
+		if a.cfg.redisName == "" {
+			if a.cfg.redisName, err = os.Hostname(); err != nil {
+				return nil, fmt.Errorf("failed to get hostname for -redis-consumer: %w", err)
+			}
+		}
+	}
+	if cfg.kafkaBrokers != "" {
+		if cfg.kafkaTopic == "" {
+			return nil, fmt.Errorf("-kafka-brokers needs -kafka-topic")
+		}
+		if cfg.kafkaOffset != "oldest" && cfg.kafkaOffset != "newest" {
+			return nil, fmt.Errorf("invalid -kafka-offset %q, expected oldest or newest", cfg.kafkaOffset)
+		}
+	}
+	if cfg.registryURL != "" && cfg.kafkaBrokers == "" {
+		return nil, fmt.Errorf("-schema-registry requires -kafka-brokers")
+	}
+
+	if cfg.verifyInput != "" && cfg.listening() {
+		return nil, fmt.Errorf("-verify-input cannot be combined with network listeners")
+	}
+	if cfg.shard != "" {
+		shard, err := cluster.ParseShard(cfg.shard)
+		if err != nil {
+			return nil, err
+		}
+		a.shard = &shard
+		if cfg.listening() {
+			return nil, fmt.Errorf("-shard splits input files and cannot be combined with network listeners")
+		}
+	}
+	if cfg.coordinator != "" && (a.shard == nil || cfg.schedule != "") {
+		return nil, fmt.Errorf("-coordinator needs -shard and cannot be combined with -schedule")
+	}
+	if cfg.aggregator != "" {
+		if cfg.pushInterval <= 0 {
+			return nil, fmt.Errorf("-push-interval must be positive")
+		}
+		if a.cfg.workerName == "" {
+			if a.cfg.workerName, err = os.Hostname(); err != nil {
+				return nil, fmt.Errorf("failed to get hostname for -worker-name: %w", err)
+			}
+		}
+	}
+	if err := checkRetention(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.verify {
+		// The recount only sees this run's input
+		switch {
+		case cfg.listening():
+			return nil, fmt.Errorf("-verify cannot be combined with network listeners")
+		case cfg.stateIn != "" || cfg.checkpoint != "" || (cfg.schedule != "" && cfg.scheduleMode == "merge"):
+			return nil, fmt.Errorf("-verify cannot be combined with -state-in, -checkpoint or -schedule-mode merge")
+		}
+	}
+
+	if cfg.slackCommand && cfg.healthAddr == "" {
+		return nil, fmt.Errorf("-slack-command needs -health-addr")
+	}
+
+	if cfg.pidFile != "" {
+		if a.removePIDFile, err = daemon.WritePIDFile(cfg.pidFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.healthAddr != "" {
+		a.health = server.New()
+		a.health.SetSummaryFunc(a.currentSummary)
+		if a.converter != nil {
+			a.health.SetMetricsFunc(a.converter.WritePrometheus)
+		}
+		if a.tenants != nil {
+			a.health.SetTenants(a.tenants)
+		}
+		if contains(cfg.sections, "health") {
+			a.health.SetHealthFunc(a.healthScores)
+		}
+		if cfg.statusOrigin != "" {
+			a.health.SetCORSOrigins(strings.Split(cfg.statusOrigin, ","))
+		}
+		if cfg.compactEvery > 0 {
+			a.health.SetHistoryFunc(func(from, to time.Time) ([]models.PeriodSummary, error) {
+				return a.partitions.History(cfg.partitionDir, from, to)
+			})
+		}
+		if cfg.slackCommand {
+			secret := os.Getenv("SLACK_SIGNING_SECRET")
+			if secret == "" {
+				a.close()
+				return nil, fmt.Errorf("-slack-command needs the signing secret in SLACK_SIGNING_SECRET")
+			}
+			a.activity = recent.New(slackRetention, a.clock)
+			a.activity.SetNormalization(a.form)
+			a.health.SetSlackCommand(secret, a.activity)
+		}
+		if err := a.health.Start(cfg.healthAddr); err != nil {
+			a.close()
+			return nil, err
+		}
+	}
+
+	// Setup signal handling for graceful shutdown and reloads
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		for sig := range sigCh {
+			if sig == syscall.SIGHUP {
+				select {
+				case a.reload <- struct{}{}:
+				default:
+					// A reload is already pending
+				}
+				continue
+			}
+			fmt.Println("\nShutting down...")
+			a.setStatus(server.StatusStopping, daemon.Stopping)
+			close(a.stopping)
+			a.stopCurrent()
+			return
+		}
+	}()
+	go daemon.RunWatchdog(a.stopping)
+
+	return a, nil
+}
+
+// close releases resources held across runs
+func (a *app) close() {
+	if a.metrics != nil {
+		// Send the final counters even if processing failed
+		a.metrics.Close()
+		a.metrics = nil
+	}
+	if a.health != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		a.health.Shutdown(ctx)
+		cancel()
+		a.health = nil
+	}
+	if a.removePIDFile != nil {
+		a.removePIDFile()
+		a.removePIDFile = nil
+	}
+}
+
+// setStatus reports a state change to the health endpoint and systemd
+func (a *app) setStatus(status, notify string) {
+	if a.health != nil {
+		a.health.SetStatus(status)
+	}
+	if _, err := daemon.Notify(notify); err != nil {
+		fmt.Printf("Error notifying service manager: %v\n", err)
+	}
+}
+
+// stopCurrent stops the processor of the run in progress, if any
+func (a *app) stopCurrent() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.current != nil {
+		a.current.Stop()
+	}
+}
+
+// currentSummary returns the summary of the latest run, or nil before the
+// first run has started
+// healthScores returns the health of every service from the latest run
+func (a *app) healthScores() []analyzer.ServiceHealth {
+	a.mu.Lock()
+	scores := a.scores
+	a.mu.Unlock()
+	if scores == nil {
+		return nil
+	}
+	return scores.Scores()
+}
+
+// healthSection returns the health section among sections, if any
+func healthSection(sections []analyzer.Section) *analyzer.HealthSection {
+	for _, s := range sections {
+		if h, ok := analyzer.Unwrap(s).(*analyzer.HealthSection); ok {
+			return h
+		}
+	}
+	return nil
+}
+
+func (a *app) currentSummary() *models.LogSummary {
+	a.mu.Lock()
+	proc := a.current
+	a.mu.Unlock()
+	if proc == nil {
+		return nil
+	}
+	return proc.GetSummary()
+}
+
+// newProcessor creates a processor for one run. Sinks are opened per run
+// because the processor closes them when it finishes.
+func (a *app) newProcessor(logAnalyzer analyzer.Stateful) (*processor.LogProcessor, error) {
+	opts := []processor.Option{
+		processor.WithParser(a.parser),
+		processor.WithClock(a.clock),
+		processor.WithPattern(a.cfg.pattern),
+		processor.WithDecodeWorkers(a.cfg.decoders),
+		processor.WithAutoscaling(a.minWorkers, a.maxWorkers),
+		processor.WithQueueSize(a.cfg.queueSize),
+		processor.WithReaders(a.cfg.readers),
+		processor.WithParsers(a.cfg.parsers),
+		processor.WithPriority(a.priority),
+		processor.WithMaxAge(a.cfg.maxAge),
+		processor.WithWatchdog(a.cfg.stallTimeout, a.stalled),
+		processor.WithMmap(a.cfg.mmap),
+		processor.WithFileTimeout(a.cfg.fileTimeout),
+		processor.WithInUsePolicy(a.inUse, a.cfg.quietPeriod),
+		processor.WithSourceNames(a.naming),
+		processor.WithCardinalityLimit(a.cfg.cardinality, a.cfg.guarded...),
+		processor.WithEncoding(a.cfg.encoding),
+		processor.WithCipher(a.cipher),
+	}
+	opts = append(opts, processor.WithAnalyzer(logAnalyzer))
+	if a.cfg.manifest != "" || a.cfg.verifyInput != "" {
+		opts = append(opts, processor.WithFileHashes())
+	}
+	if a.cfg.recursive {
+		opts = append(opts, processor.WithRecursive())
+	}
+	if a.cfg.archives {
+		opts = append(opts, processor.WithArchives())
+	}
+	if a.shard != nil {
+		opts = append(opts, processor.WithShard(*a.shard))
+	}
+	if a.metrics != nil {
+		opts = append(opts, processor.WithMetrics(a.metrics))
+	}
+	if a.partitions != nil {
+		opts = append(opts, processor.WithSink(partitionSink{a.partitions}))
+	}
+	if a.activity != nil {
+		opts = append(opts, processor.WithSink(activitySink{a.activity}))
+	}
+	if a.converter != nil {
+		opts = append(opts, processor.WithSink(a.converter))
+	}
+	if a.tenants != nil {
+		opts = append(opts, processor.WithSink(a.tenants))
+	}
+	for _, s := range a.sections {
+		opts = append(opts, processor.WithSection(s))
+	}
+	for _, output := range a.cfg.outputs {
+		s, err := sink.Open(output)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, processor.WithSink(a.wrapOutput(s)))
+	}
+	if len(a.routes) > 0 {
+		router, err := a.newRouter()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, processor.WithSink(router))
+	}
+
+	if a.cfg.annotateDir != "" {
+		var annotateOpts []sink.AnnotatingOption
+		if a.annotatePath != nil {
+			annotateOpts = append(annotateOpts, sink.WithPathTemplate(a.annotatePath))
+		}
+		if a.form != 0 {
+			annotateOpts = append(annotateOpts, sink.WithNormalization(a.form))
+		}
+		if a.cfg.annotateShards > 1 {
+			annotateOpts = append(annotateOpts, sink.WithHashShards(a.cfg.annotateShards))
+		}
+		if field := a.cfg.annotateShard; field != "" {
+			value, _ := rules.Field(field)
+			annotateOpts = append(annotateOpts, sink.WithFieldShards(strings.TrimPrefix(field, "fields."), value))
+		}
+		annotator, err := sink.NewAnnotating(a.cfg.annotateDir, a.cipher, annotateOpts...)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, processor.WithSink(annotator))
+	}
+	if a.cfg.mergeSort != "" {
+		sorter, err := mergesort.Create(a.cfg.mergeSort, mergesort.DefaultRunSize, mergesort.WithCipher(a.cipher))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, processor.WithSink(sorter))
+	}
+
+	if a.cfg.stageReport {
+		opts = append(opts, processor.WithStageMetrics())
+	}
+	if a.cfg.checkpoint != "" {
+		opts = append(opts, processor.WithCheckpoint(a.cfg.checkpoint, a.cfg.checkpointN))
+	}
+	if a.cfg.snapshotEvery > 0 || a.cfg.snapshotN > 0 {
+		opts = append(opts, processor.WithSnapshots(a.cfg.snapshotEvery, a.cfg.snapshotN, a.snapshot))
+	}
+
+	proc := processor.NewLogProcessor(a.cfg.inputDir, opts...)
+	if a.settings != nil {
+		proc.Use(a.settings.MapLevel)
+	}
+	proc.Use(processor.NormalizeLevel())
+	if len(a.cfg.services) > 0 {
+		proc.Use(processor.OnlyServices(a.cfg.services...))
+	}
+	if !a.cfg.since.IsZero() || !a.cfg.until.IsZero() {
+		proc.Use(processor.TimeWindow(a.cfg.since, a.cfg.until))
+	}
+	if a.cfg.inferLevel {
+		defaultLevel := models.LogLevel(strings.ToUpper(a.cfg.defaultLevel))
+		if level, ok := models.ParseLevel(a.cfg.defaultLevel); ok {
+			defaultLevel = level
+		}
+		proc.Use(processor.InferLevel(defaultLevel))
+	}
+	if a.minLevel != "" {
+		// After inference, so inferred levels are held to the threshold too
+		proc.Use(processor.MinLevel(a.minLevel))
+	}
+	if a.chaos != nil {
+		proc.Use(a.chaos.Middleware())
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-a.stopping:
+		proc.Stop()
+	default:
+	}
+	a.current = proc
+	a.patterns = patternSection(a.sections)
+	a.scores = healthSection(a.sections)
+	return proc, nil
+}
+
+// snapshot reports an intermediate summary while processing is running
+func (a *app) snapshot(summary *models.LogSummary) {
+	if a.cfg.snapshotFile != "" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err == nil {
+			err = partition.WriteFile(a.cfg.snapshotFile, a.cipher.Seal(append(data, '\n')))
+		}
+		if err != nil {
+			fmt.Printf("Error writing snapshot: %v\n", err)
+		}
+		return
+	}
+	fmt.Printf("\nSnapshot at %s (processing continues):\n", a.clock.Now().Format("15:04:05"))
+	report.WriteText(os.Stdout, summary)
+}
+
+// sources returns the configured network listeners
+func (a *app) sources() []processor.Source {
+	var sources []processor.Source
+	if a.cfg.fluentAddr != "" {
+		sources = append(sources, input.NewFluentSource(a.cfg.fluentAddr))
+	}
+	if a.cfg.gelfUDPAddr != "" {
+		sources = append(sources, input.NewGELFUDPSource(a.cfg.gelfUDPAddr))
+	}
+	if a.cfg.gelfTCPAddr != "" {
+		sources = append(sources, input.NewGELFTCPSource(a.cfg.gelfTCPAddr))
+	}
+	if a.cfg.httpAddr != "" {
+		sources = append(sources, input.NewHTTPSource(a.cfg.httpAddr, a.jsonParser(), a.tenants))
+	}
+	if a.cfg.natsURL != "" {
+		sources = append(sources, input.NewNATSSource(a.cfg.natsURL, a.cfg.natsStream, a.cfg.natsConsumer, a.cfg.natsSubject, a.jsonParser()))
+	}
+	if a.cfg.amqpURL != "" {
+		sources = append(sources, input.NewAMQPSource(a.cfg.amqpURL, a.cfg.amqpQueue, a.cfg.amqpPrefetch, a.jsonParser()))
+	}
+	if a.cfg.redisURL != "" {
+		sources = append(sources, input.NewRedisSource(a.cfg.redisURL, a.cfg.redisStream, a.cfg.redisGroup, a.cfg.redisName, a.cfg.redisClaim, a.jsonParser()))
+	}
+	if a.cfg.kafkaBrokers != "" {
+		var decoder *avro.MessageDecoder
+		if a.cfg.registryURL != "" {
+			decoder = avro.NewMessageDecoder(avro.NewRegistry(a.cfg.registryURL), avro.DefaultFieldMapping())
+		}
+		brokers := strings.Split(a.cfg.kafkaBrokers, ",")
+		sources = append(sources, input.NewKafkaSource(brokers, a.cfg.kafkaTopic, a.cfg.kafkaGroup, a.cfg.kafkaOffset == "oldest", a.jsonParser(), decoder))
+	}
+	return sources
+}
+
+// jsonParser returns the NDJSON parser configured by the flags and config
+func (a *app) jsonParser() parser.JSONParser {
+	jsonParser := parser.JSONParser{MaxLineSize: int(a.cfg.maxLineSize), Mapping: a.mapping}
+	if a.settings != nil {
+		jsonParser.TimestampLayouts = a.settings.TimestampLayouts()
+	}
+	return jsonParser
+}
+
+// runOnce processes the input directory, or serves network input until
+// interrupted, and then publishes the summary
+func (a *app) runOnce() error {
+	if len(a.sources()) > 0 {
+		return a.serve()
+	}
+	a.stats = newRunStats(a.clock)
+	logAnalyzer, err := a.newAnalyzer()
+	if err != nil {
+		return err
+	}
+	defer closeAnalyzer(logAnalyzer)
+	proc, err := a.process(logAnalyzer)
+	if err != nil {
+		return err
+	}
+	summary := proc.GetSummary()
+	if err := a.saveState(logAnalyzer, summary); err != nil {
+		return err
+	}
+	if a.cfg.coordinator != "" {
+		if err := cluster.Send(context.Background(), a.cfg.coordinator, os.Getenv(cluster.TokenEnv), *a.shard, exportState(logAnalyzer, summary)); err != nil {
+			return err
+		}
+		fmt.Printf("Sent shard %s to %s\n", a.shard, a.cfg.coordinator)
+	}
+	// Only remove input once what was learned from it is saved
+	if err := a.enforceRetention(proc); err != nil {
+		return err
+	}
+	a.stats.end("save")
+	if err := a.publish(summary); err != nil {
+		return err
+	}
+	a.stats.end("report")
+	a.stats.print(os.Stdout, proc, summary)
+	return nil
+}
+
+// emptyAnalyzer creates an analyzer of the kind chosen with -analyzer
+func (a *app) emptyAnalyzer() analyzer.Stateful {
+	if a.cfg.analyzerKind == "actor" {
+		return analyzer.NewActorAnalyzer(analyzer.WithCounters(a.counters...))
+	}
+	return analyzer.NewLogAnalyzer(analyzer.WithCounters(a.counters...))
+}
+
+// closeAnalyzer stops the goroutine owning the state of an actor analyzer.
+// Its summary can still be read afterwards.
+func closeAnalyzer(logAnalyzer analyzer.Stateful) {
+	if actor, ok := logAnalyzer.(*analyzer.ActorAnalyzer); ok {
+		actor.Close()
+	}
+}
+
+// newAnalyzer creates an analyzer, restored from -state-in if given
+func (a *app) newAnalyzer() (analyzer.Stateful, error) {
+	logAnalyzer := a.emptyAnalyzer()
+	if a.cfg.stateIn == "" {
+		return logAnalyzer, nil
+	}
+	data, err := os.ReadFile(a.cfg.stateIn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read analyzer state: %w", err)
+	}
+	if data, err = a.cipher.Open(data); err != nil {
+		return nil, fmt.Errorf("failed to read analyzer state %s: %w", a.cfg.stateIn, err)
+	}
+	var state analyzer.State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode analyzer state %s: %w", a.cfg.stateIn, err)
+	}
+	if err := logAnalyzer.Import(&state); err != nil {
+		closeAnalyzer(logAnalyzer)
+		return nil, fmt.Errorf("failed to restore analyzer state %s: %w", a.cfg.stateIn, err)
+	}
+	return logAnalyzer, nil
+}
+
+// saveState writes the analyzer state to -state-out if given, with the
+// accounting of summary, which the analyzer does not track
+func (a *app) saveState(logAnalyzer analyzer.Stateful, summary *models.LogSummary) error {
+	if a.cfg.stateOut == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(exportState(logAnalyzer, summary), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode analyzer state: %w", err)
+	}
+	if err := partition.WriteFile(a.cfg.stateOut, a.cipher.Seal(append(data, '\n'))); err != nil {
+		return fmt.Errorf("failed to save analyzer state: %w", err)
+	}
+	return nil
+}
+
+// exportState returns the analyzer state with the accounting of summary,
+// which the analyzer does not track
+func exportState(logAnalyzer analyzer.Stateful, summary *models.LogSummary) *analyzer.State {
+	state := logAnalyzer.Export()
+	state.Summary.Accounting = summary.Accounting
+	return state
+}
+
+// serve runs the network listeners until interrupted. SIGHUP restarts the
+// listeners and reopens the outputs; counts accumulate across reloads.
+func (a *app) serve() error {
+	shared, err := a.newAnalyzer()
+	if err != nil {
+		return err
+	}
+	defer closeAnalyzer(shared)
+	defer a.startCompaction()()
+	for {
+		proc, err := a.newProcessor(shared)
+		if err != nil {
+			return err
+		}
+
+		// Stop the processor on reload so it can be restarted
+		finished := make(chan struct{})
+		reloading := make(chan bool, 1)
+		go func() {
+			select {
+			case <-a.reload:
+				a.setStatus(server.StatusReloading, daemon.Reloading)
+				proc.Stop()
+				reloading <- true
+			case <-finished:
+				reloading <- false
+			}
+		}()
+
+		a.setStatus(server.StatusRunning, daemon.Ready)
+		err = a.run(proc)
+		close(finished)
+		reloaded := <-reloading
+		if err != nil {
+			return err
+		}
+		if !reloaded {
+			summary := proc.GetSummary()
+			if err := a.saveState(shared, summary); err != nil {
+				return err
+			}
+			return a.publish(summary)
+		}
+		// The next processor starts its accounting afresh
+		accounting := proc.Accounting()
+		shared.Merge(&models.LogSummary{Accounting: &accounting})
+		fmt.Println("Reloading...")
+	}
+}
+
+// process performs a single processing pass and returns the processor
+// that completed it
+func (a *app) process(logAnalyzer analyzer.Stateful) (*processor.LogProcessor, error) {
+	m := a.newManifest()
+	proc, err := a.newProcessor(logAnalyzer)
+	a.stats.end("setup")
+	if err != nil {
+		a.writeManifest(m, nil, err)
+		return nil, err
+	}
+	err = a.run(proc)
+	a.stats.end("process")
+	if err != nil {
+		a.writeManifest(m, proc, err)
+		return nil, err
+	}
+	if a.cfg.verify {
+		if err := proc.Verify(); err != nil {
+			err = fmt.Errorf("verification failed: %w", err)
+			a.writeManifest(m, proc, err)
+			return nil, err
+		}
+		fmt.Println("Verified: summary matches a single-threaded recount")
+	}
+	if a.cfg.verifyInput != "" {
+		if err := a.verifyInput(m, proc); err != nil {
+			a.writeManifest(m, proc, err)
+			return nil, err
+		}
+	}
+	if a.cfg.verify || a.cfg.verifyInput != "" {
+		a.stats.end("verify")
+	}
+	a.writeManifest(m, proc, nil)
+	return proc, nil
+}
+
+// run processes input with proc until it finishes or is stopped
+func (a *app) run(proc *processor.LogProcessor) error {
+	var err error
+	fmt.Println("Starting log processor...")
+	// Network listeners run until interrupted; otherwise process the directory
+	if sources := a.sources(); len(sources) > 0 {
+		// Check thresholds periodically while serving
+		ctx, cancel := context.WithCancel(context.Background())
+		if a.monitor != nil {
+			go a.monitor.Run(ctx, a.cfg.alertInterval, proc.GetSummary)
+		}
+		err = proc.Serve(sources...)
+		cancel()
+	} else {
+		err = proc.Start()
+	}
+	if err != nil {
+		return fmt.Errorf("processing failed: %w", err)
+	}
+	return nil
+}
+
+// publish prints the summary and delivers alerts and the email report
+func (a *app) publish(summary *models.LogSummary) error {
+	// Alerts and partitions use the exact counts, reports leaving the
+	// process the anonymized ones
+	shared := summary
+	if a.anonymizer != nil {
+		shared = a.anonymizer.Anonymize(summary)
+	}
+	fmt.Println()
+	report.WriteText(os.Stdout, shared)
+	if err := report.WriteSections(os.Stdout, a.sections); err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+	// Errors are only recorded once filed, so a failed run files them again
+	if err := a.fileIssues(); err != nil {
+		return err
+	}
+	if err := a.recordErrors(); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	router := a.router
+	a.mu.Unlock()
+	if router != nil {
+		fmt.Println("\nRouted Entries:")
+		for _, c := range router.Counts() {
+			fmt.Printf("  %s: %d\n", c.Name, c.Count)
+		}
+	}
+	if a.tenants != nil {
+		fmt.Println("\nTenants:")
+		for _, s := range a.tenants.Stats() {
+			fmt.Printf("  %s: %d entries, %d rejected over quota\n", s.Name, s.Entries, s.Rejected)
+		}
+	}
+	if a.cfg.stageReport {
+		a.mu.Lock()
+		proc := a.current
+		a.mu.Unlock()
+		if proc != nil {
+			printStages(proc.Stages())
+		}
+	}
+	if a.chaos != nil {
+		fmt.Println("\nInjected Faults:")
+		for _, c := range a.chaos.Counts() {
+			fmt.Printf("  %s: %d\n", c.Kind, c.Count)
+		}
+	}
+
+	if a.partitions != nil && a.cfg.compactEvery > 0 {
+		// Files in the directory hold compacted entries, so add to them
+		n, err := a.partitions.CompactAll(a.cfg.partitionDir)
+		if err != nil {
+			return fmt.Errorf("failed to compact partitioned summaries: %w", err)
+		}
+		fmt.Printf("\nCompacted %d %s summaries into %s\n", n, a.cfg.partitionBy, a.cfg.partitionDir)
+	} else if a.partitions != nil {
+		n, err := a.partitions.WriteDir(a.cfg.partitionDir)
+		if err != nil {
+			return fmt.Errorf("failed to write partitioned summaries: %w", err)
+		}
+		fmt.Printf("\nWrote %d %s summaries to %s\n", n, a.cfg.partitionBy, a.cfg.partitionDir)
+	}
+
+	if a.monitor != nil {
+		if err := a.monitor.Check(context.Background(), summary); err != nil {
+			fmt.Printf("Error sending alert: %v\n", err)
+		}
+	}
+
+	if a.mailer != nil {
+		if err := a.mailer.Send(shared); err != nil {
+			return fmt.Errorf("failed to email summary: %w", err)
+		}
+		fmt.Printf("\nSummary emailed to %s\n", a.cfg.emailTo)
+	}
+	return nil
+}
+
+// runScheduled re-processes the input whenever the cron schedule fires,
+// until interrupted. In merge mode one analyzer accumulates across runs, so
+// entries already counted by an earlier run are skipped. SIGHUP triggers an
+// immediate run.
+func (a *app) runScheduled() error {
+	sched, err := schedule.Parse(a.cfg.schedule)
+	if err != nil {
+		return err
+	}
+	if a.cfg.scheduleMode != "replace" && a.cfg.scheduleMode != "merge" {
+		return fmt.Errorf("invalid schedule mode %q (expected replace or merge)", a.cfg.scheduleMode)
+	}
+	if len(a.sources()) > 0 {
+		return fmt.Errorf("-schedule cannot be combined with network listeners")
+	}
+
+	var merged analyzer.Stateful
+	if a.cfg.scheduleMode == "merge" {
+		merged = a.emptyAnalyzer()
+		defer closeAnalyzer(merged)
+	}
+
+	defer a.startCompaction()()
+	a.setStatus(server.StatusRunning, daemon.Ready)
+	for {
+		next := sched.Next(a.clock.Now())
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q never fires", a.cfg.schedule)
+		}
+		fmt.Printf("Next run at %s\n", next.Format(time.RFC3339))
+
+		select {
+		case <-a.stopping:
+			return nil
+		case <-a.clock.After(next.Sub(a.clock.Now())):
+		case <-a.reload:
+			fmt.Println("Reload requested, running now")
+		}
+
+		if merged == nil {
+			// Each fresh summary may alert again and starts new partitions
+			if a.monitor != nil {
+				a.monitor.Reset()
+			}
+			if a.partitions != nil {
+				a.partitions.Reset()
+			}
+			a.sections, _ = a.newSections()
+		}
+		a.stats = newRunStats(a.clock)
+		logAnalyzer := merged
+		if logAnalyzer == nil {
+			logAnalyzer = a.emptyAnalyzer()
+		}
+		proc, err := a.process(logAnalyzer)
+		if merged == nil {
+			closeAnalyzer(logAnalyzer)
+		}
+		if err != nil {
+			// A failed run should not end the schedule
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		if err := a.enforceRetention(proc); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		a.stats.end("save")
+		summary := proc.GetSummary()
+		if err := a.publish(summary); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		a.stats.end("report")
+		a.stats.print(os.Stdout, proc, summary)
+	}
+}
+
+// newSections creates the analysis sections named by -section
+func (a *app) newSections() ([]analyzer.Section, error) {
+	var excluded []string
+	if a.settings != nil {
+		excluded = a.settings.ExcludedFromSections()
+	}
+
+	names := a.cfg.sections
+	// The gotest format always reports test results
+	if a.cfg.format == "gotest" && !contains(names, "tests") {
+		names = append(names, "tests")
+	}
+
+	var sections []analyzer.Section
+	for _, name := range names {
+		// Sections with thresholds take them from their flags
+		var s analyzer.Section
+		var err error
+		switch name {
+		case "storms":
+			s = analyzer.NewStormSection(a.cfg.stormThreshold)
+		case "cost":
+			perGB, currency := analyzer.DefaultPricePerGB, analyzer.DefaultCurrency
+			if a.settings != nil && a.settings.Pricing != nil {
+				perGB = a.settings.Pricing.PerGB
+				if a.settings.Pricing.Currency != "" {
+					currency = a.settings.Pricing.Currency
+				}
+			}
+			s = analyzer.NewCostSection(perGB, currency)
+		case "sizes":
+			s = analyzer.NewSizeSection(int(a.cfg.largeMessage))
+		case "new-errors":
+			s = analyzer.NewErrorHistorySection(20, a.errorHistory)
+		case "skew":
+			s = analyzer.NewSkewSection(a.cfg.skewThreshold)
+		case "gaps":
+			s = analyzer.NewGapSection(a.cfg.gapThreshold)
+		case "health":
+			weights, err := analyzer.ParseHealthWeights(a.cfg.healthWeights)
+			if err != nil {
+				return nil, err
+			}
+			// Storms and gaps count as anomalies, with the same thresholds
+			// as their own sections
+			s = analyzer.NewHealthSection(weights,
+				analyzer.NewStormSection(a.cfg.stormThreshold),
+				analyzer.NewGapSection(a.cfg.gapThreshold))
+		default:
+			if s, err = analyzer.NewSection(name); err != nil {
+				return nil, err
+			}
+		}
+		if r, ok := s.(analyzer.ReleaseSection); ok && a.releases != nil {
+			r.SetReleases(a.releases)
+		}
+		if n, ok := s.(analyzer.NormalizingSection); ok {
+			n.SetNormalization(a.form)
+		}
+		sections = append(sections, analyzer.ExcludeServices(s, excluded...))
+	}
+	return sections, nil
+}
+
+// newFilers creates a filer for each issue tracker configured
+func newFilers(cfg *options) ([]*issue.Filer, error) {
+	if cfg.issueMinCount < 1 {
+		return nil, fmt.Errorf("-issue-min-count must be positive")
+	}
+	var filers []*issue.Filer
+	if cfg.gitHubIssues != "" {
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			return nil, fmt.Errorf("-github-issues needs a token in GITHUB_TOKEN")
+		}
+		tracker, err := issue.NewGitHub(cfg.gitHubIssues, token)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -github-issues: %w", err)
+		}
+		filers = append(filers, issue.NewFiler(tracker.WithURL(cfg.gitHubAPI), cfg.issueMinCount))
+	}
+	if cfg.jiraURL != "" {
+		token := os.Getenv("JIRA_API_TOKEN")
+		if token == "" || cfg.jiraProject == "" {
+			return nil, fmt.Errorf("-jira-url needs -jira-project and a token in JIRA_API_TOKEN")
+		}
+		tracker := issue.NewJira(cfg.jiraURL, cfg.jiraProject, cfg.jiraIssueType, cfg.jiraUser, token)
+		filers = append(filers, issue.NewFiler(tracker, cfg.issueMinCount))
+	}
+	return filers, nil
+}
+
+// fileIssues files the new errors of -section new-errors in the issue
+// trackers and prints the issues created or commented on
+func (a *app) fileIssues() error {
+	if len(a.filers) == 0 {
+		return nil
+	}
+	var groups []analyzer.ErrorGroup
+	for _, s := range a.sections {
+		if h, ok := analyzer.Unwrap(s).(*analyzer.ErrorHistorySection); ok {
+			groups = append(groups, h.Groups()...)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	fmt.Println("\nFiled Issues:")
+	for _, f := range a.filers {
+		filed, err := f.File(ctx, groups)
+		for _, i := range filed {
+			action := "commented on"
+			if i.Created {
+				action = "created"
+			}
+			fmt.Printf("  %s %s: [%s] %s\n", action, i.Key, i.Group.Service, i.Group.Pattern)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to file issues: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadReleases builds the timeline of the -release flags and the -releases
+// file, or returns nil if neither is given
+func loadReleases(flags []string, path string) (*release.Timeline, error) {
+	var releases []release.Release
+	if path != "" {
+		var err error
+		if releases, err = release.Load(path); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range flags {
+		r, err := release.Parse(f, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("invalid -release: %w", err)
+		}
+		releases = append(releases, r)
+	}
+	if len(releases) == 0 {
+		return nil, nil
+	}
+	return release.NewTimeline(releases), nil
+}
+
+// Size of the -error-history filter: 100,000 distinct errors take 180KB
+// with one in a thousand new errors mistaken for a known one
+const (
+	errorHistoryCapacity       = 100000
+	errorHistoryFalsePositives = 0.001
+)
+
+// recordErrors adds the new errors of -section new-errors to -error-history,
+// so later runs report them as seen
+func (a *app) recordErrors() error {
+	if a.errorHistory == nil {
+		return nil
+	}
+	for _, s := range a.sections {
+		if h, ok := analyzer.Unwrap(s).(*analyzer.ErrorHistorySection); ok {
+			for _, g := range h.Groups() {
+				if g.New {
+					a.errorHistory.Add(g.Fingerprint())
+				}
+			}
+		}
+	}
+	if rate := a.errorHistory.FalsePositiveRate(); rate > 0.01 {
+		fmt.Printf("Warning: %s is nearly full and reports %.1f%% of new errors as seen; delete it to start over\n", a.cfg.errorHistory, rate*100)
+	}
+	return a.errorHistory.Save(a.cfg.errorHistory)
+}
+
+// exitStalled is the exit status of a run the watchdog ended
+const exitStalled = 3
+
+// stalled reports a hung pipeline with the stacks of all goroutines, then
+// exits or has the stuck workers replaced, as -stall-action says
+func (a *app) stalled(s processor.Stall) bool {
+	fmt.Fprintf(os.Stderr, "Pipeline stalled for %s: %d entries in flight, %d files pending, %d stuck workers\n",
+		s.For.Round(time.Millisecond), s.InFlight, s.PendingFiles, s.StuckWorkers)
+	pprof.Lookup("goroutine").WriteTo(os.Stderr, 2)
+	if a.cfg.stallAction == "exit" {
+		os.Exit(exitStalled)
+	}
+	fmt.Fprintf(os.Stderr, "Replacing %d stuck workers\n", s.StuckWorkers)
+	return true
+}
+
+// printStages prints the time spent in each pipeline stage and names the
+// stage most of it went to
+func printStages(r processor.StageReport) {
+	fmt.Println("\nPipeline Stages:")
+	for _, s := range r.Stages {
+		fmt.Printf("  %-40s %10d entries %12s %12.0f/s\n", s.Name, s.Entries, s.Time.Round(time.Microsecond), s.Throughput())
+	}
+	fmt.Printf("  Queue depth: mean %.1f, max %d of %d; parsers blocked %s\n",
+		r.Queue.MeanDepth, r.Queue.MaxDepth, r.Queue.Capacity, r.Queue.Blocked.Round(time.Microsecond))
+	if r.Queue.Prioritized > 0 {
+		fmt.Printf("  Priority lane: %d entries\n", r.Queue.Prioritized)
+	}
+	fmt.Printf("  Workers: peak %d\n", r.PeakWorkers)
+	if slowest, share, ok := r.Bottleneck(); ok {
+		fmt.Printf("  Bottleneck: %.0f%% of time in %s\n", share*100, slowest.Name)
+	}
+}
+
+// wrapOutput applies the transforms requested on the command line to an
+// output sink
+func (a *app) wrapOutput(s sink.Sink) sink.Sink {
+	if a.chaos != nil {
+		// Innermost, so the faults look like the destination's
+		s = a.chaos.Sink(s)
+	}
+	if a.signingKey != nil {
+		// Inside the other transforms, so the entries are signed as sent
+		s = sink.NewSigning(s, a.signingKey)
+	}
+	if a.cfg.truncateAt > 0 {
+		s = sink.NewTruncating(s, int(a.cfg.truncateAt))
+	}
+	if a.cfg.collapseWindow > 0 {
+		s = sink.NewCollapsing(s, a.cfg.collapseWindow, a.form)
+	}
+	if len(a.samples) > 0 {
+		// Outermost, so entries left out are not collapsed or truncated
+		s = sink.NewSampling(s, a.samples)
+	}
+	return s
+}
+
+// newConverter creates the converter of the configured metrics, reporting
+// them to the statsd client too if there is one
+func newConverter(configs []config.MetricConfig, client *statsd.Client) (*logmetric.Converter, error) {
+	defs := make([]logmetric.Definition, 0, len(configs))
+	for _, mc := range configs {
+		defs = append(defs, logmetric.Definition{
+			Name:    mc.Name,
+			Type:    mc.Type,
+			Match:   mc.Match,
+			Value:   mc.Value,
+			Labels:  mc.Labels,
+			Buckets: mc.Buckets,
+		})
+	}
+	var opts []logmetric.Option
+	if client != nil {
+		opts = append(opts, logmetric.WithReporter(client))
+	}
+	converter, err := logmetric.New(defs, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics in config: %w", err)
+	}
+	return converter, nil
+}
+
+// newTenants creates the tenants configured in -config
+func newTenants(configs []config.TenantConfig, c clock.Clock) (*tenant.Registry, error) {
+	defs := make([]tenant.Definition, 0, len(configs))
+	for _, tc := range configs {
+		defs = append(defs, tenant.Definition{
+			Name:      tc.Name,
+			KeySHA256: tc.APIKeySHA256,
+			PerMinute: tc.MaxEntriesPerMinute,
+			Admin:     tc.Admin,
+		})
+	}
+	tenants, err := tenant.New(defs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenants in config: %w", err)
+	}
+	return tenants.WithClock(c), nil
+}
+
+// contains reports whether list includes value
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newMonitor creates an alert monitor for the configured thresholds, or nil
+// when no thresholds are set
+func newMonitor(thresholds []string, pagerDutyKey, opsgenieKey string, c clock.Clock) (*alert.Monitor, error) {
+	if len(thresholds) == 0 {
+		return nil, nil
+	}
+
+	var parsed []alert.Threshold
+	for _, t := range thresholds {
+		th, err := alert.ParseThreshold(t)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, th)
+	}
+
+	var notifiers []alert.Notifier
+	if pagerDutyKey != "" {
+		notifiers = append(notifiers, alert.NewPagerDuty(pagerDutyKey))
+	}
+	if opsgenieKey != "" {
+		notifiers = append(notifiers, alert.NewOpsgenie(opsgenieKey))
+	}
+	if len(notifiers) == 0 {
+		return nil, fmt.Errorf("alert thresholds need a PagerDuty routing key or Opsgenie API key")
+	}
+
+	host, _ := os.Hostname()
+	return alert.NewMonitor(host, parsed, notifiers...).WithClock(c), nil
+}
+
+// slackRetention is how far back -slack-command can answer
+const slackRetention = 24 * time.Hour
+
+// activitySink feeds processed entries into the counts -slack-command
+// answers from, which outlive each processor like partitionSink's summaries
+type activitySink struct {
+	activity *recent.Activity
+}
+
+func (s activitySink) Write(entry models.LogEntry) error {
+	s.activity.Add(entry)
+	return nil
+}
+
+func (s activitySink) Close() error {
+	return nil
+}
+
+// partitionSink feeds processed entries into the partitioned summaries. The
+// summaries outlive each processor, so closing the sink leaves them intact.
+type partitionSink struct {
+	summaries *partition.Summaries
+}
+
+func (s partitionSink) Write(entry models.LogEntry) error {
+	s.summaries.Add(entry)
+	return nil
+}
+
+func (s partitionSink) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/processor"
+)
+
+// runStats times the phases of a batch run for the statistics printed at
+// its end and the manifest
+type runStats struct {
+	clock   clock.Clock
+	started time.Time
+	// last is when the current phase started
+	last   time.Time
+	phases []runPhase
+}
+
+// runPhase is the time spent in one part of a run
+type runPhase struct {
+	name string
+	time time.Duration
+}
+
+func newRunStats(c clock.Clock) *runStats {
+	now := c.Now()
+	return &runStats{clock: c, started: now, last: now}
+}
+
+// end ends the current phase, naming it, and starts the next
+func (s *runStats) end(name string) {
+	now := s.clock.Now()
+	s.phases = append(s.phases, runPhase{name: name, time: now.Sub(s.last)})
+	s.last = now
+}
+
+// print writes the wall time of the run so far, what proc read and how
+// fast, and the time spent in each phase
+func (s *runStats) print(w io.Writer, proc *processor.LogProcessor, summary *models.LogSummary) {
+	wall := s.clock.Now().Sub(s.started)
+	entries := summary.TotalEntries
+	if summary.Accounting != nil {
+		entries = summary.Accounting.Read
+	}
+	bytes := proc.BytesRead()
+
+	fmt.Fprintln(w, "\nRun Statistics:")
+	fmt.Fprintf(w, "  Wall time: %s\n", roundDuration(wall))
+	fmt.Fprintf(w, "  Read: %d files, %d entries, %s\n", len(proc.FileResults()), entries, formatBytes(float64(bytes)))
+	if seconds := wall.Seconds(); seconds > 0 {
+		fmt.Fprintf(w, "  Throughput: %.0f entries/s, %s/s\n", float64(entries)/seconds, formatBytes(float64(bytes)/seconds))
+	}
+	phases := make([]string, len(s.phases))
+	for i, p := range s.phases {
+		phases[i] = fmt.Sprintf("%s %s", p.name, roundDuration(p.time))
+	}
+	fmt.Fprintf(w, "  Phases: %s\n", strings.Join(phases, ", "))
+}
+
+// roundDuration rounds a duration for display, to milliseconds from a
+// second on
+func roundDuration(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// formatBytes writes a number of bytes with a binary unit, e.g. 4.5 MiB
+func formatBytes(n float64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	units := []string{"KiB", "MiB", "GiB", "TiB"}
+	unit := ""
+	for _, u := range units {
+		if n < 1024 {
+			break
+		}
+		n, unit = n/1024, u
+	}
+	return fmt.Sprintf("%.1f %s", n, unit)
+}
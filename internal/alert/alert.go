@@ -0,0 +1,164 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Alert describes a threshold breach to be delivered to a notifier
+type Alert struct {
+	// Key deduplicates repeated notifications for the same condition
+	Key       string
+	Summary   string
+	Level     models.LogLevel
+	Source    string
+	Details   map[string]string
+	Timestamp time.Time
+}
+
+// Notifier delivers alerts to an external paging or chat system
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// Threshold fires when the number of entries at Level reaches Count
+type Threshold struct {
+	Level models.LogLevel
+	Count int
+}
+
+// ParseThreshold parses thresholds written as "LEVEL>=N", e.g. "ERROR>=100"
+func ParseThreshold(s string) (Threshold, error) {
+	level, count, ok := strings.Cut(s, ">=")
+	if !ok {
+		return Threshold{}, fmt.Errorf("invalid threshold %q, expected LEVEL>=N", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return Threshold{}, fmt.Errorf("invalid threshold count in %q", s)
+	}
+	return Threshold{
+		Level: models.LogLevel(strings.ToUpper(strings.TrimSpace(level))),
+		Count: n,
+	}, nil
+}
+
+// String returns the threshold in the form accepted by ParseThreshold
+func (t Threshold) String() string {
+	return fmt.Sprintf("%s>=%d", t.Level, t.Count)
+}
+
+// Monitor checks summaries against thresholds and notifies each breach once
+type Monitor struct {
+	thresholds []Threshold
+	notifiers  []Notifier
+	source     string
+	clock      clock.Clock
+
+	mu    sync.Mutex
+	fired map[string]bool
+}
+
+// NewMonitor creates a monitor. source identifies this processor in alerts.
+func NewMonitor(source string, thresholds []Threshold, notifiers ...Notifier) *Monitor {
+	return &Monitor{
+		thresholds: thresholds,
+		notifiers:  notifiers,
+		source:     source,
+		clock:      clock.System,
+		fired:      make(map[string]bool),
+	}
+}
+
+// WithClock sets the clock that stamps alerts and paces Run, e.g. the log
+// time of a replay
+func (m *Monitor) WithClock(c clock.Clock) *Monitor {
+	m.clock = c
+	return m
+}
+
+// Check notifies every threshold newly breached by summary and returns the
+// first delivery error. An alert no notifier delivered fires again on the
+// next check.
+func (m *Monitor) Check(ctx context.Context, summary *models.LogSummary) error {
+	var firstErr error
+	for _, a := range m.breaches(summary) {
+		delivered := false
+		for _, n := range m.notifiers {
+			err := n.Notify(ctx, a)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			delivered = delivered || err == nil
+		}
+		if !delivered {
+			m.mu.Lock()
+			delete(m.fired, a.Key)
+			m.mu.Unlock()
+		}
+	}
+	return firstErr
+}
+
+// breaches returns alerts for thresholds that have not fired before, marking
+// them fired so concurrent checks do not send them twice
+func (m *Monitor) breaches(summary *models.LogSummary) []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var alerts []Alert
+	for _, t := range m.thresholds {
+		count := summary.ByLevel[t.Level]
+		key := fmt.Sprintf("logprocessor:%s:%s", m.source, t)
+		if count < t.Count || m.fired[key] {
+			continue
+		}
+		m.fired[key] = true
+
+		alerts = append(alerts, Alert{
+			Key:     key,
+			Summary: fmt.Sprintf("%d %s log entries on %s (threshold %d)", count, t.Level, m.source, t.Count),
+			Level:   t.Level,
+			Source:  m.source,
+			Details: map[string]string{
+				"threshold":     t.String(),
+				"count":         strconv.Itoa(count),
+				"total_entries": strconv.Itoa(summary.TotalEntries),
+			},
+			Timestamp: m.clock.Now().UTC(),
+		})
+	}
+	return alerts
+}
+
+// Reset forgets which thresholds have fired, e.g. before analyzing a fresh
+// data set
+func (m *Monitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fired = make(map[string]bool)
+}
+
+// Run checks the summary returned by get every interval until ctx is done
+func (m *Monitor) Run(ctx context.Context, interval time.Duration, get func() *models.LogSummary) {
+	ticker := m.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := m.Check(ctx, get()); err != nil {
+				fmt.Printf("Error sending alert: %v\n", err)
+			}
+		}
+	}
+}
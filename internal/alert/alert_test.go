@@ -0,0 +1,177 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestParseThreshold(t *testing.T) {
+	th, err := ParseThreshold("error >= 10")
+	if err != nil {
+		t.Fatalf("Failed to parse threshold: %v", err)
+	}
+	if th.Level != models.ERROR || th.Count != 10 {
+		t.Errorf("Unexpected threshold %+v", th)
+	}
+
+	for _, bad := range []string{"ERROR", "ERROR>=x", "ERROR>=0"} {
+		if _, err := ParseThreshold(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestMonitorNotifiers(t *testing.T) {
+	var pdEvents, ogAlerts []map[string]interface{}
+	var ogAuth string
+
+	pd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+		pdEvents = append(pdEvents, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer pd.Close()
+
+	og := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&alert)
+		ogAlerts = append(ogAlerts, alert)
+		ogAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer og.Close()
+
+	monitor := NewMonitor("host-1",
+		[]Threshold{{Level: models.FATAL, Count: 1}, {Level: models.ERROR, Count: 5}},
+		NewPagerDuty("routing-key").WithURL(pd.URL),
+		NewOpsgenie("api-key").WithURL(og.URL))
+
+	summary := models.NewLogSummary()
+	summary.ByLevel[models.ERROR] = 3
+	summary.ByLevel[models.FATAL] = 1
+
+	// Only the FATAL threshold is breached, and repeated checks do not re-fire
+	for i := 0; i < 2; i++ {
+		if err := monitor.Check(context.Background(), summary); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+	}
+	if len(pdEvents) != 1 || len(ogAlerts) != 1 {
+		t.Fatalf("Expected one notification each, got %d and %d", len(pdEvents), len(ogAlerts))
+	}
+
+	payload := pdEvents[0]["payload"].(map[string]interface{})
+	if pdEvents[0]["routing_key"] != "routing-key" || payload["severity"] != "critical" {
+		t.Errorf("Unexpected PagerDuty event %v", pdEvents[0])
+	}
+	if pdEvents[0]["dedup_key"] != "logprocessor:host-1:FATAL>=1" {
+		t.Errorf("Unexpected dedup key %v", pdEvents[0]["dedup_key"])
+	}
+	if ogAlerts[0]["priority"] != "P1" || ogAlerts[0]["alias"] != pdEvents[0]["dedup_key"] {
+		t.Errorf("Unexpected Opsgenie alert %v", ogAlerts[0])
+	}
+	if ogAuth != "GenieKey api-key" {
+		t.Errorf("Unexpected Opsgenie authorization %q", ogAuth)
+	}
+
+	// Crossing the ERROR threshold fires a second, error-severity event
+	summary.ByLevel[models.ERROR] = 5
+	monitor.Check(context.Background(), summary)
+	if len(pdEvents) != 2 || pdEvents[1]["payload"].(map[string]interface{})["severity"] != "error" {
+		t.Errorf("Expected an error-severity event, got %v", pdEvents)
+	}
+}
+
+func TestNotifierRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid routing key", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := NewPagerDuty("bad").WithURL(server.URL).Notify(context.Background(), Alert{Level: models.ERROR})
+	if err == nil {
+		t.Error("Expected an error for a rejected event")
+	}
+}
+
+// channelNotifier passes alerts to a channel
+type channelNotifier chan Alert
+
+func (c channelNotifier) Notify(ctx context.Context, a Alert) error {
+	c <- a
+	return nil
+}
+
+func TestMonitorRunClock(t *testing.T) {
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	alerts := make(channelNotifier, 1)
+	monitor := NewMonitor("host-1", []Threshold{{Level: models.ERROR, Count: 1}}, alerts).WithClock(fake)
+
+	summary := models.NewLogSummary()
+	summary.ByLevel[models.ERROR] = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		monitor.Run(ctx, time.Minute, func() *models.LogSummary { return summary })
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	for fake.Pending() == 0 {
+		runtime.Gosched()
+	}
+	fake.Advance(time.Minute)
+	a := <-alerts
+	if !a.Timestamp.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected the alert stamped %v, got %v", start.Add(time.Minute), a.Timestamp)
+	}
+}
+
+// flakyNotifier fails the first failures notifications and counts the rest
+type flakyNotifier struct {
+	failures  int
+	delivered int
+}
+
+func (f *flakyNotifier) Notify(ctx context.Context, a Alert) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("service unavailable")
+	}
+	f.delivered++
+	return nil
+}
+
+func TestMonitorRetriesUndelivered(t *testing.T) {
+	notifier := &flakyNotifier{failures: 1}
+	monitor := NewMonitor("host-1", []Threshold{{Level: models.ERROR, Count: 1}}, notifier)
+	summary := models.NewLogSummary()
+	summary.ByLevel[models.ERROR] = 1
+
+	if err := monitor.Check(context.Background(), summary); err == nil {
+		t.Error("Expected the failed delivery to be reported")
+	}
+	// A transient failure must not suppress the alert
+	for i := 0; i < 2; i++ {
+		if err := monitor.Check(context.Background(), summary); err != nil {
+			t.Errorf("Expected the alert to be delivered, got %v", err)
+		}
+	}
+	if notifier.delivered != 1 {
+		t.Errorf("Expected the alert delivered once after the failure, got %d", notifier.delivered)
+	}
+}
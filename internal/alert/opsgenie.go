@@ -0,0 +1,70 @@
+package alert
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const opsgenieAlertsURL = "https://api.opsgenie.com/v2/alerts"
+
+// Opsgenie creates alerts through the Opsgenie Alert API
+type Opsgenie struct {
+	apiKey string
+	url    string
+	client *http.Client
+}
+
+// NewOpsgenie creates a notifier authenticating with an API integration key.
+// EU accounts use https://api.eu.opsgenie.com, set with WithURL.
+func NewOpsgenie(apiKey string) *Opsgenie {
+	return &Opsgenie{
+		apiKey: apiKey,
+		url:    opsgenieAlertsURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// WithURL overrides the alerts endpoint
+func (o *Opsgenie) WithURL(url string) *Opsgenie {
+	o.url = url
+	return o
+}
+
+// Notify creates an alert. Opsgenie deduplicates open alerts by alias.
+func (o *Opsgenie) Notify(ctx context.Context, a Alert) error {
+	message := a.Summary
+	if len(message) > 130 {
+		message = message[:130]
+	}
+
+	body := map[string]interface{}{
+		"message":     message,
+		"alias":       a.Key,
+		"description": a.Summary,
+		"priority":    opsgeniePriority(a.Level),
+		"source":      a.Source,
+		"details":     a.Details,
+		"tags":        []string{"logprocessor", string(a.Level)},
+	}
+	headers := map[string]string{"Authorization": "GenieKey " + o.apiKey}
+	return postJSON(ctx, o.client, o.url, headers, body)
+}
+
+// opsgeniePriority maps levels onto priorities P1 (highest) to P5
+func opsgeniePriority(level models.LogLevel) string {
+	switch level {
+	case models.FATAL:
+		return "P1"
+	case models.ERROR:
+		return "P2"
+	case models.WARNING:
+		return "P3"
+	case models.INFO:
+		return "P4"
+	default:
+		return "P5"
+	}
+}
@@ -0,0 +1,99 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers incidents through the PagerDuty Events API v2
+type PagerDuty struct {
+	routingKey string
+	url        string
+	client     *http.Client
+}
+
+// NewPagerDuty creates a notifier for the service integration routingKey
+func NewPagerDuty(routingKey string) *PagerDuty {
+	return &PagerDuty{
+		routingKey: routingKey,
+		url:        pagerDutyEventsURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// WithURL overrides the events endpoint, e.g. for EU service regions
+func (p *PagerDuty) WithURL(url string) *PagerDuty {
+	p.url = url
+	return p
+}
+
+// Notify triggers an event. PagerDuty groups events with the same dedup key
+// into a single incident.
+func (p *PagerDuty) Notify(ctx context.Context, a Alert) error {
+	event := map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    a.Key,
+		"payload": map[string]interface{}{
+			"summary":        a.Summary,
+			"source":         a.Source,
+			"severity":       pagerDutySeverity(a.Level),
+			"timestamp":      a.Timestamp.Format(time.RFC3339),
+			"component":      "logprocessor",
+			"custom_details": a.Details,
+		},
+	}
+	return postJSON(ctx, p.client, p.url, nil, event)
+}
+
+// pagerDutySeverity maps levels onto PagerDuty's four severities
+func pagerDutySeverity(level models.LogLevel) string {
+	switch level {
+	case models.FATAL:
+		return "critical"
+	case models.ERROR:
+		return "error"
+	case models.WARNING:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// postJSON posts body as JSON and treats any non-2xx response as an error
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("alert rejected with %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package analyzer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+var _ Analyzer = (*ActorAnalyzer)(nil)
+
+// ActorAnalyzer aggregates statistics like LogAnalyzer, but its state is
+// owned by a single goroutine that applies requests sent over a channel one
+// at a time. Nothing is shared between callers, so no interleaving of
+// workers can corrupt the counts. Counter expressions are still matched in
+// the calling goroutine. Close stops the goroutine.
+type ActorAnalyzer struct {
+	counters  []*Counter
+	requests  chan func()
+	quit      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+
+	// Owned by the goroutine started in NewActorAnalyzer until it stops
+	agg         *aggregate
+	ids         idTable
+	hashMatches int
+}
+
+// NewActorAnalyzer creates an analyzer and starts the goroutine owning its
+// state. It accepts the options of NewLogAnalyzer.
+func NewActorAnalyzer(opts ...Option) *ActorAnalyzer {
+	// Options configure a LogAnalyzer; only their settings are taken over
+	settings := &LogAnalyzer{}
+	for _, opt := range opts {
+		opt(settings)
+	}
+	a := &ActorAnalyzer{
+		counters: settings.counters,
+		requests: make(chan func()),
+		quit:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		agg:      newAggregate(settings.counters),
+		ids:      newIDTable(),
+	}
+	go a.run()
+	return a
+}
+
+// run applies requests until Close is called
+func (a *ActorAnalyzer) run() {
+	defer close(a.stopped)
+	for {
+		select {
+		case request := <-a.requests:
+			request()
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+// do has the owner goroutine apply request. It reports false if the
+// analyzer is closed, in which case request is not applied.
+func (a *ActorAnalyzer) do(request func()) bool {
+	select {
+	case a.requests <- request:
+		return true
+	case <-a.quit:
+		return false
+	}
+}
+
+// add counts an entry unless its ID was seen before. Only the owner
+// goroutine calls it.
+func (a *ActorAnalyzer) add(entry models.LogEntry, matched []*Counter, labels []string) bool {
+	switch a.ids.add(entry.ID, hashID(entry.ID)) {
+	case idDuplicate:
+		return false
+	case idHashMatch:
+		a.hashMatches++
+		return false
+	}
+	a.agg.add(entry, matched, labels)
+	return true
+}
+
+// Process analyzes a log entry and reports whether it was new. Entries sent
+// after Close are not counted.
+func (a *ActorAnalyzer) Process(entry models.LogEntry) bool {
+	matched, labels := matchCounters(a.counters, entry.Message)
+	result := make(chan bool, 1)
+	if !a.do(func() { result <- a.add(entry, matched, labels) }) {
+		return false
+	}
+	return <-result
+}
+
+// ProcessBatch analyzes several entries with a single request
+func (a *ActorAnalyzer) ProcessBatch(entries []models.LogEntry) {
+	matched := make([][]*Counter, len(entries))
+	labels := make([][]string, len(entries))
+	for i, entry := range entries {
+		matched[i], labels[i] = matchCounters(a.counters, entry.Message)
+	}
+	done := make(chan struct{})
+	if !a.do(func() {
+		for i, entry := range entries {
+			a.add(entry, matched[i], labels[i])
+		}
+		close(done)
+	}) {
+		return
+	}
+	<-done
+}
+
+// Merge adds the counts and time range of a summary produced elsewhere
+func (a *ActorAnalyzer) Merge(summary *models.LogSummary) {
+	done := make(chan struct{})
+	if a.do(func() {
+		a.agg.merge(summary)
+		close(done)
+	}) {
+		<-done
+	}
+}
+
+// GetSummary returns a copy of the current summary, including every entry
+// whose Process call has returned. After Close it returns the final summary.
+func (a *ActorAnalyzer) GetSummary() *models.LogSummary {
+	result := make(chan *models.LogSummary, 1)
+	if a.do(func() { result <- a.agg.snapshot() }) {
+		return <-result
+	}
+	// The owner has stopped, so the state no longer changes
+	<-a.stopped
+	return a.agg.snapshot()
+}
+
+// HashMatches returns how many entries Process skipped only because the hash
+// of their ID matched one restored with Import
+func (a *ActorAnalyzer) HashMatches() int {
+	result := make(chan int, 1)
+	if a.do(func() { result <- a.hashMatches }) {
+		return <-result
+	}
+	<-a.stopped
+	return a.hashMatches
+}
+
+// Export returns the analyzer's current state
+func (a *ActorAnalyzer) Export() *State {
+	result := make(chan *State, 1)
+	export := func() {
+		hashes := a.ids.appendHashes(nil)
+		sortHashes(hashes)
+		result <- &State{Version: StateVersion, Summary: a.agg.snapshot(), IDHashes: hashes}
+	}
+	if !a.do(export) {
+		<-a.stopped
+		export()
+	}
+	return <-result
+}
+
+// Import replaces the analyzer's state with one returned by Export
+func (a *ActorAnalyzer) Import(state *State) error {
+	if err := checkState(state); err != nil {
+		return err
+	}
+	agg := newAggregate(a.counters)
+	agg.merge(state.Summary)
+	ids := newIDTable()
+	for _, h := range state.IDHashes {
+		ids.addHash(h)
+	}
+
+	done := make(chan struct{})
+	if !a.do(func() {
+		a.agg, a.ids = agg, ids
+		close(done)
+	}) {
+		return fmt.Errorf("analyzer is closed")
+	}
+	<-done
+	return nil
+}
+
+// Close stops the goroutine owning the state. It is safe to call more than
+// once.
+func (a *ActorAnalyzer) Close() {
+	a.closeOnce.Do(func() {
+		close(a.quit)
+	})
+	<-a.stopped
+}
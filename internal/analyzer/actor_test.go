@@ -0,0 +1,188 @@
+package analyzer
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestActorAnalyzerMatchesLogAnalyzer(t *testing.T) {
+	counter, err := NewCounter("checkouts", `checkout for (?P<region>\w+)`, "region")
+	if err != nil {
+		t.Fatalf("Failed to create counter: %v", err)
+	}
+	actor := NewActorAnalyzer(WithCounters(counter))
+	defer actor.Close()
+	locked := NewLogAnalyzer(WithCounters(counter))
+
+	var entries []models.LogEntry
+	for i := 0; i < 200; i++ {
+		entries = append(entries, models.LogEntry{
+			// Every ID twice
+			ID:        fmt.Sprintf("%d", i%100),
+			Timestamp: time.Date(2023, 1, 1, i%24, 0, 0, 0, time.UTC),
+			Level:     []models.LogLevel{models.INFO, models.ERROR}[i%2],
+			Service:   []string{"api", "db", "auth"}[i%3],
+			Message:   fmt.Sprintf("checkout for eu%d", i%4),
+		})
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := worker; i < len(entries); i += 4 {
+				actor.Process(entries[i])
+			}
+		}(worker)
+	}
+	wg.Wait()
+	for _, entry := range entries {
+		locked.Process(entry)
+	}
+
+	// Which copy of an ID wins depends on timing, so compare what does not
+	got, want := actor.GetSummary(), locked.GetSummary()
+	if got.TotalEntries != 100 || got.TotalEntries != want.TotalEntries {
+		t.Errorf("Expected total entries to be %d, got %d", want.TotalEntries, got.TotalEntries)
+	}
+	if !reflect.DeepEqual(got.Counters, want.Counters) {
+		t.Errorf("Expected counters %v, got %v", want.Counters, got.Counters)
+	}
+	if !got.TimeRange.Start.Equal(want.TimeRange.Start) || !got.TimeRange.End.Equal(want.TimeRange.End) {
+		t.Errorf("Expected time range %v, got %v", want.TimeRange, got.TimeRange)
+	}
+}
+
+func TestActorAnalyzerConcurrentDuplicates(t *testing.T) {
+	actor := NewActorAnalyzer()
+	defer actor.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if actor.Process(models.LogEntry{ID: fmt.Sprintf("id-%d", i), Level: models.INFO}) {
+					mu.Lock()
+					accepted++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1000 {
+		t.Errorf("Expected 1000 entries to be accepted, got %d", accepted)
+	}
+	if summary := actor.GetSummary(); summary.TotalEntries != 1000 {
+		t.Errorf("Expected total entries to be 1000, got %d", summary.TotalEntries)
+	}
+}
+
+func TestActorAnalyzerBatchMergeClose(t *testing.T) {
+	actor := NewActorAnalyzer()
+	actor.ProcessBatch([]models.LogEntry{
+		{ID: "1", Level: models.INFO, Service: "api"},
+		{ID: "2", Level: models.ERROR, Service: "db"},
+		{ID: "1", Level: models.INFO, Service: "api"},
+	})
+	other := NewLogAnalyzer()
+	other.Process(models.LogEntry{ID: "3", Level: models.ERROR, Service: "db"})
+	actor.Merge(other.GetSummary())
+
+	actor.Close()
+	actor.Close()
+	if actor.Process(models.LogEntry{ID: "4", Level: models.INFO}) {
+		t.Error("Expected entries after Close to be rejected")
+	}
+	summary := actor.GetSummary()
+	if summary.TotalEntries != 3 || summary.ByLevel[models.ERROR] != 2 {
+		t.Errorf("Expected 3 entries with 2 errors, got %d with %d", summary.TotalEntries, summary.ByLevel[models.ERROR])
+	}
+}
+
+// BenchmarkAnalyzers compares the sharded-lock LogAnalyzer with the
+// single-owner ActorAnalyzer under parallel workers
+func BenchmarkAnalyzers(b *testing.B) {
+	entry := func(pb *testing.PB, i int) models.LogEntry {
+		return models.LogEntry{ID: fmt.Sprintf("%p-%d", pb, i), Level: models.INFO, Service: "api"}
+	}
+	b.Run("sharded-lock", func(b *testing.B) {
+		a := NewLogAnalyzer()
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				a.Process(entry(pb, i))
+			}
+		})
+	})
+	b.Run("actor", func(b *testing.B) {
+		a := NewActorAnalyzer()
+		defer a.Close()
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				a.Process(entry(pb, i))
+			}
+		})
+	})
+	b.Run("actor-batch-100", func(b *testing.B) {
+		a := NewActorAnalyzer()
+		defer a.Close()
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			batch := make([]models.LogEntry, 0, 100)
+			for i := 0; pb.Next(); i++ {
+				if batch = append(batch, entry(pb, i)); len(batch) == cap(batch) {
+					a.ProcessBatch(batch)
+					batch = batch[:0]
+				}
+			}
+			a.ProcessBatch(batch)
+		})
+	})
+}
+
+func TestActorAnalyzerExportImport(t *testing.T) {
+	locked := NewLogAnalyzer()
+	for i := 0; i < 5; i++ {
+		locked.Process(models.LogEntry{
+			ID:        fmt.Sprintf("entry-%d", i),
+			Timestamp: time.Date(2023, 1, 1, 10+i, 0, 0, 0, time.UTC),
+			Level:     models.ERROR,
+			Service:   "api",
+		})
+	}
+
+	// States are interchangeable between the two analyzers
+	actor := NewActorAnalyzer()
+	defer actor.Close()
+	if err := actor.Import(locked.Export()); err != nil {
+		t.Fatalf("Failed to import state: %v", err)
+	}
+	if !reflect.DeepEqual(actor.Export(), locked.Export()) {
+		t.Errorf("Expected the exported state to match, got %+v", actor.Export())
+	}
+	if actor.Process(models.LogEntry{ID: "entry-3", Service: "api"}) {
+		t.Error("Expected entry-3 to be a duplicate after import")
+	}
+	if actor.HashMatches() != 1 {
+		t.Errorf("Expected 1 hash match, got %d", actor.HashMatches())
+	}
+
+	state := locked.Export()
+	state.Version = StateVersion + 1
+	if err := actor.Import(state); err == nil {
+		t.Error("Expected an error for an unknown state version")
+	}
+}
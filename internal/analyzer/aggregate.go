@@ -0,0 +1,209 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// aggregate holds the counts behind a summary. It is not safe for concurrent
+// use: LogAnalyzer guards it with a mutex and ActorAnalyzer confines it to a
+// single goroutine.
+type aggregate struct {
+	summary *models.LogSummary
+}
+
+func newAggregate(counters []*Counter) *aggregate {
+	g := &aggregate{summary: models.NewLogSummary()}
+	// Counters are reported even before their first match
+	for _, c := range counters {
+		g.summary.AddCounter(c.Name, c.Label, "", 0)
+		if c.Label != "" {
+			delete(g.summary.Counters[c.Name].Counts, "")
+		}
+	}
+	return g
+}
+
+// matchCounters returns the counters matching message and their label
+// values
+func matchCounters(counters []*Counter, message string) ([]*Counter, []string) {
+	var matched []*Counter
+	var labels []string
+	for _, c := range counters {
+		if label, ok := c.match(message); ok {
+			matched = append(matched, c)
+			labels = append(labels, label)
+		}
+	}
+	return matched, labels
+}
+
+// add counts an entry and the counter matches returned by matchCounters
+func (g *aggregate) add(entry models.LogEntry, matched []*Counter, labels []string) {
+	for i, c := range matched {
+		g.countMatch(c, labels[i])
+	}
+
+	// Update total count
+	g.summary.TotalEntries++
+
+	// Update counts by level
+	g.summary.ByLevel[entry.Level]++
+
+	// Update counts by service
+	g.summary.ByService[entry.Service]++
+
+	if entry.LevelInferred {
+		g.summary.InferredLevels++
+	}
+
+	if !entry.Timestamp.IsZero() {
+		g.summary.AddHour(entry.Timestamp, entry.Level, 1)
+	}
+
+	// Update time range
+	if g.summary.TimeRange.Start.IsZero() || entry.Timestamp.Before(g.summary.TimeRange.Start) {
+		g.summary.TimeRange.Start = entry.Timestamp
+	}
+	if g.summary.TimeRange.End.IsZero() || entry.Timestamp.After(g.summary.TimeRange.End) {
+		g.summary.TimeRange.End = entry.Timestamp
+	}
+}
+
+// countMatch adds one match of a counter to the summary
+func (g *aggregate) countMatch(c *Counter, value string) {
+	counts := g.summary.Counters[c.Name].Counts
+	if _, seen := counts[value]; !seen && len(counts) >= maxCounterLabels {
+		value = OtherLabel
+	}
+	g.summary.AddCounter(c.Name, c.Label, value, 1)
+}
+
+// merge adds the counts and time range of another summary
+func (g *aggregate) merge(summary *models.LogSummary) {
+	g.summary.TotalEntries += summary.TotalEntries
+	g.summary.InferredLevels += summary.InferredLevels
+	for source, stats := range summary.OutOfOrder {
+		if g.summary.OutOfOrder == nil {
+			g.summary.OutOfOrder = make(map[string]models.OrderStats)
+		}
+		g.summary.OutOfOrder[source] = g.summary.OutOfOrder[source].Add(stats)
+	}
+	for file, reason := range summary.FailedFiles {
+		if g.summary.FailedFiles == nil {
+			g.summary.FailedFiles = make(map[string]string)
+		}
+		g.summary.FailedFiles[file] = reason
+	}
+	for file, reason := range summary.CorruptArchives {
+		if g.summary.CorruptArchives == nil {
+			g.summary.CorruptArchives = make(map[string]string)
+		}
+		g.summary.CorruptArchives[file] = reason
+	}
+	for name, counter := range summary.Counters {
+		for value, count := range counter.Counts {
+			g.summary.AddCounter(name, counter.Label, value, count)
+		}
+	}
+	for hour, levels := range summary.ByHour {
+		for level, count := range levels {
+			g.summary.AddHour(hour, level, count)
+		}
+	}
+	for level, count := range summary.ByLevel {
+		g.summary.ByLevel[level] += count
+	}
+	for service, count := range summary.ByService {
+		g.summary.ByService[service] += count
+	}
+
+	if summary.Accounting != nil {
+		var total models.Accounting
+		if g.summary.Accounting != nil {
+			total = *g.summary.Accounting
+		}
+		total = total.Add(*summary.Accounting)
+		g.summary.Accounting = &total
+	}
+
+	if start := summary.TimeRange.Start; !start.IsZero() && (g.summary.TimeRange.Start.IsZero() || start.Before(g.summary.TimeRange.Start)) {
+		g.summary.TimeRange.Start = start
+	}
+	if end := summary.TimeRange.End; end.After(g.summary.TimeRange.End) {
+		g.summary.TimeRange.End = end
+	}
+}
+
+// snapshot returns a deep copy of the summary
+func (g *aggregate) snapshot() *models.LogSummary {
+	copy := &models.LogSummary{
+		TotalEntries:   g.summary.TotalEntries,
+		InferredLevels: g.summary.InferredLevels,
+		ByLevel:        make(map[models.LogLevel]int),
+		ByService:      make(map[string]int),
+	}
+
+	// Copy maps
+	for k, v := range g.summary.ByLevel {
+		copy.ByLevel[k] = v
+	}
+	for k, v := range g.summary.ByService {
+		copy.ByService[k] = v
+	}
+
+	if len(g.summary.OutOfOrder) > 0 {
+		copy.OutOfOrder = make(map[string]models.OrderStats, len(g.summary.OutOfOrder))
+		for k, v := range g.summary.OutOfOrder {
+			copy.OutOfOrder[k] = v
+		}
+	}
+
+	if len(g.summary.FailedFiles) > 0 {
+		copy.FailedFiles = make(map[string]string, len(g.summary.FailedFiles))
+		for k, v := range g.summary.FailedFiles {
+			copy.FailedFiles[k] = v
+		}
+	}
+
+	if len(g.summary.CorruptArchives) > 0 {
+		copy.CorruptArchives = make(map[string]string, len(g.summary.CorruptArchives))
+		for k, v := range g.summary.CorruptArchives {
+			copy.CorruptArchives[k] = v
+		}
+	}
+
+	if len(g.summary.Counters) > 0 {
+		copy.Counters = make(map[string]models.CounterCounts, len(g.summary.Counters))
+		for name, counter := range g.summary.Counters {
+			counts := make(map[string]int, len(counter.Counts))
+			for value, count := range counter.Counts {
+				counts[value] = count
+			}
+			copy.Counters[name] = models.CounterCounts{Label: counter.Label, Counts: counts}
+		}
+	}
+
+	if len(g.summary.ByHour) > 0 {
+		copy.ByHour = make(map[time.Time]map[models.LogLevel]int, len(g.summary.ByHour))
+		for hour, levels := range g.summary.ByHour {
+			counts := make(map[models.LogLevel]int, len(levels))
+			for level, count := range levels {
+				counts[level] = count
+			}
+			copy.ByHour[hour] = counts
+		}
+	}
+
+	if g.summary.Accounting != nil {
+		accounting := g.summary.Accounting.Add(models.Accounting{})
+		copy.Accounting = &accounting
+	}
+
+	// Copy time range
+	copy.TimeRange.Start = g.summary.TimeRange.Start
+	copy.TimeRange.End = g.summary.TimeRange.End
+
+	return copy
+}
@@ -2,55 +2,72 @@ package analyzer
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/interview/junior-go-challenge/internal/models"
 )
 
+// Analyzer aggregates log entries into a summary. The processor calls it from
+// several workers at once, so implementations must be safe for concurrent use.
+type Analyzer interface {
+	// Process counts an entry and reports whether it was new
+	Process(entry models.LogEntry) bool
+	// ProcessBatch counts several entries
+	ProcessBatch(entries []models.LogEntry)
+	// GetSummary returns a snapshot of the current summary
+	GetSummary() *models.LogSummary
+	// Merge adds the counts of a summary produced elsewhere
+	Merge(summary *models.LogSummary)
+}
+
+var _ Analyzer = (*LogAnalyzer)(nil)
+
 // LogAnalyzer aggregates statistics from log entries
 type LogAnalyzer struct {
 	mu           sync.Mutex
-	summary      *models.LogSummary
-	processedIDs map[string]bool
+	agg          *aggregate
+	processedIDs *idSet
+	counters     []*Counter
+	hashMatches  atomic.Int64
 }
 
 // NewLogAnalyzer creates a new log analyzer
-func NewLogAnalyzer() *LogAnalyzer {
-	return &LogAnalyzer{
-		summary:      models.NewLogSummary(),
-		processedIDs: make(map[string]bool),
+func NewLogAnalyzer(opts ...Option) *LogAnalyzer {
+	a := &LogAnalyzer{processedIDs: newIDSet()}
+	for _, opt := range opts {
+		opt(a)
 	}
+	a.agg = newAggregate(a.counters)
+	return a
 }
 
-// Process analyzes a log entry and updates the summary
-func (a *LogAnalyzer) Process(entry models.LogEntry) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	if a.processedIDs[entry.ID] {
+// Process analyzes a log entry and updates the summary. It reports whether
+// the entry was new, i.e. not a duplicate of an already processed ID.
+func (a *LogAnalyzer) Process(entry models.LogEntry) bool {
+	switch a.processedIDs.add(entry.ID) {
+	case idDuplicate:
 		// Skip already processed entries
-		return
+		return false
+	case idHashMatch:
+		a.hashMatches.Add(1)
+		return false
 	}
 
-	// Update total count
-	a.summary.TotalEntries++
-
-	// Update counts by level
-	a.summary.ByLevel[entry.Level]++
+	// Match counters before locking, as regular expressions are slow
+	matched, labels := matchCounters(a.counters, entry.Message)
 
-	// Update counts by service
-	a.summary.ByService[entry.Service]++
-
-	// Update time range
-	if a.summary.TimeRange.Start.IsZero() || entry.Timestamp.Before(a.summary.TimeRange.Start) {
-		a.summary.TimeRange.Start = entry.Timestamp
-	}
-	if a.summary.TimeRange.End.IsZero() || entry.Timestamp.After(a.summary.TimeRange.End) {
-		a.summary.TimeRange.End = entry.Timestamp
-	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.agg.add(entry, matched, labels)
+	return true
+}
 
-	// Mark as processed
-	a.processedIDs[entry.ID] = true
+// HashMatches returns how many entries Process skipped only because the hash
+// of their ID matched one restored with Import or MergeState. Such an entry
+// is rarely, but not certainly, a duplicate.
+func (a *LogAnalyzer) HashMatches() int {
+	return int(a.hashMatches.Load())
 }
 
 // ProcessBatch processes multiple log entries concurrently
@@ -62,38 +79,27 @@ func (a *LogAnalyzer) ProcessBatch(entries []models.LogEntry) {
 		go func(e models.LogEntry) {
 			defer wg.Done()
 			a.Process(e)
-			
+
 			// Simulate some processing time
 			time.Sleep(time.Millisecond * 10)
 		}(entry)
 	}
-	
+
 	wg.Wait()
 }
 
+// Merge adds the counts and time range of a summary produced elsewhere, such
+// as by another process. Its entries are not known individually, so they are
+// not considered when deduplicating later entries.
+func (a *LogAnalyzer) Merge(summary *models.LogSummary) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.agg.merge(summary)
+}
+
 // GetSummary returns a copy of the current log summary
 func (a *LogAnalyzer) GetSummary() *models.LogSummary {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-
-	// Create a deep copy of the summary
-	copy := &models.LogSummary{
-		TotalEntries: a.summary.TotalEntries,
-		ByLevel:      make(map[models.LogLevel]int),
-		ByService:    make(map[string]int),
-	}
-
-	// Copy maps
-	for k, v := range a.summary.ByLevel {
-		copy.ByLevel[k] = v
-	}
-	for k, v := range a.summary.ByService {
-		copy.ByService[k] = v
-	}
-
-	// Copy time range
-	copy.TimeRange.Start = a.summary.TimeRange.Start
-	copy.TimeRange.End = a.summary.TimeRange.End
-
-	return copy
-}
\ No newline at end of file
+	return a.agg.snapshot()
+}
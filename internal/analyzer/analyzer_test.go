@@ -1,12 +1,15 @@
 package analyzer
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
 )
 
 func TestLogAnalyzerProcess(t *testing.T) {
@@ -142,4 +145,256 @@ func TestLogAnalyzerConcurrentProcessing(t *testing.T) {
 	if summary.TotalEntries != 100 {
 		t.Errorf("Expected total entries to be 100, got %d", summary.TotalEntries)
 	}
-}
\ No newline at end of file
+}
+
+func TestLogAnalyzerConcurrentDuplicates(t *testing.T) {
+	analyzer := NewLogAnalyzer()
+
+	// Every ID is processed by several workers at once, but counted once
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if analyzer.Process(models.LogEntry{ID: fmt.Sprintf("id-%d", i), Level: models.INFO}) {
+					mu.Lock()
+					accepted++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1000 {
+		t.Errorf("Expected 1000 entries to be accepted, got %d", accepted)
+	}
+	if summary := analyzer.GetSummary(); summary.TotalEntries != 1000 {
+		t.Errorf("Expected total entries to be 1000, got %d", summary.TotalEntries)
+	}
+}
+
+func BenchmarkLogAnalyzerProcess(b *testing.B) {
+	analyzer := NewLogAnalyzer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			i++
+			analyzer.Process(models.LogEntry{ID: fmt.Sprintf("%p-%d", pb, i), Level: models.INFO, Service: "api"})
+		}
+	})
+}
+
+func TestLogAnalyzerMerge(t *testing.T) {
+	analyzer := NewLogAnalyzer()
+	analyzer.Process(models.LogEntry{
+		ID:        "1",
+		Timestamp: time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC),
+		Level:     models.INFO,
+		Service:   "api",
+	})
+
+	// Merge a summary produced by another analyzer
+	other := models.NewLogSummary()
+	other.TotalEntries = 3
+	other.InferredLevels = 2
+	other.ByLevel[models.INFO] = 1
+	other.ByLevel[models.ERROR] = 2
+	other.ByService["api"] = 3
+	other.TimeRange.Start = time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	other.TimeRange.End = time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	analyzer.Merge(other)
+
+	summary := analyzer.GetSummary()
+	if summary.TotalEntries != 4 {
+		t.Errorf("Expected total entries to be 4, got %d", summary.TotalEntries)
+	}
+	if summary.ByLevel[models.INFO] != 2 || summary.ByLevel[models.ERROR] != 2 {
+		t.Errorf("Unexpected level counts after merge: %v", summary.ByLevel)
+	}
+	if summary.InferredLevels != 2 {
+		t.Errorf("Expected inferred levels to be 2, got %d", summary.InferredLevels)
+	}
+	if summary.ByService["api"] != 4 {
+		t.Errorf("Expected api service count to be 4, got %d", summary.ByService["api"])
+	}
+	if !summary.TimeRange.Start.Equal(other.TimeRange.Start) {
+		t.Errorf("Expected start to be %v, got %v", other.TimeRange.Start, summary.TimeRange.Start)
+	}
+	if !summary.TimeRange.End.Equal(time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected end to be unchanged, got %v", summary.TimeRange.End)
+	}
+}
+
+func TestLogAnalyzerExportImport(t *testing.T) {
+	analyzer := NewLogAnalyzer()
+	for i := 0; i < 5; i++ {
+		analyzer.Process(models.LogEntry{
+			ID:        fmt.Sprintf("entry-%d", i),
+			Timestamp: time.Date(2023, 1, 1, 10+i, 0, 0, 0, time.UTC),
+			Level:     models.ERROR,
+			Service:   "api",
+		})
+	}
+
+	// The state survives a round trip through JSON
+	data, err := json.Marshal(analyzer.Export())
+	if err != nil {
+		t.Fatalf("Failed to encode state: %v", err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Failed to decode state: %v", err)
+	}
+
+	restored := NewLogAnalyzer()
+	restored.Process(models.LogEntry{ID: "replaced", Service: "db"})
+	if err := restored.Import(&state); err != nil {
+		t.Fatalf("Failed to import state: %v", err)
+	}
+	if !reflect.DeepEqual(restored.GetSummary(), analyzer.GetSummary()) {
+		t.Errorf("Expected the restored summary to match, got %+v", restored.GetSummary())
+	}
+
+	// Entries processed before the export are still duplicates
+	if restored.Process(models.LogEntry{ID: "entry-3", Service: "api"}) {
+		t.Error("Expected entry-3 to be a duplicate after import")
+	}
+	if !restored.Process(models.LogEntry{ID: "replaced", Service: "db"}) {
+		t.Error("Expected entries from before the import to be forgotten")
+	}
+
+	state.Version = StateVersion + 1
+	if err := restored.Import(&state); err == nil {
+		t.Error("Expected an error for an unknown state version")
+	}
+}
+
+func TestPatternSection(t *testing.T) {
+	section := NewPatternSection(2)
+	messages := []string{
+		"Connection to 10.0.0.1 timed out after 30s",
+		"Connection to 10.0.0.2 timed out after 5s",
+		"Connection to 10.0.0.3 timed out after 5s",
+		"User 42 logged in",
+		"User 7 logged in",
+		"Cache cleared",
+	}
+	for _, message := range messages {
+		section.Observe(models.LogEntry{Message: message})
+	}
+
+	top := section.Top()
+	if len(top) != 2 {
+		t.Fatalf("Expected the top 2 patterns, got %d", len(top))
+	}
+	if top[0].Pattern != "Connection to <*> timed out after <*>" || top[0].Count != 3 {
+		t.Errorf("Unexpected top pattern: %+v", top[0])
+	}
+	if top[1].Pattern != "User <*> logged in" || top[1].Count != 2 {
+		t.Errorf("Unexpected second pattern: %+v", top[1])
+	}
+
+	if _, err := NewSection("nonexistent"); err == nil {
+		t.Error("Expected an error for an unknown section")
+	}
+}
+
+func TestPatternNormalization(t *testing.T) {
+	// The same message from a platform writing decomposed accents and one
+	// writing upper case
+	messages := []string{"Caf\u00e9 order 1 failed", "Cafe\u0301 order 2 failed", "CAF\u00c9 ORDER 3 FAILED"}
+	patterns := func(form textnorm.Form) map[string]bool {
+		seen := make(map[string]bool)
+		for _, message := range messages {
+			seen[NormalizedPattern(message, form)] = true
+		}
+		return seen
+	}
+	if n := len(patterns(0)); n != 3 {
+		t.Errorf("Expected 3 patterns without normalization, got %d", n)
+	}
+	if n := len(patterns(textnorm.NFC)); n != 2 {
+		t.Errorf("Expected 2 patterns with nfc, got %d", n)
+	}
+
+	// Sections normalize independently of each other
+	section := NewPatternSection(5)
+	section.SetNormalization(textnorm.NFC | textnorm.Fold)
+	plain := NewPatternSection(5)
+	for _, message := range messages {
+		section.Observe(models.LogEntry{Message: message})
+		plain.Observe(models.LogEntry{Message: message})
+	}
+	if n := len(plain.Top()); n != 3 {
+		t.Errorf("Expected 3 patterns in a section without normalization, got %d", n)
+	}
+	top := section.Top()
+	if len(top) != 1 || top[0].Pattern != "caf\u00e9 order <*> failed" || top[0].Count != 3 {
+		t.Errorf("Expected one pattern with nfc,fold, got %+v", top)
+	}
+	if top[0].Example != messages[0] {
+		t.Errorf("Expected the example as received, got %q", top[0].Example)
+	}
+}
+
+func TestStormSection(t *testing.T) {
+	section := NewStormSection(10)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// 30 timeouts in the first minute and 5 in the next
+	for i := 0; i < 35; i++ {
+		offset := time.Duration(i) * time.Second
+		if i >= 30 {
+			offset = time.Minute + time.Duration(i)*time.Second
+		}
+		section.Observe(models.LogEntry{
+			Timestamp: start.Add(offset),
+			Service:   "db",
+			Message:   fmt.Sprintf("query %d timed out", i),
+		})
+	}
+	// A rare message never storms
+	section.Observe(models.LogEntry{Timestamp: start, Service: "db", Message: "connected"})
+
+	storms := section.Storms()
+	if len(storms) != 1 {
+		t.Fatalf("Expected 1 storm, got %d", len(storms))
+	}
+	storm := storms[0]
+	if storm.Pattern != "query <*> timed out" || storm.Count != 30 || storm.PeakPerMinute != 30 {
+		t.Errorf("Unexpected storm: %+v", storm)
+	}
+	if !storm.First.Equal(start) || !storm.Last.Equal(start.Add(29*time.Second)) {
+		t.Errorf("Unexpected storm range %v to %v", storm.First, storm.Last)
+	}
+}
+
+func TestStormSectionPrunesQuietMinutes(t *testing.T) {
+	section := NewStormSection(10)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	observe := func(at time.Time) {
+		section.Observe(models.LogEntry{Timestamp: at, Service: "db", Message: "query timed out"})
+	}
+
+	// A storm in the first minute, then a day of single repeats
+	for i := 0; i < 20; i++ {
+		observe(start)
+	}
+	for m := 1; m < 24*60; m++ {
+		observe(start.Add(time.Duration(m) * time.Minute))
+	}
+
+	for _, stream := range section.streams {
+		if len(stream.minutes) > stormLateness+2 {
+			t.Errorf("Expected quiet minutes to be pruned, got %d", len(stream.minutes))
+		}
+	}
+	if storms := section.Storms(); len(storms) != 1 || storms[0].Count != 20 {
+		t.Errorf("Expected the storm to be kept, got %+v", storms)
+	}
+}
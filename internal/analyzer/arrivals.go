@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// OrderedSection is a section whose results depend on the order of the
+// entries. The processor runs it in a second pass, after all entries have
+// been read and sorted, observing one entry at a time in timestamp order.
+type OrderedSection interface {
+	Section
+	// ObservesInOrder marks the section as order-dependent
+	ObservesInOrder()
+}
+
+// ArrivalStats are the exact inter-arrival times of one service's entries
+type ArrivalStats struct {
+	Service string
+	Entries int
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// FirstOccurrence is the first entry of a message pattern
+type FirstOccurrence struct {
+	Pattern string
+	Service string
+	Time    time.Time
+}
+
+// ArrivalSection reports, per service, percentiles of the time between
+// consecutive entries, and the message patterns that first appeared latest
+// in the data, such as new errors during an incident. Both need the entries
+// in timestamp order, so it is an OrderedSection.
+type ArrivalSection struct {
+	top  int
+	form textnorm.Form
+
+	services map[string]*serviceArrivals
+	first    map[string]FirstOccurrence
+}
+
+// serviceArrivals holds the gaps between one service's entries
+type serviceArrivals struct {
+	last time.Time
+	gaps []time.Duration
+}
+
+// NewArrivalSection creates a section listing the top most recently first
+// seen patterns
+func NewArrivalSection(top int) *ArrivalSection {
+	return &ArrivalSection{
+		top:      top,
+		services: make(map[string]*serviceArrivals),
+		first:    make(map[string]FirstOccurrence),
+	}
+}
+
+func (s *ArrivalSection) Name() string {
+	return "Inter-Arrival Times and New Patterns"
+}
+
+func (s *ArrivalSection) ObservesInOrder() {}
+
+// SetNormalization normalizes messages with form before their pattern is
+// taken
+func (s *ArrivalSection) SetNormalization(form textnorm.Form) {
+	s.form = form
+}
+
+// Observe must be called with entries in timestamp order, from a single
+// goroutine
+func (s *ArrivalSection) Observe(entry models.LogEntry) {
+	if entry.Timestamp.IsZero() {
+		return
+	}
+
+	arrivals, ok := s.services[entry.Service]
+	if !ok {
+		arrivals = &serviceArrivals{}
+		s.services[entry.Service] = arrivals
+	} else {
+		arrivals.gaps = append(arrivals.gaps, entry.Timestamp.Sub(arrivals.last))
+	}
+	arrivals.last = entry.Timestamp
+
+	pattern := NormalizedPattern(entry.Message, s.form)
+	if _, seen := s.first[pattern]; !seen && len(s.first) < maxPatterns {
+		s.first[pattern] = FirstOccurrence{Pattern: pattern, Service: entry.Service, Time: entry.Timestamp}
+	}
+}
+
+// Arrivals returns the inter-arrival statistics of services with at least
+// two entries, by service name
+func (s *ArrivalSection) Arrivals() []ArrivalStats {
+	var stats []ArrivalStats
+	for service, arrivals := range s.services {
+		if len(arrivals.gaps) == 0 {
+			continue
+		}
+		gaps := append([]time.Duration(nil), arrivals.gaps...)
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		stats = append(stats, ArrivalStats{
+			Service: service,
+			Entries: len(gaps) + 1,
+			P50:     percentile(gaps, 50),
+			P90:     percentile(gaps, 90),
+			P99:     percentile(gaps, 99),
+			Max:     gaps[len(gaps)-1],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Service < stats[j].Service })
+	return stats
+}
+
+// NewestPatterns returns the patterns whose first occurrence was latest,
+// latest first
+func (s *ArrivalSection) NewestPatterns() []FirstOccurrence {
+	firsts := make([]FirstOccurrence, 0, len(s.first))
+	for _, f := range s.first {
+		firsts = append(firsts, f)
+	}
+	sort.Slice(firsts, func(i, j int) bool {
+		if !firsts[i].Time.Equal(firsts[j].Time) {
+			return firsts[i].Time.After(firsts[j].Time)
+		}
+		return firsts[i].Pattern < firsts[j].Pattern
+	})
+	if len(firsts) > s.top {
+		firsts = firsts[:s.top]
+	}
+	return firsts
+}
+
+func (s *ArrivalSection) WriteText(w io.Writer) error {
+	for _, a := range s.Arrivals() {
+		_, err := fmt.Fprintf(w, "  %s: p50 %v, p90 %v, p99 %v, max %v between %d entries\n",
+			a.Service, a.P50, a.P90, a.P99, a.Max, a.Entries)
+		if err != nil {
+			return err
+		}
+	}
+	if newest := s.NewestPatterns(); len(newest) > 0 {
+		fmt.Fprintln(w, "  Newest patterns:")
+		for _, f := range newest {
+			_, err := fmt.Fprintf(w, "    %s %s: %q\n", f.Time.Format("2006-01-02 15:04:05"), f.Service, f.Pattern)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// percentile returns the p-th percentile of sorted values by the nearest
+// rank method
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,37 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestArrivalSection(t *testing.T) {
+	section := NewArrivalSection(10)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// 100 entries a second apart, then one after a minute's silence
+	for i := 0; i < 100; i++ {
+		section.Observe(models.LogEntry{Service: "api", Message: "ok", Timestamp: start.Add(time.Duration(i) * time.Second)})
+	}
+	section.Observe(models.LogEntry{Service: "api", Message: "restarted", Timestamp: start.Add(159 * time.Second)})
+	section.Observe(models.LogEntry{Service: "cron", Message: "ok", Timestamp: start})
+
+	stats := section.Arrivals()
+	if len(stats) != 1 {
+		t.Fatalf("Expected stats for api only, got %+v", stats)
+	}
+	if stats[0].P50 != time.Second || stats[0].P99 != time.Second || stats[0].Max != time.Minute {
+		t.Errorf("Expected p50 and p99 of 1s and max of 1m, got %+v", stats[0])
+	}
+
+	newest := section.NewestPatterns()
+	if len(newest) != 2 || newest[0].Pattern != "restarted" || !newest[1].Time.Equal(start) {
+		t.Errorf("Expected restarted to be the newest pattern, got %+v", newest)
+	}
+
+	if _, ok := ExcludeServices(section, "cron").(OrderedSection); !ok {
+		t.Error("Expected excluding services to keep the section ordered")
+	}
+}
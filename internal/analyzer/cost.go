@@ -0,0 +1,186 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Default ingestion pricing, used unless the config sets one
+const (
+	DefaultPricePerGB = 0.50
+	DefaultCurrency   = "USD"
+)
+
+// timestampSize is the stored size of a timestamp in RFC 3339
+const timestampSize = len("2006-01-02T15:04:05.000Z")
+
+// bytesPerGB converts bytes to the gigabytes log vendors bill by
+const bytesPerGB = 1e9
+
+// EntrySize estimates the bytes an entry takes up when ingested: its
+// timestamp, level, service, source, message and fields
+func EntrySize(entry models.LogEntry) int {
+	size := timestampSize + len(entry.ID) + len(entry.Level) + len(entry.Service) + len(entry.Source) + len(entry.Message)
+	for k, v := range entry.Fields {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+// ServiceCost is the estimated ingestion cost of one service's entries
+type ServiceCost struct {
+	Service string
+	Entries int
+	Bytes   int64
+	Cost    float64
+	// ByLevel holds the bytes of each level
+	ByLevel map[models.LogLevel]int64
+}
+
+// CostSection estimates the ingestion cost of each service and level from
+// the volume of their entries and a price per GB
+type CostSection struct {
+	perGB    float64
+	currency string
+
+	mu          sync.Mutex
+	services    map[string]*ServiceCost
+	first, last time.Time
+}
+
+// NewCostSection creates a cost estimate at perGB in currency, e.g. "USD",
+// per gigabyte ingested
+func NewCostSection(perGB float64, currency string) *CostSection {
+	return &CostSection{perGB: perGB, currency: currency, services: make(map[string]*ServiceCost)}
+}
+
+func (s *CostSection) Name() string {
+	return fmt.Sprintf("Ingestion Cost (%.2f %s per GB)", s.perGB, s.currency)
+}
+
+func (s *CostSection) Observe(entry models.LogEntry) {
+	size := int64(EntrySize(entry))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.services[entry.Service]
+	if !ok {
+		c = &ServiceCost{Service: entry.Service, ByLevel: make(map[models.LogLevel]int64)}
+		s.services[entry.Service] = c
+	}
+	c.Entries++
+	c.Bytes += size
+	c.ByLevel[entry.Level] += size
+	if ts := entry.Timestamp; !ts.IsZero() {
+		if s.first.IsZero() || ts.Before(s.first) {
+			s.first = ts
+		}
+		if ts.After(s.last) {
+			s.last = ts
+		}
+	}
+}
+
+// Costs returns the estimated cost of every service, most expensive first
+func (s *CostSection) Costs() []ServiceCost {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	costs := make([]ServiceCost, 0, len(s.services))
+	for _, service := range s.services {
+		c := *service
+		c.ByLevel = make(map[models.LogLevel]int64, len(service.ByLevel))
+		for level, bytes := range service.ByLevel {
+			c.ByLevel[level] = bytes
+		}
+		c.Cost = float64(c.Bytes) / bytesPerGB * s.perGB
+		costs = append(costs, c)
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if costs[i].Bytes != costs[j].Bytes {
+			return costs[i].Bytes > costs[j].Bytes
+		}
+		return costs[i].Service < costs[j].Service
+	})
+	return costs
+}
+
+// MonthlyFactor returns what the observed cost is multiplied by to project
+// it to 30 days, from the span of the entries' timestamps. It is 0 when the
+// span is under an hour, too short to project from.
+func (s *CostSection) MonthlyFactor() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	span := s.last.Sub(s.first)
+	if span < time.Hour {
+		return 0
+	}
+	return float64(30*24*time.Hour) / float64(span)
+}
+
+func (s *CostSection) WriteText(w io.Writer) error {
+	costs := s.Costs()
+	var total float64
+	var totalBytes int64
+	for _, c := range costs {
+		total += c.Cost
+		totalBytes += c.Bytes
+	}
+	factor := s.MonthlyFactor()
+
+	line := fmt.Sprintf("  Total: %.3f GB, %.2f %s", float64(totalBytes)/bytesPerGB, total, s.currency)
+	if factor > 0 {
+		line += fmt.Sprintf(" (%.2f %s per 30 days)", total*factor, s.currency)
+	}
+	if _, err := fmt.Fprintln(w, line); err != nil {
+		return err
+	}
+	for _, c := range costs {
+		share := 0.0
+		if totalBytes > 0 {
+			share = float64(c.Bytes) / float64(totalBytes) * 100
+		}
+		line := fmt.Sprintf("  %s: %.2f %s (%.1f%%), %d entries, %.3f GB",
+			c.Service, c.Cost, s.currency, share, c.Entries, float64(c.Bytes)/bytesPerGB)
+		if factor > 0 {
+			line += fmt.Sprintf(", %.2f %s per 30 days", c.Cost*factor, s.currency)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "    %s\n", levelShares(c.ByLevel, c.Bytes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// levelShares lists each level's share of bytes, largest first
+func levelShares(byLevel map[models.LogLevel]int64, total int64) string {
+	levels := make([]models.LogLevel, 0, len(byLevel))
+	for level := range byLevel {
+		levels = append(levels, level)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if byLevel[levels[i]] != byLevel[levels[j]] {
+			return byLevel[levels[i]] > byLevel[levels[j]]
+		}
+		return levels[i] < levels[j]
+	})
+	parts := make([]string, 0, len(levels))
+	for _, level := range levels {
+		name := string(level)
+		if name == "" {
+			name = "(none)"
+		}
+		parts = append(parts, fmt.Sprintf("%s %.1f%%", name, float64(byLevel[level])/float64(total)*100))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,43 @@
+package analyzer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestCostSection(t *testing.T) {
+	section := NewCostSection(2, "EUR")
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	debug := models.LogEntry{Timestamp: start, Level: models.DEBUG, Service: "api", Message: strings.Repeat("x", 976)}
+	for i := 0; i < 3; i++ {
+		section.Observe(debug)
+	}
+	section.Observe(models.LogEntry{Timestamp: start.Add(24 * time.Hour), Level: models.ERROR, Service: "worker", Message: "failed"})
+
+	costs := section.Costs()
+	if len(costs) != 2 || costs[0].Service != "api" {
+		t.Fatalf("Expected api to cost the most, got %+v", costs)
+	}
+	size := int64(EntrySize(debug))
+	if costs[0].Bytes != 3*size || costs[0].ByLevel[models.DEBUG] != 3*size || costs[0].Entries != 3 {
+		t.Errorf("Expected api to have 3 DEBUG entries of %d bytes, got %+v", size, costs[0])
+	}
+	if want := float64(3*size) / 1e9 * 2; costs[0].Cost != want {
+		t.Errorf("Expected api to cost %v, got %v", want, costs[0].Cost)
+	}
+	if factor := section.MonthlyFactor(); factor != 30 {
+		t.Errorf("Expected a day of entries to project by 30, got %v", factor)
+	}
+
+	var buf bytes.Buffer
+	if err := section.WriteText(&buf); err != nil {
+		t.Fatalf("Failed to write section: %v", err)
+	}
+	if !strings.Contains(buf.String(), "api: 0.00 EUR") || !strings.Contains(buf.String(), "DEBUG 100.0%") {
+		t.Errorf("Expected api's cost and level shares, got %q", buf.String())
+	}
+}
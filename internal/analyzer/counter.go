@@ -0,0 +1,82 @@
+package analyzer
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// maxCounterLabels bounds the distinct label values of one counter. Further
+// values are counted under OtherLabel.
+const maxCounterLabels = 1000
+
+// OtherLabel is the label value of matches beyond maxCounterLabels distinct
+// values
+const OtherLabel = "other"
+
+// validName matches metric and label names accepted by Prometheus
+var validName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedNames are used by the built-in entry metrics
+var reservedNames = map[string]bool{"entries": true, "entries_by_level": true, "entries_by_service": true}
+
+// Counter counts entries whose message matches a regular expression, such
+// as KPIs embedded in log text. A labeled counter counts matches separately
+// per value of a capture group.
+type Counter struct {
+	Name  string
+	Label string
+	re    *regexp.Regexp
+	group int
+}
+
+// NewCounter creates a counter. If label is set, matches are counted by the
+// value of the capture group named label, or else of the first group.
+func NewCounter(name, pattern, label string) (*Counter, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid counter name %q: use letters, digits and underscores", name)
+	}
+	if reservedNames[name] {
+		return nil, fmt.Errorf("counter name %q is reserved", name)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern for counter %s: %w", name, err)
+	}
+
+	c := &Counter{Name: name, Label: label, re: re}
+	if label != "" {
+		if !validName.MatchString(label) {
+			return nil, fmt.Errorf("invalid label %q for counter %s: use letters, digits and underscores", label, name)
+		}
+		if c.group = re.SubexpIndex(label); c.group < 0 {
+			c.group = 1
+		}
+		if re.NumSubexp() < c.group {
+			return nil, fmt.Errorf("counter %s is labeled but its pattern has no capture group", name)
+		}
+	}
+	return c, nil
+}
+
+// match reports whether message matches and returns the label value
+func (c *Counter) match(message string) (string, bool) {
+	if c.Label == "" {
+		return "", c.re.MatchString(message)
+	}
+	m := c.re.FindStringSubmatch(message)
+	if m == nil {
+		return "", false
+	}
+	return m[c.group], true
+}
+
+// Option configures a LogAnalyzer
+type Option func(*LogAnalyzer)
+
+// WithCounters adds regular expression counters, reported in the summary's
+// Counters
+func WithCounters(counters ...*Counter) Option {
+	return func(a *LogAnalyzer) {
+		a.counters = append(a.counters, counters...)
+	}
+}
@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestCounters(t *testing.T) {
+	misses, err := NewCounter("cache_misses", `cache miss for key (\w+)`, "key")
+	if err != nil {
+		t.Fatalf("Failed to create counter: %v", err)
+	}
+	slow, err := NewCounter("slow_queries", `query took (?P<ms>\d+)ms`, "")
+	if err != nil {
+		t.Fatalf("Failed to create counter: %v", err)
+	}
+	analyzer := NewLogAnalyzer(WithCounters(misses, slow))
+
+	// Counters are reported before any match
+	if summary := analyzer.GetSummary(); summary.Counters["slow_queries"].Counts[""] != 0 || len(summary.Counters) != 2 {
+		t.Errorf("Expected both counters without matches, got %v", summary.Counters)
+	}
+
+	messages := []string{"cache miss for key users", "cache miss for key users", "cache miss for key orders", "query took 900ms", "ok"}
+	for i, message := range messages {
+		analyzer.Process(models.LogEntry{ID: fmt.Sprint(i), Message: message})
+	}
+
+	summary := analyzer.GetSummary()
+	counter := summary.Counters["cache_misses"]
+	if counter.Label != "key" || counter.Counts["users"] != 2 || counter.Counts["orders"] != 1 {
+		t.Errorf("Expected cache misses by key, got %+v", counter)
+	}
+	if n := summary.Counters["slow_queries"].Counts[""]; n != 1 {
+		t.Errorf("Expected 1 slow query, got %d", n)
+	}
+
+	// Too many distinct values are grouped
+	for i := 0; i < maxCounterLabels+10; i++ {
+		analyzer.Process(models.LogEntry{ID: fmt.Sprint("key-", i), Message: fmt.Sprint("cache miss for key k", i)})
+	}
+	counts := analyzer.GetSummary().Counters["cache_misses"].Counts
+	if len(counts) != maxCounterLabels+1 || counts[OtherLabel] != 12 {
+		t.Errorf("Expected %d values including %d other, got %d values and %d other", maxCounterLabels+1, 12, len(counts), counts[OtherLabel])
+	}
+
+	for _, tc := range [][3]string{{"cache-misses", "x", ""}, {"entries", "x", ""}, {"c", "(", ""}, {"c", "no groups", "key"}} {
+		if _, err := NewCounter(tc[0], tc[1], tc[2]); err == nil {
+			t.Errorf("Expected an error for counter %v", tc)
+		}
+	}
+}
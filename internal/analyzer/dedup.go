@@ -0,0 +1,171 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// idShards spreads IDs over several locks so workers rarely contend
+const idShards = 64
+
+// idStatus is what recording an ID found out about it
+type idStatus int
+
+const (
+	// idNew is an ID not seen before
+	idNew idStatus = iota
+	// idDuplicate is an ID seen before
+	idDuplicate
+	// idHashMatch is an ID whose hash matches one restored from a State.
+	// States hold no more than the hashes, so it may be a distinct ID.
+	idHashMatch
+)
+
+// idTable records the IDs of processed entries. IDs are stored as 64-bit
+// hashes, which keeps memory independent of ID length and avoids retaining
+// the strings. The hash is FNV-1a, so exported hashes stay valid in other
+// processes. Each hash comes with a 32-bit fingerprint of the ID, so two IDs
+// sharing a hash are told apart and neither is dropped; the rare later ones
+// are then kept in full.
+//
+// Restored hashes have no fingerprint, and an ID matching one is skipped on
+// the hash alone. With a billion restored and a billion new IDs, the chance
+// that any new entry is skipped that way is about 5%. Such skips are
+// counted apart from other duplicates.
+type idTable struct {
+	// fingerprints maps hashes to fingerprints, or to 0 if restored
+	fingerprints map[uint64]uint32
+	// collided holds the IDs whose hash was recorded for another ID
+	collided map[string]struct{}
+}
+
+func newIDTable() idTable {
+	return idTable{fingerprints: make(map[uint64]uint32)}
+}
+
+// add records id, whose hash is h
+func (t *idTable) add(id string, h uint64) idStatus {
+	fp := fingerprintID(id)
+	seen, ok := t.fingerprints[h]
+	switch {
+	case !ok:
+		t.fingerprints[h] = fp
+		return idNew
+	case seen == fp:
+		return idDuplicate
+	case seen == 0:
+		return idHashMatch
+	}
+	if _, ok := t.collided[id]; ok {
+		return idDuplicate
+	}
+	if t.collided == nil {
+		t.collided = make(map[string]struct{})
+	}
+	// The ID may be a slice of a whole input line
+	t.collided[strings.Clone(id)] = struct{}{}
+	return idNew
+}
+
+// addHash records the hash of an ID restored from a State
+func (t *idTable) addHash(h uint64) {
+	if _, ok := t.fingerprints[h]; !ok {
+		t.fingerprints[h] = 0
+	}
+}
+
+// appendHashes appends the recorded hashes to hashes in no particular order
+func (t *idTable) appendHashes(hashes []uint64) []uint64 {
+	for h := range t.fingerprints {
+		hashes = append(hashes, h)
+	}
+	return hashes
+}
+
+// idSet is an idTable split into shards with their own locks, for use by
+// several workers at once
+type idSet struct {
+	shards [idShards]struct {
+		mu sync.Mutex
+		idTable
+	}
+}
+
+func newIDSet() *idSet {
+	s := &idSet{}
+	for i := range s.shards {
+		s.shards[i].idTable = newIDTable()
+	}
+	return s
+}
+
+// add records id and reports whether it was seen before
+func (s *idSet) add(id string) idStatus {
+	h := hashID(id)
+	shard := &s.shards[h%idShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	return shard.add(id, h)
+}
+
+// addHash records the hash of an ID restored from a State
+func (s *idSet) addHash(h uint64) {
+	shard := &s.shards[h%idShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	shard.idTable.addHash(h)
+}
+
+// hashes returns the recorded hashes in ascending order
+func (s *idSet) hashes() []uint64 {
+	var hashes []uint64
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		hashes = shard.appendHashes(hashes)
+		shard.mu.Unlock()
+	}
+	sortHashes(hashes)
+	return hashes
+}
+
+// sortHashes sorts hashes in ascending order
+func sortHashes(hashes []uint64) {
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+}
+
+// hashID returns the 64-bit FNV-1a hash of id without allocating
+func hashID(id string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := 0; i < len(id); i++ {
+		h ^= uint64(id[i])
+		h *= prime
+	}
+	return h
+}
+
+// fingerprintID returns the 32-bit FNV-1 hash of id, which is never 0. It
+// differs from hashID in the size and the order of its steps, so IDs sharing
+// one hash rarely share the other.
+func fingerprintID(id string) uint32 {
+	const (
+		offset = 2166136261
+		prime  = 16777619
+	)
+	h := uint32(offset)
+	for i := 0; i < len(id); i++ {
+		h *= prime
+		h ^= uint32(id[i])
+	}
+	if h == 0 {
+		return 1
+	}
+	return h
+}
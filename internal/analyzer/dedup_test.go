@@ -0,0 +1,57 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestIDTableCollisions(t *testing.T) {
+	table := newIDTable()
+
+	// Distinct IDs sharing a hash are both new, and both duplicates later
+	if table.add("first", 42) != idNew {
+		t.Error("Expected the first ID to be new")
+	}
+	if table.add("second", 42) != idNew {
+		t.Error("Expected an ID sharing the hash of another to be new")
+	}
+	if table.add("first", 42) != idDuplicate {
+		t.Error("Expected the first ID to be a duplicate")
+	}
+	if table.add("second", 42) != idDuplicate {
+		t.Error("Expected the second ID to be a duplicate")
+	}
+
+	// A restored hash can only be matched by hash
+	table.addHash(7)
+	if table.add("restored", 7) != idHashMatch {
+		t.Error("Expected an ID with a restored hash to be a hash match")
+	}
+	table.addHash(42)
+	if table.add("third", 42) != idNew {
+		t.Error("Expected a restored hash not to replace a recorded one")
+	}
+}
+
+func TestLogAnalyzerHashMatches(t *testing.T) {
+	analyzer := NewLogAnalyzer()
+	analyzer.Process(models.LogEntry{ID: "entry-1", Level: models.INFO})
+
+	restored := NewLogAnalyzer()
+	if err := restored.Import(analyzer.Export()); err != nil {
+		t.Fatalf("Failed to import state: %v", err)
+	}
+	if restored.Process(models.LogEntry{ID: "entry-1", Level: models.INFO}) {
+		t.Error("Expected entry-1 to be skipped after import")
+	}
+	if !restored.Process(models.LogEntry{ID: "entry-2", Level: models.INFO}) {
+		t.Error("Expected entry-2 to be analyzed")
+	}
+	restored.Process(models.LogEntry{ID: "entry-2", Level: models.INFO})
+
+	// Only the entry matched against the state counts
+	if restored.HashMatches() != 1 {
+		t.Errorf("Expected 1 hash match, got %d", restored.HashMatches())
+	}
+}
@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// stackFields are entry fields checked for stack traces besides the message
+var stackFields = []string{"stack_trace", "stacktrace", "stack", "exception", "error.stack"}
+
+// ExceptionCount is how often an exception type was raised at one location
+type ExceptionCount struct {
+	StackTrace
+	Count int
+}
+
+// ExceptionSection groups stack traces by language, exception type and the
+// frame that raised them
+type ExceptionSection struct {
+	top int
+
+	mu     sync.Mutex
+	counts map[StackTrace]int
+}
+
+// NewExceptionSection creates a section reporting the top most frequent exceptions
+func NewExceptionSection(top int) *ExceptionSection {
+	return &ExceptionSection{top: top, counts: make(map[StackTrace]int)}
+}
+
+func (s *ExceptionSection) Name() string {
+	return "Exceptions by Type and Location"
+}
+
+func (s *ExceptionSection) Observe(entry models.LogEntry) {
+	trace, ok := DetectStackTrace(entry.Message)
+	for _, field := range stackFields {
+		if ok {
+			break
+		}
+		if text, present := entry.Fields[field]; present {
+			trace, ok = DetectStackTrace(text)
+		}
+	}
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Bound memory like the pattern section does
+	if _, seen := s.counts[trace]; !seen && len(s.counts) >= maxPatterns {
+		return
+	}
+	s.counts[trace]++
+}
+
+// Top returns the most frequent exceptions, largest first
+func (s *ExceptionSection) Top() []ExceptionCount {
+	s.mu.Lock()
+	counts := make([]ExceptionCount, 0, len(s.counts))
+	for trace, n := range s.counts {
+		counts = append(counts, ExceptionCount{StackTrace: trace, Count: n})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		if counts[i].Type != counts[j].Type {
+			return counts[i].Type < counts[j].Type
+		}
+		return counts[i].Frame < counts[j].Frame
+	})
+	if len(counts) > s.top {
+		counts = counts[:s.top]
+	}
+	return counts
+}
+
+func (s *ExceptionSection) WriteText(w io.Writer) error {
+	for _, ec := range s.Top() {
+		if _, err := fmt.Fprintf(w, "  %d: [%s] %s at %s\n", ec.Count, ec.Language, ec.Type, ec.Frame); err != nil {
+			return err
+		}
+	}
+	return nil
+}
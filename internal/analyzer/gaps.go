@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/release"
+)
+
+const (
+	// DefaultGapThreshold is the silence reported as a gap
+	DefaultGapThreshold = 10 * time.Minute
+	// gapMinMinutes is the number of active minutes a service needs before
+	// its silences are judged
+	gapMinMinutes = 10
+)
+
+// Gap is a period in which a normally continuous service logged nothing
+type Gap struct {
+	Service string
+	// Start is the minute of the last entry before the gap
+	Start time.Time
+	// End is the minute of the first entry after the gap, or the end of the
+	// data if the service never resumed
+	End time.Time
+	// Stopped is set when the service logged nothing until the end of the data
+	Stopped bool
+	// After is the latest release before the gap began, if a timeline was
+	// set
+	After *release.Release
+}
+
+// Duration returns the length of the silence
+func (g Gap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// GapSection reports silent periods in services that otherwise log
+// continuously, such as crashed agents or stopped services. A service counts
+// as continuous when the typical spacing between its active minutes is at
+// most a quarter of the threshold.
+type GapSection struct {
+	threshold time.Duration
+	releases  *release.Timeline
+
+	mu       sync.Mutex
+	services map[string]map[int64]struct{}
+	last     int64
+}
+
+// NewGapSection creates a gap detector reporting silences above threshold
+func NewGapSection(threshold time.Duration) *GapSection {
+	return &GapSection{threshold: threshold, services: make(map[string]map[int64]struct{})}
+}
+
+func (s *GapSection) Name() string {
+	return "Gaps in Logging"
+}
+
+// SetReleases has each gap name the release it began after
+func (s *GapSection) SetReleases(timeline *release.Timeline) {
+	s.releases = timeline
+}
+
+func (s *GapSection) Observe(entry models.LogEntry) {
+	if entry.Timestamp.IsZero() {
+		return
+	}
+	minute := entry.Timestamp.Unix() / 60
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	minutes, ok := s.services[entry.Service]
+	if !ok {
+		minutes = make(map[int64]struct{})
+		s.services[entry.Service] = minutes
+	}
+	minutes[minute] = struct{}{}
+	if minute > s.last {
+		s.last = minute
+	}
+}
+
+// Gaps returns the gaps found, longest first
+func (s *GapSection) Gaps() []Gap {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	threshold := int64(s.threshold / time.Minute)
+	var gaps []Gap
+	for service, set := range s.services {
+		if len(set) < gapMinMinutes {
+			continue
+		}
+		minutes := make([]int64, 0, len(set))
+		for m := range set {
+			minutes = append(minutes, m)
+		}
+		sort.Slice(minutes, func(i, j int) bool { return minutes[i] < minutes[j] })
+
+		spacing := make([]int64, len(minutes)-1)
+		for i := 1; i < len(minutes); i++ {
+			spacing[i-1] = minutes[i] - minutes[i-1]
+		}
+		if median(spacing)*4 > threshold {
+			// The service logs too sparsely for silences to stand out
+			continue
+		}
+
+		for i, d := range spacing {
+			if d > threshold {
+				gaps = append(gaps, Gap{Service: service, Start: minuteTime(minutes[i]), End: minuteTime(minutes[i+1])})
+			}
+		}
+		if last := minutes[len(minutes)-1]; s.last-last > threshold {
+			gaps = append(gaps, Gap{Service: service, Start: minuteTime(last), End: minuteTime(s.last), Stopped: true})
+		}
+	}
+
+	sort.Slice(gaps, func(i, j int) bool {
+		if gaps[i].Duration() != gaps[j].Duration() {
+			return gaps[i].Duration() > gaps[j].Duration()
+		}
+		return gaps[i].Service < gaps[j].Service
+	})
+	for i := range gaps {
+		gaps[i].After = releaseBefore(s.releases, gaps[i].Service, gaps[i].Start)
+	}
+	return gaps
+}
+
+func (s *GapSection) WriteText(w io.Writer) error {
+	for _, g := range s.Gaps() {
+		end := "until " + g.End.Format("2006-01-02 15:04")
+		if g.Stopped {
+			end = "until the end of the data at " + g.End.Format("2006-01-02 15:04")
+		}
+		_, err := fmt.Fprintf(w, "  %s: silent for %v from %s %s%s\n", g.Service, g.Duration(), g.Start.Format("2006-01-02 15:04"), end, afterRelease(g.After))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func minuteTime(minute int64) time.Time {
+	return time.Unix(minute*60, 0).UTC()
+}
@@ -0,0 +1,43 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestGapSection(t *testing.T) {
+	section := NewGapSection(10 * time.Minute)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	observe := func(service string, from, to int) {
+		for m := from; m < to; m++ {
+			section.Observe(models.LogEntry{Service: service, Timestamp: start.Add(time.Duration(m) * time.Minute)})
+		}
+	}
+
+	// api logs every minute for two hours, except for a 30 minute outage
+	observe("api", 0, 40)
+	observe("api", 70, 120)
+	// worker stops logging an hour in
+	observe("worker", 0, 60)
+	// cron runs every 30 minutes, so its silences are normal
+	for m := 0; m < 120; m += 30 {
+		section.Observe(models.LogEntry{Service: "cron", Timestamp: start.Add(time.Duration(m) * time.Minute)})
+	}
+
+	gaps := section.Gaps()
+	if len(gaps) != 2 {
+		t.Fatalf("Expected 2 gaps, got %+v", gaps)
+	}
+
+	if gaps[0].Service != "worker" || !gaps[0].Stopped || gaps[0].Duration() != 60*time.Minute {
+		t.Errorf("Expected worker to have stopped for 60m, got %+v", gaps[0])
+	}
+	if gaps[1].Service != "api" || gaps[1].Stopped || gaps[1].Duration() != 31*time.Minute {
+		t.Errorf("Expected a 31m api gap, got %+v", gaps[1])
+	}
+	if !gaps[1].Start.Equal(start.Add(39 * time.Minute)) {
+		t.Errorf("Expected the api gap to start at its last entry, got %v", gaps[1].Start)
+	}
+}
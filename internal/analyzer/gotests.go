@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// PackageResult counts the test outcomes of one Go package
+type PackageResult struct {
+	Package string
+	Passed  int
+	Failed  int
+	Skipped int
+	Panics  int
+	Races   int
+	// FailedTests lists the failing tests in the order they were seen
+	FailedTests []string
+	// PackageFailed is set when the package as a whole failed
+	PackageFailed bool
+}
+
+// TestSection summarizes Go test results read with the gotest format, from
+// the package, test and action fields of its entries
+type TestSection struct {
+	mu       sync.Mutex
+	packages map[string]*PackageResult
+}
+
+// NewTestSection creates an empty test summary
+func NewTestSection() *TestSection {
+	return &TestSection{packages: make(map[string]*PackageResult)}
+}
+
+func (s *TestSection) Name() string {
+	return "Go Test Results"
+}
+
+func (s *TestSection) Observe(entry models.LogEntry) {
+	action := entry.Fields["action"]
+	if action == "" {
+		return
+	}
+	pkg, test := entry.Fields["package"], entry.Fields["test"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, ok := s.packages[pkg]
+	if !ok {
+		result = &PackageResult{Package: pkg}
+		s.packages[pkg] = result
+	}
+
+	switch action {
+	case "panic":
+		result.Panics++
+	case "race":
+		result.Races++
+	case "fail":
+		if test == "" {
+			result.PackageFailed = true
+			return
+		}
+		result.Failed++
+		result.FailedTests = append(result.FailedTests, test)
+	case "pass":
+		if test != "" {
+			result.Passed++
+		}
+	case "skip":
+		if test != "" {
+			result.Skipped++
+		}
+	}
+}
+
+// Packages returns the results per package, failing packages first
+func (s *TestSection) Packages() []PackageResult {
+	s.mu.Lock()
+	results := make([]PackageResult, 0, len(s.packages))
+	for _, result := range s.packages {
+		r := *result
+		r.FailedTests = append([]string(nil), result.FailedTests...)
+		results = append(results, r)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].failing() != results[j].failing() {
+			return results[i].failing()
+		}
+		return results[i].Package < results[j].Package
+	})
+	return results
+}
+
+// failing reports whether anything in the package went wrong
+func (r PackageResult) failing() bool {
+	return r.PackageFailed || r.Failed > 0 || r.Panics > 0 || r.Races > 0
+}
+
+func (s *TestSection) WriteText(w io.Writer) error {
+	for _, r := range s.Packages() {
+		status := "ok"
+		if r.failing() {
+			status = "FAIL"
+		}
+		name := r.Package
+		if name == "" {
+			name = "(unknown package)"
+		}
+		line := fmt.Sprintf("  %s %s: %d passed, %d failed, %d skipped", status, name, r.Passed, r.Failed, r.Skipped)
+		if r.Panics > 0 {
+			line += fmt.Sprintf(", %d panics", r.Panics)
+		}
+		if r.Races > 0 {
+			line += fmt.Sprintf(", %d data races", r.Races)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		if len(r.FailedTests) > 0 {
+			if _, err := fmt.Fprintf(w, "    failing: %s\n", strings.Join(r.FailedTests, ", ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package analyzer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func testEntry(action, pkg, test string) models.LogEntry {
+	return models.LogEntry{Fields: map[string]string{"action": action, "package": pkg, "test": test}}
+}
+
+func TestTestSection(t *testing.T) {
+	section := NewTestSection()
+	section.Observe(testEntry("pass", "example.com/util", "TestA"))
+	section.Observe(testEntry("pass", "example.com/util", ""))
+	section.Observe(testEntry("pass", "example.com/api", "TestList"))
+	section.Observe(testEntry("fail", "example.com/api", "TestGet"))
+	section.Observe(testEntry("panic", "example.com/api", "TestGet"))
+	section.Observe(testEntry("fail", "example.com/api", ""))
+	section.Observe(models.LogEntry{Message: "not a test result"})
+
+	packages := section.Packages()
+	if len(packages) != 2 {
+		t.Fatalf("Expected 2 packages, got %d", len(packages))
+	}
+
+	// Failing packages are listed first
+	api := packages[0]
+	if api.Package != "example.com/api" || !api.PackageFailed || api.Failed != 1 || api.Passed != 1 || api.Panics != 1 {
+		t.Errorf("Unexpected api result: %+v", api)
+	}
+	if packages[1].Passed != 1 {
+		t.Errorf("Expected package results not to count as tests, got %+v", packages[1])
+	}
+
+	var buf bytes.Buffer
+	section.WriteText(&buf)
+	if !strings.Contains(buf.String(), "failing: TestGet") {
+		t.Errorf("Expected failing tests in the report, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,220 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// HealthWeights sets how much each signal lowers a service's health score.
+// Only their proportions matter.
+type HealthWeights struct {
+	// ErrorRatio weighs the share of ERROR and FATAL entries
+	ErrorRatio float64
+	// Fatal weighs whether the service logged any FATAL entry
+	Fatal float64
+	// Anomaly weighs the storms and gaps detected for the service
+	Anomaly float64
+}
+
+// DefaultHealthWeights ranks services mostly by their error ratio
+var DefaultHealthWeights = HealthWeights{ErrorRatio: 0.6, Fatal: 0.3, Anomaly: 0.1}
+
+// String formats the weights as accepted by ParseHealthWeights
+func (w HealthWeights) String() string {
+	return fmt.Sprintf("error=%g,fatal=%g,anomaly=%g", w.ErrorRatio, w.Fatal, w.Anomaly)
+}
+
+// ParseHealthWeights parses weights such as "error=0.6,fatal=0.3,anomaly=0.1".
+// Weights that are not given keep their default.
+func ParseHealthWeights(s string) (HealthWeights, error) {
+	w := DefaultHealthWeights
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return w, fmt.Errorf("invalid health weight %q (expected name=value)", part)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || f < 0 {
+			return w, fmt.Errorf("invalid health weight %q: must be a non-negative number", part)
+		}
+		switch strings.TrimSpace(name) {
+		case "error":
+			w.ErrorRatio = f
+		case "fatal":
+			w.Fatal = f
+		case "anomaly":
+			w.Anomaly = f
+		default:
+			return w, fmt.Errorf("unknown health weight %q (supported: error, fatal, anomaly)", name)
+		}
+	}
+	if w.ErrorRatio+w.Fatal+w.Anomaly == 0 {
+		return w, fmt.Errorf("health weights must not all be zero")
+	}
+	return w, nil
+}
+
+// AnomalyDetector is a section whose findings can be attributed to services
+type AnomalyDetector interface {
+	Section
+	// AnomaliesByService returns the number of findings per service
+	AnomaliesByService() map[string]int
+}
+
+var (
+	_ AnomalyDetector = (*StormSection)(nil)
+	_ AnomalyDetector = (*GapSection)(nil)
+)
+
+// AnomaliesByService returns the number of storms per service
+func (s *StormSection) AnomaliesByService() map[string]int {
+	counts := make(map[string]int)
+	for _, storm := range s.Storms() {
+		counts[storm.Service]++
+	}
+	return counts
+}
+
+// AnomaliesByService returns the number of gaps per service
+func (s *GapSection) AnomaliesByService() map[string]int {
+	counts := make(map[string]int)
+	for _, gap := range s.Gaps() {
+		counts[gap.Service]++
+	}
+	return counts
+}
+
+// ServiceHealth is the health score of one service
+type ServiceHealth struct {
+	Service string
+	// Score ranges from 100 for a healthy service down to 0
+	Score      float64
+	Entries    int
+	ErrorRatio float64
+	Fatal      int
+	Anomalies  int
+}
+
+// serviceLevels counts the entries of one service
+type serviceLevels struct {
+	total, errors, fatal int
+}
+
+// HealthSection scores each service from its error ratio, whether it logged
+// FATAL entries and the anomalies its detectors found, and ranks the
+// services worst first
+type HealthSection struct {
+	weights   HealthWeights
+	detectors []AnomalyDetector
+
+	mu       sync.Mutex
+	services map[string]*serviceLevels
+}
+
+// NewHealthSection creates a health scorer. The detectors are fed the same
+// entries as the section itself.
+func NewHealthSection(weights HealthWeights, detectors ...AnomalyDetector) *HealthSection {
+	return &HealthSection{weights: weights, detectors: detectors, services: make(map[string]*serviceLevels)}
+}
+
+// SetNormalization passes form on to the detectors grouping messages by
+// pattern
+func (s *HealthSection) SetNormalization(form textnorm.Form) {
+	for _, d := range s.detectors {
+		if n, ok := d.(NormalizingSection); ok {
+			n.SetNormalization(form)
+		}
+	}
+}
+
+func (s *HealthSection) Name() string {
+	return "Service Health (worst first)"
+}
+
+func (s *HealthSection) Observe(entry models.LogEntry) {
+	for _, d := range s.detectors {
+		d.Observe(entry)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	levels, ok := s.services[entry.Service]
+	if !ok {
+		levels = &serviceLevels{}
+		s.services[entry.Service] = levels
+	}
+	levels.total++
+	switch entry.Level {
+	case models.FATAL:
+		levels.fatal++
+		levels.errors++
+	case models.ERROR:
+		levels.errors++
+	}
+}
+
+// Scores returns the health of every service, lowest score first
+func (s *HealthSection) Scores() []ServiceHealth {
+	anomalies := make(map[string]int)
+	for _, d := range s.detectors {
+		for service, n := range d.AnomaliesByService() {
+			anomalies[service] += n
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := s.weights.ErrorRatio + s.weights.Fatal + s.weights.Anomaly
+	scores := make([]ServiceHealth, 0, len(s.services))
+	for service, levels := range s.services {
+		h := ServiceHealth{
+			Service:    service,
+			Entries:    levels.total,
+			ErrorRatio: float64(levels.errors) / float64(levels.total),
+			Fatal:      levels.fatal,
+			Anomalies:  anomalies[service],
+		}
+
+		penalty := s.weights.ErrorRatio * h.ErrorRatio
+		if h.Fatal > 0 {
+			penalty += s.weights.Fatal
+		}
+		// Each further anomaly matters less than the one before
+		penalty += s.weights.Anomaly * (1 - 1/float64(1+h.Anomalies))
+		if total > 0 {
+			h.Score = 100 * (1 - penalty/total)
+		}
+		scores = append(scores, h)
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score < scores[j].Score
+		}
+		return scores[i].Service < scores[j].Service
+	})
+	return scores
+}
+
+func (s *HealthSection) WriteText(w io.Writer) error {
+	for i, h := range s.Scores() {
+		_, err := fmt.Fprintf(w, "  %d. %s: %.1f (%.1f%% errors, %d fatal, %d anomalies, %d entries)\n",
+			i+1, h.Service, h.Score, 100*h.ErrorRatio, h.Fatal, h.Anomalies, h.Entries)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
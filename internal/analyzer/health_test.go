@@ -0,0 +1,73 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestHealthSection(t *testing.T) {
+	storms := NewStormSection(5)
+	section := NewHealthSection(DefaultHealthWeights, storms)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	observe := func(service string, level models.LogLevel, n int) {
+		for i := 0; i < n; i++ {
+			section.Observe(models.LogEntry{Service: service, Level: level, Message: "request " + service, Timestamp: start.Add(time.Duration(i) * time.Hour)})
+		}
+	}
+
+	// api is healthy, worker has a few errors, and db has fewer errors but
+	// crashed once
+	observe("api", models.INFO, 10)
+	observe("worker", models.INFO, 6)
+	observe("worker", models.ERROR, 4)
+	observe("db", models.INFO, 9)
+	observe("db", models.FATAL, 1)
+	// cache storms within a single minute
+	for i := 0; i < 10; i++ {
+		section.Observe(models.LogEntry{Service: "cache", Level: models.INFO, Message: "miss", Timestamp: start})
+	}
+
+	scores := section.Scores()
+	if len(scores) != 4 {
+		t.Fatalf("Expected 4 services, got %+v", scores)
+	}
+
+	var order []string
+	for _, h := range scores {
+		order = append(order, h.Service)
+	}
+	if got := strings.Join(order, ","); got != "db,worker,cache,api" {
+		t.Errorf("Expected services ranked db,worker,cache,api, got %s", got)
+	}
+	if scores[3].Score != 100 {
+		t.Errorf("Expected api to score 100, got %v", scores[3].Score)
+	}
+	if scores[2].Anomalies != 1 {
+		t.Errorf("Expected 1 anomaly for cache, got %d", scores[2].Anomalies)
+	}
+
+	// Weighing only errors puts worker first
+	section.weights = HealthWeights{ErrorRatio: 1}
+	if worst := section.Scores()[0]; worst.Service != "worker" || worst.Score != 60 {
+		t.Errorf("Expected worker to score 60 on errors alone, got %+v", worst)
+	}
+}
+
+func TestParseHealthWeights(t *testing.T) {
+	w, err := ParseHealthWeights("error=2, anomaly=1")
+	if err != nil {
+		t.Fatalf("Failed to parse weights: %v", err)
+	}
+	if w.ErrorRatio != 2 || w.Fatal != DefaultHealthWeights.Fatal || w.Anomaly != 1 {
+		t.Errorf("Expected error=2 and anomaly=1 with the default fatal weight, got %+v", w)
+	}
+
+	for _, s := range []string{"errors=1", "error", "fatal=-1", "error=0,fatal=0,anomaly=0"} {
+		if _, err := ParseHealthWeights(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/release"
+)
+
+// ErrorHistory holds the fingerprints of errors seen by earlier runs, such
+// as a bloom.Filter
+type ErrorHistory interface {
+	Has(fingerprint string) bool
+}
+
+// ErrorGroup is an error pattern of one service and whether earlier runs
+// saw it
+type ErrorGroup struct {
+	Service string `json:"service"`
+	Pattern string `json:"pattern"`
+	Example string `json:"example"`
+	Count   int    `json:"count"`
+	// New is set if no earlier run saw the pattern
+	New bool `json:"new"`
+	// FirstSeen and LastSeen are the earliest and latest timestamps of the
+	// group's entries, zero if none had one
+	FirstSeen time.Time `json:"first_seen,omitempty"`
+	LastSeen  time.Time `json:"last_seen,omitempty"`
+	// Trend is TrendRising, TrendFalling or TrendSteady, or empty if the
+	// group has too few timestamped entries to tell
+	Trend string `json:"trend,omitempty"`
+	// After is the latest release before the group was first seen, if a
+	// timeline was set
+	After *release.Release `json:"after_release,omitempty"`
+	// Samples are the first few entries of the group
+	Samples []models.LogEntry `json:"samples,omitempty"`
+}
+
+// maxErrorSamples is the number of entries kept as samples of a group
+const maxErrorSamples = 3
+
+// Trends of error groups
+const (
+	// TrendRising groups occur mostly late in the data
+	TrendRising = "rising"
+	// TrendFalling groups occur mostly early in the data
+	TrendFalling = "falling"
+	// TrendSteady groups occur throughout the data
+	TrendSteady = "steady"
+)
+
+// minTrendEntries is the fewest timestamped entries a group needs for a trend
+const minTrendEntries = 5
+
+// errorGroup is an ErrorGroup with the sum of its timestamps, in seconds
+// since the Unix epoch, for its trend
+type errorGroup struct {
+	ErrorGroup
+	sum   float64
+	timed int
+}
+
+// Fingerprint identifies the group across runs
+func (g ErrorGroup) Fingerprint() string {
+	return ErrorFingerprint(g.Service, g.Pattern)
+}
+
+// ErrorFingerprint identifies the errors of a service with a message pattern
+func ErrorFingerprint(service, pattern string) string {
+	return service + "\x00" + pattern
+}
+
+// ErrorHistorySection groups the ERROR and FATAL entries by service and
+// message pattern and labels each group NEW if the history does not know it,
+// since new kinds of errors are what on-call looks at first
+type ErrorHistorySection struct {
+	top      int
+	history  ErrorHistory
+	releases *release.Timeline
+
+	// start and end bound the timestamps of all entries, in nanoseconds
+	// since the Unix epoch
+	start, end atomic.Int64
+
+	mu     sync.Mutex
+	groups map[string]*errorGroup
+}
+
+// NewErrorHistorySection creates a section reporting the top error groups,
+// new ones first. A nil history makes every error new.
+func NewErrorHistorySection(top int, history ErrorHistory) *ErrorHistorySection {
+	return &ErrorHistorySection{top: top, history: history, groups: make(map[string]*errorGroup)}
+}
+
+func (s *ErrorHistorySection) Name() string {
+	return "New Errors"
+}
+
+// SetReleases has each group name the release it was first seen after
+func (s *ErrorHistorySection) SetReleases(timeline *release.Timeline) {
+	s.releases = timeline
+}
+
+func (s *ErrorHistorySection) Observe(entry models.LogEntry) {
+	if !entry.Timestamp.IsZero() {
+		s.extend(entry.Timestamp.UnixNano())
+	}
+	if entry.Level.Severity() == 0 || entry.Level.Compare(models.ERROR) < 0 {
+		return
+	}
+	pattern := Pattern(entry.Message)
+	fingerprint := ErrorFingerprint(entry.Service, pattern)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[fingerprint]
+	if !ok {
+		// Bound memory like the pattern section does
+		if len(s.groups) >= maxPatterns {
+			return
+		}
+		g = &errorGroup{ErrorGroup: ErrorGroup{Service: entry.Service, Pattern: pattern, Example: entry.Message}}
+		g.New = s.history == nil || !s.history.Has(fingerprint)
+		s.groups[fingerprint] = g
+	}
+	g.Count++
+	if len(g.Samples) < maxErrorSamples {
+		g.Samples = append(g.Samples, entry)
+	}
+	if ts := entry.Timestamp; !ts.IsZero() {
+		if g.FirstSeen.IsZero() || ts.Before(g.FirstSeen) {
+			g.FirstSeen = ts
+		}
+		if ts.After(g.LastSeen) {
+			g.LastSeen = ts
+		}
+		g.sum += float64(ts.UnixNano()) / 1e9
+		g.timed++
+	}
+}
+
+// extend widens the time range of all entries to include ts
+func (s *ErrorHistorySection) extend(ts int64) {
+	for {
+		start := s.start.Load()
+		if (start != 0 && start <= ts) || s.start.CompareAndSwap(start, ts) {
+			break
+		}
+	}
+	for {
+		end := s.end.Load()
+		if (end != 0 && end >= ts) || s.end.CompareAndSwap(end, ts) {
+			break
+		}
+	}
+}
+
+// trend tells whether a group's entries lean towards the start or the end of
+// the time range of all entries, from where their mean timestamp falls
+func (s *ErrorHistorySection) trend(g *errorGroup) string {
+	start, end := float64(s.start.Load())/1e9, float64(s.end.Load())/1e9
+	if g.timed < minTrendEntries || end <= start {
+		return ""
+	}
+	// Entries spread evenly over the range have their mean in the middle
+	switch position := (g.sum/float64(g.timed) - start) / (end - start); {
+	case position >= 0.6:
+		return TrendRising
+	case position <= 0.4:
+		return TrendFalling
+	default:
+		return TrendSteady
+	}
+}
+
+// Groups returns every error group, new ones first, then the most frequent.
+// Trends are relative to the time range of all entries observed so far.
+func (s *ErrorHistorySection) Groups() []ErrorGroup {
+	s.mu.Lock()
+	groups := make([]ErrorGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		group := g.ErrorGroup
+		group.Samples = append([]models.LogEntry(nil), g.Samples...)
+		group.Trend = s.trend(g)
+		group.After = releaseBefore(s.releases, g.Service, g.FirstSeen)
+		groups = append(groups, group)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].New != groups[j].New {
+			return groups[i].New
+		}
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Fingerprint() < groups[j].Fingerprint()
+	})
+	return groups
+}
+
+func (s *ErrorHistorySection) WriteText(w io.Writer) error {
+	groups := s.Groups()
+	fresh := 0
+	for _, g := range groups {
+		if g.New {
+			fresh++
+		}
+	}
+	if _, err := fmt.Fprintf(w, "  %d new and %d previously seen error patterns\n", fresh, len(groups)-fresh); err != nil {
+		return err
+	}
+	if len(groups) > s.top {
+		groups = groups[:s.top]
+	}
+	for _, g := range groups {
+		label := "seen"
+		if g.New {
+			label = "NEW"
+		}
+		if _, err := fmt.Fprintf(w, "  %-4s %d: [%s] %s%s\n", label, g.Count, g.Service, g.Pattern, seen(g)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seen describes when a group occurred and its trend
+func seen(g ErrorGroup) string {
+	if g.FirstSeen.IsZero() {
+		return ""
+	}
+	const layout = "2006-01-02 15:04:05"
+	text := " (" + g.FirstSeen.Format(layout)
+	if g.LastSeen.After(g.FirstSeen) {
+		text += " to " + g.LastSeen.Format(layout)
+	}
+	if g.Trend != "" {
+		text += ", " + g.Trend
+	}
+	return text + afterRelease(g.After) + ")"
+}
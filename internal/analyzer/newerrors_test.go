@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// knownErrors is an error history holding a fixed set of fingerprints
+type knownErrors map[string]bool
+
+func (k knownErrors) Has(fingerprint string) bool {
+	return k[fingerprint]
+}
+
+func TestErrorHistorySection(t *testing.T) {
+	history := knownErrors{ErrorFingerprint("db", "query <*> timed out"): true}
+	section := NewErrorHistorySection(10, history)
+	entries := []models.LogEntry{
+		{Level: models.ERROR, Service: "db", Message: "query 1 timed out"},
+		{Level: models.ERROR, Service: "db", Message: "query 2 timed out"},
+		{Level: models.FATAL, Service: "api", Message: "out of memory"},
+		// The same message from another service is a different error
+		{Level: models.ERROR, Service: "api", Message: "query 3 timed out"},
+		{Level: models.WARNING, Service: "api", Message: "slow request"},
+	}
+	for _, entry := range entries {
+		section.Observe(entry)
+	}
+
+	groups := section.Groups()
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 error groups, got %+v", groups)
+	}
+	if !groups[0].New || !groups[1].New || groups[2].New {
+		t.Errorf("Expected the two new groups before the known one, got %+v", groups)
+	}
+	if groups[2].Service != "db" || groups[2].Count != 2 || groups[2].Example != "query 1 timed out" {
+		t.Errorf("Unexpected known group: %+v", groups[2])
+	}
+
+	var b strings.Builder
+	if err := section.WriteText(&b); err != nil {
+		t.Fatalf("Failed to write section: %v", err)
+	}
+	for _, want := range []string{"2 new and 1 previously seen", "NEW  1: [api] out of memory", "seen 2: [db] query <*> timed out"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestErrorHistorySectionTrend(t *testing.T) {
+	section := NewErrorHistorySection(10, nil)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 60; i++ {
+		ts := start.Add(time.Duration(i) * time.Minute)
+		section.Observe(models.LogEntry{Timestamp: ts, Level: models.INFO, Service: "api", Message: "ok"})
+		section.Observe(models.LogEntry{Timestamp: ts, Level: models.ERROR, Service: "api", Message: "retrying"})
+		if i < 10 {
+			section.Observe(models.LogEntry{Timestamp: ts, Level: models.ERROR, Service: "db", Message: "cold cache"})
+		}
+		if i >= 50 {
+			section.Observe(models.LogEntry{Timestamp: ts, Level: models.ERROR, Service: "db", Message: "disk full"})
+		}
+		if i == 30 {
+			section.Observe(models.LogEntry{Timestamp: ts, Level: models.ERROR, Service: "api", Message: "panic"})
+		}
+	}
+
+	trends := make(map[string]ErrorGroup)
+	for _, g := range section.Groups() {
+		trends[g.Pattern] = g
+	}
+	for pattern, want := range map[string]string{"retrying": TrendSteady, "cold cache": TrendFalling, "disk full": TrendRising, "panic": ""} {
+		if got := trends[pattern].Trend; got != want {
+			t.Errorf("Expected trend %q for %q, got %q", want, pattern, got)
+		}
+	}
+	if g := trends["disk full"]; !g.FirstSeen.Equal(start.Add(50*time.Minute)) || !g.LastSeen.Equal(start.Add(59*time.Minute)) {
+		t.Errorf("Expected disk full seen from 10:50 to 10:59, got %v to %v", g.FirstSeen, g.LastSeen)
+	}
+
+	var b strings.Builder
+	if err := section.WriteText(&b); err != nil {
+		t.Fatalf("Failed to write section: %v", err)
+	}
+	for _, want := range []string{"[db] disk full (2023-01-01 10:50:00 to 2023-01-01 10:59:00, rising)", "[api] panic (2023-01-01 10:30:00)"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// maxPatterns bounds the number of distinct patterns tracked, so messages
+// with unbounded variety cannot exhaust memory
+const maxPatterns = 10000
+
+// PatternCount is a message pattern and how often it occurred
+type PatternCount struct {
+	Pattern string `json:"pattern"`
+	Example string `json:"example"`
+	Count   int    `json:"count"`
+}
+
+// PatternTable holds every pattern a section counted, so the counts of
+// several instances can be combined
+type PatternTable struct {
+	Patterns []PatternCount `json:"patterns"`
+	// Other counts entries whose pattern was not tracked
+	Other int `json:"other,omitempty"`
+}
+
+// PatternSection reports the most frequent message patterns. Messages are
+// grouped by replacing every word containing a digit, such as IDs, numbers
+// and timestamps, with a placeholder.
+type PatternSection struct {
+	top  int
+	form textnorm.Form
+
+	mu       sync.Mutex
+	patterns map[string]*PatternCount
+	// other counts entries whose pattern was not tracked
+	other int
+}
+
+// NewPatternSection creates a section reporting the top most frequent patterns
+func NewPatternSection(top int) *PatternSection {
+	return &PatternSection{top: top, patterns: make(map[string]*PatternCount)}
+}
+
+// NormalizingSection is a section grouping messages by pattern, which can
+// normalize them first so messages from platforms that encode accents or
+// case differently fall into the same patterns. Messages and pattern
+// examples are still reported as they were received.
+type NormalizingSection interface {
+	Section
+	// SetNormalization sets the normalizations applied to messages before
+	// their pattern is taken, before any entry is observed
+	SetNormalization(form textnorm.Form)
+}
+
+var (
+	_ NormalizingSection = (*PatternSection)(nil)
+	_ NormalizingSection = (*StormSection)(nil)
+	_ NormalizingSection = (*ArrivalSection)(nil)
+	_ NormalizingSection = (*HealthSection)(nil)
+)
+
+// Pattern normalizes a message into its pattern
+func Pattern(message string) string {
+	words := strings.Fields(message)
+	for i, word := range words {
+		if strings.IndexFunc(word, unicode.IsDigit) >= 0 {
+			words[i] = "<*>"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// NormalizedPattern returns the pattern of message after normalizing it with
+// form
+func NormalizedPattern(message string, form textnorm.Form) string {
+	return Pattern(form.Apply(message))
+}
+
+// PatternID returns a short, stable identifier of a pattern, so entries
+// can be grouped by pattern downstream without comparing the full text
+func PatternID(pattern string) string {
+	sum := sha256.Sum256([]byte(pattern))
+	return hex.EncodeToString(sum[:6])
+}
+
+func (s *PatternSection) Name() string {
+	return "Top Message Patterns"
+}
+
+// SetNormalization normalizes messages with form before their pattern is
+// taken
+func (s *PatternSection) SetNormalization(form textnorm.Form) {
+	s.form = form
+}
+
+func (s *PatternSection) Observe(entry models.LogEntry) {
+	pattern := NormalizedPattern(entry.Message, s.form)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pc, ok := s.patterns[pattern]
+	if !ok {
+		if len(s.patterns) >= maxPatterns {
+			s.other++
+			return
+		}
+		pc = &PatternCount{Pattern: pattern, Example: entry.Message}
+		s.patterns[pattern] = pc
+	}
+	pc.Count++
+}
+
+// Top returns the most frequent patterns, largest first
+func (s *PatternSection) Top() []PatternCount {
+	s.mu.Lock()
+	counts := make([]PatternCount, 0, len(s.patterns))
+	for _, pc := range s.patterns {
+		counts = append(counts, *pc)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Pattern < counts[j].Pattern
+	})
+	if len(counts) > s.top {
+		counts = counts[:s.top]
+	}
+	return counts
+}
+
+// Table returns every pattern counted so far
+func (s *PatternSection) Table() *PatternTable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := &PatternTable{Patterns: make([]PatternCount, 0, len(s.patterns)), Other: s.other}
+	for _, pc := range s.patterns {
+		t.Patterns = append(t.Patterns, *pc)
+	}
+	return t
+}
+
+// MergeTable adds the counts of t, e.g. the table of another instance
+func (s *PatternSection) MergeTable(t *PatternTable) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.other += t.Other
+	for _, in := range t.Patterns {
+		pc, ok := s.patterns[in.Pattern]
+		if !ok {
+			if len(s.patterns) >= maxPatterns {
+				s.other += in.Count
+				continue
+			}
+			pc = &PatternCount{Pattern: in.Pattern, Example: in.Example}
+			s.patterns[in.Pattern] = pc
+		}
+		pc.Count += in.Count
+	}
+}
+
+func (s *PatternSection) WriteText(w io.Writer) error {
+	for _, pc := range s.Top() {
+		if _, err := fmt.Fprintf(w, "  %d: %s\n", pc.Count, pc.Pattern); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	other := s.other
+	s.mu.Unlock()
+	if other > 0 {
+		_, err := fmt.Fprintf(w, "  %d entries beyond the first %d patterns were not grouped\n", other, maxPatterns)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/release"
+)
+
+// ReleaseSection is a section whose findings name the release they began
+// after, to tell whether a deploy caused them
+type ReleaseSection interface {
+	Section
+	// SetReleases sets the timeline of releases, before any entry is
+	// observed
+	SetReleases(timeline *release.Timeline)
+}
+
+// releaseBefore returns the latest release of service before at, or nil
+func releaseBefore(timeline *release.Timeline, service string, at time.Time) *release.Release {
+	r, ok := timeline.Before(service, at)
+	if !ok {
+		return nil
+	}
+	return &r
+}
+
+// afterRelease describes the release a finding began after, if any
+func afterRelease(r *release.Release) string {
+	if r == nil {
+		return ""
+	}
+	return ", after " + r.String()
+}
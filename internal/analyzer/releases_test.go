@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/release"
+)
+
+func TestReleaseSections(t *testing.T) {
+	start := time.Date(2023, 1, 1, 14, 0, 0, 0, time.UTC)
+	timeline := release.NewTimeline([]release.Release{
+		{Version: "v1.3", Time: start.Add(-24 * time.Hour)},
+		{Version: "v1.4", Service: "api", Time: start.Add(5 * time.Minute)},
+		{Version: "v9", Service: "db", Time: start.Add(10 * time.Minute)},
+	})
+	errors := NewErrorHistorySection(10, nil)
+	storms := NewStormSection(5)
+	gaps := NewGapSection(10 * time.Minute)
+	for _, s := range []ReleaseSection{errors, storms, gaps} {
+		s.SetReleases(timeline)
+	}
+
+	for m := 0; m < 40; m++ {
+		ts := start.Add(time.Duration(m) * time.Minute)
+		entries := []models.LogEntry{{Timestamp: ts, Level: models.ERROR, Service: "db", Message: "slow query"}}
+		if m < 20 {
+			entries = append(entries, models.LogEntry{Timestamp: ts, Level: models.INFO, Service: "api", Message: "ok"})
+		}
+		if m == 7 {
+			for i := 0; i < 10; i++ {
+				entries = append(entries, models.LogEntry{Timestamp: ts, Level: models.ERROR, Service: "api", Message: "nil pointer"})
+			}
+		}
+		for _, entry := range entries {
+			errors.Observe(entry)
+			storms.Observe(entry)
+			gaps.Observe(entry)
+		}
+	}
+
+	for _, g := range errors.Groups() {
+		want := map[string]string{"nil pointer": "v1.4", "slow query": "v1.3"}[g.Pattern]
+		if g.After == nil || g.After.Version != want {
+			t.Errorf("Expected %q to start after %s, got %+v", g.Pattern, want, g.After)
+		}
+	}
+	if s := storms.Storms(); len(s) != 1 || s[0].After == nil || s[0].After.Version != "v1.4" {
+		t.Errorf("Expected the storm to start after v1.4, got %+v", s)
+	}
+	if g := gaps.Gaps(); len(g) != 1 || g[0].Service != "api" || g[0].After == nil || g[0].After.Version != "v1.4" {
+		t.Errorf("Expected api to stop after v1.4, got %+v", g)
+	}
+
+	var b strings.Builder
+	if err := storms.WriteText(&b); err != nil {
+		t.Fatalf("Failed to write section: %v", err)
+	}
+	if want := ", after api v1.4 at 2023-01-01 14:05 UTC\n"; !strings.HasSuffix(b.String(), want) {
+		t.Errorf("Expected %q in:\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,102 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Section is an additional analysis fed from the same pass over the input as
+// the summary. Each section contributes its own part of the final report.
+type Section interface {
+	// Name titles the section in reports
+	Name() string
+	// Observe is called for every new entry, concurrently from several workers
+	Observe(entry models.LogEntry)
+	// WriteText writes the section's findings as plain text
+	WriteText(w io.Writer) error
+}
+
+// sections maps the names accepted by NewSection to their constructors
+var sections = map[string]func() Section{
+	"patterns":   func() Section { return NewPatternSection(10) },
+	"exceptions": func() Section { return NewExceptionSection(10) },
+	"tests":      func() Section { return NewTestSection() },
+	"storms":     func() Section { return NewStormSection(DefaultStormThreshold) },
+	"skew":       func() Section { return NewSkewSection(DefaultSkewThreshold) },
+	"gaps":       func() Section { return NewGapSection(DefaultGapThreshold) },
+	"arrivals":   func() Section { return NewArrivalSection(10) },
+	"cost":       func() Section { return NewCostSection(DefaultPricePerGB, DefaultCurrency) },
+	"sizes":      func() Section { return NewSizeSection(DefaultLargeMessage) },
+	"new-errors": func() Section { return NewErrorHistorySection(20, nil) },
+	"health": func() Section {
+		return NewHealthSection(DefaultHealthWeights, NewStormSection(DefaultStormThreshold), NewGapSection(DefaultGapThreshold))
+	},
+}
+
+// NewSection creates a built-in section by name
+func NewSection(name string) (Section, error) {
+	newSection, ok := sections[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown analysis section %q (supported: %v)", name, SectionNames())
+	}
+	return newSection(), nil
+}
+
+// SectionNames returns the names of all built-in sections
+func SectionNames() []string {
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExcludeServices wraps s so it does not observe entries of the given services
+func ExcludeServices(s Section, services ...string) Section {
+	if len(services) == 0 {
+		return s
+	}
+	excluded := make(map[string]bool, len(services))
+	for _, service := range services {
+		excluded[service] = true
+	}
+	if _, ok := s.(OrderedSection); ok {
+		return orderedExcludingSection{excludingSection{Section: s, excluded: excluded}}
+	}
+	return excludingSection{Section: s, excluded: excluded}
+}
+
+// Unwrap returns the section ExcludeServices wrapped, or s if it is not
+// wrapped
+func Unwrap(s Section) Section {
+	switch w := s.(type) {
+	case excludingSection:
+		return w.Section
+	case orderedExcludingSection:
+		return w.Section
+	}
+	return s
+}
+
+type excludingSection struct {
+	Section
+	excluded map[string]bool
+}
+
+func (s excludingSection) Observe(entry models.LogEntry) {
+	if !s.excluded[entry.Service] {
+		s.Section.Observe(entry)
+	}
+}
+
+// orderedExcludingSection keeps an excluding wrapper of an OrderedSection
+// in the second pass
+type orderedExcludingSection struct {
+	excludingSection
+}
+
+func (orderedExcludingSection) ObservesInOrder() {}
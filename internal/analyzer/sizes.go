@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/human"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// DefaultLargeMessage is the message size in bytes above which a message
+// counts as large, typically a serialized payload dumped into the log
+const DefaultLargeMessage = 16 << 10
+
+// sizeBuckets are the upper bounds of the message size distribution
+var sizeBuckets = []int{128, 1 << 10, 4 << 10, 16 << 10, 64 << 10}
+
+// MessageSizes is the distribution of one service's message sizes
+type MessageSizes struct {
+	Service string
+	Count   int
+	// Bytes is the total size of the messages
+	Bytes int64
+	Max   int
+	// Large counts the messages over the large message threshold
+	Large int
+	// Buckets counts the messages up to each of the bounds of
+	// SizeBucketBounds, with the last one counting the larger messages
+	Buckets []int
+}
+
+// Mean returns the average message size
+func (s MessageSizes) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return float64(s.Bytes) / float64(s.Count)
+}
+
+// SizeBucketBounds returns the upper bounds of the buckets of MessageSizes
+func SizeBucketBounds() []int {
+	return append([]int(nil), sizeBuckets...)
+}
+
+// SizeSection tracks the distribution of message sizes per service and
+// flags services emitting messages over a size threshold
+type SizeSection struct {
+	large int
+
+	mu       sync.Mutex
+	services map[string]*MessageSizes
+}
+
+// NewSizeSection creates a size tracker counting messages over large bytes
+// as large
+func NewSizeSection(large int) *SizeSection {
+	return &SizeSection{large: large, services: make(map[string]*MessageSizes)}
+}
+
+func (s *SizeSection) Name() string {
+	return fmt.Sprintf("Message Sizes (large over %s)", human.FormatSize(int64(s.large)))
+}
+
+func (s *SizeSection) Observe(entry models.LogEntry) {
+	size := len(entry.Message)
+	bucket := sort.SearchInts(sizeBuckets, size)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes, ok := s.services[entry.Service]
+	if !ok {
+		sizes = &MessageSizes{Service: entry.Service, Buckets: make([]int, len(sizeBuckets)+1)}
+		s.services[entry.Service] = sizes
+	}
+	sizes.Count++
+	sizes.Bytes += int64(size)
+	sizes.Buckets[bucket]++
+	if size > sizes.Max {
+		sizes.Max = size
+	}
+	if size > s.large {
+		sizes.Large++
+	}
+}
+
+// Sizes returns the distribution of every service, the ones with the most
+// large messages first, then by total size
+func (s *SizeSection) Sizes() []MessageSizes {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sizes := make([]MessageSizes, 0, len(s.services))
+	for _, service := range s.services {
+		c := *service
+		c.Buckets = append([]int(nil), service.Buckets...)
+		sizes = append(sizes, c)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Large != sizes[j].Large {
+			return sizes[i].Large > sizes[j].Large
+		}
+		if sizes[i].Bytes != sizes[j].Bytes {
+			return sizes[i].Bytes > sizes[j].Bytes
+		}
+		return sizes[i].Service < sizes[j].Service
+	})
+	return sizes
+}
+
+func (s *SizeSection) WriteText(w io.Writer) error {
+	for _, sizes := range s.Sizes() {
+		flag := ""
+		if sizes.Large > 0 {
+			flag = fmt.Sprintf(", %d large", sizes.Large)
+		}
+		_, err := fmt.Fprintf(w, "  %s: %d messages, %d bytes total, mean %.0f bytes, max %d bytes%s\n",
+			sizes.Service, sizes.Count, sizes.Bytes, sizes.Mean(), sizes.Max, flag)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprint(w, "   "); err != nil {
+			return err
+		}
+		for i, n := range sizes.Buckets {
+			label := fmt.Sprintf(">%s", human.FormatSize(int64(sizeBuckets[len(sizeBuckets)-1])))
+			if i < len(sizeBuckets) {
+				label = fmt.Sprintf("<=%s", human.FormatSize(int64(sizeBuckets[i])))
+			}
+			if _, err := fmt.Fprintf(w, " %s: %d", label, n); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package analyzer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestSizeSection(t *testing.T) {
+	section := NewSizeSection(1024)
+	for i := 0; i < 10; i++ {
+		section.Observe(models.LogEntry{Service: "api", Message: "request handled"})
+	}
+	section.Observe(models.LogEntry{Service: "worker", Message: "ok"})
+	section.Observe(models.LogEntry{Service: "worker", Message: strings.Repeat("x", 5000)})
+
+	sizes := section.Sizes()
+	if len(sizes) != 2 {
+		t.Fatalf("Expected 2 services, got %+v", sizes)
+	}
+	worker := sizes[0]
+	if worker.Service != "worker" || worker.Large != 1 || worker.Max != 5000 || worker.Bytes != 5002 {
+		t.Errorf("Expected worker first with one large message of 5000 bytes, got %+v", worker)
+	}
+	if worker.Buckets[0] != 1 || worker.Buckets[3] != 1 {
+		t.Errorf("Expected worker messages in the first and 16KiB buckets, got %v", worker.Buckets)
+	}
+	api := sizes[1]
+	if api.Count != 10 || api.Large != 0 || api.Mean() != 15 {
+		t.Errorf("Expected 10 api messages of 15 bytes, got %+v", api)
+	}
+
+	var buf bytes.Buffer
+	if err := section.WriteText(&buf); err != nil {
+		t.Fatalf("Failed to write section: %v", err)
+	}
+	if !strings.Contains(buf.String(), "worker: 2 messages") || !strings.Contains(buf.String(), "1 large") {
+		t.Errorf("Expected worker to be flagged, got %q", buf.String())
+	}
+}
@@ -0,0 +1,170 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const (
+	// DefaultSkewThreshold is the offset from other sources reported as skew
+	DefaultSkewThreshold = 30 * time.Minute
+	// skewSamples is the number of timestamps sampled per source
+	skewSamples = 1000
+	// skewMinEntries is the number of entries a source needs to be compared
+	skewMinEntries = 10
+	// futureTolerance allows for small clock differences with this host
+	futureTolerance = time.Minute
+)
+
+// SourceSkew describes the clock of one source
+type SourceSkew struct {
+	Source string
+	// Offset is how far the source's median timestamp lies from the median
+	// of all sources
+	Offset time.Duration
+	// Future counts entries with timestamps after the time they were processed
+	Future  int
+	Entries int
+}
+
+// sourceClock samples the timestamps of one source
+type sourceClock struct {
+	entries int
+	future  int
+	sample  []int64
+}
+
+// SkewSection detects sources whose clocks are offset from the others, by
+// comparing the median timestamp of each source with the median across all
+// sources, and sources logging timestamps in the future. It assumes the
+// sources cover roughly the same period, as logs gathered for an incident do.
+type SkewSection struct {
+	threshold time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	sources map[string]*sourceClock
+}
+
+// NewSkewSection creates a skew detector reporting offsets above threshold
+func NewSkewSection(threshold time.Duration) *SkewSection {
+	return &SkewSection{
+		threshold: threshold,
+		now:       time.Now,
+		rng:       rand.New(rand.NewSource(1)),
+		sources:   make(map[string]*sourceClock),
+	}
+}
+
+func (s *SkewSection) Name() string {
+	return "Clock Skew"
+}
+
+func (s *SkewSection) Observe(entry models.LogEntry) {
+	if entry.Timestamp.IsZero() {
+		return
+	}
+	future := entry.Timestamp.After(s.now().Add(futureTolerance))
+	ts := entry.Timestamp.UnixNano()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.sources[entry.Source]
+	if !ok {
+		c = &sourceClock{}
+		s.sources[entry.Source] = c
+	}
+	c.entries++
+	if future {
+		c.future++
+	}
+	// Reservoir sampling keeps a uniform sample of the source's timestamps
+	if len(c.sample) < skewSamples {
+		c.sample = append(c.sample, ts)
+	} else if i := s.rng.Intn(c.entries); i < skewSamples {
+		c.sample[i] = ts
+	}
+}
+
+// Skewed returns the sources whose offset exceeds the threshold or that
+// logged future timestamps, largest offset first
+func (s *SkewSection) Skewed() []SourceSkew {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	medians := make(map[string]int64)
+	var all []int64
+	for source, c := range s.sources {
+		if c.entries < skewMinEntries {
+			continue
+		}
+		m := median(c.sample)
+		medians[source] = m
+		all = append(all, m)
+	}
+
+	var reference int64
+	compare := len(all) >= 2
+	if compare {
+		reference = median(all)
+	}
+
+	var skewed []SourceSkew
+	for source, c := range s.sources {
+		sk := SourceSkew{Source: source, Future: c.future, Entries: c.entries}
+		if m, ok := medians[source]; ok && compare {
+			sk.Offset = time.Duration(m - reference)
+		}
+		if abs(sk.Offset) > s.threshold || sk.Future > 0 {
+			skewed = append(skewed, sk)
+		}
+	}
+	sort.Slice(skewed, func(i, j int) bool {
+		if abs(skewed[i].Offset) != abs(skewed[j].Offset) {
+			return abs(skewed[i].Offset) > abs(skewed[j].Offset)
+		}
+		return skewed[i].Source < skewed[j].Source
+	})
+	return skewed
+}
+
+func (s *SkewSection) WriteText(w io.Writer) error {
+	for _, sk := range s.Skewed() {
+		line := fmt.Sprintf("  %s:", sk.Source)
+		if abs(sk.Offset) > s.threshold {
+			line += fmt.Sprintf(" offset %+v from other sources", sk.Offset.Round(time.Second))
+			if whole := sk.Offset.Round(time.Hour); whole != 0 && abs(sk.Offset-whole) < 5*time.Minute {
+				line += " (whole hours, possibly a time zone error)"
+			}
+		}
+		if sk.Future > 0 {
+			line += fmt.Sprintf(" %d of %d entries in the future", sk.Future, sk.Entries)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// median returns the median of values without modifying them
+func median(values []int64) int64 {
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
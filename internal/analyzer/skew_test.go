@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestSkewSection(t *testing.T) {
+	section := NewSkewSection(30 * time.Minute)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	section.now = func() time.Time { return start.Add(24 * time.Hour) }
+
+	// Three hosts log the same hour; host-c's clock is two hours ahead
+	offsets := map[string]time.Duration{"host-a": 0, "host-b": time.Minute, "host-c": 2 * time.Hour}
+	for source, offset := range offsets {
+		for i := 0; i < 60; i++ {
+			section.Observe(models.LogEntry{Source: source, Timestamp: start.Add(offset + time.Duration(i)*time.Minute)})
+		}
+	}
+	// A few entries from the future on an otherwise quiet source
+	for i := 0; i < 3; i++ {
+		section.Observe(models.LogEntry{Source: "host-d", Timestamp: start.Add(48 * time.Hour)})
+	}
+
+	skewed := section.Skewed()
+	if len(skewed) != 2 {
+		t.Fatalf("Expected 2 skewed sources, got %+v", skewed)
+	}
+	if skewed[0].Source != "host-c" || skewed[0].Offset < 119*time.Minute || skewed[0].Offset > 121*time.Minute {
+		t.Errorf("Expected host-c to be about 2h ahead, got %+v", skewed[0])
+	}
+	if skewed[1].Source != "host-d" || skewed[1].Future != 3 || skewed[1].Offset != 0 {
+		t.Errorf("Expected host-d to have 3 future entries and no offset, got %+v", skewed[1])
+	}
+
+	var buf bytes.Buffer
+	section.WriteText(&buf)
+	if !strings.Contains(buf.String(), "time zone") {
+		t.Errorf("Expected a whole-hour offset to be flagged, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// StackTrace describes an exception or panic found in a log entry
+type StackTrace struct {
+	// Language is java, python or go
+	Language string
+	// Type is the exception class, or the panic message for Go
+	Type string
+	// Frame is the innermost application frame, e.g. "com.acme.Api.get(Api.java:42)"
+	Frame string
+}
+
+var (
+	// javaException matches "java.lang.IllegalStateException: message" with
+	// an optional "Exception in thread ..." prefix
+	javaException = regexp.MustCompile(`^(?:Exception in thread "[^"]*" )?((?:[a-zA-Z_$][\w$]*\.)+[A-Z][\w$]*)(?::|$)`)
+	javaFrame     = regexp.MustCompile(`^\s+at (\S+\(.*\))`)
+	pythonFrame   = regexp.MustCompile(`^\s+File "([^"]+)", line (\d+), in (\S+)`)
+	pythonError   = regexp.MustCompile(`^([A-Za-z_][\w.]*)(?::|$)`)
+	goFrameFile   = regexp.MustCompile(`^\s+(\S+\.go:\d+)`)
+)
+
+// DetectStackTrace recognizes Java, Python and Go stack traces in text
+func DetectStackTrace(text string) (StackTrace, bool) {
+	if !strings.Contains(text, "\n") {
+		return StackTrace{}, false
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	switch {
+	case strings.Contains(text, "Traceback (most recent call last):"):
+		return detectPython(lines)
+	case strings.Contains(text, "panic: ") && strings.Contains(text, "goroutine "):
+		return detectGo(lines)
+	case strings.Contains(text, "\tat ") || strings.Contains(text, "    at "):
+		return detectJava(lines)
+	}
+	return StackTrace{}, false
+}
+
+// detectJava uses the first exception line and the frame that follows it
+func detectJava(lines []string) (StackTrace, bool) {
+	trace := StackTrace{Language: "java"}
+	for _, line := range lines {
+		if trace.Type == "" {
+			if m := javaException.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				trace.Type = m[1]
+			}
+			continue
+		}
+		if m := javaFrame.FindStringSubmatch(line); m != nil {
+			trace.Frame = m[1]
+			break
+		}
+	}
+	return trace, trace.Type != "" && trace.Frame != ""
+}
+
+// detectPython uses the last frame and the exception line that ends the
+// traceback, since Python prints the innermost call last
+func detectPython(lines []string) (StackTrace, bool) {
+	trace := StackTrace{Language: "python"}
+	inTraceback := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "Traceback (most recent call last):") {
+			inTraceback = true
+			continue
+		}
+		if !inTraceback {
+			continue
+		}
+		if m := pythonFrame.FindStringSubmatch(line); m != nil {
+			trace.Frame = m[1] + ":" + m[2] + " in " + m[3]
+			continue
+		}
+		if line != "" && !strings.HasPrefix(line, " ") {
+			if m := pythonError.FindStringSubmatch(line); m != nil {
+				trace.Type = m[1]
+			}
+			inTraceback = false
+		}
+	}
+	return trace, trace.Type != "" && trace.Frame != ""
+}
+
+// detectGo uses the panic message and the first frame of the panicking
+// goroutine outside the runtime
+func detectGo(lines []string) (StackTrace, bool) {
+	trace := StackTrace{Language: "go"}
+	inGoroutine := false
+	for i, line := range lines {
+		if trace.Type == "" {
+			if strings.HasPrefix(line, "panic: ") {
+				message := strings.TrimSuffix(strings.TrimPrefix(line, "panic: "), " [recovered]")
+				trace.Type = Pattern(message)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "goroutine ") {
+			inGoroutine = true
+			continue
+		}
+		if !inGoroutine || line == "" || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		// Function lines are followed by their file and line
+		function := line
+		if idx := strings.LastIndex(function, "("); idx > 0 {
+			function = function[:idx]
+		}
+		if function == "panic" || strings.HasPrefix(function, "runtime.") {
+			continue
+		}
+		trace.Frame = function
+		if i+1 < len(lines) {
+			if m := goFrameFile.FindStringSubmatch(lines[i+1]); m != nil {
+				// Build paths differ between hosts, so keep only the file name
+				trace.Frame += " (" + path.Base(m[1]) + ")"
+			}
+		}
+		break
+	}
+	return trace, trace.Type != "" && trace.Frame != ""
+}
@@ -0,0 +1,81 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const javaTrace = `Request failed
+java.lang.IllegalStateException: connection closed
+	at com.acme.db.Pool.acquire(Pool.java:88)
+	at com.acme.api.Handler.get(Handler.java:42)
+Caused by: java.io.IOException: broken pipe
+	at java.base/sun.nio.ch.IOUtil.write(IOUtil.java:62)`
+
+const pythonTrace = `Traceback (most recent call last):
+  File "/app/server.py", line 120, in handle
+    result = process(request)
+  File "/app/orders.py", line 33, in process
+    total = sum(item["price"] for item in items)
+KeyError: 'price'`
+
+const goPanic = `panic: runtime error: index out of range [5] with length 3
+
+goroutine 17 [running]:
+panic({0x4b2a40, 0xc000012345})
+	/usr/local/go/src/runtime/panic.go:884 +0x212
+github.com/acme/api/orders.(*Cart).Item(...)
+	/home/ci/build/orders/cart.go:42 +0x1d
+main.main()
+	/home/ci/build/main.go:12 +0x25
+exit status 2`
+
+func TestDetectStackTrace(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected StackTrace
+	}{
+		{"java", javaTrace, StackTrace{"java", "java.lang.IllegalStateException", "com.acme.db.Pool.acquire(Pool.java:88)"}},
+		{"python", pythonTrace, StackTrace{"python", "KeyError", "/app/orders.py:33 in process"}},
+		{"go", goPanic, StackTrace{"go", "runtime error: index out of range <*> with length <*>", "github.com/acme/api/orders.(*Cart).Item (cart.go:42)"}},
+	}
+
+	for _, tt := range tests {
+		trace, ok := DetectStackTrace(tt.text)
+		if !ok {
+			t.Errorf("Expected a %s stack trace to be detected", tt.name)
+			continue
+		}
+		if trace != tt.expected {
+			t.Errorf("Expected %s trace %+v, got %+v", tt.name, tt.expected, trace)
+		}
+	}
+
+	if _, ok := DetectStackTrace("User login successful"); ok {
+		t.Error("Expected no stack trace in a plain message")
+	}
+	if _, ok := DetectStackTrace("line one\nline two"); ok {
+		t.Error("Expected no stack trace in a plain multi-line message")
+	}
+}
+
+func TestExceptionSection(t *testing.T) {
+	section := NewExceptionSection(10)
+	section.Observe(models.LogEntry{Message: javaTrace})
+	section.Observe(models.LogEntry{Message: javaTrace})
+	section.Observe(models.LogEntry{Message: "Order failed", Fields: map[string]string{"stack_trace": pythonTrace}})
+	section.Observe(models.LogEntry{Message: "No trace here"})
+
+	top := section.Top()
+	if len(top) != 2 {
+		t.Fatalf("Expected 2 exception groups, got %d", len(top))
+	}
+	if top[0].Type != "java.lang.IllegalStateException" || top[0].Count != 2 {
+		t.Errorf("Unexpected top exception: %+v", top[0])
+	}
+	if top[1].Language != "python" {
+		t.Errorf("Expected the stack trace field to be checked, got %+v", top[1])
+	}
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"fmt"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// StateVersion is the version of the State format written by Export
+const StateVersion = 1
+
+// State is the complete state of a LogAnalyzer in a form that can be
+// serialized, e.g. as JSON, and restored in another process
+type State struct {
+	Version int                `json:"version"`
+	Summary *models.LogSummary `json:"summary"`
+	// IDHashes holds the FNV-1a hashes of the processed entry IDs, in
+	// ascending order, so a restored analyzer still skips their duplicates
+	IDHashes []uint64 `json:"id_hashes,omitempty"`
+}
+
+// Stateful is an Analyzer whose state can be saved and restored
+type Stateful interface {
+	Analyzer
+	// Export returns the current state
+	Export() *State
+	// Import replaces the state with one returned by Export
+	Import(state *State) error
+}
+
+var (
+	_ Stateful = (*LogAnalyzer)(nil)
+	_ Stateful = (*ActorAnalyzer)(nil)
+)
+
+// checkState returns an error if state cannot be restored
+func checkState(state *State) error {
+	if state.Version != StateVersion {
+		return fmt.Errorf("unsupported analyzer state version %d (expected %d)", state.Version, StateVersion)
+	}
+	if state.Summary == nil {
+		return fmt.Errorf("analyzer state has no summary")
+	}
+	return nil
+}
+
+// Export returns the analyzer's current state
+func (a *LogAnalyzer) Export() *State {
+	return &State{
+		Version:  StateVersion,
+		Summary:  a.GetSummary(),
+		IDHashes: a.processedIDs.hashes(),
+	}
+}
+
+// Import replaces the analyzer's state with one returned by Export. It must
+// not be called while entries are being processed.
+func (a *LogAnalyzer) Import(state *State) error {
+	if err := checkState(state); err != nil {
+		return err
+	}
+
+	restored := NewLogAnalyzer(WithCounters(a.counters...))
+	restored.Merge(state.Summary)
+	for _, h := range state.IDHashes {
+		restored.processedIDs.addHash(h)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.agg = restored.agg
+	a.processedIDs = restored.processedIDs
+	return nil
+}
+
+// MergeState adds the counts and entry IDs of a state exported by another
+// analyzer, such as one that processed part of the same input. Entries
+// counted by both are counted twice.
+func (a *LogAnalyzer) MergeState(state *State) error {
+	if err := checkState(state); err != nil {
+		return err
+	}
+	a.Merge(state.Summary)
+	for _, h := range state.IDHashes {
+		a.processedIDs.addHash(h)
+	}
+	return nil
+}
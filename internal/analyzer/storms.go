@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/release"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// DefaultStormThreshold is the number of repeats per minute above which a
+// message counts as a storm
+const DefaultStormThreshold = 100
+
+// stormLateness is how many minutes behind the latest one of its stream an
+// entry may arrive and still be counted with the rest of its minute. Older
+// minutes below the threshold are dropped to bound memory.
+const stormLateness = 10
+
+// Storm is a message pattern that exceeded the storm threshold
+type Storm struct {
+	Pattern string
+	Service string
+	// First and Last bound the minutes in which the threshold was exceeded
+	First time.Time
+	Last  time.Time
+	// Count is the number of entries in those minutes
+	Count int
+	// PeakPerMinute is the highest count in a single minute
+	PeakPerMinute int
+	// After is the latest release before the storm began, if a timeline
+	// was set
+	After *release.Release
+}
+
+// stormKey identifies a message stream
+type stormKey struct {
+	service string
+	pattern string
+}
+
+// minuteBucket counts one pattern's entries in one minute
+type minuteBucket struct {
+	count       int
+	first, last time.Time
+}
+
+// stormStream holds the minutes of one message stream that may still storm,
+// or did
+type stormStream struct {
+	minutes map[int64]*minuteBucket
+	latest  int64
+}
+
+// StormSection detects message storms: the same normalized message from
+// one service repeated more than a threshold number of times in a minute
+type StormSection struct {
+	threshold int
+	releases  *release.Timeline
+	form      textnorm.Form
+
+	mu      sync.Mutex
+	streams map[stormKey]*stormStream
+}
+
+// NewStormSection creates a storm detector for the given repeats per minute
+func NewStormSection(threshold int) *StormSection {
+	return &StormSection{threshold: threshold, streams: make(map[stormKey]*stormStream)}
+}
+
+func (s *StormSection) Name() string {
+	return fmt.Sprintf("Message Storms (over %d/min)", s.threshold)
+}
+
+// SetReleases has each storm name the release it began after
+func (s *StormSection) SetReleases(timeline *release.Timeline) {
+	s.releases = timeline
+}
+
+// SetNormalization normalizes messages with form before their pattern is
+// taken
+func (s *StormSection) SetNormalization(form textnorm.Form) {
+	s.form = form
+}
+
+func (s *StormSection) Observe(entry models.LogEntry) {
+	if entry.Timestamp.IsZero() {
+		return
+	}
+	key := stormKey{service: entry.Service, pattern: NormalizedPattern(entry.Message, s.form)}
+	minute := entry.Timestamp.Unix() / 60
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream, ok := s.streams[key]
+	if !ok {
+		if len(s.streams) >= maxPatterns {
+			return
+		}
+		stream = &stormStream{minutes: make(map[int64]*minuteBucket), latest: minute}
+		s.streams[key] = stream
+	}
+	if minute > stream.latest {
+		stream.latest = minute
+		s.prune(stream)
+	}
+	b, ok := stream.minutes[minute]
+	if !ok {
+		b = &minuteBucket{first: entry.Timestamp, last: entry.Timestamp}
+		stream.minutes[minute] = b
+	}
+	b.count++
+	if entry.Timestamp.Before(b.first) {
+		b.first = entry.Timestamp
+	}
+	if entry.Timestamp.After(b.last) {
+		b.last = entry.Timestamp
+	}
+}
+
+// prune drops the minutes of a stream more than stormLateness behind its
+// latest that stayed below the threshold. An entry arriving later still
+// starts its minute over, so heavily out of order input may miss a storm.
+func (s *StormSection) prune(stream *stormStream) {
+	for minute, b := range stream.minutes {
+		if minute < stream.latest-stormLateness && b.count <= s.threshold {
+			delete(stream.minutes, minute)
+		}
+	}
+}
+
+// Storms returns the detected storms, largest first
+func (s *StormSection) Storms() []Storm {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var storms []Storm
+	for key, stream := range s.streams {
+		storm := Storm{Pattern: key.pattern, Service: key.service}
+		for _, b := range stream.minutes {
+			if b.count <= s.threshold {
+				continue
+			}
+			storm.Count += b.count
+			if b.count > storm.PeakPerMinute {
+				storm.PeakPerMinute = b.count
+			}
+			if storm.First.IsZero() || b.first.Before(storm.First) {
+				storm.First = b.first
+			}
+			if b.last.After(storm.Last) {
+				storm.Last = b.last
+			}
+		}
+		if storm.Count > 0 {
+			storm.After = releaseBefore(s.releases, storm.Service, storm.First)
+			storms = append(storms, storm)
+		}
+	}
+
+	sort.Slice(storms, func(i, j int) bool {
+		if storms[i].Count != storms[j].Count {
+			return storms[i].Count > storms[j].Count
+		}
+		return storms[i].First.Before(storms[j].First)
+	})
+	return storms
+}
+
+func (s *StormSection) WriteText(w io.Writer) error {
+	for _, storm := range s.Storms() {
+		_, err := fmt.Fprintf(w, "  %s: %q repeated %d times (peak %d/min) from %s to %s%s\n",
+			storm.Service, storm.Pattern, storm.Count, storm.PeakPerMinute,
+			storm.First.Format("2006-01-02 15:04:05"), storm.Last.Format("2006-01-02 15:04:05"), afterRelease(storm.After))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const testSchema = `{
+	"type": "record",
+	"name": "LogRecord",
+	"namespace": "com.example.logs",
+	"fields": [
+		{"name": "id", "type": "string"},
+		{"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["DEBUG", "INFO", "WARNING", "ERROR", "FATAL"]}},
+		{"name": "meta", "type": {"type": "record", "name": "Meta", "fields": [
+			{"name": "service", "type": "string"},
+			{"name": "tags", "type": {"type": "array", "items": "string"}}
+		]}},
+		{"name": "message", "type": ["null", "string"]}
+	]
+}`
+
+// appendLong appends a zig-zag varint
+func appendLong(buf []byte, n int64) []byte {
+	return binary.AppendUvarint(buf, uint64((n<<1)^(n>>63)))
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = appendLong(buf, int64(len(s)))
+	return append(buf, s...)
+}
+
+func encodeTestRecord(schemaID uint32) []byte {
+	msg := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(msg[1:], schemaID)
+
+	msg = appendString(msg, "abc-1")
+	msg = appendLong(msg, time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC).UnixMilli())
+	msg = appendLong(msg, 3) // ERROR
+	msg = appendString(msg, "api")
+	msg = appendLong(msg, 2) // tags block of two
+	msg = appendString(msg, "a")
+	msg = appendString(msg, "b")
+	msg = appendLong(msg, 0) // end of tags
+	msg = appendLong(msg, 1) // union branch: string
+	msg = appendString(msg, "Connection refused")
+	return msg
+}
+
+func TestParseSchema(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	if schema.Name != "com.example.logs.LogRecord" {
+		t.Errorf("Expected qualified record name, got %s", schema.Name)
+	}
+	if len(schema.Fields) != 5 {
+		t.Fatalf("Expected 5 fields, got %d", len(schema.Fields))
+	}
+	if schema.Fields[4].Type.Type != "union" || len(schema.Fields[4].Type.Branches) != 2 {
+		t.Errorf("Expected message to be a two-branch union, got %+v", schema.Fields[4].Type)
+	}
+
+	if _, err := ParseSchema([]byte(`{"type": "record", "name": "R", "fields": [{"name": "x", "type": "Missing"}]}`)); err == nil {
+		t.Error("Expected an error for an unknown named type")
+	}
+}
+
+func TestMessageDecoder(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/schemas/ids/42" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"schema": testSchema})
+	}))
+	defer server.Close()
+
+	mapping := DefaultFieldMapping()
+	mapping.Service = "meta.service"
+	decoder := NewMessageDecoder(NewRegistry(server.URL), mapping)
+
+	for i := 0; i < 2; i++ {
+		entry, err := decoder.Decode(encodeTestRecord(42))
+		if err != nil {
+			t.Fatalf("Failed to decode message: %v", err)
+		}
+
+		expected := models.LogEntry{
+			ID:        "abc-1",
+			Timestamp: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC),
+			Level:     models.ERROR,
+			Service:   "api",
+			Message:   "Connection refused",
+		}
+		if !reflect.DeepEqual(entry, expected) {
+			t.Errorf("Expected %+v, got %+v", expected, entry)
+		}
+	}
+
+	// The schema should only be fetched once
+	if requests != 1 {
+		t.Errorf("Expected 1 registry request, got %d", requests)
+	}
+
+	if _, err := decoder.Decode(encodeTestRecord(7)); err == nil {
+		t.Error("Expected an error for an unknown schema ID")
+	}
+	if _, err := decoder.Decode([]byte("plain text")); err == nil {
+		t.Error("Expected an error for a message without the magic byte")
+	}
+	if _, err := decoder.Decode(encodeTestRecord(42)[:12]); err == nil {
+		t.Error("Expected an error for a truncated message")
+	}
+}
+
+func TestDecodeCraftedLengths(t *testing.T) {
+	str := &Schema{Type: "string"}
+	// A string length near the largest long must not overflow the bounds check
+	if _, err := Decode(str, appendLong(nil, math.MaxInt64-1)); err == nil {
+		t.Error("Expected an error for a string longer than the input")
+	}
+	if _, err := Decode(str, appendLong(nil, -3)); err == nil {
+		t.Error("Expected an error for a negative length")
+	}
+
+	array := &Schema{Type: "array", Items: &Schema{Type: "null"}}
+	if _, err := Decode(array, appendLong(nil, math.MaxInt64)); err == nil {
+		t.Error("Expected an error for a block of more items than bytes left")
+	}
+}
@@ -0,0 +1,214 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// errShortBuffer is returned when a value extends past the end of the input
+var errShortBuffer = errors.New("unexpected end of avro data")
+
+// Decode decodes a single datum written with the given schema using the Avro
+// binary encoding. Records decode to map[string]interface{}, arrays to
+// []interface{}, maps to map[string]interface{}, enums to their symbol and
+// timestamp logical types to time.Time.
+func Decode(schema *Schema, data []byte) (interface{}, error) {
+	d := &decoder{buf: data}
+	return d.value(schema)
+}
+
+// decoder walks the binary encoding of a datum
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *decoder) value(s *Schema) (interface{}, error) {
+	switch s.Type {
+	case "null":
+		return nil, nil
+	case "boolean":
+		b, err := d.bytesN(1)
+		if err != nil {
+			return nil, err
+		}
+		return b[0] != 0, nil
+	case "int":
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		return int32(n), nil
+	case "long":
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		return logicalLong(s.LogicalType, n), nil
+	case "float":
+		b, err := d.bytesN(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "double":
+		b, err := d.bytesN(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "bytes":
+		return d.bytes()
+	case "string":
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case "fixed":
+		return d.bytesN(s.Size)
+	case "enum":
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || int(n) >= len(s.Symbols) {
+			return nil, fmt.Errorf("enum %s index %d out of range", s.Name, n)
+		}
+		return s.Symbols[n], nil
+	case "union":
+		n, err := d.long()
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 || int(n) >= len(s.Branches) {
+			return nil, fmt.Errorf("union index %d out of range", n)
+		}
+		return d.value(s.Branches[n])
+	case "record":
+		record := make(map[string]interface{}, len(s.Fields))
+		for _, f := range s.Fields {
+			v, err := d.value(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			record[f.Name] = v
+		}
+		return record, nil
+	case "array":
+		var items []interface{}
+		err := d.blocks(func() error {
+			v, err := d.value(s.Items)
+			if err != nil {
+				return err
+			}
+			items = append(items, v)
+			return nil
+		})
+		return items, err
+	case "map":
+		values := make(map[string]interface{})
+		err := d.blocks(func() error {
+			key, err := d.bytes()
+			if err != nil {
+				return err
+			}
+			v, err := d.value(s.Values)
+			if err != nil {
+				return err
+			}
+			values[string(key)] = v
+			return nil
+		})
+		return values, err
+	default:
+		return nil, fmt.Errorf("unsupported avro type %q", s.Type)
+	}
+}
+
+// blocks reads the block-encoded items of an array or map
+func (d *decoder) blocks(item func() error) error {
+	for {
+		count, err := d.long()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return nil
+		}
+		if count < 0 {
+			// A negative count is followed by the block size in bytes
+			count = -count
+			if _, err := d.long(); err != nil {
+				return err
+			}
+		}
+		// Bound the work a crafted count causes by the input left; only
+		// nulls take no bytes, and no schema repeats them that often
+		if count < 0 || count > int64(len(d.buf)-d.pos) {
+			return fmt.Errorf("block of %d items exceeds the remaining %d bytes", count, len(d.buf)-d.pos)
+		}
+		for i := int64(0); i < count; i++ {
+			if err := item(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// long reads a zig-zag encoded variable-length integer
+func (d *decoder) long() (int64, error) {
+	var u uint64
+	var shift uint
+	for {
+		if d.pos >= len(d.buf) {
+			return 0, errShortBuffer
+		}
+		b := d.buf[d.pos]
+		d.pos++
+		u |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		shift += 7
+		if shift > 63 {
+			return 0, errors.New("avro varint overflows a long")
+		}
+	}
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.long()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("negative length %d", n)
+	}
+	return d.bytesN(int(n))
+}
+
+func (d *decoder) bytesN(n int) ([]byte, error) {
+	// Lengths come from the input, so d.pos+n may overflow
+	if n < 0 || n > len(d.buf)-d.pos {
+		return nil, errShortBuffer
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// logicalLong converts longs annotated with a timestamp logical type
+func logicalLong(logicalType string, n int64) interface{} {
+	switch logicalType {
+	case "timestamp-millis", "local-timestamp-millis":
+		return time.UnixMilli(n).UTC()
+	case "timestamp-micros", "local-timestamp-micros":
+		return time.UnixMicro(n).UTC()
+	}
+	return n
+}
@@ -0,0 +1,131 @@
+package avro
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// magicByte prefixes every message in the Confluent wire format
+const magicByte = 0
+
+// FieldMapping names the record fields that populate each LogEntry field.
+// Nested fields are addressed with dots, e.g. "meta.service".
+type FieldMapping struct {
+	ID        string
+	Timestamp string
+	Level     string
+	Service   string
+	Message   string
+}
+
+// DefaultFieldMapping maps record fields named after the LogEntry JSON keys
+func DefaultFieldMapping() FieldMapping {
+	return FieldMapping{
+		ID:        "id",
+		Timestamp: "timestamp",
+		Level:     "level",
+		Service:   "service",
+		Message:   "message",
+	}
+}
+
+// MessageDecoder turns Confluent-framed Avro messages into log entries
+type MessageDecoder struct {
+	registry *Registry
+	mapping  FieldMapping
+}
+
+// NewMessageDecoder creates a decoder that resolves writer schemas through registry
+func NewMessageDecoder(registry *Registry, mapping FieldMapping) *MessageDecoder {
+	return &MessageDecoder{
+		registry: registry,
+		mapping:  mapping,
+	}
+}
+
+// Decode decodes a message consisting of the magic byte, a 4-byte big-endian
+// schema ID and the Avro-encoded record
+func (d *MessageDecoder) Decode(msg []byte) (models.LogEntry, error) {
+	if len(msg) < 5 || msg[0] != magicByte {
+		return models.LogEntry{}, fmt.Errorf("message is not in the schema registry wire format")
+	}
+
+	schema, err := d.registry.Schema(binary.BigEndian.Uint32(msg[1:5]))
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+
+	value, err := Decode(schema, msg[5:])
+	if err != nil {
+		return models.LogEntry{}, fmt.Errorf("failed to decode avro record: %w", err)
+	}
+
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return models.LogEntry{}, fmt.Errorf("avro message is a %s, not a record", schema.Type)
+	}
+	return d.toEntry(record)
+}
+
+// toEntry maps a decoded record onto a LogEntry
+func (d *MessageDecoder) toEntry(record map[string]interface{}) (models.LogEntry, error) {
+	entry := models.LogEntry{
+		ID:      stringValue(lookup(record, d.mapping.ID)),
+		Level:   models.LogLevel(strings.ToUpper(stringValue(lookup(record, d.mapping.Level)))),
+		Service: stringValue(lookup(record, d.mapping.Service)),
+		Message: stringValue(lookup(record, d.mapping.Message)),
+	}
+
+	switch ts := lookup(record, d.mapping.Timestamp).(type) {
+	case nil:
+	case time.Time:
+		entry.Timestamp = ts
+	case int64:
+		// Plain longs without a logical type are taken as epoch milliseconds
+		entry.Timestamp = time.UnixMilli(ts).UTC()
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return models.LogEntry{}, fmt.Errorf("invalid timestamp %q: %w", ts, err)
+		}
+		entry.Timestamp = t
+	default:
+		return models.LogEntry{}, fmt.Errorf("unsupported timestamp value %v", ts)
+	}
+
+	return entry, nil
+}
+
+// lookup resolves a dotted field path within nested records
+func lookup(record map[string]interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	var value interface{} = record
+	for _, part := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[part]
+	}
+	return value
+}
+
+// stringValue renders scalar values as strings
+func stringValue(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	default:
+		return fmt.Sprint(s)
+	}
+}
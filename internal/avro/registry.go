@@ -0,0 +1,93 @@
+package avro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRegistryUnavailable marks failures to reach the schema registry, which
+// may succeed when retried
+var ErrRegistryUnavailable = errors.New("schema registry unavailable")
+
+// Registry fetches schemas by ID from a Confluent schema registry and caches
+// them, since schema IDs are immutable once assigned
+type Registry struct {
+	baseURL string
+	client  *http.Client
+
+	mu      sync.Mutex
+	schemas map[uint32]*Schema
+}
+
+// NewRegistry creates a client for the schema registry at baseURL.
+// Credentials may be supplied as userinfo in the URL.
+func NewRegistry(baseURL string) *Registry {
+	return &Registry{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		schemas: make(map[uint32]*Schema),
+	}
+}
+
+// Schema returns the schema registered under id
+func (r *Registry) Schema(id uint32) (*Schema, error) {
+	r.mu.Lock()
+	s, ok := r.schemas[id]
+	r.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	s, err := r.fetch(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.schemas[id] = s
+	r.mu.Unlock()
+	return s, nil
+}
+
+func (r *Registry) fetch(id uint32) (*Schema, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/schemas/ids/%d", r.baseURL, id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build registry request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schema %d: %w: %w", id, ErrRegistryUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("failed to fetch schema %d: %w: registry returned %s", id, ErrRegistryUnavailable, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch schema %d: registry returned %s", id, resp.Status)
+	}
+
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode registry response: %w", err)
+	}
+	if body.SchemaType != "" && body.SchemaType != "AVRO" {
+		return nil, fmt.Errorf("schema %d has unsupported type %s", id, body.SchemaType)
+	}
+
+	s, err := ParseSchema([]byte(body.Schema))
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,180 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Schema is a parsed Avro schema. Unions are represented with Type "union"
+// and their alternatives in Branches.
+type Schema struct {
+	Type        string
+	Name        string
+	LogicalType string
+	Fields      []Field
+	Items       *Schema
+	Values      *Schema
+	Symbols     []string
+	Size        int
+	Branches    []*Schema
+}
+
+// Field is a single field of a record schema
+type Field struct {
+	Name string
+	Type *Schema
+}
+
+var primitives = map[string]bool{
+	"null":    true,
+	"boolean": true,
+	"int":     true,
+	"long":    true,
+	"float":   true,
+	"double":  true,
+	"bytes":   true,
+	"string":  true,
+}
+
+// ParseSchema parses an Avro schema from its JSON representation
+func ParseSchema(data []byte) (*Schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse schema JSON: %w", err)
+	}
+	p := &schemaParser{named: make(map[string]*Schema)}
+	return p.parse(raw, "")
+}
+
+// schemaParser tracks named types so later parts of a schema can refer to them
+type schemaParser struct {
+	named map[string]*Schema
+}
+
+func (p *schemaParser) parse(raw interface{}, namespace string) (*Schema, error) {
+	switch v := raw.(type) {
+	case string:
+		if primitives[v] {
+			return &Schema{Type: v}, nil
+		}
+		if s, ok := p.named[qualify(v, namespace)]; ok {
+			return s, nil
+		}
+		if s, ok := p.named[v]; ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("unknown type %q", v)
+	case []interface{}:
+		union := &Schema{Type: "union"}
+		for _, branch := range v {
+			s, err := p.parse(branch, namespace)
+			if err != nil {
+				return nil, err
+			}
+			union.Branches = append(union.Branches, s)
+		}
+		return union, nil
+	case map[string]interface{}:
+		return p.parseComplex(v, namespace)
+	default:
+		return nil, fmt.Errorf("invalid schema element %v", raw)
+	}
+}
+
+func (p *schemaParser) parseComplex(obj map[string]interface{}, namespace string) (*Schema, error) {
+	typeName, _ := obj["type"].(string)
+	if typeName == "" {
+		// {"type": {...}} or {"type": [...]} wraps another schema
+		if inner, ok := obj["type"]; ok {
+			return p.parse(inner, namespace)
+		}
+		return nil, fmt.Errorf("schema object is missing a type")
+	}
+
+	s := &Schema{Type: typeName}
+	s.LogicalType, _ = obj["logicalType"].(string)
+
+	if ns, ok := obj["namespace"].(string); ok {
+		namespace = ns
+	}
+	if name, ok := obj["name"].(string); ok {
+		s.Name = qualify(name, namespace)
+	}
+
+	switch typeName {
+	case "record", "error":
+		s.Type = "record"
+		// Register before parsing fields so recursive references resolve
+		p.register(s)
+		fields, _ := obj["fields"].([]interface{})
+		for _, f := range fields {
+			fobj, ok := f.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid field in record %s", s.Name)
+			}
+			name, _ := fobj["name"].(string)
+			ftype, err := p.parse(fobj["type"], namespace)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", s.Name, name, err)
+			}
+			s.Fields = append(s.Fields, Field{Name: name, Type: ftype})
+		}
+	case "enum":
+		symbols, _ := obj["symbols"].([]interface{})
+		for _, sym := range symbols {
+			str, _ := sym.(string)
+			s.Symbols = append(s.Symbols, str)
+		}
+		p.register(s)
+	case "fixed":
+		size, _ := obj["size"].(float64)
+		s.Size = int(size)
+		p.register(s)
+	case "array":
+		items, err := p.parse(obj["items"], namespace)
+		if err != nil {
+			return nil, fmt.Errorf("array items: %w", err)
+		}
+		s.Items = items
+	case "map":
+		values, err := p.parse(obj["values"], namespace)
+		if err != nil {
+			return nil, fmt.Errorf("map values: %w", err)
+		}
+		s.Values = values
+	default:
+		if !primitives[typeName] {
+			// A named reference written in object form
+			return p.parse(typeName, namespace)
+		}
+	}
+
+	return s, nil
+}
+
+func (p *schemaParser) register(s *Schema) {
+	if s.Name == "" {
+		return
+	}
+	p.named[s.Name] = s
+	// Also allow references by the unqualified name
+	for i := len(s.Name) - 1; i >= 0; i-- {
+		if s.Name[i] == '.' {
+			p.named[s.Name[i+1:]] = s
+			break
+		}
+	}
+}
+
+// qualify prefixes a name with its namespace unless it is already qualified
+func qualify(name, namespace string) string {
+	if namespace == "" {
+		return name
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] == '.' {
+			return name
+		}
+	}
+	return namespace + "." + name
+}
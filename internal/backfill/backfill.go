@@ -0,0 +1,291 @@
+package backfill
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/partition"
+	"github.com/interview/junior-go-challenge/internal/processor"
+	"github.com/interview/junior-go-challenge/internal/sink"
+)
+
+// ErrInterrupted is returned when a backfill is stopped before finishing.
+// Running it again with the same output directory resumes where it left off.
+var ErrInterrupted = errors.New("backfill interrupted")
+
+// Config describes a backfill
+type Config struct {
+	InputDir  string
+	Pattern   string
+	Parser    parser.Parser
+	OutputDir string
+	// Outputs are sink URLs every partition's entries are forwarded to
+	Outputs []string
+}
+
+// Backfill processes an archive one day at a time. The input is first split
+// into per-day partition files under the output directory, then each day is
+// analyzed in chronological order and its summary written next to it.
+// Progress is recorded after every step so an interrupted backfill resumes
+// with the first unfinished day.
+type Backfill struct {
+	cfg Config
+
+	mu      sync.Mutex
+	stopped bool
+	stop    chan struct{}
+	current *processor.LogProcessor
+}
+
+// New creates a backfill
+func New(cfg Config) *Backfill {
+	return &Backfill{cfg: cfg, stop: make(chan struct{})}
+}
+
+// Stop interrupts the backfill after the entry in progress
+func (b *Backfill) Stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		return
+	}
+	b.stopped = true
+	close(b.stop)
+	if b.current != nil {
+		b.current.Stop()
+	}
+}
+
+func (b *Backfill) isStopped() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stopped
+}
+
+// Run performs or resumes the backfill, returning the combined summary of
+// all partitions. progress, if not nil, is called after each finished day.
+func (b *Backfill) Run(progress func(day string, summary *models.LogSummary)) (*models.LogSummary, error) {
+	if err := os.MkdirAll(b.summaryDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	st, err := loadState(b.statePath())
+	if err != nil {
+		return nil, err
+	}
+	input := filepath.Join(b.cfg.InputDir, b.cfg.Pattern)
+	if st.Input == "" {
+		st.Input = input
+	} else if st.Input != input {
+		return nil, fmt.Errorf("%s holds a backfill of %s, not %s", b.cfg.OutputDir, st.Input, input)
+	}
+
+	if !st.Split {
+		if err := b.split(); err != nil {
+			return nil, err
+		}
+		st.Split = true
+		if err := st.save(b.statePath()); err != nil {
+			return nil, err
+		}
+	}
+
+	days, err := b.partitions()
+	if err != nil {
+		return nil, err
+	}
+	for _, day := range days {
+		if st.done(day) {
+			continue
+		}
+		summary, err := b.processDay(day)
+		if err != nil {
+			return nil, err
+		}
+		if err := partition.WriteJSON(b.summaryPath(day), summary); err != nil {
+			return nil, err
+		}
+		st.Completed = append(st.Completed, day)
+		if err := st.save(b.statePath()); err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(day, summary)
+		}
+	}
+
+	return b.combine(days)
+}
+
+// split writes every unique input entry to the partition file of its day
+func (b *Backfill) split() error {
+	// Discard partitions left over from an interrupted split
+	if err := os.RemoveAll(b.partitionDir()); err != nil {
+		return fmt.Errorf("failed to clear partitions: %w", err)
+	}
+	if err := os.MkdirAll(b.partitionDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create partition directory: %w", err)
+	}
+
+	writer := newPartitionWriter(b.partitionDir())
+	proc := processor.NewLogProcessor(b.cfg.InputDir,
+		processor.WithParser(b.cfg.Parser),
+		processor.WithPattern(b.cfg.Pattern),
+		processor.WithSink(writer),
+	)
+
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		writer.Close()
+		return ErrInterrupted
+	}
+	b.current = proc
+	b.mu.Unlock()
+
+	err := proc.Start()
+
+	b.mu.Lock()
+	b.current = nil
+	b.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("failed to partition input: %w", err)
+	}
+	if b.isStopped() {
+		return ErrInterrupted
+	}
+	return nil
+}
+
+// partitions lists the partition days in chronological order, with undated
+// entries last
+func (b *Backfill) partitions() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(b.partitionDir(), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	var days []string
+	undated := false
+	for _, file := range files {
+		day := strings.TrimSuffix(filepath.Base(file), ".json")
+		if day == partition.Undated {
+			undated = true
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	if undated {
+		days = append(days, partition.Undated)
+	}
+	return days, nil
+}
+
+// processDay analyzes one partition and forwards its entries to the outputs
+func (b *Backfill) processDay(day string) (*models.LogSummary, error) {
+	file, err := os.Open(b.partitionPath(day))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open partition: %w", err)
+	}
+	defer file.Close()
+
+	var sinks []sink.Sink
+	closeSinks := func() error {
+		var firstErr error
+		for _, s := range sinks {
+			if err := s.Close(); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("failed to close sink: %w", err)
+			}
+		}
+		return firstErr
+	}
+	for _, output := range b.cfg.Outputs {
+		s, err := sink.Open(output)
+		if err != nil {
+			closeSinks()
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	dayAnalyzer := analyzer.NewLogAnalyzer()
+	failed := 0
+	err = parser.JSONParser{}.Parse(file, func(entry models.LogEntry) error {
+		select {
+		case <-b.stop:
+			return ErrInterrupted
+		default:
+		}
+		dayAnalyzer.Process(entry)
+		for _, s := range sinks {
+			if err := s.Write(entry); err != nil {
+				fmt.Printf("Error writing entry %s to sink: %v\n", entry.ID, err)
+				failed++
+			}
+		}
+		return nil
+	})
+	closeErr := closeSinks()
+	if err != nil {
+		if errors.Is(err, ErrInterrupted) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read partition %s: %w", day, err)
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	if failed > 0 {
+		// The day stays unfinished so the next run forwards it again
+		return nil, fmt.Errorf("failed to forward %d entries of %s", failed, day)
+	}
+	return dayAnalyzer.GetSummary(), nil
+}
+
+// combine adds up the summaries of all partitions
+func (b *Backfill) combine(days []string) (*models.LogSummary, error) {
+	total := analyzer.NewLogAnalyzer()
+	for _, day := range days {
+		data, err := os.ReadFile(b.summaryPath(day))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read summary: %w", err)
+		}
+		var summary models.LogSummary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return nil, fmt.Errorf("failed to decode summary %s: %w", day, err)
+		}
+		total.Merge(&summary)
+	}
+	return total.GetSummary(), nil
+}
+
+func (b *Backfill) statePath() string {
+	return filepath.Join(b.cfg.OutputDir, "state.json")
+}
+
+func (b *Backfill) partitionDir() string {
+	return filepath.Join(b.cfg.OutputDir, "partitions")
+}
+
+func (b *Backfill) partitionPath(day string) string {
+	return filepath.Join(b.partitionDir(), day+".json")
+}
+
+func (b *Backfill) summaryDir() string {
+	return filepath.Join(b.cfg.OutputDir, "summaries")
+}
+
+func (b *Backfill) summaryPath(day string) string {
+	return filepath.Join(b.summaryDir(), day+".json")
+}
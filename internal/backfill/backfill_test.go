@@ -0,0 +1,121 @@
+package backfill
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/partition"
+)
+
+func writeArchive(t *testing.T, dir string) {
+	file, err := os.Create(filepath.Join(dir, "archive.json"))
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	// Entries are deliberately out of order and include a duplicate
+	entries := []models.LogEntry{
+		{ID: "3", Timestamp: time.Date(2023, 1, 3, 9, 0, 0, 0, time.UTC), Level: models.ERROR, Service: "db"},
+		{ID: "1", Timestamp: time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC), Level: models.INFO, Service: "api"},
+		{ID: "2", Timestamp: time.Date(2023, 1, 1, 23, 59, 0, 0, time.UTC), Level: models.WARNING, Service: "api"},
+		{ID: "1", Timestamp: time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC), Level: models.INFO, Service: "api"},
+		{ID: "4", Level: models.DEBUG, Service: "cron"},
+	}
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			t.Fatalf("Failed to encode entry: %v", err)
+		}
+	}
+}
+
+func TestBackfillPartitionsAndResumes(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	writeArchive(t, inputDir)
+
+	cfg := Config{InputDir: inputDir, Pattern: "*.json", Parser: parser.JSONParser{}, OutputDir: outputDir}
+
+	var days []string
+	summary, err := New(cfg).Run(func(day string, s *models.LogSummary) {
+		days = append(days, day)
+	})
+	if err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+
+	// Days are processed chronologically, with undated entries last
+	expected := []string{"2023-01-01", "2023-01-03", partition.Undated}
+	if len(days) != len(expected) {
+		t.Fatalf("Expected partitions %v, got %v", expected, days)
+	}
+	for i := range expected {
+		if days[i] != expected[i] {
+			t.Errorf("Expected partition %d to be %s, got %s", i, expected[i], days[i])
+		}
+	}
+
+	if summary.TotalEntries != 4 {
+		t.Errorf("Expected 4 entries in total, got %d", summary.TotalEntries)
+	}
+	if summary.ByService["api"] != 2 {
+		t.Errorf("Expected 2 api entries, got %d", summary.ByService["api"])
+	}
+
+	// Simulate an interruption before the last two days finished
+	st, err := loadState(filepath.Join(outputDir, "state.json"))
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	st.Completed = st.Completed[:1]
+	if err := st.save(filepath.Join(outputDir, "state.json")); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+
+	days = nil
+	summary, err = New(cfg).Run(func(day string, s *models.LogSummary) {
+		days = append(days, day)
+	})
+	if err != nil {
+		t.Fatalf("Resumed backfill failed: %v", err)
+	}
+	if len(days) != 2 || days[0] != "2023-01-03" {
+		t.Errorf("Expected only the unfinished days to be processed, got %v", days)
+	}
+	if summary.TotalEntries != 4 {
+		t.Errorf("Expected 4 entries after resuming, got %d", summary.TotalEntries)
+	}
+}
+
+func TestBackfillRejectsOtherInput(t *testing.T) {
+	inputDir := t.TempDir()
+	outputDir := t.TempDir()
+	writeArchive(t, inputDir)
+
+	cfg := Config{InputDir: inputDir, Pattern: "*.json", Parser: parser.JSONParser{}, OutputDir: outputDir}
+	if _, err := New(cfg).Run(nil); err != nil {
+		t.Fatalf("Backfill failed: %v", err)
+	}
+
+	cfg.Pattern = "*.log"
+	if _, err := New(cfg).Run(nil); err == nil {
+		t.Error("Expected an error when resuming with a different input")
+	}
+}
+
+func TestBackfillStoppedBeforeStart(t *testing.T) {
+	inputDir := t.TempDir()
+	writeArchive(t, inputDir)
+
+	b := New(Config{InputDir: inputDir, Pattern: "*.json", Parser: parser.JSONParser{}, OutputDir: t.TempDir()})
+	b.Stop()
+	if _, err := b.Run(nil); err != ErrInterrupted {
+		t.Errorf("Expected ErrInterrupted, got %v", err)
+	}
+}
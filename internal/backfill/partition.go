@@ -0,0 +1,71 @@
+package backfill
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/partition"
+)
+
+// partitionWriter is a sink appending each entry to the NDJSON file of the
+// UTC day it was logged on
+type partitionWriter struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]*partitionFile
+}
+
+type partitionFile struct {
+	file *os.File
+	buf  *bufio.Writer
+}
+
+func newPartitionWriter(dir string) *partitionWriter {
+	return &partitionWriter{dir: dir, files: make(map[string]*partitionFile)}
+}
+
+func (w *partitionWriter) Write(entry models.LogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	day := partition.Day.Key(entry.Timestamp)
+	pf, ok := w.files[day]
+	if !ok {
+		file, err := os.Create(filepath.Join(w.dir, day+".json"))
+		if err != nil {
+			return fmt.Errorf("failed to create partition: %w", err)
+		}
+		pf = &partitionFile{file: file, buf: bufio.NewWriter(file)}
+		w.files[day] = pf
+	}
+	pf.buf.Write(data)
+	return pf.buf.WriteByte('\n')
+}
+
+func (w *partitionWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var firstErr error
+	for day, pf := range w.files {
+		if err := pf.buf.Flush(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to write partition %s: %w", day, err)
+		}
+		if err := pf.file.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close partition %s: %w", day, err)
+		}
+	}
+	w.files = nil
+	return firstErr
+}
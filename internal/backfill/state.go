@@ -0,0 +1,48 @@
+package backfill
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/interview/junior-go-challenge/internal/partition"
+)
+
+// state records backfill progress between runs
+type state struct {
+	// Input is the glob the backfill was started with
+	Input string `json:"input"`
+	// Split is set once every entry has been written to its partition
+	Split bool `json:"split"`
+	// Completed lists the partitions whose summaries have been written
+	Completed []string `json:"completed"`
+}
+
+// loadState reads the state file, returning an empty state if there is none
+func loadState(path string) (*state, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &state{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backfill state: %w", err)
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("failed to decode backfill state: %w", err)
+	}
+	return &st, nil
+}
+
+func (st *state) save(path string) error {
+	return partition.WriteJSON(path, st)
+}
+
+func (st *state) done(day string) bool {
+	for _, d := range st.Completed {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
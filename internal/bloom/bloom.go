@@ -0,0 +1,162 @@
+// Package bloom implements a Bloom filter that is saved to a file between
+// runs, so a run can tell whether something was seen by earlier ones with
+// little memory or disk space. Keys are never reported missing once added,
+// but a key never added is reported present with a small probability.
+package bloom
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// magic starts every saved filter
+const magic = "BLM1"
+
+// headerSize is the size of the magic, hash count, key count and bit count
+const headerSize = len(magic) + 4 + 8 + 8
+
+// Filter is a Bloom filter. Has may be called concurrently, but not while
+// Add is.
+type Filter struct {
+	bits []uint64
+	m    uint64
+	k    uint32
+	// n counts the keys added, including repeated ones
+	n uint64
+}
+
+// New creates a filter sized for capacity keys, reporting a key never added
+// as present with about the probability falsePositive while it holds no
+// more keys than that
+func New(capacity int, falsePositive float64) *Filter {
+	n := float64(max(capacity, 1))
+	m := math.Ceil(-n * math.Log(falsePositive) / (math.Ln2 * math.Ln2))
+	k := math.Max(1, math.Round(m/n*math.Ln2))
+	words := (uint64(m) + 63) / 64
+	return &Filter{bits: make([]uint64, words), m: words * 64, k: uint32(k)}
+}
+
+// Load reads a filter saved with Save, or returns New(capacity,
+// falsePositive) if the file does not exist yet
+func Load(path string, capacity int, falsePositive float64) (*Filter, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(capacity, falsePositive), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filter: %w", err)
+	}
+	f := &Filter{}
+	if err := f.UnmarshalBinary(data); err != nil {
+		return nil, fmt.Errorf("failed to read filter %s: %w", filepath.Base(path), err)
+	}
+	return f, nil
+}
+
+// Save writes the filter to path, replacing the previous file only once the
+// new one is complete
+func (f *Filter) Save(path string) error {
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write filter: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write filter: %w", err)
+	}
+	return nil
+}
+
+// Add records key
+func (f *Filter) Add(key string) {
+	h1, h2 := hashes(key)
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.n++
+}
+
+// Has reports whether key may have been added. False means it certainly was
+// not.
+func (f *Filter) Has(key string) bool {
+	h1, h2 := hashes(key)
+	for i := uint32(0); i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Added returns the number of keys added over the life of the filter,
+// counting keys added repeatedly each time
+func (f *Filter) Added() uint64 {
+	return f.n
+}
+
+// FalsePositiveRate estimates the probability that Has reports a key never
+// added from the share of bits set
+func (f *Filter) FalsePositiveRate() float64 {
+	set := 0
+	for _, w := range f.bits {
+		for ; w != 0; w &= w - 1 {
+			set++
+		}
+	}
+	return math.Pow(float64(set)/float64(f.m), float64(f.k))
+}
+
+// MarshalBinary encodes the filter as the magic, the number of hashes, keys
+// added and bits, and the bits, all little endian
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, headerSize+len(f.bits)*8)
+	data = append(data, magic...)
+	data = binary.LittleEndian.AppendUint32(data, f.k)
+	data = binary.LittleEndian.AppendUint64(data, f.n)
+	data = binary.LittleEndian.AppendUint64(data, f.m)
+	for _, w := range f.bits {
+		data = binary.LittleEndian.AppendUint64(data, w)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a filter encoded by MarshalBinary
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return fmt.Errorf("not a saved filter")
+	}
+	data = data[len(magic):]
+	k := binary.LittleEndian.Uint32(data)
+	n := binary.LittleEndian.Uint64(data[4:])
+	m := binary.LittleEndian.Uint64(data[12:])
+	data = data[20:]
+	if k == 0 || m == 0 || m%64 != 0 || uint64(len(data)) != m/8 {
+		return fmt.Errorf("saved filter is truncated or corrupt")
+	}
+	bits := make([]uint64, m/64)
+	for i := range bits {
+		bits[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	f.bits, f.m, f.k, f.n = bits, m, k, n
+	return nil
+}
+
+// hashes returns two 64-bit hashes of key from its SHA-256, from which the
+// positions of its bits are derived. Faster hashes such as FNV spread keys
+// differing only in a suffix, like numbered ones, poorly.
+func hashes(key string) (uint64, uint64) {
+	sum := sha256.Sum256([]byte(key))
+	// A step of zero would set a single bit
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]) | 1
+}
@@ -0,0 +1,63 @@
+package bloom
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	f := New(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add(fmt.Sprintf("added-%d", i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.Has(fmt.Sprintf("added-%d", i)) {
+			t.Fatalf("Expected added-%d to be present", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.Has(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 300 {
+		t.Errorf("Expected about 1%% false positives, got %d of 10000", falsePositives)
+	}
+	if rate := f.FalsePositiveRate(); rate < 0.002 || rate > 0.03 {
+		t.Errorf("Expected an estimated false positive rate near 1%%, got %f", rate)
+	}
+}
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.bloom")
+	f, err := Load(path, 100, 0.001)
+	if err != nil {
+		t.Fatalf("Failed to create filter: %v", err)
+	}
+	if f.Has("api timeout") {
+		t.Error("Expected a new filter to be empty")
+	}
+	f.Add("api timeout")
+	if err := f.Save(path); err != nil {
+		t.Fatalf("Failed to save filter: %v", err)
+	}
+
+	loaded, err := Load(path, 100, 0.001)
+	if err != nil {
+		t.Fatalf("Failed to load filter: %v", err)
+	}
+	if !loaded.Has("api timeout") || loaded.Has("db deadlock") || loaded.Added() != 1 {
+		t.Errorf("Expected the saved key only, got %d keys", loaded.Added())
+	}
+
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, data[:len(data)-1], 0644); err != nil {
+		t.Fatalf("Failed to truncate filter: %v", err)
+	}
+	if _, err := Load(path, 100, 0.001); err == nil {
+		t.Error("Expected an error for a truncated filter")
+	}
+}
@@ -0,0 +1,164 @@
+// Package cgroup reads the CPU limit of the container the process runs in,
+// so defaults can be sized for the CPUs actually available rather than those
+// of the host.
+package cgroup
+
+import (
+	"bufio"
+	"io/fs"
+	"math"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// CPUQuota returns the number of CPUs the process may use under its cgroup
+// CPU bandwidth limit, e.g. 0.5 for a pod limited to 500m, or false if it is
+// unlimited or cannot be read
+func CPUQuota() (float64, bool) {
+	return cpuQuota(os.DirFS("/"))
+}
+
+// AvailableCPUs returns the CPUs the process can keep busy: GOMAXPROCS, or
+// the CPU quota if that is lower
+func AvailableCPUs() float64 {
+	cpus := float64(runtime.GOMAXPROCS(0))
+	if quota, ok := CPUQuota(); ok && quota < cpus {
+		return quota
+	}
+	return cpus
+}
+
+// SetMaxProcs lowers GOMAXPROCS to the CPU quota rounded up, unless the
+// GOMAXPROCS environment variable sets it. Without this, a process limited
+// to half a CPU on a large host runs a thread per host CPU and is throttled.
+// It returns the new value and whether it changed.
+func SetMaxProcs() (int, bool) {
+	current := runtime.GOMAXPROCS(0)
+	if os.Getenv("GOMAXPROCS") != "" {
+		return current, false
+	}
+	quota, ok := CPUQuota()
+	if !ok {
+		return current, false
+	}
+	procs := int(math.Ceil(quota))
+	if procs < 1 {
+		procs = 1
+	}
+	if procs >= current {
+		return current, false
+	}
+	runtime.GOMAXPROCS(procs)
+	return procs, true
+}
+
+// cpuQuota reads the quota from the cgroup filesystems in fsys, which is
+// rooted at /
+func cpuQuota(fsys fs.FS) (float64, bool) {
+	file, err := fsys.Open("proc/self/cgroup")
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	// Lines are ID:CONTROLLERS:PATH; cgroup v2 has a single line 0::PATH
+	var v1Path, v1Mount, v2Path string
+	v2 := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2, v2Path = true, parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "cpu" {
+				v1Path, v1Mount = parts[2], parts[1]
+			}
+		}
+	}
+
+	if v1Mount != "" {
+		for _, mount := range []string{v1Mount, "cpu", "cpu,cpuacct"} {
+			if quota, ok := v1Quota(fsys, path.Join("sys/fs/cgroup", mount), v1Path); ok {
+				return quota, true
+			}
+		}
+	}
+	if v2 {
+		return v2Quota(fsys, "sys/fs/cgroup", v2Path)
+	}
+	return 0, false
+}
+
+// v2Quota returns the lowest cpu.max limit of the cgroup at dir and its
+// ancestors up to root. Inside a container the cgroup path may not exist
+// under the mounted root, which is then the container's own cgroup.
+func v2Quota(fsys fs.FS, root, dir string) (float64, bool) {
+	quota, found := math.Inf(1), false
+	for _, d := range ancestors(root, dir) {
+		data, err := fs.ReadFile(fsys, path.Join(d, "cpu.max"))
+		if err != nil {
+			continue
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) != 2 || fields[0] == "max" {
+			continue
+		}
+		limit, err1 := strconv.ParseFloat(fields[0], 64)
+		period, err2 := strconv.ParseFloat(fields[1], 64)
+		if err1 != nil || err2 != nil || limit <= 0 || period <= 0 {
+			continue
+		}
+		if q := limit / period; q < quota {
+			quota, found = q, true
+		}
+	}
+	return quota, found
+}
+
+// v1Quota returns the cpu.cfs_quota_us limit of the cgroup at dir under
+// mount, or of the mount itself if the cgroup is not visible there
+func v1Quota(fsys fs.FS, mount, dir string) (float64, bool) {
+	for _, d := range []string{path.Join(mount, dir), mount} {
+		limit, err1 := readInt(fsys, path.Join(d, "cpu.cfs_quota_us"))
+		period, err2 := readInt(fsys, path.Join(d, "cpu.cfs_period_us"))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if limit <= 0 || period <= 0 {
+			// -1 means unlimited
+			return 0, false
+		}
+		return float64(limit) / float64(period), true
+	}
+	return 0, false
+}
+
+// ancestors returns root joined with dir and each of its parents, ending
+// with root itself
+func ancestors(root, dir string) []string {
+	dir = path.Clean("/" + dir)
+	var dirs []string
+	for {
+		dirs = append(dirs, path.Join(root, dir))
+		if dir == "/" {
+			return dirs
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+func readInt(fsys fs.FS, name string) (int64, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,76 @@
+package cgroup
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestCPUQuota(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		quota float64
+		ok    bool
+	}{
+		{
+			name: "v2 limit",
+			files: fstest.MapFS{
+				"proc/self/cgroup":                        {Data: []byte("0::/kubepods/pod1/app\n")},
+				"sys/fs/cgroup/kubepods/pod1/app/cpu.max": {Data: []byte("50000 100000\n")},
+			},
+			quota: 0.5, ok: true,
+		},
+		{
+			name: "v2 limit of a parent",
+			files: fstest.MapFS{
+				"proc/self/cgroup":                        {Data: []byte("0::/kubepods/pod1/app\n")},
+				"sys/fs/cgroup/kubepods/pod1/app/cpu.max": {Data: []byte("max 100000\n")},
+				"sys/fs/cgroup/kubepods/pod1/cpu.max":     {Data: []byte("200000 100000\n")},
+				"sys/fs/cgroup/kubepods/cpu.max":          {Data: []byte("800000 100000\n")},
+			},
+			quota: 2, ok: true,
+		},
+		{
+			name: "v2 inside a container namespace",
+			files: fstest.MapFS{
+				"proc/self/cgroup":      {Data: []byte("0::/\n")},
+				"sys/fs/cgroup/cpu.max": {Data: []byte("150000 100000\n")},
+			},
+			quota: 1.5, ok: true,
+		},
+		{
+			name: "v2 unlimited",
+			files: fstest.MapFS{
+				"proc/self/cgroup":      {Data: []byte("0::/user.slice\n")},
+				"sys/fs/cgroup/cpu.max": {Data: []byte("max 100000\n")},
+			},
+		},
+		{
+			name: "v1 limit",
+			files: fstest.MapFS{
+				"proc/self/cgroup": {Data: []byte("4:memory:/docker/abc\n3:cpu,cpuacct:/docker/abc\n")},
+				"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpu.cfs_quota_us":  {Data: []byte("25000\n")},
+				"sys/fs/cgroup/cpu,cpuacct/docker/abc/cpu.cfs_period_us": {Data: []byte("100000\n")},
+			},
+			quota: 0.25, ok: true,
+		},
+		{
+			name: "v1 unlimited at the mount root",
+			files: fstest.MapFS{
+				"proc/self/cgroup":                    {Data: []byte("3:cpu,cpuacct:/docker/abc\n")},
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":  {Data: []byte("-1\n")},
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us": {Data: []byte("100000\n")},
+			},
+		},
+		{
+			name:  "no cgroups",
+			files: fstest.MapFS{},
+		},
+	}
+	for _, tt := range tests {
+		quota, ok := cpuQuota(tt.files)
+		if ok != tt.ok || (ok && quota != tt.quota) {
+			t.Errorf("%s: expected %v, %v, got %v, %v", tt.name, tt.quota, tt.ok, quota, ok)
+		}
+	}
+}
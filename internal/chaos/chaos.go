@@ -0,0 +1,197 @@
+// Package chaos injects faults into the pipeline, failing parses, slowing
+// down and failing sinks and panicking workers at configured rates, to check
+// that a configuration degrades gracefully before relying on it.
+package chaos
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/human"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/processor"
+	"github.com/interview/junior-go-challenge/internal/sink"
+)
+
+// Kinds of injected faults
+const (
+	ParseError = "parse-error"
+	SlowSink   = "slow-sink"
+	SinkError  = "sink-error"
+	Panic      = "panic"
+)
+
+// Config holds the rate of each fault, as a fraction of the entries it
+// applies to
+type Config struct {
+	// ParseErrors fails parsing at an entry, failing the rest of its file
+	ParseErrors float64
+	// SlowSinks delays sink writes by SinkDelay
+	SlowSinks float64
+	SinkDelay time.Duration
+	// SinkErrors fails sink writes
+	SinkErrors float64
+	// Panics panics the worker processing an entry
+	Panics float64
+	// Seed makes the faults reproducible
+	Seed int64
+}
+
+// ParseConfig parses a comma-separated spec such as
+// "parse-error=0.01,slow-sink=0.1,sink-delay=200ms,sink-error=0.01,panic=0.001"
+func ParseConfig(spec string) (Config, error) {
+	cfg := Config{SinkDelay: 100 * time.Millisecond, Seed: time.Now().UnixNano()}
+	for _, part := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Config{}, fmt.Errorf("invalid fault %q, expected KIND=RATE", part)
+		}
+		var err error
+		switch key {
+		case ParseError:
+			cfg.ParseErrors, err = parseRate(value)
+		case SlowSink:
+			cfg.SlowSinks, err = parseRate(value)
+		case SinkError:
+			cfg.SinkErrors, err = parseRate(value)
+		case Panic:
+			cfg.Panics, err = parseRate(value)
+		case "sink-delay":
+			cfg.SinkDelay, err = human.ParseDuration(value)
+		case "seed":
+			cfg.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return Config{}, fmt.Errorf("unknown fault %q (expected %s, %s, %s, %s, sink-delay or seed)", key, ParseError, SlowSink, SinkError, Panic)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+	}
+	return cfg, nil
+}
+
+func parseRate(s string) (float64, error) {
+	rate, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("must be between 0 and 1")
+	}
+	return rate, nil
+}
+
+// Injector injects the faults of a Config into the parts of the pipeline
+// it wraps, and counts them
+type Injector struct {
+	cfg Config
+
+	mu     sync.Mutex
+	rng    *rand.Rand
+	counts map[string]int
+}
+
+// New creates an injector for cfg
+func New(cfg Config) *Injector {
+	return &Injector{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), counts: make(map[string]int)}
+}
+
+// roll reports whether a fault of kind happening at rate strikes now, and
+// counts it if so
+func (i *Injector) roll(kind string, rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.rng.Float64() >= rate {
+		return false
+	}
+	i.counts[kind]++
+	return true
+}
+
+// Count is the number of faults of a kind injected
+type Count struct {
+	Kind  string
+	Count int
+}
+
+// Counts returns the faults injected so far, by kind
+func (i *Injector) Counts() []Count {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	counts := make([]Count, 0, len(i.counts))
+	for kind, n := range i.counts {
+		counts = append(counts, Count{Kind: kind, Count: n})
+	}
+	sort.Slice(counts, func(a, b int) bool { return counts[a].Kind < counts[b].Kind })
+	return counts
+}
+
+// Parser wraps p so that parsing fails at an entry with the configured
+// rate, as it would on a damaged line
+func (i *Injector) Parser(p parser.Parser) parser.Parser {
+	return faultyParser{inner: p, injector: i}
+}
+
+type faultyParser struct {
+	inner    parser.Parser
+	injector *Injector
+}
+
+func (f faultyParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	return f.inner.Parse(r, func(entry models.LogEntry) error {
+		if f.injector.roll(ParseError, f.injector.cfg.ParseErrors) {
+			return fmt.Errorf("injected parse error at entry %s", entry.ID)
+		}
+		return emit(entry)
+	})
+}
+
+// Sink wraps s so that writes are delayed or fail with the configured rates
+func (i *Injector) Sink(s sink.Sink) sink.Sink {
+	return &faultySink{inner: s, injector: i}
+}
+
+type faultySink struct {
+	inner    sink.Sink
+	injector *Injector
+}
+
+func (f *faultySink) Write(entry models.LogEntry) error {
+	if f.injector.roll(SlowSink, f.injector.cfg.SlowSinks) {
+		time.Sleep(f.injector.cfg.SinkDelay)
+	}
+	if f.injector.roll(SinkError, f.injector.cfg.SinkErrors) {
+		return fmt.Errorf("injected sink error")
+	}
+	return f.inner.Write(entry)
+}
+
+// Flush flushes the wrapped sink
+func (f *faultySink) Flush() error {
+	return sink.Flush(f.inner)
+}
+
+func (f *faultySink) Close() error {
+	return f.inner.Close()
+}
+
+// Middleware returns middleware panicking with the configured rate, as a
+// bug in processing an entry would
+func (i *Injector) Middleware() processor.Middleware {
+	return func(entry models.LogEntry) (models.LogEntry, bool) {
+		if i.roll(Panic, i.cfg.Panics) {
+			panic(fmt.Sprintf("injected panic at entry %s", entry.ID))
+		}
+		return entry, true
+	}
+}
@@ -0,0 +1,120 @@
+package chaos
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/processor"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig("parse-error=0.01, slow-sink=0.5,sink-delay=2s,sink-error=1,panic=0.001,seed=7")
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	want := Config{ParseErrors: 0.01, SlowSinks: 0.5, SinkDelay: 2 * time.Second, SinkErrors: 1, Panics: 0.001, Seed: 7}
+	if cfg != want {
+		t.Errorf("Expected %+v, got %+v", want, cfg)
+	}
+
+	for _, spec := range []string{"panic", "panic=2", "panic=-0.1", "crash=0.1", "sink-delay=soon", "seed=x"} {
+		if _, err := ParseConfig(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+type countingSink struct {
+	written int
+}
+
+func (s *countingSink) Write(models.LogEntry) error {
+	s.written++
+	return nil
+}
+
+func (s *countingSink) Close() error { return nil }
+
+func TestSinkFaults(t *testing.T) {
+	inner := &countingSink{}
+	injector := New(Config{SinkErrors: 0.25, Seed: 1})
+	s := injector.Sink(inner)
+
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if err := s.Write(models.LogEntry{ID: fmt.Sprint(i)}); err != nil {
+			failed++
+		}
+	}
+	if failed < 200 || failed > 300 {
+		t.Errorf("Expected about 250 failed writes, got %d", failed)
+	}
+	if inner.written != 1000-failed {
+		t.Errorf("Expected %d entries to reach the sink, got %d", 1000-failed, inner.written)
+	}
+	counts := injector.Counts()
+	if len(counts) != 1 || counts[0].Kind != SinkError || counts[0].Count != failed {
+		t.Errorf("Expected %d sink errors to be counted, got %+v", failed, counts)
+	}
+}
+
+func TestSlowSink(t *testing.T) {
+	s := New(Config{SlowSinks: 1, SinkDelay: 20 * time.Millisecond}).Sink(&countingSink{})
+	start := time.Now()
+	if err := s.Write(models.LogEntry{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Expected the write to be delayed, took %v", elapsed)
+	}
+}
+
+func TestParserFaults(t *testing.T) {
+	jsonParser, _ := parser.ForFormat("json")
+	p := New(Config{ParseErrors: 1}).Parser(jsonParser)
+	input := `{"id":"1","timestamp":"2023-01-01T00:00:00Z","level":"INFO","service":"api","message":"ok"}` + "\n"
+	err := p.Parse(strings.NewReader(input), func(models.LogEntry) error {
+		t.Errorf("Expected no entry to be emitted")
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "injected parse error") {
+		t.Errorf("Expected an injected parse error, got %v", err)
+	}
+}
+
+func TestPipelineDegrades(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf(`{"id":"%d","timestamp":"2023-01-01T00:00:00Z","level":"INFO","service":"api","message":"ok"}`, i))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.json"), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	injector := New(Config{Panics: 0.1, Seed: 3})
+	proc := processor.NewLogProcessor(dir, processor.WithPattern("*.json"))
+	proc.Use(injector.Middleware())
+	if err := proc.Start(); err != nil {
+		t.Fatalf("Expected panics not to stop processing, got %v", err)
+	}
+
+	counts := injector.Counts()
+	if len(counts) != 1 || counts[0].Kind != Panic || counts[0].Count == 0 {
+		t.Fatalf("Expected panics to be injected, got %+v", counts)
+	}
+	panics := counts[0].Count
+	summary := proc.GetSummary()
+	if summary.TotalEntries != 100-panics {
+		t.Errorf("Expected %d entries to survive, got %d", 100-panics, summary.TotalEntries)
+	}
+	if got := summary.Accounting.Dropped[processor.DropPanic]; got != panics {
+		t.Errorf("Expected %d entries dropped for panics, got %d", panics, got)
+	}
+}
@@ -0,0 +1,224 @@
+// Package charset converts input in other encodings, such as the UTF-16 and
+// Windows-1252 files written by Windows tools, to UTF-8.
+package charset
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Supported encodings
+const (
+	Auto        = "auto"
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	Latin1      = "latin1"
+	Windows1252 = "windows-1252"
+)
+
+// sniffSize is how much of the input is examined to detect its encoding
+const sniffSize = 4096
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// Names returns the accepted encoding names
+func Names() []string {
+	return []string{Auto, UTF8, UTF16LE, UTF16BE, Latin1, Windows1252}
+}
+
+// Supported reports whether name is an accepted encoding
+func Supported(name string) bool {
+	for _, n := range Names() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect guesses the encoding of input starting with head and returns it with
+// the length of its byte order mark, if any. Input that is neither UTF-16 nor
+// valid UTF-8 is taken to be Windows-1252.
+func Detect(head []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		return UTF8, len(bomUTF8)
+	case bytes.HasPrefix(head, bomUTF16LE):
+		return UTF16LE, len(bomUTF16LE)
+	case bytes.HasPrefix(head, bomUTF16BE):
+		return UTF16BE, len(bomUTF16BE)
+	}
+
+	// ASCII text in UTF-16 has a zero in every other byte
+	var evenZeros, oddZeros int
+	for i := 0; i+1 < len(head); i += 2 {
+		if head[i] == 0 {
+			evenZeros++
+		}
+		if head[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	if pairs := len(head) / 2; pairs > 0 {
+		switch {
+		case oddZeros*10 >= pairs*4 && evenZeros*10 < pairs:
+			return UTF16LE, 0
+		case evenZeros*10 >= pairs*4 && oddZeros*10 < pairs:
+			return UTF16BE, 0
+		}
+	}
+
+	// The sample may end in the middle of a character
+	valid := head
+	for i := 0; i < utf8.UTFMax && len(valid) > 0 && !utf8.Valid(valid); i++ {
+		valid = valid[:len(valid)-1]
+	}
+	if utf8.Valid(valid) {
+		return UTF8, 0
+	}
+	return Windows1252, 0
+}
+
+// NewReader returns a reader converting r from the named encoding to UTF-8.
+// With Auto the encoding is detected from the start of the input. Byte order
+// marks are removed.
+func NewReader(r io.Reader, encoding string) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, _ := br.Peek(sniffSize)
+
+	detected, bom := Detect(head)
+	if encoding == Auto || encoding == "" {
+		encoding = detected
+	} else if detected != encoding {
+		// Only a byte order mark of the requested encoding is removed
+		bom = 0
+	}
+	br.Discard(bom)
+
+	switch encoding {
+	case UTF8:
+		return br, nil
+	case UTF16LE:
+		return &utf16Reader{r: br, bigEndian: false}, nil
+	case UTF16BE:
+		return &utf16Reader{r: br, bigEndian: true}, nil
+	case Latin1:
+		return &singleByteReader{r: br, table: nil}, nil
+	case Windows1252:
+		return &singleByteReader{r: br, table: &windows1252}, nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q (supported: %v)", encoding, Names())
+	}
+}
+
+// utf16Reader converts UTF-16 to UTF-8
+type utf16Reader struct {
+	r         io.Reader
+	bigEndian bool
+	in        [4096]byte
+	pending   int // bytes of in carried over from the last read
+	high      rune
+	out       []byte
+	err       error
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	for len(u.out) == 0 {
+		if u.err != nil {
+			if u.high != 0 || u.pending > 0 {
+				// A lone surrogate or odd trailing byte
+				u.high, u.pending = 0, 0
+				return copy(p, string(utf8.RuneError)), nil
+			}
+			return 0, u.err
+		}
+		n, err := u.r.Read(u.in[u.pending:])
+		u.err = err
+		n += u.pending
+		u.pending = n % 2
+		for i := 0; i+1 < n; i += 2 {
+			var c rune
+			if u.bigEndian {
+				c = rune(u.in[i])<<8 | rune(u.in[i+1])
+			} else {
+				c = rune(u.in[i+1])<<8 | rune(u.in[i])
+			}
+			u.out = u.appendUnit(u.out, c)
+		}
+		if u.pending == 1 {
+			u.in[0] = u.in[n-1]
+		}
+	}
+	n := copy(p, u.out)
+	u.out = u.out[n:]
+	return n, nil
+}
+
+// appendUnit decodes one UTF-16 code unit, pairing surrogates
+func (u *utf16Reader) appendUnit(out []byte, c rune) []byte {
+	if u.high != 0 {
+		high := u.high
+		u.high = 0
+		if r := utf16.DecodeRune(high, c); r != utf8.RuneError {
+			return utf8.AppendRune(out, r)
+		}
+		out = utf8.AppendRune(out, utf8.RuneError)
+	}
+	if c >= 0xd800 && c < 0xdc00 {
+		u.high = c
+		return out
+	}
+	if utf16.IsSurrogate(c) {
+		return utf8.AppendRune(out, utf8.RuneError)
+	}
+	return utf8.AppendRune(out, c)
+}
+
+// singleByteReader converts Latin-1 or a code page extending it to UTF-8
+type singleByteReader struct {
+	r     io.Reader
+	table *[32]rune // characters for 0x80-0x9f, nil for Latin-1
+	in    [4096]byte
+	buf   []byte
+	out   []byte
+}
+
+func (s *singleByteReader) Read(p []byte) (int, error) {
+	if len(s.out) == 0 {
+		n, err := s.r.Read(s.in[:])
+		if n == 0 {
+			return 0, err
+		}
+		s.buf = s.buf[:0]
+		for _, b := range s.in[:n] {
+			switch {
+			case b < utf8.RuneSelf:
+				s.buf = append(s.buf, b)
+			case b < 0xa0 && s.table != nil:
+				s.buf = utf8.AppendRune(s.buf, s.table[b-0x80])
+			default:
+				s.buf = utf8.AppendRune(s.buf, rune(b))
+			}
+		}
+		s.out = s.buf
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+// windows1252 maps 0x80-0x9f, where Windows-1252 differs from Latin-1.
+// Undefined positions keep their Latin-1 control characters.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
@@ -0,0 +1,78 @@
+package charset
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+// encodeUTF16 encodes s as UTF-16 with an optional byte order mark
+func encodeUTF16(s string, bigEndian, bom bool) string {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+	var b strings.Builder
+	for _, u := range units {
+		if bigEndian {
+			b.WriteByte(byte(u >> 8))
+			b.WriteByte(byte(u))
+		} else {
+			b.WriteByte(byte(u))
+			b.WriteByte(byte(u >> 8))
+		}
+	}
+	return b.String()
+}
+
+func TestNewReader(t *testing.T) {
+	text := `{"message":"café ünïcödé 😀"}` + "\n"
+	tests := []struct {
+		name     string
+		input    string
+		encoding string
+		expected string
+	}{
+		{"utf-8", text, Auto, text},
+		{"utf-8 bom", "\xef\xbb\xbf" + text, Auto, text},
+		{"utf-16le bom", encodeUTF16(text, false, true), Auto, text},
+		{"utf-16be bom", encodeUTF16(text, true, true), Auto, text},
+		{"utf-16le detected", encodeUTF16(text, false, false), Auto, text},
+		{"utf-16be forced", encodeUTF16(text, true, false), UTF16BE, text},
+		{"windows-1252 detected", "price \x80 5 \x93quoted\x94 caf\xe9", Auto, "price € 5 “quoted” café"},
+		{"latin1 forced", "caf\xe9 \x80", Latin1, "café \u0080"},
+	}
+
+	for _, tt := range tests {
+		r, err := NewReader(strings.NewReader(tt.input), tt.encoding)
+		if err != nil {
+			t.Fatalf("%s: failed to create reader: %v", tt.name, err)
+		}
+		out, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%s: failed to read: %v", tt.name, err)
+		}
+		if string(out) != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, out)
+		}
+	}
+
+	if _, err := NewReader(strings.NewReader(text), "ebcdic"); err == nil {
+		t.Error("Expected an unknown encoding to be rejected")
+	}
+}
+
+func TestUTF16ReaderSmallReads(t *testing.T) {
+	// Surrogate pairs and code units split across reads
+	text := strings.Repeat("a😀b", 3000)
+	r, _ := NewReader(iotest.OneByteReader(strings.NewReader(encodeUTF16(text, false, true))), Auto)
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	if string(out) != text {
+		t.Errorf("Expected %d bytes of decoded text, got %d", len(text), len(out))
+	}
+}
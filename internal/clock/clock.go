@@ -0,0 +1,160 @@
+// Package clock abstracts the passing of time, so time-dependent features
+// can be tested deterministically with a fake clock.
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the time and signals when it has passed
+type Clock interface {
+	Now() time.Time
+	// After sends the time on the returned channel once d has passed
+	After(d time.Duration) <-chan time.Time
+	// NewTicker sends the time on its channel every d, dropping ticks for
+	// slow receivers like time.Ticker
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks of a Clock
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// System is the clock of the operating system
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct{ t *time.Ticker }
+
+func (t systemTicker) C() <-chan time.Time { return t.t.C }
+func (t systemTicker) Stop()               { t.t.Stop() }
+
+// Fake is a clock that only moves when advanced, firing the timers and
+// tickers that come due on the way
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a pending After or a ticker
+type fakeTimer struct {
+	due    time.Time
+	period time.Duration // zero for After
+	ch     chan time.Time
+}
+
+// NewFake creates a fake clock set to start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{due: f.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- f.now
+		return t.ch
+	}
+	f.timers = append(f.timers, t)
+	return t.ch
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{due: f.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	f.timers = append(f.timers, t)
+	return &fakeTicker{clock: f, timer: t}
+}
+
+// Advance moves the clock forward by d, firing due timers and tickers in
+// order of their due times
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.advanceTo(f.now.Add(d))
+}
+
+// Set moves the clock forward to t, like Advance. Times before the current
+// one leave the clock as it is.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if t.After(f.now) {
+		f.advanceTo(t)
+	}
+}
+
+func (f *Fake) advanceTo(end time.Time) {
+	for {
+		sort.SliceStable(f.timers, func(i, j int) bool { return f.timers[i].due.Before(f.timers[j].due) })
+		if len(f.timers) == 0 || f.timers[0].due.After(end) {
+			break
+		}
+		t := f.timers[0]
+		f.now = t.due
+		select {
+		case t.ch <- t.due:
+		default:
+			// Dropped like a tick the receiver was too slow for
+		}
+		if t.period == 0 {
+			f.timers = f.timers[1:]
+			continue
+		}
+		// Further ticks up to end would be dropped, as nothing can receive
+		// while the clock is locked, so skip to the next one after end
+		t.due = t.due.Add(t.period * (end.Sub(t.due)/t.period + 1))
+		if !t.due.After(end) {
+			// The jump was too long for a Duration
+			t.due = end.Add(t.period)
+		}
+	}
+	f.now = end
+}
+
+// Pending returns the number of timers and tickers waiting to fire, so tests
+// can tell when the code under test has started waiting
+func (f *Fake) Pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+type fakeTicker struct {
+	clock *Fake
+	timer *fakeTimer
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.timer.ch }
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, timer := range t.clock.timers {
+		if timer == t.timer {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+
+	after := c.After(90 * time.Second)
+	ticker := c.NewTicker(time.Minute)
+	if c.Pending() != 2 {
+		t.Fatalf("Expected 2 pending timers, got %d", c.Pending())
+	}
+
+	c.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatalf("Expected no tick before a minute has passed")
+	case <-after:
+		t.Fatalf("Expected After not to fire early")
+	default:
+	}
+
+	c.Advance(time.Second)
+	if got := <-ticker.C(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected a tick at %v, got %v", start.Add(time.Minute), got)
+	}
+
+	// Ticks the receiver misses are dropped, After still fires once
+	c.Advance(3 * time.Minute)
+	if got := <-after; !got.Equal(start.Add(90 * time.Second)) {
+		t.Errorf("Expected After to fire at %v, got %v", start.Add(90*time.Second), got)
+	}
+	if got := <-ticker.C(); !got.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("Expected the first missed tick at %v, got %v", start.Add(2*time.Minute), got)
+	}
+	if now := c.Now(); !now.Equal(start.Add(4 * time.Minute)) {
+		t.Errorf("Expected the clock at %v, got %v", start.Add(4*time.Minute), now)
+	}
+
+	// Setting a time far ahead fires the ticker once without stepping
+	// through every interval
+	c.Set(start.AddDate(10, 0, 0))
+	if got := <-ticker.C(); !got.Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("Expected one tick at %v, got %v", start.Add(5*time.Minute), got)
+	}
+	c.Set(start)
+	if now := c.Now(); !now.Equal(start.AddDate(10, 0, 0)) {
+		t.Errorf("Expected the clock not to go back, got %v", now)
+	}
+
+	// Jumps too long for a Duration
+	c.Set(start.AddDate(1000, 0, 0))
+	if got := <-ticker.C(); !got.After(start) {
+		t.Errorf("Expected a tick after a long jump, got %v", got)
+	}
+
+	ticker.Stop()
+	if c.Pending() != 0 {
+		t.Errorf("Expected no pending timers after Stop, got %d", c.Pending())
+	}
+	select {
+	case <-c.After(0):
+	default:
+		t.Errorf("Expected After(0) to fire immediately")
+	}
+}
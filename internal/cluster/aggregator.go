@@ -0,0 +1,204 @@
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// PushPath is where the aggregator accepts reports
+const PushPath = "/push"
+
+// defaultTopPatterns is how many patterns /patterns returns by default
+const defaultTopPatterns = 20
+
+// Report is what a worker pushes to the aggregator: everything the worker
+// counted so far. Each report replaces the worker's previous one.
+type Report struct {
+	Worker string `json:"worker"`
+	// Sent orders the reports of a worker, so a report delivered late does
+	// not replace a newer one
+	Sent     time.Time              `json:"sent"`
+	Summary  *models.LogSummary     `json:"summary"`
+	Patterns *analyzer.PatternTable `json:"patterns,omitempty"`
+}
+
+// WorkerStatus describes the latest report of a worker
+type WorkerStatus struct {
+	Worker   string    `json:"worker"`
+	Entries  int       `json:"entries"`
+	Sent     time.Time `json:"sent"`
+	Received time.Time `json:"received"`
+}
+
+// Aggregator merges the reports of workers into a cluster-wide summary
+type Aggregator struct {
+	token string
+	now   func() time.Time
+
+	mu       sync.Mutex
+	reports  map[string]*Report
+	received map[string]time.Time
+}
+
+// NewAggregator creates an aggregator. If token is set, workers must
+// present it as a bearer token.
+func NewAggregator(token string) *Aggregator {
+	return &Aggregator{
+		token:    token,
+		now:      time.Now,
+		reports:  make(map[string]*Report),
+		received: make(map[string]time.Time),
+	}
+}
+
+// Add stores report as the latest of its worker. It reports whether the
+// report was used, which it is not if a newer one arrived first.
+func (a *Aggregator) Add(report *Report) (bool, error) {
+	if report.Worker == "" {
+		return false, fmt.Errorf("report names no worker")
+	}
+	if report.Summary == nil {
+		return false, fmt.Errorf("report of %s has no summary", report.Worker)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if prev := a.reports[report.Worker]; prev != nil && report.Sent.Before(prev.Sent) {
+		return false, nil
+	}
+	a.reports[report.Worker] = report
+	a.received[report.Worker] = a.now()
+	return true, nil
+}
+
+// Summary merges the latest summary of every worker
+func (a *Aggregator) Summary() *models.LogSummary {
+	merged := analyzer.NewLogAnalyzer()
+	a.mu.Lock()
+	for _, r := range a.reports {
+		merged.Merge(r.Summary)
+	}
+	a.mu.Unlock()
+	return merged.GetSummary()
+}
+
+// Patterns merges the pattern tables of every worker and returns the top
+// most frequent patterns
+func (a *Aggregator) Patterns(top int) []analyzer.PatternCount {
+	merged := analyzer.NewPatternSection(top)
+	a.mu.Lock()
+	for _, r := range a.reports {
+		if r.Patterns != nil {
+			merged.MergeTable(r.Patterns)
+		}
+	}
+	a.mu.Unlock()
+	return merged.Top()
+}
+
+// Workers returns the status of every worker that reported, sorted by name
+func (a *Aggregator) Workers() []WorkerStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	workers := make([]WorkerStatus, 0, len(a.reports))
+	for name, r := range a.reports {
+		workers = append(workers, WorkerStatus{
+			Worker:   name,
+			Entries:  r.Summary.TotalEntries,
+			Sent:     r.Sent,
+			Received: a.received[name],
+		})
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Worker < workers[j].Worker })
+	return workers
+}
+
+// Handler returns the HTTP handler accepting reports at PushPath and
+// serving the merged patterns at /patterns and the workers at /workers. If
+// the aggregator has a token, every route requires it.
+func (a *Aggregator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PushPath, a.handlePush)
+	mux.HandleFunc("/patterns", func(w http.ResponseWriter, r *http.Request) {
+		top := defaultTopPatterns
+		if s := r.URL.Query().Get("top"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "top must be a positive number"})
+				return
+			}
+			top = n
+		}
+		writeJSON(w, http.StatusOK, a.Patterns(top))
+	})
+	mux.HandleFunc("/workers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, a.Workers())
+	})
+	return RequireToken(a.token)(mux)
+}
+
+// RequireToken returns a wrapper rejecting requests that do not present
+// token as a bearer token, or one leaving handlers as they are if token is
+// empty
+func RequireToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or wrong token"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (a *Aggregator) handlePush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+		return
+	}
+	var report Report
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPartial)).Decode(&report); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid report: %v", err)})
+		return
+	}
+	used, err := a.Add(&report)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	status := "received"
+	if !used {
+		status = "outdated"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": status})
+}
+
+// Push sends report to the aggregator at url. It does not retry: the next
+// report carries the same counts and more.
+func Push(ctx context.Context, url, token string, report *Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	if err := send(ctx, strings.TrimSuffix(url, "/")+PushPath, token, body); err != nil {
+		return fmt.Errorf("failed to push report to aggregator: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+// Package cluster splits the processing of a large input across several
+// worker instances. Each worker owns a disjoint share of the input files,
+// chosen by hashing their paths, and ships the analyzer state of its share
+// to a coordinator that merges the states into one summary. Long-running
+// workers instead push their summaries periodically to an aggregator,
+// which serves the summary of the whole cluster.
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+)
+
+// TokenEnv is the environment variable holding the token workers present
+// to the coordinator
+const TokenEnv = "LOGPROCESSOR_CLUSTER_TOKEN"
+
+// PartialPath is where the coordinator accepts partial states
+const PartialPath = "/partial"
+
+// maxPartial bounds the size of a partial state
+const maxPartial = 256 << 20
+
+// Shard is one worker's share of the input: shard Index of Count
+type Shard struct {
+	Index int
+	Count int
+}
+
+// ParseShard parses a shard given as "INDEX/COUNT", e.g. "0/8"
+func ParseShard(s string) (Shard, error) {
+	index, count, ok := strings.Cut(s, "/")
+	if !ok {
+		return Shard{}, fmt.Errorf("invalid shard %q: expected INDEX/COUNT, e.g. 0/8", s)
+	}
+	var sh Shard
+	var err error
+	if sh.Index, err = strconv.Atoi(index); err != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q: expected INDEX/COUNT, e.g. 0/8", s)
+	}
+	if sh.Count, err = strconv.Atoi(count); err != nil {
+		return Shard{}, fmt.Errorf("invalid shard %q: expected INDEX/COUNT, e.g. 0/8", s)
+	}
+	if sh.Count < 1 || sh.Index < 0 || sh.Index >= sh.Count {
+		return Shard{}, fmt.Errorf("invalid shard %q: the index must be from 0 to the count minus one", s)
+	}
+	return sh, nil
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Count)
+}
+
+// Owns reports whether the file at path, relative to the input, belongs to
+// this shard. Files are assigned by rendezvous hashing, so every worker
+// agrees without talking to the others, and a changed worker count only
+// moves the files of the shards added or removed.
+func (s Shard) Owns(path string) bool {
+	best, bestScore := 0, uint64(0)
+	for i := 0; i < s.Count; i++ {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%d\x00%s", i, path)
+		if score := h.Sum64(); i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best == s.Index
+}
+
+// Coordinator collects the partial states of every shard and merges them
+type Coordinator struct {
+	shards int
+	token  string
+
+	mu       sync.Mutex
+	partials map[int]*analyzer.State
+	// done is set once every shard arrived; later retries are ignored
+	done     bool
+	complete chan struct{}
+}
+
+// NewCoordinator creates a coordinator waiting for shards partial states.
+// If token is set, workers must present it as a bearer token.
+func NewCoordinator(shards int, token string) *Coordinator {
+	return &Coordinator{
+		shards:   shards,
+		token:    token,
+		partials: make(map[int]*analyzer.State),
+		complete: make(chan struct{}),
+	}
+}
+
+// Handler returns the HTTP handler accepting partial states at PartialPath
+// and reporting progress at /status
+func (c *Coordinator) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PartialPath, c.handlePartial)
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"shards": c.shards, "received": c.Received()})
+	})
+	return mux
+}
+
+// handlePartial stores the state of one shard. A shard sending again, e.g.
+// after a retry or a rerun, replaces its earlier state.
+func (c *Coordinator) handlePartial(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+		return
+	}
+	if c.token != "" {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.token)) != 1 {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or wrong token"})
+			return
+		}
+	}
+	shard, err := ParseShard(r.URL.Query().Get("shard"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if shard.Count != c.shards {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": fmt.Sprintf("shard %s does not belong to a run of %d shards", shard, c.shards)})
+		return
+	}
+	var state analyzer.State
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPartial)).Decode(&state); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid state: %v", err)})
+		return
+	}
+	if state.Version != analyzer.StateVersion || state.Summary == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("unsupported state version %d", state.Version)})
+		return
+	}
+
+	c.mu.Lock()
+	if !c.done {
+		c.partials[shard.Index] = &state
+		if len(c.partials) == c.shards {
+			c.done = true
+			close(c.complete)
+		}
+	}
+	c.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]string{"status": "received"})
+}
+
+// Received returns the indexes of the shards received so far
+func (c *Coordinator) Received() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	received := make([]int, 0, len(c.partials))
+	for i := range c.partials {
+		received = append(received, i)
+	}
+	sort.Ints(received)
+	return received
+}
+
+// Wait waits until every shard has sent its state, then merges the states
+// into a and returns nil. It returns ctx's error if ctx ends first.
+func (c *Coordinator) Wait(ctx context.Context, a *analyzer.LogAnalyzer) error {
+	select {
+	case <-c.complete:
+	case <-ctx.Done():
+		return fmt.Errorf("received %d of %d shards: %w", len(c.Received()), c.shards, ctx.Err())
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < c.shards; i++ {
+		if err := a.MergeState(c.partials[i]); err != nil {
+			return fmt.Errorf("failed to merge shard %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Send ships the state of shard to the coordinator at url, retrying
+// transient failures with a growing delay
+func Send(ctx context.Context, url, token string, shard Shard, state *analyzer.State) error {
+	body, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode partial state: %w", err)
+	}
+	endpoint := strings.TrimSuffix(url, "/") + PartialPath + "?shard=" + shard.String()
+
+	delay := time.Second
+	for attempt := 1; ; attempt++ {
+		err = send(ctx, endpoint, token, body)
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || attempt == 5 {
+			break
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send partial state to coordinator: %w", err)
+	}
+	return nil
+}
+
+// permanentError is a rejection that retrying cannot fix
+type permanentError struct {
+	status string
+	body   string
+}
+
+func (e *permanentError) Error() string {
+	return fmt.Sprintf("replied %s: %s", e.status, e.body)
+}
+
+func send(ctx context.Context, endpoint, token string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{status: "invalid request", body: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("replied %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return &permanentError{status: resp.Status, body: strings.TrimSpace(string(msg))}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
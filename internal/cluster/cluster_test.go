@@ -0,0 +1,226 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/server"
+)
+
+func TestParseShard(t *testing.T) {
+	shard, err := ParseShard("3/8")
+	if err != nil || shard != (Shard{Index: 3, Count: 8}) {
+		t.Errorf("Expected shard 3 of 8, got %+v (%v)", shard, err)
+	}
+	for _, s := range []string{"", "3", "8/8", "-1/8", "a/8", "0/0"} {
+		if _, err := ParseShard(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestOwns(t *testing.T) {
+	owners := make(map[string]int)
+	counts := make([]int, 4)
+	for i := 0; i < 1000; i++ {
+		path := fmt.Sprintf("2023/01/%02d/app-%d.json.gz", i%31+1, i)
+		for index := 0; index < 4; index++ {
+			if (Shard{Index: index, Count: 4}).Owns(path) {
+				if _, ok := owners[path]; ok {
+					t.Fatalf("Expected %s to have one owner", path)
+				}
+				owners[path] = index
+				counts[index]++
+			}
+		}
+		if _, ok := owners[path]; !ok {
+			t.Fatalf("Expected %s to have an owner", path)
+		}
+	}
+	for index, n := range counts {
+		if n < 150 {
+			t.Errorf("Expected shard %d to own about a quarter of the files, got %d", index, n)
+		}
+	}
+
+	// Adding a fifth worker only moves files to it
+	for path, owner := range owners {
+		for index := 0; index < 5; index++ {
+			if (Shard{Index: index, Count: 5}).Owns(path) && index != owner && index != 4 {
+				t.Fatalf("Expected %s to stay with shard %d or move to shard 4, got %d", path, owner, index)
+			}
+		}
+	}
+}
+
+// partialState returns the state of an analyzer that processed entries
+func partialState(entries ...models.LogEntry) *analyzer.State {
+	a := analyzer.NewLogAnalyzer()
+	for _, e := range entries {
+		a.Process(e)
+	}
+	return a.Export()
+}
+
+func TestCoordinator(t *testing.T) {
+	coordinator := NewCoordinator(2, "secret")
+	server := httptest.NewServer(coordinator.Handler())
+	defer server.Close()
+	ctx := context.Background()
+
+	first := partialState(models.LogEntry{ID: "1", Level: models.ERROR, Service: "api"})
+	second := partialState(models.LogEntry{ID: "2", Level: models.INFO, Service: "db"}, models.LogEntry{ID: "3", Level: models.INFO, Service: "db"})
+
+	if err := Send(ctx, server.URL, "wrong", Shard{Index: 0, Count: 2}, first); err == nil {
+		t.Error("Expected a wrong token to be rejected")
+	}
+	if err := Send(ctx, server.URL, "secret", Shard{Index: 0, Count: 3}, first); err == nil {
+		t.Error("Expected a shard of another run to be rejected")
+	}
+	// Resending a shard replaces it
+	for i := 0; i < 2; i++ {
+		if err := Send(ctx, server.URL, "secret", Shard{Index: 0, Count: 2}, first); err != nil {
+			t.Fatalf("Failed to send shard 0: %v", err)
+		}
+	}
+	if received := coordinator.Received(); len(received) != 1 || received[0] != 0 {
+		t.Errorf("Expected shard 0 to be received, got %v", received)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := coordinator.Wait(waitCtx, analyzer.NewLogAnalyzer()); err == nil {
+		t.Error("Expected Wait to time out with a shard missing")
+	}
+
+	if err := Send(ctx, server.URL, "secret", Shard{Index: 1, Count: 2}, second); err != nil {
+		t.Fatalf("Failed to send shard 1: %v", err)
+	}
+	merged := analyzer.NewLogAnalyzer()
+	if err := coordinator.Wait(ctx, merged); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	summary := merged.GetSummary()
+	if summary.TotalEntries != 3 || summary.ByService["db"] != 2 || summary.ByLevel[models.ERROR] != 1 {
+		t.Errorf("Expected the merged counts of both shards, got %+v", summary)
+	}
+	// The merged analyzer knows the IDs of both shards
+	if merged.Process(models.LogEntry{ID: "2", Level: models.INFO, Service: "db"}) {
+		t.Error("Expected an entry of a shard to count as a duplicate after merging")
+	}
+}
+
+// report returns a worker's report of entries with the given messages
+func report(worker string, sent time.Time, messages ...string) *Report {
+	a := analyzer.NewLogAnalyzer()
+	patterns := analyzer.NewPatternSection(10)
+	for i, m := range messages {
+		e := models.LogEntry{ID: fmt.Sprint(i), Level: models.INFO, Service: worker, Message: m}
+		a.Process(e)
+		patterns.Observe(e)
+	}
+	return &Report{Worker: worker, Sent: sent, Summary: a.GetSummary(), Patterns: patterns.Table()}
+}
+
+func TestAggregator(t *testing.T) {
+	aggregator := NewAggregator("secret")
+	server := httptest.NewServer(aggregator.Handler())
+	defer server.Close()
+	ctx := context.Background()
+	start := time.Now()
+
+	if err := Push(ctx, server.URL, "wrong", report("a", start, "user 1 logged in")); err == nil {
+		t.Error("Expected a wrong token to be rejected")
+	}
+	if err := Push(ctx, server.URL, "secret", &Report{Worker: "a", Sent: start}); err == nil {
+		t.Error("Expected a report without a summary to be rejected")
+	}
+
+	pushes := []*Report{
+		report("a", start, "user 1 logged in"),
+		report("b", start, "user 2 logged in", "disk full"),
+		// A newer report of a replaces its first one
+		report("a", start.Add(time.Minute), "user 1 logged in", "user 3 logged in"),
+		// A late report of b does not replace its newer one
+		report("b", start.Add(-time.Minute), "disk full"),
+	}
+	for _, r := range pushes {
+		if err := Push(ctx, server.URL, "secret", r); err != nil {
+			t.Fatalf("Failed to push report of %s: %v", r.Worker, err)
+		}
+	}
+
+	summary := aggregator.Summary()
+	if summary.TotalEntries != 4 || summary.ByService["a"] != 2 || summary.ByService["b"] != 2 {
+		t.Errorf("Expected the latest counts of both workers, got %+v", summary)
+	}
+	patterns := aggregator.Patterns(1)
+	if len(patterns) != 1 || patterns[0].Pattern != "user <*> logged in" || patterns[0].Count != 3 {
+		t.Errorf("Expected the merged top pattern to count 3 entries, got %+v", patterns)
+	}
+	workers := aggregator.Workers()
+	if len(workers) != 2 || workers[0].Worker != "a" || workers[0].Entries != 2 || !workers[1].Sent.Equal(start) {
+		t.Errorf("Expected the latest report of each worker, got %+v", workers)
+	}
+}
+
+func TestAggregatorRequiresToken(t *testing.T) {
+	aggregator := NewAggregator("secret")
+	// Served as the aggregate subcommand does
+	srv := server.New()
+	srv.Use(RequireToken("secret"))
+	srv.SetSummaryFunc(aggregator.Summary)
+	for _, path := range []string{PushPath, "/patterns", "/workers"} {
+		srv.Handle(path, aggregator.Handler())
+	}
+	srv.SetStatus(server.StatusRunning)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	get := func(url, token string) int {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", url, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, path := range []string{"/summary", "/metrics", "/patterns", "/workers", PushPath} {
+		if code := get(ts.URL+path, ""); code != http.StatusUnauthorized {
+			t.Errorf("Expected %s to require the token, got status %d", path, code)
+		}
+		if code := get(ts.URL+path, "wrong"); code != http.StatusUnauthorized {
+			t.Errorf("Expected %s to reject a wrong token, got status %d", path, code)
+		}
+	}
+	for _, path := range []string{"/summary", "/metrics", "/patterns", "/workers"} {
+		if code := get(ts.URL+path, "secret"); code != http.StatusOK {
+			t.Errorf("Expected %s to be served with the token, got status %d", path, code)
+		}
+	}
+	if code := get(ts.URL+"/healthz", ""); code != http.StatusOK {
+		t.Errorf("Expected /healthz to stay open, got status %d", code)
+	}
+
+	// The aggregator's own handler is guarded too
+	direct := httptest.NewServer(aggregator.Handler())
+	defer direct.Close()
+	for _, path := range []string{"/patterns", "/workers"} {
+		if code := get(direct.URL+path, ""); code != http.StatusUnauthorized {
+			t.Errorf("Expected %s to require the token, got status %d", path, code)
+		}
+	}
+}
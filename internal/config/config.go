@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Config holds settings loaded from a JSON config file
+type Config struct {
+	// Services holds per-service overrides keyed by service name
+	Services map[string]ServiceConfig `json:"services"`
+	// Counters count entries whose message matches a regular expression
+	Counters []CounterConfig `json:"counters"`
+	// Routes forward subsets of the entries to different outputs
+	Routes []RouteConfig `json:"routes"`
+	// Levels registers levels beyond the standard ones
+	Levels []LevelConfig `json:"levels"`
+	// Pricing sets the ingestion price used by the cost section
+	Pricing *PricingConfig `json:"pricing"`
+	// Metrics convert matching entries into time-series metrics
+	Metrics []MetricConfig `json:"metrics"`
+	// Tenants share a serving instance with isolated summaries
+	Tenants []TenantConfig `json:"tenants"`
+	// JSONFields maps entry attributes onto the JSONPath of their values in
+	// JSON input of another schema, e.g. {"level": "$.severity"}
+	JSONFields map[string]string `json:"json_fields"`
+}
+
+// TenantConfig defines a tenant of a serving instance
+type TenantConfig struct {
+	Name string `json:"name"`
+	// APIKeySHA256 is the hex SHA-256 of the tenant's API key
+	APIKeySHA256 string `json:"api_key_sha256"`
+	// MaxEntriesPerMinute limits the entries the tenant may send; 0 means
+	// no limit
+	MaxEntriesPerMinute int `json:"max_entries_per_minute"`
+	// Admin lets the tenant read every endpoint and all tenants' entries
+	Admin bool `json:"admin"`
+}
+
+// MetricConfig defines a metric derived from entries
+type MetricConfig struct {
+	// Name names the metric, e.g. http_request_duration_ms
+	Name string `json:"name"`
+	// Type is counter, gauge or histogram
+	Type string `json:"type"`
+	// Match is a rule match expression; empty matches every entry
+	Match string `json:"match"`
+	// Value is the field holding the number, e.g. fields.duration_ms;
+	// counters without one count entries
+	Value string `json:"value"`
+	// Labels are the fields labeling the series, e.g. service
+	Labels []string `json:"labels"`
+	// Buckets are the upper bounds of a histogram's buckets
+	Buckets []float64 `json:"buckets"`
+}
+
+// PricingConfig is the price of ingesting logs
+type PricingConfig struct {
+	// PerGB is the price per gigabyte ingested
+	PerGB float64 `json:"per_gb"`
+	// Currency labels the prices, USD by default
+	Currency string `json:"currency"`
+}
+
+// LevelConfig defines an additional level
+type LevelConfig struct {
+	// Name is the level, e.g. NOTICE
+	Name string `json:"name"`
+	// Severity orders the level among the others; the standard levels are
+	// DEBUG 10, INFO 20, WARNING 30, ERROR 40 and FATAL 50
+	Severity int `json:"severity"`
+	// Aliases are other names entries may use for the level
+	Aliases []string `json:"aliases"`
+}
+
+// RouteConfig sends the entries matching an expression to an output
+type RouteConfig struct {
+	// Name identifies the route in reports, the output by default
+	Name string `json:"name"`
+	// Match is a rule match expression; empty matches every entry
+	Match string `json:"match"`
+	// Output is a sink URL as accepted by -output, or "count" to only count
+	// the matched entries
+	Output string `json:"output"`
+}
+
+// CounterConfig defines a message counter
+type CounterConfig struct {
+	// Name names the counter in the summary and metrics
+	Name string `json:"name"`
+	// Match is a regular expression matched against messages
+	Match string `json:"match"`
+	// Label, if set, counts matches per value of the capture group of that
+	// name, or else of the first capture group
+	Label string `json:"label"`
+}
+
+// ServiceConfig overrides the default handling of one service's entries
+type ServiceConfig struct {
+	// TimestampLayout is a Go time layout for timestamps in JSON input,
+	// used instead of RFC 3339
+	TimestampLayout string `json:"timestamp_layout"`
+	// Levels maps the service's own level names, matched case-insensitively,
+	// onto standard levels, e.g. {"SEVERE": "ERROR"}
+	Levels map[string]models.LogLevel `json:"levels"`
+	// ExcludeFromSections keeps the service out of additional report
+	// sections while still counting it in the summary
+	ExcludeFromSections bool `json:"exclude_from_sections"`
+}
+
+// Load reads a config file
+func Load(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	defer file.Close()
+
+	var cfg Config
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	if cfg.Pricing != nil && cfg.Pricing.PerGB < 0 {
+		return nil, fmt.Errorf("invalid config %s: negative pricing per_gb", path)
+	}
+
+	// Normalize level names so lookups are case-insensitive
+	for name, svc := range cfg.Services {
+		levels := make(map[string]models.LogLevel, len(svc.Levels))
+		for from, to := range svc.Levels {
+			levels[strings.ToUpper(from)] = models.LogLevel(strings.ToUpper(string(to)))
+		}
+		svc.Levels = levels
+		cfg.Services[name] = svc
+	}
+	return &cfg, nil
+}
+
+// RegisterLevels registers the configured levels with models
+func (c *Config) RegisterLevels() error {
+	for _, l := range c.Levels {
+		if err := models.RegisterLevel(models.LogLevel(l.Name), l.Severity, l.Aliases...); err != nil {
+			return fmt.Errorf("invalid level in config: %w", err)
+		}
+	}
+	return nil
+}
+
+// TimestampLayouts returns the custom timestamp layouts by service
+func (c *Config) TimestampLayouts() map[string]string {
+	layouts := make(map[string]string)
+	for name, svc := range c.Services {
+		if svc.TimestampLayout != "" {
+			layouts[name] = svc.TimestampLayout
+		}
+	}
+	return layouts
+}
+
+// ExcludedFromSections returns the services kept out of report sections
+func (c *Config) ExcludedFromSections() []string {
+	var services []string
+	for name, svc := range c.Services {
+		if svc.ExcludeFromSections {
+			services = append(services, name)
+		}
+	}
+	return services
+}
+
+// MapLevel applies the service's level mapping to an entry, reporting true
+// so it can be used as processor middleware
+func (c *Config) MapLevel(entry models.LogEntry) (models.LogEntry, bool) {
+	svc, ok := c.Services[entry.Service]
+	if !ok || len(svc.Levels) == 0 {
+		return entry, true
+	}
+	if level, ok := svc.Levels[strings.ToUpper(string(entry.Level))]; ok {
+		entry.Level = level
+	}
+	return entry, true
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadServiceOverrides(t *testing.T) {
+	path := writeConfig(t, `{
+		"services": {
+			"legacy-api": {
+				"timestamp_layout": "02/01/2006 15:04:05",
+				"levels": {"severe": "error", "Notice": "INFO"},
+				"exclude_from_sections": true
+			},
+			"api": {}
+		}
+	}`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if layouts := cfg.TimestampLayouts(); len(layouts) != 1 || layouts["legacy-api"] != "02/01/2006 15:04:05" {
+		t.Errorf("Unexpected timestamp layouts: %v", layouts)
+	}
+	if excluded := cfg.ExcludedFromSections(); len(excluded) != 1 || excluded[0] != "legacy-api" {
+		t.Errorf("Unexpected excluded services: %v", excluded)
+	}
+
+	// Level names match case-insensitively and only for their service
+	entry, keep := cfg.MapLevel(models.LogEntry{Service: "legacy-api", Level: "SEVERE"})
+	if !keep || entry.Level != models.ERROR {
+		t.Errorf("Expected SEVERE to map to ERROR, got %s", entry.Level)
+	}
+	entry, _ = cfg.MapLevel(models.LogEntry{Service: "api", Level: "SEVERE"})
+	if entry.Level != "SEVERE" {
+		t.Errorf("Expected other services to be unchanged, got %s", entry.Level)
+	}
+}
+
+func TestLoadRejectsUnknownSettings(t *testing.T) {
+	path := writeConfig(t, `{"services": {"api": {"timestamp_format": "x"}}}`)
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for an unknown setting")
+	}
+}
+
+func TestRegisterLevels(t *testing.T) {
+	path := writeConfig(t, `{"levels": [{"name": "trace", "severity": 5, "aliases": ["trc"]}]}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if err := cfg.RegisterLevels(); err != nil {
+		t.Fatalf("Failed to register levels: %v", err)
+	}
+	if level, ok := models.ParseLevel("TRC"); !ok || level != "TRACE" {
+		t.Errorf("Expected TRC to name TRACE, got %q", level)
+	}
+
+	conflicting := writeConfig(t, `{"levels": [{"name": "SEVERE", "severity": 45, "aliases": ["warn"]}]}`)
+	if cfg, err = Load(conflicting); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if err := cfg.RegisterLevels(); err == nil {
+		t.Error("Expected an error for an alias of another level")
+	}
+}
@@ -0,0 +1,112 @@
+// Package crypt encrypts the files the processor persists, such as analyzer
+// state, checkpoints and exported entries, with AES-GCM. Small files are
+// sealed whole; exports are written as a stream of authenticated chunks so
+// they never have to fit in memory.
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// KeyEnv is the environment variable holding the encryption key
+const KeyEnv = "LOGPROCESSOR_ENCRYPTION_KEY"
+
+// sealedMagic starts every file sealed whole
+var sealedMagic = []byte("LPENC1\n")
+
+// ErrNoKey is returned when encrypted data is read without a key
+var ErrNoKey = errors.New("data is encrypted but no encryption key is set")
+
+// Cipher encrypts and decrypts with one key. A nil Cipher leaves data as
+// it is, so callers need not check whether encryption is enabled.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher from a 16, 24 or 32 byte AES key
+func NewCipher(key []byte) (*Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// ParseKey creates a cipher from a base64 or hex encoded key, as printed
+// by e.g. "openssl rand -base64 32" or a KMS decrypt call
+func ParseKey(s string) (*Cipher, error) {
+	s = strings.TrimSpace(s)
+	if key, err := hex.DecodeString(s); err == nil {
+		return NewCipher(key)
+	}
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: not base64 or hex")
+	}
+	return NewCipher(key)
+}
+
+// KeyFromCommand runs command with the shell and parses the key it prints,
+// so keys can come from a KMS or secret manager CLI
+func KeyFromCommand(command string) (*Cipher, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run encryption key command: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return ParseKey(string(out))
+}
+
+// Seal encrypts data as a whole, returning it unchanged for a nil Cipher
+func (c *Cipher) Seal(data []byte) []byte {
+	if c == nil {
+		return data
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("crypt: failed to read random nonce: %v", err))
+	}
+	out := append(append([]byte(nil), sealedMagic...), nonce...)
+	return c.aead.Seal(out, nonce, data, sealedMagic)
+}
+
+// Open decrypts data sealed by Seal. Data that is not encrypted is returned
+// unchanged, so files written before encryption was enabled stay readable.
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, sealedMagic) {
+		return data, nil
+	}
+	if c == nil {
+		return nil, ErrNoKey
+	}
+	data = data[len(sealedMagic):]
+	if len(data) < c.aead.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt: data is truncated")
+	}
+	nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, ciphertext, sealedMagic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: wrong key or corrupted data")
+	}
+	return plain, nil
+}
+
+// Sealed reports whether data was encrypted by Seal
+func Sealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealedMagic)
+}
@@ -0,0 +1,94 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func TestSealOpen(t *testing.T) {
+	c, err := ParseKey(testKey)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	plain := []byte(`{"total_entries": 3}`)
+	sealed := c.Seal(plain)
+	if !Sealed(sealed) || bytes.Contains(sealed, plain) {
+		t.Fatalf("Expected sealed data to be encrypted, got %q", sealed)
+	}
+	opened, err := c.Open(sealed)
+	if err != nil || !bytes.Equal(opened, plain) {
+		t.Errorf("Expected %q back, got %q (%v)", plain, opened, err)
+	}
+
+	// Tampering and other keys are detected
+	sealed[len(sealed)-1] ^= 1
+	if _, err := c.Open(sealed); err == nil {
+		t.Error("Expected an error for tampered data")
+	}
+	other, _ := ParseKey(strings.Repeat("ab", 32))
+	if _, err := other.Open(c.Seal(plain)); err == nil {
+		t.Error("Expected an error for the wrong key")
+	}
+
+	// Plain data stays readable, and a nil cipher passes data through
+	var none *Cipher
+	if opened, err := c.Open(plain); err != nil || !bytes.Equal(opened, plain) {
+		t.Errorf("Expected plain data unchanged, got %q (%v)", opened, err)
+	}
+	if !bytes.Equal(none.Seal(plain), plain) {
+		t.Error("Expected a nil cipher not to encrypt")
+	}
+	if _, err := none.Open(c.Seal(plain)); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey without a key, got %v", err)
+	}
+
+	if _, err := ParseKey("c2hvcnQ="); err == nil {
+		t.Error("Expected an error for a key of the wrong length")
+	}
+}
+
+func TestStream(t *testing.T) {
+	c, err := ParseKey(testKey)
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	for _, size := range []int{0, 10, chunkSize, 3*chunkSize + 5} {
+		plain := bytes.Repeat([]byte("entry\n"), size/6+1)[:size]
+		var buf bytes.Buffer
+		w := c.NewWriter(&buf)
+		if _, err := w.Write(plain); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+		encrypted := buf.Bytes()
+		if !Streamed(encrypted) {
+			t.Fatalf("Expected an encrypted stream for %d bytes", size)
+		}
+
+		got, err := io.ReadAll(c.NewReader(bytes.NewReader(encrypted)))
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("Expected %d bytes back, got %d (%v)", size, len(got), err)
+		}
+
+		// Cutting off the final chunk is detected
+		if size > chunkSize {
+			cut := encrypted[:len(encrypted)-30]
+			if _, err := io.ReadAll(c.NewReader(bytes.NewReader(cut))); err == nil {
+				t.Errorf("Expected an error for a truncated stream of %d bytes", size)
+			}
+		}
+	}
+
+	// Unencrypted streams are read unchanged
+	got, err := io.ReadAll(c.NewReader(strings.NewReader("plain\n")))
+	if err != nil || string(got) != "plain\n" {
+		t.Errorf("Expected a plain stream unchanged, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,202 @@
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// streamMagic starts every encrypted stream
+var streamMagic = []byte("LPENS1\n")
+
+// chunkSize is the plaintext size of each chunk of a stream
+const chunkSize = 64 << 10
+
+// prefixSize is the random part of the chunk nonces. The rest is the chunk
+// number and a flag marking the last chunk, so chunks cannot be reordered,
+// dropped or cut off at the end without failing authentication.
+const prefixSize = 7
+
+// Streamed reports whether data starts an encrypted stream
+func Streamed(data []byte) bool {
+	return bytes.HasPrefix(data, streamMagic)
+}
+
+// nopCloser adds a Close that does nothing to a writer
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// streamWriter encrypts a stream chunk by chunk
+type streamWriter struct {
+	c       *Cipher
+	w       io.Writer
+	header  []byte
+	started bool
+	buf     []byte
+	counter uint32
+	err     error
+}
+
+// NewWriter returns a writer encrypting what is written to it into w. Close
+// writes the final chunk; it does not close w. A nil Cipher writes to w
+// unchanged.
+func (c *Cipher) NewWriter(w io.Writer) io.WriteCloser {
+	if c == nil {
+		return nopCloser{w}
+	}
+	header := append([]byte(nil), streamMagic...)
+	prefix := make([]byte, prefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		panic(fmt.Sprintf("crypt: failed to read random nonce: %v", err))
+	}
+	return &streamWriter{c: c, w: w, header: append(header, prefix...)}
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.buf = append(s.buf, p...)
+	// A full chunk is held back, as it may turn out to be the last
+	for len(s.buf) > chunkSize {
+		if err := s.writeChunk(s.buf[:chunkSize], false); err != nil {
+			return 0, err
+		}
+		s.buf = s.buf[chunkSize:]
+	}
+	return len(p), nil
+}
+
+func (s *streamWriter) Close() error {
+	if s.err != nil {
+		return s.err
+	}
+	err := s.writeChunk(s.buf, true)
+	s.buf = nil
+	if err == nil {
+		s.err = errors.New("crypt: write to closed stream")
+	}
+	return err
+}
+
+// writeChunk encrypts and writes one chunk, after the header if it is the
+// first
+func (s *streamWriter) writeChunk(plain []byte, last bool) error {
+	if !s.started {
+		if _, err := s.w.Write(s.header); err != nil {
+			s.err = err
+			return err
+		}
+		s.started = true
+	}
+	sealed := s.c.aead.Seal(nil, s.nonce(last), plain, s.header)
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+	if _, err := s.w.Write(append(length[:], sealed...)); err != nil {
+		s.err = err
+		return err
+	}
+	s.counter++
+	return nil
+}
+
+// nonce returns the nonce of the current chunk
+func (s *streamWriter) nonce(last bool) []byte {
+	return chunkNonce(s.c, s.header[len(streamMagic):], s.counter, last)
+}
+
+// chunkNonce builds the nonce of a chunk from the stream's random prefix
+func chunkNonce(c *Cipher, prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, c.aead.NonceSize())
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[prefixSize:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// streamReader decrypts a stream chunk by chunk
+type streamReader struct {
+	c       *Cipher
+	r       *bufio.Reader
+	header  []byte
+	plain   []byte
+	counter uint32
+	done    bool
+	err     error
+}
+
+// NewReader returns a reader decrypting the stream NewWriter wrote to r. A
+// stream that is not encrypted is read unchanged. Reading fails if the
+// stream was modified or cut short.
+func (c *Cipher) NewReader(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(streamMagic)); !bytes.Equal(head, streamMagic) {
+		return br
+	}
+	if c == nil {
+		return &streamReader{err: ErrNoKey}
+	}
+	return &streamReader{c: c, r: br}
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+		s.err = s.next()
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+// next decrypts the next chunk
+func (s *streamReader) next() error {
+	if s.header == nil {
+		s.header = make([]byte, len(streamMagic)+prefixSize)
+		if _, err := io.ReadFull(s.r, s.header); err != nil {
+			return fmt.Errorf("failed to decrypt: stream is truncated")
+		}
+	}
+	var length [4]byte
+	if _, err := io.ReadFull(s.r, length[:]); err != nil {
+		return fmt.Errorf("failed to decrypt: stream is truncated")
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n > chunkSize+uint32(s.c.aead.Overhead()) {
+		return fmt.Errorf("failed to decrypt: corrupted data")
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(s.r, sealed); err != nil {
+		return fmt.Errorf("failed to decrypt: stream is truncated")
+	}
+
+	prefix := s.header[len(streamMagic):]
+	plain, err := s.c.aead.Open(nil, chunkNonce(s.c, prefix, s.counter, false), sealed, s.header)
+	if err != nil {
+		plain, err = s.c.aead.Open(nil, chunkNonce(s.c, prefix, s.counter, true), sealed, s.header)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt: wrong key or corrupted data")
+		}
+		if _, err := s.r.ReadByte(); err != io.EOF {
+			return fmt.Errorf("failed to decrypt: data after the end of the stream")
+		}
+		s.done = true
+	}
+	s.counter++
+	s.plain = plain
+	return nil
+}
@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Notification states understood by systemd
+const (
+	Ready     = "READY=1"
+	Reloading = "RELOADING=1"
+	Stopping  = "STOPPING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// Notify sends a state update to the service manager via $NOTIFY_SOCKET.
+// It reports false without error when not running under systemd.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// A leading @ denotes a socket in the abstract namespace
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify service manager: %w", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns how often watchdog keep-alives must be sent, or
+// false when the watchdog is not enabled for this process
+func WatchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond, true
+}
+
+// RunWatchdog sends keep-alives at half the required interval until done is
+// closed. It returns immediately if the watchdog is not enabled.
+func RunWatchdog(done <-chan struct{}) {
+	interval, ok := WatchdogInterval()
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			Notify(Watchdog)
+		}
+	}
+}
+
+// WritePIDFile writes the current process ID to path, refusing to overwrite
+// the file of another running instance. The returned function removes it.
+func WritePIDFile(path string) (func(), error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && processAlive(pid) {
+			return nil, fmt.Errorf("pid file %s belongs to running process %d", path, pid)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write pid file: %w", err)
+	}
+	return func() { os.Remove(path) }, nil
+}
+
+// processAlive reports whether another process with the given PID exists
+func processAlive(pid int) bool {
+	if pid == os.Getpid() {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
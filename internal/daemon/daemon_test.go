@@ -0,0 +1,77 @@
+package daemon
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	// Without NOTIFY_SOCKET notifications are a no-op
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := Notify(Ready); sent || err != nil {
+		t.Errorf("Expected no notification outside systemd, got %v, %v", sent, err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to create notify socket: %v", err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socketPath)
+	if sent, err := Notify(Ready); !sent || err != nil {
+		t.Fatalf("Expected notification to be sent, got %v, %v", sent, err)
+	}
+
+	buf := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read notification: %v", err)
+	}
+	if string(buf[:n]) != Ready {
+		t.Errorf("Expected %q, got %q", Ready, buf[:n])
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	if d, ok := WatchdogInterval(); !ok || d != 30*time.Second {
+		t.Errorf("Expected a 30s watchdog, got %v, %v", d, ok)
+	}
+
+	// The watchdog belongs to a different process
+	t.Setenv("WATCHDOG_PID", "1")
+	if _, ok := WatchdogInterval(); ok {
+		t.Error("Expected the watchdog to be ignored for another PID")
+	}
+}
+
+func TestWritePIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logprocessor.pid")
+
+	remove, err := WritePIDFile(path)
+	if err != nil {
+		t.Fatalf("Failed to write pid file: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != strconv.Itoa(os.Getpid())+"\n" {
+		t.Errorf("Unexpected pid file contents %q", data)
+	}
+
+	// A stale pid file of our own process is replaced
+	if _, err := WritePIDFile(path); err != nil {
+		t.Errorf("Expected a stale pid file to be replaced: %v", err)
+	}
+
+	remove()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the pid file to be removed")
+	}
+}
@@ -0,0 +1,214 @@
+// Package gcp obtains OAuth access tokens for Google Cloud APIs, from a
+// service account key or the metadata server of the instance the processor
+// runs on.
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CloudPlatformScope grants access to all Google Cloud APIs the credentials
+// are authorised for
+const CloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+const defaultTokenURL = "https://oauth2.googleapis.com/token"
+
+// serviceAccountKey is the part of a JSON service account key in use
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// TokenSource hands out access tokens, fetching a new one shortly before
+// the current one expires
+type TokenSource struct {
+	scope     string
+	key       *rsa.PrivateKey
+	account   serviceAccountKey
+	projectID string
+	client    *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewTokenSource creates a token source for scope. keyFile is a service
+// account key; if empty, GOOGLE_APPLICATION_CREDENTIALS is used, and
+// without that the metadata server.
+func NewTokenSource(keyFile, scope string) (*TokenSource, error) {
+	ts := &TokenSource{scope: scope, client: &http.Client{Timeout: 10 * time.Second}}
+	if keyFile == "" {
+		keyFile = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if keyFile == "" {
+		return ts, nil
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials: %w", err)
+	}
+	if err := json.Unmarshal(data, &ts.account); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials %s: %w", keyFile, err)
+	}
+	if ts.account.Type != "service_account" {
+		return nil, fmt.Errorf("unsupported credentials type %q in %s (expected service_account)", ts.account.Type, keyFile)
+	}
+	block, _ := pem.Decode([]byte(ts.account.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("no private key in %s", keyFile)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to parse the private key in %s: %w", keyFile, err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key in %s is not an RSA key", keyFile)
+	}
+	ts.key = key
+	ts.projectID = ts.account.ProjectID
+	if ts.account.TokenURI == "" {
+		ts.account.TokenURI = defaultTokenURL
+	}
+	return ts, nil
+}
+
+// ProjectID returns the project of the service account, or of the instance
+// when using the metadata server
+func (ts *TokenSource) ProjectID(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.projectID != "" {
+		return ts.projectID, nil
+	}
+	body, err := ts.metadata(ctx, "project/project-id")
+	if err != nil {
+		return "", err
+	}
+	ts.projectID = strings.TrimSpace(string(body))
+	return ts.projectID, nil
+}
+
+// Token returns a valid access token
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token != "" && time.Now().Add(time.Minute).Before(ts.expires) {
+		return ts.token, nil
+	}
+
+	var body []byte
+	var err error
+	if ts.key != nil {
+		body, err = ts.exchangeJWT(ctx)
+	} else {
+		body, err = ts.metadata(ctx, "instance/service-accounts/default/token?scopes="+url.QueryEscape(ts.scope))
+	}
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", fmt.Errorf("failed to parse access token response: %s", body)
+	}
+	ts.token = token.AccessToken
+	ts.expires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// exchangeJWT trades a signed assertion for an access token
+func (ts *TokenSource) exchangeJWT(ctx context.Context) ([]byte, error) {
+	assertion, err := ts.assertion(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return ts.do(req)
+}
+
+// assertion builds the JWT identifying the service account, signed with
+// RS256
+func (ts *TokenSource) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": ts.account.PrivateKeyID})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   ts.account.ClientEmail,
+		"scope": ts.scope,
+		"aud":   ts.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, ts.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// metadata queries the metadata server. GCE_METADATA_HOST overrides its
+// address, as with Google's client libraries.
+func (ts *TokenSource) metadata(ctx context.Context, path string) ([]byte, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	body, err := ts.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("no credentials given and the metadata server is unavailable: %w", err)
+	}
+	return body, nil
+}
+
+func (ts *TokenSource) do(req *http.Request) ([]byte, error) {
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to request access token: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
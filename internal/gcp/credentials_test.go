@@ -0,0 +1,112 @@
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServiceAccountToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		r.ParseForm()
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var c map[string]interface{}
+		json.Unmarshal(claims, &c)
+		if c["iss"] != "loader@project.iam.gserviceaccount.com" || c["scope"] != CloudPlatformScope {
+			http.Error(w, "bad claims", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"token-1","expires_in":3600}`)
+	}))
+	defer server.Close()
+
+	keyFile := filepath.Join(t.TempDir(), "key.json")
+	data, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"project_id":   "project",
+		"client_email": "loader@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL,
+	})
+	os.WriteFile(keyFile, data, 0600)
+
+	ts, err := NewTokenSource(keyFile, CloudPlatformScope)
+	if err != nil {
+		t.Fatalf("Failed to load key: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		token, err := ts.Token(context.Background())
+		if err != nil || token != "token-1" {
+			t.Fatalf("Expected token-1, got %q (%v)", token, err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("Expected the token to be reused, got %d requests", requests)
+	}
+	if project, _ := ts.ProjectID(context.Background()); project != "project" {
+		t.Errorf("Expected the project of the key, got %q", project)
+	}
+}
+
+func TestMetadataToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			fmt.Fprint(w, `{"access_token":"instance-token","expires_in":3600}`)
+		case "/computeMetadata/v1/project/project-id":
+			fmt.Fprint(w, "instance-project")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	ts, err := NewTokenSource("", CloudPlatformScope)
+	if err != nil {
+		t.Fatalf("Failed to create token source: %v", err)
+	}
+	if token, err := ts.Token(context.Background()); err != nil || token != "instance-token" {
+		t.Errorf("Expected the instance token, got %q (%v)", token, err)
+	}
+	if project, err := ts.ProjectID(context.Background()); err != nil || project != "instance-project" {
+		t.Errorf("Expected the instance project, got %q (%v)", project, err)
+	}
+}
@@ -0,0 +1,255 @@
+// Package generate produces synthetic log entries for benchmarking the
+// pipeline and testing rules and alerts without production data.
+package generate
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Formats are the formats entries can be written in
+var Formats = []string{"json", "gcp"}
+
+// DefaultLevels are the level weights used unless configured otherwise
+var DefaultLevels = map[models.LogLevel]float64{
+	models.DEBUG:   10,
+	models.INFO:    70,
+	models.WARNING: 12,
+	models.ERROR:   7,
+	models.FATAL:   1,
+}
+
+// messages are message templates per level; %d is replaced by a number so
+// the entries form realistic patterns
+var messages = map[models.LogLevel][]string{
+	models.DEBUG: {
+		"Cache lookup for key user:%d",
+		"Loaded %d rows from replica",
+		"Retrying idempotent request %d",
+	},
+	models.INFO: {
+		"User %d logged in",
+		"Request GET /api/v1/orders/%d completed in %dms",
+		"Processed batch of %d events",
+		"Health check passed",
+		"Scheduled job %d finished",
+	},
+	models.WARNING: {
+		"Slow query took %dms",
+		"Retrying connection attempt %d",
+		"Memory usage at %d%%",
+		"Rate limit nearly exhausted for client %d",
+	},
+	models.ERROR: {
+		"Connection timeout after %dms",
+		"Failed to process order %d: upstream returned 503",
+		"Payment declined for user %d",
+		"Database deadlock detected on table orders",
+	},
+	models.FATAL: {
+		"Out of memory: killed worker %d",
+		"Unable to bind port %d",
+	},
+}
+
+// corruptions damage an encoded line the way real log files get damaged
+var corruptions = []func(rng *rand.Rand, line []byte) []byte{
+	// Truncated by a crash or rotation
+	func(rng *rand.Rand, line []byte) []byte { return line[:rng.Intn(len(line))] },
+	// Not JSON at all
+	func(rng *rand.Rand, line []byte) []byte { return []byte("panic: runtime error: index out of range") },
+	// Invalid UTF-8 in the message
+	func(rng *rand.Rand, line []byte) []byte {
+		return []byte(strings.Replace(string(line), `"message":"`, "\"message\":\"\xff\xfe", 1))
+	},
+	// A timestamp in an unexpected layout
+	func(rng *rand.Rand, line []byte) []byte {
+		return []byte(strings.Replace(string(line), `"timestamp":"`, `"timestamp":"yesterday `, 1))
+	},
+}
+
+// Config describes the entries to generate
+type Config struct {
+	// Services emit the entries, picked uniformly
+	Services []string
+	// Levels weights the levels; DefaultLevels if empty
+	Levels map[models.LogLevel]float64
+	// Start is the timestamp of the first entry
+	Start time.Time
+	// Rate is how many entries are logged per second on average, spacing
+	// their timestamps
+	Rate float64
+	// Format is one of Formats
+	Format string
+	// Corrupt is the fraction of lines damaged, between 0 and 1
+	Corrupt float64
+	// Seed makes the output reproducible
+	Seed int64
+}
+
+// Generator produces entries as described by a Config
+type Generator struct {
+	cfg    Config
+	rng    *rand.Rand
+	levels []models.LogLevel
+	// cumulative holds the running sum of the weights of levels
+	cumulative []float64
+	next       time.Time
+	count      int
+}
+
+// New creates a generator for cfg
+func New(cfg Config) (*Generator, error) {
+	if len(cfg.Services) == 0 {
+		return nil, fmt.Errorf("no services to generate entries for")
+	}
+	if cfg.Rate <= 0 {
+		return nil, fmt.Errorf("invalid rate %g: must be positive", cfg.Rate)
+	}
+	if cfg.Corrupt < 0 || cfg.Corrupt > 1 {
+		return nil, fmt.Errorf("invalid corruption rate %g: must be between 0 and 1", cfg.Corrupt)
+	}
+	if cfg.Format == "" {
+		cfg.Format = "json"
+	}
+	if cfg.Format != "json" && cfg.Format != "gcp" {
+		return nil, fmt.Errorf("unsupported format %q (supported: %v)", cfg.Format, Formats)
+	}
+	if len(cfg.Levels) == 0 {
+		cfg.Levels = DefaultLevels
+	}
+
+	g := &Generator{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed)), next: cfg.Start}
+	for level := range cfg.Levels {
+		g.levels = append(g.levels, level)
+	}
+	sort.Slice(g.levels, func(i, j int) bool { return g.levels[i].Compare(g.levels[j]) < 0 })
+	var sum float64
+	for _, level := range g.levels {
+		weight := cfg.Levels[level]
+		if weight < 0 {
+			return nil, fmt.Errorf("invalid weight %g for %s", weight, level)
+		}
+		sum += weight
+		g.cumulative = append(g.cumulative, sum)
+	}
+	if sum == 0 {
+		return nil, fmt.Errorf("the level weights add up to zero")
+	}
+	return g, nil
+}
+
+// Next returns the next entry. Timestamps are spaced exponentially, as
+// arrivals of independent events are, with the configured mean rate.
+func (g *Generator) Next() models.LogEntry {
+	g.count++
+	ts := g.next
+	g.next = g.next.Add(time.Duration(g.rng.ExpFloat64() / g.cfg.Rate * float64(time.Second)))
+
+	pick := g.rng.Float64() * g.cumulative[len(g.cumulative)-1]
+	level := g.levels[sort.SearchFloat64s(g.cumulative, pick)]
+	templates, ok := messages[level]
+	if !ok {
+		// Custom levels borrow the messages of a built-in one
+		templates = messages[models.INFO]
+	}
+	template := templates[g.rng.Intn(len(templates))]
+	var args []interface{}
+	for i := 0; i < strings.Count(template, "%d"); i++ {
+		args = append(args, g.rng.Intn(10000))
+	}
+
+	return models.LogEntry{
+		ID:        strconv.Itoa(g.count),
+		Timestamp: ts.UTC(),
+		Level:     level,
+		Service:   g.cfg.Services[g.rng.Intn(len(g.cfg.Services))],
+		Message:   fmt.Sprintf(template, args...),
+	}
+}
+
+// Line returns the next entry encoded in the configured format, possibly
+// corrupted, without a trailing newline
+func (g *Generator) Line() []byte {
+	entry := g.Next()
+	var line []byte
+	if g.cfg.Format == "gcp" {
+		line, _ = json.Marshal(map[string]interface{}{
+			"insertId":    entry.ID,
+			"timestamp":   entry.Timestamp.Format(time.RFC3339Nano),
+			"severity":    gcpSeverity(entry.Level),
+			"textPayload": entry.Message,
+			"resource": map[string]interface{}{
+				"type":   "k8s_container",
+				"labels": map[string]string{"container_name": entry.Service},
+			},
+		})
+	} else {
+		line, _ = json.Marshal(map[string]string{
+			"id":        entry.ID,
+			"timestamp": entry.Timestamp.Format(time.RFC3339Nano),
+			"level":     string(entry.Level),
+			"service":   entry.Service,
+			"message":   entry.Message,
+		})
+	}
+	if g.cfg.Corrupt > 0 && g.rng.Float64() < g.cfg.Corrupt {
+		line = corruptions[g.rng.Intn(len(corruptions))](g.rng, line)
+	}
+	return line
+}
+
+// gcpSeverity maps a level onto a Cloud Logging severity
+func gcpSeverity(level models.LogLevel) string {
+	if level == models.FATAL {
+		return "CRITICAL"
+	}
+	return string(level)
+}
+
+// Write writes n lines to w
+func (g *Generator) Write(w io.Writer, n int) error {
+	bw := bufio.NewWriter(w)
+	for i := 0; i < n; i++ {
+		bw.Write(g.Line())
+		if err := bw.WriteByte('\n'); err != nil {
+			return fmt.Errorf("failed to write entries: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write entries: %w", err)
+	}
+	return nil
+}
+
+// Stream writes lines to w at the configured rate in real time, stamped
+// with the current time, until ctx is cancelled or n lines are written (n
+// of 0 means no limit)
+func (g *Generator) Stream(ctx context.Context, w io.Writer, n int) error {
+	g.next = time.Now()
+	for i := 0; n == 0 || i < n; i++ {
+		if wait := time.Until(g.next); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(wait):
+			}
+		} else if ctx.Err() != nil {
+			return nil
+		}
+		if _, err := w.Write(append(g.Line(), '\n')); err != nil {
+			return fmt.Errorf("failed to write entries: %w", err)
+		}
+	}
+	return nil
+}
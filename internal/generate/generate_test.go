@@ -0,0 +1,88 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestGenerator(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Services: []string{"api", "db"},
+		Levels:   map[models.LogLevel]float64{models.INFO: 3, models.ERROR: 1},
+		Start:    start,
+		Rate:     10,
+		Seed:     42,
+	}
+	g, err := New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	const n = 10000
+	levels := make(map[models.LogLevel]int)
+	var last models.LogEntry
+	for i := 0; i < n; i++ {
+		entry := g.Next()
+		levels[entry.Level]++
+		if entry.Service != "api" && entry.Service != "db" {
+			t.Fatalf("Unexpected service %q", entry.Service)
+		}
+		if entry.Timestamp.Before(last.Timestamp) {
+			t.Fatalf("Expected increasing timestamps, got %v after %v", entry.Timestamp, last.Timestamp)
+		}
+		last = entry
+	}
+	if len(levels) != 2 || levels[models.ERROR] < n/5 || levels[models.ERROR] > n*3/10 {
+		t.Errorf("Expected about a quarter ERROR entries, got %v", levels)
+	}
+	// 10000 entries at 10 per second span about 1000 seconds
+	if span := last.Timestamp.Sub(start); span < 900*time.Second || span > 1100*time.Second {
+		t.Errorf("Expected the entries to span about 1000s, got %v", span)
+	}
+
+	// The same seed gives the same entries
+	var a, b bytes.Buffer
+	g1, _ := New(cfg)
+	g2, _ := New(cfg)
+	g1.Write(&a, 100)
+	g2.Write(&b, 100)
+	if a.String() != b.String() {
+		t.Error("Expected the same output for the same seed")
+	}
+}
+
+func TestGeneratorCorruption(t *testing.T) {
+	g, err := New(Config{Services: []string{"api"}, Rate: 1, Corrupt: 0.1, Seed: 1})
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	var out bytes.Buffer
+	if err := g.Write(&out, 2000); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2000 {
+		t.Fatalf("Expected 2000 lines, got %d", len(lines))
+	}
+	bad := 0
+	for _, line := range lines {
+		var entry models.LogEntry
+		if json.Unmarshal([]byte(line), &entry) != nil {
+			bad++
+		}
+	}
+	// Some corruptions, like invalid UTF-8, still decode
+	if bad < 100 || bad > 250 {
+		t.Errorf("Expected around 10%% undecodable lines, got %d", bad)
+	}
+
+	if _, err := New(Config{Services: []string{"api"}, Rate: 1, Corrupt: 2}); err == nil {
+		t.Error("Expected a corruption rate above 1 to be rejected")
+	}
+}
@@ -0,0 +1,79 @@
+package human
+
+import (
+	"flag"
+	"time"
+)
+
+// Duration is a flag.Value accepting any duration ParseDuration does
+type Duration time.Duration
+
+func (d *Duration) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *Duration) Set(s string) error {
+	v, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// DurationVar defines a duration flag on fs that accepts human-friendly
+// values such as "2d" or "90 minutes"
+func DurationVar(fs *flag.FlagSet, p *time.Duration, name string, value time.Duration, usage string) {
+	*p = value
+	fs.Var((*Duration)(p), name, usage)
+}
+
+// Size is a flag.Value holding a number of bytes, set from any size
+// ParseSize accepts
+type Size int64
+
+func (s *Size) String() string {
+	return FormatSize(int64(*s))
+}
+
+func (s *Size) Set(value string) error {
+	v, err := ParseSize(value)
+	if err != nil {
+		return err
+	}
+	*s = Size(v)
+	return nil
+}
+
+// SizeVar defines a size flag on fs that accepts values such as "64k" or
+// "2GiB"
+func SizeVar(fs *flag.FlagSet, p *int64, name string, value int64, usage string) {
+	*p = value
+	fs.Var((*Size)(p), name, usage)
+}
+
+// Time is a flag.Value holding a point in time, set from any time
+// ParseTime accepts relative to the moment the flag is parsed
+type Time time.Time
+
+func (t *Time) String() string {
+	if time.Time(*t).IsZero() {
+		return ""
+	}
+	return time.Time(*t).Format(time.RFC3339)
+}
+
+func (t *Time) Set(s string) error {
+	v, err := ParseTime(s, time.Now())
+	if err != nil {
+		return err
+	}
+	*t = Time(v)
+	return nil
+}
+
+// TimeVar defines a time flag on fs that accepts values such as
+// "yesterday 09:00" or "2h ago"
+func TimeVar(fs *flag.FlagSet, p *time.Time, name string, usage string) {
+	fs.Var((*Time)(p), name, usage)
+}
@@ -0,0 +1,209 @@
+// Package human parses the durations, sizes and times people type on the
+// command line and in config files, such as "2 days", "2GiB" or
+// "yesterday 09:00", and provides flag values for them.
+package human
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// durationUnits maps unit names to their length. Go's own units are handled
+// by time.ParseDuration.
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond, "msec": time.Millisecond, "millisecond": time.Millisecond, "milliseconds": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// ParseDuration parses a duration such as "15m", "1h30m", "2d", "1.5 hours"
+// or "1 day 6 hours". Days are 24 hours and weeks 7 days.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	rest := strings.TrimSpace(strings.TrimPrefix(s, "-"))
+	if rest == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var total time.Duration
+	for rest != "" {
+		num, unit, next, ok := nextQuantity(rest)
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		length, ok := durationUnits[strings.ToLower(unit)]
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q: unknown unit %q", s, unit)
+		}
+		total += time.Duration(num * float64(length))
+		rest = next
+	}
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+// nextQuantity splits a number and the unit following it off s, e.g.
+// "1.5 hours 10m" into 1.5, "hours" and "10m"
+func nextQuantity(s string) (float64, string, string, bool) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	num, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", "", false
+	}
+	s = strings.TrimLeft(s[i:], " ")
+	j := 0
+	for j < len(s) && unicode.IsLetter(rune(s[j])) {
+		j++
+	}
+	if j == 0 {
+		return 0, "", "", false
+	}
+	rest := strings.TrimLeft(s[j:], " ,")
+	rest = strings.TrimPrefix(rest, "and ")
+	return num, s[:j], rest, true
+}
+
+// sizeUnits maps size suffixes, lower-cased, to their number of bytes.
+// Single letters are binary, like most tools that take sizes.
+var sizeUnits = map[string]float64{
+	"": 1, "b": 1, "byte": 1, "bytes": 1,
+	"k": 1 << 10, "kib": 1 << 10, "kb": 1e3,
+	"m": 1 << 20, "mib": 1 << 20, "mb": 1e6,
+	"g": 1 << 30, "gib": 1 << 30, "gb": 1e9,
+	"t": 1 << 40, "tib": 1 << 40, "tb": 1e12,
+}
+
+// ParseSize parses a number of bytes such as "512", "64k", "2GiB" or
+// "1.5 MB". KB, MB, GB and TB are decimal, KiB, MiB, GiB and TiB as well as
+// plain K, M, G and T binary.
+func ParseSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	i := 0
+	for i < len(trimmed) && (trimmed[i] >= '0' && trimmed[i] <= '9' || trimmed[i] == '.') {
+		i++
+	}
+	num, err := strconv.ParseFloat(trimmed[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(trimmed[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", s, strings.TrimSpace(trimmed[i:]))
+	}
+	bytes := num * unit
+	if bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(bytes), nil
+}
+
+// FormatSize renders a number of bytes with a binary unit, e.g. 2GiB
+func FormatSize(n int64) string {
+	units := []string{"KiB", "MiB", "GiB", "TiB"}
+	if n < 1<<10 || n%(1<<10) != 0 {
+		return strconv.FormatInt(n, 10)
+	}
+	unit := ""
+	for _, u := range units {
+		if n%(1<<10) != 0 {
+			break
+		}
+		n /= 1 << 10
+		unit = u
+	}
+	return strconv.FormatInt(n, 10) + unit
+}
+
+// timeLayouts are the absolute time formats ParseTime accepts, in local
+// time unless they carry an offset
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// clockLayouts are the times of day accepted after a day
+var clockLayouts = []string{"15:04:05", "15:04", "3pm", "3:04pm"}
+
+// ParseTime parses a point in time relative to now: an absolute time such
+// as "2023-01-01 09:00" or RFC 3339, "now", a day ("today", "yesterday",
+// "tomorrow" or a weekday, meaning its latest occurrence) optionally followed
+// by a time of day, a time of day alone meaning today, or a duration with
+// "ago" such as "2 hours ago". Times without an offset are in now's location.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	trimmed := strings.TrimSpace(s)
+	lower := strings.ToLower(trimmed)
+	loc := now.Location()
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, trimmed, loc); err == nil {
+			return t, nil
+		}
+	}
+	if lower == "now" {
+		return now, nil
+	}
+	if ago, ok := strings.CutSuffix(lower, " ago"); ok {
+		d, err := ParseDuration(ago)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %w", s, err)
+		}
+		return now.Add(-d), nil
+	}
+
+	day, clock, _ := strings.Cut(lower, " ")
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	start, ok := dayStart(day, midnight)
+	if !ok {
+		// A time of day alone
+		start, clock = midnight, lower
+	}
+	if clock = strings.TrimPrefix(strings.TrimSpace(clock), "at "); clock == "" {
+		return start, nil
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, clock); err == nil {
+			return start.Add(time.Duration(t.Hour())*time.Hour +
+				time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// dayStart returns the start of a named day relative to today's midnight
+func dayStart(name string, midnight time.Time) (time.Time, bool) {
+	switch name {
+	case "today":
+		return midnight, true
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), true
+	case "tomorrow":
+		return midnight.AddDate(0, 0, 1), true
+	}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if name == strings.ToLower(wd.String()) {
+			back := (int(midnight.Weekday()) - int(wd) + 7) % 7
+			return midnight.AddDate(0, 0, -back), true
+		}
+	}
+	return time.Time{}, false
+}
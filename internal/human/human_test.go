@@ -0,0 +1,117 @@
+package human
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"15m":                15 * time.Minute,
+		"1h30m":              90 * time.Minute,
+		"2d":                 48 * time.Hour,
+		"1w":                 7 * 24 * time.Hour,
+		"1.5 hours":          90 * time.Minute,
+		"1 day 6 hours":      30 * time.Hour,
+		"2 days and 3 hours": 51 * time.Hour,
+		"90 minutes":         90 * time.Minute,
+	}
+	for input, want := range tests {
+		got, err := ParseDuration(input)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Expected %q to be %v, got %v", input, want, got)
+		}
+	}
+	for _, input := range []string{"", "soon", "5 fortnights", "d"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("Expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":    512,
+		"64k":    64 << 10,
+		"2GiB":   2 << 30,
+		"1.5 MB": 1500000,
+		"10 mib": 10 << 20,
+	}
+	for input, want := range tests {
+		got, err := ParseSize(input)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("Expected %q to be %d bytes, got %d", input, want, got)
+		}
+	}
+	if _, err := ParseSize("2 parsecs"); err == nil {
+		t.Error("Expected an unknown unit to be rejected")
+	}
+	if s := FormatSize(2 << 30); s != "2GiB" {
+		t.Errorf("Expected 2GiB, got %s", s)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2023, 3, 15, 14, 30, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"now":                  now,
+		"yesterday 09:00":      time.Date(2023, 3, 14, 9, 0, 0, 0, time.UTC),
+		"today":                time.Date(2023, 3, 15, 0, 0, 0, 0, time.UTC),
+		"tomorrow at 3pm":      time.Date(2023, 3, 16, 15, 0, 0, 0, time.UTC),
+		"08:15":                time.Date(2023, 3, 15, 8, 15, 0, 0, time.UTC),
+		"monday 10:00":         time.Date(2023, 3, 13, 10, 0, 0, 0, time.UTC),
+		"2 hours ago":          now.Add(-2 * time.Hour),
+		"2023-01-01":           time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		"2023-01-01 12:00":     time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+		"2023-01-01T12:00:00Z": time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	for input, want := range tests {
+		got, err := ParseTime(input, now)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", input, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("Expected %q to be %v, got %v", input, want, got)
+		}
+	}
+	for _, input := range []string{"someday", "yesterday noonish", "25:00"} {
+		if _, err := ParseTime(input, now); err == nil {
+			t.Errorf("Expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var (
+		window time.Duration
+		limit  int64
+		since  time.Time
+	)
+	DurationVar(fs, &window, "window", time.Minute, "")
+	SizeVar(fs, &limit, "max-memory", 0, "")
+	TimeVar(fs, &since, "since", "")
+	if window != time.Minute {
+		t.Errorf("Expected the default window to be set, got %v", window)
+	}
+	if err := fs.Parse([]string{"-window", "2d", "-max-memory", "2GiB", "-since", "2h ago"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if window != 48*time.Hour || limit != 2<<30 || since.IsZero() {
+		t.Errorf("Unexpected flag values %v, %d, %v", window, limit, since)
+	}
+	if err := fs.Parse([]string{"-window", "a while"}); err == nil {
+		t.Error("Expected an invalid duration flag to be rejected")
+	}
+}
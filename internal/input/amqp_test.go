@@ -0,0 +1,157 @@
+package input
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+)
+
+// fakeBroker serves one AMQP client: it accepts the credentials guest and
+// secret, delivers messages to the consumer of queue "logs" and reports
+// the acks and rejects it receives on results
+func fakeBroker(listener net.Listener, messages []string, results chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	srv := &amqpConn{conn: conn, reader: bufio.NewReader(conn)}
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(srv.reader, header); err != nil {
+		return
+	}
+	// next returns the arguments of the next method the client sends
+	next := func() (uint32, []byte) {
+		_, _, payload, err := srv.readFrame()
+		if err != nil || len(payload) < 4 {
+			return 0, nil
+		}
+		return binary.BigEndian.Uint32(payload), payload[4:]
+	}
+
+	var w amqpWriter
+	w.octet(0)
+	w.octet(9)
+	w.table(nil)
+	w.longstr("AMQPLAIN PLAIN")
+	w.longstr("en_US")
+	srv.writeMethod(0, amqpConnectionStart, w.bytes())
+	if _, args := next(); !strings.Contains(string(args), "\x00guest\x00secret") {
+		// Wrong credentials close the connection
+		return
+	}
+	w = amqpWriter{}
+	w.short(0)
+	w.long(amqpMaxFrame)
+	w.short(0)
+	srv.writeMethod(0, amqpConnectionTune, w.bytes())
+	next()
+	next()
+	srv.writeMethod(0, amqpConnectionOpenOk, []byte{0})
+	next()
+	srv.writeMethod(amqpChannel, amqpChannelOpenOk, []byte{0, 0, 0, 0})
+	next()
+	srv.writeMethod(amqpChannel, amqpBasicQosOk, nil)
+	if _, args := next(); !strings.Contains(string(args), "logs") {
+		srv.writeMethod(amqpChannel, amqpChannelClose, []byte{1, 148, 0})
+		return
+	}
+	srv.writeMethod(amqpChannel, amqpBasicConsumeOk, []byte{4, 'c', 't', 'a', 'g'})
+
+	for i, m := range messages {
+		w = amqpWriter{}
+		w.shortstr("ctag")
+		w.longlong(uint64(i + 1))
+		w.octet(0)
+		w.shortstr("")
+		w.shortstr("logs")
+		srv.writeMethod(amqpChannel, amqpBasicDeliver, w.bytes())
+
+		// content-type and message-id set
+		w = amqpWriter{}
+		w.short(60)
+		w.short(0)
+		w.longlong(uint64(len(m)))
+		w.short(1<<15 | 1<<7)
+		w.shortstr("application/x-ndjson")
+		w.shortstr(fmt.Sprintf("msg-%d", i+1))
+		srv.writeFrame(amqpFrameHeader, amqpChannel, w.bytes())
+		// Split the body across two frames
+		half := len(m) / 2
+		srv.writeFrame(amqpFrameBody, amqpChannel, []byte(m[:half]))
+		srv.writeFrame(amqpFrameBody, amqpChannel, []byte(m[half:]))
+	}
+	for range messages {
+		method, args := next()
+		r := amqpReader{b: args}
+		tag := r.longlong()
+		switch method {
+		case amqpBasicAck:
+			results <- fmt.Sprintf("ack %d", tag)
+		case amqpBasicReject:
+			results <- fmt.Sprintf("reject %d", tag)
+		}
+	}
+	// Keep the connection open until the client leaves
+	io.Copy(io.Discard, conn)
+}
+
+func TestAMQPSource(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	results := make(chan string, 2)
+	go fakeBroker(listener, []string{
+		`{"timestamp":"2023-01-01T10:00:00Z","level":"ERROR","service":"api","message":"failed"}` + "\n",
+		"not json",
+	}, results)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries := make(chan models.LogEntry, 4)
+	source := NewAMQPSource("amqp://guest:secret@"+listener.Addr().String()+"/", "logs", 10, parser.JSONParser{})
+	done := make(chan error, 1)
+	go func() {
+		done <- source.Run(ctx, func(e models.LogEntry) error {
+			entries <- e
+			return nil
+		})
+	}()
+
+	for _, want := range []string{"ack 1", "reject 2"} {
+		select {
+		case got := <-results:
+			if got != want {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for acknowledgements")
+		}
+	}
+
+	select {
+	case e := <-entries:
+		// IDs derive from the message ID, so redeliveries are duplicates
+		if e.ID != "amqp:msg-1:0" || e.Source != "amqp" || e.Level != models.ERROR {
+			t.Errorf("Unexpected entry %+v", e)
+		}
+	default:
+		t.Fatal("Expected an entry")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Run to stop cleanly, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package input
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+)
+
+// brokerMaxDelay bounds the wait between attempts to reconnect to a broker
+const brokerMaxDelay = 30 * time.Second
+
+// consumeReconnecting runs consume until ctx is cancelled, reconnecting
+// with a growing delay whenever the connection to the broker is lost
+func consumeReconnecting(ctx context.Context, broker string, consume func(ctx context.Context) error) error {
+	delay := time.Second
+	for {
+		start := time.Now()
+		err := consume(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		// A connection that worked for a while starts over with a short delay
+		if time.Since(start) > brokerMaxDelay {
+			delay = time.Second
+		}
+		fmt.Printf("%s: %v; reconnecting in %s\n", broker, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+		if delay *= 2; delay > brokerMaxDelay {
+			delay = brokerMaxDelay
+		}
+	}
+}
+
+// decodeMessage parses the NDJSON entries of a broker message. Entries
+// without an ID get one derived from id, which identifies the message in
+// the broker, so a redelivered message is recognized as a duplicate.
+func decodeMessage(p parser.JSONParser, body []byte, source, id string) ([]models.LogEntry, error) {
+	var entries []models.LogEntry
+	err := p.Parse(bytes.NewReader(body), func(entry models.LogEntry) error {
+		entry.Source = source
+		if entry.ID == "" && id != "" {
+			entry.ID = fmt.Sprintf("%s:%s:%d", source, id, len(entries))
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
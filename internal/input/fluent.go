@@ -0,0 +1,180 @@
+package input
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// FluentSource accepts entries from Fluentd and Fluent Bit agents speaking
+// the Forward protocol (msgpack over TCP). Message, Forward, PackedForward and
+// gzip-compressed PackedForward modes are supported, and chunk options are
+// acknowledged. Shared-key authentication is not implemented.
+type FluentSource struct {
+	addr string
+}
+
+// NewFluentSource creates a Forward protocol listener on addr, e.g. ":24224"
+func NewFluentSource(addr string) *FluentSource {
+	return &FluentSource{addr: addr}
+}
+
+// Run accepts connections until ctx is cancelled
+func (f *FluentSource) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	listener, err := net.Listen("tcp", f.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", f.addr, err)
+	}
+	return serveTCP(ctx, listener, func(conn net.Conn) {
+		if err := serveFluentConn(ctx, conn, emit); err != nil && ctx.Err() == nil {
+			fmt.Printf("Fluent connection from %s: %v\n", conn.RemoteAddr(), err)
+		}
+	})
+}
+
+// serveFluentConn decodes Forward protocol messages until the peer disconnects
+func serveFluentConn(ctx context.Context, conn net.Conn, emit func(models.LogEntry) error) error {
+	reader := newMsgpackReader(conn)
+	for {
+		msg, err := reader.Decode()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		arr, ok := msg.([]interface{})
+		if !ok || len(arr) < 2 {
+			return fmt.Errorf("unexpected forward message %v", msg)
+		}
+		tag, _ := arr[0].(string)
+
+		options, err := handleForwardMessage(tag, arr, emit)
+		if err != nil {
+			return err
+		}
+
+		// Acknowledge the chunk once all of its entries have been accepted
+		if chunk, ok := options["chunk"]; ok {
+			ack := appendMsgpack(nil, map[string]interface{}{"ack": chunk})
+			if _, err := conn.Write(ack); err != nil {
+				return fmt.Errorf("failed to send ack: %w", err)
+			}
+		}
+	}
+}
+
+// handleForwardMessage emits the entries of one message and returns its options
+func handleForwardMessage(tag string, arr []interface{}, emit func(models.LogEntry) error) (map[string]interface{}, error) {
+	optionsAt := func(i int) map[string]interface{} {
+		if len(arr) > i {
+			if opts, ok := arr[i].(map[string]interface{}); ok {
+				return opts
+			}
+		}
+		return nil
+	}
+
+	switch events := arr[1].(type) {
+	case []interface{}:
+		// Forward mode: [tag, [[time, record], ...], options]
+		for _, event := range events {
+			if err := emitFluentEvent(tag, event, emit); err != nil {
+				return nil, err
+			}
+		}
+		return optionsAt(2), nil
+
+	case string, []byte:
+		// PackedForward mode: [tag, <concatenated msgpack events>, options]
+		options := optionsAt(2)
+		var packed []byte
+		if s, ok := events.(string); ok {
+			packed = []byte(s)
+		} else {
+			packed = events.([]byte)
+		}
+
+		var r io.Reader = bytes.NewReader(packed)
+		if options["compressed"] == "gzip" {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress packed entries: %w", err)
+			}
+			defer gz.Close()
+			r = gz
+		}
+
+		reader := newMsgpackReader(r)
+		for {
+			event, err := reader.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode packed entry: %w", err)
+			}
+			if err := emitFluentEvent(tag, event, emit); err != nil {
+				return nil, err
+			}
+		}
+		return options, nil
+
+	default:
+		// Message mode: [tag, time, record, options]
+		if len(arr) < 3 {
+			return nil, fmt.Errorf("message mode entry without a record")
+		}
+		if err := emitFluentEvent(tag, []interface{}{arr[1], arr[2]}, emit); err != nil {
+			return nil, err
+		}
+		return optionsAt(3), nil
+	}
+}
+
+// emitFluentEvent converts a [time, record] pair into a LogEntry
+func emitFluentEvent(tag string, event interface{}, emit func(models.LogEntry) error) error {
+	pair, ok := event.([]interface{})
+	if !ok || len(pair) < 2 {
+		return fmt.Errorf("unexpected forward event %v", event)
+	}
+	record, ok := pair[1].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("forward event record is not a map")
+	}
+
+	ts, err := fluentTime(pair[0])
+	if err != nil {
+		return err
+	}
+	return emit(recordToEntry(record, ts, tag, "fluent:"+tag))
+}
+
+// fluentTime decodes an integer epoch or an EventTime extension value
+func fluentTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case int64:
+		return time.Unix(t, 0).UTC(), nil
+	case uint64:
+		return time.Unix(int64(t), 0).UTC(), nil
+	case float64:
+		return time.Unix(0, int64(t*float64(time.Second))).UTC(), nil
+	case msgpackExt:
+		if t.Type != 0 || len(t.Data) != 8 {
+			return time.Time{}, fmt.Errorf("unsupported time extension type %d", t.Type)
+		}
+		sec := binary.BigEndian.Uint32(t.Data[:4])
+		nsec := binary.BigEndian.Uint32(t.Data[4:])
+		return time.Unix(int64(sec), int64(nsec)).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported event time %v", v)
+	}
+}
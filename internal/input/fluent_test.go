@@ -0,0 +1,171 @@
+package input
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func eventTime(t time.Time) msgpackExt {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data[:4], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(data[4:], uint32(t.Nanosecond()))
+	return msgpackExt{Type: 0, Data: data}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	value := map[string]interface{}{
+		"s":   "hello",
+		"n":   int64(-5),
+		"big": int64(1 << 40),
+		"f":   1.5,
+		"b":   true,
+		"nil": nil,
+		"arr": []interface{}{int64(1), "two"},
+	}
+
+	decoded, err := newMsgpackReader(bytes.NewReader(appendMsgpack(nil, value))).Decode()
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	m := decoded.(map[string]interface{})
+	if m["s"] != "hello" || m["n"] != int64(-5) || m["big"] != int64(1<<40) || m["f"] != 1.5 || m["b"] != true {
+		t.Errorf("Unexpected round trip result: %v", m)
+	}
+	if arr := m["arr"].([]interface{}); len(arr) != 2 || arr[1] != "two" {
+		t.Errorf("Unexpected array: %v", arr)
+	}
+
+	// Compact encodings produced by other implementations
+	compact := []byte{0x93, 0x01, 0xd0, 0xfe, 0xa2, 'h', 'i'}
+	decoded, err = newMsgpackReader(bytes.NewReader(compact)).Decode()
+	if err != nil {
+		t.Fatalf("Failed to decode compact value: %v", err)
+	}
+	if arr := decoded.([]interface{}); arr[0] != int64(1) || arr[1] != int64(-2) || arr[2] != "hi" {
+		t.Errorf("Unexpected compact result: %v", arr)
+	}
+}
+
+func TestMsgpackLimits(t *testing.T) {
+	// A 5-byte header announcing a huge array or string must not allocate it
+	for _, header := range [][]byte{
+		{0xdd, 0x03, 0xff, 0xff, 0xff},
+		{0xdf, 0x03, 0xff, 0xff, 0xff},
+		{0xdb, 0x03, 0xff, 0xff, 0xff},
+	} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		if _, err := newMsgpackReader(bytes.NewReader(header)).Decode(); err == nil {
+			t.Errorf("Expected an error for the truncated value % x", header)
+		}
+		runtime.ReadMemStats(&after)
+		if grown := after.TotalAlloc - before.TotalAlloc; grown > 16*1024*1024 {
+			t.Errorf("Expected little memory for % x, allocated %d bytes", header, grown)
+		}
+	}
+
+	// Nesting beyond the limit is rejected instead of recursing on
+	nested := bytes.Repeat([]byte{0x91}, maxMsgpackDepth+1)
+	if _, err := newMsgpackReader(bytes.NewReader(append(nested, 0xc0))).Decode(); err == nil {
+		t.Error("Expected an error for deeply nested arrays")
+	}
+	nested = bytes.Repeat([]byte{0x91}, maxMsgpackDepth)
+	if _, err := newMsgpackReader(bytes.NewReader(append(nested, 0xc0))).Decode(); err != nil {
+		t.Errorf("Expected arrays nested %d deep to decode, got %v", maxMsgpackDepth, err)
+	}
+}
+
+func TestFluentForwardModes(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 10, 0, 0, 500, time.UTC)
+	record := func(msg string) map[string]interface{} {
+		return map[string]interface{}{"log": msg + "\n", "level": "error", "pod": "api-1"}
+	}
+
+	// Build a gzip-compressed PackedForward payload
+	var packed []byte
+	packed = appendMsgpack(packed, []interface{}{eventTime(ts), record("packed 1")})
+	packed = appendMsgpack(packed, []interface{}{eventTime(ts), record("packed 2")})
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(packed)
+	gz.Close()
+
+	messages := [][]byte{
+		// Message mode
+		appendMsgpack(nil, []interface{}{"app.api", int64(ts.Unix()), record("message")}),
+		// Forward mode with a chunk to acknowledge
+		appendMsgpack(nil, []interface{}{"app.api", []interface{}{
+			[]interface{}{eventTime(ts), record("forward 1")},
+			[]interface{}{eventTime(ts), record("forward 2")},
+		}, map[string]interface{}{"chunk": "c1"}}),
+		// Compressed PackedForward mode
+		appendMsgpack(nil, []interface{}{"app.db", compressed.Bytes(), map[string]interface{}{"compressed": "gzip", "chunk": "c2"}}),
+	}
+
+	server, client := net.Pipe()
+	var (
+		mu      sync.Mutex
+		entries []models.LogEntry
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- serveFluentConn(context.Background(), server, func(e models.LogEntry) error {
+			mu.Lock()
+			entries = append(entries, e)
+			mu.Unlock()
+			return nil
+		})
+	}()
+
+	// The pipe is synchronous, so read each ack before sending more data
+	var acks []string
+	reader := newMsgpackReader(client)
+	for i, msg := range messages {
+		if _, err := client.Write(msg); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+		if i == 0 {
+			continue
+		}
+		ack, err := reader.Decode()
+		if err != nil {
+			t.Fatalf("Failed to read ack: %v", err)
+		}
+		acks = append(acks, ack.(map[string]interface{})["ack"].(string))
+	}
+	client.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Connection failed: %v", err)
+	}
+
+	if len(acks) != 2 || acks[0] != "c1" || acks[1] != "c2" {
+		t.Errorf("Expected acks for c1 and c2, got %v", acks)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 entries, got %d", len(entries))
+	}
+
+	first := entries[0]
+	if first.Message != "message" || first.Level != models.ERROR || first.Service != "app.api" || first.Source != "fluent:app.api" {
+		t.Errorf("Unexpected message mode entry: %+v", first)
+	}
+	if first.Fields["pod"] != "api-1" {
+		t.Errorf("Expected remaining keys as fields, got %v", first.Fields)
+	}
+	if !entries[1].Timestamp.Equal(ts) {
+		t.Errorf("Expected EventTime %v, got %v", ts, entries[1].Timestamp)
+	}
+	if entries[4].Message != "packed 2" || entries[4].Service != "app.db" {
+		t.Errorf("Unexpected packed entry: %+v", entries[4])
+	}
+}
@@ -0,0 +1,305 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const (
+	// gelfChunkTimeout is how long the chunks of a message may take to arrive
+	gelfChunkTimeout = 5 * time.Second
+	// gelfMaxChunks is the protocol limit on chunks per message
+	gelfMaxChunks = 128
+	// gelfMaxPending bounds the number of partially received messages
+	gelfMaxPending = 1024
+	// gelfMaxSize bounds the decompressed size of a message, and the size of
+	// a TCP frame
+	gelfMaxSize = 8 * 1024 * 1024
+)
+
+// gelfChunkMagic prefixes every chunk of a chunked UDP message
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+// GELFUDPSource receives GELF messages over UDP, including gzip/zlib
+// compressed and chunked messages
+type GELFUDPSource struct {
+	addr string
+}
+
+// NewGELFUDPSource creates a GELF UDP listener on addr, e.g. ":12201"
+func NewGELFUDPSource(addr string) *GELFUDPSource {
+	return &GELFUDPSource{addr: addr}
+}
+
+// Run receives datagrams until ctx is cancelled
+func (g *GELFUDPSource) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	conn, err := net.ListenPacket("udp", g.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", g.addr, err)
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	assembler := newGELFAssembler()
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read datagram: %w", err)
+		}
+
+		payload := assembler.add(buf[:n], time.Now())
+		if payload == nil {
+			continue
+		}
+		entry, err := decodeGELF(payload, "gelf-udp")
+		if err != nil {
+			// A bad datagram only loses that message
+			fmt.Printf("Dropping GELF message: %v\n", err)
+			continue
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// GELFTCPSource receives null-byte delimited GELF messages over TCP
+type GELFTCPSource struct {
+	addr string
+}
+
+// NewGELFTCPSource creates a GELF TCP listener on addr, e.g. ":12201"
+func NewGELFTCPSource(addr string) *GELFTCPSource {
+	return &GELFTCPSource{addr: addr}
+}
+
+// Run accepts connections until ctx is cancelled
+func (g *GELFTCPSource) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	listener, err := net.Listen("tcp", g.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", g.addr, err)
+	}
+	return serveTCP(ctx, listener, func(conn net.Conn) {
+		if err := serveGELFConn(conn, emit); err != nil && ctx.Err() == nil {
+			fmt.Printf("GELF connection from %s: %v\n", conn.RemoteAddr(), err)
+		}
+	})
+}
+
+// serveGELFConn reads null-delimited messages until the peer disconnects or
+// sends a frame larger than gelfMaxSize
+func serveGELFConn(conn io.Reader, emit func(models.LogEntry) error) error {
+	reader := bufio.NewReader(conn)
+	for {
+		frame, err := readGELFFrame(reader)
+		if len(frame) > 0 && frame[len(frame)-1] == 0 {
+			frame = frame[:len(frame)-1]
+		}
+		if len(bytes.TrimSpace(frame)) > 0 {
+			entry, decodeErr := decodeGELF(frame, "gelf-tcp")
+			if decodeErr != nil {
+				return decodeErr
+			}
+			if emitErr := emit(entry); emitErr != nil {
+				return emitErr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// readGELFFrame reads up to and including the next null byte, failing once
+// the frame exceeds gelfMaxSize rather than buffering an endless one
+func readGELFFrame(reader *bufio.Reader) ([]byte, error) {
+	var frame []byte
+	for {
+		part, err := reader.ReadSlice(0)
+		if len(frame)+len(part) > gelfMaxSize+1 {
+			return nil, fmt.Errorf("GELF frame exceeds %d bytes", gelfMaxSize)
+		}
+		frame = append(frame, part...)
+		if err != bufio.ErrBufferFull {
+			return frame, err
+		}
+	}
+}
+
+// gelfAssembler reassembles chunked UDP messages
+type gelfAssembler struct {
+	mu      sync.Mutex
+	pending map[string]*gelfChunks
+}
+
+type gelfChunks struct {
+	first    time.Time
+	chunks   [][]byte
+	received int
+}
+
+func newGELFAssembler() *gelfAssembler {
+	return &gelfAssembler{pending: make(map[string]*gelfChunks)}
+}
+
+// add accepts a datagram and returns a complete message payload, or nil when
+// more chunks are needed or the datagram was invalid
+func (a *gelfAssembler) add(datagram []byte, now time.Time) []byte {
+	if !bytes.HasPrefix(datagram, gelfChunkMagic) {
+		return append([]byte(nil), datagram...)
+	}
+	// magic (2) + message ID (8) + sequence number (1) + sequence count (1)
+	if len(datagram) < 12 {
+		return nil
+	}
+	id := string(datagram[2:10])
+	seq, count := int(datagram[10]), int(datagram[11])
+	if count == 0 || count > gelfMaxChunks || seq >= count {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.expire(now)
+
+	msg, ok := a.pending[id]
+	if !ok {
+		if len(a.pending) >= gelfMaxPending {
+			return nil
+		}
+		msg = &gelfChunks{first: now, chunks: make([][]byte, count)}
+		a.pending[id] = msg
+	}
+	if len(msg.chunks) != count || msg.chunks[seq] != nil {
+		return nil
+	}
+	msg.chunks[seq] = append([]byte(nil), datagram[12:]...)
+	msg.received++
+
+	if msg.received < count {
+		return nil
+	}
+	delete(a.pending, id)
+	return bytes.Join(msg.chunks, nil)
+}
+
+// expire drops messages whose chunks did not all arrive in time
+func (a *gelfAssembler) expire(now time.Time) {
+	for id, msg := range a.pending {
+		if now.Sub(msg.first) > gelfChunkTimeout {
+			delete(a.pending, id)
+		}
+	}
+}
+
+// decodeGELF decompresses if needed and maps a GELF message onto a LogEntry
+func decodeGELF(payload []byte, source string) (models.LogEntry, error) {
+	data, err := decompressGELF(payload)
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return models.LogEntry{}, fmt.Errorf("invalid GELF JSON: %w", err)
+	}
+
+	entry := models.LogEntry{
+		Level:  models.INFO,
+		Source: source,
+		Fields: make(map[string]string),
+	}
+	entry.Message, _ = msg["short_message"].(string)
+
+	if ts, ok := msg["timestamp"].(float64); ok {
+		sec, frac := math.Modf(ts)
+		entry.Timestamp = time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3).UTC()
+	} else {
+		entry.Timestamp = time.Now().UTC()
+	}
+	// The spec defaults a missing level to ALERT, but senders that omit it
+	// almost always mean an ordinary message
+	if level, ok := msg["level"].(float64); ok {
+		entry.Level = syslogLevel(int(level))
+	}
+
+	for k, v := range msg {
+		value := scalarString(v)
+		if value == "" {
+			continue
+		}
+		switch k {
+		case "host", "full_message":
+			entry.Fields[k] = value
+		case "_service", "_application_name", "_app":
+			entry.Service = value
+		default:
+			if strings.HasPrefix(k, "_") {
+				entry.Fields[k[1:]] = value
+			}
+		}
+	}
+	if entry.Service == "" {
+		entry.Service = entry.Fields["host"]
+	}
+
+	return entry, nil
+}
+
+// decompressGELF detects gzip and zlib payloads by their magic bytes
+func decompressGELF(payload []byte) ([]byte, error) {
+	var r io.ReadCloser
+	var err error
+	switch {
+	case len(payload) >= 2 && payload[0] == 0x1f && payload[1] == 0x8b:
+		r, err = gzip.NewReader(bytes.NewReader(payload))
+	case len(payload) >= 2 && payload[0] == 0x78 && (int(payload[0])<<8|int(payload[1]))%31 == 0:
+		r, err = zlib.NewReader(bytes.NewReader(payload))
+	default:
+		return payload, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress GELF message: %w", err)
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, gelfMaxSize))
+}
+
+// syslogLevel maps syslog severities (0-7) used by GELF onto LogLevel
+func syslogLevel(level int) models.LogLevel {
+	switch {
+	case level <= 2:
+		return models.FATAL
+	case level == 3:
+		return models.ERROR
+	case level == 4:
+		return models.WARNING
+	case level == 7:
+		return models.DEBUG
+	default:
+		return models.INFO
+	}
+}
@@ -0,0 +1,120 @@
+package input
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestDecodeGELF(t *testing.T) {
+	payload := `{"version":"1.1","host":"web-1","short_message":"Disk full","full_message":"trace...","timestamp":1672567200.25,"level":3,"_service":"storage","_request_id":"r-1","_attempt":2}`
+
+	// zlib compressed payloads are detected automatically
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write([]byte(payload))
+	zw.Close()
+
+	for _, data := range [][]byte{[]byte(payload), compressed.Bytes()} {
+		entry, err := decodeGELF(data, "gelf-udp")
+		if err != nil {
+			t.Fatalf("Failed to decode GELF: %v", err)
+		}
+		if entry.Message != "Disk full" || entry.Level != models.ERROR || entry.Service != "storage" {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+		if !entry.Timestamp.Equal(time.Date(2023, 1, 1, 10, 0, 0, 250000000, time.UTC)) {
+			t.Errorf("Unexpected timestamp %v", entry.Timestamp)
+		}
+		if entry.Fields["request_id"] != "r-1" || entry.Fields["attempt"] != "2" || entry.Fields["host"] != "web-1" {
+			t.Errorf("Unexpected fields: %v", entry.Fields)
+		}
+	}
+
+	// Without a service field the host identifies the sender
+	entry, err := decodeGELF([]byte(`{"host":"db-1","short_message":"hi"}`), "gelf-tcp")
+	if err != nil {
+		t.Fatalf("Failed to decode GELF: %v", err)
+	}
+	if entry.Service != "db-1" || entry.Level != models.INFO {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+}
+
+func TestGELFAssembler(t *testing.T) {
+	message := []byte(`{"short_message":"` + strings.Repeat("x", 100) + `"}`)
+	chunk := func(id string, seq, count int, data []byte) []byte {
+		out := append([]byte{0x1e, 0x0f}, id...)
+		out = append(out, byte(seq), byte(count))
+		return append(out, data...)
+	}
+
+	now := time.Now()
+	a := newGELFAssembler()
+
+	// Chunks may arrive out of order
+	if a.add(chunk("msgid-01", 1, 2, message[50:]), now) != nil {
+		t.Fatal("Expected no message after the first chunk")
+	}
+	if a.add(chunk("msgid-01", 1, 2, message[50:]), now) != nil {
+		t.Fatal("Expected duplicate chunks to be ignored")
+	}
+	got := a.add(chunk("msgid-01", 0, 2, message[:50]), now)
+	if !bytes.Equal(got, message) {
+		t.Errorf("Expected reassembled message, got %q", got)
+	}
+
+	// Incomplete messages expire
+	a.add(chunk("msgid-02", 0, 2, message[:50]), now)
+	if a.add(chunk("msgid-02", 1, 2, message[50:]), now.Add(2*gelfChunkTimeout)) != nil {
+		t.Error("Expected an expired message not to be completed")
+	}
+
+	// Unchunked datagrams pass straight through
+	if got := a.add(message, now); !bytes.Equal(got, message) {
+		t.Errorf("Expected unchunked datagram to pass through, got %q", got)
+	}
+}
+
+func TestServeGELFConn(t *testing.T) {
+	stream := `{"short_message":"one","host":"a"}` + "\x00" + `{"short_message":"two","host":"b"}` + "\x00"
+
+	var entries []models.LogEntry
+	err := serveGELFConn(strings.NewReader(stream), func(e models.LogEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to read stream: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Message != "two" || entries[1].Source != "gelf-tcp" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestServeGELFConnFrameLimit(t *testing.T) {
+	// A peer that never sends a null byte is dropped instead of buffered
+	stream := io.LimitReader(spaces{}, gelfMaxSize+1024)
+	err := serveGELFConn(stream, func(e models.LogEntry) error {
+		t.Errorf("Expected no entries, got %+v", e)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("Expected an oversized frame error, got %v", err)
+	}
+}
+
+// spaces is an endless stream of spaces, without a null byte
+type spaces struct{}
+
+func (spaces) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = ' '
+	}
+	return len(b), nil
+}
@@ -0,0 +1,137 @@
+package input
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/tenant"
+)
+
+// IngestPath is where HTTPSource accepts entries
+const IngestPath = "/ingest"
+
+// httpMaxBody bounds the decompressed size of one request
+const httpMaxBody = 16 * 1024 * 1024
+
+// HTTPSource accepts NDJSON entries posted to IngestPath. With tenants,
+// every request must carry a tenant's API key, and its entries are tagged
+// with the tenant and counted against the tenant's quota.
+type HTTPSource struct {
+	addr    string
+	parser  parser.JSONParser
+	tenants *tenant.Registry
+}
+
+// NewHTTPSource creates an HTTP listener on addr, e.g. ":8080", parsing
+// entries with p. tenants may be nil to accept entries from anyone.
+func NewHTTPSource(addr string, p parser.JSONParser, tenants *tenant.Registry) *HTTPSource {
+	return &HTTPSource{addr: addr, parser: p, tenants: tenants}
+}
+
+// Run serves requests until ctx is cancelled
+func (h *HTTPSource) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	listener, err := net.Listen("tcp", h.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", h.addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(IngestPath, func(w http.ResponseWriter, r *http.Request) {
+		h.handleIngest(w, r, emit)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	err = server.Serve(listener)
+	close(done)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return fmt.Errorf("failed to serve HTTP: %w", err)
+}
+
+// handleIngest parses a request's entries and emits them all, or none if
+// the request is rejected
+func (h *HTTPSource) handleIngest(w http.ResponseWriter, r *http.Request, emit func(models.LogEntry) error) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+	var name string
+	if h.tenants != nil {
+		t := h.tenants.Authenticate(tenant.KeyFromRequest(r))
+		if t == nil {
+			writeError(w, http.StatusUnauthorized, "missing or unknown API key")
+			return
+		}
+		name = t.Name
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, httpMaxBody)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid gzip body: %v", err))
+			return
+		}
+		defer gz.Close()
+		body = io.LimitReader(gz, httpMaxBody)
+	}
+	var entries []models.LogEntry
+	err := h.parser.Parse(body, func(entry models.LogEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if name != "" && !h.tenants.Allow(name, len(entries)) {
+		writeError(w, http.StatusTooManyRequests, "entries per minute quota exceeded")
+		return
+	}
+
+	for i, entry := range entries {
+		entry.Source = "http"
+		if name != "" {
+			entry.Tenant = name
+			// Tenants choose their own IDs, which must not clash with
+			// another tenant's in the shared analyzer
+			if entry.ID != "" {
+				entry.ID = name + "/" + entry.ID
+			}
+		}
+		if err := emit(entry); err != nil {
+			writeError(w, http.StatusServiceUnavailable, fmt.Sprintf("accepted %d of %d entries: %v", i, len(entries), err))
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]int{"accepted": len(entries)})
+}
+
+// writeError replies with a JSON error
+func writeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
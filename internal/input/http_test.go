@@ -0,0 +1,67 @@
+package input
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/tenant"
+)
+
+func TestHTTPIngest(t *testing.T) {
+	sum := sha256.Sum256([]byte("pay-key"))
+	tenants, err := tenant.New([]tenant.Definition{{Name: "payments", KeySHA256: hex.EncodeToString(sum[:]), PerMinute: 2}})
+	if err != nil {
+		t.Fatalf("Failed to create tenants: %v", err)
+	}
+	source := NewHTTPSource(":0", parser.JSONParser{}, tenants)
+
+	var got []models.LogEntry
+	emit := func(entry models.LogEntry) error {
+		got = append(got, entry)
+		return nil
+	}
+	post := func(key, body string) int {
+		req := httptest.NewRequest(http.MethodPost, IngestPath, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		source.handleIngest(rec, req, emit)
+		return rec.Code
+	}
+
+	body := `{"id":"1","timestamp":"2023-01-01T10:00:00Z","level":"ERROR","service":"checkout","message":"declined","tenant":"search"}` + "\n" +
+		`{"id":"2","timestamp":"2023-01-01T10:00:01Z","level":"INFO","service":"checkout","message":"ok"}` + "\n"
+	if code := post("pay-key", body); code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", code)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(got))
+	}
+	// The tenant comes from the key, never from the entry
+	if got[0].Tenant != "payments" || got[0].ID != "payments/1" || got[0].Source != "http" {
+		t.Errorf("Unexpected entry %+v", got[0])
+	}
+
+	if code := post("wrong-key", body); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown key, got %d", code)
+	}
+	if code := post("", body); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a key, got %d", code)
+	}
+	if code := post("pay-key", body); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 over the quota, got %d", code)
+	}
+	if code := post("pay-key", "{not json"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid input, got %d", code)
+	}
+	if len(got) != 2 {
+		t.Errorf("Expected rejected requests to emit nothing, got %d entries", len(got))
+	}
+}
@@ -0,0 +1,117 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// serveTCP accepts connections on listener and hands each to handle in its
+// own goroutine. Open connections are closed when ctx is cancelled.
+func serveTCP(ctx context.Context, listener net.Listener, handle func(net.Conn)) error {
+	var (
+		mu    sync.Mutex
+		conns = make(map[net.Conn]struct{})
+		wg    sync.WaitGroup
+	)
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+		mu.Lock()
+		for conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			wg.Wait()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				mu.Lock()
+				delete(conns, conn)
+				mu.Unlock()
+				conn.Close()
+			}()
+			handle(conn)
+		}()
+	}
+}
+
+// recordToEntry maps a structured record onto a LogEntry, using the
+// conventional key names for message, level and service. Remaining scalar
+// values become fields. defaultService is used when the record names none.
+func recordToEntry(record map[string]interface{}, ts time.Time, defaultService, source string) models.LogEntry {
+	entry := models.LogEntry{
+		Timestamp: ts,
+		Service:   defaultService,
+		Source:    source,
+		Fields:    make(map[string]string),
+	}
+
+	for k, v := range record {
+		value := scalarString(v)
+		switch strings.ToLower(k) {
+		case "message", "msg", "log":
+			if entry.Message == "" {
+				entry.Message = strings.TrimRight(value, "\n")
+				continue
+			}
+		case "level", "severity", "loglevel":
+			if entry.Level == "" {
+				entry.Level = models.LogLevel(strings.ToUpper(value))
+				continue
+			}
+		case "service", "service_name", "app":
+			if value != "" {
+				entry.Service = value
+				continue
+			}
+		case "id":
+			entry.ID = value
+			continue
+		}
+		if value != "" {
+			entry.Fields[k] = value
+		}
+	}
+
+	if entry.Level == "" {
+		entry.Level = models.INFO
+	}
+	return entry
+}
+
+// scalarString renders scalar values; nested structures are skipped
+func scalarString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case int64, uint64, float64, bool:
+		return fmt.Sprint(s)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,270 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/avro"
+	"github.com/interview/junior-go-challenge/internal/kafka"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+)
+
+const (
+	// kafkaWait is how long a fetch waits for new messages
+	kafkaWait = 5 * time.Second
+	// kafkaFetchBytes is the most a fetch returns of a partition
+	kafkaFetchBytes = 4 * 1024 * 1024
+)
+
+// KafkaSource consumes every partition of a Kafka topic. Messages hold
+// NDJSON entries or, with a schema registry, one Avro record in the
+// Confluent wire format. With a group, the offsets are committed once the
+// entries of a fetch are accepted, and a restart resumes from them; without
+// one, or for partitions the group has no offset of yet, reading starts at
+// the oldest or the newest message.
+type KafkaSource struct {
+	brokers []string
+	topic   string
+	group   string
+	oldest  bool
+	parser  parser.JSONParser
+	// avro decodes the messages, if set
+	avro *avro.MessageDecoder
+
+	mu sync.Mutex
+	// offsets holds the next offset of every partition, kept across
+	// reconnects
+	offsets map[int32]int64
+}
+
+// NewKafkaSource creates a consumer of topic on the cluster reachable at
+// any of brokers, e.g. "kafka:9092", committing offsets as group unless it
+// is empty. Messages are decoded with decoder if it is set.
+func NewKafkaSource(brokers []string, topic, group string, oldest bool, p parser.JSONParser, decoder *avro.MessageDecoder) *KafkaSource {
+	return &KafkaSource{brokers: brokers, topic: topic, group: group, oldest: oldest, parser: p, avro: decoder, offsets: make(map[int32]int64)}
+}
+
+// Run consumes the topic until ctx is cancelled, reconnecting if a
+// connection is lost or a partition moves to another broker
+func (s *KafkaSource) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	return consumeReconnecting(ctx, "Kafka", func(ctx context.Context) error {
+		return s.consume(ctx, emit)
+	})
+}
+
+// consume reads all partitions from their leaders until ctx is cancelled or
+// one of them fails
+func (s *KafkaSource) consume(ctx context.Context, emit func(models.LogEntry) error) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	partitions, err := conn.Partitions(s.topic)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	var offsets *groupOffsets
+	if s.group != "" {
+		addr, err := conn.Coordinator(s.group)
+		conn.Close()
+		if err != nil {
+			return err
+		}
+		coordinator, err := kafka.Dial(ctx, addr)
+		if err != nil {
+			return err
+		}
+		defer coordinator.Close()
+		offsets = &groupOffsets{conn: coordinator, group: s.group, topic: s.topic}
+	} else {
+		conn.Close()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(partitions))
+	for _, p := range partitions {
+		go func(p kafka.Partition) {
+			errs <- s.consumePartition(ctx, p, offsets, emit)
+		}(p)
+	}
+	// The first failure stops the other partitions too
+	err = <-errs
+	cancel()
+	for range partitions[1:] {
+		<-errs
+	}
+	if ctx.Err() != nil && err == nil {
+		return nil
+	}
+	return err
+}
+
+// dial connects to the first of the brokers that is reachable
+func (s *KafkaSource) dial(ctx context.Context) (*kafka.Conn, error) {
+	var errs []error
+	for _, broker := range s.brokers {
+		conn, err := kafka.Dial(ctx, broker)
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// consumePartition fetches a partition from its leader until ctx is
+// cancelled or the connection fails
+func (s *KafkaSource) consumePartition(ctx context.Context, p kafka.Partition, offsets *groupOffsets, emit func(models.LogEntry) error) error {
+	conn, err := kafka.Dial(ctx, p.Leader)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	offset, err := s.start(conn, p.ID, offsets)
+	if err != nil {
+		return s.stopped(ctx, err)
+	}
+	for {
+		records, next, err := conn.Fetch(s.topic, p.ID, offset, kafkaWait, kafkaFetchBytes)
+		if errors.Is(err, kafka.ErrOffsetOutOfRange) {
+			// Deleted by retention, or from a topic created again
+			fmt.Printf("Kafka offset %d of %s/%d is out of range; starting over\n", offset, s.topic, p.ID)
+			if offset, err = conn.Offset(s.topic, p.ID, s.oldest); err != nil {
+				return s.stopped(ctx, err)
+			}
+			continue
+		}
+		if err != nil {
+			return s.stopped(ctx, err)
+		}
+
+		delivered := offset
+		for _, r := range records {
+			if r.Offset < offset {
+				continue
+			}
+			entries, err := s.decode(p.ID, r)
+			if err != nil {
+				// Retried from this message after reconnecting
+				s.commit(offsets, p.ID, delivered)
+				return s.stopped(ctx, err)
+			}
+			for _, entry := range entries {
+				if err := emit(entry); err != nil {
+					s.commit(offsets, p.ID, delivered)
+					return err
+				}
+			}
+			delivered = r.Offset + 1
+		}
+		offset = max(delivered, next)
+		if err := s.commit(offsets, p.ID, offset); err != nil {
+			return s.stopped(ctx, err)
+		}
+	}
+}
+
+// start returns the offset a partition is read from: where the previous
+// connection stopped, the group's committed offset or the oldest or newest
+// one
+func (s *KafkaSource) start(conn *kafka.Conn, partition int32, offsets *groupOffsets) (int64, error) {
+	s.mu.Lock()
+	offset, ok := s.offsets[partition]
+	s.mu.Unlock()
+	if ok {
+		return offset, nil
+	}
+	if offsets != nil {
+		offset, err := offsets.committed(partition)
+		if err != nil || offset >= 0 {
+			return offset, err
+		}
+	}
+	return conn.Offset(s.topic, partition, s.oldest)
+}
+
+// commit records offset as the next of a partition, committing it for the
+// group if there is one
+func (s *KafkaSource) commit(offsets *groupOffsets, partition int32, offset int64) error {
+	s.mu.Lock()
+	previous, ok := s.offsets[partition]
+	s.offsets[partition] = offset
+	s.mu.Unlock()
+	if offsets == nil || (ok && previous == offset) {
+		return nil
+	}
+	return offsets.commit(partition, offset)
+}
+
+// stopped returns nil for an error caused by cancelling ctx, and err
+// otherwise
+func (s *KafkaSource) stopped(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// decode maps a message onto log entries. Messages that cannot be decoded
+// are reported and skipped, as they would never succeed; an error is only
+// returned if the schema registry cannot be reached.
+func (s *KafkaSource) decode(partition int32, r kafka.Record) ([]models.LogEntry, error) {
+	id := fmt.Sprintf("%s/%d/%d", s.topic, partition, r.Offset)
+	if s.avro == nil {
+		entries, err := decodeMessage(s.parser, r.Value, "kafka", id)
+		if err != nil {
+			fmt.Printf("Dropping Kafka message %s: %v\n", id, err)
+		}
+		return entries, nil
+	}
+
+	entry, err := s.avro.Decode(r.Value)
+	if errors.Is(err, avro.ErrRegistryUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		fmt.Printf("Dropping Kafka message %s: %v\n", id, err)
+		return nil, nil
+	}
+	entry.Source = "kafka"
+	if entry.ID == "" {
+		entry.ID = "kafka:" + id
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = r.Timestamp
+	}
+	return []models.LogEntry{entry}, nil
+}
+
+// groupOffsets reads and commits the offsets of a consumer group on its
+// coordinator, for the partitions consumed at once
+type groupOffsets struct {
+	mu    sync.Mutex
+	conn  *kafka.Conn
+	group string
+	topic string
+}
+
+func (g *groupOffsets) committed(partition int32) (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.conn.CommittedOffset(g.group, g.topic, partition)
+}
+
+func (g *groupOffsets) commit(partition int32, offset int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.conn.CommitOffset(g.group, g.topic, partition, offset)
+}
@@ -0,0 +1,240 @@
+package input
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/avro"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+)
+
+const kafkaTestSchema = `{"type": "record", "name": "Log", "fields": [
+	{"name": "level", "type": "string"},
+	{"name": "service", "type": "string"},
+	{"name": "message", "type": "string"}
+]}`
+
+// kafkaString appends a Kafka string
+func kafkaString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// avroMessage encodes a record of kafkaTestSchema in the Confluent wire
+// format with schema ID 1
+func avroMessage(level, service, message string) []byte {
+	b := []byte{0, 0, 0, 0, 1}
+	for _, s := range []string{level, service, message} {
+		b = binary.AppendVarint(b, int64(len(s)))
+		b = append(b, s...)
+	}
+	return b
+}
+
+// recordBatch encodes an uncompressed record batch starting at base
+func recordBatch(base int64, values ...[]byte) []byte {
+	var records []byte
+	for i, v := range values {
+		r := []byte{0}
+		r = binary.AppendVarint(r, 0)
+		r = binary.AppendVarint(r, int64(i))
+		r = binary.AppendVarint(r, -1)
+		r = binary.AppendVarint(r, int64(len(v)))
+		r = append(r, v...)
+		r = binary.AppendVarint(r, 0)
+		records = binary.AppendVarint(records, int64(len(r)))
+		records = append(records, r...)
+	}
+	body := binary.BigEndian.AppendUint16(nil, 0)
+	body = binary.BigEndian.AppendUint32(body, uint32(len(values)-1))
+	body = binary.BigEndian.AppendUint64(body, uint64(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC).UnixMilli()))
+	body = binary.BigEndian.AppendUint64(body, 0)
+	body = binary.BigEndian.AppendUint64(body, ^uint64(0))
+	body = binary.BigEndian.AppendUint16(body, 0xffff)
+	body = binary.BigEndian.AppendUint32(body, ^uint32(0))
+	body = binary.BigEndian.AppendUint32(body, uint32(len(values)))
+	body = append(body, records...)
+
+	b := binary.BigEndian.AppendUint64(nil, uint64(base))
+	b = binary.BigEndian.AppendUint32(b, uint32(9+len(body)))
+	b = binary.BigEndian.AppendUint32(b, 0)
+	b = append(b, 2)
+	b = binary.BigEndian.AppendUint32(b, crc32.Checksum(body, crc32.MakeTable(crc32.Castagnoli)))
+	return append(b, body...)
+}
+
+// fakeKafka serves the topic "logs" with one partition led by itself,
+// holding an Avro message at offset 5 and an undecodable one at 6.
+// Committed offsets are sent on commits.
+func fakeKafka(listener net.Listener, commits chan<- int64) {
+	host, portText, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portText)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				var size uint32
+				if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+					return
+				}
+				req := make([]byte, size)
+				if _, err := io.ReadFull(conn, req); err != nil {
+					return
+				}
+				api := binary.BigEndian.Uint16(req)
+				// The body follows the header and client ID
+				body := req[10+binary.BigEndian.Uint16(req[8:]):]
+
+				resp := binary.BigEndian.AppendUint32(nil, 0)
+				resp = append(resp, req[4:8]...)
+				u16 := func(v uint16) { resp = binary.BigEndian.AppendUint16(resp, v) }
+				u32 := func(v uint32) { resp = binary.BigEndian.AppendUint32(resp, v) }
+				u64 := func(v uint64) { resp = binary.BigEndian.AppendUint64(resp, v) }
+				switch api {
+				case 3: // metadata
+					u32(1)
+					u32(1)
+					resp = kafkaString(resp, host)
+					u32(uint32(port))
+					u16(0xffff)
+					u32(1)
+					u32(1)
+					u16(0)
+					resp = kafkaString(resp, "logs")
+					resp = append(resp, 0)
+					u32(1)
+					u16(0)
+					u32(0)
+					u32(1)
+					u32(0)
+					u32(0)
+				case 10: // find coordinator
+					u16(0)
+					u32(1)
+					resp = kafkaString(resp, host)
+					u32(uint32(port))
+				case 9: // offset fetch: nothing committed yet
+					u32(1)
+					resp = kafkaString(resp, "logs")
+					u32(1)
+					u32(0)
+					u64(^uint64(0))
+					u16(0xffff)
+					u16(0)
+				case 2: // list offsets
+					u32(1)
+					resp = kafkaString(resp, "logs")
+					u32(1)
+					u32(0)
+					u16(0)
+					u64(0)
+					u64(5)
+				case 1: // fetch
+					offset := binary.BigEndian.Uint64(body[len(body)-12:])
+					var records []byte
+					if offset <= 5 {
+						records = recordBatch(5, avroMessage("ERROR", "api", "timeout"), []byte("not avro"))
+					} else {
+						time.Sleep(10 * time.Millisecond)
+					}
+					u32(0)
+					u32(1)
+					resp = kafkaString(resp, "logs")
+					u32(1)
+					u32(0)
+					u16(0)
+					u64(7)
+					u64(7)
+					u32(0)
+					u32(uint32(len(records)))
+					resp = append(resp, records...)
+				case 8: // offset commit
+					// group, generation, member, retention, topics, topic,
+					// partitions, partition, then the offset
+					at := 2 + int(binary.BigEndian.Uint16(body)) + 4
+					at += 2 + int(binary.BigEndian.Uint16(body[at:])) + 8 + 4
+					at += 2 + int(binary.BigEndian.Uint16(body[at:])) + 4 + 4
+					commits <- int64(binary.BigEndian.Uint64(body[at:]))
+					u32(1)
+					resp = kafkaString(resp, "logs")
+					u32(1)
+					u32(0)
+					u16(0)
+				}
+				binary.BigEndian.PutUint32(resp, uint32(len(resp)-4))
+				conn.Write(resp)
+			}
+		}()
+	}
+}
+
+func TestKafkaSourceAvro(t *testing.T) {
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"schema": kafkaTestSchema})
+	}))
+	defer registry.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	commits := make(chan int64, 16)
+	go fakeKafka(listener, commits)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries := make(chan models.LogEntry, 4)
+	decoder := avro.NewMessageDecoder(avro.NewRegistry(registry.URL), avro.DefaultFieldMapping())
+	source := NewKafkaSource([]string{listener.Addr().String()}, "logs", "processors", false, parser.JSONParser{}, decoder)
+	done := make(chan error, 1)
+	go func() {
+		done <- source.Run(ctx, func(e models.LogEntry) error {
+			entries <- e
+			return nil
+		})
+	}()
+
+	select {
+	case entry := <-entries:
+		if entry.Level != models.ERROR || entry.Service != "api" || entry.Message != "timeout" || entry.ID != "kafka:logs/0/5" {
+			t.Errorf("Expected the Avro record as an entry, got %+v", entry)
+		}
+		if !entry.Timestamp.Equal(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected the message timestamp, got %s", entry.Timestamp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an entry")
+	}
+
+	// The undecodable message is skipped and committed too
+	select {
+	case offset := <-commits:
+		if offset != 7 {
+			t.Errorf("Expected offset 7 to be committed, got %d", offset)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a commit")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected a clean stop, got %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected only the Avro record, got %d more entries", len(entries))
+	}
+}
@@ -0,0 +1,316 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// maxMsgpackLength bounds the size of any single string, binary, array or
+// map so a malformed or hostile stream cannot exhaust memory
+const maxMsgpackLength = 64 * 1024 * 1024
+
+// maxMsgpackDepth bounds the nesting of arrays and maps
+const maxMsgpackDepth = 64
+
+// msgpackPrealloc is the most memory allocated for a value before its
+// bytes arrive; larger ones grow as they are read
+const msgpackPrealloc = 64 * 1024
+
+// msgpackExt is an extension value, such as Fluent's EventTime (type 0)
+type msgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+// msgpackReader decodes consecutive MessagePack values from a stream
+type msgpackReader struct {
+	r *bufio.Reader
+	// depth is the number of arrays and maps being decoded
+	depth int
+}
+
+func newMsgpackReader(r io.Reader) *msgpackReader {
+	return &msgpackReader{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next value. Integers decode to int64 (or uint64 when they
+// do not fit), maps to map[string]interface{} and arrays to []interface{}.
+func (m *msgpackReader) Decode() (interface{}, error) {
+	b, err := m.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return m.decodeMap(int(b & 0x0f))
+	case b&0xf0 == 0x90:
+		return m.decodeArray(int(b & 0x0f))
+	case b&0xe0 == 0xa0:
+		return m.decodeString(int(b & 0x1f))
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := m.length(b - 0xc4)
+		if err != nil {
+			return nil, err
+		}
+		return m.read(n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := m.length(b - 0xc7)
+		if err != nil {
+			return nil, err
+		}
+		return m.decodeExt(n)
+	case 0xca:
+		v, err := m.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := m.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := m.uint(1 << (b - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0:
+		v, err := m.uint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := m.uint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := m.uint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := m.uint(8)
+		return int64(v), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return m.decodeExt(1 << (b - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := m.length(b - 0xd9)
+		if err != nil {
+			return nil, err
+		}
+		return m.decodeString(n)
+	case 0xdc, 0xdd:
+		n, err := m.length(b - 0xdc + 1)
+		if err != nil {
+			return nil, err
+		}
+		return m.decodeArray(n)
+	case 0xde, 0xdf:
+		n, err := m.length(b - 0xde + 1)
+		if err != nil {
+			return nil, err
+		}
+		return m.decodeMap(n)
+	}
+	return nil, fmt.Errorf("invalid msgpack type byte 0x%02x", b)
+}
+
+// length reads a 1, 2 or 4 byte length prefix (selected by size 0, 1 or 2)
+func (m *msgpackReader) length(size byte) (int, error) {
+	v, err := m.uint(1 << size)
+	if err != nil {
+		return 0, err
+	}
+	if v > maxMsgpackLength {
+		return 0, fmt.Errorf("msgpack value of %d bytes exceeds limit", v)
+	}
+	return int(v), nil
+}
+
+func (m *msgpackReader) uint(n int) (uint64, error) {
+	b, err := m.read(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// read reads n bytes. Lengths come from the stream, so large values are
+// read in pieces rather than allocated up front.
+func (m *msgpackReader) read(n int) ([]byte, error) {
+	if n <= msgpackPrealloc {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(m.r, b); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		return b, nil
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, m.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *msgpackReader) decodeString(n int) (interface{}, error) {
+	b, err := m.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (m *msgpackReader) decodeExt(n int) (interface{}, error) {
+	t, err := m.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := m.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return msgpackExt{Type: int8(t), Data: data}, nil
+}
+
+// nest enters an array or map, failing if they are nested too deeply. The
+// returned function leaves it.
+func (m *msgpackReader) nest() (func(), error) {
+	if m.depth >= maxMsgpackDepth {
+		return nil, fmt.Errorf("msgpack values nested deeper than %d", maxMsgpackDepth)
+	}
+	m.depth++
+	return func() { m.depth-- }, nil
+}
+
+func (m *msgpackReader) decodeArray(n int) (interface{}, error) {
+	leave, err := m.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	// Every element takes at least a byte, so capacity grows with the input
+	arr := make([]interface{}, 0, min(n, msgpackPrealloc))
+	for i := 0; i < n; i++ {
+		v, err := m.Decode()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (m *msgpackReader) decodeMap(n int) (interface{}, error) {
+	leave, err := m.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	obj := make(map[string]interface{}, min(n, msgpackPrealloc))
+	for i := 0; i < n; i++ {
+		k, err := m.Decode()
+		if err != nil {
+			return nil, err
+		}
+		v, err := m.Decode()
+		if err != nil {
+			return nil, err
+		}
+		switch key := k.(type) {
+		case string:
+			obj[key] = v
+		case []byte:
+			obj[string(key)] = v
+		default:
+			obj[fmt.Sprint(key)] = v
+		}
+	}
+	return obj, nil
+}
+
+// appendMsgpack encodes v onto buf. It supports the types Decode produces.
+func appendMsgpack(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+		return append(buf, 0xc0)
+	case bool:
+		if x {
+			return append(buf, 0xc3)
+		}
+		return append(buf, 0xc2)
+	case int:
+		return appendMsgpack(buf, int64(x))
+	case int64:
+		if x >= 0 && x <= 0x7f {
+			return append(buf, byte(x))
+		}
+		buf = append(buf, 0xd3)
+		return binary.BigEndian.AppendUint64(buf, uint64(x))
+	case float64:
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(x))
+	case string:
+		switch n := len(x); {
+		case n < 32:
+			buf = append(buf, 0xa0|byte(n))
+		case n < 1<<8:
+			buf = append(buf, 0xd9, byte(n))
+		case n < 1<<16:
+			buf = append(buf, 0xda)
+			buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+		default:
+			buf = append(buf, 0xdb)
+			buf = binary.BigEndian.AppendUint32(buf, uint32(n))
+		}
+		return append(buf, x...)
+	case []byte:
+		buf = append(buf, 0xc6)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(x)))
+		return append(buf, x...)
+	case msgpackExt:
+		buf = append(buf, 0xc9)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(x.Data)))
+		buf = append(buf, byte(x.Type))
+		return append(buf, x.Data...)
+	case []interface{}:
+		buf = append(buf, 0xdd)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(x)))
+		for _, item := range x {
+			buf = appendMsgpack(buf, item)
+		}
+		return buf
+	case map[string]interface{}:
+		buf = append(buf, 0xdf)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(x)))
+		for k, item := range x {
+			buf = appendMsgpack(buf, k)
+			buf = appendMsgpack(buf, item)
+		}
+		return buf
+	default:
+		return appendMsgpack(buf, fmt.Sprint(x))
+	}
+}
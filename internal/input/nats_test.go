@@ -0,0 +1,126 @@
+package input
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+)
+
+// fakeJetStream serves one NATS client: it answers the consumer creation,
+// delivers messages to the first pull request and reports the bodies the
+// client acknowledges with on acks
+func fakeJetStream(listener net.Listener, messages []string, acks chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"headers\":true}\r\n")
+
+	pulled := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		switch f[0] {
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case "PUB":
+			size, _ := strconv.Atoi(f[len(f)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(f[1], "$JS.API.CONSUMER.DURABLE.CREATE.logs.lp"):
+				reply := `{"type":"io.nats.jetstream.api.v1.consumer_create_response"}`
+				fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", f[2], len(reply), reply)
+			case strings.HasPrefix(f[1], "$JS.API.CONSUMER.MSG.NEXT.logs.lp"):
+				if pulled {
+					continue
+				}
+				pulled = true
+				for i, m := range messages {
+					ack := fmt.Sprintf("$JS.ACK.logs.lp.1.%d.%d.1672567200000000000.0", i+7, i+1)
+					fmt.Fprintf(conn, "MSG %s 1 %s %d\r\n%s\r\n", f[2], ack, len(m), m)
+				}
+				status := "NATS/1.0 408 Request Timeout\r\n\r\n"
+				fmt.Fprintf(conn, "HMSG %s 1 %d %d\r\n%s\r\n", f[2], len(status), len(status), status)
+			case strings.HasPrefix(f[1], "$JS.ACK."):
+				acks <- f[1] + " " + string(payload[:size])
+			}
+		}
+	}
+}
+
+func TestNATSSource(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	acks := make(chan string, 4)
+	go fakeJetStream(listener, []string{
+		`{"timestamp":"2023-01-01T10:00:00Z","level":"ERROR","service":"api","message":"failed"}` + "\n" +
+			`{"id":"x","timestamp":"2023-01-01T10:00:01Z","level":"INFO","service":"api","message":"ok"}`,
+		"not json",
+	}, acks)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries := make(chan models.LogEntry, 4)
+	source := NewNATSSource("nats://"+listener.Addr().String(), "logs", "lp", "", parser.JSONParser{})
+	done := make(chan error, 1)
+	go func() {
+		done <- source.Run(ctx, func(e models.LogEntry) error {
+			entries <- e
+			return nil
+		})
+	}()
+
+	want := map[string]string{
+		"$JS.ACK.logs.lp.1.7.1.1672567200000000000.0": "+ACK",
+		"$JS.ACK.logs.lp.1.8.2.1672567200000000000.0": "+TERM",
+	}
+	for range want {
+		select {
+		case ack := <-acks:
+			subject, body, _ := strings.Cut(ack, " ")
+			if want[subject] != body {
+				t.Errorf("Expected %s to be answered with %q, got %q", subject, want[subject], body)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for acknowledgements")
+		}
+	}
+
+	close(entries)
+	var got []models.LogEntry
+	for e := range entries {
+		got = append(got, e)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", got)
+	}
+	// IDs derive from the stream sequence, so redeliveries are duplicates
+	if got[0].ID != "nats:logs:7:0" || got[0].Source != "nats" || got[1].ID != "x" {
+		t.Errorf("Unexpected entries %+v", got)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Run to stop cleanly, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/redis"
+)
+
+const (
+	// redisBatch is how many stream entries one read returns at most
+	redisBatch = 100
+	// redisBlock is how long a read waits for new entries
+	redisBlock = 5 * time.Second
+	// redisReclaimInterval is how often entries left pending by other
+	// consumers are looked for
+	redisReclaimInterval = 30 * time.Second
+)
+
+// RedisSource consumes entries from a Redis stream as a member of a
+// consumer group, which it creates if needed. A stream entry holds either
+// NDJSON entries in its "entry" field or the fields of one entry, such as
+// message, level and service. Stream entries are acknowledged once their
+// entries are accepted; entries another consumer left pending for longer
+// than the claim timeout are claimed and processed.
+type RedisSource struct {
+	url          string
+	stream       string
+	group        string
+	consumer     string
+	claimTimeout time.Duration
+	parser       parser.JSONParser
+}
+
+// NewRedisSource creates a consumer named consumer in group on stream at
+// the server at rawURL, e.g. "redis://:pass@redis:6379/0". Entries pending
+// for longer than claimTimeout are claimed from other consumers.
+func NewRedisSource(rawURL, stream, group, consumer string, claimTimeout time.Duration, p parser.JSONParser) *RedisSource {
+	return &RedisSource{url: rawURL, stream: stream, group: group, consumer: consumer, claimTimeout: claimTimeout, parser: p}
+}
+
+// Run consumes the stream until ctx is cancelled, reconnecting if the
+// connection is lost
+func (s *RedisSource) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	return consumeReconnecting(ctx, "Redis", func(ctx context.Context) error {
+		conn, err := redis.Dial(ctx, s.url)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return s.consume(ctx, conn, emit)
+	})
+}
+
+// consume reads the stream until ctx is cancelled or the connection fails
+func (s *RedisSource) consume(ctx context.Context, conn *redis.Conn, emit func(models.LogEntry) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	// A new group starts at the beginning of the stream
+	_, err := conn.Do("XGROUP", "CREATE", s.stream, s.group, "0", "MKSTREAM")
+	var redisErr redis.Error
+	if err != nil && !(errors.As(err, &redisErr) && redisErr.Code() == "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group %s on %s: %w", s.group, s.stream, err)
+	}
+
+	// Entries delivered to this consumer before a restart come first
+	for {
+		messages, err := s.read(conn, "0", 0)
+		if err != nil {
+			return s.stopped(ctx, err)
+		}
+		if len(messages) == 0 {
+			break
+		}
+		if err := s.deliver(conn, messages, emit); err != nil {
+			return s.stopped(ctx, err)
+		}
+	}
+
+	var lastReclaim time.Time
+	for {
+		if s.claimTimeout > 0 && time.Since(lastReclaim) >= redisReclaimInterval {
+			if err := s.reclaim(conn, emit); err != nil {
+				return s.stopped(ctx, err)
+			}
+			lastReclaim = time.Now()
+		}
+		messages, err := s.read(conn, ">", redisBlock)
+		if err != nil {
+			return s.stopped(ctx, err)
+		}
+		if err := s.deliver(conn, messages, emit); err != nil {
+			return s.stopped(ctx, err)
+		}
+	}
+}
+
+// stopped returns nil for an error caused by cancelling ctx, and err
+// otherwise
+func (s *RedisSource) stopped(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// read reads entries of the group after id: ">" for new entries, "0" for
+// those delivered to this consumer but not acknowledged
+func (s *RedisSource) read(conn *redis.Conn, id string, block time.Duration) ([]redis.Message, error) {
+	args := []string{"XREADGROUP", "GROUP", s.group, s.consumer, "COUNT", strconv.Itoa(redisBatch)}
+	if block > 0 {
+		args = append(args, "BLOCK", strconv.FormatInt(block.Milliseconds(), 10))
+	}
+	reply, err := conn.DoBlocking(block, append(args, "STREAMS", s.stream, id)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream %s: %w", s.stream, err)
+	}
+	// The reply lists [stream, entries] per stream, or is nil on timeout
+	streams, _ := reply.([]interface{})
+	if len(streams) == 0 {
+		return nil, nil
+	}
+	pair, ok := streams[0].([]interface{})
+	if !ok || len(pair) != 2 {
+		return nil, fmt.Errorf("unexpected reply reading stream %s", s.stream)
+	}
+	return redis.ParseMessages(pair[1])
+}
+
+// reclaim claims and processes the entries other consumers left pending
+// for longer than the claim timeout, e.g. because they crashed
+func (s *RedisSource) reclaim(conn *redis.Conn, emit func(models.LogEntry) error) error {
+	cursor := "0-0"
+	for {
+		reply, err := conn.Do("XAUTOCLAIM", s.stream, s.group, s.consumer,
+			strconv.FormatInt(s.claimTimeout.Milliseconds(), 10), cursor, "COUNT", strconv.Itoa(redisBatch))
+		if err != nil {
+			return fmt.Errorf("failed to claim pending entries of %s: %w", s.stream, err)
+		}
+		// [next cursor, entries, deleted IDs (Redis 7)]
+		parts, _ := reply.([]interface{})
+		if len(parts) < 2 {
+			return fmt.Errorf("unexpected reply claiming entries of %s", s.stream)
+		}
+		next, _ := parts[0].([]byte)
+		messages, err := redis.ParseMessages(parts[1])
+		if err != nil {
+			return err
+		}
+		if err := s.deliver(conn, messages, emit); err != nil {
+			return err
+		}
+		cursor = string(next)
+		if cursor == "0-0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// deliver emits the entries of messages and acknowledges them. Stream
+// entries that cannot be parsed are acknowledged too, as they would never
+// succeed.
+func (s *RedisSource) deliver(conn *redis.Conn, messages []redis.Message, emit func(models.LogEntry) error) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ack := []string{"XACK", s.stream, s.group}
+	for _, m := range messages {
+		entries, err := s.decode(m)
+		if err != nil {
+			fmt.Printf("Dropping Redis stream entry %s: %v\n", m.ID, err)
+		}
+		for _, entry := range entries {
+			if err := emit(entry); err != nil {
+				// Acknowledge what was accepted; the rest stays pending
+				conn.Do(ack...)
+				return err
+			}
+		}
+		ack = append(ack, m.ID)
+	}
+	if _, err := conn.Do(ack...); err != nil {
+		return fmt.Errorf("failed to acknowledge entries of %s: %w", s.stream, err)
+	}
+	return nil
+}
+
+// decode maps a stream entry onto log entries
+func (s *RedisSource) decode(m redis.Message) ([]models.LogEntry, error) {
+	if data, ok := m.Fields["entry"]; ok {
+		return decodeMessage(s.parser, []byte(data), "redis", m.ID)
+	}
+	if len(m.Fields) == 0 {
+		// Deleted from the stream while pending
+		return nil, nil
+	}
+	record := make(map[string]interface{}, len(m.Fields))
+	for k, v := range m.Fields {
+		record[k] = v
+	}
+	ts := time.Now().UTC()
+	for _, key := range []string{"timestamp", "time", "ts"} {
+		if v, ok := m.Fields[key]; ok {
+			if parsed, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				ts = parsed
+				delete(record, key)
+			}
+			break
+		}
+	}
+	entry := recordToEntry(record, ts, "", "redis")
+	if entry.ID == "" {
+		entry.ID = "redis:" + m.ID
+	}
+	return []models.LogEntry{entry}, nil
+}
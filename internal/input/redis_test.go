@@ -0,0 +1,144 @@
+package input
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+)
+
+// bulk encodes a RESP bulk string
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+// streamEntry encodes a stream entry with the given field/value pairs
+func streamEntry(id string, fields ...string) string {
+	s := "*2\r\n" + bulk(id) + fmt.Sprintf("*%d\r\n", len(fields))
+	for _, f := range fields {
+		s += bulk(f)
+	}
+	return s
+}
+
+// fakeRedis serves one client of the stream "logs": one entry is pending
+// for the consumer, one is claimable from another consumer and one is new.
+// Acknowledged IDs are sent on acks.
+func fakeRedis(listener net.Listener, acks chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	pending, fresh := true, true
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := make([]string, n)
+		for i := range args {
+			line, _ = r.ReadString('\n')
+			size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			buf := make([]byte, size+2)
+			io.ReadFull(r, buf)
+			args[i] = string(buf[:size])
+		}
+
+		switch args[0] {
+		case "XGROUP":
+			fmt.Fprint(conn, "-BUSYGROUP Consumer Group name already exists\r\n")
+		case "XREADGROUP":
+			id := args[len(args)-1]
+			var entries []string
+			switch {
+			case id == "0" && pending:
+				pending = false
+				entries = append(entries, streamEntry("1-0", "entry", `{"timestamp":"2023-01-01T10:00:00Z","level":"ERROR","service":"api","message":"failed"}`))
+			case id == ">" && fresh:
+				fresh = false
+				entries = append(entries, streamEntry("3-0", "entry", "not json"))
+			case id == ">":
+				// Nothing new within the block time
+				time.Sleep(10 * time.Millisecond)
+				fmt.Fprint(conn, "*-1\r\n")
+				continue
+			}
+			fmt.Fprintf(conn, "*1\r\n*2\r\n%s*%d\r\n%s", bulk("logs"), len(entries), strings.Join(entries, ""))
+		case "XAUTOCLAIM":
+			fmt.Fprintf(conn, "*3\r\n%s*1\r\n%s*0\r\n", bulk("0-0"),
+				streamEntry("2-0", "message", "claimed", "level", "WARNING", "service", "db", "timestamp", "2023-01-01T10:00:01Z"))
+		case "XACK":
+			for _, id := range args[3:] {
+				acks <- id
+			}
+			fmt.Fprintf(conn, ":%d\r\n", len(args)-3)
+		default:
+			fmt.Fprint(conn, "-ERR unknown command\r\n")
+		}
+	}
+}
+
+func TestRedisSource(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	acks := make(chan string, 4)
+	go fakeRedis(listener, acks)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	entries := make(chan models.LogEntry, 4)
+	source := NewRedisSource("redis://"+listener.Addr().String(), "logs", "processors", "worker-1", time.Minute, parser.JSONParser{})
+	done := make(chan error, 1)
+	go func() {
+		done <- source.Run(ctx, func(e models.LogEntry) error {
+			entries <- e
+			return nil
+		})
+	}()
+
+	// The own pending entry, then the claimed one, then the new one, which
+	// is acknowledged although it cannot be parsed
+	for _, want := range []string{"1-0", "2-0", "3-0"} {
+		select {
+		case got := <-acks:
+			if got != want {
+				t.Errorf("Expected %s to be acknowledged, got %s", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for acknowledgements")
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected Run to stop cleanly, got %v", err)
+	}
+
+	close(entries)
+	var got []models.LogEntry
+	for e := range entries {
+		got = append(got, e)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", got)
+	}
+	if got[0].ID != "redis:1-0:0" || got[0].Level != models.ERROR {
+		t.Errorf("Unexpected NDJSON entry %+v", got[0])
+	}
+	if got[1].ID != "redis:2-0" || got[1].Message != "claimed" || got[1].Service != "db" || got[1].Level != models.WARNING || got[1].Timestamp.IsZero() {
+		t.Errorf("Unexpected field entry %+v", got[1])
+	}
+}
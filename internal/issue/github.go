@@ -0,0 +1,93 @@
+package issue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+)
+
+const gitHubAPIURL = "https://api.github.com"
+
+// GitHub files issues in a GitHub repository through the REST API
+type GitHub struct {
+	repo   string
+	token  string
+	url    string
+	client *http.Client
+}
+
+// NewGitHub creates a tracker for the repository given as OWNER/NAME,
+// authenticating with a token allowed to write its issues
+func NewGitHub(repo, token string) (*GitHub, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("GitHub repository %q is not OWNER/NAME", repo)
+	}
+	return &GitHub{
+		repo:   repo,
+		token:  token,
+		url:    gitHubAPIURL,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// WithURL overrides the API endpoint, e.g. https://github.example.com/api/v3
+// for GitHub Enterprise Server
+func (g *GitHub) WithURL(url string) *GitHub {
+	g.url = strings.TrimSuffix(url, "/")
+	return g
+}
+
+func (g *GitHub) headers() map[string]string {
+	return map[string]string{
+		"Authorization":        "Bearer " + g.token,
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+}
+
+// Find lists the open issues with label, which unlike the search API sees
+// issues created moments ago
+func (g *GitHub) Find(ctx context.Context, label string) (string, error) {
+	query := url.Values{"state": {"open"}, "labels": {label}, "per_page": {"1"}}
+	var issues []struct {
+		Number int `json:"number"`
+	}
+	if err := send(ctx, g.client, http.MethodGet, g.url+"/repos/"+g.repo+"/issues?"+query.Encode(), g.headers(), nil, &issues); err != nil {
+		return "", err
+	}
+	if len(issues) == 0 {
+		return "", nil
+	}
+	return fmt.Sprintf("#%d", issues[0].Number), nil
+}
+
+// Create opens an issue labeled with label and "logprocessor". GitHub
+// creates labels that do not exist yet.
+func (g *GitHub) Create(ctx context.Context, label string, group analyzer.ErrorGroup) (string, error) {
+	body := map[string]interface{}{
+		"title":  Title(group),
+		"body":   "A new error pattern was found by logprocessor.\n\n" + details(group, "```", "```"),
+		"labels": []string{label, "logprocessor"},
+	}
+	var issue struct {
+		Number int `json:"number"`
+	}
+	if err := send(ctx, g.client, http.MethodPost, g.url+"/repos/"+g.repo+"/issues", g.headers(), body, &issue); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("#%d", issue.Number), nil
+}
+
+// Comment adds a comment to the issue with key, e.g. "#12"
+func (g *GitHub) Comment(ctx context.Context, key string, group analyzer.ErrorGroup) error {
+	body := map[string]string{
+		"body": "The error pattern was found again.\n\n" + details(group, "```", "```"),
+	}
+	return send(ctx, g.client, http.MethodPost, g.url+"/repos/"+g.repo+"/issues/"+strings.TrimPrefix(key, "#")+"/comments", g.headers(), body, nil)
+}
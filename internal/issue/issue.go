@@ -0,0 +1,183 @@
+// Package issue files error groups as issues in GitHub or Jira, so new
+// high-volume errors reach the tracker without manual triage. Each group is
+// filed once: its issue carries a label derived from the group's
+// fingerprint, and a group that already has an open issue gets a comment
+// instead of a second issue.
+package issue
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+)
+
+// Tracker creates and comments on issues
+type Tracker interface {
+	// Find returns the key of an open issue with label, or "" if there is
+	// none
+	Find(ctx context.Context, label string) (string, error)
+	// Create opens an issue for the group with label and returns its key
+	Create(ctx context.Context, label string, g analyzer.ErrorGroup) (string, error)
+	// Comment adds the group's latest counts and samples to an issue
+	Comment(ctx context.Context, key string, g analyzer.ErrorGroup) error
+}
+
+// Filed is an error group filed in a tracker
+type Filed struct {
+	Group analyzer.ErrorGroup
+	// Key identifies the issue, e.g. "#12" or "OPS-34"
+	Key string
+	// Created is set for a new issue, and unset if the group was added to
+	// an existing one as a comment
+	Created bool
+}
+
+// Filer files the new error groups with many entries
+type Filer struct {
+	tracker  Tracker
+	minCount int
+}
+
+// NewFiler creates a filer for the new groups with at least minCount entries
+func NewFiler(tracker Tracker, minCount int) *Filer {
+	return &Filer{tracker: tracker, minCount: minCount}
+}
+
+// File files every new group with enough entries, commenting on the open
+// issue of a group if it has one, and returns the groups filed up to the
+// first error
+func (f *Filer) File(ctx context.Context, groups []analyzer.ErrorGroup) ([]Filed, error) {
+	var filed []Filed
+	for _, g := range groups {
+		if !g.New || g.Count < f.minCount {
+			continue
+		}
+		label := Label(g.Fingerprint())
+		key, err := f.tracker.Find(ctx, label)
+		if err != nil {
+			return filed, fmt.Errorf("failed to find issue of %s: %w", label, err)
+		}
+		if key != "" {
+			if err := f.tracker.Comment(ctx, key, g); err != nil {
+				return filed, fmt.Errorf("failed to comment on issue %s: %w", key, err)
+			}
+			filed = append(filed, Filed{Group: g, Key: key})
+			continue
+		}
+		if key, err = f.tracker.Create(ctx, label, g); err != nil {
+			return filed, fmt.Errorf("failed to create issue: %w", err)
+		}
+		filed = append(filed, Filed{Group: g, Key: key, Created: true})
+	}
+	return filed, nil
+}
+
+// Label returns the label that marks the issue of a fingerprint. Labels may
+// be short and must not contain spaces in Jira, so it holds a hash.
+func Label(fingerprint string) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return "logprocessor-" + hex.EncodeToString(sum[:6])
+}
+
+// maxTitle is the longest issue title, within the limits of both trackers
+const maxTitle = 200
+
+// Title returns the title of a group's issue
+func Title(g analyzer.ErrorGroup) string {
+	title := fmt.Sprintf("New error in %s: %s", g.Service, g.Pattern)
+	if len(title) > maxTitle {
+		// Cut at a rune boundary
+		cut := maxTitle - len("...")
+		for cut > 0 && !utf8.RuneStart(title[cut]) {
+			cut--
+		}
+		title = title[:cut] + "..."
+	}
+	return title
+}
+
+// details describes a group's counts as lines of text, with fence around
+// its samples, e.g. "```" in Markdown
+func details(g analyzer.ErrorGroup, fenceStart, fenceEnd string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Service: %s\n", g.Service)
+	fmt.Fprintf(&b, "Pattern: %s\n", g.Pattern)
+	fmt.Fprintf(&b, "Entries: %d\n", g.Count)
+	if !g.FirstSeen.IsZero() {
+		fmt.Fprintf(&b, "Seen: %s to %s\n", g.FirstSeen.UTC().Format("2006-01-02 15:04:05 MST"), g.LastSeen.UTC().Format("2006-01-02 15:04:05 MST"))
+	}
+	if g.Trend != "" {
+		fmt.Fprintf(&b, "Trend: %s\n", g.Trend)
+	}
+	if g.After != nil {
+		fmt.Fprintf(&b, "Began after release: %s\n", g.After)
+	}
+	fmt.Fprintf(&b, "Fingerprint: %s\n", Label(g.Fingerprint()))
+	if len(g.Samples) > 0 {
+		fmt.Fprintf(&b, "\nSample entries:\n%s\n", fenceStart)
+		for _, e := range g.Samples {
+			ts := "-"
+			if !e.Timestamp.IsZero() {
+				ts = e.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z")
+			}
+			fmt.Fprintf(&b, "%s %s [%s] %s", ts, e.Level, e.Service, e.Message)
+			if e.Source != "" {
+				fmt.Fprintf(&b, " (%s)", e.Source)
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s\n", fenceEnd)
+	}
+	return b.String()
+}
+
+// send sends body as JSON, or no body if it is nil, and decodes a JSON
+// response into out unless it is nil. Any non-2xx response is an error.
+func send(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("request rejected with %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
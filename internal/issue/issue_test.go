@@ -0,0 +1,153 @@
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func testGroups() []analyzer.ErrorGroup {
+	ts := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	return []analyzer.ErrorGroup{
+		{Service: "api", Pattern: "out of memory", Count: 500, New: true, FirstSeen: ts, LastSeen: ts.Add(time.Hour),
+			Samples: []models.LogEntry{{Timestamp: ts, Level: models.FATAL, Service: "api", Message: "out of memory", Source: "api.log"}}},
+		{Service: "db", Pattern: "query <*> timed out", Count: 300, New: true},
+		// Too few entries
+		{Service: "api", Pattern: "bad request", Count: 3, New: true},
+		// Filed by an earlier run
+		{Service: "api", Pattern: "connection reset", Count: 1000},
+	}
+}
+
+func TestGitHubFiler(t *testing.T) {
+	existing := Label(analyzer.ErrorFingerprint("db", "query <*> timed out"))
+	var created, comments []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/shop/issues":
+			if r.URL.Query().Get("labels") == existing {
+				w.Write([]byte(`[{"number": 7}]`))
+				return
+			}
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/shop/issues":
+			var issue map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&issue)
+			created = append(created, issue)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 12}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/shop/issues/7/comments":
+			var comment map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&comment)
+			comments = append(comments, comment)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tracker, err := NewGitHub("acme/shop", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filed, err := NewFiler(tracker.WithURL(server.URL), 100).File(context.Background(), testGroups())
+	if err != nil {
+		t.Fatalf("Failed to file issues: %v", err)
+	}
+
+	if len(filed) != 2 || filed[0].Key != "#12" || !filed[0].Created || filed[1].Key != "#7" || filed[1].Created {
+		t.Fatalf("Expected a new issue and a comment, got %+v", filed)
+	}
+	if len(created) != 1 || created[0]["title"] != "New error in api: out of memory" {
+		t.Fatalf("Unexpected issues created: %v", created)
+	}
+	body, _ := created[0]["body"].(string)
+	for _, want := range []string{"Entries: 500", "2023-01-01T12:00:00.000Z FATAL [api] out of memory (api.log)", "Fingerprint: logprocessor-"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in issue body:\n%s", want, body)
+		}
+	}
+	if labels, _ := created[0]["labels"].([]interface{}); len(labels) != 2 || labels[0] != Label(analyzer.ErrorFingerprint("api", "out of memory")) {
+		t.Errorf("Expected the fingerprint label, got %v", created[0]["labels"])
+	}
+	if len(comments) != 1 || !strings.Contains(comments[0]["body"].(string), "Entries: 300") {
+		t.Errorf("Unexpected comments: %v", comments)
+	}
+}
+
+func TestJiraFiler(t *testing.T) {
+	var created map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "bot@example.com" || token != "secret" {
+			t.Errorf("Unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/search":
+			if jql := r.URL.Query().Get("jql"); !strings.Contains(jql, `project = "OPS"`) || !strings.Contains(jql, "labels = ") {
+				t.Errorf("Unexpected query %q", jql)
+			}
+			w.Write([]byte(`{"issues": []}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"key": "OPS-34"}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tracker := NewJira(server.URL+"/", "OPS", "Bug", "bot@example.com", "secret")
+	filed, err := NewFiler(tracker, 100).File(context.Background(), testGroups()[:1])
+	if err != nil {
+		t.Fatalf("Failed to file issues: %v", err)
+	}
+	if len(filed) != 1 || filed[0].Key != "OPS-34" || !filed[0].Created {
+		t.Fatalf("Expected issue OPS-34, got %+v", filed)
+	}
+	fields, _ := created["fields"].(map[string]interface{})
+	if description, _ := fields["description"].(string); !strings.Contains(description, "{noformat}\n2023-01-01T12:00:00.000Z FATAL") {
+		t.Errorf("Expected the samples in a noformat block, got:\n%s", description)
+	}
+}
+
+func TestFilerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad credentials", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	tracker, _ := NewGitHub("acme/shop", "wrong")
+	_, err := NewFiler(tracker.WithURL(server.URL), 1).File(context.Background(), testGroups())
+	if err == nil || !strings.Contains(err.Error(), "bad credentials") {
+		t.Errorf("Expected the rejection in the error, got %v", err)
+	}
+	if _, err := NewGitHub("shop", "token"); err == nil {
+		t.Error("Expected an error for a repository without an owner")
+	}
+}
+
+func TestTitle(t *testing.T) {
+	long := analyzer.ErrorGroup{Service: "api", Pattern: strings.Repeat("é", 150)}
+	title := Title(long)
+	if len(title) > maxTitle || !strings.HasSuffix(title, "...") || !strings.HasPrefix(title, "New error in api: é") {
+		t.Errorf("Unexpected title %q", title)
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(title, "..."), "é") {
+		t.Errorf("Expected the title cut between characters, got %q", title)
+	}
+}
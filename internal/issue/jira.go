@@ -0,0 +1,90 @@
+package issue
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+)
+
+// Jira files issues in a Jira project through the REST API version 2,
+// which Jira Cloud, Server and Data Center all serve
+type Jira struct {
+	url       string
+	project   string
+	issueType string
+	auth      string
+	client    *http.Client
+}
+
+// NewJira creates a tracker for the project with key at the Jira site at
+// baseURL, e.g. https://example.atlassian.net, authenticating as user with
+// an API token, or with a personal access token if user is empty
+func NewJira(baseURL, project, issueType, user, token string) *Jira {
+	auth := "Bearer " + token
+	if user != "" {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+token))
+	}
+	return &Jira{
+		url:       strings.TrimSuffix(baseURL, "/"),
+		project:   project,
+		issueType: issueType,
+		auth:      auth,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (j *Jira) headers() map[string]string {
+	return map[string]string{"Authorization": j.auth}
+}
+
+// Find searches the project for an unresolved issue with label
+func (j *Jira) Find(ctx context.Context, label string) (string, error) {
+	jql := fmt.Sprintf("project = %q AND labels = %q AND statusCategory != Done", j.project, label)
+	query := url.Values{"jql": {jql}, "maxResults": {"1"}, "fields": {"key"}}
+	var result struct {
+		Issues []struct {
+			Key string `json:"key"`
+		} `json:"issues"`
+	}
+	if err := send(ctx, j.client, http.MethodGet, j.url+"/rest/api/2/search?"+query.Encode(), j.headers(), nil, &result); err != nil {
+		return "", err
+	}
+	if len(result.Issues) == 0 {
+		return "", nil
+	}
+	return result.Issues[0].Key, nil
+}
+
+// Create opens an issue labeled with label and "logprocessor"
+func (j *Jira) Create(ctx context.Context, label string, g analyzer.ErrorGroup) (string, error) {
+	body := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": j.project},
+			"issuetype":   map[string]string{"name": j.issueType},
+			"summary":     Title(g),
+			"description": "A new error pattern was found by logprocessor.\n\n" + details(g, "{noformat}", "{noformat}"),
+			"labels":      []string{label, "logprocessor"},
+		},
+	}
+	var issue struct {
+		Key string `json:"key"`
+	}
+	if err := send(ctx, j.client, http.MethodPost, j.url+"/rest/api/2/issue", j.headers(), body, &issue); err != nil {
+		return "", err
+	}
+	return issue.Key, nil
+}
+
+// Comment adds a comment to the issue with key, e.g. "OPS-34"
+func (j *Jira) Comment(ctx context.Context, key string, g analyzer.ErrorGroup) error {
+	body := map[string]string{
+		"body": "The error pattern was found again.\n\n" + details(g, "{noformat}", "{noformat}"),
+	}
+	return send(ctx, j.client, http.MethodPost, j.url+"/rest/api/2/issue/"+url.PathEscape(key)+"/comment", j.headers(), body, nil)
+}
@@ -0,0 +1,238 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// errShort is returned when a value extends past the end of a response
+var errShort = errors.New("unexpected end of response")
+
+// encoder appends the big-endian encoding of request fields
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *encoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *encoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *encoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+
+func (e *encoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// decoder reads the fields of a response. The first error is kept and
+// every later read returns zero values, so a response is checked once at
+// the end.
+type decoder struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	// Lengths come from the broker, so d.pos+n may overflow
+	if d.err != nil || n < 0 || n > len(d.buf)-d.pos {
+		if d.err == nil {
+			d.err = errShort
+		}
+		return nil
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string reads a string, with null read as ""
+func (d *decoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+// bytes reads bytes with a 32-bit length, with null read as nil
+func (d *decoder) bytes() []byte {
+	n := d.int32()
+	if n < 0 {
+		return nil
+	}
+	return d.next(int(n))
+}
+
+// arrayLen reads the length of an array. Null arrays are empty, and every
+// element takes at least a byte, which bounds a crafted length.
+func (d *decoder) arrayLen() int {
+	n := int(d.int32())
+	if n > len(d.buf)-d.pos && d.err == nil {
+		d.err = errShort
+	}
+	if d.err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) int32s() {
+	for n := d.arrayLen(); n > 0; n-- {
+		d.int32()
+	}
+}
+
+// varint reads a zig-zag encoded variable-length integer
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf[d.pos:])
+	if n <= 0 {
+		d.err = errShort
+		return 0
+	}
+	d.pos += n
+	return v
+}
+
+// varbytes reads bytes with a varint length, with null read as nil
+func (d *decoder) varbytes() []byte {
+	n := d.varint()
+	if n < 0 {
+		return nil
+	}
+	if n > int64(len(d.buf)-d.pos) {
+		d.err = errShort
+		return nil
+	}
+	return d.next(int(n))
+}
+
+// Attributes of a record batch
+const (
+	compressionMask = 0x07
+	compressionGzip = 1
+	controlBatch    = 0x20
+)
+
+// batchHeader is the size of a record batch up to its records
+const batchHeader = 61
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// parseRecords decodes the record batches of a fetch response, returning
+// their records and the offset after the last complete batch, or offset
+// if there is none. Control batches are skipped, and so is the incomplete
+// batch a response may end with.
+func parseRecords(data []byte, offset int64) ([]Record, int64, error) {
+	var records []Record
+	next := offset
+	d := &decoder{buf: data}
+	for len(data)-d.pos >= batchHeader {
+		base := d.int64()
+		length := int(d.int32())
+		if length < batchHeader-12 || length > len(data)-d.pos {
+			break
+		}
+		batch := &decoder{buf: d.next(length)}
+		batch.int32() // leader epoch
+		if magic := batch.int8(); magic != 2 {
+			return nil, offset, fmt.Errorf("unsupported message format %d at offset %d", magic, base)
+		}
+		crc := uint32(batch.int32())
+		if crc32.Checksum(batch.buf[batch.pos:], castagnoli) != crc {
+			return nil, offset, fmt.Errorf("corrupt record batch at offset %d", base)
+		}
+		attributes := batch.int16()
+		lastDelta := batch.int32()
+		first := batch.int64()
+		batch.int64() // max timestamp
+		batch.int64() // producer
+		batch.int16() // producer epoch
+		batch.int32() // base sequence
+		// Compressed records may outnumber the bytes, so count is not
+		// bounded here; reading stops at the end of the records instead
+		count := int(batch.int32())
+		next = base + int64(lastDelta) + 1
+		if attributes&controlBatch != 0 {
+			continue
+		}
+
+		body := batch.buf[batch.pos:]
+		switch attributes & compressionMask {
+		case 0:
+		case compressionGzip:
+			gz, err := gzip.NewReader(bytes.NewReader(body))
+			if err != nil {
+				return nil, offset, fmt.Errorf("failed to decompress batch at offset %d: %w", base, err)
+			}
+			if body, err = io.ReadAll(io.LimitReader(gz, maxResponse)); err != nil {
+				return nil, offset, fmt.Errorf("failed to decompress batch at offset %d: %w", base, err)
+			}
+		default:
+			return nil, offset, fmt.Errorf("unsupported compression codec %d at offset %d", attributes&compressionMask, base)
+		}
+
+		r := &decoder{buf: body}
+		for i := 0; i < count && r.err == nil; i++ {
+			record := &decoder{buf: r.varbytes()}
+			record.int8() // attributes
+			ts := first + record.varint()
+			delta := record.varint()
+			key := record.varbytes()
+			value := record.varbytes()
+			if record.err != nil {
+				r.err = record.err
+				break
+			}
+			records = append(records, Record{
+				Offset:    base + delta,
+				Timestamp: time.UnixMilli(ts).UTC(),
+				Key:       key,
+				Value:     value,
+			})
+		}
+		if r.err != nil {
+			return nil, offset, fmt.Errorf("invalid record batch at offset %d: %w", base, r.err)
+		}
+	}
+	if d.err != nil {
+		return nil, offset, d.err
+	}
+	return records, next, nil
+}
@@ -0,0 +1,373 @@
+// Package kafka is a minimal Kafka client, enough to read the partitions of
+// a topic and keep the offsets of a consumer group. A Conn is not safe for
+// concurrent use.
+package kafka
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// maxResponse bounds the size of a response
+const maxResponse = 64 * 1024 * 1024
+
+// API keys and the versions used of them
+const (
+	apiFetch           = 1
+	apiListOffsets     = 2
+	apiMetadata        = 3
+	apiOffsetCommit    = 8
+	apiOffsetFetch     = 9
+	apiFindCoordinator = 10
+)
+
+// Error is an error code returned by the broker
+type Error int16
+
+// Error codes the consumer handles
+const (
+	ErrOffsetOutOfRange     Error = 1
+	ErrUnknownTopic         Error = 3
+	ErrLeaderNotAvailable   Error = 5
+	ErrNotLeader            Error = 6
+	ErrCoordinatorLoading   Error = 14
+	ErrCoordinatorNotActive Error = 15
+	ErrNotCoordinator       Error = 16
+)
+
+var errorNames = map[Error]string{
+	ErrOffsetOutOfRange:     "offset out of range",
+	ErrUnknownTopic:         "unknown topic or partition",
+	ErrLeaderNotAvailable:   "leader not available",
+	ErrNotLeader:            "not leader for partition",
+	ErrCoordinatorLoading:   "coordinator loading",
+	ErrCoordinatorNotActive: "coordinator not available",
+	ErrNotCoordinator:       "not coordinator",
+}
+
+func (e Error) Error() string {
+	if name, ok := errorNames[e]; ok {
+		return name
+	}
+	return "kafka error " + strconv.Itoa(int(e))
+}
+
+// Conn is a connection to a Kafka broker
+type Conn struct {
+	conn        net.Conn
+	reader      *bufio.Reader
+	correlation int32
+	// ClientID identifies the client in the broker's logs
+	ClientID string
+	// Timeout bounds each request; fetches extend it by their wait
+	Timeout time.Duration
+}
+
+// Dial connects to the broker at addr, e.g. "kafka:9092"
+func Dial(ctx context.Context, addr string) (*Conn, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "9092")
+	}
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Kafka at %s: %w", addr, err)
+	}
+	return &Conn{conn: conn, reader: bufio.NewReader(conn), ClientID: "logprocessor", Timeout: 10 * time.Second}, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// roundTrip sends a request and returns the body of its response
+func (c *Conn) roundTrip(apiKey, version int16, body []byte, wait time.Duration) (*decoder, error) {
+	if c.Timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.Timeout + wait))
+	}
+	c.correlation++
+	var e encoder
+	e.int32(0)
+	e.int16(apiKey)
+	e.int16(version)
+	e.int32(c.correlation)
+	e.string(c.ClientID)
+	e.buf = append(e.buf, body...)
+	binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+	if _, err := c.conn.Write(e.buf); err != nil {
+		return nil, fmt.Errorf("failed to send to Kafka: %w", err)
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return nil, fmt.Errorf("failed to read from Kafka: %w", err)
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > maxResponse {
+		return nil, fmt.Errorf("invalid Kafka response size %d", size)
+	}
+	if id := int32(binary.BigEndian.Uint32(header[4:])); id != c.correlation {
+		return nil, fmt.Errorf("unexpected Kafka response %d to request %d", id, c.correlation)
+	}
+	buf := make([]byte, size-4)
+	if _, err := io.ReadFull(c.reader, buf); err != nil {
+		return nil, fmt.Errorf("failed to read from Kafka: %w", err)
+	}
+	return &decoder{buf: buf}, nil
+}
+
+// Partition is a partition of a topic and the address of its leader
+type Partition struct {
+	ID     int32
+	Leader string
+}
+
+// Partitions returns the partitions of topic
+func (c *Conn) Partitions(topic string) ([]Partition, error) {
+	var e encoder
+	e.int32(1)
+	e.string(topic)
+	d, err := c.roundTrip(apiMetadata, 1, e.buf, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	brokers := make(map[int32]string)
+	for n := d.arrayLen(); n > 0; n-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.int32() // controller
+	var partitions []Partition
+	for n := d.arrayLen(); n > 0; n-- {
+		code := Error(d.int16())
+		name := d.string()
+		d.int8() // internal
+		if name == topic && code != 0 {
+			return nil, fmt.Errorf("failed to get partitions of %s: %w", topic, code)
+		}
+		for m := d.arrayLen(); m > 0; m-- {
+			code := Error(d.int16())
+			p := Partition{ID: d.int32()}
+			leader := d.int32()
+			d.int32s() // replicas
+			d.int32s() // in-sync replicas
+			if name != topic {
+				continue
+			}
+			if code != 0 && code != ErrLeaderNotAvailable {
+				return nil, fmt.Errorf("failed to get partition %d of %s: %w", p.ID, topic, code)
+			}
+			if p.Leader = brokers[leader]; p.Leader == "" {
+				return nil, fmt.Errorf("partition %d of %s has no leader", p.ID, topic)
+			}
+			partitions = append(partitions, p)
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid metadata response: %w", d.err)
+	}
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", topic)
+	}
+	return partitions, nil
+}
+
+// Offset returns the offset of the oldest message in a partition, or the
+// offset the next message will get
+func (c *Conn) Offset(topic string, partition int32, oldest bool) (int64, error) {
+	timestamp := int64(-1)
+	if oldest {
+		timestamp = -2
+	}
+	var e encoder
+	e.int32(-1) // replica
+	e.int32(1)
+	e.string(topic)
+	e.int32(1)
+	e.int32(partition)
+	e.int64(timestamp)
+	d, err := c.roundTrip(apiListOffsets, 1, e.buf, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	for n := d.arrayLen(); n > 0; n-- {
+		name := d.string()
+		for m := d.arrayLen(); m > 0; m-- {
+			id := d.int32()
+			code := Error(d.int16())
+			d.int64() // timestamp
+			offset := d.int64()
+			if d.err == nil && name == topic && id == partition {
+				if code != 0 {
+					return 0, fmt.Errorf("failed to list offsets of %s/%d: %w", topic, partition, code)
+				}
+				return offset, nil
+			}
+		}
+	}
+	if d.err != nil {
+		return 0, fmt.Errorf("invalid offsets response: %w", d.err)
+	}
+	return 0, fmt.Errorf("no offset returned for %s/%d", topic, partition)
+}
+
+// Record is a message of a partition
+type Record struct {
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+}
+
+// Fetch returns the records of a partition from offset on, waiting up to
+// wait for some to arrive, and the offset to fetch next. It may return
+// records before offset, which start the first batch.
+func (c *Conn) Fetch(topic string, partition int32, offset int64, wait time.Duration, maxBytes int32) ([]Record, int64, error) {
+	var e encoder
+	e.int32(-1) // replica
+	e.int32(int32(wait.Milliseconds()))
+	e.int32(1) // min bytes
+	e.int32(maxBytes)
+	e.int8(0) // read uncommitted: log topics are not transactional
+	e.int32(1)
+	e.string(topic)
+	e.int32(1)
+	e.int32(partition)
+	e.int64(offset)
+	e.int32(maxBytes)
+	d, err := c.roundTrip(apiFetch, 4, e.buf, wait)
+	if err != nil {
+		return nil, offset, err
+	}
+
+	d.int32() // throttle time
+	for n := d.arrayLen(); n > 0; n-- {
+		name := d.string()
+		for m := d.arrayLen(); m > 0; m-- {
+			id := d.int32()
+			code := Error(d.int16())
+			d.int64() // high watermark
+			d.int64() // last stable offset
+			for k := d.arrayLen(); k > 0; k-- {
+				d.int64() // producer
+				d.int64() // first offset
+			}
+			records := d.bytes()
+			if d.err != nil || name != topic || id != partition {
+				continue
+			}
+			if code != 0 {
+				return nil, offset, fmt.Errorf("failed to fetch %s/%d: %w", topic, partition, code)
+			}
+			return parseRecords(records, offset)
+		}
+	}
+	if d.err != nil {
+		return nil, offset, fmt.Errorf("invalid fetch response: %w", d.err)
+	}
+	return nil, offset, fmt.Errorf("no records returned for %s/%d", topic, partition)
+}
+
+// Coordinator returns the address of the broker keeping the offsets of
+// group
+func (c *Conn) Coordinator(group string) (string, error) {
+	var e encoder
+	e.string(group)
+	d, err := c.roundTrip(apiFindCoordinator, 0, e.buf, 0)
+	if err != nil {
+		return "", err
+	}
+	code := Error(d.int16())
+	d.int32() // node
+	host := d.string()
+	port := d.int32()
+	if d.err != nil {
+		return "", fmt.Errorf("invalid coordinator response: %w", d.err)
+	}
+	if code != 0 {
+		return "", fmt.Errorf("failed to find the coordinator of %s: %w", group, code)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// CommittedOffset returns the offset group committed for a partition, or
+// -1 if none
+func (c *Conn) CommittedOffset(group, topic string, partition int32) (int64, error) {
+	var e encoder
+	e.string(group)
+	e.int32(1)
+	e.string(topic)
+	e.int32(1)
+	e.int32(partition)
+	d, err := c.roundTrip(apiOffsetFetch, 1, e.buf, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	for n := d.arrayLen(); n > 0; n-- {
+		name := d.string()
+		for m := d.arrayLen(); m > 0; m-- {
+			id := d.int32()
+			offset := d.int64()
+			d.string() // metadata
+			code := Error(d.int16())
+			if d.err == nil && name == topic && id == partition {
+				if code != 0 {
+					return 0, fmt.Errorf("failed to fetch the offset of %s/%d: %w", topic, partition, code)
+				}
+				return offset, nil
+			}
+		}
+	}
+	if d.err != nil {
+		return 0, fmt.Errorf("invalid offset response: %w", d.err)
+	}
+	return -1, nil
+}
+
+// CommitOffset commits offset, the next one to read, for a partition as
+// group. The group need not have members: offsets are kept like those of a
+// consumer assigning itself its partitions.
+func (c *Conn) CommitOffset(group, topic string, partition int32, offset int64) error {
+	var e encoder
+	e.string(group)
+	e.int32(-1) // generation
+	e.string("")
+	e.int64(-1) // retention
+	e.int32(1)
+	e.string(topic)
+	e.int32(1)
+	e.int32(partition)
+	e.int64(offset)
+	e.string("")
+	d, err := c.roundTrip(apiOffsetCommit, 2, e.buf, 0)
+	if err != nil {
+		return err
+	}
+
+	for n := d.arrayLen(); n > 0; n-- {
+		d.string()
+		for m := d.arrayLen(); m > 0; m-- {
+			d.int32()
+			if code := Error(d.int16()); d.err == nil && code != 0 {
+				return fmt.Errorf("failed to commit the offset of %s/%d: %w", topic, partition, code)
+			}
+		}
+	}
+	if d.err != nil {
+		return fmt.Errorf("invalid commit response: %w", d.err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package kafka
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// encodeBatch encodes a record batch starting at base with the given
+// values, compressing the records with gzip if attributes say so
+func encodeBatch(base int64, attributes int16, values ...string) []byte {
+	var records []byte
+	for i, v := range values {
+		var r []byte
+		r = append(r, 0)                        // attributes
+		r = binary.AppendVarint(r, int64(i)*10) // timestamp delta
+		r = binary.AppendVarint(r, int64(i))    // offset delta
+		r = binary.AppendVarint(r, -1)          // null key
+		r = binary.AppendVarint(r, int64(len(v)))
+		r = append(r, v...)
+		r = binary.AppendVarint(r, 0) // headers
+		records = binary.AppendVarint(records, int64(len(r)))
+		records = append(records, r...)
+	}
+	if attributes&compressionMask == compressionGzip {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(records)
+		gz.Close()
+		records = buf.Bytes()
+	}
+
+	var e encoder
+	e.int16(attributes)
+	e.int32(int32(len(values) - 1))
+	e.int64(1672567200000) // first timestamp
+	e.int64(1672567200000)
+	e.int64(-1) // producer
+	e.int16(-1)
+	e.int32(-1)
+	e.int32(int32(len(values)))
+	e.buf = append(e.buf, records...)
+	crc := crc32.Checksum(e.buf, castagnoli)
+
+	var batch encoder
+	batch.int64(base)
+	batch.int32(int32(4 + 1 + 4 + len(e.buf)))
+	batch.int32(0) // leader epoch
+	batch.int8(2)
+	batch.int32(int32(crc))
+	return append(batch.buf, e.buf...)
+}
+
+func TestParseRecords(t *testing.T) {
+	data := encodeBatch(10, 0, "a", "b")
+	data = append(data, encodeBatch(12, controlBatch, "commit")...)
+	data = append(data, encodeBatch(13, compressionGzip, "c", "d", "e")...)
+	// A response may end with part of a batch
+	data = append(data, encodeBatch(16, 0, "f")[:30]...)
+
+	records, next, err := parseRecords(data, 11)
+	if err != nil {
+		t.Fatalf("Failed to parse records: %v", err)
+	}
+	var values []string
+	for _, r := range records {
+		values = append(values, string(r.Value))
+	}
+	if len(records) != 5 || records[0].Offset != 10 || records[4].Offset != 15 || values[2] != "c" {
+		t.Errorf("Expected records 10 to 15 without the control batch, got %v", values)
+	}
+	if next != 16 {
+		t.Errorf("Expected to continue at 16, got %d", next)
+	}
+	if ts := records[1].Timestamp; !ts.Equal(time.UnixMilli(1672567200010)) {
+		t.Errorf("Expected the timestamp of the second record, got %s", ts)
+	}
+
+	corrupt := encodeBatch(0, 0, "a")
+	corrupt[len(corrupt)-2] ^= 0xff
+	if _, _, err := parseRecords(corrupt, 0); err == nil {
+		t.Error("Expected an error for a batch failing its checksum")
+	}
+}
+
+func TestDecoderCraftedLengths(t *testing.T) {
+	d := &decoder{buf: []byte{0x7f, 0xff, 0xff, 0xff, 1, 2}}
+	if b := d.bytes(); b != nil || d.err == nil {
+		t.Error("Expected an error for bytes longer than the response")
+	}
+	d = &decoder{buf: []byte{0x7f, 0xff, 0xff, 0xff}}
+	if n := d.arrayLen(); n != 0 || d.err == nil {
+		t.Errorf("Expected an error for an array longer than the response, got %d", n)
+	}
+	d = &decoder{buf: binary.AppendVarint(nil, 1<<62)}
+	if b := d.varbytes(); b != nil || d.err == nil {
+		t.Error("Expected an error for a record longer than the batch")
+	}
+}
+
+func TestConnFetch(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var size int32
+			if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+				return
+			}
+			req := make([]byte, size)
+			io.ReadFull(conn, req)
+			api := int16(binary.BigEndian.Uint16(req))
+			correlation := int32(binary.BigEndian.Uint32(req[4:]))
+
+			var e encoder
+			e.int32(0)
+			e.int32(correlation)
+			switch api {
+			case apiMetadata:
+				e.int32(1)
+				e.int32(7)
+				e.string("127.0.0.1")
+				e.int32(9092)
+				e.int16(-1)
+				e.int32(7) // controller
+				e.int32(1)
+				e.int16(0)
+				e.string("logs")
+				e.int8(0)
+				e.int32(1)
+				e.int16(0)
+				e.int32(0)
+				e.int32(7) // leader
+				e.int32(0)
+				e.int32(0)
+			case apiFetch:
+				e.int32(0)
+				e.int32(1)
+				e.string("logs")
+				e.int32(1)
+				e.int32(0)
+				e.int16(int16(ErrOffsetOutOfRange))
+				e.int64(0)
+				e.int64(0)
+				e.int32(-1)
+				e.int32(-1)
+			}
+			binary.BigEndian.PutUint32(e.buf, uint32(len(e.buf)-4))
+			conn.Write(e.buf)
+		}
+	}()
+
+	conn, err := Dial(context.Background(), listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	partitions, err := conn.Partitions("logs")
+	if err != nil {
+		t.Fatalf("Failed to get partitions: %v", err)
+	}
+	if len(partitions) != 1 || partitions[0].Leader != "127.0.0.1:9092" {
+		t.Errorf("Expected partition 0 led by 127.0.0.1:9092, got %+v", partitions)
+	}
+	if _, _, err := conn.Fetch("logs", 0, 5, 0, 1024); !errors.Is(err, ErrOffsetOutOfRange) {
+		t.Errorf("Expected an out of range error, got %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Package logmetric converts matching log entries into time-series metrics:
+// counters, gauges and histograms labeled by entry fields, exported in the
+// Prometheus text format and to statsd, so the processor can act as a
+// lightweight bridge from logs to metrics.
+package logmetric
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/rules"
+)
+
+// Metric types
+const (
+	Counter   = "counter"
+	Gauge     = "gauge"
+	Histogram = "histogram"
+)
+
+// maxSeries bounds the label combinations of one metric. Further
+// combinations are recorded with every label set to OtherLabel.
+const maxSeries = 1000
+
+// OtherLabel is the label value of series beyond maxSeries
+const OtherLabel = "other"
+
+// DefaultBuckets are the histogram bucket bounds used when none are given,
+// suited to durations in milliseconds
+var DefaultBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// validName matches metric and label names accepted by Prometheus
+var validName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Definition describes a metric derived from entries
+type Definition struct {
+	// Name names the metric, e.g. http_request_duration_ms
+	Name string
+	// Type is Counter, Gauge or Histogram
+	Type string
+	// Match is a rule match expression selecting the entries; empty matches
+	// every entry
+	Match string
+	// Value is the field holding the number, e.g. fields.duration_ms. It is
+	// required for gauges and histograms; counters add it if set, or else
+	// count entries.
+	Value string
+	// Labels are the fields labeling the series, e.g. service or
+	// fields.status. The label name is the field name without "fields.".
+	Labels []string
+	// Buckets are the upper bounds of a histogram's buckets, DefaultBuckets
+	// if empty
+	Buckets []float64
+}
+
+// Reporter receives every observation, e.g. a statsd client. Tags are
+// "key:value" pairs.
+type Reporter interface {
+	Count(name string, value int64, tags ...string)
+	Gauge(name string, value float64, tags ...string)
+	Histogram(name string, value float64, tags ...string)
+}
+
+// metric is a validated definition with its series
+type metric struct {
+	Definition
+	match      rules.Matcher
+	value      func(models.LogEntry) string
+	labels     []func(models.LogEntry) string
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one label combination of a metric
+type series struct {
+	labels []string
+	// value is the counter's total or the gauge's latest value
+	value float64
+	// counts holds a histogram's per-bucket counts, the last one for values
+	// above every bound
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Converter turns entries into metrics. It is a sink: the processor writes
+// every analyzed entry to it.
+type Converter struct {
+	metrics  []*metric
+	reporter Reporter
+}
+
+// Option configures a Converter
+type Option func(*Converter)
+
+// WithReporter also sends every observation to r
+func WithReporter(r Reporter) Option {
+	return func(c *Converter) {
+		c.reporter = r
+	}
+}
+
+// New validates the definitions and creates a converter for them
+func New(defs []Definition, opts ...Option) (*Converter, error) {
+	c := &Converter{}
+	seen := make(map[string]bool)
+	for _, def := range defs {
+		m, err := newMetric(def)
+		if err != nil {
+			return nil, err
+		}
+		if seen[def.Name] {
+			return nil, fmt.Errorf("duplicate metric %s", def.Name)
+		}
+		seen[def.Name] = true
+		c.metrics = append(c.metrics, m)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// newMetric validates a definition
+func newMetric(def Definition) (*metric, error) {
+	if !validName.MatchString(def.Name) {
+		return nil, fmt.Errorf("invalid metric name %q: use letters, digits and underscores", def.Name)
+	}
+	m := &metric{Definition: def, series: make(map[string]*series)}
+	switch def.Type {
+	case Counter:
+	case Gauge, Histogram:
+		if def.Value == "" {
+			return nil, fmt.Errorf("%s metric %s needs a value field", def.Type, def.Name)
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %q of metric %s (supported: counter, gauge, histogram)", def.Type, def.Name)
+	}
+	if def.Type == Histogram {
+		if len(m.Buckets) == 0 {
+			m.Buckets = DefaultBuckets
+		}
+		if !sort.Float64sAreSorted(m.Buckets) {
+			return nil, fmt.Errorf("buckets of metric %s are not in increasing order", def.Name)
+		}
+	}
+
+	var err error
+	if m.match, err = rules.ParseMatch(def.Match); err != nil {
+		return nil, fmt.Errorf("invalid match of metric %s: %w", def.Name, err)
+	}
+	if def.Value != "" {
+		if m.value, err = rules.Field(def.Value); err != nil {
+			return nil, fmt.Errorf("invalid value of metric %s: %w", def.Name, err)
+		}
+	}
+	for _, field := range def.Labels {
+		get, err := rules.Field(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label of metric %s: %w", def.Name, err)
+		}
+		name := strings.TrimPrefix(field, "fields.")
+		if !validName.MatchString(name) {
+			return nil, fmt.Errorf("invalid label %q of metric %s: use letters, digits and underscores", name, def.Name)
+		}
+		m.labels = append(m.labels, get)
+		m.labelNames = append(m.labelNames, name)
+	}
+	return m, nil
+}
+
+// Write records entry in every metric it matches. Entries whose value
+// field is missing or not a number are skipped by that metric.
+func (c *Converter) Write(entry models.LogEntry) error {
+	for _, m := range c.metrics {
+		if !m.match(entry) {
+			continue
+		}
+		value := 1.0
+		if m.value != nil {
+			v, err := strconv.ParseFloat(strings.TrimSpace(m.value(entry)), 64)
+			if err != nil {
+				continue
+			}
+			value = v
+		}
+		labels := m.observe(entry, value)
+		if c.reporter != nil {
+			c.report(m, labels, value)
+		}
+	}
+	return nil
+}
+
+// Close does nothing: the metrics outlive the processor writing to them
+func (c *Converter) Close() error {
+	return nil
+}
+
+// observe records value in the series of entry's labels and returns them
+func (m *metric) observe(entry models.LogEntry, value float64) []string {
+	labels := make([]string, len(m.labels))
+	for i, get := range m.labels {
+		labels[i] = get(entry)
+	}
+	key := strings.Join(labels, "\x00")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.series[key]
+	if !ok {
+		if len(m.series) >= maxSeries {
+			for i := range labels {
+				labels[i] = OtherLabel
+			}
+			key = strings.Join(labels, "\x00")
+			s, ok = m.series[key]
+		}
+		if !ok {
+			s = &series{labels: labels}
+			if m.Type == Histogram {
+				s.counts = make([]uint64, len(m.Buckets)+1)
+			}
+			m.series[key] = s
+		}
+	}
+	switch m.Type {
+	case Counter:
+		s.value += value
+	case Gauge:
+		s.value = value
+	case Histogram:
+		s.counts[sort.SearchFloat64s(m.Buckets, value)]++
+		s.count++
+		s.sum += value
+	}
+	return s.labels
+}
+
+// report sends an observation to the reporter
+func (c *Converter) report(m *metric, labels []string, value float64) {
+	tags := make([]string, len(labels))
+	for i, label := range labels {
+		tags[i] = m.labelNames[i] + ":" + label
+	}
+	switch m.Type {
+	case Counter:
+		c.reporter.Count(m.Name, int64(value), tags...)
+	case Gauge:
+		c.reporter.Gauge(m.Name, value, tags...)
+	case Histogram:
+		c.reporter.Histogram(m.Name, value, tags...)
+	}
+}
+
+// WritePrometheus writes every metric in the Prometheus text format, with
+// each name prefixed by prefix
+func (c *Converter) WritePrometheus(w io.Writer, prefix string) error {
+	var b strings.Builder
+	for _, m := range c.metrics {
+		m.writePrometheus(&b, prefix)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (m *metric) writePrometheus(b *strings.Builder, prefix string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name := prefix + m.Name
+	if m.Type == Counter {
+		name += "_total"
+	}
+	fmt.Fprintf(b, "# HELP %s Derived from entries matching %q.\n# TYPE %s %s\n", name, m.Match, name, m.Type)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := m.series[key]
+		if m.Type != Histogram {
+			fmt.Fprintf(b, "%s%s %s\n", name, m.labelSet(s.labels, ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range m.Buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", name, m.labelSet(s.labels, formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", name, m.labelSet(s.labels, "+Inf"), s.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", name, m.labelSet(s.labels, ""), formatFloat(s.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", name, m.labelSet(s.labels, ""), s.count)
+	}
+}
+
+// labelSet renders the labels of a series, adding le if set
+func (m *metric) labelSet(values []string, le string) string {
+	var pairs []string
+	for i, value := range values {
+		pairs = append(pairs, m.labelNames[i]+"="+quoteLabel(value))
+	}
+	if le != "" {
+		pairs = append(pairs, "le="+quoteLabel(le))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// quoteLabel quotes a label value, escaping backslashes, quotes and newlines
+func quoteLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
+
+// formatFloat renders a sample value
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
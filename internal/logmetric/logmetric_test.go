@@ -0,0 +1,96 @@
+package logmetric
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// recordingReporter keeps every observation as a line
+type recordingReporter struct {
+	lines []string
+}
+
+func (r *recordingReporter) Count(name string, value int64, tags ...string) {
+	r.lines = append(r.lines, fmt.Sprintf("count %s %d %v", name, value, tags))
+}
+
+func (r *recordingReporter) Gauge(name string, value float64, tags ...string) {
+	r.lines = append(r.lines, fmt.Sprintf("gauge %s %v %v", name, value, tags))
+}
+
+func (r *recordingReporter) Histogram(name string, value float64, tags ...string) {
+	r.lines = append(r.lines, fmt.Sprintf("histogram %s %v %v", name, value, tags))
+}
+
+func TestConverter(t *testing.T) {
+	reporter := &recordingReporter{}
+	c, err := New([]Definition{
+		{Name: "http_errors", Type: Counter, Match: `fields.status ~ "^5"`, Labels: []string{"service", "fields.status"}},
+		{Name: "queue_depth", Type: Gauge, Value: "fields.depth", Labels: []string{"service"}},
+		{Name: "request_ms", Type: Histogram, Value: "fields.duration_ms", Buckets: []float64{10, 100}},
+	}, WithReporter(reporter))
+	if err != nil {
+		t.Fatalf("Failed to create converter: %v", err)
+	}
+
+	entries := []models.LogEntry{
+		{Service: "api", Fields: map[string]string{"status": "503", "duration_ms": "5"}},
+		{Service: "api", Fields: map[string]string{"status": "503", "duration_ms": "50"}},
+		{Service: "api", Fields: map[string]string{"status": "200", "duration_ms": "500"}},
+		{Service: "worker", Fields: map[string]string{"depth": "7"}},
+		{Service: "worker", Fields: map[string]string{"depth": "3"}},
+		{Service: "worker", Fields: map[string]string{"depth": "n/a"}},
+	}
+	for _, entry := range entries {
+		if err := c.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+
+	var b strings.Builder
+	if err := c.WritePrometheus(&b, "lp_"); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE lp_http_errors_total counter\n",
+		`lp_http_errors_total{service="api",status="503"} 2`,
+		`lp_queue_depth{service="worker"} 3`,
+		`lp_request_ms_bucket{le="10"} 1`,
+		`lp_request_ms_bucket{le="100"} 2`,
+		`lp_request_ms_bucket{le="+Inf"} 3`,
+		"lp_request_ms_sum 555\n",
+		"lp_request_ms_count 3\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), `status="200"`) {
+		t.Errorf("Expected entries not matching to be left out, got:\n%s", b.String())
+	}
+
+	if len(reporter.lines) != 7 || reporter.lines[0] != "count http_errors 1 [service:api status:503]" {
+		t.Errorf("Expected every observation to be reported, got %v", reporter.lines)
+	}
+}
+
+func TestNewRejectsInvalidDefinitions(t *testing.T) {
+	for _, def := range []Definition{
+		{Name: "bad-name", Type: Counter},
+		{Name: "depth", Type: Gauge},
+		{Name: "depth", Type: "summary", Value: "fields.depth"},
+		{Name: "errors", Type: Counter, Match: "nope == 1"},
+		{Name: "errors", Type: Counter, Labels: []string{"fields.http-status"}},
+		{Name: "ms", Type: Histogram, Value: "fields.ms", Buckets: []float64{100, 10}},
+	} {
+		if _, err := New([]Definition{def}); err == nil {
+			t.Errorf("Expected an error for %+v", def)
+		}
+	}
+	if _, err := New([]Definition{{Name: "errors", Type: Counter}, {Name: "errors", Type: Counter}}); err == nil {
+		t.Error("Expected an error for a duplicate metric")
+	}
+}
@@ -0,0 +1,100 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problems found when verifying input against a manifest
+const (
+	// ProblemModified is a file whose content differs from the manifest
+	ProblemModified = "modified"
+	// ProblemMissing is a file in the manifest that was not read
+	ProblemMissing = "missing"
+	// ProblemUnexpected is a file that was read but is not in the manifest
+	ProblemUnexpected = "unexpected"
+)
+
+// Custody records the verification of a run's input against the file
+// hashes of an earlier manifest, showing whether the logs analyzed are the
+// ones that manifest recorded
+type Custody struct {
+	// Manifest is the path of the earlier manifest and ManifestSHA256 the
+	// digest of its contents
+	Manifest       string        `json:"manifest"`
+	ManifestSHA256 string        `json:"manifest_sha256"`
+	Verified       bool          `json:"verified"`
+	Discrepancies  []Discrepancy `json:"discrepancies,omitempty"`
+}
+
+// Discrepancy is an input file that does not match the earlier manifest
+type Discrepancy struct {
+	Path     string `json:"path"`
+	Problem  string `json:"problem"`
+	Expected string `json:"expected_sha256,omitempty"`
+	Actual   string `json:"actual_sha256,omitempty"`
+}
+
+// Verify compares the hashes of files, read from input, with those of the
+// manifest at path. Files are matched by their path relative to the input
+// of each run, so the logs may have been copied elsewhere since.
+func Verify(path, input string, files []File) (*Custody, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var expected Manifest
+	if err := json.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	c := &Custody{Manifest: path, ManifestSHA256: hex.EncodeToString(sum[:])}
+
+	want := make(map[string]string)
+	for _, f := range expected.Files {
+		if f.SHA256 != "" {
+			want[relPath(expected.Input, f.Path)] = f.SHA256
+		}
+	}
+	if len(want) == 0 {
+		return nil, fmt.Errorf("manifest %s has no input file hashes", path)
+	}
+
+	for _, f := range files {
+		rel := relPath(input, f.Path)
+		expectedSum, ok := want[rel]
+		delete(want, rel)
+		switch {
+		case f.SHA256 == "" && ok:
+			// The file was skipped or failed before it was read
+			c.Discrepancies = append(c.Discrepancies, Discrepancy{Path: rel, Problem: ProblemMissing, Expected: expectedSum})
+		case f.SHA256 == "":
+		case !ok:
+			c.Discrepancies = append(c.Discrepancies, Discrepancy{Path: rel, Problem: ProblemUnexpected, Actual: f.SHA256})
+		case f.SHA256 != expectedSum:
+			c.Discrepancies = append(c.Discrepancies, Discrepancy{Path: rel, Problem: ProblemModified, Expected: expectedSum, Actual: f.SHA256})
+		}
+	}
+	for rel, expectedSum := range want {
+		c.Discrepancies = append(c.Discrepancies, Discrepancy{Path: rel, Problem: ProblemMissing, Expected: expectedSum})
+	}
+	sort.Slice(c.Discrepancies, func(i, j int) bool { return c.Discrepancies[i].Path < c.Discrepancies[j].Path })
+	c.Verified = len(c.Discrepancies) == 0
+	return c, nil
+}
+
+// relPath returns path relative to the input of its run, or its base name
+// if the input was the file itself
+func relPath(input, path string) string {
+	rel, err := filepath.Rel(input, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return filepath.Base(path)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,70 @@
+package manifest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	earlier := New("/evidence/logs", nil)
+	earlier.AddFile("/evidence/logs/a.json", "ok", "", 10, 0)
+	earlier.AddFile("/evidence/logs/b.json", "ok", "", 5, 0)
+	earlier.AddFile("/evidence/logs/c.json", "ok", "", 5, 0)
+	earlier.SetHash("/evidence/logs/a.json", "aaaa", 100)
+	earlier.SetHash("/evidence/logs/b.json", "bbbb", 50)
+	earlier.SetHash("/evidence/logs/c.json", "cccc", 50)
+	data, _ := json.Marshal(earlier)
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The logs were copied elsewhere before the second run
+	files := []File{
+		{Path: "/copy/a.json", SHA256: "aaaa"},
+		{Path: "/copy/b.json", SHA256: "bad0"},
+		{Path: "/copy/d.json", SHA256: "dddd"},
+	}
+	c, err := Verify(path, "/copy", files)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if c.Verified || c.ManifestSHA256 == "" {
+		t.Errorf("Expected an unverified custody with the manifest's hash, got %+v", c)
+	}
+	want := []Discrepancy{
+		{Path: "b.json", Problem: ProblemModified, Expected: "bbbb", Actual: "bad0"},
+		{Path: "c.json", Problem: ProblemMissing, Expected: "cccc"},
+		{Path: "d.json", Problem: ProblemUnexpected, Actual: "dddd"},
+	}
+	if len(c.Discrepancies) != len(want) {
+		t.Fatalf("Expected %d discrepancies, got %+v", len(want), c.Discrepancies)
+	}
+	for i := range want {
+		if c.Discrepancies[i] != want[i] {
+			t.Errorf("Expected discrepancy %+v, got %+v", want[i], c.Discrepancies[i])
+		}
+	}
+
+	files = []File{
+		{Path: "/copy/a.json", SHA256: "aaaa"},
+		{Path: "/copy/b.json", SHA256: "bbbb"},
+		{Path: "/copy/c.json", SHA256: "cccc"},
+	}
+	if c, err := Verify(path, "/copy", files); err != nil || !c.Verified {
+		t.Errorf("Expected unchanged input to verify, got %+v (%v)", c, err)
+	}
+}
+
+func TestVerifyWithoutHashes(t *testing.T) {
+	earlier := New("/logs", nil)
+	earlier.AddFile("/logs/a.json", "ok", "", 10, 0)
+	data, _ := json.Marshal(earlier)
+	path := filepath.Join(t.TempDir(), "run.json")
+	os.WriteFile(path, data, 0644)
+	if _, err := Verify(path, "/logs", []File{{Path: "/logs/a.json", SHA256: "aaaa"}}); err == nil {
+		t.Error("Expected an error for a manifest without file hashes")
+	}
+}
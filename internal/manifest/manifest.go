@@ -0,0 +1,255 @@
+// Package manifest records what a processing run did: its settings, the
+// build that ran it, the outcome of every input file and how long it took,
+// so batch pipelines can archive an auditable record next to each summary.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Version is the version of the manifest format
+const Version = 1
+
+// Redacted replaces the values of secret settings
+const Redacted = "REDACTED"
+
+// Run outcomes
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Manifest describes one processing run
+type Manifest struct {
+	Version         int       `json:"manifest_version"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Status          string    `json:"status"`
+	Error           string    `json:"error,omitempty"`
+	Build           Build     `json:"build"`
+	// Settings holds the flags given on the command line, with secrets
+	// redacted
+	Settings map[string]string `json:"settings"`
+	// ConfigHash is the SHA-256 of the settings and the contents of the
+	// config files, identifying runs with the same configuration
+	ConfigHash string `json:"config_hash"`
+	Input      string `json:"input"`
+	Files      []File `json:"files"`
+	// Skipped lists the paths a recursive scan of the input left out
+	Skipped []Skipped `json:"skipped_paths,omitempty"`
+	Entries int       `json:"entries"`
+	// ByLevel counts the entries of each level
+	ByLevel map[models.LogLevel]int `json:"by_level,omitempty"`
+	// Accounting reconciles the entries read with Entries
+	Accounting *models.Accounting `json:"accounting,omitempty"`
+	// Custody is the check of the input against an earlier manifest, if
+	// one was requested
+	Custody *Custody `json:"custody,omitempty"`
+	// BytesRead is the total size of the input files
+	BytesRead int64 `json:"bytes_read"`
+	// EntriesPerSecond and BytesPerSecond are the entries and bytes read
+	// over the duration of the run
+	EntriesPerSecond float64 `json:"entries_per_second"`
+	BytesPerSecond   float64 `json:"bytes_per_second"`
+	// Phases is the time spent in each part of the run up to the manifest,
+	// in order
+	Phases []Phase `json:"phases,omitempty"`
+}
+
+// Phase is a part of the run, such as reading the input
+type Phase struct {
+	Name            string  `json:"name"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// Build identifies the binary that performed the run
+type Build struct {
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"revision,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// File is the outcome of one input file
+type File struct {
+	Path            string  `json:"path"`
+	Status          string  `json:"status"`
+	Error           string  `json:"error,omitempty"`
+	Entries         int     `json:"entries"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	// SHA256 is the hex digest of the Bytes read from the file
+	SHA256 string `json:"sha256,omitempty"`
+	Bytes  int64  `json:"bytes,omitempty"`
+}
+
+// Skipped is a path left out of the input, such as an unreadable
+// directory or a symbolic link loop
+type Skipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// New starts the manifest of a run over input with the given settings. The
+// values of the settings named in secret are redacted, and so are the
+// passwords of URLs in any setting, such as an -output or -redis-url.
+func New(input string, settings map[string]string, secret ...string) *Manifest {
+	m := &Manifest{
+		Version:   Version,
+		StartedAt: time.Now().UTC(),
+		Build:     currentBuild(),
+		Input:     input,
+		Settings:  make(map[string]string, len(settings)),
+	}
+	for name, value := range settings {
+		m.Settings[name] = redactURLs(value)
+	}
+	for _, name := range secret {
+		if _, ok := m.Settings[name]; ok {
+			m.Settings[name] = Redacted
+		}
+	}
+	return m
+}
+
+// tokenSchemes are the URL schemes whose clients take a user without a
+// password as a token
+var tokenSchemes = map[string]bool{"nats": true, "redis": true, "rediss": true}
+
+// redactURLs redacts the passwords of the URLs in a setting, which holds
+// the values of a repeated flag separated by commas
+func redactURLs(value string) string {
+	if !strings.Contains(value, "@") {
+		return value
+	}
+	parts := strings.Split(value, ",")
+	for i, part := range parts {
+		u, err := url.Parse(part)
+		if err != nil || u.User == nil {
+			continue
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), Redacted)
+			parts[i] = u.String()
+		} else if tokenSchemes[u.Scheme] {
+			u.User = url.User(Redacted)
+			parts[i] = u.String()
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// HashConfig sets ConfigHash from the settings and the contents of files,
+// such as the -config file
+func (m *Manifest) HashConfig(files ...string) error {
+	h := sha256.New()
+	names := make([]string, 0, len(m.Settings))
+	for name := range m.Settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s=%q\n", name, m.Settings[name])
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to hash config file: %w", err)
+		}
+		fmt.Fprintf(h, "%s %d\n", file, len(data))
+		h.Write(data)
+	}
+	m.ConfigHash = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// AddFile records the outcome of an input file
+func (m *Manifest) AddFile(path, status, reason string, entries int, d time.Duration) {
+	m.Files = append(m.Files, File{
+		Path:            path,
+		Status:          status,
+		Error:           reason,
+		Entries:         entries,
+		DurationSeconds: d.Seconds(),
+	})
+}
+
+// AddPhase records the time spent in a part of the run
+func (m *Manifest) AddPhase(name string, d time.Duration) {
+	m.Phases = append(m.Phases, Phase{Name: name, DurationSeconds: d.Seconds()})
+}
+
+// AddSkipped records a path left out of the input and why
+func (m *Manifest) AddSkipped(path, reason string) {
+	m.Skipped = append(m.Skipped, Skipped{Path: path, Reason: reason})
+}
+
+// SetHash records the digest of the bytes read from an input file added
+// with AddFile
+func (m *Manifest) SetHash(path, sum string, bytes int64) {
+	for i := range m.Files {
+		if m.Files[i].Path == path {
+			m.Files[i].SHA256, m.Files[i].Bytes = sum, bytes
+		}
+	}
+}
+
+// Finish records the end of the run, with its summary if it produced one
+// and err if it failed
+func (m *Manifest) Finish(summary *models.LogSummary, err error) {
+	m.FinishedAt = time.Now().UTC()
+	m.DurationSeconds = m.FinishedAt.Sub(m.StartedAt).Seconds()
+	m.Status = StatusOK
+	if err != nil {
+		m.Status, m.Error = StatusFailed, err.Error()
+	}
+	if summary != nil {
+		m.Entries = summary.TotalEntries
+		m.ByLevel = summary.ByLevel
+		m.Accounting = summary.Accounting
+	}
+
+	m.BytesRead = 0
+	for _, f := range m.Files {
+		m.BytesRead += f.Bytes
+	}
+	if m.DurationSeconds > 0 {
+		read := m.Entries
+		if m.Accounting != nil {
+			read = m.Accounting.Read
+		}
+		m.EntriesPerSecond = float64(read) / m.DurationSeconds
+		m.BytesPerSecond = float64(m.BytesRead) / m.DurationSeconds
+	}
+}
+
+// currentBuild reads the build information embedded in the binary
+func currentBuild() Build {
+	b := Build{GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	b.Module, b.Version = info.Main.Path, info.Main.Version
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Revision = s.Value
+		case "vcs.modified":
+			b.Modified = s.Value == "true"
+		}
+	}
+	return b
+}
@@ -0,0 +1,106 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestManifest(t *testing.T) {
+	settings := map[string]string{"dir": "/logs", "opsgenie-api-key": "secret"}
+	m := New("/logs", settings, "opsgenie-api-key", "pagerduty-routing-key")
+	if m.Settings["opsgenie-api-key"] != Redacted {
+		t.Errorf("Expected the API key to be redacted, got %q", m.Settings["opsgenie-api-key"])
+	}
+	if _, ok := m.Settings["pagerduty-routing-key"]; ok {
+		t.Error("Expected secrets that were not set to stay absent")
+	}
+	if m.Build.GoVersion == "" {
+		t.Error("Expected the Go version to be recorded")
+	}
+
+	m.AddFile("/logs/a.json", "ok", "", 10, 2*time.Second)
+	m.AddSkipped("/logs/private", "permission denied")
+	summary := models.NewLogSummary()
+	summary.TotalEntries = 10
+	m.Finish(summary, nil)
+	if m.Status != StatusOK || m.Entries != 10 || m.Files[0].DurationSeconds != 2 || m.Skipped[0].Reason != "permission denied" {
+		t.Errorf("Unexpected manifest %+v", m)
+	}
+
+	m.SetHash("/logs/a.json", "abc", 2048)
+	m.AddPhase("process", 1500*time.Millisecond)
+	m.StartedAt = time.Now().Add(-2 * time.Second)
+	m.Finish(summary, nil)
+	if m.BytesRead != 2048 || m.EntriesPerSecond < 4 || m.EntriesPerSecond > 5 || m.BytesPerSecond < 900 || m.BytesPerSecond > 1100 {
+		t.Errorf("Unexpected throughput: %d bytes, %f entries/s, %f bytes/s", m.BytesRead, m.EntriesPerSecond, m.BytesPerSecond)
+	}
+	if len(m.Phases) != 1 || m.Phases[0].Name != "process" || m.Phases[0].DurationSeconds != 1.5 {
+		t.Errorf("Unexpected phases %+v", m.Phases)
+	}
+
+	m.Finish(nil, errors.New("no log files found"))
+	if m.Status != StatusFailed || m.Error != "no log files found" {
+		t.Errorf("Expected a failed run, got %s: %s", m.Status, m.Error)
+	}
+}
+
+func TestManifestRedactsURLPasswords(t *testing.T) {
+	settings := map[string]string{
+		"output":    "postgres://user:hunter2@db:5432/logs?sslmode=require,gelf+tcp://graylog:12201",
+		"redis-url": "redis://:s3cret@cache:6379/0",
+		"nats-url":  "nats://t0ken@nats:4222",
+		"amqp-url":  "amqp://guest@rabbit:5672/",
+		"dir":       "/logs",
+	}
+	m := New("/logs", settings)
+	if got := m.Settings["output"]; got != "postgres://user:REDACTED@db:5432/logs?sslmode=require,gelf+tcp://graylog:12201" {
+		t.Errorf("Expected the output password to be redacted, got %q", got)
+	}
+	if got := m.Settings["redis-url"]; got != "redis://:REDACTED@cache:6379/0" {
+		t.Errorf("Expected the Redis password to be redacted, got %q", got)
+	}
+	if got := m.Settings["nats-url"]; got != "nats://REDACTED@nats:4222" {
+		t.Errorf("Expected the NATS token to be redacted, got %q", got)
+	}
+	if got := m.Settings["amqp-url"]; got != settings["amqp-url"] {
+		t.Errorf("Expected a user without a password to be kept, got %q", got)
+	}
+	for name, value := range m.Settings {
+		if strings.Contains(value, "hunter2") || strings.Contains(value, "s3cret") || strings.Contains(value, "t0ken") {
+			t.Errorf("Expected no password in %s, got %q", name, value)
+		}
+	}
+}
+
+func TestHashConfig(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(config, []byte(`{"services":{}}`), 0644)
+
+	hash := func(settings map[string]string) string {
+		m := New("/logs", settings)
+		if err := m.HashConfig(config); err != nil {
+			t.Fatalf("Failed to hash config: %v", err)
+		}
+		return m.ConfigHash
+	}
+	first := hash(map[string]string{"format": "alb", "section": "storms"})
+	if again := hash(map[string]string{"section": "storms", "format": "alb"}); again != first {
+		t.Error("Expected the same settings to give the same hash")
+	}
+	if other := hash(map[string]string{"format": "json", "section": "storms"}); other == first {
+		t.Error("Expected different settings to give a different hash")
+	}
+	os.WriteFile(config, []byte(`{"services":{"api":{}}}`), 0644)
+	if changed := hash(map[string]string{"format": "alb", "section": "storms"}); changed == first {
+		t.Error("Expected a changed config file to change the hash")
+	}
+	if err := New("/logs", nil).HashConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing config file")
+	}
+}
@@ -0,0 +1,259 @@
+package mergesort
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/crypt"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// DefaultRunSize is the number of entries held in memory before a sorted
+// run is spilled to disk
+const DefaultRunSize = 100000
+
+// maxOpenRuns bounds the number of run files merged at once
+const maxOpenRuns = 128
+
+// Sorter is a sink that writes every entry it receives as NDJSON in
+// timestamp order once it is closed. At most runSize entries are kept in
+// memory; larger inputs are spilled to sorted temporary files and combined
+// with an external merge sort. Entries with equal timestamps are ordered by
+// source and ID so the output is deterministic.
+type Sorter struct {
+	w       io.Writer
+	closer  io.Closer
+	runSize int
+	// cipher encrypts the output and the run files, if set
+	cipher *crypt.Cipher
+
+	mu   sync.Mutex
+	buf  []models.LogEntry
+	runs []string
+}
+
+// Option configures a Sorter
+type Option func(*Sorter)
+
+// WithCipher encrypts the output and the run files spilled to disk with c
+func WithCipher(c *crypt.Cipher) Option {
+	return func(s *Sorter) {
+		s.cipher = c
+	}
+}
+
+// NewSorter creates a sorter writing to w
+func NewSorter(w io.Writer, runSize int, opts ...Option) *Sorter {
+	if runSize <= 0 {
+		runSize = DefaultRunSize
+	}
+	s := &Sorter{w: w, runSize: runSize}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create creates a sorter writing to the file at path, or to stdout for "-"
+func Create(path string, runSize int, opts ...Option) (*Sorter, error) {
+	if path == "-" {
+		return NewSorter(os.Stdout, runSize, opts...), nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merge output: %w", err)
+	}
+	s := NewSorter(file, runSize, opts...)
+	s.closer = file
+	return s, nil
+}
+
+// less orders entries chronologically
+func less(a, b *models.LogEntry) bool {
+	if !a.Timestamp.Equal(b.Timestamp) {
+		return a.Timestamp.Before(b.Timestamp)
+	}
+	if a.Source != b.Source {
+		return a.Source < b.Source
+	}
+	return a.ID < b.ID
+}
+
+func (s *Sorter) Write(entry models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf = append(s.buf, entry)
+	if len(s.buf) >= s.runSize {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the buffered entries to a sorted run file. Callers hold mu.
+func (s *Sorter) spill() error {
+	sortEntries(s.buf)
+
+	file, err := os.CreateTemp("", "logprocessor-run-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create sort run: %w", err)
+	}
+	s.runs = append(s.runs, file.Name())
+
+	err = writeEncrypted(file, s.cipher, func(w io.Writer) error { return writeEntries(w, s.buf) })
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write sort run: %w", err)
+	}
+	s.buf = s.buf[:0]
+	return nil
+}
+
+// Close writes all entries in order, removes the run files and closes the
+// output file, if the sorter created it
+func (s *Sorter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer func() {
+		for _, run := range s.runs {
+			os.Remove(run)
+		}
+		s.runs = nil
+	}()
+
+	err := s.finish()
+	if s.closer != nil {
+		if closeErr := s.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// finish merges the runs and the buffer into the output. Callers hold mu.
+func (s *Sorter) finish() error {
+	if len(s.runs) == 0 {
+		// Everything fit in memory
+		sortEntries(s.buf)
+		return writeEncrypted(s.w, s.cipher, func(w io.Writer) error { return writeEntries(w, s.buf) })
+	}
+	if len(s.buf) > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+
+	// Merge runs in groups until few enough remain to open at once
+	for len(s.runs) > maxOpenRuns {
+		file, err := os.CreateTemp("", "logprocessor-run-*.json")
+		if err != nil {
+			return fmt.Errorf("failed to create sort run: %w", err)
+		}
+		group := s.runs[:maxOpenRuns]
+		err = writeEncrypted(file, s.cipher, func(w io.Writer) error { return mergeRuns(group, w, s.cipher) })
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		for _, run := range group {
+			os.Remove(run)
+		}
+		s.runs = append(s.runs[maxOpenRuns:], file.Name())
+		if err != nil {
+			return err
+		}
+	}
+	return writeEncrypted(s.w, s.cipher, func(w io.Writer) error { return mergeRuns(s.runs, w, s.cipher) })
+}
+
+// writeEncrypted calls write with a writer encrypting into w with c, which
+// may be nil
+func writeEncrypted(w io.Writer, c *crypt.Cipher, write func(io.Writer) error) error {
+	ew := c.NewWriter(w)
+	if err := write(ew); err != nil {
+		return err
+	}
+	return ew.Close()
+}
+
+func sortEntries(entries []models.LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool { return less(&entries[i], &entries[j]) })
+}
+
+func writeEntries(w io.Writer, entries []models.LogEntry) error {
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	for i := range entries {
+		if err := encoder.Encode(&entries[i]); err != nil {
+			return fmt.Errorf("failed to write entry: %w", err)
+		}
+	}
+	return buf.Flush()
+}
+
+// runReader is the next entry of an open run
+type runReader struct {
+	decoder *json.Decoder
+	next    models.LogEntry
+}
+
+// runHeap orders open runs by their next entry
+type runHeap []*runReader
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return less(&h[i].next, &h[j].next) }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*runReader)) }
+func (h *runHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
+}
+
+// mergeRuns writes the entries of the sorted run files to w in order. The
+// runs are encrypted with c if it is set.
+func mergeRuns(paths []string, w io.Writer, c *crypt.Cipher) error {
+	h := make(runHeap, 0, len(paths))
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open sort run: %w", err)
+		}
+		defer file.Close()
+
+		r := &runReader{decoder: json.NewDecoder(c.NewReader(file))}
+		if err := r.decoder.Decode(&r.next); err == io.EOF {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to read sort run: %w", err)
+		}
+		h = append(h, r)
+	}
+	heap.Init(&h)
+
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	for h.Len() > 0 {
+		r := h[0]
+		if err := encoder.Encode(&r.next); err != nil {
+			return fmt.Errorf("failed to write entry: %w", err)
+		}
+		r.next = models.LogEntry{}
+		if err := r.decoder.Decode(&r.next); err == io.EOF {
+			heap.Pop(&h)
+		} else if err != nil {
+			return fmt.Errorf("failed to read sort run: %w", err)
+		} else {
+			heap.Fix(&h, 0)
+		}
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,88 @@
+package mergesort
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/crypt"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func sortAndRead(t *testing.T, runSize, n int, c *crypt.Cipher) []models.LogEntry {
+	t.Helper()
+	var out bytes.Buffer
+	s := NewSorter(&out, runSize, WithCipher(c))
+
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(1))
+	for _, i := range rng.Perm(n) {
+		entry := models.LogEntry{
+			ID: fmt.Sprintf("e%d", i),
+			// Pairs of entries share a timestamp
+			Timestamp: start.Add(time.Duration(i/2) * time.Second),
+			Source:    fmt.Sprintf("file%d.json", i%2),
+		}
+		if err := s.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sorter: %v", err)
+	}
+
+	if c != nil && !crypt.Streamed(out.Bytes()) {
+		t.Fatalf("Expected encrypted output")
+	}
+	var entries []models.LogEntry
+	decoder := json.NewDecoder(c.NewReader(&out))
+	for decoder.More() {
+		var entry models.LogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatalf("Invalid output: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSorterOrdersEntries(t *testing.T) {
+	key, err := crypt.ParseKey(strings.Repeat("0f", 32))
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+	tests := []struct {
+		name    string
+		runSize int
+		n       int
+		cipher  *crypt.Cipher
+	}{
+		{"in memory", 1000, 100, nil},
+		{"spilled runs", 7, 100, nil},
+		{"multi-pass merge", 1, maxOpenRuns*2 + 5, nil},
+		{"encrypted in memory", 1000, 100, key},
+		{"encrypted multi-pass merge", 1, maxOpenRuns*2 + 5, key},
+	}
+
+	for _, tt := range tests {
+		entries := sortAndRead(t, tt.runSize, tt.n, tt.cipher)
+		if len(entries) != tt.n {
+			t.Errorf("%s: expected %d entries, got %d", tt.name, tt.n, len(entries))
+			continue
+		}
+		for i := 1; i < len(entries); i++ {
+			if less(&entries[i], &entries[i-1]) {
+				t.Errorf("%s: entry %d (%s) is out of order", tt.name, i, entries[i].ID)
+				break
+			}
+		}
+		// Equal timestamps are ordered by source
+		if entries[0].ID != "e0" || entries[1].ID != "e1" {
+			t.Errorf("%s: expected ties ordered by source, got %s, %s", tt.name, entries[0].ID, entries[1].ID)
+		}
+	}
+}
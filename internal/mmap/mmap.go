@@ -0,0 +1,14 @@
+// Package mmap maps files into memory for reading on platforms that
+// support it.
+package mmap
+
+// Mapping is a read-only view of a file's contents
+type Mapping struct {
+	data []byte
+}
+
+// Bytes returns the contents of the file. They are only valid until Close
+// and must not be modified.
+func (m *Mapping) Bytes() []byte {
+	return m.data
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package mmap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Open reports that memory mapping is not supported on this platform
+func Open(path string) (*Mapping, error) {
+	return nil, fmt.Errorf("failed to map %s: %w", path, errors.ErrUnsupported)
+}
+
+// Close does nothing
+func (m *Mapping) Close() error {
+	return nil
+}
@@ -0,0 +1,46 @@
+package mmap
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("memory mapping is not supported")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs.json")
+	content := `{"id":"1","message":"mapped"}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	m, err := Open(path)
+	if err != nil {
+		t.Fatalf("Failed to map file: %v", err)
+	}
+	if string(m.Bytes()) != content {
+		t.Errorf("Expected mapped contents %q, got %q", content, m.Bytes())
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Failed to unmap file: %v", err)
+	}
+
+	empty := filepath.Join(dir, "empty.json")
+	os.WriteFile(empty, nil, 0644)
+	m, err = Open(empty)
+	if err != nil {
+		t.Fatalf("Failed to map empty file: %v", err)
+	}
+	if len(m.Bytes()) != 0 {
+		t.Errorf("Expected no contents, got %d bytes", len(m.Bytes()))
+	}
+	m.Close()
+
+	if _, err := Open(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
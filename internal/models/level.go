@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// levels holds the registered levels with their severity and the aliases
+// that name them, upper-cased
+var levels = struct {
+	sync.RWMutex
+	severity map[LogLevel]int
+	aliases  map[string]LogLevel
+}{
+	severity: map[LogLevel]int{DEBUG: 10, INFO: 20, WARNING: 30, ERROR: 40, FATAL: 50},
+	aliases:  map[string]LogLevel{"WARN": WARNING, "ERR": ERROR},
+}
+
+// RegisterLevel adds a level beyond the standard ones, or changes the
+// severity of a registered one. Severities order the levels for threshold
+// comparisons: the standard levels are DEBUG 10, INFO 20, WARNING 30, ERROR
+// 40 and FATAL 50, so e.g. TRACE 5, NOTICE 25 and CRITICAL 45 fit in
+// between. Aliases are other names of the level, such as CRIT.
+func RegisterLevel(level LogLevel, severity int, aliases ...string) error {
+	name := strings.ToUpper(strings.TrimSpace(string(level)))
+	if name == "" {
+		return fmt.Errorf("level name must not be empty")
+	}
+	level = LogLevel(name)
+
+	levels.Lock()
+	defer levels.Unlock()
+	if target, ok := levels.aliases[name]; ok && target != level {
+		return fmt.Errorf("level %s is already an alias of %s", name, target)
+	}
+	for _, alias := range aliases {
+		alias = strings.ToUpper(strings.TrimSpace(alias))
+		if _, ok := levels.severity[LogLevel(alias)]; ok && LogLevel(alias) != level {
+			return fmt.Errorf("alias %s of level %s is a level itself", alias, name)
+		}
+		if target, ok := levels.aliases[alias]; ok && target != level {
+			return fmt.Errorf("alias %s of level %s is already an alias of %s", alias, name, target)
+		}
+	}
+	levels.severity[level] = severity
+	for _, alias := range aliases {
+		levels.aliases[strings.ToUpper(strings.TrimSpace(alias))] = level
+	}
+	return nil
+}
+
+// LevelSeverity returns the severity of a registered level
+func LevelSeverity(level LogLevel) (int, bool) {
+	levels.RLock()
+	defer levels.RUnlock()
+	severity, ok := levels.severity[level]
+	return severity, ok
+}
+
+// ParseLevel returns the registered level named by s, or by one of its
+// aliases, in any case
+func ParseLevel(s string) (LogLevel, bool) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	levels.RLock()
+	defer levels.RUnlock()
+	if _, ok := levels.severity[LogLevel(name)]; ok {
+		return LogLevel(name), true
+	}
+	level, ok := levels.aliases[name]
+	return level, ok
+}
+
+// Levels returns the registered levels from least to most severe
+func Levels() []LogLevel {
+	levels.RLock()
+	defer levels.RUnlock()
+	ordered := make([]LogLevel, 0, len(levels.severity))
+	for level := range levels.severity {
+		ordered = append(ordered, level)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		si, sj := levels.severity[ordered[i]], levels.severity[ordered[j]]
+		if si != sj {
+			return si < sj
+		}
+		return ordered[i] < ordered[j]
+	})
+	return ordered
+}
+
+// Severity returns the severity of the level, resolving aliases and case,
+// or 0 if it is not registered
+func (l LogLevel) Severity() int {
+	level, ok := ParseLevel(string(l))
+	if !ok {
+		return 0
+	}
+	severity, _ := LevelSeverity(level)
+	return severity
+}
+
+// Compare orders two levels by severity, returning -1 if l is less severe
+// than other, 0 if they are equally severe and +1 if l is more severe.
+// Unregistered levels are less severe than any registered one.
+func (l LogLevel) Compare(other LogLevel) int {
+	a, b := l.Severity(), other.Severity()
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegisterLevel(t *testing.T) {
+	if err := RegisterLevel("notice", 25); err != nil {
+		t.Fatalf("Failed to register level: %v", err)
+	}
+	if err := RegisterLevel("CRITICAL", 45, "crit"); err != nil {
+		t.Fatalf("Failed to register level: %v", err)
+	}
+
+	for input, want := range map[string]LogLevel{"Notice": "NOTICE", "crit": "CRITICAL", "warn": WARNING, "error": ERROR} {
+		if level, ok := ParseLevel(input); !ok || level != want {
+			t.Errorf("Expected %q to parse as %s, got %q (%v)", input, want, level, ok)
+		}
+	}
+	if _, ok := ParseLevel("verbose"); ok {
+		t.Error("Expected an unregistered level not to parse")
+	}
+	if severity, ok := LevelSeverity("CRITICAL"); !ok || severity != 45 {
+		t.Errorf("Expected CRITICAL to have severity 45, got %d", severity)
+	}
+
+	want := []LogLevel{DEBUG, INFO, "NOTICE", WARNING, ERROR, "CRITICAL", FATAL}
+	if got := Levels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected levels %v, got %v", want, got)
+	}
+
+	if err := RegisterLevel("SEVERE", 45, "warn"); err == nil {
+		t.Error("Expected an error for an alias of another level")
+	}
+	if err := RegisterLevel("ERR", 40); err == nil {
+		t.Error("Expected an error for a level named like an alias")
+	}
+	if err := RegisterLevel(" ", 1); err == nil {
+		t.Error("Expected an error for an empty level name")
+	}
+}
+
+func TestLevelCompare(t *testing.T) {
+	if WARNING.Severity() != 30 || LogLevel("warn").Severity() != 30 {
+		t.Errorf("Expected WARNING and warn to have severity 30, got %d and %d", WARNING.Severity(), LogLevel("warn").Severity())
+	}
+	if LogLevel("VERBOSE").Severity() != 0 {
+		t.Errorf("Expected an unregistered level to have severity 0, got %d", LogLevel("VERBOSE").Severity())
+	}
+	tests := []struct {
+		a, b LogLevel
+		want int
+	}{
+		{ERROR, WARNING, 1},
+		{DEBUG, INFO, -1},
+		{"err", ERROR, 0},
+		{"VERBOSE", DEBUG, -1},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("Expected %s.Compare(%s) to be %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -24,6 +25,29 @@ type LogEntry struct {
 	Service   string    `json:"service"`
 	Message   string    `json:"message"`
 	Source    string    `json:"source"`
+	// Fields holds format-specific attributes such as HTTP status codes
+	Fields map[string]string `json:"fields,omitempty"`
+	// LevelInferred is set when Level was derived from the message because
+	// the entry had none
+	LevelInferred bool `json:"level_inferred,omitempty"`
+	// Tenant is the tenant that sent the entry in serve mode. It is set by
+	// the authenticated input only, never decoded from the entry itself.
+	Tenant string `json:"-"`
+}
+
+// UnmarshalJSON decodes an entry, accepting epoch timestamps as well as
+// RFC 3339 ones (see Timestamp)
+func (l *LogEntry) UnmarshalJSON(data []byte) error {
+	type fields LogEntry
+	raw := struct {
+		*fields
+		Timestamp Timestamp `json:"timestamp"`
+	}{fields: (*fields)(l), Timestamp: Timestamp(l.Timestamp)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	l.Timestamp = time.Time(raw.Timestamp)
+	return nil
 }
 
 // String returns a string representation of a LogEntry
@@ -38,13 +62,134 @@ func (l LogEntry) String() string {
 
 // LogSummary contains aggregated statistics for log entries
 type LogSummary struct {
-	TotalEntries int
-	ByLevel      map[LogLevel]int
-	ByService    map[string]int
+	TotalEntries int              `json:"total_entries"`
+	ByLevel      map[LogLevel]int `json:"by_level"`
+	ByService    map[string]int   `json:"by_service"`
 	TimeRange    struct {
-		Start time.Time
-		End   time.Time
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	} `json:"time_range"`
+	// InferredLevels counts entries whose level was derived from the message
+	InferredLevels int `json:"inferred_levels,omitempty"`
+	// OutOfOrder holds ordering statistics for sources with entries that
+	// were read after a later entry of the same source
+	OutOfOrder map[string]OrderStats `json:"out_of_order,omitempty"`
+	// FailedFiles maps input files that could not be processed, e.g.
+	// because reading them timed out, to the reason
+	FailedFiles map[string]string `json:"failed_files,omitempty"`
+	// CorruptArchives maps truncated or corrupt compressed input files to
+	// the damage found. Entries before the damage are counted.
+	CorruptArchives map[string]string `json:"corrupt_archives,omitempty"`
+	// ByHour counts entries by level for each hour, keyed by the start of
+	// the hour in UTC. Entries without a timestamp are not included.
+	ByHour map[time.Time]map[LogLevel]int `json:"by_hour,omitempty"`
+	// Counters holds the matches of configured message counters by name
+	Counters map[string]CounterCounts `json:"counters,omitempty"`
+	// Accounting reconciles the entries read with TotalEntries
+	Accounting *Accounting `json:"accounting,omitempty"`
+	// Privacy describes how the counts were anonymized for sharing, if they
+	// were
+	Privacy *Privacy `json:"privacy,omitempty"`
+}
+
+// Privacy describes the anonymization of a summary's counts
+type Privacy struct {
+	// MinCount is the fewest entries a reported group stands for
+	MinCount int `json:"min_count,omitempty"`
+	// Epsilon is the privacy parameter of the noise added to counts, 0 if
+	// none was
+	Epsilon float64 `json:"epsilon,omitempty"`
+	// Suppressed counts the groups left out for having too few entries
+	Suppressed int `json:"suppressed_groups"`
+}
+
+// Accounting tracks what became of every entry read from the input. Once
+// processing is finished each entry read is in exactly one of the other
+// counts, and Analyzed equals the entries added to TotalEntries.
+type Accounting struct {
+	// Read counts the entries parsed from input files or received over the
+	// network
+	Read int `json:"read"`
+	// Skipped counts entries already delivered in an earlier run according
+	// to a checkpoint
+	Skipped int `json:"skipped,omitempty"`
+	// Filtered counts entries removed by filters such as a time window
+	Filtered int `json:"filtered"`
+	// Duplicates counts entries with an ID that was already analyzed
+	Duplicates int `json:"duplicates"`
+	// HashMatches counts the Duplicates recognized only by the hash of an ID
+	// restored from a saved state, which may rarely be distinct entries
+	HashMatches int `json:"hash_matches,omitempty"`
+	// Dropped counts entries lost to errors or shutdown, by reason
+	Dropped map[string]int `json:"dropped,omitempty"`
+	// Analyzed counts entries added to the summary
+	Analyzed int `json:"analyzed"`
+}
+
+// TotalDropped returns the number of dropped entries over all reasons
+func (a Accounting) TotalDropped() int {
+	n := 0
+	for _, count := range a.Dropped {
+		n += count
 	}
+	return n
+}
+
+// Unaccounted returns the entries read that are in none of the other
+// counts, such as entries still being processed. It is zero when the
+// accounting balances.
+func (a Accounting) Unaccounted() int {
+	return a.Read - a.Skipped - a.Filtered - a.Duplicates - a.TotalDropped() - a.Analyzed
+}
+
+// Add combines the accounting of two runs
+func (a Accounting) Add(other Accounting) Accounting {
+	dropped := make(map[string]int, len(a.Dropped)+len(other.Dropped))
+	for reason, count := range a.Dropped {
+		dropped[reason] += count
+	}
+	for reason, count := range other.Dropped {
+		dropped[reason] += count
+	}
+	if len(dropped) == 0 {
+		dropped = nil
+	}
+	return Accounting{
+		Read:        a.Read + other.Read,
+		Skipped:     a.Skipped + other.Skipped,
+		Filtered:    a.Filtered + other.Filtered,
+		Duplicates:  a.Duplicates + other.Duplicates,
+		HashMatches: a.HashMatches + other.HashMatches,
+		Dropped:     dropped,
+		Analyzed:    a.Analyzed + other.Analyzed,
+	}
+}
+
+// CounterCounts holds the matches of one message counter
+type CounterCounts struct {
+	// Label names the label of a labeled counter
+	Label string `json:"label,omitempty"`
+	// Counts maps label values to matches. Unlabeled counters use the empty
+	// value.
+	Counts map[string]int `json:"counts"`
+}
+
+// OrderStats describes the out-of-order entries of one source
+type OrderStats struct {
+	// Count is the number of entries older than an entry read before them
+	Count int `json:"count"`
+	// MaxDisplacement is the largest gap between such an entry and the
+	// latest timestamp read before it
+	MaxDisplacement time.Duration `json:"max_displacement"`
+}
+
+// Add combines two sets of statistics
+func (o OrderStats) Add(other OrderStats) OrderStats {
+	o.Count += other.Count
+	if other.MaxDisplacement > o.MaxDisplacement {
+		o.MaxDisplacement = other.MaxDisplacement
+	}
+	return o
 }
 
 // NewLogSummary creates a new initialized LogSummary
@@ -53,4 +198,39 @@ func NewLogSummary() *LogSummary {
 		ByLevel:   make(map[LogLevel]int),
 		ByService: make(map[string]int),
 	}
-}
\ No newline at end of file
+}
+
+// AddHour adds count entries of level to the hour containing t
+func (s *LogSummary) AddHour(t time.Time, level LogLevel, count int) {
+	hour := t.UTC().Truncate(time.Hour)
+	if s.ByHour == nil {
+		s.ByHour = make(map[time.Time]map[LogLevel]int)
+	}
+	levels, ok := s.ByHour[hour]
+	if !ok {
+		levels = make(map[LogLevel]int)
+		s.ByHour[hour] = levels
+	}
+	levels[level] += count
+}
+
+// AddCounter adds count matches with the given label value to a counter
+func (s *LogSummary) AddCounter(name, label, value string, count int) {
+	if s.Counters == nil {
+		s.Counters = make(map[string]CounterCounts)
+	}
+	counter, ok := s.Counters[name]
+	if !ok {
+		counter = CounterCounts{Label: label, Counts: make(map[string]int)}
+		s.Counters[name] = counter
+	}
+	counter.Counts[value] += count
+}
+
+// PeriodSummary is the summary of the entries of one hour or day
+type PeriodSummary struct {
+	// Period names the hour or day, e.g. 2023-01-01T10 or 2023-01-01
+	Period  string      `json:"period"`
+	Start   time.Time   `json:"start"`
+	Summary *LogSummary `json:"summary"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamp is a time decoded from JSON either as an RFC 3339 string or as
+// the time since the Unix epoch in seconds, milliseconds, microseconds or
+// nanoseconds, given as a number or a string. It encodes as RFC 3339.
+type Timestamp time.Time
+
+// UnmarshalJSON decodes an RFC 3339 or epoch timestamp. null and the empty
+// string leave the time zero.
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		if text == "" {
+			*t = Timestamp{}
+			return nil
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			*t = Timestamp(ts)
+			return nil
+		}
+	}
+	ts, ok := ParseEpoch(text)
+	if !ok {
+		return fmt.Errorf("invalid timestamp %s, expected RFC 3339 or a Unix epoch", data)
+	}
+	*t = Timestamp(ts)
+	return nil
+}
+
+// MarshalJSON encodes the time as RFC 3339
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return time.Time(t).MarshalJSON()
+}
+
+// ParseEpoch parses s as the time since the Unix epoch, e.g. 1672567200,
+// 1672567200.5 or 1672567200123. The unit follows from the magnitude:
+// values below 1e11 are seconds (up to the year 5138), below 1e14
+// milliseconds, below 1e17 microseconds and larger ones nanoseconds.
+// Fractions are kept to the nanosecond.
+func ParseEpoch(s string) (time.Time, bool) {
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return time.Time{}, false
+		}
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	negative := strings.HasPrefix(s, "-")
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if whole == "" || !isDigits(whole) || !isDigits(frac) {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// perSecond is the number of units in a second
+	var perSecond int64
+	switch {
+	case n < 1e11:
+		perSecond = 1
+	case n < 1e14:
+		perSecond = 1e3
+	case n < 1e17:
+		perSecond = 1e6
+	default:
+		perSecond = 1e9
+	}
+	nanosPerUnit := int64(time.Second) / perSecond
+	// The fraction is of a unit, with as many digits as it has nanoseconds
+	digits := len(strconv.FormatInt(nanosPerUnit, 10)) - 1
+	frac = (frac + strings.Repeat("0", digits))[:digits]
+	var sub int64
+	if frac != "" {
+		sub, _ = strconv.ParseInt(frac, 10, 64)
+	}
+
+	sec, nsec := n/perSecond, (n%perSecond)*nanosPerUnit+sub
+	if negative {
+		sec, nsec = -sec, -nsec
+	}
+	return time.Unix(sec, nsec).UTC(), true
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	want := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		`"2023-01-01T10:00:00Z"`:           want,
+		`"2023-01-01T11:00:00+01:00"`:      want,
+		`1672567200`:                       want,
+		`"1672567200"`:                     want,
+		`1672567200.25`:                    want.Add(250 * time.Millisecond),
+		`1672567200123`:                    want.Add(123 * time.Millisecond),
+		`"1672567200123.5"`:                want.Add(123500 * time.Microsecond),
+		`1672567200123456`:                 want.Add(123456 * time.Microsecond),
+		`1672567200123456789`:              want.Add(123456789),
+		`"1672567200123456789"`:            want.Add(123456789),
+		`1.6725672e9`:                      want,
+		`"2023-01-01T10:00:00.123456789Z"`: want.Add(123456789),
+		`null`:                             {},
+		`""`:                               {},
+	}
+	for input, expected := range tests {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(input), &ts); err != nil {
+			t.Errorf("Failed to decode %s: %v", input, err)
+			continue
+		}
+		if !time.Time(ts).Equal(expected) {
+			t.Errorf("Expected %s to decode as %v, got %v", input, expected, time.Time(ts))
+		}
+	}
+
+	for _, input := range []string{`"yesterday"`, `"12:00"`, `true`, `"1e400"`, `"-"`, `99999999999999999999`} {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(input), &ts); err == nil {
+			t.Errorf("Expected an error for %s, got %v", input, time.Time(ts))
+		}
+	}
+}
+
+func TestLogEntryUnmarshalJSON(t *testing.T) {
+	var entry LogEntry
+	if err := json.Unmarshal([]byte(`{"id":"1","timestamp":1672567200000,"level":"ERROR","service":"api","message":"boom","fields":{"a":"b"}}`), &entry); err != nil {
+		t.Fatalf("Failed to decode entry: %v", err)
+	}
+	if !entry.Timestamp.Equal(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)) || entry.Level != ERROR || entry.Fields["a"] != "b" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	// Encoding and decoding keep the entry
+	encoded, _ := json.Marshal(entry)
+	var decoded LogEntry
+	if err := json.Unmarshal(encoded, &decoded); err != nil || !decoded.Timestamp.Equal(entry.Timestamp) || decoded.Message != "boom" {
+		t.Errorf("Unexpected round trip: %+v, %v", decoded, err)
+	}
+}
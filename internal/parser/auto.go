@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// sniffSize is how much of a file is read by default to detect its format
+const sniffSize = 64 * 1024
+
+// sniffLines is the number of lines examined to tell the text formats apart
+const sniffLines = 50
+
+// jsonArray is the detected format of files holding one JSON array
+const jsonArray = "json-array"
+
+// AutoParser detects the format of every input from its first bytes:
+// newline-delimited JSON, a JSON array, entries wrapped in an envelope,
+// logfmt, CSV with a header row or plain text. Files of different formats can thus be read together.
+type AutoParser struct {
+	// JSON parses the JSON inputs
+	JSON JSONParser
+	// SniffSize is the number of bytes examined, 64 KB if zero
+	SniffSize int
+}
+
+// Parse detects the format of r and parses it accordingly
+func (p AutoParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	br := bufio.NewReaderSize(r, p.sniffSize())
+	head, err := br.Peek(p.sniffSize())
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return p.parserFor(head).Parse(br, emit)
+}
+
+// ParseChunks parses newline-delimited JSON in parallel chunks and every
+// other format sequentially
+func (p AutoParser) ParseChunks(r io.ReaderAt, size int64, chunks int, emit func(models.LogEntry) error) error {
+	head := make([]byte, min(size, int64(p.sniffSize())))
+	if n, err := r.ReadAt(head, 0); err != nil && !(err == io.EOF && n == len(head)) {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	parser := p.parserFor(head)
+	if cp, ok := parser.(ChunkParser); ok {
+		return cp.ParseChunks(r, size, chunks, emit)
+	}
+	return parser.Parse(io.NewSectionReader(r, 0, size), emit)
+}
+
+func (p AutoParser) sniffSize() int {
+	if p.SniffSize > 0 {
+		return p.SniffSize
+	}
+	return sniffSize
+}
+
+// parserFor returns the parser for an input starting with head. JSON
+// documents are searched for the configured envelope of p.JSON and the
+// well-known ones.
+func (p AutoParser) parserFor(head []byte) Parser {
+	switch detect(head) {
+	case "json":
+		json := p.JSON
+		json.Envelope = detectEnvelope(head, p.JSON.Envelope)
+		return json
+	case jsonArray:
+		json := p.JSON
+		json.Envelope = nil
+		return json
+	case "logfmt":
+		return LogfmtParser{}
+	case "csv":
+		return CSVParser{}
+	}
+	return TextParser{}
+}
+
+// detect returns the format of an input starting with head: "json",
+// jsonArray, "logfmt", "csv" or "text"
+func detect(head []byte) string {
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\ufeff")), " \t\r\n")
+	switch {
+	case len(head) == 0 || head[0] == '{':
+		return "json"
+	case head[0] == '[':
+		return jsonArray
+	}
+
+	lines := strings.Split(string(head), "\n")
+	if len(lines) > 1 && !bytes.HasSuffix(head, []byte("\n")) {
+		// The last line may be cut off
+		lines = lines[:len(lines)-1]
+	}
+	sample := make([]string, 0, sniffLines)
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			sample = append(sample, line)
+		}
+		if len(sample) == sniffLines {
+			break
+		}
+	}
+
+	switch {
+	case isLogfmt(sample):
+		return "logfmt"
+	case isCSV(sample):
+		return "csv"
+	}
+	return "text"
+}
+
+// isLogfmt reports whether most lines consist mostly of key=value pairs
+func isLogfmt(lines []string) bool {
+	matches := 0
+	for _, line := range lines {
+		names, values, err := splitLogfmt(line)
+		if err != nil {
+			continue
+		}
+		pairs := 0
+		for i, name := range names {
+			if values[i] != "" && logfmtKey(name) {
+				pairs++
+			}
+		}
+		if pairs >= 2 && 2*pairs >= len(names) {
+			matches++
+		}
+	}
+	return len(lines) > 0 && 5*matches >= 4*len(lines)
+}
+
+// logfmtKey reports whether name can be a logfmt key
+func logfmtKey(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("_.-/@", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCSV reports whether the lines are rows of as many columns as the first,
+// which names at least one entry attribute
+func isCSV(lines []string) bool {
+	if len(lines) == 0 {
+		return false
+	}
+	reader := csv.NewReader(strings.NewReader(strings.Join(lines, "\n")))
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil || len(rows[0]) < 2 {
+		return false
+	}
+	for _, name := range rows[0] {
+		if _, ok := fieldNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,322 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// ALBParser parses Application and Classic Load Balancer access logs
+type ALBParser struct{}
+
+// albRequestTypes are the values of the leading type field in ALB logs.
+// Classic ELB logs have no type field and start with the timestamp.
+var albRequestTypes = map[string]bool{
+	"http": true, "https": true, "h2": true, "grpcs": true, "ws": true, "wss": true,
+}
+
+// albFields names the fields that follow the type field in ALB logs
+var albFields = []string{
+	"time", "elb", "client", "target",
+	"request_processing_time", "target_processing_time", "response_processing_time",
+	"elb_status_code", "target_status_code", "received_bytes", "sent_bytes",
+	"request", "user_agent", "ssl_cipher", "ssl_protocol", "target_group_arn",
+	"trace_id", "domain_name", "chosen_cert_arn", "matched_rule_priority",
+	"request_creation_time", "actions_executed", "redirect_url", "error_reason",
+	"target_port_list", "target_status_code_list", "classification", "classification_reason",
+}
+
+// elbFields names the fields of Classic Load Balancer logs
+var elbFields = []string{
+	"time", "elb", "client", "target",
+	"request_processing_time", "target_processing_time", "response_processing_time",
+	"elb_status_code", "target_status_code", "received_bytes", "sent_bytes",
+	"request", "user_agent", "ssl_cipher", "ssl_protocol",
+}
+
+// Parse reads one access log record per line
+func (ALBParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseALBLine(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func parseALBLine(line string) (models.LogEntry, error) {
+	tokens, err := splitQuoted(line)
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+
+	names := elbFields
+	fields := make(map[string]string)
+	if len(tokens) > 0 && albRequestTypes[tokens[0]] {
+		fields["type"] = tokens[0]
+		tokens = tokens[1:]
+		names = albFields
+	}
+	if len(tokens) < len(elbFields) {
+		return models.LogEntry{}, fmt.Errorf("expected at least %d fields, got %d", len(elbFields), len(tokens))
+	}
+	for i, name := range names {
+		if i >= len(tokens) {
+			break
+		}
+		if tokens[i] != "-" && tokens[i] != "" {
+			fields[name] = tokens[i]
+		}
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, fields["time"])
+	if err != nil {
+		return models.LogEntry{}, fmt.Errorf("invalid timestamp %q: %w", fields["time"], err)
+	}
+
+	// Split the request line into method, URL and protocol
+	request := fields["request"]
+	if parts := strings.SplitN(request, " ", 3); len(parts) == 3 {
+		fields["request_method"] = parts[0]
+		fields["request_url"] = parts[1]
+		fields["request_protocol"] = parts[2]
+	}
+	if client, _, ok := strings.Cut(fields["client"], ":"); ok {
+		fields["client_ip"] = client
+	}
+
+	entry := models.LogEntry{
+		Timestamp: ts,
+		Level:     levelForStatus(fields["elb_status_code"]),
+		Service:   fields["elb"],
+		Message:   request,
+		Fields:    fields,
+	}
+	delete(fields, "time")
+	delete(fields, "elb")
+	delete(fields, "request")
+	return entry, nil
+}
+
+// levelForStatus derives a level from an HTTP status code. A missing status
+// means the load balancer never produced a response.
+func levelForStatus(status string) models.LogLevel {
+	switch {
+	case status == "" || strings.HasPrefix(status, "5"):
+		return models.ERROR
+	case strings.HasPrefix(status, "4"):
+		return models.WARNING
+	default:
+		return models.INFO
+	}
+}
+
+// splitQuoted splits a line on spaces, keeping double-quoted sections together
+func splitQuoted(line string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+	quoted := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case c == ' ' && !inQuotes:
+			if current.Len() > 0 || quoted {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				quoted = false
+			}
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quoted field")
+	}
+	if current.Len() > 0 || quoted {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+// CloudTrailParser parses CloudTrail log files, which wrap events in a
+// top-level Records array
+type CloudTrailParser struct{}
+
+// cloudTrailRecord holds the CloudTrail event attributes mapped onto entries
+type cloudTrailRecord struct {
+	EventID      string    `json:"eventID"`
+	EventTime    time.Time `json:"eventTime"`
+	EventSource  string    `json:"eventSource"`
+	EventName    string    `json:"eventName"`
+	EventType    string    `json:"eventType"`
+	AWSRegion    string    `json:"awsRegion"`
+	SourceIP     string    `json:"sourceIPAddress"`
+	UserAgent    string    `json:"userAgent"`
+	ErrorCode    string    `json:"errorCode"`
+	ErrorMessage string    `json:"errorMessage"`
+	RequestID    string    `json:"requestID"`
+	ReadOnly     *bool     `json:"readOnly"`
+	UserIdentity struct {
+		Type      string `json:"type"`
+		ARN       string `json:"arn"`
+		AccountID string `json:"accountId"`
+		UserName  string `json:"userName"`
+	} `json:"userIdentity"`
+}
+
+// Parse streams the Records array so large files are not held in memory
+func (CloudTrailParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	decoder := json.NewDecoder(r)
+
+	for {
+		if err := seekRecords(decoder); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		for decoder.More() {
+			var record cloudTrailRecord
+			if err := decoder.Decode(&record); err != nil {
+				return fmt.Errorf("failed to decode CloudTrail record: %w", err)
+			}
+			if err := emit(record.toEntry()); err != nil {
+				return err
+			}
+		}
+
+		// Consume the closing bracket of Records and the rest of the object
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("failed to read CloudTrail file: %w", err)
+		}
+		if err := skipObject(decoder); err != nil {
+			return err
+		}
+	}
+}
+
+// seekRecords advances the decoder to the start of the next Records array
+func seekRecords(decoder *json.Decoder) error {
+	tok, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected a CloudTrail object, got %v", tok)
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("failed to read CloudTrail file: %w", err)
+		}
+		if key == "Records" {
+			tok, err := decoder.Token()
+			if err != nil {
+				return fmt.Errorf("failed to read CloudTrail file: %w", err)
+			}
+			if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+				return fmt.Errorf("expected Records to be an array")
+			}
+			return nil
+		}
+		// Skip the value of any other key
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return fmt.Errorf("failed to read CloudTrail file: %w", err)
+		}
+	}
+	return fmt.Errorf("CloudTrail object has no Records array")
+}
+
+// skipObject consumes the remaining keys of the current object
+func skipObject(decoder *json.Decoder) error {
+	for decoder.More() {
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("failed to read CloudTrail file: %w", err)
+		}
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return fmt.Errorf("failed to read CloudTrail file: %w", err)
+		}
+	}
+	_, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read CloudTrail file: %w", err)
+	}
+	return nil
+}
+
+func (r cloudTrailRecord) toEntry() models.LogEntry {
+	level := models.INFO
+	if r.ErrorCode != "" {
+		level = models.ERROR
+	}
+
+	message := r.EventName
+	if r.ErrorMessage != "" {
+		message = fmt.Sprintf("%s: %s", r.EventName, r.ErrorMessage)
+	} else if r.ErrorCode != "" {
+		message = fmt.Sprintf("%s: %s", r.EventName, r.ErrorCode)
+	}
+
+	fields := map[string]string{
+		"event_name":   r.EventName,
+		"event_type":   r.EventType,
+		"aws_region":   r.AWSRegion,
+		"source_ip":    r.SourceIP,
+		"user_agent":   r.UserAgent,
+		"user_type":    r.UserIdentity.Type,
+		"user_arn":     r.UserIdentity.ARN,
+		"user_name":    r.UserIdentity.UserName,
+		"account_id":   r.UserIdentity.AccountID,
+		"error_code":   r.ErrorCode,
+		"request_id":   r.RequestID,
+		"event_source": r.EventSource,
+	}
+	if r.ReadOnly != nil {
+		fields["read_only"] = fmt.Sprint(*r.ReadOnly)
+	}
+	for k, v := range fields {
+		if v == "" {
+			delete(fields, k)
+		}
+	}
+
+	return models.LogEntry{
+		ID:        r.EventID,
+		Timestamp: r.EventTime,
+		Level:     level,
+		Service:   strings.TrimSuffix(r.EventSource, ".amazonaws.com"),
+		Message:   message,
+		Fields:    fields,
+	}
+}
@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// ChunkParser is implemented by parsers that can decode byte ranges of a file
+// in parallel, e.g. because every entry ends at a newline
+type ChunkParser interface {
+	// ParseChunks decodes the first size bytes of r in up to chunks parallel
+	// parts, calling emit for every entry in file order
+	ParseChunks(r io.ReaderAt, size int64, chunks int, emit func(models.LogEntry) error) error
+}
+
+// ParseChunks splits the input at newlines into byte ranges decoded in
+// parallel. If any range fails to decode, e.g. because an object spans a
+// range boundary, the whole input is parsed again sequentially. Arrays and
+// envelopes are always parsed sequentially.
+func (p JSONParser) ParseChunks(r io.ReaderAt, size int64, chunks int, emit func(models.LogEntry) error) error {
+	if p.Envelope != nil || startsArray(bufio.NewReaderSize(io.NewSectionReader(r, 0, size), 512)) {
+		return p.Parse(io.NewSectionReader(r, 0, size), emit)
+	}
+	bounds, err := splitLines(r, size, chunks)
+	if err != nil {
+		return err
+	}
+
+	results := make([][]models.LogEntry, len(bounds)-1)
+	errs := make([]error, len(bounds)-1)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			section := io.NewSectionReader(r, bounds[i], bounds[i+1]-bounds[i])
+			errs[i] = p.Parse(section, func(entry models.LogEntry) error {
+				results[i] = append(results[i], entry)
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return p.Parse(io.NewSectionReader(r, 0, size), emit)
+		}
+	}
+	for _, entries := range results {
+		for _, entry := range entries {
+			if err := emit(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitLines returns the offsets dividing r into up to n ranges of similar
+// size, each ending just after a newline or at the end of the input
+func splitLines(r io.ReaderAt, size int64, n int) ([]int64, error) {
+	bounds := []int64{0}
+	buf := make([]byte, 64*1024)
+	for i := 1; i < n; i++ {
+		offset := size * int64(i) / int64(n)
+		if offset <= bounds[len(bounds)-1] {
+			continue
+		}
+		// Move the boundary past the next newline
+		for offset < size {
+			read, err := r.ReadAt(buf, offset)
+			if idx := bytes.IndexByte(buf[:read], '\n'); idx >= 0 {
+				offset += int64(idx) + 1
+				break
+			}
+			offset += int64(read)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to split input: %w", err)
+			}
+		}
+		if offset >= size {
+			break
+		}
+		bounds = append(bounds, offset)
+	}
+	return append(bounds, size), nil
+}
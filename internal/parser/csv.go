@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// CSVParser parses comma-separated values with a header row naming the
+// columns, e.g. timestamp,level,service,message
+type CSVParser struct{}
+
+// Parse reads one entry per row after the header
+func (CSVParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	names := make([]string, len(header))
+	for i, name := range header {
+		names[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV row: %w", err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		entry, err := entryFromFields(names, record)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Envelope locates the entries of a JSON document that wraps them, e.g.
+// the hits of an Elasticsearch export
+type Envelope struct {
+	// path holds the keys leading to the array of entries
+	path []string
+	// element holds the keys leading from each array element to its entry
+	element []string
+}
+
+// envelopes are the well-known wrappers recognized without configuration
+var envelopes = []Envelope{
+	{path: []string{"entries"}},
+	{path: []string{"hits", "hits"}, element: []string{"_source"}},
+}
+
+// ParseEnvelope parses a JSONPath-style path to the entries of a document,
+// e.g. "$.entries[*]" or "hits.hits[*]._source". Without "[*]" the path
+// names the array of entries itself.
+func ParseEnvelope(path string) (*Envelope, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(path), "$"), ".")
+	array, element, _ := strings.Cut(path, "[*]")
+	if strings.Contains(element, "[") || strings.Contains(array, "[") {
+		return nil, fmt.Errorf("envelope %q must select one array with [*]", path)
+	}
+	var env Envelope
+	var err error
+	if env.path, err = splitKeys(array); err != nil {
+		return nil, err
+	}
+	if env.element, err = splitKeys(strings.TrimPrefix(element, ".")); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+func splitKeys(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		if key == "" {
+			return nil, fmt.Errorf("envelope %q has an empty key", path)
+		}
+	}
+	return keys, nil
+}
+
+// String returns the envelope as a path such as "$.hits.hits[*]._source"
+func (e Envelope) String() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, key := range e.path {
+		b.WriteString("." + key)
+	}
+	b.WriteString("[*]")
+	for _, key := range e.element {
+		b.WriteString("." + key)
+	}
+	return b.String()
+}
+
+// seek advances the decoder to the first element of the array of entries
+func (e Envelope) seek(decoder *json.Decoder) error {
+	for _, key := range e.path {
+		if err := seekKey(decoder, key); err != nil {
+			return err
+		}
+	}
+	tok, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", e, err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected %s to be an array", e)
+	}
+	return nil
+}
+
+// seekKey advances the decoder, at the start of an object, to the value of
+// key
+func seekKey(decoder *json.Decoder, key string) error {
+	tok, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read envelope: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected an object holding %q, got %v", key, tok)
+	}
+	for decoder.More() {
+		name, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("failed to read envelope: %w", err)
+		}
+		if name == key {
+			return nil
+		}
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return fmt.Errorf("failed to read envelope: %w", err)
+		}
+	}
+	return fmt.Errorf("envelope has no %q", key)
+}
+
+// parseEnvelope decodes the entries the envelope locates in r. The rest of
+// the document after them is not read.
+func (p JSONParser) parseEnvelope(r io.Reader, env Envelope, emit func(models.LogEntry) error) error {
+	decoder := json.NewDecoder(r)
+	if err := env.seek(decoder); err != nil {
+		return err
+	}
+	for i := 0; decoder.More(); i++ {
+		unmarshal := decoder.Decode
+		if len(env.element) > 0 {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				return fmt.Errorf("failed to decode entry: %w", err)
+			}
+			for _, key := range env.element {
+				var object map[string]json.RawMessage
+				if err := json.Unmarshal(raw, &object); err != nil {
+					return fmt.Errorf("element %d of %s: %w", i, env, err)
+				}
+				var ok bool
+				if raw, ok = object[key]; !ok {
+					return fmt.Errorf("element %d of %s has no %q", i, env, key)
+				}
+			}
+			unmarshal = func(v any) error { return json.Unmarshal(raw, v) }
+		}
+		entry, err := p.decode(unmarshal)
+		if err != nil {
+			return fmt.Errorf("failed to decode entry: %w", err)
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detectEnvelope returns the first of candidates, followed by the
+// well-known envelopes, that an input starting with head is wrapped in. A
+// document that looks like an entry itself is not an envelope.
+func detectEnvelope(head []byte, candidates ...*Envelope) *Envelope {
+	for _, key := range topLevelKeys(head) {
+		if fieldNames[strings.ToLower(key)] != "" {
+			return nil
+		}
+	}
+	for i := range envelopes {
+		candidates = append(candidates, &envelopes[i])
+	}
+	for _, env := range candidates {
+		if env != nil && env.seek(json.NewDecoder(bytes.NewReader(head))) == nil {
+			return env
+		}
+	}
+	return nil
+}
+
+// topLevelKeys returns the keys of the object starting head, as far as head
+// reaches
+func topLevelKeys(head []byte) []string {
+	decoder := json.NewDecoder(bytes.NewReader(head))
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		keys = append(keys, key.(string))
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			break
+		}
+	}
+	return keys
+}
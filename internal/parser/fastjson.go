@@ -0,0 +1,271 @@
+package parser
+
+import (
+	"bufio"
+	"sync"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// readerPool reuses the read buffers of JSONParser across files
+var readerPool = sync.Pool{
+	New: func() any { return bufio.NewReaderSize(nil, 64*1024) },
+}
+
+// entryPool holds the LogEntry structs encoding/json decodes into, which
+// would otherwise escape to the heap for every entry. Entries are emitted as
+// copies, so a struct is zeroed and returned as soon as it is copied.
+var entryPool = sync.Pool{
+	New: func() any { return new(models.LogEntry) },
+}
+
+// getEntry takes a zeroed LogEntry from the pool
+func getEntry() *models.LogEntry {
+	return entryPool.Get().(*models.LogEntry)
+}
+
+// putEntry zeroes entry, so its Fields map is not shared with the copy
+// emitted, and returns it to the pool
+func putEntry(entry *models.LogEntry) {
+	*entry = models.LogEntry{}
+	entryPool.Put(entry)
+}
+
+// span is the position of a string value within a line
+type span struct {
+	start, end int
+	set        bool
+	escaped    bool
+}
+
+// fastDecode decodes a flat object whose keys are LogEntry fields and whose
+// values are strings without escape sequences, the shape written by most
+// shippers. All strings of the entry share one allocation; those with escape
+// sequences are first unescaped into scratch, which is kept for the next
+// line. It reports false for anything else, which is left to encoding/json,
+// and for every entry if a field mapping is set.
+func (p JSONParser) fastDecode(line []byte, scratch *[]byte) (models.LogEntry, bool) {
+	var entry models.LogEntry
+	if p.Mapping != nil {
+		return entry, false
+	}
+	var id, ts, level, service, message, source span
+	ascii := true
+
+	i := skipSpace(line, 0)
+	if i == len(line) || line[i] != '{' {
+		return entry, false
+	}
+	i = skipSpace(line, i+1)
+	if i < len(line) && line[i] == '}' {
+		i++
+	} else {
+		for {
+			key, next, ok := scanString(line, i)
+			if !ok {
+				return entry, false
+			}
+			i = skipSpace(line, next)
+			if i == len(line) || line[i] != ':' {
+				return entry, false
+			}
+			value, next, ok := scanString(line, skipSpace(line, i+1))
+			if !ok {
+				return entry, false
+			}
+			for _, b := range line[value.start:value.end] {
+				if b >= utf8.RuneSelf {
+					ascii = false
+					break
+				}
+			}
+
+			switch string(line[key.start:key.end]) {
+			case "id":
+				id = value
+			case "timestamp":
+				ts = value
+			case "level":
+				level = value
+			case "service":
+				service = value
+			case "message":
+				message = value
+			case "source":
+				source = value
+			default:
+				return entry, false
+			}
+
+			i = skipSpace(line, next)
+			if i == len(line) {
+				return entry, false
+			}
+			if line[i] == '}' {
+				i++
+				break
+			}
+			if line[i] != ',' {
+				return entry, false
+			}
+			i = skipSpace(line, i+1)
+		}
+	}
+	if skipSpace(line, i) != len(line) {
+		return entry, false
+	}
+	// encoding/json replaces invalid UTF-8, so leave such lines to it
+	if !ascii && !utf8.Valid(line) {
+		return entry, false
+	}
+
+	var s string
+	if id.escaped || ts.escaped || level.escaped || service.escaped || message.escaped || source.escaped {
+		buf := (*scratch)[:0]
+		for _, v := range []*span{&id, &ts, &level, &service, &message, &source} {
+			start := len(buf)
+			if v.escaped {
+				var ok bool
+				if buf, ok = unescape(buf, line[v.start:v.end]); !ok {
+					return entry, false
+				}
+			} else {
+				buf = append(buf, line[v.start:v.end]...)
+			}
+			v.start, v.end = start, len(buf)
+		}
+		*scratch = buf
+		s = string(buf)
+	} else {
+		s = string(line)
+	}
+	str := func(v span) string { return s[v.start:v.end] }
+	entry.ID = str(id)
+	entry.Level = models.LogLevel(str(level))
+	entry.Service = str(service)
+	entry.Message = str(message)
+	entry.Source = str(source)
+
+	if ts.set {
+		layout, ok := p.TimestampLayouts[entry.Service]
+		if !ok {
+			layout = time.RFC3339Nano
+		}
+		t, err := time.Parse(layout, str(ts))
+		if err != nil {
+			return entry, false
+		}
+		entry.Timestamp = t
+	}
+	return entry, true
+}
+
+// scanString finds the string starting at line[i], returning the span of its
+// contents and the index after the closing quote. Strings with control
+// characters are rejected.
+func scanString(line []byte, i int) (span, int, bool) {
+	if i == len(line) || line[i] != '"' {
+		return span{}, i, false
+	}
+	escaped := false
+	for j := i + 1; j < len(line); j++ {
+		switch c := line[j]; {
+		case c == '"':
+			return span{start: i + 1, end: j, set: true, escaped: escaped}, j + 1, true
+		case c == '\\':
+			escaped = true
+			j++
+		case c < 0x20:
+			return span{}, j, false
+		}
+	}
+	return span{}, len(line), false
+}
+
+// unescape appends the JSON string b to out with its escape sequences
+// decoded
+func unescape(out, b []byte) ([]byte, bool) {
+	for i := 0; i < len(b); i++ {
+		if b[i] != '\\' {
+			out = append(out, b[i])
+			continue
+		}
+		i++
+		if i == len(b) {
+			return nil, false
+		}
+		switch b[i] {
+		case '"', '\\', '/':
+			out = append(out, b[i])
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'u':
+			r, ok := hex4(b[i+1:])
+			if !ok {
+				return nil, false
+			}
+			i += 4
+			if utf16.IsSurrogate(r) {
+				// A valid pair continues with a second \u escape
+				if r2, ok := hex4(b[min(i+3, len(b)):]); ok && i+2 < len(b) && b[i+1] == '\\' && b[i+2] == 'u' {
+					if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+						out = utf8.AppendRune(out, dec)
+						i += 6
+						continue
+					}
+				}
+				r = utf8.RuneError
+			}
+			out = utf8.AppendRune(out, r)
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// hex4 decodes the four hex digits of a \u escape
+func hex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r*16 + rune(c)
+	}
+	return r, true
+}
+
+// skipSpace returns the index of the first non-whitespace byte from i
+func skipSpace(line []byte, i int) int {
+	for i < len(line) {
+		switch line[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// fieldNames maps the usual names of key=value and CSV attributes onto the
+// entry attributes they hold
+var fieldNames = map[string]string{
+	"id":          "id",
+	"timestamp":   "timestamp",
+	"@timestamp":  "timestamp",
+	"time":        "timestamp",
+	"ts":          "timestamp",
+	"datetime":    "timestamp",
+	"level":       "level",
+	"lvl":         "level",
+	"severity":    "level",
+	"loglevel":    "level",
+	"service":     "service",
+	"svc":         "service",
+	"app":         "service",
+	"application": "service",
+	"component":   "service",
+	"message":     "message",
+	"msg":         "message",
+	"source":      "source",
+}
+
+// timestampLayouts are tried in order on timestamps of text formats
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05.999999999",
+}
+
+// parseTimestamp parses a timestamp in one of timestampLayouts, or since
+// the Unix epoch. Timestamps without a zone are UTC.
+func parseTimestamp(s string) (time.Time, error) {
+	if ts, ok := parseLayouts(s); ok {
+		return ts, nil
+	}
+	if ts, ok := models.ParseEpoch(s); ok {
+		return ts, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// parseLayouts parses a timestamp in one of timestampLayouts
+func parseLayouts(s string) (time.Time, bool) {
+	for _, layout := range timestampLayouts {
+		if ts, err := time.Parse(layout, s); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseLevel returns the registered level named by s, or s in upper case
+func parseLevel(s string) models.LogLevel {
+	if level, ok := models.ParseLevel(s); ok {
+		return level
+	}
+	return models.LogLevel(strings.ToUpper(s))
+}
+
+// entryFromFields builds an entry from named values, keeping the values
+// that are no entry attribute as fields
+func entryFromFields(names, values []string) (models.LogEntry, error) {
+	var entry models.LogEntry
+	for i, name := range names {
+		if i >= len(values) || values[i] == "" {
+			continue
+		}
+		value := values[i]
+		switch fieldNames[strings.ToLower(name)] {
+		case "id":
+			entry.ID = value
+		case "timestamp":
+			ts, err := parseTimestamp(value)
+			if err != nil {
+				return entry, err
+			}
+			entry.Timestamp = ts
+		case "level":
+			entry.Level = parseLevel(value)
+		case "service":
+			entry.Service = value
+		case "message":
+			entry.Message = value
+		case "source":
+			entry.Source = value
+		default:
+			if entry.Fields == nil {
+				entry.Fields = make(map[string]string)
+			}
+			entry.Fields[name] = value
+		}
+	}
+	return entry, nil
+}
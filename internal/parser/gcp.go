@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// GCPParser parses Google Cloud Logging LogEntry exports, as written by log
+// sinks to Cloud Storage (one JSON entry per line)
+type GCPParser struct{}
+
+// gcpEntry holds the Cloud Logging LogEntry attributes mapped onto entries
+type gcpEntry struct {
+	InsertID  string    `json:"insertId"`
+	LogName   string    `json:"logName"`
+	Timestamp time.Time `json:"timestamp"`
+	Severity  string    `json:"severity"`
+	Resource  struct {
+		Type   string            `json:"type"`
+		Labels map[string]string `json:"labels"`
+	} `json:"resource"`
+	Labels      map[string]string      `json:"labels"`
+	TextPayload string                 `json:"textPayload"`
+	JSONPayload map[string]interface{} `json:"jsonPayload"`
+	HTTPRequest *struct {
+		RequestMethod string `json:"requestMethod"`
+		RequestURL    string `json:"requestUrl"`
+		Status        int    `json:"status"`
+		Latency       string `json:"latency"`
+		RemoteIP      string `json:"remoteIp"`
+		UserAgent     string `json:"userAgent"`
+	} `json:"httpRequest"`
+	Trace string `json:"trace"`
+}
+
+// gcpServiceLabels lists, per monitored resource type, the label that best
+// identifies the emitting service
+var gcpServiceLabels = map[string]string{
+	"k8s_container":      "container_name",
+	"k8s_pod":            "pod_name",
+	"cloud_run_revision": "service_name",
+	"cloud_run_job":      "job_name",
+	"cloud_function":     "function_name",
+	"gae_app":            "module_id",
+	"gce_instance":       "instance_id",
+	"cloudsql_database":  "database_id",
+	"http_load_balancer": "forwarding_rule_name",
+}
+
+// Parse decodes a stream of Cloud Logging entries
+func (GCPParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var raw gcpEntry
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode Cloud Logging entry: %w", err)
+		}
+		if err := emit(raw.toEntry()); err != nil {
+			return err
+		}
+	}
+}
+
+func (g gcpEntry) toEntry() models.LogEntry {
+	fields := make(map[string]string)
+	if g.Resource.Type != "" {
+		fields["resource_type"] = g.Resource.Type
+	}
+	for k, v := range g.Resource.Labels {
+		fields["resource."+k] = v
+	}
+	for k, v := range g.Labels {
+		fields["label."+k] = v
+	}
+	if g.LogName != "" {
+		fields["log_name"] = g.LogName
+	}
+	if g.Trace != "" {
+		fields["trace"] = g.Trace
+	}
+
+	message := g.TextPayload
+	for k, v := range g.JSONPayload {
+		if (k == "message" || k == "msg") && message == "" {
+			message = fmt.Sprint(v)
+			continue
+		}
+		// Keep scalar payload values; nested objects are too noisy as strings
+		switch v.(type) {
+		case string, float64, bool:
+			fields["payload."+k] = fmt.Sprint(v)
+		}
+	}
+
+	if req := g.HTTPRequest; req != nil {
+		fields["request_method"] = req.RequestMethod
+		fields["request_url"] = req.RequestURL
+		fields["status"] = fmt.Sprint(req.Status)
+		fields["latency"] = req.Latency
+		fields["remote_ip"] = req.RemoteIP
+		fields["user_agent"] = req.UserAgent
+		if message == "" {
+			message = fmt.Sprintf("%s %s %d", req.RequestMethod, req.RequestURL, req.Status)
+		}
+	}
+	for k, v := range fields {
+		if v == "" {
+			delete(fields, k)
+		}
+	}
+
+	return models.LogEntry{
+		ID:        g.InsertID,
+		Timestamp: g.Timestamp,
+		Level:     gcpLevel(g.Severity),
+		Service:   g.service(),
+		Message:   message,
+		Fields:    fields,
+	}
+}
+
+// service picks the most specific service name the resource provides
+func (g gcpEntry) service() string {
+	if label, ok := gcpServiceLabels[g.Resource.Type]; ok {
+		if name := g.Resource.Labels[label]; name != "" {
+			return name
+		}
+	}
+	if g.Resource.Type != "" {
+		return g.Resource.Type
+	}
+	// Fall back to the short log name, e.g. "stdout"
+	if i := strings.LastIndex(g.LogName, "/"); i >= 0 {
+		return g.LogName[i+1:]
+	}
+	return g.LogName
+}
+
+// gcpLevel maps Cloud Logging severities onto LogLevel
+func gcpLevel(severity string) models.LogLevel {
+	switch strings.ToUpper(severity) {
+	case "DEBUG":
+		return models.DEBUG
+	case "WARNING":
+		return models.WARNING
+	case "ERROR":
+		return models.ERROR
+	case "CRITICAL", "ALERT", "EMERGENCY":
+		return models.FATAL
+	default:
+		// DEFAULT, INFO and NOTICE
+		return models.INFO
+	}
+}
@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// GoTestParser reads Go test and service output, either plain text or the
+// events written by go test -json. Lines without a level are classified by
+// content: panics are FATAL, data races and failing tests or packages are
+// ERROR, and passing or skipped tests are INFO. Other output is ignored.
+//
+// Entries carry the fields package, test and action (pass, fail, skip,
+// panic or race).
+type GoTestParser struct{}
+
+// goTestEvent is one line of go test -json output
+type goTestEvent struct {
+	Time    time.Time `json:"Time"`
+	Action  string    `json:"Action"`
+	Package string    `json:"Package"`
+	Test    string    `json:"Test"`
+	Elapsed float64   `json:"Elapsed"`
+	Output  string    `json:"Output"`
+}
+
+// Parse reads Go test output
+func (GoTestParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	s := &goTestScanner{emit: emit}
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		var event goTestEvent
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &event) == nil && event.Action != "" {
+			if err := s.event(event); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.line(line, "", "", time.Time{}, false); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read go test output: %w", err)
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return s.adopt("")
+}
+
+// goTestScanner classifies output line by line, collecting the lines of a
+// panic or data race report into a single entry
+type goTestScanner struct {
+	emit func(models.LogEntry) error
+
+	// pending is the panic or race report being collected
+	pending *models.LogEntry
+	lines   []string
+	// orphans are plain text results whose package is not known yet. Plain
+	// go test output only names the package after its tests.
+	orphans []models.LogEntry
+}
+
+// out emits an entry, holding back entries without a package until the
+// package result line names it
+func (s *goTestScanner) out(entry models.LogEntry) error {
+	if entry.Fields["package"] == "" {
+		s.orphans = append(s.orphans, entry)
+		return nil
+	}
+	return s.emit(entry)
+}
+
+// adopt assigns a package to the held back entries and emits them
+func (s *goTestScanner) adopt(pkg string) error {
+	for _, entry := range s.orphans {
+		if pkg != "" {
+			entry.Service = pkg
+			entry.Fields["package"] = pkg
+		}
+		if err := s.emit(entry); err != nil {
+			return err
+		}
+	}
+	s.orphans = nil
+	return nil
+}
+
+// event handles a go test -json event. Test results come from the pass,
+// fail and skip actions, so only panics and races are taken from the output.
+func (s *goTestScanner) event(e goTestEvent) error {
+	switch e.Action {
+	case "output":
+		return s.line(strings.TrimSuffix(e.Output, "\n"), e.Package, e.Test, e.Time, true)
+	case "pass", "fail", "skip":
+		if err := s.flush(); err != nil {
+			return err
+		}
+		entry := goTestEntry(e.Action, e.Package, e.Test, e.Time)
+		entry.Fields["elapsed"] = fmt.Sprintf("%.2fs", e.Elapsed)
+		return s.out(entry)
+	}
+	return nil
+}
+
+// line handles one line of output. In JSON mode test results are reported
+// by events, so result lines in the output are skipped.
+func (s *goTestScanner) line(line, pkg, test string, ts time.Time, jsonMode bool) error {
+	if s.pending != nil {
+		if s.pending.Fields["action"] == "race" {
+			s.lines = append(s.lines, line)
+			// Race reports end with a line of equals signs
+			if strings.HasPrefix(line, "==================") {
+				return s.flush()
+			}
+			return nil
+		}
+		if !goTestResultLine(line) {
+			s.lines = append(s.lines, line)
+			return nil
+		}
+		if err := s.flush(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(line, "panic: "):
+		entry := goTestEntry("panic", pkg, test, ts)
+		s.pending, s.lines = &entry, []string{line}
+	case strings.HasPrefix(line, "WARNING: DATA RACE"):
+		entry := goTestEntry("race", pkg, test, ts)
+		s.pending, s.lines = &entry, []string{line}
+	case jsonMode:
+	case strings.HasPrefix(strings.TrimSpace(line), "--- "):
+		// "--- FAIL: TestName (0.00s)", indented for subtests
+		fields := strings.Fields(line)
+		if len(fields) >= 3 {
+			action := strings.ToLower(strings.TrimSuffix(fields[1], ":"))
+			if action == "pass" || action == "fail" || action == "skip" {
+				return s.out(goTestEntry(action, pkg, fields[2], ts))
+			}
+		}
+	case strings.HasPrefix(line, "FAIL\t"), strings.HasPrefix(line, "ok  \t"):
+		// Package results: "FAIL\tpkg\t0.01s" or "ok  \tpkg\t0.01s"
+		fields := strings.Split(line, "\t")
+		action := "fail"
+		if strings.HasPrefix(line, "ok") {
+			action = "pass"
+		}
+		pkg = strings.TrimSpace(fields[1])
+		if err := s.adopt(pkg); err != nil {
+			return err
+		}
+		return s.out(goTestEntry(action, pkg, "", ts))
+	}
+	return nil
+}
+
+// flush emits the collected panic or race report
+func (s *goTestScanner) flush() error {
+	if s.pending == nil {
+		return nil
+	}
+	entry := *s.pending
+	entry.Message = strings.TrimRight(strings.Join(s.lines, "\n"), "\n")
+	s.pending, s.lines = nil, nil
+	return s.out(entry)
+}
+
+// goTestResultLine reports whether a line ends a panic's goroutine dump
+func goTestResultLine(line string) bool {
+	for _, prefix := range []string{"FAIL", "ok  \t", "PASS", "exit status", "--- ", "=== "} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// goTestEntry creates an entry for a test result, panic or race
+func goTestEntry(action, pkg, test string, ts time.Time) models.LogEntry {
+	entry := models.LogEntry{
+		Timestamp: ts,
+		Service:   pkg,
+		Fields:    map[string]string{"action": action},
+	}
+	if pkg != "" {
+		entry.Fields["package"] = pkg
+	}
+	if test != "" {
+		entry.Fields["test"] = test
+	}
+
+	subject := test
+	if subject == "" {
+		subject = pkg
+	}
+	switch action {
+	case "panic":
+		entry.Level = models.FATAL
+	case "race":
+		entry.Level = models.ERROR
+	case "fail":
+		entry.Level = models.ERROR
+		entry.Message = "FAIL " + subject
+	case "pass":
+		entry.Level = models.INFO
+		entry.Message = "PASS " + subject
+	case "skip":
+		entry.Level = models.INFO
+		entry.Message = "SKIP " + subject
+	}
+	return entry
+}
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestGoTestParserJSON(t *testing.T) {
+	input := `{"Time":"2023-01-01T10:00:00Z","Action":"run","Package":"example.com/api","Test":"TestGet"}
+{"Time":"2023-01-01T10:00:00Z","Action":"output","Package":"example.com/api","Test":"TestGet","Output":"=== RUN   TestGet\n"}
+{"Time":"2023-01-01T10:00:01Z","Action":"output","Package":"example.com/api","Test":"TestGet","Output":"--- FAIL: TestGet (0.50s)\n"}
+{"Time":"2023-01-01T10:00:01Z","Action":"fail","Package":"example.com/api","Test":"TestGet","Elapsed":0.5}
+{"Time":"2023-01-01T10:00:01Z","Action":"pass","Package":"example.com/api","Test":"TestList","Elapsed":0.1}
+{"Time":"2023-01-01T10:00:02Z","Action":"output","Package":"example.com/db","Test":"TestPool","Output":"panic: runtime error: invalid memory address or nil pointer dereference\n"}
+{"Time":"2023-01-01T10:00:02Z","Action":"output","Package":"example.com/db","Test":"TestPool","Output":"\n"}
+{"Time":"2023-01-01T10:00:02Z","Action":"output","Package":"example.com/db","Test":"TestPool","Output":"goroutine 7 [running]:\n"}
+{"Time":"2023-01-01T10:00:02Z","Action":"output","Package":"example.com/db","Test":"TestPool","Output":"example.com/db.(*Pool).Get(...)\n"}
+{"Time":"2023-01-01T10:00:02Z","Action":"output","Package":"example.com/db","Test":"TestPool","Output":"\t/src/db/pool.go:31 +0x1d\n"}
+{"Time":"2023-01-01T10:00:02Z","Action":"fail","Package":"example.com/db","Test":"TestPool","Elapsed":0}
+{"Time":"2023-01-01T10:00:02Z","Action":"fail","Package":"example.com/db","Elapsed":0.2}`
+
+	entries := collect(t, GoTestParser{}, input)
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 entries, got %d: %+v", len(entries), entries)
+	}
+
+	if entries[0].Level != models.ERROR || entries[0].Message != "FAIL TestGet" || entries[0].Service != "example.com/api" {
+		t.Errorf("Unexpected failing test entry: %+v", entries[0])
+	}
+	if entries[0].Fields["elapsed"] != "0.50s" {
+		t.Errorf("Expected elapsed time, got %v", entries[0].Fields)
+	}
+	if entries[1].Level != models.INFO {
+		t.Errorf("Expected passing test to be INFO, got %s", entries[1].Level)
+	}
+
+	// The panic and its goroutine dump form one FATAL entry
+	panicEntry := entries[2]
+	if panicEntry.Level != models.FATAL || panicEntry.Fields["action"] != "panic" || panicEntry.Fields["test"] != "TestPool" {
+		t.Errorf("Unexpected panic entry: %+v", panicEntry)
+	}
+	if panicEntry.Message[:6] != "panic:" || len(panicEntry.Message) < 100 {
+		t.Errorf("Expected the whole panic report in the message, got %q", panicEntry.Message)
+	}
+	if entries[4].Fields["test"] != "" || entries[4].Fields["action"] != "fail" {
+		t.Errorf("Expected a package failure, got %+v", entries[4])
+	}
+}
+
+func TestGoTestParserText(t *testing.T) {
+	input := `=== RUN   TestCounter
+==================
+WARNING: DATA RACE
+Write at 0x00c000012345 by goroutine 8:
+  example.com/stats.(*Counter).Inc()
+      /src/stats/counter.go:12 +0x44
+==================
+--- FAIL: TestCounter (0.01s)
+    testing.go:1446: race detected during execution of test
+--- PASS: TestReset (0.00s)
+FAIL
+FAIL	example.com/stats	0.021s
+ok  	example.com/util	0.004s`
+
+	entries := collect(t, GoTestParser{}, input)
+	if len(entries) != 5 {
+		t.Fatalf("Expected 5 entries, got %d: %+v", len(entries), entries)
+	}
+
+	race := entries[0]
+	if race.Level != models.ERROR || race.Fields["action"] != "race" {
+		t.Errorf("Unexpected race entry: %+v", race)
+	}
+	// Plain text output names the package after its tests
+	for _, entry := range entries[:4] {
+		if entry.Service != "example.com/stats" || entry.Fields["package"] != "example.com/stats" {
+			t.Errorf("Expected entry to belong to example.com/stats, got %+v", entry)
+		}
+	}
+	if entries[1].Message != "FAIL TestCounter" || entries[2].Message != "PASS TestReset" {
+		t.Errorf("Unexpected test results: %q, %q", entries[1].Message, entries[2].Message)
+	}
+	if entries[4].Level != models.INFO || entries[4].Service != "example.com/util" {
+		t.Errorf("Unexpected package result: %+v", entries[4])
+	}
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// LogfmtParser parses logfmt lines such as
+// ts=2023-01-01T10:00:00Z level=error service=api msg="timeout"
+type LogfmtParser struct{}
+
+// Parse reads one entry per line
+func (LogfmtParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		names, values, err := splitLogfmt(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		entry, err := entryFromFields(names, values)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// splitLogfmt splits a line into its keys and values. Values may be double
+// quoted; a key without a value is kept with an empty one.
+func splitLogfmt(line string) (names, values []string, err error) {
+	i := 0
+	for i < len(line) {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		name := line[start:i]
+		if i >= len(line) || line[i] != '=' {
+			names, values = append(names, name), append(values, "")
+			continue
+		}
+		i++
+
+		var value strings.Builder
+		if i < len(line) && line[i] == '"' {
+			i++
+			closed := false
+			for ; i < len(line); i++ {
+				c := line[i]
+				if c == '\\' && i+1 < len(line) {
+					i++
+					switch line[i] {
+					case 'n':
+						value.WriteByte('\n')
+					case 't':
+						value.WriteByte('\t')
+					default:
+						value.WriteByte(line[i])
+					}
+					continue
+				}
+				if c == '"' {
+					closed = true
+					i++
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return nil, nil, fmt.Errorf("unterminated quoted value of %s", name)
+			}
+		} else {
+			start := i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			value.WriteString(line[start:i])
+		}
+		names, values = append(names, name), append(values, value.String())
+	}
+	return names, values, nil
+}
@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// mappedAttributes are the entry attributes a FieldMapping can take from a
+// path, besides fields.NAME
+var mappedAttributes = []string{"id", "timestamp", "level", "service", "message", "source"}
+
+// FieldMapping takes the attributes of entries from JSON documents of any
+// schema, e.g. the level from $.severity. Attributes without a path are
+// read from their usual key.
+type FieldMapping struct {
+	paths map[string]jsonPath
+	// fields maps field names onto the paths of their values
+	fields map[string]jsonPath
+}
+
+// jsonPath is a sequence of object keys and array indexes
+type jsonPath []pathStep
+
+type pathStep struct {
+	key   string
+	index int
+}
+
+// NewFieldMapping parses paths such as "$.ts" or "$.kubernetes.labels.app"
+// by entry attribute: id, timestamp, level, service, message, source or
+// fields.NAME
+func NewFieldMapping(paths map[string]string) (*FieldMapping, error) {
+	m := &FieldMapping{paths: make(map[string]jsonPath), fields: make(map[string]jsonPath)}
+	for attr, path := range paths {
+		parsed, err := parsePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path of %s: %w", attr, err)
+		}
+		if name, ok := strings.CutPrefix(attr, "fields."); ok && name != "" {
+			m.fields[name] = parsed
+			continue
+		}
+		if !contains(mappedAttributes, attr) {
+			return nil, fmt.Errorf("unknown attribute %q (known: %s, fields.NAME)", attr, strings.Join(mappedAttributes, ", "))
+		}
+		m.paths[attr] = parsed
+	}
+	for _, attr := range mappedAttributes {
+		if _, ok := m.paths[attr]; !ok {
+			m.paths[attr] = jsonPath{{key: attr}}
+		}
+	}
+	return m, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePath parses a path of keys and indexes such as $.a.b[0]["c.d"]
+func parsePath(path string) (jsonPath, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, fmt.Errorf("%q does not start with $", path)
+	}
+	var parsed jsonPath
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("%q has an empty key", path)
+			}
+			parsed, rest = append(parsed, pathStep{key: key}), rest[end+1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%q has an unclosed [", path)
+			}
+			inner := rest[1:end]
+			if key, err := strconv.Unquote(strings.ReplaceAll(inner, "'", `"`)); err == nil {
+				parsed = append(parsed, pathStep{key: key})
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				parsed = append(parsed, pathStep{index: index})
+			} else {
+				return nil, fmt.Errorf("%q has an invalid index %q", path, inner)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", rest, path)
+		}
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("%q selects no value", path)
+	}
+	return parsed, nil
+}
+
+// lookup returns the value at the path in a decoded document
+func (path jsonPath) lookup(doc any) (any, bool) {
+	for _, step := range path {
+		switch v := doc.(type) {
+		case map[string]any:
+			if step.key == "" {
+				return nil, false
+			}
+			if doc = v[step.key]; doc == nil {
+				return nil, false
+			}
+		case []any:
+			if step.key != "" || step.index >= len(v) {
+				return nil, false
+			}
+			doc = v[step.index]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// decodeMapped reads an entry with unmarshal, taking its attributes from
+// the paths of p.Mapping
+func (p JSONParser) decodeMapped(unmarshal func(any) error) (models.LogEntry, error) {
+	var entry models.LogEntry
+	var raw json.RawMessage
+	if err := unmarshal(&raw); err != nil {
+		return entry, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var doc any
+	if err := decoder.Decode(&doc); err != nil {
+		return entry, err
+	}
+
+	value := func(attr string) string {
+		v, _ := p.Mapping.paths[attr].lookup(doc)
+		return stringValue(v)
+	}
+	entry.ID = value("id")
+	entry.Level = models.LogLevel(value("level"))
+	entry.Service = value("service")
+	entry.Message = value("message")
+	entry.Source = value("source")
+
+	if ts := value("timestamp"); ts != "" {
+		var err error
+		layout, ok := p.TimestampLayouts[entry.Service]
+		if v, _ := p.Mapping.paths["timestamp"].lookup(doc); ok && !isNumber(v) {
+			entry.Timestamp, err = time.Parse(layout, ts)
+		} else {
+			// Numbers are epoch timestamps whatever the layout
+			entry.Timestamp, err = parseTimestamp(ts)
+		}
+		if err != nil {
+			return entry, fmt.Errorf("invalid timestamp %q", ts)
+		}
+	}
+
+	// The usual fields object is kept next to the mapped fields
+	if fields, ok := (jsonPath{{key: "fields"}}).lookup(doc); ok {
+		if object, ok := fields.(map[string]any); ok {
+			for name, v := range object {
+				setField(&entry, name, stringValue(v))
+			}
+		}
+	}
+	for name, path := range p.Mapping.fields {
+		if v, ok := path.lookup(doc); ok {
+			setField(&entry, name, stringValue(v))
+		}
+	}
+	return entry, nil
+}
+
+func isNumber(v any) bool {
+	_, ok := v.(json.Number)
+	return ok
+}
+
+func setField(entry *models.LogEntry, name, value string) {
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]string)
+	}
+	entry.Fields[name] = value
+}
+
+// stringValue returns a decoded JSON value as text, with objects and
+// arrays as compact JSON
+func stringValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,253 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Parser reads log entries from a stream, calling emit for each entry.
+// Parsing stops at the first error returned by emit.
+type Parser interface {
+	Parse(r io.Reader, emit func(models.LogEntry) error) error
+}
+
+// format describes a supported input format
+type format struct {
+	parser  Parser
+	pattern string
+}
+
+var formats = map[string]format{
+	"json":       {parser: JSONParser{}, pattern: "*.json"},
+	"alb":        {parser: ALBParser{}, pattern: "*.log*"},
+	"cloudtrail": {parser: CloudTrailParser{}, pattern: "*.json*"},
+	"gcp":        {parser: GCPParser{}, pattern: "*.json*"},
+	"gotest":     {parser: GoTestParser{}, pattern: "*"},
+	"logfmt":     {parser: LogfmtParser{}, pattern: "*.log*"},
+	"csv":        {parser: CSVParser{}, pattern: "*.csv*"},
+	"text":       {parser: TextParser{}, pattern: "*.log*"},
+	"auto":       {parser: AutoParser{}, pattern: "*"},
+}
+
+// ForFormat returns the parser for the named format
+func ForFormat(name string) (Parser, error) {
+	f, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown log format %q (supported: %v)", name, Formats())
+	}
+	return f.parser, nil
+}
+
+// DefaultPattern returns the file glob typically used for the named format
+func DefaultPattern(name string) string {
+	if f, ok := formats[name]; ok {
+		return f.pattern
+	}
+	return "*"
+}
+
+// Formats returns the names of all supported formats
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// JSONParser parses newline-delimited JSON log entries
+type JSONParser struct {
+	// TimestampLayouts holds Go time layouts for services whose timestamps
+	// are not RFC 3339, keyed by service name
+	TimestampLayouts map[string]string
+	// MaxLineSize limits the length of a line in bytes, so a file without
+	// newlines cannot exhaust memory. Zero means no limit.
+	MaxLineSize int
+	// Envelope locates the entries in documents wrapping them, such as
+	// {"entries": [...]}. Inputs that are a single JSON array of entries
+	// are read without one.
+	Envelope *Envelope
+	// Mapping, if set, takes the attributes of entries from other keys than
+	// the usual ones, e.g. the level from $.severity
+	Mapping *FieldMapping
+}
+
+// Parse decodes a stream of JSON-encoded LogEntry objects. Entries on a line
+// of their own take a fast path without reflection; once a line holds
+// anything other than a single object, such as pretty-printed JSON, the rest
+// of the stream is read with a regular JSON decoder. A stream starting with
+// "[" is read as a single array of entries.
+func (p JSONParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	br := readerPool.Get().(*bufio.Reader)
+	br.Reset(r)
+	defer func() {
+		br.Reset(nil)
+		readerPool.Put(br)
+	}()
+	if p.Envelope != nil {
+		return p.parseEnvelope(br, *p.Envelope, emit)
+	}
+	if startsArray(br) {
+		return p.parseArray(br, emit)
+	}
+
+	var long, scratch []byte
+	for {
+		line, readErr := br.ReadSlice('\n')
+		if readErr == bufio.ErrBufferFull {
+			// Lines longer than the buffer are collected separately
+			long = append(long[:0], line...)
+			for readErr == bufio.ErrBufferFull && (p.MaxLineSize <= 0 || len(long) <= p.MaxLineSize) {
+				line, readErr = br.ReadSlice('\n')
+				long = append(long, line...)
+			}
+			line = long
+		}
+		if p.MaxLineSize > 0 && len(bytes.TrimSuffix(line, []byte("\n"))) > p.MaxLineSize {
+			return fmt.Errorf("line exceeds the maximum size of %d bytes", p.MaxLineSize)
+		}
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("failed to read entry: %w", readErr)
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			entry, ok := p.fastDecode(line, &scratch)
+			if !ok {
+				var err error
+				entry, err = p.decode(func(v any) error { return json.Unmarshal(line, v) })
+				var syntaxErr *json.SyntaxError
+				if errors.As(err, &syntaxErr) {
+					rest := io.MultiReader(bytes.NewReader(append([]byte(nil), line...)), br)
+					return p.parseStream(rest, emit)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to decode entry: %w", err)
+				}
+			}
+			if err := emit(entry); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// parseStream decodes the remaining entries with a JSON decoder, which also
+// accepts objects spanning several lines
+func (p JSONParser) parseStream(r io.Reader, emit func(models.LogEntry) error) error {
+	decoder := json.NewDecoder(r)
+	for {
+		entry, err := p.decode(decoder.Decode)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode entry: %w", err)
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// decode reads an entry with unmarshal, e.g. a decoder's Decode method
+func (p JSONParser) decode(unmarshal func(any) error) (models.LogEntry, error) {
+	if p.Mapping != nil {
+		return p.decodeMapped(unmarshal)
+	}
+	if len(p.TimestampLayouts) > 0 {
+		return p.decodeWithLayouts(unmarshal)
+	}
+	entry := getEntry()
+	defer putEntry(entry)
+	err := unmarshal(entry)
+	return *entry, err
+}
+
+// entryFields has the fields of LogEntry without its JSON handling of time
+type entryFields models.LogEntry
+
+// decodeWithLayouts decodes an entry whose timestamp layout depends on its
+// service
+func (p JSONParser) decodeWithLayouts(unmarshal func(any) error) (models.LogEntry, error) {
+	var raw struct {
+		*entryFields
+		Timestamp json.RawMessage `json:"timestamp"`
+	}
+	entry := getEntry()
+	defer putEntry(entry)
+	raw.entryFields = (*entryFields)(entry)
+	if err := unmarshal(&raw); err != nil {
+		return *entry, err
+	}
+	if len(raw.Timestamp) == 0 || string(raw.Timestamp) == "null" {
+		return *entry, nil
+	}
+
+	layout, ok := p.TimestampLayouts[entry.Service]
+	var value string
+	if !ok || json.Unmarshal(raw.Timestamp, &value) != nil {
+		// Numbers are epoch timestamps whatever the layout
+		var ts models.Timestamp
+		err := json.Unmarshal(raw.Timestamp, &ts)
+		entry.Timestamp = time.Time(ts)
+		return *entry, err
+	}
+	ts, err := time.Parse(layout, value)
+	if err != nil {
+		return *entry, fmt.Errorf("invalid timestamp for service %s: %w", entry.Service, err)
+	}
+	entry.Timestamp = ts
+	return *entry, nil
+}
+
+// parseArray decodes the entries of a single JSON array
+func (p JSONParser) parseArray(r io.Reader, emit func(models.LogEntry) error) error {
+	decoder := json.NewDecoder(r)
+	if tok, err := decoder.Token(); err != nil {
+		return fmt.Errorf("failed to decode entries: %w", err)
+	} else if tok != json.Delim('[') {
+		return fmt.Errorf("expected a JSON array of entries, got %v", tok)
+	}
+	for decoder.More() {
+		entry, err := p.decode(decoder.Decode)
+		if err != nil {
+			return fmt.Errorf("failed to decode entry: %w", err)
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("failed to decode entries: %w", err)
+	}
+	return nil
+}
+
+// startsArray skips the whitespace at the start of br and reports whether a
+// JSON array follows
+func startsArray(br *bufio.Reader) bool {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return false
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		br.UnreadByte()
+		return c == '['
+	}
+}
@@ -0,0 +1,677 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// collect parses input and returns all emitted entries
+func collect(t *testing.T, p Parser, input string) []models.LogEntry {
+	t.Helper()
+	var entries []models.LogEntry
+	err := p.Parse(strings.NewReader(input), func(e models.LogEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	return entries
+}
+
+func TestJSONParser(t *testing.T) {
+	input := `{"id":"1","timestamp":"2023-01-01T10:00:00Z","level":"INFO","service":"api","message":"ok"}
+{"id":"2","timestamp":"2023-01-01T10:05:00Z","level":"ERROR","service":"db","message":"timeout"}`
+
+	entries := collect(t, JSONParser{}, input)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[1].Level != models.ERROR || entries[1].Service != "db" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+
+	err := JSONParser{}.Parse(strings.NewReader(`{"id":`), func(models.LogEntry) error { return nil })
+	if err == nil {
+		t.Error("Expected an error for truncated JSON")
+	}
+}
+
+func TestALBParser(t *testing.T) {
+	input := `https 2023-01-01T10:00:00.123456Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 502 - 34 366 "GET https://www.example.com:443/api/users HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678" 0 2023-01-01T09:59:59.999000Z "forward" "-" "-" "10.0.0.1:80" "-" "-" "-"
+2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 404 404 0 29 "GET http://www.example.com:80/missing HTTP/1.1" "curl/7.38.0" - -
+`
+	entries := collect(t, ALBParser{}, input)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	alb := entries[0]
+	if alb.Service != "app/my-lb/50dc6c495c0c9188" {
+		t.Errorf("Expected service to be the load balancer name, got %s", alb.Service)
+	}
+	if alb.Level != models.ERROR {
+		t.Errorf("Expected a 502 to be ERROR, got %s", alb.Level)
+	}
+	if !alb.Timestamp.Equal(time.Date(2023, 1, 1, 10, 0, 0, 123456000, time.UTC)) {
+		t.Errorf("Unexpected timestamp %v", alb.Timestamp)
+	}
+	expectedFields := map[string]string{
+		"type":           "https",
+		"client_ip":      "192.168.131.39",
+		"request_method": "GET",
+		"request_url":    "https://www.example.com:443/api/users",
+		"user_agent":     "curl/7.46.0",
+		"trace_id":       "Root=1-58337262-36d228ad5d99923122bbe354",
+		"domain_name":    "www.example.com",
+	}
+	for k, v := range expectedFields {
+		if alb.Fields[k] != v {
+			t.Errorf("Expected field %s to be %q, got %q", k, v, alb.Fields[k])
+		}
+	}
+	if _, ok := alb.Fields["target_status_code"]; ok {
+		t.Error("Expected '-' values to be omitted from fields")
+	}
+
+	elb := entries[1]
+	if elb.Service != "my-loadbalancer" || elb.Level != models.WARNING {
+		t.Errorf("Unexpected classic ELB entry: %+v", elb)
+	}
+	if elb.Message != "GET http://www.example.com:80/missing HTTP/1.1" {
+		t.Errorf("Expected the request line as message, got %q", elb.Message)
+	}
+
+	err := ALBParser{}.Parse(strings.NewReader("https not-a-time lb\n"), func(models.LogEntry) error { return nil })
+	if err == nil {
+		t.Error("Expected an error for a malformed line")
+	}
+}
+
+func TestCloudTrailParser(t *testing.T) {
+	input := `{"Records": [
+		{"eventVersion": "1.08", "eventID": "ev-1", "eventTime": "2023-01-01T10:00:00Z",
+		 "eventSource": "s3.amazonaws.com", "eventName": "GetObject", "awsRegion": "us-east-1",
+		 "sourceIPAddress": "203.0.113.7", "readOnly": true,
+		 "userIdentity": {"type": "IAMUser", "arn": "arn:aws:iam::123:user/alice", "userName": "alice"},
+		 "requestParameters": {"bucketName": "logs"}},
+		{"eventID": "ev-2", "eventTime": "2023-01-01T10:01:00Z",
+		 "eventSource": "iam.amazonaws.com", "eventName": "CreateUser",
+		 "errorCode": "AccessDenied", "errorMessage": "User is not authorized"}
+	], "digest": {"ignored": true}}`
+
+	entries := collect(t, CloudTrailParser{}, input)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	if entries[0].ID != "ev-1" || entries[0].Service != "s3" || entries[0].Level != models.INFO {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[0].Fields["user_name"] != "alice" || entries[0].Fields["read_only"] != "true" {
+		t.Errorf("Unexpected first entry fields: %v", entries[0].Fields)
+	}
+
+	if entries[1].Level != models.ERROR {
+		t.Errorf("Expected failed calls to be ERROR, got %s", entries[1].Level)
+	}
+	if entries[1].Message != "CreateUser: User is not authorized" {
+		t.Errorf("Unexpected message %q", entries[1].Message)
+	}
+	if entries[1].Fields["error_code"] != "AccessDenied" {
+		t.Errorf("Expected error_code field, got %v", entries[1].Fields)
+	}
+
+	err := CloudTrailParser{}.Parse(strings.NewReader(`{"Other": []}`), func(models.LogEntry) error { return nil })
+	if err == nil {
+		t.Error("Expected an error for a file without Records")
+	}
+}
+
+func TestForFormat(t *testing.T) {
+	for _, name := range Formats() {
+		if _, err := ForFormat(name); err != nil {
+			t.Errorf("Expected format %s to be supported: %v", name, err)
+		}
+	}
+	if _, err := ForFormat("nope"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestGCPParser(t *testing.T) {
+	input := `{"insertId":"abc","logName":"projects/p/logs/stdout","timestamp":"2023-01-01T10:00:00Z","severity":"CRITICAL","resource":{"type":"k8s_container","labels":{"container_name":"checkout","namespace_name":"shop"}},"jsonPayload":{"message":"payment failed","order":"42","nested":{"a":1}}}
+{"insertId":"def","logName":"projects/p/logs/run.googleapis.com%2Frequests","timestamp":"2023-01-01T10:01:00Z","severity":"NOTICE","resource":{"type":"cloud_run_revision","labels":{"service_name":"frontend"}},"textPayload":"started"}
+{"insertId":"ghi","logName":"projects/p/logs/syslog","timestamp":"2023-01-01T10:02:00Z","httpRequest":{"requestMethod":"GET","requestUrl":"/health","status":200}}`
+
+	entries := collect(t, GCPParser{}, input)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	first := entries[0]
+	if first.ID != "abc" || first.Service != "checkout" || first.Level != models.FATAL || first.Message != "payment failed" {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if first.Fields["resource.namespace_name"] != "shop" || first.Fields["payload.order"] != "42" {
+		t.Errorf("Unexpected first entry fields: %v", first.Fields)
+	}
+	if _, ok := first.Fields["payload.nested"]; ok {
+		t.Error("Expected nested payload objects to be skipped")
+	}
+
+	if entries[1].Service != "frontend" || entries[1].Level != models.INFO || entries[1].Message != "started" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+
+	if entries[2].Service != "syslog" || entries[2].Message != "GET /health 200" {
+		t.Errorf("Unexpected third entry: %+v", entries[2])
+	}
+}
+
+func TestJSONParserTimestampLayouts(t *testing.T) {
+	input := `{"id":"1","timestamp":"01/02/2023 10:00:00","level":"INFO","service":"legacy-api","message":"ok"}
+{"id":"2","timestamp":"2023-01-01T10:05:00Z","level":"ERROR","service":"db","message":"timeout"}
+{"id":"3","level":"ERROR","service":"legacy-api","message":"no timestamp"}`
+
+	p := JSONParser{TimestampLayouts: map[string]string{"legacy-api": "02/01/2006 15:04:05"}}
+	entries := collect(t, p, input)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	expected := time.Date(2023, 2, 1, 10, 0, 0, 0, time.UTC)
+	if !entries[0].Timestamp.Equal(expected) {
+		t.Errorf("Expected legacy timestamp %v, got %v", expected, entries[0].Timestamp)
+	}
+	if entries[0].ID != "1" || entries[0].Message != "ok" {
+		t.Errorf("Expected other fields to be decoded, got %+v", entries[0])
+	}
+	// Other services keep RFC 3339
+	if !entries[1].Timestamp.Equal(time.Date(2023, 1, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected default timestamp %v", entries[1].Timestamp)
+	}
+	if !entries[2].Timestamp.IsZero() {
+		t.Errorf("Expected a missing timestamp to stay zero, got %v", entries[2].Timestamp)
+	}
+
+	err := p.Parse(strings.NewReader(`{"service":"legacy-api","timestamp":"2023-01-01T10:05:00Z"}`), func(models.LogEntry) error { return nil })
+	if err == nil {
+		t.Error("Expected an error for a timestamp not matching the service layout")
+	}
+}
+
+func TestJSONParserFastPath(t *testing.T) {
+	// Every line must decode exactly as encoding/json would, whichever path
+	// it takes
+	lines := []string{
+		`{"id":"1","timestamp":"2023-01-01T10:00:00.123Z","level":"INFO","service":"api","message":"ok"}`,
+		` { "id" : "2" , "level" : "ERROR" , "message" : "naïve" } `,
+		`{"id":"3","message":"quoted \"value\"","fields":{"status":"500"}}`,
+		`{"id":"3a","message":"tab\there \u00e9 \ud83d\ude00 \ud800 a\/b \\"}`,
+		`{"ID":"4","Level":"WARNING","timestamp":"2023-01-01T10:00:00+02:00"}`,
+		`{"id":"5","level_inferred":true,"level":"DEBUG","unknown":1}`,
+		`{}`,
+	}
+	entries := collect(t, JSONParser{}, strings.Join(lines, "\n"))
+	if len(entries) != len(lines) {
+		t.Fatalf("Expected %d entries, got %d", len(lines), len(entries))
+	}
+	for i, line := range lines {
+		var expected models.LogEntry
+		if err := json.Unmarshal([]byte(line), &expected); err != nil {
+			t.Fatalf("Failed to unmarshal line %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(entries[i], expected) {
+			t.Errorf("Expected line %d to decode to %+v, got %+v", i, expected, entries[i])
+		}
+	}
+
+	// Objects spanning several lines fall back to a streaming decoder
+	pretty := "{\"id\":\"1\",\"level\":\"INFO\"}\n{\n  \"id\": \"2\",\n  \"level\": \"ERROR\"\n}\n{\"id\":\"3\"}{\"id\":\"4\"}"
+	entries = collect(t, JSONParser{}, pretty)
+	if len(entries) != 4 || entries[1].Level != models.ERROR || entries[3].ID != "4" {
+		t.Errorf("Expected 4 entries from pretty-printed input, got %+v", entries)
+	}
+
+	// Lines longer than the read buffer
+	long := strings.Repeat("x", 200*1024)
+	entries = collect(t, JSONParser{}, `{"id":"1","message":"`+long+`"}`+"\n"+`{"id":"2"}`)
+	if len(entries) != 2 || entries[0].Message != long {
+		t.Errorf("Expected a long message to be decoded intact, got %d entries", len(entries))
+	}
+}
+
+// benchmarkEntries is the size of the generated datasets, large enough for
+// the garbage collector's share to show
+const benchmarkEntries = 10_000_000
+
+// Shapes of generated entries: decoded by the fast path, with escapes in the
+// message, or with nested fields left to encoding/json
+const (
+	plainEntries = iota
+	escapedEntries
+	nestedEntries
+)
+
+// entryStream generates NDJSON entries of a shape on the fly, so datasets
+// need not fit in memory
+type entryStream struct {
+	n, i    int
+	shape   int
+	line    []byte
+	pending []byte
+}
+
+func (s *entryStream) Read(b []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.i == s.n {
+			return 0, io.EOF
+		}
+		s.line = appendEntry(s.line[:0], s.i, s.shape)
+		s.pending = s.line
+		s.i++
+	}
+	n := copy(b, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// appendEntry appends the i-th generated entry of a shape, without
+// allocating so as not to skew the benchmarks
+func appendEntry(b []byte, i, shape int) []byte {
+	b = append(b, `{"id":"`...)
+	b = strconv.AppendInt(b, int64(i), 10)
+	b = append(b, `","timestamp":"2023-01-01T10:`...)
+	b = append(b, byte('0'+i/600%6), byte('0'+i/60%10), ':', byte('0'+i%60/10), byte('0'+i%10))
+	b = append(b, `Z","level":"INFO","service":"api","message":"request `...)
+	if shape == escapedEntries {
+		b = append(b, `\"`...)
+	}
+	b = strconv.AppendInt(b, int64(i), 10)
+	if shape == escapedEntries {
+		b = append(b, `\"`...)
+	}
+	b = append(b, " completed in "...)
+	b = strconv.AppendInt(b, int64(i%500), 10)
+	b = append(b, `ms"`...)
+	if shape == nestedEntries {
+		b = append(b, `,"fields":{"status":"200"}`...)
+	}
+	return append(b, "}\n"...)
+}
+
+// benchmarkJSON parses benchmarkEntries generated entries per iteration
+func benchmarkJSON(b *testing.B, shape int) {
+	size, err := io.Copy(io.Discard, &entryStream{n: benchmarkEntries, shape: shape})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entries := 0
+		err := JSONParser{}.Parse(&entryStream{n: benchmarkEntries, shape: shape}, func(models.LogEntry) error {
+			entries++
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if entries != benchmarkEntries {
+			b.Fatalf("Expected %d entries, got %d", benchmarkEntries, entries)
+		}
+	}
+}
+
+func BenchmarkJSONParser(b *testing.B) {
+	b.Run("fast", func(b *testing.B) { benchmarkJSON(b, plainEntries) })
+	b.Run("escaped", func(b *testing.B) { benchmarkJSON(b, escapedEntries) })
+	b.Run("nested", func(b *testing.B) { benchmarkJSON(b, nestedEntries) })
+}
+
+func TestJSONParserChunks(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&sb, `{"id":"%d","level":"INFO","message":"entry %d"}`+"\n", i, i)
+	}
+	input := sb.String()
+
+	for _, chunks := range []int{1, 3, 8, 5000} {
+		var entries []models.LogEntry
+		err := JSONParser{}.ParseChunks(strings.NewReader(input), int64(len(input)), chunks, func(e models.LogEntry) error {
+			entries = append(entries, e)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to parse %d chunks: %v", chunks, err)
+		}
+		if len(entries) != 1000 {
+			t.Fatalf("Expected 1000 entries from %d chunks, got %d", chunks, len(entries))
+		}
+		for i, e := range entries {
+			if e.ID != fmt.Sprint(i) {
+				t.Fatalf("Expected entries in file order, got %s at %d", e.ID, i)
+			}
+		}
+	}
+
+	// Objects spanning chunk boundaries are read again sequentially
+	pretty := strings.Repeat("{\n  \"id\": \"1\",\n  \"level\": \"INFO\"\n}\n", 50)
+	var count int
+	err := JSONParser{}.ParseChunks(strings.NewReader(pretty), int64(len(pretty)), 4, func(models.LogEntry) error {
+		count++
+		return nil
+	})
+	if err != nil || count != 50 {
+		t.Errorf("Expected 50 pretty-printed entries, got %d (%v)", count, err)
+	}
+}
+
+func TestJSONParserMaxLineSize(t *testing.T) {
+	p := JSONParser{MaxLineSize: 100}
+	if entries := collect(t, p, `{"id":"1","message":"short"}`); len(entries) != 1 {
+		t.Errorf("Expected 1 entry, got %d", len(entries))
+	}
+
+	long := `{"id":"1","message":"` + strings.Repeat("x", 100*1024) + `"}`
+	err := p.Parse(strings.NewReader(long), func(models.LogEntry) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "maximum size") {
+		t.Errorf("Expected a line size error, got %v", err)
+	}
+}
+
+func TestLogfmtParser(t *testing.T) {
+	input := `ts=2023-01-01T10:00:00Z level=warn service=api msg="slow query \"users\"" duration=1.5s
+time="2023-01-01 10:05:00" lvl=error app=db msg=timeout retry
+`
+	entries := collect(t, LogfmtParser{}, input)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Level != models.WARNING || entries[0].Service != "api" || entries[0].Message != `slow query "users"` {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[0].Fields["duration"] != "1.5s" {
+		t.Errorf("Expected other keys as fields, got %v", entries[0].Fields)
+	}
+	if !entries[1].Timestamp.Equal(time.Date(2023, 1, 1, 10, 5, 0, 0, time.UTC)) || entries[1].Level != models.ERROR {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+
+	err := LogfmtParser{}.Parse(strings.NewReader(`msg="open`), func(models.LogEntry) error { return nil })
+	if err == nil {
+		t.Error("Expected an error for an unterminated value")
+	}
+}
+
+func TestCSVParser(t *testing.T) {
+	input := "timestamp,level,service,message,user\n" +
+		"2023-01-01T10:00:00Z,INFO,api,\"login ok, welcome\",bob\n" +
+		"2023-01-01T10:01:00Z,ERROR,api,login failed,\n"
+	entries := collect(t, CSVParser{}, input)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Message != "login ok, welcome" || entries[0].Fields["user"] != "bob" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Level != models.ERROR || entries[1].Fields != nil {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+
+	err := CSVParser{}.Parse(strings.NewReader("time,message\nyesterday,hi\n"), func(models.LogEntry) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error naming the line, got %v", err)
+	}
+}
+
+func TestTextParser(t *testing.T) {
+	input := `2023-01-01 10:00:00 [ERROR] payment failed
+	at com.shop.Pay(Pay.java:12)
+2023/01/01 10:00:01 WARN: retrying
+just a message
+`
+	entries := collect(t, TextParser{}, input)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Level != models.ERROR || entries[0].Message != "payment failed\n\tat com.shop.Pay(Pay.java:12)" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if !entries[1].Timestamp.Equal(time.Date(2023, 1, 1, 10, 0, 1, 0, time.UTC)) || entries[1].Level != models.WARNING || entries[1].Message != "retrying" {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+	if !entries[2].Timestamp.IsZero() || entries[2].Level != "" || entries[2].Message != "just a message" {
+		t.Errorf("Unexpected third entry: %+v", entries[2])
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		input, format string
+	}{
+		{`{"level":"INFO","message":"ok"}` + "\n", "json"},
+		{"  \n[\n  {\"level\": \"INFO\"}\n]", jsonArray},
+		{"ts=2023-01-01T10:00:00Z level=info msg=ok\nts=2023-01-01T10:00:01Z level=error msg=\"bad thing\"\n", "logfmt"},
+		{"time,level,message\n2023-01-01T10:00:00Z,INFO,ok\n", "csv"},
+		{"2023-01-01 10:00:00 ERROR user=bob login failed\n", "text"},
+		{"name,count\nbob,1\n", "text"},
+		{"hello, world\n", "text"},
+		// The cut off last line is not examined
+		{"ts=1 level=info\nts=2 level=info\nts=3 lev", "logfmt"},
+	}
+	for _, tt := range tests {
+		if got := detect([]byte(tt.input)); got != tt.format {
+			t.Errorf("Expected %q to be detected as %s, got %s", tt.input, tt.format, got)
+		}
+	}
+}
+
+func TestAutoParser(t *testing.T) {
+	inputs := map[string]string{
+		"ndjson":     `{"timestamp":"2023-01-01T10:00:00Z","level":"ERROR","service":"api","message":"boom"}` + "\n",
+		"json array": `[{"timestamp":"2023-01-01T10:00:00Z","level":"ERROR","service":"api","message":"boom"}]`,
+		"logfmt":     "ts=2023-01-01T10:00:00Z level=error service=api msg=boom\n",
+		"csv":        "timestamp,level,service,message\n2023-01-01T10:00:00Z,ERROR,api,boom\n",
+	}
+	for name, input := range inputs {
+		entries := collect(t, AutoParser{}, input)
+		if len(entries) != 1 || entries[0].Level != models.ERROR || entries[0].Service != "api" || entries[0].Message != "boom" {
+			t.Errorf("Unexpected entries from %s: %+v", name, entries)
+		}
+	}
+
+	// Chunked parsing of a format other than NDJSON
+	input := "2023-01-01T10:00:00Z ERROR boom\n2023-01-01T10:00:01Z INFO ok\n"
+	var entries []models.LogEntry
+	err := AutoParser{SniffSize: 16}.ParseChunks(strings.NewReader(input), int64(len(input)), 4, func(e models.LogEntry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil || len(entries) != 2 || entries[1].Message != "ok" {
+		t.Errorf("Unexpected chunked entries: %+v, %v", entries, err)
+	}
+}
+
+func TestJSONParserArray(t *testing.T) {
+	input := `
+[
+  {"id": "1", "level": "INFO", "service": "api", "message": "ok"},
+  {"id": "2", "level": "ERROR", "service": "db", "message": "timeout"}
+]`
+	entries := collect(t, JSONParser{}, input)
+	if len(entries) != 2 || entries[1].Service != "db" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+
+	var chunked []models.LogEntry
+	err := JSONParser{}.ParseChunks(strings.NewReader(input), int64(len(input)), 4, func(e models.LogEntry) error {
+		chunked = append(chunked, e)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(chunked, entries) {
+		t.Errorf("Expected chunked parsing to read the array, got %+v, %v", chunked, err)
+	}
+}
+
+func TestJSONParserEnvelope(t *testing.T) {
+	export := `{"took": 3, "timed_out": false, "_shards": {"total": 1},
+  "hits": {"total": {"value": 2}, "hits": [
+    {"_index": "logs", "_id": "a", "_source": {"level": "ERROR", "service": "api", "message": "boom"}},
+    {"_index": "logs", "_id": "b", "_source": {"level": "INFO", "service": "api", "message": "ok"}}
+  ]}}`
+	env, err := ParseEnvelope("$.hits.hits[*]._source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := collect(t, JSONParser{Envelope: env}, export)
+	if len(entries) != 2 || entries[0].Message != "boom" || entries[1].Level != models.INFO {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+
+	env, _ = ParseEnvelope("entries")
+	entries = collect(t, JSONParser{Envelope: env}, `{"version": 1, "entries": [{"message": "first"}]}`)
+	if len(entries) != 1 || entries[0].Message != "first" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+	err = JSONParser{Envelope: env}.Parse(strings.NewReader(`{"items": []}`), func(models.LogEntry) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), `"entries"`) {
+		t.Errorf("Expected an error naming the missing key, got %v", err)
+	}
+}
+
+func TestParseEnvelope(t *testing.T) {
+	for path, want := range map[string]string{
+		"$.hits.hits[*]._source": "$.hits.hits[*]._source",
+		"entries":                "$.entries[*]",
+		"data.logs[*]":           "$.data.logs[*]",
+		"$[*].entry":             "$[*].entry",
+	} {
+		env, err := ParseEnvelope(path)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", path, err)
+			continue
+		}
+		if env.String() != want {
+			t.Errorf("Expected %q to parse as %s, got %s", path, want, env)
+		}
+	}
+	for _, path := range []string{"a..b", "a[*].b[*]", "a[0]"} {
+		if _, err := ParseEnvelope(path); err == nil {
+			t.Errorf("Expected an error for %q", path)
+		}
+	}
+}
+
+func TestAutoParserEnvelope(t *testing.T) {
+	inputs := map[string]string{
+		"entries":       `{"entries": [{"level": "ERROR", "service": "api", "message": "boom"}]}`,
+		"elasticsearch": `{"took": 1, "hits": {"hits": [{"_source": {"level": "ERROR", "service": "api", "message": "boom"}}]}}`,
+	}
+	for name, input := range inputs {
+		entries := collect(t, AutoParser{}, input)
+		if len(entries) != 1 || entries[0].Message != "boom" {
+			t.Errorf("Unexpected entries from %s: %+v", name, entries)
+		}
+	}
+
+	// An entry with a field named like an envelope is no envelope
+	entries := collect(t, AutoParser{}, `{"message": "batch", "entries": [{"message": "inner"}]}`+"\n")
+	if len(entries) != 1 || entries[0].Message != "batch" {
+		t.Errorf("Expected the entry itself, got %+v", entries)
+	}
+
+	// A configured envelope applies to the files wrapped in it only
+	env, _ := ParseEnvelope("$.data.items[*]")
+	p := AutoParser{JSON: JSONParser{Envelope: env}}
+	if entries := collect(t, p, `{"data": {"items": [{"message": "wrapped"}]}}`); len(entries) != 1 || entries[0].Message != "wrapped" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+	if entries := collect(t, p, `{"message": "plain"}`+"\n"); len(entries) != 1 || entries[0].Message != "plain" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+}
+
+func TestJSONParserMapping(t *testing.T) {
+	mapping, err := NewFieldMapping(map[string]string{
+		"timestamp":   "$.ts",
+		"level":       "$.severity",
+		"service":     "$.kubernetes.labels.app",
+		"message":     "$.log.msg",
+		"fields.user": "$.ctx.users[0]",
+		"fields.pod":  `$.kubernetes["pod.name"]`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := `{"ts": "2023-01-01 10:00:00", "severity": "error", "kubernetes": {"labels": {"app": "api"}, "pod.name": "api-1"}, "log": {"msg": "boom"}, "ctx": {"users": ["bob", "eve"]}, "fields": {"status": 500}}
+{"ts": "2023-01-01T10:00:01Z", "severity": "info", "log": {"msg": "ok"}, "id": "x1"}`
+	entries := collect(t, JSONParser{Mapping: mapping}, input)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	first := entries[0]
+	if !first.Timestamp.Equal(time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)) || first.Level != "error" || first.Service != "api" || first.Message != "boom" {
+		t.Errorf("Unexpected first entry: %+v", first)
+	}
+	if !reflect.DeepEqual(first.Fields, map[string]string{"user": "bob", "pod": "api-1", "status": "500"}) {
+		t.Errorf("Unexpected fields: %v", first.Fields)
+	}
+	// Unmapped attributes come from their usual keys
+	if entries[1].ID != "x1" || entries[1].Service != "" || entries[1].Fields != nil {
+		t.Errorf("Unexpected second entry: %+v", entries[1])
+	}
+
+	for _, paths := range []map[string]string{
+		{"severity": "$.level"},
+		{"level": "severity"},
+		{"level": "$.a..b"},
+		{"level": "$.a[x]"},
+	} {
+		if _, err := NewFieldMapping(paths); err == nil {
+			t.Errorf("Expected an error for %v", paths)
+		}
+	}
+}
+
+func TestJSONParserEpochTimestamps(t *testing.T) {
+	input := `{"timestamp":1672567200,"level":"INFO","service":"api","message":"seconds"}
+{"timestamp":"1672567200123","level":"INFO","service":"api","message":"millis"}
+{"timestamp":1672567200000000001,"level":"INFO","service":"legacy-api","message":"nanos"}`
+	want := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	expected := []time.Time{want, want.Add(123 * time.Millisecond), want.Add(1)}
+
+	// Services with a layout still accept epoch numbers
+	for _, p := range []JSONParser{{}, {TimestampLayouts: map[string]string{"legacy-api": "02/01/2006 15:04:05"}}} {
+		entries := collect(t, p, input)
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 entries, got %d", len(entries))
+		}
+		for i, entry := range entries {
+			if !entry.Timestamp.Equal(expected[i]) {
+				t.Errorf("Expected %s at %v, got %v", entry.Message, expected[i], entry.Timestamp)
+			}
+		}
+	}
+
+	entries := collect(t, CSVParser{}, "time,message\n1672567200.5,half\n")
+	if len(entries) != 1 || !entries[0].Timestamp.Equal(want.Add(500*time.Millisecond)) {
+		t.Errorf("Unexpected CSV entries: %+v", entries)
+	}
+	// Numbers starting plain text are part of the message
+	entries = collect(t, TextParser{}, "404 not found\n")
+	if len(entries) != 1 || !entries[0].Timestamp.IsZero() || entries[0].Message != "404 not found" {
+		t.Errorf("Unexpected text entries: %+v", entries)
+	}
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// TextParser parses plain text lines, taking a leading timestamp and level
+// such as "2023-01-01T10:00:00Z [ERROR] timeout" from them if present.
+// Indented lines, e.g. of stack traces, continue the message of the line
+// before them.
+type TextParser struct{}
+
+// Parse reads one entry per line that is not indented
+func (TextParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var pending *models.LogEntry
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" {
+			continue
+		}
+		if pending != nil && (line[0] == ' ' || line[0] == '\t') {
+			pending.Message += "\n" + line
+			continue
+		}
+		if pending != nil {
+			if err := emit(*pending); err != nil {
+				return err
+			}
+		}
+		entry := parseTextLine(strings.TrimSpace(line))
+		pending = &entry
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if pending != nil {
+		return emit(*pending)
+	}
+	return nil
+}
+
+// parseTextLine takes the timestamp and level the line starts with, if
+// any, leaving the rest as the message
+func parseTextLine(line string) models.LogEntry {
+	var entry models.LogEntry
+	rest := line
+	first, after, _ := strings.Cut(rest, " ")
+	// Numbers starting a line are not taken for epoch timestamps
+	if ts, ok := parseLayouts(strings.Trim(first, "[]")); ok {
+		entry.Timestamp, rest = ts, after
+	} else if second, after, _ := strings.Cut(after, " "); second != "" {
+		// A date and time separated by a space
+		if ts, ok := parseLayouts(strings.Trim(first+" "+second, "[]")); ok {
+			entry.Timestamp, rest = ts, after
+		}
+	}
+
+	word, after, _ := strings.Cut(strings.TrimLeft(rest, " "), " ")
+	if level, ok := models.ParseLevel(strings.Trim(strings.TrimSuffix(word, ":"), "[]")); ok {
+		entry.Level, rest = level, after
+	}
+	entry.Message = strings.TrimSpace(rest)
+	return entry
+}
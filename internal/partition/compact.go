@@ -0,0 +1,253 @@
+package partition
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// keyLayouts are the time layouts of hourly and daily keys
+var keyLayouts = map[Period]string{Hour: "2006-01-02T15", Day: "2006-01-02"}
+
+// parseKey returns the period and start time named by a partition key
+func parseKey(key string) (Period, time.Time, bool) {
+	for period, layout := range keyLayouts {
+		if len(key) != len(layout) {
+			continue
+		}
+		if start, err := time.Parse(layout, key); err == nil {
+			return period, start, true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+// end returns the end of the partition starting at start
+func (p Period) end(start time.Time) time.Time {
+	if p == Hour {
+		return start.Add(time.Hour)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// Compact moves the partitions that ended before cutoff out of memory into
+// <dir>/<key>.json and returns the number moved. Summaries already written
+// for the same partitions are merged rather than replaced, so entries that
+// arrive late for a compacted partition are added to it on the next
+// compaction. Undated entries stay in memory.
+func (s *Summaries) Compact(dir string, cutoff time.Time) (int, error) {
+	return s.compact(dir, func(key string) bool {
+		period, start, ok := parseKey(key)
+		return ok && !period.end(start).After(cutoff)
+	})
+}
+
+// CompactAll moves all partitions, undated entries included, into dir as
+// Compact does, e.g. on shutdown
+func (s *Summaries) CompactAll(dir string) (int, error) {
+	return s.compact(dir, func(string) bool { return true })
+}
+
+// compact moves the partitions whose keys are selected into dir
+func (s *Summaries) compact(dir string, selected func(key string) bool) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create summary directory: %w", err)
+	}
+
+	s.mu.Lock()
+	compacted := make(map[string]*analyzer.LogAnalyzer)
+	for key, a := range s.analyzers {
+		if selected(key) {
+			compacted[key] = a
+			delete(s.analyzers, key)
+		}
+	}
+	s.mu.Unlock()
+
+	n := 0
+	for key, a := range compacted {
+		if err := mergeFile(filepath.Join(dir, key+".json"), a.GetSummary()); err != nil {
+			// Keep what could not be written for the next compaction
+			s.restore(compacted)
+			return n, err
+		}
+		delete(compacted, key)
+		n++
+	}
+	return n, nil
+}
+
+// restore puts compacted partitions back, merging entries added meanwhile
+func (s *Summaries) restore(compacted map[string]*analyzer.LogAnalyzer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, a := range compacted {
+		if added, ok := s.analyzers[key]; ok {
+			a.Merge(added.GetSummary())
+		}
+		s.analyzers[key] = a
+	}
+}
+
+// Rollup merges the hourly summaries in dir of the days that ended before
+// cutoff into one summary per day, removing the hourly files, and returns
+// the number of days rolled up
+func Rollup(dir string, cutoff time.Time) (int, error) {
+	stored, err := readDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	days := make(map[string][]string)
+	for key := range stored {
+		period, start, _ := parseKey(key)
+		if period != Hour {
+			continue
+		}
+		day := Day.Key(start)
+		if _, dayStart, _ := parseKey(day); !Day.end(dayStart).After(cutoff) {
+			days[day] = append(days[day], key)
+		}
+	}
+
+	for day, hours := range days {
+		total := analyzer.NewLogAnalyzer()
+		for _, hour := range hours {
+			summary, err := readSummary(stored[hour])
+			if err != nil {
+				return 0, err
+			}
+			total.Merge(summary)
+		}
+		if err := mergeFile(filepath.Join(dir, day+".json"), total.GetSummary()); err != nil {
+			return 0, err
+		}
+		for _, hour := range hours {
+			if err := os.Remove(stored[hour]); err != nil {
+				return 0, fmt.Errorf("failed to remove rolled up summary: %w", err)
+			}
+		}
+	}
+	return len(days), nil
+}
+
+// History returns the summaries of the hours and days overlapping the range
+// from to to, both those compacted into dir and those still in memory, in
+// chronological order. A zero from or to leaves that side open.
+func (s *Summaries) History(dir string, from, to time.Time) ([]models.PeriodSummary, error) {
+	stored, err := readDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make(map[string]*models.LogSummary)
+	for key, path := range stored {
+		if !overlaps(key, from, to) {
+			continue
+		}
+		if summaries[key], err = readSummary(path); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range s.Keys() {
+		if !overlaps(key, from, to) {
+			continue
+		}
+		current := s.Summary(key)
+		if current == nil {
+			continue
+		}
+		if compacted, ok := summaries[key]; ok {
+			// Late entries for a partition already compacted
+			merged := analyzer.NewLogAnalyzer()
+			merged.Merge(compacted)
+			merged.Merge(current)
+			current = merged.GetSummary()
+		}
+		summaries[key] = current
+	}
+
+	history := make([]models.PeriodSummary, 0, len(summaries))
+	for key, summary := range summaries {
+		_, start, _ := parseKey(key)
+		history = append(history, models.PeriodSummary{Period: key, Start: start, Summary: summary})
+	}
+	sort.Slice(history, func(i, j int) bool {
+		if !history[i].Start.Equal(history[j].Start) {
+			return history[i].Start.Before(history[j].Start)
+		}
+		// A day sorts before the hours starting with it
+		return len(history[i].Period) < len(history[j].Period)
+	})
+	return history, nil
+}
+
+// overlaps reports whether the partition named key overlaps from to to
+func overlaps(key string, from, to time.Time) bool {
+	period, start, ok := parseKey(key)
+	if !ok {
+		return false
+	}
+	if !from.IsZero() && !period.end(start).After(from) {
+		return false
+	}
+	return to.IsZero() || start.Before(to)
+}
+
+// readDir returns the paths of the partition summaries in dir by key
+func readDir(dir string) (map[string]string, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read summary directory: %w", err)
+	}
+	stored := make(map[string]string)
+	for _, f := range files {
+		key, ok := strings.CutSuffix(f.Name(), ".json")
+		if !ok || f.IsDir() {
+			continue
+		}
+		if _, _, ok := parseKey(key); ok {
+			stored[key] = filepath.Join(dir, f.Name())
+		}
+	}
+	return stored, nil
+}
+
+// readSummary reads a summary written by WriteJSON
+func readSummary(path string) (*models.LogSummary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read summary: %w", err)
+	}
+	var summary models.LogSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to decode summary %s: %w", filepath.Base(path), err)
+	}
+	return &summary, nil
+}
+
+// mergeFile adds summary to the one stored at path, creating it if needed
+func mergeFile(path string, summary *models.LogSummary) error {
+	if _, err := os.Stat(path); err == nil {
+		stored, err := readSummary(path)
+		if err != nil {
+			return err
+		}
+		merged := analyzer.NewLogAnalyzer()
+		merged.Merge(stored)
+		merged.Merge(summary)
+		summary = merged.GetSummary()
+	}
+	return WriteJSON(path, summary)
+}
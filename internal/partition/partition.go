@@ -0,0 +1,137 @@
+package partition
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Period is the length of a time partition
+type Period string
+
+const (
+	Day  Period = "day"
+	Hour Period = "hour"
+)
+
+// Undated is the partition holding entries without a timestamp
+const Undated = "undated"
+
+// ParsePeriod validates a period name
+func ParsePeriod(name string) (Period, error) {
+	switch Period(name) {
+	case Day, Hour:
+		return Period(name), nil
+	default:
+		return "", fmt.Errorf("invalid partition period %q (expected day or hour)", name)
+	}
+}
+
+// Key names the partition containing t, e.g. 2023-01-01 or 2023-01-01T10.
+// Keys sort chronologically. Times are partitioned in UTC.
+func (p Period) Key(t time.Time) string {
+	if t.IsZero() {
+		return Undated
+	}
+	if p == Hour {
+		return t.UTC().Format("2006-01-02T15")
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+// Summaries aggregates entries into a separate summary per partition. It is
+// safe for concurrent use.
+type Summaries struct {
+	period Period
+
+	mu        sync.RWMutex
+	analyzers map[string]*analyzer.LogAnalyzer
+}
+
+// NewSummaries creates an empty set of partitioned summaries
+func NewSummaries(period Period) *Summaries {
+	return &Summaries{period: period, analyzers: make(map[string]*analyzer.LogAnalyzer)}
+}
+
+// Add counts an entry towards the summary of its partition
+func (s *Summaries) Add(entry models.LogEntry) {
+	key := s.period.Key(entry.Timestamp)
+
+	// The read lock is held while processing so Compact cannot move a
+	// partition out while an entry is being added to it
+	s.mu.RLock()
+	a, ok := s.analyzers[key]
+	if ok {
+		a.Process(entry)
+		s.mu.RUnlock()
+		return
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok = s.analyzers[key]; !ok {
+		a = analyzer.NewLogAnalyzer()
+		s.analyzers[key] = a
+	}
+	a.Process(entry)
+}
+
+// Keys returns the partitions seen so far in chronological order, with
+// undated entries last
+func (s *Summaries) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.analyzers))
+	for key := range s.analyzers {
+		if key != Undated {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := s.analyzers[Undated]; ok {
+		keys = append(keys, Undated)
+	}
+	return keys
+}
+
+// Summary returns the summary of one partition, or nil if it has no entries
+func (s *Summaries) Summary(key string) *models.LogSummary {
+	s.mu.Lock()
+	a, ok := s.analyzers[key]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return a.GetSummary()
+}
+
+// Reset discards all partitions
+func (s *Summaries) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.analyzers = make(map[string]*analyzer.LogAnalyzer)
+}
+
+// WriteDir writes each partition's summary to <dir>/<key>.json, replacing
+// earlier files for the same partitions, and returns the number written
+func (s *Summaries) WriteDir(dir string) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create summary directory: %w", err)
+	}
+
+	keys := s.Keys()
+	for _, key := range keys {
+		if err := WriteJSON(filepath.Join(dir, key+".json"), s.Summary(key)); err != nil {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
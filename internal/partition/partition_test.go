@@ -0,0 +1,123 @@
+package partition
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestPeriodKey(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 23, 30, 0, 0, time.FixedZone("CET", -3600))
+	if key := Day.Key(ts); key != "2023-01-02" {
+		t.Errorf("Expected day key in UTC to be 2023-01-02, got %s", key)
+	}
+	if key := Hour.Key(ts); key != "2023-01-02T00" {
+		t.Errorf("Expected hour key to be 2023-01-02T00, got %s", key)
+	}
+	if key := Hour.Key(time.Time{}); key != Undated {
+		t.Errorf("Expected zero time to be undated, got %s", key)
+	}
+
+	if _, err := ParsePeriod("week"); err == nil {
+		t.Error("Expected an error for an unsupported period")
+	}
+}
+
+func TestSummariesWriteDir(t *testing.T) {
+	s := NewSummaries(Hour)
+	s.Add(models.LogEntry{ID: "1", Timestamp: time.Date(2023, 1, 1, 11, 5, 0, 0, time.UTC), Level: models.INFO, Service: "api"})
+	s.Add(models.LogEntry{ID: "2", Timestamp: time.Date(2023, 1, 1, 10, 5, 0, 0, time.UTC), Level: models.ERROR, Service: "db"})
+	s.Add(models.LogEntry{ID: "3", Timestamp: time.Date(2023, 1, 1, 10, 6, 0, 0, time.UTC), Level: models.ERROR, Service: "db"})
+	s.Add(models.LogEntry{ID: "4", Level: models.DEBUG, Service: "cron"})
+
+	dir := t.TempDir()
+	n, err := s.WriteDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to write summaries: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("Expected 3 partitions, got %d", n)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "2023-01-01T10.json"))
+	if err != nil {
+		t.Fatalf("Failed to read partition summary: %v", err)
+	}
+	var summary models.LogSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("Invalid summary JSON: %v", err)
+	}
+	if summary.TotalEntries != 2 || summary.ByLevel[models.ERROR] != 2 {
+		t.Errorf("Unexpected summary for 10:00: %+v", summary)
+	}
+
+	keys := s.Keys()
+	if keys[0] != "2023-01-01T10" || keys[2] != Undated {
+		t.Errorf("Expected chronological keys with undated last, got %v", keys)
+	}
+
+	s.Reset()
+	if len(s.Keys()) != 0 {
+		t.Error("Expected no partitions after Reset")
+	}
+}
+
+func TestSummariesCompact(t *testing.T) {
+	dir := t.TempDir()
+	day := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewSummaries(Hour)
+	s.Add(models.LogEntry{ID: "1", Timestamp: day.Add(10 * time.Hour), Level: models.INFO, Service: "api"})
+	s.Add(models.LogEntry{ID: "2", Timestamp: day.Add(11 * time.Hour), Level: models.ERROR, Service: "api"})
+	s.Add(models.LogEntry{ID: "3", Timestamp: day.Add(25 * time.Hour), Level: models.INFO, Service: "api"})
+
+	// Only the hours that ended before the cutoff leave memory
+	n, err := s.Compact(dir, day.Add(12*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to compact summaries: %v", err)
+	}
+	if n != 2 || len(s.Keys()) != 1 {
+		t.Errorf("Expected 2 hours compacted and 1 kept, got %d and %v", n, s.Keys())
+	}
+
+	// A late entry is added to the compacted hour rather than replacing it
+	s.Add(models.LogEntry{ID: "4", Timestamp: day.Add(10 * time.Hour), Level: models.WARNING, Service: "db"})
+	if _, err := s.Compact(dir, day.Add(12*time.Hour)); err != nil {
+		t.Fatalf("Failed to compact summaries: %v", err)
+	}
+
+	// Hours of finished days are rolled up into one summary per day
+	days, err := Rollup(dir, day.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to roll up summaries: %v", err)
+	}
+	if days != 1 {
+		t.Errorf("Expected 1 day rolled up, got %d", days)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2023-01-01T10.json")); !os.IsNotExist(err) {
+		t.Error("Expected hourly summaries to be removed after the rollup")
+	}
+
+	history, err := s.History(dir, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if len(history) != 2 || history[0].Period != "2023-01-01" || history[1].Period != "2023-01-02T01" {
+		t.Fatalf("Expected the rolled up day and the hour in memory, got %+v", history)
+	}
+	if total := history[0].Summary.TotalEntries; total != 3 {
+		t.Errorf("Expected 3 entries in the day, got %d", total)
+	}
+
+	// The range selects overlapping periods only
+	history, err = s.History(dir, day.Add(24*time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if len(history) != 1 || history[0].Summary.TotalEntries != 1 {
+		t.Errorf("Expected only the second day's hour, got %+v", history)
+	}
+}
@@ -0,0 +1,366 @@
+// Package postgres is a minimal PostgreSQL client speaking the frontend/
+// backend protocol, enough to run statements and bulk load rows with COPY.
+// A Conn is not safe for concurrent use.
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// protocolVersion is protocol 3.0
+const protocolVersion = 196608
+
+// sslRequestCode asks the server to switch to TLS
+const sslRequestCode = 80877103
+
+// maxMessage bounds the size of a message from the server
+const maxMessage = 64 * 1024 * 1024
+
+// Error is an error reported by the server
+type Error struct {
+	Severity string
+	// Code is the SQLSTATE, e.g. "42P01" for an undefined table
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (SQLSTATE %s)", e.Severity, e.Message, e.Code)
+}
+
+// Conn is a connection to a PostgreSQL server
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// Timeout bounds each statement
+	Timeout time.Duration
+}
+
+// Dial connects to the server at rawURL, e.g.
+// "postgres://user:pass@db:5432/logs?sslmode=require". sslmode is disable
+// (the default), require, which encrypts without verifying the server, or
+// verify-full. The password is only sent in cleartext, if the server asks
+// for that, over TLS or with allow_cleartext=true.
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PostgreSQL URL: %w", err)
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return nil, fmt.Errorf("invalid PostgreSQL URL %q: expected postgres://", u.Redacted())
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "5432")
+	}
+	user := "postgres"
+	var pass string
+	if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+	}
+	database := strings.TrimPrefix(u.Path, "/")
+	if database == "" {
+		database = user
+	}
+
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL at %s: %w", host, err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	cleartext := u.Query().Get("allow_cleartext") == "true"
+	switch mode := u.Query().Get("sslmode"); mode {
+	case "", "disable":
+	case "require", "verify-full":
+		cleartext = true
+		if conn, err = startTLS(conn, &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: mode == "require"}); err != nil {
+			return nil, err
+		}
+	default:
+		conn.Close()
+		return nil, fmt.Errorf("unsupported sslmode %q (expected disable, require or verify-full)", mode)
+	}
+
+	c := &Conn{conn: conn, reader: bufio.NewReader(conn), Timeout: 30 * time.Second}
+	if err := c.startup(user, pass, database, cleartext); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return c, nil
+}
+
+// startTLS asks the server to switch conn to TLS
+func startTLS(conn net.Conn, config *tls.Config) (net.Conn, error) {
+	request := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, 8), sslRequestCode)
+	if _, err := conn.Write(request); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to request TLS: %w", err)
+	}
+	answer := make([]byte, 1)
+	if _, err := io.ReadFull(conn, answer); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to request TLS: %w", err)
+	}
+	if answer[0] != 'S' {
+		conn.Close()
+		return nil, fmt.Errorf("server does not support TLS")
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+// startup sends the startup message and authenticates, sending the
+// password in cleartext only if allowed. Once SCRAM has begun, the server
+// must prove it knows the password before the session starts.
+func (c *Conn) startup(user, pass, database string, cleartext bool) error {
+	var body []byte
+	body = binary.BigEndian.AppendUint32(body, protocolVersion)
+	for _, kv := range [][2]string{{"user", user}, {"database", database}, {"application_name", "logprocessor"}, {"client_encoding", "UTF8"}} {
+		body = append(append(body, kv[0]...), 0)
+		body = append(append(body, kv[1]...), 0)
+	}
+	body = append(body, 0)
+	msg := binary.BigEndian.AppendUint32(nil, uint32(len(body)+4))
+	if _, err := c.conn.Write(append(msg, body...)); err != nil {
+		return fmt.Errorf("failed to start PostgreSQL session: %w", err)
+	}
+
+	var scram *scramClient
+	verified := false
+	for {
+		typ, payload, err := c.receive()
+		if err != nil {
+			return fmt.Errorf("failed to start PostgreSQL session: %w", err)
+		}
+		switch typ {
+		case 'R':
+			if len(payload) < 4 {
+				return fmt.Errorf("malformed authentication request")
+			}
+			data := payload[4:]
+			switch method := binary.BigEndian.Uint32(payload); method {
+			case 0:
+				if scram != nil && !verified {
+					return fmt.Errorf("server ended SCRAM authentication without proving its identity")
+				}
+			case 3:
+				if scram != nil {
+					return fmt.Errorf("server switched from SCRAM to cleartext authentication")
+				}
+				if !cleartext {
+					return fmt.Errorf("server asks for the password in cleartext; use sslmode=require or verify-full, or allow_cleartext=true")
+				}
+				err = c.send('p', append([]byte(pass), 0))
+			case 5:
+				if scram != nil {
+					return fmt.Errorf("server switched from SCRAM to MD5 authentication")
+				}
+				if len(data) < 4 {
+					return fmt.Errorf("malformed MD5 authentication request")
+				}
+				err = c.send('p', append([]byte(md5Password(user, pass, data[:4])), 0))
+			case 10:
+				if !strings.Contains(string(data), "SCRAM-SHA-256\x00") {
+					return fmt.Errorf("server offers no supported SASL mechanism")
+				}
+				scram = newSCRAMClient(pass)
+				first := scram.clientFirst()
+				msg := append([]byte("SCRAM-SHA-256\x00"), binary.BigEndian.AppendUint32(nil, uint32(len(first)))...)
+				err = c.send('p', append(msg, first...))
+			case 11:
+				if scram == nil {
+					return fmt.Errorf("unexpected SASL continuation")
+				}
+				final, scramErr := scram.clientFinal(string(data))
+				if scramErr != nil {
+					return scramErr
+				}
+				err = c.send('p', []byte(final))
+			case 12:
+				if scram == nil {
+					return fmt.Errorf("unexpected SASL completion")
+				}
+				err = scram.verifyServer(string(data))
+				verified = err == nil
+			default:
+				return fmt.Errorf("unsupported authentication method %d", method)
+			}
+			if err != nil {
+				return err
+			}
+		case 'E':
+			return parseError(payload)
+		case 'Z':
+			return nil
+		}
+	}
+}
+
+// md5Password computes the response to MD5 authentication
+func md5Password(user, pass string, salt []byte) string {
+	inner := md5.Sum([]byte(pass + user))
+	outer := md5.Sum(append([]byte(hex.EncodeToString(inner[:])), salt...))
+	return "md5" + hex.EncodeToString(outer[:])
+}
+
+// Close ends the session
+func (c *Conn) Close() error {
+	c.send('X', nil)
+	return c.conn.Close()
+}
+
+// Exec runs one or more SQL statements with the simple query protocol and
+// returns the tag of the last completed one, e.g. "INSERT 0 5"
+func (c *Conn) Exec(sql string) (string, error) {
+	return c.run(sql, nil)
+}
+
+// CopyIn runs a COPY ... FROM STDIN statement, sending data, which must be
+// in the format the statement names, and returns the command tag
+func (c *Conn) CopyIn(sql string, data []byte) (string, error) {
+	if data == nil {
+		data = []byte{}
+	}
+	return c.run(sql, data)
+}
+
+// run sends a query and reads its results until the server is ready for
+// the next one. copyData is sent if the server asks for COPY data.
+func (c *Conn) run(sql string, copyData []byte) (string, error) {
+	if c.Timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.Timeout))
+		defer c.conn.SetDeadline(time.Time{})
+	}
+	if err := c.send('Q', append([]byte(sql), 0)); err != nil {
+		return "", err
+	}
+	var tag string
+	var queryErr error
+	for {
+		typ, payload, err := c.receive()
+		if err != nil {
+			return "", fmt.Errorf("failed to read from PostgreSQL: %w", err)
+		}
+		switch typ {
+		case 'C':
+			tag = strings.TrimRight(string(payload), "\x00")
+		case 'E':
+			if queryErr == nil {
+				queryErr = parseError(payload)
+			}
+		case 'G':
+			if copyData == nil {
+				c.send('f', []byte("unexpected COPY\x00"))
+				continue
+			}
+			if err := c.sendCopy(copyData); err != nil {
+				return "", err
+			}
+		case 'Z':
+			return tag, queryErr
+		}
+	}
+}
+
+// sendCopy sends data in CopyData messages followed by CopyDone
+func (c *Conn) sendCopy(data []byte) error {
+	const chunk = 64 * 1024
+	w := bufio.NewWriterSize(c.conn, chunk+5)
+	for len(data) > 0 {
+		n := len(data)
+		if n > chunk {
+			n = chunk
+		}
+		if err := writeMessage(w, 'd', data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	if err := writeMessage(w, 'c', nil); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to send COPY data: %w", err)
+	}
+	return nil
+}
+
+func (c *Conn) send(typ byte, payload []byte) error {
+	if err := writeMessage(c.conn, typ, payload); err != nil {
+		return fmt.Errorf("failed to write to PostgreSQL: %w", err)
+	}
+	return nil
+}
+
+func writeMessage(w io.Writer, typ byte, payload []byte) error {
+	msg := make([]byte, 0, len(payload)+5)
+	msg = append(msg, typ)
+	msg = binary.BigEndian.AppendUint32(msg, uint32(len(payload)+4))
+	_, err := w.Write(append(msg, payload...))
+	return err
+}
+
+// receive reads one message from the server
+func (c *Conn) receive() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size < 4 || size > maxMessage {
+		return 0, nil, fmt.Errorf("invalid message length %d", size)
+	}
+	payload := make([]byte, size-4)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// parseError decodes the fields of an ErrorResponse
+func parseError(payload []byte) error {
+	e := &Error{}
+	for _, field := range strings.Split(string(payload), "\x00") {
+		if field == "" {
+			continue
+		}
+		switch field[0] {
+		case 'S':
+			e.Severity = field[1:]
+		case 'C':
+			e.Code = field[1:]
+		case 'M':
+			e.Message = field[1:]
+		}
+	}
+	return e
+}
+
+// QuoteIdentifier quotes name for use as an SQL identifier
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteLiteral quotes s for use as an SQL string literal
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSCRAM(t *testing.T) {
+	// The example exchange of RFC 7677
+	s := &scramClient{user: "user", password: "pencil", nonce: "rOprNGfwEbeRWgbNEkqO"}
+	if first := s.clientFirst(); first != "n,,n=user,r=rOprNGfwEbeRWgbNEkqO" {
+		t.Errorf("Unexpected client-first-message %q", first)
+	}
+	final, err := s.clientFinal("r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096")
+	if err != nil {
+		t.Fatalf("clientFinal failed: %v", err)
+	}
+	if want := "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ="; final != want {
+		t.Errorf("Expected client-final-message %q, got %q", want, final)
+	}
+	if err := s.verifyServer("v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4="); err != nil {
+		t.Errorf("Expected the server signature to verify, got %v", err)
+	}
+	if err := s.verifyServer("v=AAAA"); err == nil {
+		t.Error("Expected a wrong server signature to be rejected")
+	}
+}
+
+func TestMD5Password(t *testing.T) {
+	// "md5" + md5(hex(md5("secret" + "alice")) + salt)
+	if got := md5Password("alice", "secret", []byte{1, 2, 3, 4}); got != "md598a0412b9c31436fc53776e863350083" {
+		t.Errorf("Unexpected MD5 response %q", got)
+	}
+}
+
+// fakeAuthServer answers the startup of each client with the
+// authentication requests in requests, reading a password message after
+// each except the last, and reports the passwords received on passwords
+func fakeAuthServer(t *testing.T, requests [][]byte, passwords chan<- string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			size := make([]byte, 4)
+			io.ReadFull(r, size)
+			io.ReadFull(r, make([]byte, binary.BigEndian.Uint32(size)-4))
+			for i, request := range requests {
+				msg := binary.BigEndian.AppendUint32([]byte{'R'}, uint32(len(request)+4))
+				conn.Write(append(msg, request...))
+				if i == len(requests)-1 {
+					break
+				}
+				header := make([]byte, 5)
+				if _, err := io.ReadFull(r, header); err != nil {
+					break
+				}
+				payload := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+				io.ReadFull(r, payload)
+				passwords <- strings.TrimSuffix(string(payload), "\x00")
+			}
+			conn.Write([]byte{'Z', 0, 0, 0, 5, 'I'})
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestStartupRejectsSpoofedSCRAM(t *testing.T) {
+	// The server skips the server-final message that proves its identity
+	sasl := append(binary.BigEndian.AppendUint32(nil, 10), "SCRAM-SHA-256\x00\x00"...)
+	addr := fakeAuthServer(t, [][]byte{sasl, binary.BigEndian.AppendUint32(nil, 0)}, make(chan string, 1))
+	conn, err := Dial(context.Background(), "postgres://alice:secret@"+addr+"/logs")
+	if err == nil {
+		conn.Close()
+		t.Fatal("Expected SCRAM without the server's proof to be rejected")
+	}
+	if !strings.Contains(err.Error(), "proving its identity") {
+		t.Errorf("Expected a missing server proof error, got %v", err)
+	}
+}
+
+func TestStartupCleartextPassword(t *testing.T) {
+	passwords := make(chan string, 1)
+	requests := [][]byte{binary.BigEndian.AppendUint32(nil, 3), binary.BigEndian.AppendUint32(nil, 0)}
+	addr := fakeAuthServer(t, requests, passwords)
+
+	if _, err := Dial(context.Background(), "postgres://alice:secret@"+addr+"/logs"); err == nil {
+		t.Error("Expected the cleartext password to be refused without TLS")
+	}
+	if len(passwords) != 0 {
+		t.Errorf("Expected no password sent, got %q", <-passwords)
+	}
+
+	conn, err := Dial(context.Background(), "postgres://alice:secret@"+addr+"/logs?allow_cleartext=true")
+	if err != nil {
+		t.Fatalf("Expected the cleartext password to be sent when allowed, got %v", err)
+	}
+	conn.Close()
+	if got := <-passwords; got != "secret" {
+		t.Errorf("Expected the password secret, got %q", got)
+	}
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// scramClient performs SCRAM-SHA-256 authentication (RFC 7677)
+type scramClient struct {
+	// user is left empty: the server uses the user of the startup message
+	user     string
+	password string
+	nonce    string
+
+	clientFirstBare string
+	serverSignature []byte
+}
+
+func newSCRAMClient(password string) *scramClient {
+	nonce := make([]byte, 18)
+	rand.Read(nonce)
+	return &scramClient{password: password, nonce: base64.RawStdEncoding.EncodeToString(nonce)}
+}
+
+// clientFirst returns the client-first-message
+func (s *scramClient) clientFirst() string {
+	s.clientFirstBare = "n=" + s.user + ",r=" + s.nonce
+	return "n,," + s.clientFirstBare
+}
+
+// clientFinal answers the server-first-message with the client proof
+func (s *scramClient) clientFinal(serverFirst string) (string, error) {
+	var nonce, salt string
+	var iterations int
+	for _, attr := range strings.Split(serverFirst, ",") {
+		key, value, _ := strings.Cut(attr, "=")
+		switch key {
+		case "r":
+			nonce = value
+		case "s":
+			salt = value
+		case "i":
+			iterations, _ = strconv.Atoi(value)
+		}
+	}
+	if !strings.HasPrefix(nonce, s.nonce) || len(nonce) == len(s.nonce) {
+		return "", fmt.Errorf("invalid SCRAM server nonce")
+	}
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil || iterations < 1 {
+		return "", fmt.Errorf("invalid SCRAM server parameters")
+	}
+
+	salted := pbkdf2SHA256([]byte(s.password), saltBytes, iterations)
+	clientKey := hmacSHA256(salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	withoutProof := "c=biws,r=" + nonce
+	authMessage := s.clientFirstBare + "," + serverFirst + "," + withoutProof
+	signature := hmacSHA256(storedKey[:], authMessage)
+	proof := make([]byte, len(clientKey))
+	for i := range proof {
+		proof[i] = clientKey[i] ^ signature[i]
+	}
+	s.serverSignature = hmacSHA256(hmacSHA256(salted, "Server Key"), authMessage)
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(proof), nil
+}
+
+// verifyServer checks the server-final-message, proving the server knows
+// the password too
+func (s *scramClient) verifyServer(serverFinal string) error {
+	v, ok := strings.CutPrefix(strings.TrimSpace(serverFinal), "v=")
+	if !ok {
+		return fmt.Errorf("SCRAM authentication failed: %s", serverFinal)
+	}
+	signature, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || !hmac.Equal(signature, s.serverSignature) {
+		return fmt.Errorf("SCRAM server signature mismatch")
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// pbkdf2SHA256 derives a 32-byte key, the Hi function of SCRAM
+func pbkdf2SHA256(password, salt []byte, iterations int) []byte {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := mac.Sum(nil)
+	result := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range result {
+			result[j] ^= u[j]
+		}
+	}
+	return result
+}
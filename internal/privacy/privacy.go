@@ -0,0 +1,146 @@
+// Package privacy anonymizes summaries for sharing outside the team, so
+// their counts cannot be used to infer the activity of individual users:
+// groups with few entries are suppressed, and counts can be perturbed with
+// Laplace noise as in differential privacy.
+package privacy
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math"
+	mathrand "math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Policy decides how a summary is anonymized
+type Policy struct {
+	// MinCount suppresses every service, level, hour, and counter value with
+	// fewer entries, after noise is added, so each group reported stands
+	// for at least this many entries (k-anonymity)
+	MinCount int
+	// Epsilon adds Laplace noise of scale 1/Epsilon to every count if
+	// positive. Smaller values give more privacy and less accurate counts:
+	// at 0.5 a count is typically off by two, which hides a single user in
+	// a small group but barely changes large ones.
+	Epsilon float64
+}
+
+// Enabled reports whether the policy changes summaries
+func (p Policy) Enabled() bool {
+	return p.MinCount > 1 || p.Epsilon > 0
+}
+
+// Anonymizer applies a policy to summaries
+type Anonymizer struct {
+	policy Policy
+
+	mu  sync.Mutex
+	rng *mathrand.Rand
+}
+
+// NewAnonymizer creates an anonymizer. Its noise is seeded from the
+// operating system, so it cannot be predicted and subtracted.
+func NewAnonymizer(policy Policy) *Anonymizer {
+	var seed [8]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		binary.LittleEndian.PutUint64(seed[:], uint64(time.Now().UnixNano()))
+	}
+	return NewAnonymizerWithSource(policy, mathrand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))
+}
+
+// NewAnonymizerWithSource creates an anonymizer drawing its noise from src,
+// e.g. a seeded source for reproducible tests
+func NewAnonymizerWithSource(policy Policy, src mathrand.Source) *Anonymizer {
+	return &Anonymizer{policy: policy, rng: mathrand.New(src)}
+}
+
+// Anonymize returns a copy of summary with its counts anonymized. Entry
+// accounting and out-of-order statistics are left out, as they report
+// exact counts. The total is perturbed but not suppressed.
+func (a *Anonymizer) Anonymize(summary *models.LogSummary) *models.LogSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Groups draw their noise in a fixed order, so a seeded source gives
+	// the same result every time
+	out := models.NewLogSummary()
+	out.TimeRange = summary.TimeRange
+	out.FailedFiles = summary.FailedFiles
+	out.CorruptArchives = summary.CorruptArchives
+	note := &models.Privacy{MinCount: a.policy.MinCount, Epsilon: a.policy.Epsilon}
+	out.Privacy = note
+
+	out.TotalEntries = a.noisy(summary.TotalEntries)
+	if summary.InferredLevels > 0 {
+		out.InferredLevels = a.noisy(summary.InferredLevels)
+	}
+	for _, level := range sortedKeys(summary.ByLevel) {
+		if n, ok := a.release(summary.ByLevel[level], note); ok {
+			out.ByLevel[level] = n
+		}
+	}
+	for _, service := range sortedKeys(summary.ByService) {
+		if n, ok := a.release(summary.ByService[service], note); ok {
+			out.ByService[service] = n
+		}
+	}
+	hours := make([]time.Time, 0, len(summary.ByHour))
+	for hour := range summary.ByHour {
+		hours = append(hours, hour)
+	}
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Before(hours[j]) })
+	for _, hour := range hours {
+		levels := summary.ByHour[hour]
+		for _, level := range sortedKeys(levels) {
+			if n, ok := a.release(levels[level], note); ok {
+				out.AddHour(hour, level, n)
+			}
+		}
+	}
+	for _, name := range sortedKeys(summary.Counters) {
+		counter := summary.Counters[name]
+		for _, value := range sortedKeys(counter.Counts) {
+			if n, ok := a.release(counter.Counts[value], note); ok {
+				out.AddCounter(name, counter.Label, value, n)
+			}
+		}
+	}
+	return out
+}
+
+// sortedKeys returns the keys of m in ascending order
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// release returns the count to report for a group, or false if the group is
+// suppressed, which note counts
+func (a *Anonymizer) release(count int, note *models.Privacy) (int, bool) {
+	n := a.noisy(count)
+	if n < a.policy.MinCount || n == 0 {
+		note.Suppressed++
+		return 0, false
+	}
+	return n, true
+}
+
+// noisy adds Laplace noise to count if the policy asks for it, rounding to
+// a count that is not negative
+func (a *Anonymizer) noisy(count int) int {
+	if a.policy.Epsilon <= 0 {
+		return count
+	}
+	// Inverse of the Laplace distribution function at a uniform point
+	u := a.rng.Float64() - 0.5
+	noise := -math.Copysign(1/a.policy.Epsilon, u) * math.Log(1-2*math.Abs(u))
+	return max(0, int(math.Round(float64(count)+noise)))
+}
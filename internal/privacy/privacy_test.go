@@ -0,0 +1,85 @@
+package privacy
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func testSummary() *models.LogSummary {
+	summary := models.NewLogSummary()
+	summary.TotalEntries = 1003
+	summary.ByLevel[models.INFO] = 1000
+	summary.ByLevel[models.ERROR] = 3
+	summary.ByService["api"] = 1000
+	summary.ByService["admin"] = 3
+	hour := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	summary.AddHour(hour, models.INFO, 1000)
+	summary.AddHour(hour, models.ERROR, 3)
+	summary.AddCounter("logins", "user", "alice", 2)
+	summary.AddCounter("logins", "user", "bob", 20)
+	summary.Accounting = &models.Accounting{Read: 1003, Analyzed: 1003}
+	return summary
+}
+
+func TestAnonymizeSuppresses(t *testing.T) {
+	out := NewAnonymizer(Policy{MinCount: 5}).Anonymize(testSummary())
+
+	if out.TotalEntries != 1003 {
+		t.Errorf("Expected the exact total without noise, got %d", out.TotalEntries)
+	}
+	if !reflect.DeepEqual(out.ByService, map[string]int{"api": 1000}) {
+		t.Errorf("Expected the small service suppressed, got %v", out.ByService)
+	}
+	if !reflect.DeepEqual(out.ByLevel, map[models.LogLevel]int{models.INFO: 1000}) {
+		t.Errorf("Expected the small level suppressed, got %v", out.ByLevel)
+	}
+	if !reflect.DeepEqual(out.Counters["logins"].Counts, map[string]int{"bob": 20}) {
+		t.Errorf("Expected the small counter value suppressed, got %v", out.Counters["logins"].Counts)
+	}
+	for _, levels := range out.ByHour {
+		if _, ok := levels[models.ERROR]; ok {
+			t.Errorf("Expected the small hour suppressed, got %v", levels)
+		}
+	}
+	if out.Accounting != nil {
+		t.Error("Expected the exact accounting left out")
+	}
+	if out.Privacy == nil || out.Privacy.Suppressed != 4 || out.Privacy.MinCount != 5 {
+		t.Errorf("Expected 4 suppressed groups noted, got %+v", out.Privacy)
+	}
+}
+
+func TestAnonymizeNoise(t *testing.T) {
+	policy := Policy{Epsilon: 0.5}
+	first := NewAnonymizerWithSource(policy, rand.NewSource(1)).Anonymize(testSummary())
+	again := NewAnonymizerWithSource(policy, rand.NewSource(1)).Anonymize(testSummary())
+	if !reflect.DeepEqual(first, again) {
+		t.Error("Expected the same noise from the same seed")
+	}
+
+	// The noise averages out and stays small next to large counts
+	a := NewAnonymizerWithSource(policy, rand.NewSource(2))
+	const runs = 2000
+	sum, changed := 0.0, 0
+	for i := 0; i < runs; i++ {
+		n := a.Anonymize(testSummary()).ByService["api"]
+		if math.Abs(float64(n-1000)) > 50 {
+			t.Fatalf("Expected noise of a few entries, got %d for 1000", n)
+		}
+		if n != 1000 {
+			changed++
+		}
+		sum += float64(n)
+	}
+	if mean := sum / runs; math.Abs(mean-1000) > 0.5 {
+		t.Errorf("Expected the noise to average out, got a mean of %.2f", mean)
+	}
+	if changed < runs/2 {
+		t.Errorf("Expected most counts perturbed, got %d of %d", changed, runs)
+	}
+}
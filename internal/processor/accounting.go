@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Reasons entries are dropped, as reported in models.Accounting
+const (
+	// DropFileError is an entry of a file that failed part way through
+	DropFileError = "file_error"
+	// DropPanic is an entry whose processing panicked
+	DropPanic = "panic"
+	// DropStopped is an entry left unprocessed because the processor stopped
+	DropStopped = "stopped"
+	// DropStale is an entry Serve received older than the age set with
+	// WithMaxAge
+	DropStale = "stale"
+)
+
+// accounting counts what becomes of the entries read
+type accounting struct {
+	read       atomic.Int64
+	skipped    atomic.Int64
+	filtered   atomic.Int64
+	duplicates atomic.Int64
+	analyzed   atomic.Int64
+
+	mu      sync.Mutex
+	dropped map[string]int
+	// matcher counts the duplicates its analyzer found by ID hash only,
+	// matchBase those it had found before this processor ran
+	matcher   hashMatcher
+	matchBase int
+}
+
+// hashMatcher is implemented by analyzers that count the entries they
+// skipped only because the hash of their ID matched a restored one
+type hashMatcher interface {
+	HashMatches() int
+}
+
+// track counts the hash-only duplicates of a from now on, if it counts them
+func (a *accounting) track(an analyzer.Analyzer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if m, ok := an.(hashMatcher); ok {
+		a.matcher, a.matchBase = m, m.HashMatches()
+	}
+}
+
+// drop counts n entries dropped for reason
+func (a *accounting) drop(reason string, n int) {
+	if n <= 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.dropped == nil {
+		a.dropped = make(map[string]int)
+	}
+	a.dropped[reason] += n
+}
+
+// snapshot returns the current counts
+func (a *accounting) snapshot() models.Accounting {
+	a.mu.Lock()
+	var dropped map[string]int
+	if len(a.dropped) > 0 {
+		dropped = make(map[string]int, len(a.dropped))
+		for reason, n := range a.dropped {
+			dropped[reason] = n
+		}
+	}
+	var hashMatches int
+	if a.matcher != nil {
+		hashMatches = a.matcher.HashMatches() - a.matchBase
+	}
+	a.mu.Unlock()
+	return models.Accounting{
+		Read:        int(a.read.Load()),
+		Skipped:     int(a.skipped.Load()),
+		Filtered:    int(a.filtered.Load()),
+		Duplicates:  int(a.duplicates.Load()),
+		HashMatches: hashMatches,
+		Dropped:     dropped,
+		Analyzed:    int(a.analyzed.Load()),
+	}
+}
+
+// Accounting returns what became of the entries this processor has read so
+// far. Entries still queued or being processed are unaccounted until done.
+func (p *LogProcessor) Accounting() models.Accounting {
+	return p.accounting.snapshot()
+}
@@ -0,0 +1,213 @@
+package processor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/charset"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// ArchiveError reports a compressed input file or archive that is truncated
+// or corrupt.
+// Entries read before the damage are still analyzed.
+type ArchiveError struct {
+	// Reason describes the damage, e.g. "truncated" or "checksum mismatch"
+	Reason string
+	Err    error
+}
+
+func (e *ArchiveError) Error() string {
+	return "corrupt archive: " + e.Reason
+}
+
+func (e *ArchiveError) Unwrap() error {
+	return e.Err
+}
+
+// archiveError classifies an error from a decompressor, returning nil for
+// errors that do not mean the archive is damaged
+func archiveError(err error) *ArchiveError {
+	var corrupt flate.CorruptInputError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return &ArchiveError{Reason: "truncated", Err: err}
+	case errors.Is(err, gzip.ErrChecksum), errors.Is(err, zip.ErrChecksum):
+		return &ArchiveError{Reason: "checksum mismatch", Err: err}
+	case errors.Is(err, gzip.ErrHeader), errors.Is(err, tar.ErrHeader):
+		return &ArchiveError{Reason: "invalid header", Err: err}
+	case errors.Is(err, zip.ErrFormat), errors.Is(err, zip.ErrAlgorithm):
+		return &ArchiveError{Reason: "invalid zip archive", Err: err}
+	case errors.As(err, &corrupt):
+		return &ArchiveError{Reason: "corrupt data", Err: err}
+	default:
+		return nil
+	}
+}
+
+// checkedReader remembers the first error of the reader it wraps, so damage
+// is reported however the parser wraps or swallows the error
+type checkedReader struct {
+	r   io.Reader
+	err error
+}
+
+func (c *checkedReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	if err != nil && err != io.EOF && c.err == nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// archivePatterns select the archives in the input directory whose files are
+// processed in addition to the ones matching the pattern, with WithArchives
+var archivePatterns = []string{"*.zip", "*.tar", "*.tar.gz", "*.tgz"}
+
+// WithArchives also reads the zip and tar archives in the input directory,
+// processing the files inside them that match the pattern. An input that is
+// a single archive is read without it.
+func WithArchives() Option {
+	return func(lp *LogProcessor) {
+		lp.archives = true
+	}
+}
+
+// isArchive reports whether path is an archive of log files
+func isArchive(path string) bool {
+	for _, pattern := range archivePatterns {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readArchive parses the files in a zip or tar archive whose names match the
+// pattern, without extracting them. Each file's source is the archive name
+// joined with its path inside the archive. A file that fails to parse is
+// reported and the rest of the archive is still read.
+func (p *LogProcessor) readArchive(r readerAt, size int64, filePath string, entries *[]models.LogEntry) error {
+	name := p.sourceName(filePath)
+	if strings.HasSuffix(name, ".zip") {
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			if ae := archiveError(err); ae != nil {
+				return ae
+			}
+			return fmt.Errorf("failed to open zip archive: %w", err)
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() || !p.matchInner(f.Name) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				if ae := archiveError(err); ae != nil {
+					return ae
+				}
+				return fmt.Errorf("failed to open %s: %w", f.Name, err)
+			}
+			err = p.readInner(rc, name+"/"+f.Name, entries)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var stream io.Reader = r
+	if strings.HasSuffix(name, "gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			if ae := archiveError(err); ae != nil {
+				return ae
+			}
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		stream = gz
+	}
+	tr := tar.NewReader(stream)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ae := archiveError(err); ae != nil {
+				return ae
+			}
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !p.matchInner(hdr.Name) {
+			continue
+		}
+		if err := p.readInner(tr, name+"/"+hdr.Name, entries); err != nil {
+			return err
+		}
+	}
+}
+
+// matchInner reports whether a file inside an archive is a log file, i.e.
+// its name matches the pattern with or without a .gz suffix
+func (p *LogProcessor) matchInner(name string) bool {
+	base := path.Base(name)
+	for _, candidate := range []string{base, strings.TrimSuffix(base, ".gz")} {
+		if ok, _ := filepath.Match(p.pattern, candidate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readInner parses one file inside an archive. Damage to the archive ends
+// the traversal; a file that merely fails to parse is reported on its own.
+func (p *LogProcessor) readInner(r io.Reader, source string, entries *[]models.LogEntry) error {
+	checked := &checkedReader{r: r}
+	var in io.Reader = checked
+	if strings.HasSuffix(source, ".gz") {
+		gz, err := gzip.NewReader(checked)
+		if err != nil {
+			if ae := archiveError(err); ae != nil {
+				return ae
+			}
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		in = gz
+	}
+
+	in, err := charset.NewReader(in, p.encoding)
+	if err != nil {
+		return err
+	}
+	before := len(*entries)
+	err = p.parser.Parse(in, p.collect(source, entries))
+	if err == nil {
+		// Checksums are only verified at the end of the stream
+		_, err = io.Copy(io.Discard, in)
+	}
+	if ae := archiveError(checked.err); ae != nil {
+		return ae
+	}
+	if err != nil {
+		// Like a plain file, a file that fails to parse contributes nothing
+		*entries = (*entries)[:before]
+		fmt.Printf("Error processing file %s: %v\n", source, err)
+		p.metrics.Count("parse_errors", 1, "source:"+source)
+		p.failures.Store(source, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package processor
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/cgroup"
+)
+
+// Autoscaling tuning
+const (
+	// scaleInterval is how often the backlog is checked
+	scaleInterval = 100 * time.Millisecond
+	// scaleUpBacklog is the fill of the processing channel above which
+	// workers are added
+	scaleUpBacklog = 0.5
+	// scaleMaxCPU is the CPU utilization above which adding workers would
+	// only make them contend
+	scaleMaxCPU = 0.9
+	// scaleDownTicks is how many checks in a row the channel must be empty
+	// before a worker is retired
+	scaleDownTicks = 10
+)
+
+// WithAutoscaling scales the workers between min and max: the pool grows
+// while entries back up in the processing channel and CPU time is to spare,
+// and shrinks again while the channel stays empty
+func WithAutoscaling(min, max int) Option {
+	return func(lp *LogProcessor) {
+		if min < 1 {
+			min = 1
+		}
+		if max < min {
+			max = min
+		}
+		lp.workers, lp.maxWorkers = min, max
+	}
+}
+
+// workerPool tracks the workers processing entries
+type workerPool struct {
+	wg     sync.WaitGroup
+	n      atomic.Int64
+	peak   atomic.Int64
+	retire chan struct{}
+	// workers holds the *workerState of every running worker, and epoch
+	// is the time their states count from
+	workers sync.Map
+	epoch   time.Time
+	// stopScaler stops the autoscaler, if one runs
+	stopScaler func()
+}
+
+// Wait stops scaling and waits for the workers to exit, which they do once
+// the processing channel is closed
+func (w *workerPool) Wait() {
+	w.stopScaler()
+	w.wg.Wait()
+}
+
+// add starts n workers
+func (p *LogProcessor) addWorkers(n int) {
+	pool := p.pool
+	for i := 0; i < n; i++ {
+		pool.wg.Add(1)
+		if count := pool.n.Add(1); count > pool.peak.Load() {
+			pool.peak.Store(count)
+		}
+		w := &workerState{}
+		pool.workers.Store(w, struct{}{})
+		go func() {
+			defer func() {
+				// A worker the watchdog replaced is no longer counted
+				if w.since.Load() != detached {
+					pool.workers.Delete(w)
+					pool.n.Add(-1)
+					pool.wg.Done()
+				}
+			}()
+			p.worker(w)
+		}()
+	}
+}
+
+// autoscale adjusts the number of workers every scaleInterval until the
+// returned function is called
+func (p *LogProcessor) autoscale() func() {
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	// Created before returning, so a fake clock advanced next ticks it
+	ticker := p.clock.NewTicker(scaleInterval)
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		cpu := newCPUMeter()
+		idle := 0
+		for {
+			select {
+			case <-ticker.C():
+			case <-stop:
+				return
+			}
+			backlog := float64(len(p.processingCh)) / float64(cap(p.processingCh))
+			if p.priorityCh != nil {
+				backlog = max(backlog, float64(len(p.priorityCh))/float64(cap(p.priorityCh)))
+			}
+			if backlog == 0 {
+				idle++
+			} else {
+				idle = 0
+			}
+			n := int(p.pool.n.Load())
+			switch delta := scaleDecision(n, p.workers, p.maxWorkers, backlog, cpu.utilization(), idle); {
+			case delta > 0:
+				p.addWorkers(delta)
+			case delta < 0:
+				select {
+				case p.pool.retire <- struct{}{}:
+					idle = 0
+				default:
+					// Every worker is busy after all
+				}
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// scaleDecision returns how many workers to add, or -1 to retire one, given
+// n running workers, the fill of the channel, the CPU utilization (negative
+// if unknown) and the number of checks the channel has been empty for
+func scaleDecision(n, min, max int, backlog, cpu float64, idle int) int {
+	switch {
+	case backlog > scaleUpBacklog && n < max && cpu < scaleMaxCPU:
+		// Double, so large machines are used within a few checks
+		if n > max-n {
+			return max - n
+		}
+		return n
+	case idle >= scaleDownTicks && n > min:
+		return -1
+	}
+	return 0
+}
+
+// cpuMeter measures the utilization of the CPUs available to the process
+type cpuMeter struct {
+	cpus     float64
+	lastCPU  time.Duration
+	lastWall time.Time
+}
+
+func newCPUMeter() *cpuMeter {
+	used, _ := processCPUTime()
+	return &cpuMeter{cpus: cgroup.AvailableCPUs(), lastCPU: used, lastWall: time.Now()}
+}
+
+// utilization returns the share of the available CPUs, GOMAXPROCS or the
+// container's CPU quota, the process used since the last call, or -1 if the
+// platform cannot tell
+func (m *cpuMeter) utilization() float64 {
+	used, ok := processCPUTime()
+	if !ok {
+		return -1
+	}
+	now := time.Now()
+	wall := now.Sub(m.lastWall)
+	cpu := used - m.lastCPU
+	m.lastCPU, m.lastWall = used, now
+	if wall <= 0 {
+		return 0
+	}
+	return cpu.Seconds() / (wall.Seconds() * m.cpus)
+}
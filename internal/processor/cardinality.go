@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// OtherValue replaces the values of a service or field beyond its
+// cardinality limit
+const OtherValue = "__other__"
+
+// WithCardinalityLimit keeps at most max distinct services, and values of
+// each of the named fields, in the entries that are analyzed and forwarded
+// to sinks. Later values are replaced with OtherValue, so a source inventing
+// a service name per request cannot exhaust memory or swamp the reports.
+// Values seen first are kept, and a value once kept is kept for good, so
+// every entry of a service is either kept under its name or counted as
+// OtherValue. The first value replaced prints a warning and each counts in
+// the collapsed_values metric.
+func WithCardinalityLimit(max int, fields ...string) Option {
+	return func(lp *LogProcessor) {
+		lp.cardinality = newCardinalityLimit(max, fields)
+	}
+}
+
+// cardinalityLimit tracks the distinct values of the service and the
+// guarded fields
+type cardinalityLimit struct {
+	max     int
+	service *distinctValues
+	fields  map[string]*distinctValues
+}
+
+// distinctValues holds the values of one service or field kept so far
+type distinctValues struct {
+	name string
+
+	mu     sync.RWMutex
+	values map[string]struct{}
+	warned bool
+}
+
+func newCardinalityLimit(max int, fields []string) *cardinalityLimit {
+	c := &cardinalityLimit{
+		max:     max,
+		service: &distinctValues{name: "service", values: make(map[string]struct{})},
+		fields:  make(map[string]*distinctValues, len(fields)),
+	}
+	for _, field := range fields {
+		c.fields[field] = &distinctValues{name: "fields." + field, values: make(map[string]struct{})}
+	}
+	return c
+}
+
+// limitCardinality replaces the service and guarded fields of entry beyond
+// the limit with OtherValue
+func (p *LogProcessor) limitCardinality(entry models.LogEntry) models.LogEntry {
+	c := p.cardinality
+	if c == nil || c.max <= 0 {
+		return entry
+	}
+	if !p.admit(c.service, entry.Service) {
+		entry.Service = OtherValue
+	}
+	copied := false
+	for field, values := range c.fields {
+		value, ok := entry.Fields[field]
+		if !ok || p.admit(values, value) {
+			continue
+		}
+		if !copied {
+			// Leave the fields of the entry as parsed untouched
+			fields := make(map[string]string, len(entry.Fields))
+			for k, v := range entry.Fields {
+				fields[k] = v
+			}
+			entry.Fields, copied = fields, true
+		}
+		entry.Fields[field] = OtherValue
+	}
+	return entry
+}
+
+// admit reports whether value is kept, recording it if there is room
+func (p *LogProcessor) admit(d *distinctValues, value string) bool {
+	d.mu.RLock()
+	_, ok := d.values[value]
+	d.mu.RUnlock()
+	if ok {
+		return true
+	}
+
+	d.mu.Lock()
+	if _, ok := d.values[value]; ok {
+		d.mu.Unlock()
+		return true
+	}
+	if len(d.values) < p.cardinality.max {
+		d.values[value] = struct{}{}
+		d.mu.Unlock()
+		return true
+	}
+	warn := !d.warned
+	d.warned = true
+	d.mu.Unlock()
+
+	if warn {
+		fmt.Printf("Warning: more than %d distinct values of %s, counting further ones as %s\n", p.cardinality.max, d.name, OtherValue)
+	}
+	p.metrics.Count("collapsed_values", 1, "key:"+d.name)
+	return false
+}
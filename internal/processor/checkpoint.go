@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/crypt"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/sink"
+)
+
+// DefaultCheckpointEvery is the number of entries between checkpoint commits
+const DefaultCheckpointEvery = 10000
+
+// checkpoint records how many entries of each input file reached the sinks
+type checkpoint struct {
+	path  string
+	every int
+	// cipher encrypts the file, if set
+	cipher *crypt.Cipher
+
+	mu    sync.Mutex
+	files map[string]fileOffset
+}
+
+// fileOffset is the progress through one input file
+type fileOffset struct {
+	// Size is the file size when the offset was committed. A smaller file
+	// was replaced and is read from the start.
+	Size    int64 `json:"size"`
+	Entries int   `json:"entries"`
+}
+
+// WithCheckpoint gives at-least-once delivery to the sinks. Each file's
+// entries are processed in order by its reader, and every n entries and at
+// the end of the file the sinks are flushed and the number of delivered
+// entries is committed to the JSON file at path. Only then does the
+// checkpoint advance, so entries are forwarded again after a crash rather
+// than lost. A later run skips the entries already committed, e.g. the part
+// of a log that was there before it grew. Checkpoints apply to Start only.
+func WithCheckpoint(path string, n int) Option {
+	return func(lp *LogProcessor) {
+		if n <= 0 {
+			n = DefaultCheckpointEvery
+		}
+		lp.checkpoint = &checkpoint{path: path, every: n}
+	}
+}
+
+// load reads the committed offsets, starting afresh if there are none yet
+func (c *checkpoint) load() error {
+	c.files = make(map[string]fileOffset)
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if data, err = c.cipher.Open(data); err != nil {
+		return fmt.Errorf("failed to read checkpoint %s: %w", c.path, err)
+	}
+	if err := json.Unmarshal(data, &c.files); err != nil {
+		return fmt.Errorf("failed to decode checkpoint %s: %w", c.path, err)
+	}
+	return nil
+}
+
+// offset returns the number of entries of file already delivered
+func (c *checkpoint) offset(file string, size int64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	off, ok := c.files[file]
+	if !ok || size < off.Size {
+		return 0
+	}
+	return off.Entries
+}
+
+// commit records that the first n entries of file were delivered
+func (c *checkpoint) commit(file string, size int64, n int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[file] = fileOffset{Size: size, Entries: n}
+
+	data, err := json.MarshalIndent(c.files, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, c.cipher.Seal(append(data, '\n')), 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// deliver processes the entries of a file in order, committing the
+// checkpoint as the sinks confirm them. A failed write stops the checkpoint
+// from advancing for the rest of the file.
+func (p *LogProcessor) deliver(filePath string, entries []models.LogEntry) error {
+	var size int64
+	if info, err := os.Stat(filePath); err == nil {
+		size = info.Size()
+	}
+	start := p.checkpoint.offset(filePath, size)
+	if start > len(entries) {
+		// Rewritten with fewer entries
+		start = 0
+	}
+	p.accounting.skipped.Add(int64(start))
+
+	var failed error
+	commit := func(n int) {
+		if failed == nil {
+			if err := p.flushSinks(); err != nil {
+				failed = err
+			} else if err := p.checkpoint.commit(filePath, size, n); err != nil {
+				failed = err
+			}
+		}
+	}
+	for i := start; i < len(entries); i++ {
+		select {
+		case <-p.done:
+			p.accounting.drop(DropStopped, len(entries)-i)
+			return nil
+		default:
+		}
+		p.inflight.add()
+		delivered := p.process(entries[i])
+		p.inflight.done()
+		if !delivered && failed == nil {
+			failed = fmt.Errorf("failed to deliver entry %s", entries[i].ID)
+		}
+		if (i+1-start)%p.checkpoint.every == 0 {
+			commit(i + 1)
+		}
+	}
+	if n := len(entries) - start; n > 0 && n%p.checkpoint.every != 0 {
+		commit(len(entries))
+	}
+	if failed != nil {
+		return fmt.Errorf("%w, checkpoint not advanced", failed)
+	}
+	return nil
+}
+
+// flushSinks flushes all sinks, returning the first error
+func (p *LogProcessor) flushSinks() error {
+	for _, s := range p.sinks {
+		if err := sink.Flush(s); err != nil {
+			return fmt.Errorf("failed to flush sink: %w", err)
+		}
+	}
+	return nil
+}
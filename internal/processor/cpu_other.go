@@ -0,0 +1,10 @@
+//go:build !unix
+
+package processor
+
+import "time"
+
+// processCPUTime cannot measure CPU time on this platform
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// TimeWindow returns middleware that keeps only the entries from since up to
+// but excluding until. A zero bound leaves that side open. Entries without a
+// timestamp are dropped, as they cannot be placed in the window.
+func TimeWindow(since, until time.Time) Middleware {
+	return func(entry models.LogEntry) (models.LogEntry, bool) {
+		if entry.Timestamp.IsZero() {
+			return entry, false
+		}
+		if !since.IsZero() && entry.Timestamp.Before(since) {
+			return entry, false
+		}
+		if !until.IsZero() && !entry.Timestamp.Before(until) {
+			return entry, false
+		}
+		return entry, true
+	}
+}
+
+// OnlyServices returns middleware that keeps only the entries of the named
+// services
+func OnlyServices(names ...string) Middleware {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+	return func(entry models.LogEntry) (models.LogEntry, bool) {
+		return entry, keep[entry.Service]
+	}
+}
+
+// filter applies the middleware and the cardinality limit to an entry,
+// reporting whether it is kept
+func (p *LogProcessor) filter(entry models.LogEntry) (models.LogEntry, bool) {
+	for i, mw := range p.middleware {
+		timer := p.stages.middlewareTimer(i)
+		started := timer.start()
+		var keep bool
+		entry, keep = mw(entry)
+		timer.stop(started, 1)
+		if !keep {
+			return entry, false
+		}
+	}
+	return p.limitCardinality(entry), true
+}
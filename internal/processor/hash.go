@@ -0,0 +1,34 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// fileHash is the digest of the part of an input file that was read
+type fileHash struct {
+	sum  string
+	size int64
+}
+
+// WithFileHashes computes the SHA-256 of every input file, reported in its
+// FileResult, so a run can prove which content it analyzed. The digest
+// covers the bytes read, which for files still being written may be only
+// the part complete when reading started.
+func WithFileHashes() Option {
+	return func(lp *LogProcessor) {
+		lp.hashFiles = true
+	}
+}
+
+// hashFile records the digest of the first size bytes of r for filePath
+func (p *LogProcessor) hashFile(filePath string, r io.ReaderAt, size int64) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return fmt.Errorf("failed to hash file: %w", err)
+	}
+	p.hashes.Store(filePath, fileHash{sum: hex.EncodeToString(h.Sum(nil)), size: size})
+	return nil
+}
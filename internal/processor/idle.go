@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// inflight counts the entries queued for or being processed by the workers
+type inflight struct {
+	mu sync.Mutex
+	n  int
+	// total counts the entries done
+	total int64
+	// idle is closed whenever n drops to zero
+	idle chan struct{}
+}
+
+// add records an entry about to be queued
+func (f *inflight) add() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.n == 0 {
+		f.idle = make(chan struct{})
+	}
+	f.n++
+}
+
+// done records an entry that has been processed, or was never queued
+func (f *inflight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.total++
+	if f.n--; f.n == 0 {
+		close(f.idle)
+	}
+}
+
+// pending returns the number of entries in flight
+func (f *inflight) pending() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.n
+}
+
+// finished returns the number of entries done so far
+func (f *inflight) finished() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.total
+}
+
+// wait blocks until no entries are in flight or ctx is done
+func (f *inflight) wait(ctx context.Context) error {
+	f.mu.Lock()
+	if f.n == 0 {
+		f.mu.Unlock()
+		return nil
+	}
+	idle := f.idle
+	f.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetSummaryContext returns the summary once the pipeline is quiescent: no
+// entries are waiting in the processing channel and the workers are idle.
+// Every entry read before the call is then included. If ctx ends first, it
+// returns the summary at that moment together with ctx's error. Entries
+// still being parsed from a file are not in flight yet, and a steady stream
+// of network input may never leave the pipeline idle, so callers should
+// give ctx a deadline.
+func (p *LogProcessor) GetSummaryContext(ctx context.Context) (*models.LogSummary, error) {
+	err := p.inflight.wait(ctx)
+	return p.GetSummary(), err
+}
@@ -0,0 +1,153 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// InUsePolicy decides what happens to input files that are still being
+// written, e.g. in a live log directory
+type InUsePolicy string
+
+const (
+	// InUseProcess reads files as they are, without checking
+	InUseProcess InUsePolicy = "process"
+	// InUseSkip leaves files that are still being written for a later run
+	InUseSkip InUsePolicy = "skip"
+	// InUseWait waits until files have stopped changing
+	InUseWait InUsePolicy = "wait"
+	// InUsePrefix reads the complete lines already written
+	InUsePrefix InUsePolicy = "prefix"
+)
+
+// DefaultQuietPeriod is how long a file must stay unchanged to count as
+// complete
+const DefaultQuietPeriod = 2 * time.Second
+
+// ErrFileInUse is reported for files skipped because they are still being
+// written
+var ErrFileInUse = errors.New("file is still being written")
+
+// ParseInUsePolicy validates a policy name
+func ParseInUsePolicy(name string) (InUsePolicy, error) {
+	switch policy := InUsePolicy(name); policy {
+	case InUseProcess, InUseSkip, InUseWait, InUsePrefix:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown in-use policy %q (supported: process, skip, wait, prefix)", name)
+	}
+}
+
+// WithInUsePolicy checks whether input files are still being written before
+// reading them. A file counts as in use while another process holds a lock on
+// it or while it changes within the quiet period. Compressed files cannot be
+// read partially, so the prefix policy skips them.
+func WithInUsePolicy(policy InUsePolicy, quiet time.Duration) Option {
+	return func(lp *LogProcessor) {
+		lp.inUse = policy
+		lp.quietPeriod = quiet
+	}
+}
+
+// stableSize applies the in-use policy to file and returns how many bytes of
+// it to read
+func (p *LogProcessor) stableSize(ctx context.Context, file *os.File, size int64, compressed bool) (int64, error) {
+	if p.inUse == "" || p.inUse == InUseProcess {
+		return size, nil
+	}
+
+	for {
+		inUse, current, err := p.checkInUse(ctx, file)
+		if err != nil {
+			return 0, err
+		}
+		if !inUse {
+			return current, nil
+		}
+
+		switch p.inUse {
+		case InUseSkip:
+			return 0, ErrFileInUse
+		case InUsePrefix:
+			if compressed {
+				return 0, ErrFileInUse
+			}
+			return lastNewline(file, current)
+		}
+		// InUseWait checks again; checkInUse has already waited a quiet period
+	}
+}
+
+// checkInUse reports whether file is locked or changes within the quiet
+// period, along with its latest size
+func (p *LogProcessor) checkInUse(ctx context.Context, file *os.File) (bool, int64, error) {
+	quiet := p.quietPeriod
+	if quiet <= 0 {
+		quiet = DefaultQuietPeriod
+	}
+
+	before, err := file.Stat()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if lockHeld(file) {
+		if p.inUse == InUseWait {
+			if err := p.sleep(ctx, quiet); err != nil {
+				return false, 0, err
+			}
+		}
+		return true, before.Size(), nil
+	}
+
+	// Files untouched for the quiet period are complete without waiting
+	wait := quiet - p.clock.Now().Sub(before.ModTime())
+	if wait <= 0 {
+		return false, before.Size(), nil
+	}
+	if err := p.sleep(ctx, wait); err != nil {
+		return false, 0, err
+	}
+
+	after, err := file.Stat()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	changed := after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime())
+	return changed, after.Size(), nil
+}
+
+// sleep waits for d or until ctx is done
+func (p *LogProcessor) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-p.clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lastNewline returns the length of the complete lines in the first size
+// bytes of r
+func lastNewline(r io.ReaderAt, size int64) (int64, error) {
+	buf := make([]byte, 64*1024)
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+		n, err := r.ReadAt(buf[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, fmt.Errorf("failed to read file: %w", err)
+		}
+		if i := bytes.LastIndexByte(buf[:n], '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"regexp"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// levelKeywords are checked in order, so the most severe match wins
+var levelKeywords = []struct {
+	level   models.LogLevel
+	pattern *regexp.Regexp
+}{
+	{models.FATAL, regexp.MustCompile(`(?i)\b(panic|fatal|critical|emergency)\b`)},
+	{models.ERROR, regexp.MustCompile(`(?i)\b(errors?|failed|failure|exception|traceback)\b`)},
+	{models.WARNING, regexp.MustCompile(`(?i)\b(warn|warning|deprecated|retrying)\b`)},
+	{models.DEBUG, regexp.MustCompile(`(?i)\b(debug|trace)\b`)},
+}
+
+// InferLevel returns middleware that derives a level for entries without
+// one from keywords in their message, e.g. "failed" means ERROR. Entries
+// with no keyword get defaultLevel, or keep an empty level if defaultLevel
+// is empty. Derived levels are flagged with LevelInferred.
+func InferLevel(defaultLevel models.LogLevel) Middleware {
+	return func(entry models.LogEntry) (models.LogEntry, bool) {
+		if entry.Level != "" {
+			return entry, true
+		}
+		level := defaultLevel
+		for _, kw := range levelKeywords {
+			if kw.pattern.MatchString(entry.Message) {
+				level = kw.level
+				break
+			}
+		}
+		if level != "" {
+			entry.Level = level
+			entry.LevelInferred = true
+		}
+		return entry, true
+	}
+}
+
+// NormalizeLevel returns middleware that replaces level names and aliases
+// registered with models, in any case, by the level they name, e.g. "warn"
+// by WARNING. Other levels are left as they are.
+func NormalizeLevel() Middleware {
+	return func(entry models.LogEntry) (models.LogEntry, bool) {
+		if level, ok := models.ParseLevel(string(entry.Level)); ok {
+			entry.Level = level
+		}
+		return entry, true
+	}
+}
+
+// MinLevel returns middleware that drops the entries less severe than min.
+// Entries with an unregistered level are kept, as their severity is
+// unknown.
+func MinLevel(min models.LogLevel) Middleware {
+	return func(entry models.LogEntry) (models.LogEntry, bool) {
+		if entry.Level.Severity() == 0 {
+			return entry, true
+		}
+		return entry, entry.Level.Compare(min) >= 0
+	}
+}
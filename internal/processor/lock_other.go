@@ -0,0 +1,10 @@
+//go:build !unix
+
+package processor
+
+import "os"
+
+// lockHeld cannot detect locks on this platform
+func lockHeld(file *os.File) bool {
+	return false
+}
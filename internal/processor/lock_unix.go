@@ -0,0 +1,18 @@
+//go:build unix
+
+package processor
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockHeld reports whether another process holds an exclusive lock on file
+func lockHeld(file *os.File) bool {
+	fd := int(file.Fd())
+	if err := syscall.Flock(fd, syscall.LOCK_SH|syscall.LOCK_NB); err != nil {
+		return err == syscall.EWOULDBLOCK
+	}
+	syscall.Flock(fd, syscall.LOCK_UN)
+	return false
+}
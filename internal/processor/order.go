@@ -0,0 +1,61 @@
+package processor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// orderTracker detects entries read out of timestamp order, per source. It
+// sees entries in the order they were read, before the workers reorder them.
+type orderTracker struct {
+	mu      sync.Mutex
+	latest  map[string]time.Time
+	sources map[string]models.OrderStats
+}
+
+func newOrderTracker() *orderTracker {
+	return &orderTracker{
+		latest:  make(map[string]time.Time),
+		sources: make(map[string]models.OrderStats),
+	}
+}
+
+// observe records an entry and reports whether it was out of order
+func (o *orderTracker) observe(entry models.LogEntry) bool {
+	if entry.Timestamp.IsZero() {
+		return false
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	latest, ok := o.latest[entry.Source]
+	if !ok || entry.Timestamp.After(latest) {
+		o.latest[entry.Source] = entry.Timestamp
+		return false
+	}
+	displacement := latest.Sub(entry.Timestamp)
+	if displacement == 0 {
+		return false
+	}
+	o.sources[entry.Source] = o.sources[entry.Source].Add(models.OrderStats{Count: 1, MaxDisplacement: displacement})
+	return true
+}
+
+// addTo adds the statistics to a summary
+func (o *orderTracker) addTo(summary *models.LogSummary) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.sources) == 0 {
+		return
+	}
+	if summary.OutOfOrder == nil {
+		summary.OutOfOrder = make(map[string]models.OrderStats, len(o.sources))
+	}
+	for source, stats := range o.sources {
+		summary.OutOfOrder[source] = summary.OutOfOrder[source].Add(stats)
+	}
+}
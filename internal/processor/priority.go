@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// WithPriority gives entries Serve receives at level or above a lane of their
+// own, which workers drain before the regular queue. During a storm of
+// low-severity entries, errors then reach the analyzer, and thus alerting,
+// without waiting behind the backlog. Prioritized entries may be analyzed
+// and forwarded to sinks ahead of entries received before them.
+func WithPriority(level models.LogLevel) Option {
+	return func(lp *LogProcessor) {
+		lp.priority = level
+	}
+}
+
+// startPriority creates the priority lane, with the capacity of the
+// processing channel, if a priority level is set
+func (p *LogProcessor) startPriority() {
+	if p.priority.Severity() > 0 && p.priorityCh == nil {
+		p.priorityCh = make(chan models.LogEntry, cap(p.processingCh))
+	}
+}
+
+// lane returns the channel entry is queued on
+func (p *LogProcessor) lane(entry models.LogEntry) chan models.LogEntry {
+	if p.priorityCh == nil {
+		return p.processingCh
+	}
+	if severity := entry.Level.Severity(); severity > 0 && severity >= p.priority.Severity() {
+		p.prioritized.Add(1)
+		return p.priorityCh
+	}
+	return p.processingCh
+}
+
+// closeLanes closes the processing channel and the priority lane once every
+// producer is finished, so the workers drain them and exit
+func (p *LogProcessor) closeLanes() {
+	close(p.processingCh)
+	if p.priorityCh != nil {
+		close(p.priorityCh)
+	}
+}
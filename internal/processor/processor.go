@@ -1,144 +1,733 @@
 package processor
 
 import (
-	"encoding/json"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/charset"
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/cluster"
+	"github.com/interview/junior-go-challenge/internal/crypt"
 	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/sink"
 )
 
 // LogProcessor processes log files and aggregates statistics
 type LogProcessor struct {
-	analyzer      *analyzer.LogAnalyzer
-	inputDir      string
-	batchSize     int
-	processingCh  chan models.LogEntry
-	// BUG: The done channel is closed but never used properly
-	done          chan struct{}
+	analyzer     analyzer.Analyzer
+	inputDir     string
+	batchSize    int
+	processingCh chan models.LogEntry
+	done         chan struct{}
+	stopOnce     sync.Once
+	parser       parser.Parser
+	pattern      string
+	sinks        []sink.Sink
+	sections     []analyzer.Section
+	// streamed are the sections fed as entries are read, second feeds the
+	// order-dependent ones once all input is read
+	streamed   []analyzer.Section
+	second     *secondPass
+	middleware []Middleware
+	ordering   *orderTracker
+	metrics    Metrics
+	snapshots  *snapshots
+	checkpoint *checkpoint
+	// cipher encrypts the checkpoint file, if set
+	cipher *crypt.Cipher
+	// decodeWorkers is the number of parallel parts large files are split into
+	decodeWorkers int
+	// mmap reads input files through memory mappings
+	mmap bool
+	// fileTimeout limits the time spent reading one file
+	fileTimeout time.Duration
+	// encoding is the character encoding of the input, detected if empty
+	encoding string
+	// inUse decides how files still being written are read
+	inUse       InUsePolicy
+	quietPeriod time.Duration
+	// failures holds the error of every file that could not be processed
+	failures sync.Map
+	// corrupt holds the damage found in truncated or corrupt archives
+	corrupt sync.Map
+	// results holds the FileResult of every input file by path
+	results sync.Map
+	// undelivered holds the sources of entries a sink did not accept
+	undelivered sync.Map
+	// shard selects the input files to process, if set
+	shard *cluster.Shard
+	// hashFiles computes the digest of every input file into hashes
+	hashFiles bool
+	hashes    sync.Map
+	// inflight counts the entries between the processing channel and the
+	// end of their processing
+	inflight inflight
+	// accounting counts what becomes of the entries read
+	accounting accounting
+	// clock times files and schedules snapshots and in-use checks
+	clock clock.Clock
+	// workers is the number of entries processed concurrently, or the
+	// minimum if maxWorkers is larger
+	workers    int
+	maxWorkers int
+	pool       *workerPool
+	// priority is the level from which Serve queues entries on priorityCh
+	priority    models.LogLevel
+	priorityCh  chan models.LogEntry
+	prioritized atomic.Int64
+	// stallTimeout and onStall configure the watchdog, progress counts the
+	// entries parsed and files finished for it, and filesPending the files
+	// not yet finished
+	stallTimeout time.Duration
+	onStall      func(Stall) bool
+	progress     atomic.Int64
+	filesPending atomic.Int64
+	// recursive scans the subdirectories of the input, skipped holds the
+	// paths the scan left out
+	recursive bool
+	skipped   sync.Map
+	// archives also selects the archives in the input
+	archives bool
+	// sourceNames decides how input files are named in entries
+	sourceNames SourceNames
+	// maxAge is the age from which Serve drops entries, if set
+	maxAge time.Duration
+	// cardinality bounds the distinct services and field values, if set
+	cardinality *cardinalityLimit
+	// readers and parsers are the number of files read and parsed at once
+	readers int
+	parsers int
+	// stages times the stages of the pipeline, if enabled
+	stages stageMetrics
+}
+
+// chunkMinSize is the file size from which files are decoded in parallel
+const chunkMinSize = 32 << 20
+
+// Metrics receives counters while entries are processed, e.g. a statsd client
+type Metrics interface {
+	Count(name string, value int64, tags ...string)
+}
+
+// noMetrics discards all counters
+type noMetrics struct{}
+
+func (noMetrics) Count(string, int64, ...string) {}
+
+// Middleware transforms an entry before it is analyzed. Returning false drops
+// the entry. Middleware runs concurrently on several workers.
+type Middleware func(entry models.LogEntry) (models.LogEntry, bool)
+
+// Option configures a LogProcessor
+type Option func(*LogProcessor)
+
+// WithParser sets the parser used to read input files. Defaults to JSON.
+func WithParser(p parser.Parser) Option {
+	return func(lp *LogProcessor) {
+		lp.parser = p
+	}
+}
+
+// WithPattern sets the glob used to select input files. Defaults to *.json.
+func WithPattern(pattern string) Option {
+	return func(lp *LogProcessor) {
+		lp.pattern = pattern
+	}
+}
+
+// WithSink forwards every newly analyzed entry to s. Sinks are closed when
+// processing finishes.
+func WithSink(s sink.Sink) Option {
+	return func(lp *LogProcessor) {
+		lp.sinks = append(lp.sinks, s)
+	}
+}
+
+// WithAnalyzer aggregates into an existing analyzer, e.g. to accumulate
+// results across repeated runs or to plug in custom aggregation. Entries it
+// reports as already seen are skipped.
+func WithAnalyzer(a analyzer.Analyzer) Option {
+	return func(lp *LogProcessor) {
+		lp.analyzer = a
+	}
+}
+
+// WithSection also feeds every new entry to s, so several analyses share a
+// single read of the input
+func WithSection(s analyzer.Section) Option {
+	return func(lp *LogProcessor) {
+		lp.sections = append(lp.sections, s)
+	}
+}
+
+// WithCipher encrypts the checkpoint file with c. Checkpoints written
+// without encryption are still read.
+func WithCipher(c *crypt.Cipher) Option {
+	return func(lp *LogProcessor) {
+		lp.cipher = c
+	}
+}
+
+// WithMetrics reports entry, duplicate and parse error counters to m
+func WithMetrics(m Metrics) Option {
+	return func(lp *LogProcessor) {
+		lp.metrics = m
+	}
+}
+
+// WithDecodeWorkers decodes uncompressed files of at least 32 MiB in n
+// parallel parts when the parser supports it
+func WithDecodeWorkers(n int) Option {
+	return func(lp *LogProcessor) {
+		lp.decodeWorkers = n
+	}
+}
+
+// WithMmap reads input files through memory mappings where the platform
+// supports it, falling back to regular reads otherwise
+func WithMmap(enabled bool) Option {
+	return func(lp *LogProcessor) {
+		lp.mmap = enabled
+	}
+}
+
+// WithEncoding sets the character encoding of input files, such as
+// charset.UTF16LE, instead of detecting it. Input is converted to UTF-8.
+func WithEncoding(name string) Option {
+	return func(lp *LogProcessor) {
+		lp.encoding = name
+	}
+}
+
+// WithClock sets the clock timing files, snapshots and in-use checks.
+// Defaults to the system clock; tests use a fake one.
+func WithClock(c clock.Clock) Option {
+	return func(lp *LogProcessor) {
+		lp.clock = c
+	}
+}
+
+// WithWorkers sets a fixed number of entries processed concurrently.
+// Defaults to 5; a single worker delivers entries to sinks in the order they
+// are read.
+func WithWorkers(n int) Option {
+	return func(lp *LogProcessor) {
+		if n > 0 {
+			lp.workers, lp.maxWorkers = n, 0
+		}
+	}
+}
+
+// WithQueueSize sets how many parsed entries may wait for a worker. Defaults
+// to 1000.
+func WithQueueSize(n int) Option {
+	return func(lp *LogProcessor) {
+		if n > 0 {
+			lp.processingCh = make(chan models.LogEntry, n)
+		}
+	}
 }
 
 // NewLogProcessor creates a new log processor
-func NewLogProcessor(inputDir string) *LogProcessor {
-	return &LogProcessor{
+func NewLogProcessor(inputDir string, opts ...Option) *LogProcessor {
+	p := &LogProcessor{
 		analyzer:     analyzer.NewLogAnalyzer(),
 		inputDir:     inputDir,
 		batchSize:    100,
 		processingCh: make(chan models.LogEntry, 1000),
 		done:         make(chan struct{}),
+		parser:       parser.JSONParser{},
+		pattern:      "*.json",
+		metrics:      noMetrics{},
+		ordering:     newOrderTracker(),
+		clock:        clock.System,
+		workers:      5,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
 }
 
-// Start begins processing log files
+// setDefaults fills in the clock and pool sizes of processors not created
+// by NewLogProcessor
+func (p *LogProcessor) setDefaults() {
+	if p.clock == nil {
+		p.clock = clock.System
+	}
+	if p.workers <= 0 {
+		p.workers = 5
+	}
+	if p.readers <= 0 {
+		p.readers = defaultReaders
+	}
+	if p.parsers <= 0 {
+		p.parsers = defaultParsers()
+	}
+}
+
+// Use appends middleware to the chain run on every entry before analysis, in
+// the order added. It must be called before Start or Serve.
+func (p *LogProcessor) Use(mw ...Middleware) {
+	p.middleware = append(p.middleware, mw...)
+}
+
+// Start processes all log files in the input directory and returns once
+// every entry has been analyzed or the processor has been stopped
 func (p *LogProcessor) Start() error {
-	files, err := filepath.Glob(filepath.Join(p.inputDir, "*.json"))
+	if p.parser == nil {
+		p.parser = parser.JSONParser{}
+	}
+	if p.pattern == "" {
+		p.pattern = "*.json"
+	}
+	if p.metrics == nil {
+		p.metrics = noMetrics{}
+	}
+	if p.ordering == nil {
+		p.ordering = newOrderTracker()
+	}
+	p.setDefaults()
+	p.accounting.track(p.analyzer)
+
+	files, err := p.inputFiles()
 	if err != nil {
-		return fmt.Errorf("failed to find log files: %w", err)
+		return err
+	}
+	if p.checkpoint != nil {
+		p.checkpoint.cipher = p.cipher
+		if err := p.checkpoint.load(); err != nil {
+			return err
+		}
 	}
 
 	if len(files) == 0 {
 		return fmt.Errorf("no log files found in directory: %s", p.inputDir)
 	}
+	// A shard owning none of the files still finishes, with an empty summary
+	files = p.ownedFiles(files)
 
-	var wg sync.WaitGroup
+	workers := p.startWorkers()
+	stopSnapshots := p.startSnapshots()
+	defer stopSnapshots()
+	stopWatchdog := p.startWatchdog()
+	defer stopWatchdog()
 
-	// Start the workers to process log entries
-	// BUG: No tracking of these workers, might lead to goroutine leaks
-	for i := 0; i < 5; i++ {
-		go p.worker()
+	p.readFiles(files)
+	// All producers are finished, so the workers can drain the channel and exit
+	p.closeLanes()
+	workers.Wait()
+
+	if err := p.finishSecondPass(); err != nil {
+		p.closeSinks()
+		return err
+	}
+	return p.closeSinks()
+}
+
+// inputFiles returns the files to process: the log files, and archives if
+// enabled, in the input directory, and its subdirectories if recursive, or the input
+// itself if it is a single file
+func (p *LogProcessor) inputFiles() ([]string, error) {
+	if info, err := os.Stat(p.inputDir); err == nil && info.Mode().IsRegular() {
+		return []string{p.inputDir}, nil
+	}
+	if p.recursive {
+		return p.walkInput()
 	}
 
-	// Process each file
-	for _, file := range files {
-		wg.Add(1)
-		// BUG: Capturing loop variable in goroutine
-		go func(file string) {
-			defer wg.Done()
-			err := p.processFile(file)
-			if err != nil {
-				fmt.Printf("Error processing file %s: %v\n", file, err)
+	patterns := []string{p.pattern}
+	if p.archives {
+		patterns = append(patterns, archivePatterns...)
+	}
+	var files []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(p.inputDir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find log files: %w", err)
+		}
+		for _, file := range matches {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
 			}
-		}(file)
+		}
 	}
+	return files, nil
+}
 
-	wg.Wait()
+// processFile parses a file fetched by a reader and sends its entries to the
+// processing channel, returning the number of entries read
+func (p *LogProcessor) processFile(f *fetchedFile) (int, error) {
+	ctx, cancel := p.fileContext()
+	defer cancel()
 
-	// BUG: Channel is never closed, leading to goroutine leaks
-	// Should close the processing channel after all files are processed
-	// close(p.processingCh)
+	// Files too large to buffer are still read while parsing, and reads
+	// from a stuck mount cannot be interrupted, so parsing happens in a
+	// goroutine that is abandoned on timeout
+	type result struct {
+		entries []models.LogEntry
+		err     error
+	}
+	read := make(chan result, 1)
+	go func() {
+		started := p.stages.parse.start()
+		entries, err := p.parseFile(ctx, f)
+		f.Close()
+		p.stages.parse.stop(started, len(entries))
+		p.stages.read.count(len(entries))
+		read <- result{entries, err}
+	}()
 
-	// Simulate waiting for processing to complete
-	time.Sleep(100 * time.Millisecond)
+	var entries []models.LogEntry
+	var archiveErr *ArchiveError
+	select {
+	case r := <-read:
+		// Entries before the damage to an archive are still analyzed
+		if r.err != nil && !errors.As(r.err, &archiveErr) && ctx.Err() == nil {
+			p.accounting.read.Add(int64(len(r.entries)))
+			p.accounting.drop(DropFileError, len(r.entries))
+			return 0, r.err
+		}
+		entries = r.entries
+		p.accounting.read.Add(int64(len(entries)))
+	case <-ctx.Done():
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return 0, fmt.Errorf("%w after %v", ErrFileTimeout, p.fileTimeout)
+	}
+	if ctx.Err() != nil {
+		// Stopped
+		return 0, nil
+	}
+
+	if p.checkpoint != nil {
+		if err := p.deliver(f.path, entries); err != nil {
+			return len(entries), err
+		}
+		if archiveErr != nil {
+			return len(entries), archiveErr
+		}
+		return len(entries), nil
+	}
+
+	// Process entries in batches
+	for i := 0; i < len(entries); i += p.batchSize {
+		end := i + p.batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		batch := entries[i:end]
+
+		// Send each entry to the processing channel, giving up if stopped
+		for j, entry := range batch {
+			p.inflight.add()
+			sending := p.stages.now()
+			select {
+			case p.processingCh <- entry:
+				p.stages.waited(sending)
+			case <-p.done:
+				p.inflight.done()
+				p.accounting.drop(DropStopped, len(entries)-(i+j))
+				return i + j, nil
+			}
+		}
+	}
 
-	return nil
+	if archiveErr != nil {
+		return len(entries), archiveErr
+	}
+	return len(entries), nil
 }
 
-// processFile reads a log file and sends entries to the processing channel
-func (p *LogProcessor) processFile(filePath string) error {
-	file, err := os.Open(filePath)
+// readFile fetches and parses a log file, stopping early once ctx is done
+func (p *LogProcessor) readFile(ctx context.Context, filePath string) ([]models.LogEntry, error) {
+	f, err := p.fetchFile(ctx, filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
-	defer file.Close()
+	defer f.Close()
+	return p.parseFile(ctx, f)
+}
 
-	fileName := filepath.Base(filePath)
+// parseFile parses a fetched file, stopping early once ctx is done
+func (p *LogProcessor) parseFile(ctx context.Context, f *fetchedFile) ([]models.LogEntry, error) {
+	filePath, size, compressed := f.path, f.size, f.compressed
+	var r readerAt = ctxReader{ctx: ctx, r: f.r}
+	if p.hashFiles {
+		if err := p.hashFile(filePath, r, size); err != nil {
+			return nil, err
+		}
+	}
 
 	var entries []models.LogEntry
-	decoder := json.NewDecoder(file)
-	for {
-		var entry models.LogEntry
-		if err := decoder.Decode(&entry); err != nil {
-			if err == io.EOF {
-				break
+	if f.archive {
+		err := p.readArchive(r, size, filePath, &entries)
+		return entries, err
+	}
+	emit := p.collect(p.sourceName(filePath), &entries)
+	cp, chunked := p.parser.(parser.ChunkParser)
+
+	var err error
+	switch {
+	case !compressed && !p.plainUTF8(r):
+		// Converted input can only be read sequentially
+		var in io.Reader
+		if in, err = charset.NewReader(io.NewSectionReader(r, 0, size), p.encoding); err == nil {
+			err = p.parser.Parse(in, emit)
+		}
+	case compressed:
+		gz, gzErr := gzip.NewReader(r)
+		if gzErr != nil {
+			if ae := archiveError(gzErr); ae != nil {
+				return nil, ae
 			}
-			return fmt.Errorf("failed to decode entry: %w", err)
+			return nil, fmt.Errorf("failed to open gzip stream: %w", gzErr)
+		}
+		defer gz.Close()
+		checked := &checkedReader{r: gz}
+		var in io.Reader
+		if in, err = charset.NewReader(checked, p.encoding); err == nil {
+			err = p.parser.Parse(in, emit)
 		}
-		
-		// Set the source to the filename
-		entry.Source = fileName
-		entries = append(entries, entry)
+		if err == nil {
+			// The checksum is only verified at the end of the stream
+			_, err = io.Copy(io.Discard, in)
+		}
+		if ae := archiveError(checked.err); ae != nil {
+			return entries, ae
+		}
+	case chunked && p.decodeWorkers > 1 && size >= chunkMinSize:
+		err = cp.ParseChunks(r, size, p.decodeWorkers, emit)
+	default:
+		err = p.parser.Parse(r, emit)
 	}
+	if err != nil {
+		// The entries parsed before the error are returned for accounting
+		return entries, err
+	}
+	return entries, nil
+}
 
-	// Process entries in batches
-	for i := 0; i < len(entries); i += p.batchSize {
-		end := i + p.batchSize
-		if end > len(entries) {
-			end = len(entries)
+// plainUTF8 reports whether the file can be parsed without conversion, i.e.
+// is UTF-8 without a byte order mark
+func (p *LogProcessor) plainUTF8(r io.ReaderAt) bool {
+	head := make([]byte, 4096)
+	n, _ := r.ReadAt(head, 0)
+	detected, bom := charset.Detect(head[:n])
+	if p.encoding == "" || p.encoding == charset.Auto {
+		return detected == charset.UTF8 && bom == 0
+	}
+	return p.encoding == charset.UTF8 && !(detected == charset.UTF8 && bom > 0)
+}
+
+// collect returns an emit function that appends the entries of source
+func (p *LogProcessor) collect(source string, entries *[]models.LogEntry) func(models.LogEntry) error {
+	n := 0
+	return func(entry models.LogEntry) error {
+		n++
+		entry.Source = source
+		// Formats without IDs get one from their position so dedup keeps them apart
+		if entry.ID == "" {
+			entry.ID = fmt.Sprintf("%s:%d", source, n)
 		}
-		batch := entries[i:end]
-		
-		// Send each entry to the processing channel
-		for _, entry := range batch {
-			// BUG: No check if the channel is closed
-			// BUG: Doesn't handle blocking when the channel is full
-			p.processingCh <- entry
+		if p.ordering.observe(entry) {
+			p.metrics.Count("out_of_order", 1, "source:"+source)
 		}
+		*entries = append(*entries, entry)
+		p.progress.Add(1)
+		return nil
 	}
+}
+
+// startWorkers starts the workers that process log entries, and the
+// autoscaler if the pool may grow. They exit once the processing channel is
+// closed.
+func (p *LogProcessor) startWorkers() *workerPool {
+	p.startSecondPass()
+	p.stages.setup(p)
+
+	p.pool = &workerPool{retire: make(chan struct{}), stopScaler: func() {}, epoch: p.clock.Now()}
+	p.addWorkers(p.workers)
+	if p.maxWorkers > p.workers {
+		p.pool.stopScaler = p.autoscale()
+	}
+	return p.pool
+}
+
+// worker processes log entries from the priority lane, if any, and the
+// processing channel until they are closed or the autoscaler retires the
+// worker
+func (p *LogProcessor) worker(w *workerState) {
+	queue, priority := p.processingCh, p.priorityCh
+	for queue != nil || priority != nil {
+		// The priority lane goes ahead of any backlog in the queue
+		select {
+		case entry, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			if !p.handle(w, entry) {
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case entry, ok := <-priority:
+			if !ok {
+				priority = nil
+				continue
+			}
+			if !p.handle(w, entry) {
+				return
+			}
+		case entry, ok := <-queue:
+			if !ok {
+				queue = nil
+				continue
+			}
+			p.stages.observeDepth(len(p.processingCh))
+			if !p.handle(w, entry) {
+				return
+			}
+		case <-p.pool.retire:
+			return
+		}
+	}
+}
+
+// process analyzes a single entry, recovering from panics so one bad entry
+// cannot take down the worker. It reports whether every sink accepted the
+// entry; filtered entries and duplicates count as delivered.
+func (p *LogProcessor) process(entry models.LogEntry) (delivered bool) {
+	accounted := false
+	defer func() {
+		if !delivered {
+			p.undelivered.Store(entry.Source, true)
+		}
+	}()
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Printf("Recovered from panic processing entry %s: %v\n", entry.ID, r)
+			if !accounted {
+				p.accounting.drop(DropPanic, 1)
+			}
+			delivered = false
+		}
+	}()
+	entry, keep := p.filter(entry)
+	if !keep {
+		accounted = true
+		p.accounting.filtered.Add(1)
+		p.metrics.Count("filtered", 1)
+		return true
+	}
+
+	started := p.stages.analyze.start()
+	unique := p.analyzer.Process(entry)
+	p.stages.analyze.stop(started, 1)
+	if !unique {
+		accounted = true
+		p.accounting.duplicates.Add(1)
+		p.metrics.Count("duplicates", 1)
+		return true
+	}
+	accounted = true
+	p.accounting.analyzed.Add(1)
+	p.metrics.Count("entries", 1, "level:"+string(entry.Level), "service:"+entry.Service)
+	p.countSnapshot()
 
-	return nil
+	for i, s := range p.streamed {
+		timer := p.stages.sectionTimer(i)
+		started := timer.start()
+		s.Observe(entry)
+		timer.stop(started, 1)
+	}
+	if p.second != nil {
+		started := p.stages.secondPass.start()
+		if err := p.second.write(entry); err != nil {
+			fmt.Printf("Error recording entry %s for the second pass: %v\n", entry.ID, err)
+		}
+		p.stages.secondPass.stop(started, 1)
+	}
+
+	delivered = true
+	for i, s := range p.sinks {
+		timer := p.stages.sinkTimer(i)
+		started := timer.start()
+		if err := s.Write(entry); err != nil {
+			fmt.Printf("Error writing entry %s to sink: %v\n", entry.ID, err)
+			delivered = false
+		}
+		timer.stop(started, 1)
+	}
+	return delivered
 }
 
-// worker processes log entries from the processing channel
-func (p *LogProcessor) worker() {
-	// BUG: No graceful shutdown mechanism
-	for entry := range p.processingCh {
-		// Process the entry
-		p.analyzer.Process(entry)
+// closeSinks closes all sinks, returning the first error
+func (p *LogProcessor) closeSinks() error {
+	var firstErr error
+	for _, s := range p.sinks {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close sink: %w", err)
+		}
 	}
+	return firstErr
 }
 
-// GetSummary returns the current log summary
+// Sections returns the additional analyses fed by this processor
+func (p *LogProcessor) Sections() []analyzer.Section {
+	return p.sections
+}
+
+// GetSummary returns the current log summary, including the ordering
+// statistics of the entries read by this processor and the files it failed
+// to process
 func (p *LogProcessor) GetSummary() *models.LogSummary {
-	return p.analyzer.GetSummary()
+	summary := p.analyzer.GetSummary()
+	accounting := p.accounting.snapshot()
+	if summary.Accounting != nil {
+		// Restored or merged from earlier runs
+		accounting = summary.Accounting.Add(accounting)
+	}
+	summary.Accounting = &accounting
+	if p.ordering != nil {
+		p.ordering.addTo(summary)
+	}
+	p.failures.Range(func(file, reason any) bool {
+		if summary.FailedFiles == nil {
+			summary.FailedFiles = make(map[string]string)
+		}
+		summary.FailedFiles[file.(string)] = reason.(string)
+		return true
+	})
+	p.corrupt.Range(func(file, reason any) bool {
+		if summary.CorruptArchives == nil {
+			summary.CorruptArchives = make(map[string]string)
+		}
+		summary.CorruptArchives[file.(string)] = reason.(string)
+		return true
+	})
+	return summary
 }
 
-// Stop gracefully stops the processor
+// Stop gracefully stops the processor. It is safe to call more than once.
 func (p *LogProcessor) Stop() {
-	// BUG: Closing an already closed channel will panic
-	close(p.done)
-}
\ No newline at end of file
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+}
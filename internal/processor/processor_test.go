@@ -1,17 +1,34 @@
 package processor
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+	"unicode/utf16"
 
 	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/cluster"
+	"github.com/interview/junior-go-challenge/internal/crypt"
 	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
 )
 
 func createSampleLogs(t *testing.T, dir string) {
@@ -217,7 +234,7 @@ func TestProcessorGracefulShutdown(t *testing.T) {
 	// Check for goroutine leaks
 	finalGoroutines := runtime.NumGoroutine()
 	if finalGoroutines > initialGoroutines {
-		t.Errorf("Goroutine leak detected: started with %d, ended with %d", 
+		t.Errorf("Goroutine leak detected: started with %d, ended with %d",
 			initialGoroutines, finalGoroutines)
 	}
 }
@@ -314,7 +331,7 @@ func TestProcessorConcurrentFiles(t *testing.T) {
 
 	// Create and start processor
 	processor := NewLogProcessor(tempDir)
-	
+
 	if err := processor.Start(); err != nil {
 		t.Fatalf("Failed to start processor: %v", err)
 	}
@@ -357,7 +374,7 @@ func TestProcessorWorkerPanic(t *testing.T) {
 
 	// Create processor with a worker that might panic
 	processor := NewLogProcessor(tempDir)
-	
+
 	// Start processing
 	err = processor.Start()
 	if err != nil {
@@ -369,4 +386,1558 @@ func TestProcessorWorkerPanic(t *testing.T) {
 	if summary.TotalEntries == 0 {
 		t.Error("No entries were processed")
 	}
-}
\ No newline at end of file
+}
+
+// fakeSource emits a fixed set of entries and then waits for cancellation
+type fakeSource struct {
+	entries []models.LogEntry
+}
+
+func (f fakeSource) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	for _, entry := range f.entries {
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// gatedSink blocks writes until open is closed, signalling the first one
+type gatedSink struct {
+	open    chan struct{}
+	started chan struct{}
+	once    sync.Once
+}
+
+func (g *gatedSink) Write(models.LogEntry) error {
+	g.once.Do(func() { close(g.started) })
+	<-g.open
+	return nil
+}
+
+func (g *gatedSink) Close() error { return nil }
+
+func TestProcessorGetSummaryContext(t *testing.T) {
+	gate := &gatedSink{open: make(chan struct{}), started: make(chan struct{})}
+	processor := NewLogProcessor("", WithSink(gate))
+
+	source := fakeSource{}
+	for i := 0; i < 20; i++ {
+		source.entries = append(source.entries, models.LogEntry{Level: models.INFO, Service: "net", Source: "fake"})
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- processor.Serve(source)
+	}()
+	defer func() {
+		processor.Stop()
+		<-done
+	}()
+
+	// The workers are stuck in the sink with entries still queued
+	<-gate.started
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := processor.GetSummaryContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to time out while entries are queued, got %v", err)
+	}
+
+	close(gate.open)
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	summary, err := processor.GetSummaryContext(ctx)
+	if err != nil {
+		t.Fatalf("Expected the pipeline to become idle, got %v", err)
+	}
+	if summary.TotalEntries != 20 {
+		t.Errorf("Expected 20 entries once idle, got %d", summary.TotalEntries)
+	}
+}
+
+func TestProcessorServe(t *testing.T) {
+	processor := NewLogProcessor("")
+
+	// Entries without IDs must not be collapsed by deduplication
+	source := fakeSource{}
+	for i := 0; i < 50; i++ {
+		source.entries = append(source.entries, models.LogEntry{
+			Level:   models.INFO,
+			Service: "net",
+			Source:  "fake",
+		})
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- processor.Serve(source, source)
+	}()
+
+	// Wait until both sources have been fully analyzed, then stop
+	deadline := time.Now().Add(2 * time.Second)
+	for processor.GetSummary().TotalEntries < 100 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	processor.Stop()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Serve returned an error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not return after Stop")
+	}
+
+	if total := processor.GetSummary().TotalEntries; total != 100 {
+		t.Errorf("Expected 100 entries, got %d", total)
+	}
+}
+
+func TestProcessorServeAfterRestore(t *testing.T) {
+	source := fakeSource{}
+	for i := 0; i < 10; i++ {
+		source.entries = append(source.entries, models.LogEntry{Level: models.INFO, Service: "net", Source: "fake"})
+	}
+	serve := func(a *analyzer.LogAnalyzer) {
+		processor := NewLogProcessor("", WithAnalyzer(a))
+		done := make(chan error, 1)
+		go func() {
+			done <- processor.Serve(source)
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		processor.GetSummaryContext(ctx)
+		processor.Stop()
+		if err := <-done; err != nil {
+			t.Fatalf("Serve returned an error: %v", err)
+		}
+	}
+
+	first := analyzer.NewLogAnalyzer()
+	serve(first)
+	state := first.Export()
+
+	// A new process numbers entries from the start again
+	serveSeq, serveBoot = 0, newBootID()
+	restored := analyzer.NewLogAnalyzer()
+	if err := restored.Import(state); err != nil {
+		t.Fatalf("Failed to import state: %v", err)
+	}
+	serve(restored)
+	if total := restored.GetSummary().TotalEntries; total != 20 {
+		t.Errorf("Expected the new entries to be counted on top of the restored ones, got %d", total)
+	}
+}
+
+// customerAnalyzer counts entries per customer on top of the standard summary
+type customerAnalyzer struct {
+	*analyzer.LogAnalyzer
+
+	mu         sync.Mutex
+	byCustomer map[string]int
+}
+
+func (c *customerAnalyzer) Process(entry models.LogEntry) bool {
+	if !c.LogAnalyzer.Process(entry) {
+		return false
+	}
+	c.mu.Lock()
+	c.byCustomer[entry.Fields["customer"]]++
+	c.mu.Unlock()
+	return true
+}
+
+func TestProcessorCustomAnalyzer(t *testing.T) {
+	tempDir := t.TempDir()
+	file, err := os.Create(filepath.Join(tempDir, "billing.json"))
+	if err != nil {
+		t.Fatalf("Failed to create sample log file: %v", err)
+	}
+	encoder := json.NewEncoder(file)
+	for i := 0; i < 30; i++ {
+		entry := models.LogEntry{
+			ID:      fmt.Sprintf("bill-%d", i),
+			Level:   models.INFO,
+			Service: "api",
+			Fields:  map[string]string{"customer": fmt.Sprintf("c%d", i%3)},
+		}
+		if err := encoder.Encode(entry); err != nil {
+			t.Fatalf("Failed to encode entry: %v", err)
+		}
+	}
+	file.Close()
+
+	custom := &customerAnalyzer{LogAnalyzer: analyzer.NewLogAnalyzer(), byCustomer: make(map[string]int)}
+	processor := NewLogProcessor(tempDir, WithAnalyzer(custom))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	// The processor must route entries through the custom implementation
+	for _, customer := range []string{"c0", "c1", "c2"} {
+		if custom.byCustomer[customer] != 10 {
+			t.Errorf("Expected 10 entries for %s, got %d", customer, custom.byCustomer[customer])
+		}
+	}
+	if total := processor.GetSummary().TotalEntries; total != 30 {
+		t.Errorf("Expected 30 entries, got %d", total)
+	}
+}
+
+func TestProcessorSections(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	// Every section sees each entry from the same pass
+	first := analyzer.NewPatternSection(10)
+	second := analyzer.NewPatternSection(10)
+	processor := NewLogProcessor(tempDir, WithSection(first), WithSection(second))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	for _, section := range []*analyzer.PatternSection{first, second} {
+		total := 0
+		for _, pc := range section.Top() {
+			total += pc.Count
+		}
+		if total != 5 {
+			t.Errorf("Expected each section to observe 5 entries, got %d", total)
+		}
+	}
+	if len(processor.Sections()) != 2 {
+		t.Errorf("Expected 2 sections, got %d", len(processor.Sections()))
+	}
+}
+
+func TestProcessorMiddleware(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	processor := NewLogProcessor(tempDir)
+	// Drop debug entries, then rename a service
+	processor.Use(func(entry models.LogEntry) (models.LogEntry, bool) {
+		return entry, entry.Level != models.DEBUG
+	})
+	processor.Use(func(entry models.LogEntry) (models.LogEntry, bool) {
+		if entry.Service == "db" {
+			entry.Service = "postgres"
+		}
+		return entry, true
+	})
+
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	summary := processor.GetSummary()
+	if summary.TotalEntries != 4 {
+		t.Errorf("Expected 4 entries after filtering, got %d", summary.TotalEntries)
+	}
+	if summary.ByLevel[models.DEBUG] != 0 {
+		t.Errorf("Expected no DEBUG entries, got %d", summary.ByLevel[models.DEBUG])
+	}
+	if summary.ByService["postgres"] != 1 || summary.ByService["db"] != 0 {
+		t.Errorf("Expected db to be renamed to postgres, got %v", summary.ByService)
+	}
+}
+
+func TestInferLevel(t *testing.T) {
+	infer := InferLevel(models.INFO)
+	tests := []struct {
+		message  string
+		level    models.LogLevel
+		expected models.LogLevel
+		inferred bool
+	}{
+		{"panic: nil map", "", models.FATAL, true},
+		{"Upload failed after 3 retries", "", models.ERROR, true},
+		{"Retrying connection", "", models.WARNING, true},
+		{"Server started", "", models.INFO, true},
+		{"Errorless run", "", models.INFO, true},
+		{"Connection failed", models.DEBUG, models.DEBUG, false},
+	}
+	for _, tt := range tests {
+		entry, keep := infer(models.LogEntry{Message: tt.message, Level: tt.level})
+		if !keep {
+			t.Errorf("Expected %q to be kept", tt.message)
+		}
+		if entry.Level != tt.expected || entry.LevelInferred != tt.inferred {
+			t.Errorf("Expected %q to get level %s (inferred %v), got %s (inferred %v)",
+				tt.message, tt.expected, tt.inferred, entry.Level, entry.LevelInferred)
+		}
+	}
+
+	// Without a default, entries without keywords keep their empty level
+	entry, _ := InferLevel("")(models.LogEntry{Message: "Server started"})
+	if entry.Level != "" || entry.LevelInferred {
+		t.Errorf("Expected no level without a default, got %s", entry.Level)
+	}
+}
+
+func TestMinLevel(t *testing.T) {
+	min := MinLevel(models.WARNING)
+	tests := []struct {
+		level models.LogLevel
+		keep  bool
+	}{
+		{models.DEBUG, false},
+		{models.INFO, false},
+		{models.WARNING, true},
+		{"WARN", true},
+		{models.FATAL, true},
+		{"NOTICE", true},
+	}
+	for _, tt := range tests {
+		if _, keep := min(models.LogEntry{Level: tt.level}); keep != tt.keep {
+			t.Errorf("Expected %s to be kept %v, got %v", tt.level, tt.keep, keep)
+		}
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	since := time.Date(2023, 1, 1, 9, 0, 0, 0, time.UTC)
+	window := TimeWindow(since, since.Add(time.Hour))
+	tests := []struct {
+		ts   time.Time
+		keep bool
+	}{
+		{since.Add(-time.Second), false},
+		{since, true},
+		{since.Add(59 * time.Minute), true},
+		{since.Add(time.Hour), false},
+		{time.Time{}, false},
+	}
+	for _, tt := range tests {
+		if _, keep := window(models.LogEntry{Timestamp: tt.ts}); keep != tt.keep {
+			t.Errorf("Expected entry at %v to be kept: %v", tt.ts, tt.keep)
+		}
+	}
+}
+
+func TestOnlyServices(t *testing.T) {
+	only := OnlyServices("api", "db")
+	if _, keep := only(models.LogEntry{Service: "db"}); !keep {
+		t.Error("Expected entries of a named service to be kept")
+	}
+	if _, keep := only(models.LogEntry{Service: "web"}); keep {
+		t.Error("Expected entries of other services to be dropped")
+	}
+}
+
+func TestProcessorOutOfOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	lines := []string{
+		`{"id":"1","timestamp":"2025-01-01T10:00:00Z","level":"INFO","service":"api","message":"a"}`,
+		`{"id":"2","timestamp":"2025-01-01T10:05:00Z","level":"INFO","service":"api","message":"b"}`,
+		`{"id":"3","timestamp":"2025-01-01T10:01:00Z","level":"INFO","service":"api","message":"c"}`,
+		`{"id":"4","timestamp":"2025-01-01T10:03:00Z","level":"INFO","service":"api","message":"d"}`,
+		`{"id":"5","timestamp":"2025-01-01T10:06:00Z","level":"INFO","service":"api","message":"e"}`,
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "unordered.json"), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	processor := NewLogProcessor(tempDir)
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	summary := processor.GetSummary()
+	stats, ok := summary.OutOfOrder["unordered.json"]
+	if !ok {
+		t.Fatalf("Expected out-of-order stats for unordered.json, got %v", summary.OutOfOrder)
+	}
+	if stats.Count != 2 {
+		t.Errorf("Expected 2 out-of-order entries, got %d", stats.Count)
+	}
+	if stats.MaxDisplacement != 4*time.Minute {
+		t.Errorf("Expected max displacement to be 4m, got %v", stats.MaxDisplacement)
+	}
+}
+
+func TestProcessorSecondPass(t *testing.T) {
+	tempDir := t.TempDir()
+	// Two files whose entries interleave, each out of order
+	files := map[string][]string{
+		"a.json": {
+			`{"id":"1","timestamp":"2025-01-01T10:04:00Z","level":"INFO","service":"api","message":"late 1"}`,
+			`{"id":"2","timestamp":"2025-01-01T10:00:00Z","level":"INFO","service":"api","message":"start"}`,
+		},
+		"b.json": {
+			`{"id":"3","timestamp":"2025-01-01T10:03:00Z","level":"ERROR","service":"api","message":"timeout after 30s"}`,
+			`{"id":"4","timestamp":"2025-01-01T10:01:00Z","level":"INFO","service":"api","message":"late 2"}`,
+		},
+	}
+	for name, lines := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatalf("Failed to write log file: %v", err)
+		}
+	}
+
+	arrivals := analyzer.NewArrivalSection(1)
+	processor := NewLogProcessor(tempDir, WithSection(arrivals))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	// The section saw the entries in timestamp order: gaps of 1m, 2m and 1m
+	stats := arrivals.Arrivals()
+	if len(stats) != 1 || stats[0].Entries != 4 || stats[0].P50 != time.Minute || stats[0].Max != 2*time.Minute {
+		t.Errorf("Expected exact inter-arrival times for api, got %+v", stats)
+	}
+	// "late <*>" was read first but first occurred at 10:01
+	if newest := arrivals.NewestPatterns(); len(newest) != 1 || newest[0].Pattern != "timeout after <*>" {
+		t.Errorf("Expected timeout after <*> to be the newest pattern, got %+v", newest)
+	}
+}
+
+// lossyAnalyzer loses the entries of one service, as a racy aggregation might
+type lossyAnalyzer struct {
+	*analyzer.LogAnalyzer
+	lose string
+}
+
+func (l *lossyAnalyzer) Process(entry models.LogEntry) bool {
+	if entry.Service == l.lose {
+		return true
+	}
+	return l.LogAnalyzer.Process(entry)
+}
+
+func TestProcessorVerify(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	processor := NewLogProcessor(tempDir)
+	processor.Use(OnlyServices("api", "db"))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if err := processor.Verify(); err != nil {
+		t.Errorf("Expected the summary to match the recount, got %v", err)
+	}
+
+	lossy := NewLogProcessor(tempDir, WithAnalyzer(&lossyAnalyzer{LogAnalyzer: analyzer.NewLogAnalyzer(), lose: "db"}))
+	if err := lossy.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	err := lossy.Verify()
+	if !errors.Is(err, ErrVerifyMismatch) {
+		t.Fatalf("Expected a mismatch, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "total entries") || !strings.Contains(err.Error(), `service "db": summary 0`) {
+		t.Errorf("Expected the differing counts to be listed, got %v", err)
+	}
+}
+
+func TestProcessorAccounting(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+	// A duplicate of entry 1 and a file that fails after two entries
+	dup := `{"id":"1","timestamp":"2023-01-01T10:00:00Z","level":"INFO","service":"api","message":"again"}` + "\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "dup.json"), []byte(dup), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+	broken := `{"id":"b1","level":"INFO","service":"api"}` + "\n" + `{"id":"b2","level":"INFO","service":"api"}` + "\n{not json\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "broken.json"), []byte(broken), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	processor := NewLogProcessor(tempDir)
+	processor.Use(OnlyServices("api", "db"))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	summary := processor.GetSummary()
+	a := summary.Accounting
+	if a == nil {
+		t.Fatal("Expected the summary to include accounting")
+	}
+	// 5 sample entries (one of them auth), the duplicate and the 2 entries
+	// of the broken file
+	if a.Read != 8 || a.Filtered != 1 || a.Duplicates != 1 || a.Dropped[DropFileError] != 2 {
+		t.Errorf("Unexpected accounting %+v", *a)
+	}
+	if a.Analyzed != summary.TotalEntries {
+		t.Errorf("Expected %d analyzed entries, got %d", summary.TotalEntries, a.Analyzed)
+	}
+	if n := a.Unaccounted(); n != 0 {
+		t.Errorf("Expected the accounting to balance, %d entries are unaccounted", n)
+	}
+}
+
+func TestProcessorAccountingHashMatches(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	first := NewLogProcessor(tempDir)
+	if err := first.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	restored := analyzer.NewLogAnalyzer()
+	if err := restored.Import(first.analyzer.(*analyzer.LogAnalyzer).Export()); err != nil {
+		t.Fatalf("Failed to import state: %v", err)
+	}
+
+	// The same entries again are recognized by the restored hashes only
+	processor := NewLogProcessor(tempDir, WithAnalyzer(restored))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	a := processor.GetSummary().Accounting
+	if a.Duplicates != 5 || a.HashMatches != 5 {
+		t.Errorf("Expected 5 duplicates by hash, got %+v", *a)
+	}
+}
+
+func TestProcessorActorAnalyzer(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+	dup := `{"id":"1","timestamp":"2023-01-01T10:00:00Z","level":"INFO","service":"api","message":"again"}` + "\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "dup.json"), []byte(dup), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	locked := NewLogProcessor(tempDir)
+	if err := locked.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	actor := analyzer.NewActorAnalyzer()
+	defer actor.Close()
+	processor := NewLogProcessor(tempDir, WithAnalyzer(actor))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	got, want := processor.GetSummary(), locked.GetSummary()
+	if got.TotalEntries != 5 || !reflect.DeepEqual(got.ByLevel, want.ByLevel) || !reflect.DeepEqual(got.ByService, want.ByService) {
+		t.Errorf("Expected the summary of the sharded-lock analyzer %+v, got %+v", want, got)
+	}
+	if a := got.Accounting; a.Duplicates != 1 || a.Unaccounted() != 0 {
+		t.Errorf("Unexpected accounting %+v", *a)
+	}
+
+	// A restored actor skips the entries of the state
+	restored := analyzer.NewActorAnalyzer()
+	defer restored.Close()
+	if err := restored.Import(actor.Export()); err != nil {
+		t.Fatalf("Failed to import state: %v", err)
+	}
+	again := NewLogProcessor(tempDir, WithAnalyzer(restored))
+	if err := again.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if summary := again.GetSummary(); summary.TotalEntries != 5 || summary.Accounting.HashMatches != 6 {
+		t.Errorf("Expected 5 entries and 6 hash matches, got %d and %+v", summary.TotalEntries, *summary.Accounting)
+	}
+}
+
+func TestProcessorFileResults(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "broken.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	processor := NewLogProcessor(tempDir)
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	results := processor.FileResults()
+	entries := 0
+	for _, r := range results {
+		entries += r.Entries
+		if filepath.Base(r.Path) == "broken.json" && (r.Status != FileFailed || r.Error == "") {
+			t.Errorf("Expected broken.json to have failed with a reason, got %+v", r)
+		}
+	}
+	if entries != 5 {
+		t.Errorf("Expected 5 entries over all files, got %d", entries)
+	}
+	if len(results) < 2 || filepath.Base(results[0].Path) != "broken.json" {
+		t.Errorf("Expected results sorted by path, got %+v", results)
+	}
+}
+
+func TestProcessorFileHashes(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	processor := NewLogProcessor(tempDir, WithFileHashes())
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	for _, r := range processor.FileResults() {
+		data, err := os.ReadFile(r.Path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", r.Path, err)
+		}
+		sum := sha256.Sum256(data)
+		if r.SHA256 != hex.EncodeToString(sum[:]) || r.Bytes != int64(len(data)) {
+			t.Errorf("Expected %s to hash to %x over %d bytes, got %s over %d", r.Path, sum, len(data), r.SHA256, r.Bytes)
+		}
+	}
+}
+
+func TestProcessorShards(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	total, files := 0, 0
+	for index := 0; index < 3; index++ {
+		processor := NewLogProcessor(tempDir, WithShard(cluster.Shard{Index: index, Count: 3}))
+		if err := processor.Start(); err != nil {
+			t.Fatalf("Failed to process shard %d: %v", index, err)
+		}
+		total += processor.GetSummary().TotalEntries
+		files += len(processor.FileResults())
+	}
+	if total != 5 {
+		t.Errorf("Expected the shards to process the 5 entries between them, got %d", total)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(tempDir, "*.json")); files != len(matches) {
+		t.Errorf("Expected every file to be processed by one shard, got %d of %d", files, len(matches))
+	}
+}
+
+func TestProcessorMmap(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	processor := NewLogProcessor(tempDir, WithMmap(true))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	summary := processor.GetSummary()
+	if summary.TotalEntries != 5 {
+		t.Errorf("Expected 5 entries read through mappings, got %d", summary.TotalEntries)
+	}
+	if summary.ByService["api"] != 3 {
+		t.Errorf("Expected 3 api entries, got %d", summary.ByService["api"])
+	}
+}
+
+func TestProcessorSnapshots(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	var mu sync.Mutex
+	var totals []int
+	processor := NewLogProcessor(tempDir, WithSnapshots(0, 2, func(summary *models.LogSummary) {
+		mu.Lock()
+		defer mu.Unlock()
+		totals = append(totals, summary.TotalEntries)
+	}))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Snapshots come after every second entry, unless the previous one is
+	// still being handled
+	if len(totals) == 0 || len(totals) > 2 {
+		t.Fatalf("Expected 1 or 2 snapshots, got %d", len(totals))
+	}
+	for _, total := range totals {
+		if total < 2 || total > 5 {
+			t.Errorf("Expected intermediate totals between 2 and 5, got %d", total)
+		}
+	}
+}
+
+// stuckParser never returns for input containing "stuck", like a read from
+// a hung network mount
+type stuckParser struct {
+	release chan struct{}
+}
+
+func (s stuckParser) Parse(r io.Reader, emit func(models.LogEntry) error) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(string(data), "stuck") {
+		<-s.release
+	}
+	return parser.JSONParser{}.Parse(strings.NewReader(string(data)), emit)
+}
+
+func TestProcessorFileTimeout(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "stuck.json"), []byte(`{"id":"stuck"}`), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	processor := NewLogProcessor(tempDir, WithParser(stuckParser{release}), WithFileTimeout(50*time.Millisecond))
+
+	done := make(chan error, 1)
+	go func() { done <- processor.Start() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Failed to start processor: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the stuck file to time out")
+	}
+
+	summary := processor.GetSummary()
+	if summary.TotalEntries != 5 {
+		t.Errorf("Expected the 5 entries of the other files, got %d", summary.TotalEntries)
+	}
+	if reason := summary.FailedFiles["stuck.json"]; !strings.Contains(reason, "timed out") {
+		t.Errorf("Expected stuck.json to be reported as timed out, got %q", reason)
+	}
+}
+
+func TestProcessorInUsePolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+	path := filepath.Join(tempDir, "live.json")
+	content := `{"id":"live-1","level":"INFO","service":"web"}` + "\n" +
+		`{"id":"live-2","level":"INFO","service":"web"}` + "\n" + `{"id":"live-3","message":"`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	// Keep appending to the half-written last line
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+				f.WriteString("x")
+			}
+		}
+	}()
+
+	processor := NewLogProcessor(tempDir, WithInUsePolicy(InUsePrefix, 100*time.Millisecond))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	summary := processor.GetSummary()
+	if summary.TotalEntries != 7 || summary.ByService["web"] != 2 {
+		t.Errorf("Expected the 2 complete lines of the live file to be read, got %d entries: %v", summary.TotalEntries, summary.ByService)
+	}
+	if len(summary.FailedFiles) != 0 {
+		t.Errorf("Expected no failed files, got %v", summary.FailedFiles)
+	}
+
+	processor = NewLogProcessor(tempDir, WithInUsePolicy(InUseSkip, 100*time.Millisecond))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	summary = processor.GetSummary()
+	if summary.TotalEntries != 5 {
+		t.Errorf("Expected only the 5 entries of the complete files, got %d", summary.TotalEntries)
+	}
+	if reason := summary.FailedFiles["live.json"]; !strings.HasPrefix(reason, "skipped") {
+		t.Errorf("Expected live.json to be reported as skipped, got %q", reason)
+	}
+
+	if _, err := ParseInUsePolicy("later"); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+}
+
+func TestProcessorCorruptArchives(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(gz, `{"id":"gz-%d","level":"INFO","service":"archive","message":"entry %d"}`+"\n", i, i)
+	}
+	gz.Close()
+	archive := buf.Bytes()
+
+	// A truncated copy and one with a damaged checksum
+	if err := os.WriteFile(filepath.Join(tempDir, "truncated.json.gz"), archive[:len(archive)/2], 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+	damaged := append([]byte(nil), archive...)
+	damaged[len(damaged)-5] ^= 0xff
+	if err := os.WriteFile(filepath.Join(tempDir, "damaged.json.gz"), damaged, 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+
+	processor := NewLogProcessor(tempDir, WithPattern("*.json*"))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	summary := processor.GetSummary()
+	if reason := summary.CorruptArchives["truncated.json.gz"]; reason != "truncated" {
+		t.Errorf("Expected truncated.json.gz to be reported as truncated, got %q", reason)
+	}
+	if reason := summary.CorruptArchives["damaged.json.gz"]; reason != "checksum mismatch" {
+		t.Errorf("Expected damaged.json.gz to be reported with a checksum mismatch, got %q", reason)
+	}
+	if len(summary.FailedFiles) != 0 {
+		t.Errorf("Expected corrupt archives not to be reported as failed files, got %v", summary.FailedFiles)
+	}
+	// The damaged archive decompresses fully and the truncated one partly
+	if n := summary.ByService["archive"]; n < 100 || n >= 200 {
+		t.Errorf("Expected entries before the damage to be counted, got %d", n)
+	}
+}
+
+func TestProcessorArchives(t *testing.T) {
+	tempDir := t.TempDir()
+	line := func(id, service string) string {
+		return fmt.Sprintf(`{"id":"%s","level":"INFO","service":"%s","message":"ok"}`+"\n", id, service)
+	}
+
+	// A support bundle as a zip with nested directories
+	zipPath := filepath.Join(tempDir, "bundle.zip")
+	zf, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	zw := zip.NewWriter(zf)
+	for name, content := range map[string]string{
+		"logs/api.json":  line("z1", "api") + line("z2", "api"),
+		"logs/notes.txt": "not a log",
+		"logs/bad.json":  "{broken",
+	} {
+		w, _ := zw.Create(name)
+		io.WriteString(w, content)
+	}
+	zw.Close()
+	zf.Close()
+
+	// And a compressed tarball
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	content := line("t1", "db")
+	tw.WriteHeader(&tar.Header{Name: "var/log/db.json", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	io.WriteString(tw, content)
+	tw.Close()
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(tempDir, "host.tar.gz"), buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+
+	var mu sync.Mutex
+	sources := make(map[string]int)
+	processor := NewLogProcessor(tempDir, WithArchives())
+	processor.Use(func(entry models.LogEntry) (models.LogEntry, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		sources[entry.Source]++
+		return entry, true
+	})
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	summary := processor.GetSummary()
+	if summary.TotalEntries != 3 {
+		t.Errorf("Expected 3 entries from the archives, got %d", summary.TotalEntries)
+	}
+	if sources["bundle.zip/logs/api.json"] != 2 || sources["host.tar.gz/var/log/db.json"] != 1 {
+		t.Errorf("Expected inner paths as sources, got %v", sources)
+	}
+	if _, ok := summary.FailedFiles["bundle.zip/logs/bad.json"]; !ok {
+		t.Errorf("Expected the broken inner file to be reported, got %v", summary.FailedFiles)
+	}
+
+	// The input can also be a single archive
+	processor = NewLogProcessor(zipPath)
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if summary := processor.GetSummary(); summary.TotalEntries != 2 {
+		t.Errorf("Expected 2 entries from the zip archive, got %d", summary.TotalEntries)
+	}
+
+	// Archives in a directory are only read if enabled
+	if err := NewLogProcessor(tempDir).Start(); err == nil {
+		t.Error("Expected no input files without archives enabled")
+	}
+}
+
+func TestProcessorEncodings(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// A UTF-16 file with a byte order mark, as written by Windows tools
+	text := `{"id":"w1","level":"ERROR","service":"café","message":"échec"}` + "\n"
+	units := utf16.Encode([]rune("\ufeff" + text))
+	data := make([]byte, 0, 2*len(units))
+	for _, u := range units {
+		data = append(data, byte(u), byte(u>>8))
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "windows.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+	// And a Windows-1252 one
+	latin := []byte(`{"id":"w2","level":"INFO","service":"caf` + "\xe9" + `","message":"ok"}` + "\n")
+	if err := os.WriteFile(filepath.Join(tempDir, "latin.json"), latin, 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	processor := NewLogProcessor(tempDir)
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	summary := processor.GetSummary()
+	if summary.ByService["café"] != 2 {
+		t.Errorf("Expected both files to be converted to UTF-8, got %v (failed: %v)", summary.ByService, summary.FailedFiles)
+	}
+}
+
+// flakySink records the IDs written to it and rejects the entry failID
+type flakySink struct {
+	mu      sync.Mutex
+	ids     []string
+	failID  string
+	flushes int
+}
+
+func (s *flakySink) Write(entry models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry.ID == s.failID {
+		return fmt.Errorf("receiver unavailable")
+	}
+	s.ids = append(s.ids, entry.ID)
+	return nil
+}
+
+func (s *flakySink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushes++
+	return nil
+}
+
+func (s *flakySink) Close() error { return nil }
+
+func TestProcessorCheckpoint(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "app.json")
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	writeEntries := func(from, to int) {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("Failed to open log file: %v", err)
+		}
+		defer f.Close()
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(f, `{"id":"%d","level":"INFO","service":"api","message":"m"}`+"\n", i)
+		}
+	}
+	run := func(s *flakySink) error {
+		return NewLogProcessor(tempDir, WithSink(s), WithCheckpoint(checkpointPath, 2)).Start()
+	}
+	writeEntries(1, 5)
+
+	// Entry 3 is rejected, so only the first two are committed
+	s := &flakySink{failID: "3"}
+	if err := run(s); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if s.flushes != 1 {
+		t.Errorf("Expected the sink to be flushed once before the failure, got %d", s.flushes)
+	}
+
+	// The next run forwards everything after the committed offset again
+	s = &flakySink{}
+	if err := run(s); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if got := strings.Join(s.ids, ","); got != "3,4,5" {
+		t.Errorf("Expected entries 3,4,5 to be forwarded after the failure, got %s", got)
+	}
+
+	// Once the log grows only the new entries are forwarded
+	writeEntries(6, 7)
+	s = &flakySink{}
+	if err := run(s); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if got := strings.Join(s.ids, ","); got != "6,7" {
+		t.Errorf("Expected only the appended entries 6,7 to be forwarded, got %s", got)
+	}
+}
+
+func TestProcessorCheckpointEncrypted(t *testing.T) {
+	tempDir := t.TempDir()
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	content := `{"id":"1","level":"INFO","service":"api","message":"m"}` + "\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "app.json"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+	key, err := crypt.ParseKey(strings.Repeat("0f", 32))
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+
+	if err := NewLogProcessor(tempDir, WithSink(&flakySink{}), WithCheckpoint(checkpointPath, 2), WithCipher(key)).Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	data, err := os.ReadFile(checkpointPath)
+	if err != nil {
+		t.Fatalf("Failed to read checkpoint: %v", err)
+	}
+	if !crypt.Sealed(data) || strings.Contains(string(data), "app.json") {
+		t.Errorf("Expected an encrypted checkpoint, got %q", data)
+	}
+
+	// The next run reads the offsets back, so nothing is forwarded again
+	s := &flakySink{}
+	if err := NewLogProcessor(tempDir, WithSink(s), WithCheckpoint(checkpointPath, 2), WithCipher(key)).Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if len(s.ids) != 0 {
+		t.Errorf("Expected no entries to be forwarded again, got %v", s.ids)
+	}
+
+	// Without the key the checkpoint cannot be read
+	if err := NewLogProcessor(tempDir, WithSink(&flakySink{}), WithCheckpoint(checkpointPath, 2)).Start(); !errors.Is(err, crypt.ErrNoKey) {
+		t.Errorf("Expected ErrNoKey without the key, got %v", err)
+	}
+}
+
+func TestProcessorStageMetrics(t *testing.T) {
+	tempDir := t.TempDir()
+	f, err := os.Create(filepath.Join(tempDir, "app.json"))
+	if err != nil {
+		t.Fatalf("Failed to create log file: %v", err)
+	}
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(f, `{"id":"%d","level":"INFO","service":"api","message":"m"}`+"\n", i)
+	}
+	f.Close()
+
+	s := &flakySink{}
+	processor := NewLogProcessor(tempDir, WithSink(s), WithStageMetrics())
+	processor.Use(MinLevel(models.DEBUG), func(entry models.LogEntry) (models.LogEntry, bool) {
+		time.Sleep(time.Millisecond)
+		return entry, true
+	})
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	report := processor.Stages()
+	var names []string
+	for _, stage := range report.Stages {
+		names = append(names, stage.Name)
+		if stage.Entries != 20 {
+			t.Errorf("Expected stage %s to handle 20 entries, got %d", stage.Name, stage.Entries)
+		}
+	}
+	want := "read,parse,middleware processor.MinLevel,middleware processor.TestProcessorStageMetrics,analyze,sink processor.flakySink"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("Expected stages %s, got %s", want, got)
+	}
+	slowest, share, ok := report.Bottleneck()
+	if !ok || slowest.Name != "middleware processor.TestProcessorStageMetrics" || share < 0.5 {
+		t.Errorf("Expected the sleeping middleware to be the bottleneck, got %s at %.0f%%", slowest.Name, share*100)
+	}
+	if report.Queue.Capacity != 1000 {
+		t.Errorf("Expected the queue capacity of 1000, got %d", report.Queue.Capacity)
+	}
+
+	// Without the option nothing is timed
+	plain := NewLogProcessor(tempDir)
+	if err := plain.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if stages := plain.Stages().Stages; len(stages) != 0 {
+		t.Errorf("Expected no stages without WithStageMetrics, got %v", stages)
+	}
+}
+
+func TestScaleDecision(t *testing.T) {
+	tests := []struct {
+		name         string
+		n, min, max  int
+		backlog, cpu float64
+		idle, want   int
+	}{
+		{"backlog doubles the pool", 2, 1, 16, 0.8, 0.3, 0, 2},
+		{"growth stops at the maximum", 12, 1, 16, 0.8, 0.3, 0, 4},
+		{"unknown CPU use does not block growth", 2, 1, 16, 0.8, -1, 0, 2},
+		{"busy CPUs block growth", 2, 1, 16, 0.8, 0.95, 0, 0},
+		{"a short lull keeps the pool", 4, 1, 16, 0, 0.1, 3, 0},
+		{"a long lull retires a worker", 4, 1, 16, 0, 0.1, 10, -1},
+		{"the minimum is kept", 1, 1, 16, 0, 0.1, 10, 0},
+	}
+	for _, tt := range tests {
+		if got := scaleDecision(tt.n, tt.min, tt.max, tt.backlog, tt.cpu, tt.idle); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestProcessorAutoscaling(t *testing.T) {
+	tempDir := t.TempDir()
+	f, err := os.Create(filepath.Join(tempDir, "app.json"))
+	if err != nil {
+		t.Fatalf("Failed to create log file: %v", err)
+	}
+	for i := 1; i <= 2000; i++ {
+		fmt.Fprintf(f, `{"id":"%d","level":"INFO","service":"api","message":"m"}`+"\n", i)
+	}
+	f.Close()
+
+	processor := NewLogProcessor(tempDir, WithAutoscaling(1, 8), WithStageMetrics())
+	processor.Use(func(entry models.LogEntry) (models.LogEntry, bool) {
+		time.Sleep(time.Millisecond)
+		return entry, true
+	})
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if got := processor.GetSummary().TotalEntries; got != 2000 {
+		t.Errorf("Expected 2000 entries, got %d", got)
+	}
+	if peak := processor.Stages().PeakWorkers; peak <= 1 || peak > 8 {
+		t.Errorf("Expected the backlog to scale the pool up to at most 8 workers, peaked at %d", peak)
+	}
+}
+
+func TestProcessorReadersAndParsers(t *testing.T) {
+	tempDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		var lines strings.Builder
+		for j := 0; j < 3; j++ {
+			fmt.Fprintf(&lines, `{"id":"%d-%d","timestamp":"2023-01-01T00:00:00Z","level":"INFO","message":"m","service":"s"}`+"\n", i, j)
+		}
+		if err := os.WriteFile(filepath.Join(tempDir, fmt.Sprintf("app%02d.json", i)), []byte(lines.String()), 0644); err != nil {
+			t.Fatalf("Failed to write log file: %v", err)
+		}
+	}
+
+	processor := NewLogProcessor(tempDir, WithReaders(8), WithParsers(1), WithStageMetrics())
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if total := processor.GetSummary().TotalEntries; total != 60 {
+		t.Errorf("Expected 60 entries, got %d", total)
+	}
+	results := processor.FileResults()
+	if len(results) != 20 {
+		t.Errorf("Expected 20 file results, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Status != FileOK || r.Entries != 3 {
+			t.Errorf("Expected %s to be processed with 3 entries, got %+v", r.Path, r)
+		}
+	}
+	for _, stage := range processor.Stages().Stages[:2] {
+		if stage.Entries != 60 {
+			t.Errorf("Expected stage %s to handle 60 entries, got %d", stage.Name, stage.Entries)
+		}
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), readChunk+10)
+	got, err := readAll(context.Background(), bytes.NewReader(data), int64(len(data)))
+	if err != nil || len(got) != len(data) {
+		t.Errorf("Expected %d bytes, got %d (%v)", len(data), len(got), err)
+	}
+	// A file truncated since its size was taken
+	got, err = readAll(context.Background(), bytes.NewReader(data[:100]), int64(len(data)))
+	if err != nil || len(got) != 100 {
+		t.Errorf("Expected the 100 remaining bytes, got %d (%v)", len(got), err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := readAll(ctx, bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Errorf("Expected a cancelled read to fail")
+	}
+}
+
+// orderedGate records the levels of the entries written past a gatedSink
+type orderedGate struct {
+	*gatedSink
+	mu     sync.Mutex
+	levels []models.LogLevel
+}
+
+func (g *orderedGate) Write(entry models.LogEntry) error {
+	g.gatedSink.Write(entry)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.levels = append(g.levels, entry.Level)
+	return nil
+}
+
+func TestProcessorPriority(t *testing.T) {
+	gate := &orderedGate{gatedSink: &gatedSink{open: make(chan struct{}), started: make(chan struct{})}}
+	processor := NewLogProcessor("", WithSink(gate), WithWorkers(1), WithPriority(models.ERROR))
+
+	source := fakeSource{}
+	for i := 0; i < 100; i++ {
+		source.entries = append(source.entries, models.LogEntry{Level: models.DEBUG, Service: "net", Source: "fake"})
+	}
+	source.entries = append(source.entries, models.LogEntry{Level: "error", Service: "net", Source: "fake"})
+	done := make(chan error, 1)
+	go func() {
+		done <- processor.Serve(source)
+	}()
+	defer func() {
+		processor.Stop()
+		<-done
+	}()
+
+	// The worker is stuck on the first entry it took until the error is
+	// queued behind the backlog
+	<-gate.started
+	deadline := time.Now().Add(2 * time.Second)
+	for processor.Stages().Queue.Prioritized == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(gate.open)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := processor.GetSummaryContext(ctx); err != nil {
+		t.Fatalf("Expected the pipeline to become idle, got %v", err)
+	}
+
+	gate.mu.Lock()
+	defer gate.mu.Unlock()
+	if len(gate.levels) != 101 || (gate.levels[0] != "error" && gate.levels[1] != "error") {
+		t.Errorf("Expected the error to be written first or right after the entry in progress, got %d entries starting %v", len(gate.levels), gate.levels[:min(3, len(gate.levels))])
+	}
+}
+
+func TestProcessorMaxAge(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	processor := NewLogProcessor("", WithClock(clock.NewFake(now)), WithMaxAge(time.Hour))
+
+	source := fakeSource{}
+	for _, ts := range []time.Time{now.AddDate(0, 0, -7), now.Add(-2 * time.Hour), now.Add(-time.Minute), {}} {
+		source.entries = append(source.entries, models.LogEntry{Timestamp: ts, Level: models.ERROR, Service: "net", Source: "fake"})
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- processor.Serve(source)
+	}()
+	defer func() {
+		processor.Stop()
+		<-done
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for processor.Accounting().Read < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	summary, err := processor.GetSummaryContext(ctx)
+	if err != nil {
+		t.Fatalf("Expected the pipeline to become idle, got %v", err)
+	}
+	if summary.TotalEntries != 2 {
+		t.Errorf("Expected the recent entry and the one without a timestamp, got %d entries", summary.TotalEntries)
+	}
+	if stale := processor.Accounting().Dropped[DropStale]; stale != 2 {
+		t.Errorf("Expected 2 stale entries dropped, got %d", stale)
+	}
+}
+
+// hangingSink blocks on the first entry written until release is closed
+type hangingSink struct {
+	once    sync.Once
+	hung    chan struct{}
+	release chan struct{}
+	written atomic.Int64
+}
+
+func (h *hangingSink) Write(models.LogEntry) error {
+	first := false
+	h.once.Do(func() { first = true })
+	if first {
+		close(h.hung)
+		<-h.release
+	}
+	h.written.Add(1)
+	return nil
+}
+
+func (h *hangingSink) Close() error { return nil }
+
+func TestProcessorWatchdog(t *testing.T) {
+	sink := &hangingSink{hung: make(chan struct{}), release: make(chan struct{})}
+	defer close(sink.release)
+	fake := clock.NewFake(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	stalls := make(chan Stall, 1)
+	processor := NewLogProcessor("", WithSink(sink), WithWorkers(1), WithClock(fake), WithWatchdog(time.Minute, func(s Stall) bool {
+		stalls <- s
+		return true
+	}))
+
+	source := fakeSource{}
+	for i := 0; i < 10; i++ {
+		source.entries = append(source.entries, models.LogEntry{Level: models.INFO, Service: "net", Source: "fake"})
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- processor.Serve(source)
+	}()
+	defer func() {
+		processor.Stop()
+		<-done
+	}()
+
+	<-sink.hung
+	fake.Advance(time.Minute)
+	var stall Stall
+	select {
+	case stall = <-stalls:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the watchdog to report the stall")
+	}
+	if stall.StuckWorkers != 1 || stall.InFlight == 0 || stall.For != time.Minute {
+		t.Errorf("Expected one stuck worker with entries in flight for a minute, got %+v", stall)
+	}
+
+	// The replacement worker delivers the rest
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	summary, err := processor.GetSummaryContext(ctx)
+	if err != nil {
+		t.Fatalf("Expected the pipeline to recover, got %v", err)
+	}
+	if summary.TotalEntries != 10 || sink.written.Load() != 9 {
+		t.Errorf("Expected 10 entries analyzed and 9 written past the stuck one, got %d and %d", summary.TotalEntries, sink.written.Load())
+	}
+}
+
+func TestProcessorSourceNames(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, region := range []string{"eu", "us"} {
+		if err := os.Mkdir(filepath.Join(tempDir, region), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		// No IDs, so entries are told apart by their source and position
+		lines := `{"timestamp":"2023-01-01T00:00:00Z","level":"INFO","message":"m","service":"` + region + `"}` + "\n"
+		if err := os.WriteFile(filepath.Join(tempDir, region, "app.json"), []byte(lines+lines), 0644); err != nil {
+			t.Fatalf("Failed to write log file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		names   SourceNames
+		entries int
+		source  string
+	}{
+		{SourceRelative, 4, "eu/app.json"},
+		{SourceBase, 2, "app.json"},
+		{SourceAbsolute, 4, filepath.ToSlash(filepath.Join(tempDir, "eu", "app.json"))},
+	}
+	for _, tt := range tests {
+		sources := make(map[string]bool)
+		processor := NewLogProcessor(tempDir, WithPattern("*/app.json"), WithSourceNames(tt.names), WithWorkers(1))
+		processor.Use(func(entry models.LogEntry) (models.LogEntry, bool) {
+			sources[entry.Source] = true
+			return entry, true
+		})
+		if err := processor.Start(); err != nil {
+			t.Fatalf("Failed to start processor: %v", err)
+		}
+		if total := processor.GetSummary().TotalEntries; total != tt.entries {
+			t.Errorf("Expected %d entries with %s names, got %d", tt.entries, tt.names, total)
+		}
+		if !sources[tt.source] {
+			t.Errorf("Expected entries from source %s with %s names, got %v", tt.source, tt.names, sources)
+		}
+	}
+}
+
+func TestProcessorRecursive(t *testing.T) {
+	root := t.TempDir()
+	line := `{"timestamp":"2023-01-01T00:00:00Z","level":"INFO","message":"m","service":"s"}` + "\n"
+	for _, name := range []string{"a.json", "sub/b.json", "sub/deeper/c.json", "sub/notes.txt"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(line), 0644); err != nil {
+			t.Fatalf("Failed to write log file: %v", err)
+		}
+	}
+	links := map[string]string{
+		"sub/loop": root,
+		"again":    filepath.Join(root, "sub"),
+		"broken":   filepath.Join(root, "missing"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Skipf("Symbolic links unsupported: %v", err)
+		}
+	}
+
+	processor := NewLogProcessor(root, WithRecursive())
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if total := processor.GetSummary().TotalEntries; total != 3 {
+		t.Errorf("Expected the 3 files to be read once each, got %d entries", total)
+	}
+
+	reasons := make(map[string]string)
+	for _, s := range processor.SkippedPaths() {
+		rel, _ := filepath.Rel(root, s.Path)
+		reasons[filepath.ToSlash(rel)] = s.Reason
+	}
+	// The directory is scanned once, under whichever link is found first
+	scanned := "sub"
+	if strings.HasPrefix(reasons["sub"], "already scanned") {
+		scanned = "again"
+	} else if !strings.HasPrefix(reasons["again"], "already scanned") {
+		t.Errorf("Expected sub to be scanned only once, got %v", reasons)
+	}
+	if loop := reasons[scanned+"/loop"]; !strings.HasPrefix(loop, "symlink loop") {
+		t.Errorf("Expected %s/loop to be skipped as a loop, got %q", scanned, loop)
+	}
+	if !strings.HasPrefix(reasons["broken"], "broken symlink") {
+		t.Errorf("Expected broken to be skipped as a broken link, got %q", reasons["broken"])
+	}
+}
+
+// tenantSink counts the entries written to it by their tenant field
+type tenantSink struct {
+	mu      sync.Mutex
+	tenants map[string]int
+}
+
+func (s *tenantSink) Write(entry models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants[entry.Fields["tenant"]]++
+	return nil
+}
+
+func (s *tenantSink) Flush() error { return nil }
+
+func (s *tenantSink) Close() error { return nil }
+
+func TestProcessorCardinalityLimit(t *testing.T) {
+	tempDir := t.TempDir()
+	// A service name per request, and two of ten tenants twice each
+	var lines strings.Builder
+	for i := 0; i < 20; i++ {
+		fmt.Fprintf(&lines, `{"id":"%d","timestamp":"2023-01-01T00:00:00Z","level":"INFO","message":"m","service":"req-%d","fields":{"tenant":"t%d"}}`+"\n", i, i, i/2)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "app.json"), []byte(lines.String()), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	sink := &tenantSink{tenants: make(map[string]int)}
+	processor := NewLogProcessor(tempDir, WithCardinalityLimit(3, "tenant"), WithSink(sink), WithWorkers(1))
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+
+	summary := processor.GetSummary()
+	if len(summary.ByService) != 4 || summary.ByService[OtherValue] != 17 || summary.ByService["req-0"] != 1 {
+		t.Errorf("Expected 3 services and 17 entries as %s, got %v", OtherValue, summary.ByService)
+	}
+	expected := map[string]int{"t0": 2, "t1": 2, "t2": 2, OtherValue: 14}
+	if !reflect.DeepEqual(sink.tenants, expected) {
+		t.Errorf("Expected tenants %v, got %v", expected, sink.tenants)
+	}
+}
+
+func TestProcessorBytesRead(t *testing.T) {
+	tempDir := t.TempDir()
+	createSampleLogs(t, tempDir)
+
+	var size int64
+	files, _ := filepath.Glob(filepath.Join(tempDir, "*.json"))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+
+	processor := NewLogProcessor(tempDir)
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	if got := processor.BytesRead(); got != size || size == 0 {
+		t.Errorf("Expected %d bytes read, got %d", size, got)
+	}
+	for _, r := range processor.FileResults() {
+		if r.Size == 0 {
+			t.Errorf("Expected the size of %s, got none", r.Path)
+		}
+	}
+}
+
+func TestProcessorPartialFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	line := func(id string) string {
+		return fmt.Sprintf(`{"id":"%s","level":"INFO","service":"api","message":"ok"}`+"\n", id)
+	}
+
+	// An archive with a member that fails to parse
+	zf, err := os.Create(filepath.Join(tempDir, "bundle.zip"))
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	zw := zip.NewWriter(zf)
+	for name, content := range map[string]string{"good.json": line("z1"), "bad.json": "{broken"} {
+		w, _ := zw.Create(name)
+		io.WriteString(w, content)
+	}
+	zw.Close()
+	zf.Close()
+
+	// A file of which one entry is rejected by the sink, and one delivered whole
+	if err := os.WriteFile(filepath.Join(tempDir, "rejected.json"), []byte(line("r1")+line("r2")), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "delivered.json"), []byte(line("d1")), 0644); err != nil {
+		t.Fatalf("Failed to write log file: %v", err)
+	}
+
+	processor := NewLogProcessor(tempDir, WithSink(&flakySink{failID: "r2"}), WithArchives())
+	if err := processor.Start(); err != nil {
+		t.Fatalf("Failed to start processor: %v", err)
+	}
+	statuses := make(map[string]string)
+	for _, r := range processor.FileResults() {
+		statuses[filepath.Base(r.Path)] = r.Status
+		if r.Status == FilePartial && r.Error == "" {
+			t.Errorf("Expected the reason %s is partial", r.Path)
+		}
+	}
+	if statuses["bundle.zip"] != FilePartial {
+		t.Errorf("Expected the archive with a failed member to be partial, got %q", statuses["bundle.zip"])
+	}
+	if statuses["rejected.json"] != FilePartial {
+		t.Errorf("Expected the file with a rejected entry to be partial, got %q", statuses["rejected.json"])
+	}
+	if statuses["delivered.json"] != FileOK {
+		t.Errorf("Expected the delivered file to be ok, got %q", statuses["delivered.json"])
+	}
+}
@@ -0,0 +1,136 @@
+// Package processortest runs processors over in-memory input with a fake
+// clock, so pipeline configurations can be tested deterministically without
+// temporary directories or sleeps.
+package processortest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/processor"
+)
+
+// Start is the time the clock of a Harness starts at
+var Start = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Harness runs a processor with a single worker, a fake clock and a
+// recording sink. Entries of one source reach the sink in the order given.
+type Harness struct {
+	Clock *clock.Fake
+	Sink  *Sink
+	proc  *processor.LogProcessor
+}
+
+// New creates a harness around a processor configured with opts, which may
+// override the clock and the number of workers
+func New(opts ...processor.Option) *Harness {
+	h := &Harness{Clock: clock.NewFake(Start), Sink: &Sink{}}
+	opts = append([]processor.Option{
+		processor.WithClock(h.Clock),
+		processor.WithWorkers(1),
+		processor.WithSink(h.Sink),
+	}, opts...)
+	h.proc = processor.NewLogProcessor("", opts...)
+	return h
+}
+
+// Processor returns the processor under test, e.g. to add middleware
+func (h *Harness) Processor() *processor.LogProcessor {
+	return h.proc
+}
+
+// Run processes the entries of sources and returns the summary once every
+// entry has been processed and the sinks are closed. A processor runs once.
+func (h *Harness) Run(sources ...processor.Source) (*models.LogSummary, error) {
+	err := h.proc.Serve(sources...)
+	return h.proc.GetSummary(), err
+}
+
+// Source emits a fixed set of entries and returns. Entries without a source
+// or ID get them from the source's name and their position, as entries read
+// from files do.
+type Source struct {
+	name    string
+	entries []models.LogEntry
+	parser  parser.Parser
+	text    string
+}
+
+// Entries creates a source of entries
+func Entries(name string, entries ...models.LogEntry) *Source {
+	return &Source{name: name, entries: entries}
+}
+
+// Lines creates a source of the entries p parses from text, failing the run
+// if text cannot be parsed
+func Lines(name string, p parser.Parser, text string) *Source {
+	return &Source{name: name, parser: p, text: text}
+}
+
+// Run implements processor.Source
+func (s *Source) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	entries := s.entries
+	if s.parser != nil {
+		entries = nil
+		err := s.parser.Parse(strings.NewReader(s.text), func(entry models.LogEntry) error {
+			entries = append(entries, entry)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", s.name, err)
+		}
+	}
+	for i, entry := range entries {
+		if entry.Source == "" {
+			entry.Source = s.name
+		}
+		if entry.ID == "" {
+			entry.ID = fmt.Sprintf("%s:%d", s.name, i+1)
+		}
+		if err := emit(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sink records the entries written to it
+type Sink struct {
+	mu      sync.Mutex
+	entries []models.LogEntry
+	closed  bool
+}
+
+func (s *Sink) Write(entry models.LogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Entries returns the entries written so far, in the order written
+func (s *Sink) Entries() []models.LogEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.LogEntry(nil), s.entries...)
+}
+
+// Closed reports whether the processor has closed the sink
+func (s *Sink) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
@@ -0,0 +1,87 @@
+package processortest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/processor"
+)
+
+func TestHarness(t *testing.T) {
+	h := New()
+	h.Processor().Use(processor.MinLevel(models.WARNING))
+
+	lines := Lines("app.json", parser.JSONParser{}, `{"timestamp":"2023-01-01T10:00:00Z","level":"INFO","service":"api","message":"started"}
+{"timestamp":"2023-01-01T10:01:00Z","level":"ERROR","service":"api","message":"failed"}
+{"timestamp":"2023-01-01T10:02:00Z","level":"WARNING","service":"db","message":"slow"}
+`)
+	summary, err := h.Run(lines)
+	if err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+
+	if summary.TotalEntries != 2 {
+		t.Errorf("Expected 2 entries at WARNING or above, got %d", summary.TotalEntries)
+	}
+	if summary.Accounting.Filtered != 1 {
+		t.Errorf("Expected 1 filtered entry, got %d", summary.Accounting.Filtered)
+	}
+	var ids []string
+	for _, entry := range h.Sink.Entries() {
+		ids = append(ids, entry.ID)
+	}
+	if got := strings.Join(ids, ","); got != "app.json:2,app.json:3" {
+		t.Errorf("Expected entries app.json:2,app.json:3 in order, got %s", got)
+	}
+	if !h.Sink.Closed() {
+		t.Errorf("Expected the sink to be closed")
+	}
+}
+
+func TestHarnessParseError(t *testing.T) {
+	_, err := New().Run(Lines("bad.json", parser.JSONParser{}, "not json\n"))
+	if err == nil {
+		t.Errorf("Expected an error for unparseable input")
+	}
+}
+
+// stepSource emits entries and advances the clock between them
+type stepSource struct {
+	h       *Harness
+	step    time.Duration
+	entries []models.LogEntry
+	ticked  chan *models.LogSummary
+}
+
+func (s *stepSource) Run(ctx context.Context, emit func(models.LogEntry) error) error {
+	for _, entry := range s.entries {
+		if err := emit(entry); err != nil {
+			return err
+		}
+		s.h.Clock.Advance(s.step)
+		<-s.ticked
+	}
+	return nil
+}
+
+func TestHarnessClock(t *testing.T) {
+	ticked := make(chan *models.LogSummary)
+	h := New(processor.WithSnapshots(time.Minute, 0, func(summary *models.LogSummary) {
+		ticked <- summary
+	}))
+	source := &stepSource{h: h, step: time.Minute, ticked: ticked, entries: []models.LogEntry{
+		{ID: "1", Level: models.INFO, Service: "api"},
+		{ID: "2", Level: models.INFO, Service: "api"},
+		{ID: "3", Level: models.INFO, Service: "api"},
+	}}
+	if _, err := h.Run(source); err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+	if now := h.Clock.Now(); !now.Equal(Start.Add(3 * time.Minute)) {
+		t.Errorf("Expected the clock at %v, got %v", Start.Add(3*time.Minute), now)
+	}
+}
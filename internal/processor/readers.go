@@ -0,0 +1,287 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/mmap"
+)
+
+// Sizes of file reads
+const (
+	// bufferMaxSize is the largest file a reader loads into memory; larger
+	// files are read while they are parsed
+	bufferMaxSize = 64 << 20
+	// readChunk is how much is read at once, so reading a large file stops
+	// soon after its context is done
+	readChunk = 4 << 20
+)
+
+// defaultReaders is the number of files read at once unless WithReaders
+// says otherwise
+const defaultReaders = 16
+
+// WithReaders sets how many input files are read from storage at once.
+// Reading waits on the disk or network rather than the CPU, so slow shared
+// storage such as NFS or object store mounts benefits from many readers.
+// Defaults to 16.
+func WithReaders(n int) Option {
+	return func(lp *LogProcessor) {
+		lp.readers = n
+	}
+}
+
+// WithParsers sets how many read files are parsed at once. Parsing is CPU
+// bound, so more parsers than CPUs only contend. Defaults to GOMAXPROCS.
+func WithParsers(n int) Option {
+	return func(lp *LogProcessor) {
+		lp.parsers = n
+	}
+}
+
+// fetchedFile is an input file opened, and if small enough read into
+// memory, by a reader, waiting to be parsed
+type fetchedFile struct {
+	path string
+	// r is the content of the file, nil if it could not be read
+	r          readerAt
+	size       int64
+	compressed bool
+	archive    bool
+	// started is when reading the file began
+	started time.Time
+	// err is the error reading the file, in which case there is nothing to
+	// parse
+	err     error
+	closers []func() error
+}
+
+// Close releases the open file or memory mapping behind the file
+func (f *fetchedFile) Close() error {
+	var err error
+	for _, c := range f.closers {
+		if cerr := c(); err == nil {
+			err = cerr
+		}
+	}
+	f.closers = nil
+	return err
+}
+
+// readFiles reads files with a pool of readers and parses them with a pool of
+// parsers, sized independently, and records the result of each file. Each
+// parser has one more read file waiting, so parsers do not wait on storage
+// while readers keep up.
+func (p *LogProcessor) readFiles(files []string) {
+	p.filesPending.Add(int64(len(files)))
+	paths := make(chan string)
+	fetched := make(chan *fetchedFile, p.parsers)
+
+	var readers sync.WaitGroup
+	for i := 0; i < min(p.readers, len(files)); i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for path := range paths {
+				fetched <- p.fetch(path)
+			}
+		}()
+	}
+	var parsers sync.WaitGroup
+	for i := 0; i < min(p.parsers, len(files)); i++ {
+		parsers.Add(1)
+		go func() {
+			defer parsers.Done()
+			for f := range fetched {
+				n, err := 0, f.err
+				if f.r != nil {
+					n, err = p.processFile(f)
+				}
+				p.recordResult(f.path, f.size, f.started, n, err)
+			}
+		}()
+	}
+
+	for _, file := range files {
+		paths <- file
+	}
+	close(paths)
+	readers.Wait()
+	close(fetched)
+	parsers.Wait()
+}
+
+// fetch reads a file for a parser. Reads from a stuck mount cannot be
+// interrupted, so they happen in a goroutine that is abandoned on timeout.
+func (p *LogProcessor) fetch(filePath string) *fetchedFile {
+	started := p.clock.Now()
+	ctx, cancel := p.fileContext()
+	defer cancel()
+
+	type result struct {
+		f   *fetchedFile
+		err error
+	}
+	read := make(chan result, 1)
+	go func() {
+		timed := p.stages.read.start()
+		f, err := p.fetchFile(ctx, filePath)
+		p.stages.read.stop(timed, 0)
+		read <- result{f, err}
+	}()
+
+	var f *fetchedFile
+	var err error
+	select {
+	case r := <-read:
+		f, err = r.f, r.err
+	case <-ctx.Done():
+		// Release the file should the read still complete
+		go func() {
+			if r := <-read; r.f != nil {
+				r.f.Close()
+			}
+		}()
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		if f != nil {
+			f.Close()
+		}
+		f, err = nil, fmt.Errorf("%w after %v", ErrFileTimeout, p.fileTimeout)
+	case ctx.Err() != nil && err != nil:
+		// Stopped, which is not an error of the file
+		err = nil
+	}
+	if f == nil {
+		// Without a reader there is nothing to parse, also when stopped
+		// before the file was read
+		f = &fetchedFile{path: filePath, err: err}
+	}
+	f.started = started
+	return f
+}
+
+// fetchFile opens a file after applying the in-use policy and, unless it is
+// larger than bufferMaxSize or memory mapped, reads it into memory, so it
+// is parsed without waiting on storage
+func (p *LogProcessor) fetchFile(ctx context.Context, filePath string) (*fetchedFile, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	f := &fetchedFile{
+		path:       filePath,
+		r:          file,
+		compressed: strings.HasSuffix(filePath, ".gz"),
+		archive:    isArchive(filePath),
+		closers:    []func() error{file.Close},
+	}
+
+	if info, err := file.Stat(); err == nil {
+		f.size = info.Size()
+	}
+	stable, err := p.stableSize(ctx, file, f.size, f.compressed || f.archive)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	mapped := false
+	if p.mmap {
+		// Entries copy what they keep, so the mapping can go once parsed
+		if m, err := mmap.Open(filePath); err == nil {
+			f.r, mapped = bytes.NewReader(m.Bytes()), true
+			f.closers = append(f.closers, m.Close)
+		}
+	}
+	if stable != f.size {
+		// Leave out what was written after the check
+		f.r = io.NewSectionReader(f.r, 0, stable)
+		f.size = stable
+	}
+
+	if !mapped && f.size <= bufferMaxSize {
+		data, err := readAll(ctx, f.r, f.size)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		f.r, f.size = bytes.NewReader(data), int64(len(data))
+	}
+	return f, nil
+}
+
+// readAll reads the first size bytes of r, or fewer if it is shorter,
+// checking ctx between chunks
+func readAll(ctx context.Context, r io.ReaderAt, size int64) ([]byte, error) {
+	data := make([]byte, size)
+	for off := int64(0); off < size; off += readChunk {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(off+readChunk, size)
+		n, err := r.ReadAt(data[off:end], off)
+		if n < int(end-off) {
+			if err == io.EOF {
+				// Truncated since it was opened
+				return data[:off+int64(n)], nil
+			}
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// recordResult records the outcome of processing a file of size bytes,
+// started at started, from which n entries were read
+func (p *LogProcessor) recordResult(file string, size int64, started time.Time, n int, err error) {
+	result := FileResult{Path: file, Status: FileOK, Entries: n, Duration: p.clock.Now().Sub(started), Size: size}
+	if h, ok := p.hashes.Load(file); ok {
+		result.SHA256, result.Bytes = h.(fileHash).sum, h.(fileHash).size
+	}
+	source := p.sourceName(file)
+	var archiveErr *ArchiveError
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrFileInUse):
+		fmt.Printf("Skipping file %s: %v\n", file, err)
+		p.metrics.Count("skipped_files", 1, "source:"+source)
+		p.failures.Store(source, "skipped: "+err.Error())
+		result.Status, result.Error = FileSkipped, err.Error()
+	case errors.Is(err, fs.ErrPermission):
+		// Unreadable files are left out like files in use, not failed
+		fmt.Printf("Skipping file %s: permission denied\n", file)
+		p.metrics.Count("unreadable_files", 1, "source:"+source)
+		p.failures.Store(source, "skipped: permission denied")
+		result.Status, result.Error = FileSkipped, "permission denied"
+	case errors.As(err, &archiveErr):
+		fmt.Printf("Error processing file %s: %v\n", file, err)
+		p.metrics.Count("corrupt_archives", 1, "source:"+source)
+		p.corrupt.Store(source, archiveErr.Reason)
+		result.Status, result.Error = FileCorrupt, archiveErr.Reason
+	default:
+		fmt.Printf("Error processing file %s: %v\n", file, err)
+		p.metrics.Count("parse_errors", 1, "source:"+source)
+		p.failures.Store(source, err.Error())
+		result.Status, result.Error = FileFailed, err.Error()
+	}
+	p.results.Store(file, result)
+	p.filesPending.Add(-1)
+	p.progress.Add(1)
+}
+
+// defaultParsers is the number of files parsed at once unless WithParsers
+// says otherwise
+func defaultParsers() int {
+	return runtime.GOMAXPROCS(0)
+}
@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Outcomes of processing an input file
+const (
+	FileOK      = "ok"
+	FileSkipped = "skipped"
+	FileCorrupt = "corrupt"
+	FileFailed  = "failed"
+	// FilePartial is a file read whole of which some entries did not reach
+	// every sink, or an archive of which some files failed
+	FilePartial = "partial"
+)
+
+// FileResult is the outcome of processing one input file
+type FileResult struct {
+	Path   string
+	Status string
+	// Error explains why the file was skipped, damaged, failed or partial
+	Error string
+	// Entries is the number of entries read from the file
+	Entries int
+	// Duration is the time spent reading the file and queueing its entries
+	Duration time.Duration
+	// Size is the number of bytes in the file, before decompression
+	Size int64
+	// SHA256 is the hex digest of the Bytes read from the file, set with
+	// WithFileHashes
+	SHA256 string
+	Bytes  int64
+}
+
+// BytesRead returns the total size of the input files processed by Start
+func (p *LogProcessor) BytesRead() int64 {
+	var n int64
+	p.results.Range(func(_, result any) bool {
+		n += result.(FileResult).Size
+		return true
+	})
+	return n
+}
+
+// FileResults returns the outcome of every input file processed by Start,
+// sorted by path
+func (p *LogProcessor) FileResults() []FileResult {
+	var results []FileResult
+	p.results.Range(func(_, result any) bool {
+		r := result.(FileResult)
+		if r.Status == FileOK {
+			if reason := p.incomplete(p.sourceName(r.Path)); reason != "" {
+				r.Status, r.Error = FilePartial, reason
+			}
+		}
+		results = append(results, r)
+		return true
+	})
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	return results
+}
+
+// incomplete explains why the entries of the file named source were not all
+// delivered, or returns "" if they were. The files inside an archive have
+// sources below the archive's.
+func (p *LogProcessor) incomplete(source string) string {
+	inside := func(key any) bool {
+		return strings.HasPrefix(key.(string), source+"/")
+	}
+	reason := ""
+	p.failures.Range(func(key, err any) bool {
+		if inside(key) {
+			reason = key.(string) + ": " + err.(string)
+		}
+		return reason == ""
+	})
+	if reason != "" {
+		return reason
+	}
+	p.undelivered.Range(func(key, _ any) bool {
+		if key == source || inside(key) {
+			reason = "entries of " + key.(string) + " were not delivered to every sink"
+		}
+		return reason == ""
+	})
+	return reason
+}
@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// serveSeq numbers network entries without IDs. It is shared by every
+// processor so a server restarted in the same process never reuses an ID
+// already counted by an analyzer it shares with an earlier one.
+var serveSeq uint64
+
+// serveBoot sets apart the IDs numbered by this process from those of
+// earlier ones, whose analyzer state may be restored with -state-in
+var serveBoot = newBootID()
+
+// newBootID returns a random ID for the numbering of one process
+func newBootID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Source produces log entries from a long-running input such as a network
+// listener. Run must return once ctx is cancelled.
+type Source interface {
+	Run(ctx context.Context, emit func(models.LogEntry) error) error
+}
+
+// WithMaxAge makes Serve drop entries whose timestamp is more than d in the
+// past, counted as DropStale, so a backlog that built up while the processor
+// was down does not raise alerts for long-resolved problems. Entries without
+// a timestamp are kept.
+func WithMaxAge(d time.Duration) Option {
+	return func(lp *LogProcessor) {
+		lp.maxAge = d
+	}
+}
+
+// Serve runs the given sources until Stop is called, analyzing every entry
+// they produce. It returns the first error reported by a source.
+func (p *LogProcessor) Serve(sources ...Source) error {
+	if len(sources) == 0 {
+		return fmt.Errorf("no sources to serve")
+	}
+
+	if p.metrics == nil {
+		p.metrics = noMetrics{}
+	}
+	if p.ordering == nil {
+		p.ordering = newOrderTracker()
+	}
+	p.setDefaults()
+	p.accounting.track(p.analyzer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-p.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	p.startPriority()
+	workers := p.startWorkers()
+	stopSnapshots := p.startSnapshots()
+	defer stopSnapshots()
+	stopWatchdog := p.startWatchdog()
+	defer stopWatchdog()
+
+	emit := func(entry models.LogEntry) error {
+		// Network entries rarely carry IDs; number them so dedup keeps them apart
+		if entry.ID == "" {
+			entry.ID = fmt.Sprintf("%s:%s-%d", entry.Source, serveBoot, atomic.AddUint64(&serveSeq, 1))
+		}
+		if p.ordering.observe(entry) {
+			p.metrics.Count("out_of_order", 1, "source:"+entry.Source)
+		}
+		p.accounting.read.Add(1)
+		if p.maxAge > 0 && !entry.Timestamp.IsZero() && p.clock.Now().Sub(entry.Timestamp) > p.maxAge {
+			p.metrics.Count("stale_entries", 1, "source:"+entry.Source)
+			p.accounting.drop(DropStale, 1)
+			return nil
+		}
+		p.inflight.add()
+		sending := p.stages.now()
+		select {
+		case p.lane(entry) <- entry:
+			p.stages.waited(sending)
+			return nil
+		case <-ctx.Done():
+			p.inflight.done()
+			p.accounting.drop(DropStopped, 1)
+			return ctx.Err()
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for _, src := range sources {
+		wg.Add(1)
+		go func(src Source) {
+			defer wg.Done()
+			if err := src.Run(ctx, emit); err != nil && ctx.Err() == nil {
+				errOnce.Do(func() {
+					firstErr = err
+					// A failed source shuts the whole server down
+					cancel()
+				})
+			}
+		}(src)
+	}
+
+	wg.Wait()
+	p.closeLanes()
+	workers.Wait()
+
+	if err := p.finishSecondPass(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	if err := p.closeSinks(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
@@ -0,0 +1,28 @@
+package processor
+
+import (
+	"github.com/interview/junior-go-challenge/internal/cluster"
+)
+
+// WithShard processes only the input files owned by shard, so several
+// processors can split one input between them
+func WithShard(shard cluster.Shard) Option {
+	return func(lp *LogProcessor) {
+		lp.shard = &shard
+	}
+}
+
+// ownedFiles returns the files of this processor's shard, identified by
+// their path relative to the input so workers mounting it elsewhere agree
+func (p *LogProcessor) ownedFiles(files []string) []string {
+	if p.shard == nil {
+		return files
+	}
+	var owned []string
+	for _, file := range files {
+		if p.shard.Owns(p.relPath(file)) {
+			owned = append(owned, file)
+		}
+	}
+	return owned
+}
@@ -0,0 +1,81 @@
+package processor
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// SnapshotFunc receives intermediate summaries while processing is running
+type SnapshotFunc func(summary *models.LogSummary)
+
+// snapshots decides when intermediate summaries are taken
+type snapshots struct {
+	interval time.Duration
+	every    int64
+	fn       SnapshotFunc
+
+	count atomic.Int64
+	busy  atomic.Bool
+}
+
+// WithSnapshots calls fn with the current summary every interval and after
+// every n new entries while processing runs. A zero interval or n disables
+// that trigger. Snapshots are skipped while fn is still handling the last one.
+func WithSnapshots(interval time.Duration, n int, fn SnapshotFunc) Option {
+	return func(lp *LogProcessor) {
+		lp.snapshots = &snapshots{interval: interval, every: int64(n), fn: fn}
+	}
+}
+
+// startSnapshots takes snapshots on the interval until the returned function
+// is called
+func (p *LogProcessor) startSnapshots() func() {
+	s := p.snapshots
+	if s == nil || s.interval <= 0 {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	// Created before returning, so a fake clock advanced next ticks it
+	ticker := p.clock.NewTicker(s.interval)
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				p.snapshot()
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// countSnapshot counts a new entry, taking a snapshot every n entries
+func (p *LogProcessor) countSnapshot() {
+	s := p.snapshots
+	if s == nil || s.every <= 0 {
+		return
+	}
+	if s.count.Add(1)%s.every == 0 {
+		p.snapshot()
+	}
+}
+
+// snapshot passes the current summary to the snapshot function unless it is
+// still busy with the previous one
+func (p *LogProcessor) snapshot() {
+	s := p.snapshots
+	if !s.busy.CompareAndSwap(false, true) {
+		return
+	}
+	defer s.busy.Store(false)
+	s.fn(p.GetSummary())
+}
@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SourceNames decides how input files are named in the Source of their
+// entries, in per-file statistics and in failures
+type SourceNames string
+
+const (
+	// SourceRelative names files by their path relative to the input
+	// directory, e.g. eu/app.json, which is the file name for files directly
+	// in it
+	SourceRelative SourceNames = "relative"
+	// SourceBase names files by their file name alone, so files of the same
+	// name in different directories share a source
+	SourceBase SourceNames = "base"
+	// SourceAbsolute names files by their absolute path
+	SourceAbsolute SourceNames = "absolute"
+)
+
+// ParseSourceNames validates a source naming name
+func ParseSourceNames(name string) (SourceNames, error) {
+	switch names := SourceNames(name); names {
+	case SourceRelative, SourceBase, SourceAbsolute:
+		return names, nil
+	default:
+		return "", fmt.Errorf("unknown source naming %q (supported: relative, base, absolute)", name)
+	}
+}
+
+// WithSourceNames sets how input files are named in the Source of their
+// entries. Defaults to SourceRelative, so files of the same name in
+// different directories matched by the pattern, e.g. */app.json, are told
+// apart. Entries without IDs are numbered per source, so with SourceBase such
+// files also share IDs and are deduplicated against each other.
+func WithSourceNames(names SourceNames) Option {
+	return func(lp *LogProcessor) {
+		lp.sourceNames = names
+	}
+}
+
+// sourceName returns the source of the entries of file
+func (p *LogProcessor) sourceName(file string) string {
+	switch p.sourceNames {
+	case SourceBase:
+		return filepath.Base(file)
+	case SourceAbsolute:
+		if abs, err := filepath.Abs(file); err == nil {
+			return filepath.ToSlash(abs)
+		}
+		return filepath.ToSlash(file)
+	}
+	return p.relPath(file)
+}
+
+// relPath returns the path of file relative to the input, with forward
+// slashes, or its name if the input is the file itself
+func (p *LogProcessor) relPath(file string) string {
+	rel, err := filepath.Rel(p.inputDir, file)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(file)
+	}
+	return filepath.ToSlash(rel)
+}
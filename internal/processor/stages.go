@@ -0,0 +1,252 @@
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// StageStats describes the work done in one stage of the pipeline
+type StageStats struct {
+	Name    string
+	Entries int64
+	// Time is the time spent in the stage, summed over the goroutines
+	// running it
+	Time time.Duration
+}
+
+// Throughput returns the entries the stage handles per second of its time
+func (s StageStats) Throughput() float64 {
+	if s.Time <= 0 {
+		return 0
+	}
+	return float64(s.Entries) / s.Time.Seconds()
+}
+
+// QueueStats describes the processing channel between the parsers and the
+// workers
+type QueueStats struct {
+	Capacity  int
+	MaxDepth  int
+	MeanDepth float64
+	// Blocked is the time parsers spent waiting for room in the channel,
+	// which grows when the workers are the bottleneck
+	Blocked time.Duration
+	// Prioritized is the number of entries queued on the priority lane
+	Prioritized int64
+}
+
+// StageReport holds the metrics of every stage, in pipeline order
+type StageReport struct {
+	Stages []StageStats
+	Queue  QueueStats
+	// PeakWorkers is the most workers that ran at once
+	PeakWorkers int
+}
+
+// Bottleneck returns the stage the most time was spent in and its share of
+// the time of all stages, or false if nothing was timed
+func (r StageReport) Bottleneck() (StageStats, float64, bool) {
+	var total time.Duration
+	var slowest StageStats
+	for _, s := range r.Stages {
+		total += s.Time
+		if s.Time > slowest.Time {
+			slowest = s
+		}
+	}
+	if total <= 0 {
+		return StageStats{}, 0, false
+	}
+	return slowest, float64(slowest.Time) / float64(total), true
+}
+
+// WithStageMetrics times every stage of the pipeline and samples the depth
+// of the processing channel, for Stages. Timing costs a clock read per
+// entry and stage, so it is off by default.
+func WithStageMetrics() Option {
+	return func(lp *LogProcessor) {
+		lp.stages.enabled = true
+	}
+}
+
+// stageTimer accumulates the entries and time of one stage. A nil timer
+// records nothing.
+type stageTimer struct {
+	name    string
+	entries atomic.Int64
+	nanos   atomic.Int64
+}
+
+// start returns the time a stage is entered, if it is timed
+func (t *stageTimer) start() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// stop records n entries handled since start
+func (t *stageTimer) stop(start time.Time, n int) {
+	if t == nil {
+		return
+	}
+	t.entries.Add(int64(n))
+	t.nanos.Add(int64(time.Since(start)))
+}
+
+// count records n entries handled without timing them, for stages whose
+// entries are only known later
+func (t *stageTimer) count(n int) {
+	if t != nil {
+		t.entries.Add(int64(n))
+	}
+}
+
+// stageMetrics holds the timers of the stages of a processor. The timers
+// are nil unless stage metrics are enabled.
+type stageMetrics struct {
+	enabled bool
+
+	read       *stageTimer
+	parse      *stageTimer
+	middleware []*stageTimer
+	analyze    *stageTimer
+	sections   []*stageTimer
+	secondPass *stageTimer
+	sinks      []*stageTimer
+	// order lists the timers in pipeline order
+	order []*stageTimer
+
+	depthSum   atomic.Int64
+	depthCount atomic.Int64
+	depthMax   atomic.Int64
+	blocked    atomic.Int64
+}
+
+// setup creates the timers for the stages of p. It runs before the workers
+// start, once middleware, sections and sinks are known.
+func (m *stageMetrics) setup(p *LogProcessor) {
+	if !m.enabled || m.order != nil {
+		return
+	}
+	add := func(name string) *stageTimer {
+		t := &stageTimer{name: name}
+		m.order = append(m.order, t)
+		return t
+	}
+	m.read = add("read")
+	m.parse = add("parse")
+	for _, mw := range p.middleware {
+		m.middleware = append(m.middleware, add("middleware "+funcName(mw)))
+	}
+	m.analyze = add("analyze")
+	for _, s := range p.streamed {
+		m.sections = append(m.sections, add("section "+s.Name()))
+	}
+	if p.second != nil {
+		m.secondPass = add("second pass")
+	}
+	for _, s := range p.sinks {
+		m.sinks = append(m.sinks, add("sink "+strings.TrimPrefix(fmt.Sprintf("%T", s), "*")))
+	}
+}
+
+// middlewareTimer returns the timer of the i-th middleware, if timed
+func (m *stageMetrics) middlewareTimer(i int) *stageTimer {
+	if m.middleware == nil {
+		return nil
+	}
+	return m.middleware[i]
+}
+
+// sectionTimer returns the timer of the i-th streamed section, if timed
+func (m *stageMetrics) sectionTimer(i int) *stageTimer {
+	if m.sections == nil {
+		return nil
+	}
+	return m.sections[i]
+}
+
+// sinkTimer returns the timer of the i-th sink, if timed
+func (m *stageMetrics) sinkTimer(i int) *stageTimer {
+	if m.sinks == nil {
+		return nil
+	}
+	return m.sinks[i]
+}
+
+// observeDepth samples the number of entries waiting in the channel
+func (m *stageMetrics) observeDepth(depth int) {
+	if !m.enabled {
+		return
+	}
+	m.depthSum.Add(int64(depth))
+	m.depthCount.Add(1)
+	for {
+		max := m.depthMax.Load()
+		if int64(depth) <= max || m.depthMax.CompareAndSwap(max, int64(depth)) {
+			return
+		}
+	}
+}
+
+// waited records the time a reader spent sending an entry since start
+func (m *stageMetrics) waited(start time.Time) {
+	if m.enabled {
+		m.blocked.Add(int64(time.Since(start)))
+	}
+}
+
+// now returns the current time if stages are timed
+func (m *stageMetrics) now() time.Time {
+	if !m.enabled {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// Stages returns the time spent in every stage of the pipeline so far and
+// the depth of the processing channel, if WithStageMetrics is set
+func (p *LogProcessor) Stages() StageReport {
+	m := &p.stages
+	var r StageReport
+	for _, t := range m.order {
+		r.Stages = append(r.Stages, StageStats{Name: t.name, Entries: t.entries.Load(), Time: time.Duration(t.nanos.Load())})
+	}
+	r.Queue = QueueStats{
+		Capacity:    cap(p.processingCh),
+		MaxDepth:    int(m.depthMax.Load()),
+		Blocked:     time.Duration(m.blocked.Load()),
+		Prioritized: p.prioritized.Load(),
+	}
+	if n := m.depthCount.Load(); n > 0 {
+		r.Queue.MeanDepth = float64(m.depthSum.Load()) / float64(n)
+	}
+	if p.pool != nil {
+		r.PeakWorkers = int(p.pool.peak.Load())
+	}
+	return r
+}
+
+// closureSuffix matches the suffixes the compiler gives closures and method
+// values
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$|-fm$`)
+
+// funcName names a function by its package and the function that created it,
+// e.g. processor.MinLevel for the middleware returned by MinLevel
+func funcName(fn interface{}) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "?"
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return closureSuffix.ReplaceAllString(name, "")
+}
@@ -0,0 +1,64 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrFileTimeout is reported for files that took longer than the timeout set
+// with WithFileTimeout to read
+var ErrFileTimeout = errors.New("timed out reading file")
+
+// WithFileTimeout gives up on a file that has not been read within d, e.g.
+// because it sits on a stuck network mount, so it cannot hold up the run.
+// Entries of such a file are not analyzed.
+func WithFileTimeout(d time.Duration) Option {
+	return func(lp *LogProcessor) {
+		lp.fileTimeout = d
+	}
+}
+
+// fileContext returns the context for reading one file. It is cancelled when
+// the file timeout expires or the processor is stopped.
+func (p *LogProcessor) fileContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if p.fileTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), p.fileTimeout)
+	}
+	go func() {
+		select {
+		case <-p.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// readerAt is an input file, which parsers may read sequentially or by range
+type readerAt interface {
+	io.Reader
+	io.ReaderAt
+}
+
+// ctxReader fails reads once its context is done
+type ctxReader struct {
+	ctx context.Context
+	r   readerAt
+}
+
+func (c ctxReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+func (c ctxReader) ReadAt(b []byte, off int64) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.ReadAt(b, off)
+}
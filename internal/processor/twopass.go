@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/mergesort"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// secondPass feeds order-dependent sections. During the first pass every
+// new entry is handed to an external merge sort; once all input has been
+// read, the sorted entries are replayed to the sections one at a time, so
+// their results do not depend on file or worker order.
+type secondPass struct {
+	sections []analyzer.Section
+	sorter   *mergesort.Sorter
+	pipe     *io.PipeWriter
+	done     chan error
+}
+
+// startSecondPass splits the sections into those fed during the first pass
+// and those fed in the second. It starts the second pass if any section
+// needs it.
+func (p *LogProcessor) startSecondPass() {
+	p.streamed = nil
+	p.second = nil
+	var ordered []analyzer.Section
+	for _, s := range p.sections {
+		if _, ok := s.(analyzer.OrderedSection); ok {
+			ordered = append(ordered, s)
+		} else {
+			p.streamed = append(p.streamed, s)
+		}
+	}
+	if len(ordered) == 0 {
+		return
+	}
+
+	r, w := io.Pipe()
+	pass := &secondPass{
+		sections: ordered,
+		sorter:   mergesort.NewSorter(w, mergesort.DefaultRunSize),
+		pipe:     w,
+		done:     make(chan error, 1),
+	}
+	go func() {
+		err := pass.replay(r)
+		// Unblock the sorter if replaying stopped early
+		r.CloseWithError(err)
+		pass.done <- err
+	}()
+	p.second = pass
+}
+
+// finishSecondPass completes the second pass, if one was started
+func (p *LogProcessor) finishSecondPass() error {
+	if p.second == nil {
+		return nil
+	}
+	started := p.stages.secondPass.start()
+	defer p.stages.secondPass.stop(started, 0)
+	return p.second.finish()
+}
+
+// write records an entry of the first pass
+func (s *secondPass) write(entry models.LogEntry) error {
+	return s.sorter.Write(entry)
+}
+
+// replay decodes the sorted entries and observes them in order
+func (s *secondPass) replay(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var entry models.LogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read sorted entries: %w", err)
+		}
+		for _, section := range s.sections {
+			section.Observe(entry)
+		}
+	}
+}
+
+// finish sorts the entries of the first pass and waits until the sections
+// have observed them
+func (s *secondPass) finish() error {
+	err := s.sorter.Close()
+	s.pipe.CloseWithError(err)
+	if replayErr := <-s.done; err == nil {
+		err = replayErr
+	}
+	if err != nil {
+		return fmt.Errorf("second pass failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// ErrVerifyMismatch is returned by Verify when the recount differs from the
+// summary
+var ErrVerifyMismatch = errors.New("summary does not match recount")
+
+// Recount holds the key counts of the input, counted independently of the
+// analyzer
+type Recount struct {
+	TotalEntries   int
+	InferredLevels int
+	ByLevel        map[models.LogLevel]int
+	ByService      map[string]int
+}
+
+// Recount reads the files Start processed again, one at a time in the
+// calling goroutine, and counts their entries without the workers or the
+// analyzer. Middleware is applied and duplicate IDs are skipped as during
+// processing. Files that were skipped or failed are left out; of corrupt
+// archives the entries before the damage are counted.
+func (p *LogProcessor) Recount() (*Recount, error) {
+	// A separate processor, so reading again leaves the ordering statistics
+	// and metrics alone and large files are not decoded in parallel
+	reader := &LogProcessor{
+		inputDir:      p.inputDir,
+		sourceNames:   p.sourceNames,
+		parser:        p.parser,
+		pattern:       p.pattern,
+		encoding:      p.encoding,
+		inUse:         p.inUse,
+		quietPeriod:   p.quietPeriod,
+		decodeWorkers: 1,
+		ordering:      newOrderTracker(),
+		metrics:       noMetrics{},
+		clock:         p.clock,
+	}
+
+	recount := &Recount{
+		ByLevel:   make(map[models.LogLevel]int),
+		ByService: make(map[string]int),
+	}
+	seen := make(map[string]bool)
+	for _, result := range p.FileResults() {
+		if result.Status != FileOK && result.Status != FilePartial && result.Status != FileCorrupt {
+			continue
+		}
+		entries, err := reader.readFile(context.Background(), result.Path)
+		var archiveErr *ArchiveError
+		if err != nil && !errors.As(err, &archiveErr) {
+			return nil, fmt.Errorf("failed to recount %s: %w", result.Path, err)
+		}
+		for _, entry := range entries {
+			if entry, ok := p.filter(entry); ok && !seen[entry.ID] {
+				seen[entry.ID] = true
+				recount.add(entry)
+			}
+		}
+	}
+	return recount, nil
+}
+
+// add counts an entry
+func (r *Recount) add(entry models.LogEntry) {
+	r.TotalEntries++
+	r.ByLevel[entry.Level]++
+	r.ByService[entry.Service]++
+	if entry.LevelInferred {
+		r.InferredLevels++
+	}
+}
+
+// Verify recounts the input after Start has returned and compares the
+// totals, level and service counts with the analyzer's summary. A
+// difference is returned as an error wrapping ErrVerifyMismatch that lists
+// every count that differs. The summary must only hold this processor's
+// entries, i.e. not restored or merged state, and the input must not have
+// changed since it was processed.
+func (p *LogProcessor) Verify() error {
+	recount, err := p.Recount()
+	if err != nil {
+		return err
+	}
+	summary := p.analyzer.GetSummary()
+
+	var diffs []string
+	differ := func(name string, got, want int) {
+		if got != want {
+			diffs = append(diffs, fmt.Sprintf("%s: summary %d, recount %d", name, got, want))
+		}
+	}
+	differ("total entries", summary.TotalEntries, recount.TotalEntries)
+	differ("inferred levels", summary.InferredLevels, recount.InferredLevels)
+	for _, level := range levelKeys(summary.ByLevel, recount.ByLevel) {
+		differ(fmt.Sprintf("level %q", level), summary.ByLevel[level], recount.ByLevel[level])
+	}
+	for _, service := range serviceKeys(summary.ByService, recount.ByService) {
+		differ(fmt.Sprintf("service %q", service), summary.ByService[service], recount.ByService[service])
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%w: %s", ErrVerifyMismatch, strings.Join(diffs, "; "))
+	}
+	return nil
+}
+
+// levelKeys returns the levels counted in either map, sorted
+func levelKeys(a, b map[models.LogLevel]int) []models.LogLevel {
+	var keys []models.LogLevel
+	for level := range a {
+		keys = append(keys, level)
+	}
+	for level := range b {
+		if _, ok := a[level]; !ok {
+			keys = append(keys, level)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// serviceKeys returns the services counted in either map, sorted
+func serviceKeys(a, b map[string]int) []string {
+	var keys []string
+	for service := range a {
+		keys = append(keys, service)
+	}
+	for service := range b {
+		if _, ok := a[service]; !ok {
+			keys = append(keys, service)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,141 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SkippedPath is a file or directory left out of a recursive scan
+type SkippedPath struct {
+	Path   string
+	Reason string
+}
+
+// WithRecursive finds input files in the subdirectories of the input
+// directory as well, following symbolic links. The pattern is matched
+// against file names, or against paths relative to the input if it contains
+// a slash. Directories that cannot be read, broken links and links leading
+// back into a directory already scanned are skipped and reported by
+// SkippedPaths rather than failing the run.
+func WithRecursive() Option {
+	return func(lp *LogProcessor) {
+		lp.recursive = true
+	}
+}
+
+// SkippedPaths returns the paths a recursive scan left out, sorted by path
+func (p *LogProcessor) SkippedPaths() []SkippedPath {
+	var skipped []SkippedPath
+	p.skipped.Range(func(path, reason any) bool {
+		skipped = append(skipped, SkippedPath{Path: path.(string), Reason: reason.(string)})
+		return true
+	})
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Path < skipped[j].Path })
+	return skipped
+}
+
+// skipPath reports a path left out of the input
+func (p *LogProcessor) skipPath(path, reason string) {
+	fmt.Printf("Skipping %s: %s\n", path, reason)
+	p.metrics.Count("skipped_paths", 1)
+	p.skipped.Store(path, reason)
+	p.failures.Store(p.sourceName(path), "skipped: "+reason)
+}
+
+// walkInput returns the files under the input directory that match the
+// pattern or are archives, if enabled, scanning each directory once however many links
+// lead to it
+func (p *LogProcessor) walkInput() ([]string, error) {
+	if _, err := os.ReadDir(p.inputDir); err != nil {
+		return nil, fmt.Errorf("failed to read input directory: %w", err)
+	}
+
+	var files []string
+	// seen maps the real path of every directory and file found to the
+	// path it was first found under
+	seen := make(map[string]string)
+	var walk func(dir string, ancestors map[string]bool)
+	walk = func(dir string, ancestors map[string]bool) {
+		real, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			p.skipPath(dir, err.Error())
+			return
+		}
+		switch {
+		case ancestors[real]:
+			p.skipPath(dir, "symlink loop back to "+seen[real])
+			return
+		case seen[real] != "":
+			p.skipPath(dir, "already scanned as "+seen[real])
+			return
+		}
+		seen[real] = dir
+		ancestors[real] = true
+		defer delete(ancestors, real)
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			p.skipPath(dir, unreadable(err))
+			return
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			mode := entry.Type()
+			if mode&fs.ModeSymlink != 0 {
+				info, err := os.Stat(path)
+				if err != nil {
+					p.skipPath(path, "broken symlink: "+unreadable(err))
+					continue
+				}
+				mode = info.Mode()
+			}
+			switch {
+			case mode.IsDir():
+				walk(path, ancestors)
+			case mode.IsRegular() && p.matchesInput(path):
+				real, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					p.skipPath(path, err.Error())
+					continue
+				}
+				if first := seen[real]; first != "" {
+					p.skipPath(path, "same file as "+first)
+					continue
+				}
+				seen[real] = path
+				files = append(files, path)
+			}
+		}
+	}
+	walk(p.inputDir, make(map[string]bool))
+	return files, nil
+}
+
+// matchesInput reports whether a file found by a recursive scan is input
+func (p *LogProcessor) matchesInput(path string) bool {
+	name := filepath.Base(path)
+	if strings.Contains(p.pattern, "/") {
+		name = p.relPath(path)
+	}
+	if ok, _ := filepath.Match(p.pattern, name); ok {
+		return true
+	}
+	return p.archives && isArchive(path)
+}
+
+// unreadable describes why a path could not be read
+func unreadable(err error) string {
+	if errors.Is(err, fs.ErrPermission) {
+		return "permission denied"
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,156 @@
+package processor
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Stall describes a pipeline that has made no progress while work remains
+type Stall struct {
+	// For is how long no entry was parsed or finished and no file completed
+	For time.Duration
+	// InFlight is the number of entries queued for or held by workers
+	InFlight int
+	// PendingFiles is the number of input files not yet finished
+	PendingFiles int
+	// StuckWorkers is the number of workers busy with one entry for the
+	// whole stall timeout
+	StuckWorkers int
+}
+
+// WithWatchdog calls onStall when no entry has been parsed or processed and
+// no file finished for timeout while input remains, e.g. because a sink or
+// middleware hangs. If onStall returns true, workers stuck on one entry
+// for the whole timeout are given up on and replaced, and their entries no
+// longer count as in flight. A stuck goroutine cannot be stopped, so it
+// lingers and may still finish its entry later. The watchdog checks again
+// after another timeout.
+func WithWatchdog(timeout time.Duration, onStall func(Stall) bool) Option {
+	return func(lp *LogProcessor) {
+		lp.stallTimeout, lp.onStall = timeout, onStall
+	}
+}
+
+// detached marks a worker the watchdog has replaced
+const detached = -1
+
+// workerState holds when a worker took its current entry, in nanoseconds
+// since the pool started plus one, 0 while it waits for an entry, or
+// detached
+type workerState struct {
+	since atomic.Int64
+}
+
+// handle processes an entry on worker w. It reports false if the watchdog
+// replaced the worker meanwhile, which then has to exit.
+func (p *LogProcessor) handle(w *workerState, entry models.LogEntry) bool {
+	if p.stallTimeout <= 0 {
+		p.process(entry)
+		p.inflight.done()
+		return true
+	}
+	since := int64(p.clock.Now().Sub(p.pool.epoch)) + 1
+	w.since.Store(since)
+	p.process(entry)
+	if !w.since.CompareAndSwap(since, 0) {
+		return false
+	}
+	p.inflight.done()
+	return true
+}
+
+// startWatchdog checks for stalls at a quarter of the stall timeout until
+// the returned function is called
+func (p *LogProcessor) startWatchdog() func() {
+	if p.stallTimeout <= 0 || p.onStall == nil {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	ticker := p.clock.NewTicker(p.stallTimeout / 4)
+	last, lastAt := p.progressCount(), p.clock.Now()
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+			case <-stop:
+				return
+			}
+			now := p.clock.Now()
+			inFlight := p.inflight.pending()
+			files := int(p.filesPending.Load())
+			if progress := p.progressCount(); progress != last || (inFlight == 0 && files == 0) {
+				last, lastAt = progress, now
+				continue
+			}
+			if now.Sub(lastAt) < p.stallTimeout {
+				continue
+			}
+			stall := Stall{For: now.Sub(lastAt), InFlight: inFlight, PendingFiles: files, StuckWorkers: p.pool.stuck(p.clock.Now(), p.stallTimeout)}
+			if p.onStall(stall) {
+				p.replaceStuckWorkers()
+			}
+			// Report again only after another timeout without progress
+			lastAt = now
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+	}
+}
+
+// progressCount sums the entries parsed and finished and the files finished
+func (p *LogProcessor) progressCount() int64 {
+	return p.progress.Load() + p.inflight.finished()
+}
+
+// stuck returns the number of workers busy with one entry for at least d
+func (w *workerPool) stuck(now time.Time, d time.Duration) int {
+	n := 0
+	w.workers.Range(func(key, _ interface{}) bool {
+		if w.busyFor(key.(*workerState), now) >= d {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// busyFor returns how long worker s has been processing its current entry
+func (w *workerPool) busyFor(s *workerState, now time.Time) time.Duration {
+	since := s.since.Load()
+	if since <= 0 {
+		return 0
+	}
+	return now.Sub(w.epoch) - time.Duration(since-1)
+}
+
+// replaceStuckWorkers gives up on the workers busy with one entry for the
+// whole stall timeout and starts as many new ones
+func (p *LogProcessor) replaceStuckWorkers() {
+	pool := p.pool
+	now := p.clock.Now()
+	replaced := 0
+	pool.workers.Range(func(key, _ interface{}) bool {
+		s := key.(*workerState)
+		since := s.since.Load()
+		if pool.busyFor(s, now) >= p.stallTimeout && s.since.CompareAndSwap(since, detached) {
+			pool.workers.Delete(s)
+			pool.n.Add(-1)
+			p.inflight.done()
+			replaced++
+		}
+		return true
+	})
+	// Start the replacements before releasing the stuck workers, so the
+	// pool never looks finished in between
+	p.addWorkers(replaced)
+	for i := 0; i < replaced; i++ {
+		pool.wg.Done()
+	}
+}
@@ -0,0 +1,178 @@
+// Package recent keeps per-minute counts of the latest entries by service,
+// level and error pattern, to answer questions such as "errors of api in the
+// last hour" while serving.
+package recent
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// maxPatternsPerMinute bounds the error patterns counted in a minute of a
+// service; the errors beyond it are only counted by level
+const maxPatternsPerMinute = 1000
+
+// Stats describes the entries of a service, or of all services, since a time
+type Stats struct {
+	// Service is empty for all services
+	Service string
+	Since   time.Time
+	Total   int
+	ByLevel map[models.LogLevel]int
+	// Errors counts the ERROR and FATAL entries
+	Errors int
+	// TopErrors are the most frequent error patterns, most frequent first
+	TopErrors []analyzer.PatternCount
+}
+
+// serviceMinute counts one service's entries in one minute
+type serviceMinute struct {
+	levels map[models.LogLevel]int
+	errors map[string]*analyzer.PatternCount
+}
+
+// Activity counts the entries of the last retention period by minute
+type Activity struct {
+	retention time.Duration
+	clock     clock.Clock
+	form      textnorm.Form
+
+	mu      sync.Mutex
+	minutes map[int64]map[string]*serviceMinute
+	// expired is the minute before which all minutes were dropped
+	expired int64
+}
+
+// New keeps the counts of entries stamped up to retention before the time
+// of c
+func New(retention time.Duration, c clock.Clock) *Activity {
+	return &Activity{retention: retention, clock: c, minutes: make(map[int64]map[string]*serviceMinute)}
+}
+
+// SetNormalization normalizes error messages with form before their pattern
+// is taken. It must be called before any entry is added.
+func (a *Activity) SetNormalization(form textnorm.Form) {
+	a.form = form
+}
+
+// Retention returns how far back the counts reach
+func (a *Activity) Retention() time.Duration {
+	return a.retention
+}
+
+// Add counts an entry. Entries without a timestamp or older than the
+// retention are ignored.
+func (a *Activity) Add(entry models.LogEntry) {
+	if entry.Timestamp.IsZero() {
+		return
+	}
+	oldest := a.oldestMinute()
+	minute := entry.Timestamp.Unix() / 60
+	if minute < oldest {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.expire(oldest)
+	services, ok := a.minutes[minute]
+	if !ok {
+		services = make(map[string]*serviceMinute)
+		a.minutes[minute] = services
+	}
+	m, ok := services[entry.Service]
+	if !ok {
+		m = &serviceMinute{levels: make(map[models.LogLevel]int), errors: make(map[string]*analyzer.PatternCount)}
+		services[entry.Service] = m
+	}
+	m.levels[entry.Level]++
+	if entry.Level.Severity() == 0 || entry.Level.Compare(models.ERROR) < 0 {
+		return
+	}
+	pattern := analyzer.NormalizedPattern(entry.Message, a.form)
+	p, ok := m.errors[pattern]
+	if !ok {
+		if len(m.errors) >= maxPatternsPerMinute {
+			return
+		}
+		p = &analyzer.PatternCount{Pattern: pattern, Example: entry.Message}
+		m.errors[pattern] = p
+	}
+	p.Count++
+}
+
+// oldestMinute returns the first minute within the retention
+func (a *Activity) oldestMinute() int64 {
+	return a.clock.Now().Add(-a.retention).Unix() / 60
+}
+
+// expire drops the minutes before oldest, scanning them once a minute
+func (a *Activity) expire(oldest int64) {
+	if oldest <= a.expired {
+		return
+	}
+	a.expired = oldest
+	for minute := range a.minutes {
+		if minute < oldest {
+			delete(a.minutes, minute)
+		}
+	}
+}
+
+// Stats describes the entries of service, or of all services if it is
+// empty, within the last window, up to top error patterns
+func (a *Activity) Stats(service string, window time.Duration, top int) Stats {
+	since := a.clock.Now().Add(-window)
+	stats := Stats{Service: service, Since: since, ByLevel: make(map[models.LogLevel]int)}
+	first := since.Unix() / 60
+	errors := make(map[string]*analyzer.PatternCount)
+
+	a.mu.Lock()
+	a.expire(a.oldestMinute())
+	for minute, services := range a.minutes {
+		if minute < first {
+			continue
+		}
+		for name, m := range services {
+			if service != "" && name != service {
+				continue
+			}
+			for level, n := range m.levels {
+				stats.ByLevel[level] += n
+				stats.Total += n
+				if level.Severity() != 0 && level.Compare(models.ERROR) >= 0 {
+					stats.Errors += n
+				}
+			}
+			for pattern, p := range m.errors {
+				if e, ok := errors[pattern]; ok {
+					e.Count += p.Count
+				} else {
+					copied := *p
+					errors[pattern] = &copied
+				}
+			}
+		}
+	}
+	a.mu.Unlock()
+
+	for _, p := range errors {
+		stats.TopErrors = append(stats.TopErrors, *p)
+	}
+	sort.Slice(stats.TopErrors, func(i, j int) bool {
+		if stats.TopErrors[i].Count != stats.TopErrors[j].Count {
+			return stats.TopErrors[i].Count > stats.TopErrors[j].Count
+		}
+		return stats.TopErrors[i].Pattern < stats.TopErrors[j].Pattern
+	})
+	if len(stats.TopErrors) > top {
+		stats.TopErrors = stats.TopErrors[:top]
+	}
+	return stats
+}
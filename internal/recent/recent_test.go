@@ -0,0 +1,46 @@
+package recent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestActivityStats(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := clock.NewFake(now)
+	activity := New(3*time.Hour, c)
+
+	add := func(ago time.Duration, level models.LogLevel, service, message string) {
+		activity.Add(models.LogEntry{Timestamp: now.Add(-ago), Level: level, Service: service, Message: message})
+	}
+	add(10*time.Minute, models.ERROR, "api", "query 1 timed out")
+	add(20*time.Minute, models.ERROR, "api", "query 2 timed out")
+	add(30*time.Minute, models.FATAL, "api", "out of memory")
+	add(40*time.Minute, models.INFO, "api", "ok")
+	add(50*time.Minute, models.ERROR, "db", "disk full")
+	add(2*time.Hour, models.ERROR, "api", "old failure")
+	// Beyond the retention
+	add(4*time.Hour, models.ERROR, "api", "ancient failure")
+	activity.Add(models.LogEntry{Level: models.ERROR, Service: "api", Message: "no timestamp"})
+
+	stats := activity.Stats("api", time.Hour, 10)
+	if stats.Total != 4 || stats.Errors != 3 || stats.ByLevel[models.INFO] != 1 {
+		t.Errorf("Unexpected api stats for the last hour: %+v", stats)
+	}
+	if len(stats.TopErrors) != 2 || stats.TopErrors[0].Pattern != "query <*> timed out" || stats.TopErrors[0].Count != 2 {
+		t.Errorf("Unexpected top errors: %+v", stats.TopErrors)
+	}
+
+	if all := activity.Stats("", 24*time.Hour, 1); all.Total != 6 || all.Errors != 5 || len(all.TopErrors) != 1 {
+		t.Errorf("Expected all entries within the retention, got %+v", all)
+	}
+
+	// The entries age out as time passes
+	c.Advance(150 * time.Minute)
+	if stats := activity.Stats("", 24*time.Hour, 10); stats.Total != 3 {
+		t.Errorf("Expected the entries beyond the retention dropped, got %+v", stats)
+	}
+}
@@ -0,0 +1,240 @@
+// Package redis is a minimal Redis client speaking RESP2, enough to read
+// and write streams. A Conn is not safe for concurrent use.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxBulk bounds the size of a bulk string in a reply
+const maxBulk = 512 * 1024 * 1024
+
+// maxArray bounds the number of elements of an array in a reply
+const maxArray = 1024 * 1024
+
+// Error is an error reply from the server, such as "BUSYGROUP Consumer
+// Group name already exists"
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Code returns the first word of the error, e.g. "BUSYGROUP"
+func (e Error) Code() string {
+	code, _, _ := strings.Cut(string(e), " ")
+	return code
+}
+
+// Conn is a connection to a Redis server
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+	// Timeout bounds each command; blocking commands extend it by the time
+	// they block
+	Timeout time.Duration
+}
+
+// Dial connects to the server at rawURL, e.g. "redis://:pass@redis:6379/0",
+// authenticating and selecting the database given. rediss:// connects over
+// TLS.
+func Dial(ctx context.Context, rawURL string) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("invalid Redis URL %q: expected redis:// or rediss://", rawURL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if u.Scheme == "rediss" {
+		conn, err = (&tls.Dialer{NetDialer: &dialer, Config: &tls.Config{ServerName: u.Hostname()}}).DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", host, err)
+	}
+	c := &Conn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn), Timeout: 10 * time.Second}
+
+	if u.User != nil {
+		args := []string{"AUTH"}
+		pass, ok := u.User.Password()
+		if name := u.User.Username(); name != "" && ok {
+			args = append(args, name)
+		}
+		if !ok {
+			// redis://secret@host gives the password alone
+			pass = u.User.Username()
+		}
+		if _, err := c.Do(append(args, pass)...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to authenticate to Redis: %w", err)
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if _, err := strconv.Atoi(db); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("invalid Redis database %q", db)
+		}
+		if _, err := c.Do("SELECT", db); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to select Redis database %s: %w", db, err)
+		}
+	}
+	return c, nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// Do sends a command and returns its reply: a string, an int64, a []byte
+// or nil for bulk strings, or a []interface{} or nil for arrays. An error
+// reply is returned as an Error.
+func (c *Conn) Do(args ...string) (interface{}, error) {
+	return c.DoBlocking(0, args...)
+}
+
+// DoBlocking is Do for a command that blocks on the server for up to block
+func (c *Conn) DoBlocking(block time.Duration, args ...string) (interface{}, error) {
+	replies, err := c.Pipeline([][]string{args}, block)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := replies[0].(Error); ok {
+		return nil, e
+	}
+	return replies[0], nil
+}
+
+// Pipeline sends several commands at once and returns their replies in
+// order, with error replies as Error values. block extends the timeout for
+// commands that block on the server.
+func (c *Conn) Pipeline(cmds [][]string, block time.Duration) ([]interface{}, error) {
+	if c.Timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.Timeout + block))
+	}
+	for _, args := range cmds {
+		fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := c.writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to send to Redis: %w", err)
+	}
+	replies := make([]interface{}, len(cmds))
+	for i := range replies {
+		reply, err := c.readReply()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from Redis: %w", err)
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+func (c *Conn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxBulk {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxArray {
+			return nil, fmt.Errorf("invalid array length %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		// Grown as elements arrive, so memory follows what was sent
+		items := make([]interface{}, 0, min(n, 1024))
+		for i := 0; i < n; i++ {
+			item, err := c.readReply()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
+
+// Message is an entry of a stream
+type Message struct {
+	ID     string
+	Fields map[string]string
+}
+
+// ParseMessages decodes the list of stream entries in a reply to XRANGE,
+// XAUTOCLAIM or one stream of XREADGROUP
+func ParseMessages(reply interface{}) ([]Message, error) {
+	items, ok := reply.([]interface{})
+	if !ok && reply != nil {
+		return nil, fmt.Errorf("unexpected stream reply %T", reply)
+	}
+	messages := make([]Message, 0, len(items))
+	for _, item := range items {
+		pair, ok := item.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("unexpected stream entry %v", item)
+		}
+		id, ok := pair[0].([]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected stream entry ID %v", pair[0])
+		}
+		m := Message{ID: string(id), Fields: make(map[string]string)}
+		// Entries deleted while pending have no fields
+		values, _ := pair[1].([]interface{})
+		for i := 0; i+1 < len(values); i += 2 {
+			k, _ := values[i].([]byte)
+			v, _ := values[i+1].([]byte)
+			m.Fields[string(k)] = string(v)
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
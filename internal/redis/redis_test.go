@@ -0,0 +1,104 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeServer answers each command it receives with the RESP reply of
+// handle, and records the commands on commands
+func fakeServer(t *testing.T, handle func(args []string) string) (string, chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	commands := make(chan []string, 16)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			args := make([]string, n)
+			for i := range args {
+				line, _ = r.ReadString('\n')
+				size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+				buf := make([]byte, size+2)
+				io.ReadFull(r, buf)
+				args[i] = string(buf[:size])
+			}
+			commands <- args
+			fmt.Fprint(conn, handle(args))
+		}
+	}()
+	return listener.Addr().String(), commands
+}
+
+func TestDialAndDo(t *testing.T) {
+	addr, commands := fakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "XRANGE":
+			return "*2\r\n*2\r\n$3\r\n1-0\r\n*2\r\n$5\r\nentry\r\n$2\r\n{}\r\n*2\r\n$3\r\n2-0\r\n*-1\r\n"
+		case "XGROUP":
+			return "-BUSYGROUP Consumer Group name already exists\r\n"
+		}
+		return "+OK\r\n"
+	})
+	conn, err := Dial(context.Background(), "redis://:secret@"+addr+"/2")
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	if got := strings.Join(<-commands, " "); got != "AUTH secret" {
+		t.Errorf("Expected AUTH secret, got %q", got)
+	}
+	if got := strings.Join(<-commands, " "); got != "SELECT 2" {
+		t.Errorf("Expected SELECT 2, got %q", got)
+	}
+
+	reply, err := conn.Do("XRANGE", "logs", "-", "+")
+	if err != nil {
+		t.Fatalf("XRANGE failed: %v", err)
+	}
+	messages, err := ParseMessages(reply)
+	if err != nil {
+		t.Fatalf("ParseMessages failed: %v", err)
+	}
+	if len(messages) != 2 || messages[0].ID != "1-0" || messages[0].Fields["entry"] != "{}" || len(messages[1].Fields) != 0 {
+		t.Errorf("Unexpected messages %+v", messages)
+	}
+
+	_, err = conn.Do("XGROUP", "CREATE", "logs", "g", "0")
+	if e, ok := err.(Error); !ok || e.Code() != "BUSYGROUP" {
+		t.Errorf("Expected a BUSYGROUP error, got %v", err)
+	}
+}
+
+func TestOversizedArray(t *testing.T) {
+	addr, _ := fakeServer(t, func(args []string) string {
+		return "*9999999999999\r\n"
+	})
+	conn, err := Dial(context.Background(), "redis://"+addr)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Do("XRANGE", "logs", "-", "+"); err == nil || !strings.Contains(err.Error(), "invalid array length") {
+		t.Errorf("Expected an invalid array length error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Package release holds a timeline of deploys, so reports can tell which
+// release was the latest when an error or anomaly began.
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/human"
+)
+
+// Release is a deploy of a version at a time
+type Release struct {
+	Version string `json:"version"`
+	// Service is the service deployed, empty if the release covers all
+	Service string    `json:"service,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// String describes the release, e.g. "api v1.4 at 2023-01-01 14:05 UTC"
+func (r Release) String() string {
+	name := r.Version
+	if r.Service != "" {
+		name = r.Service + " " + name
+	}
+	return name + " at " + r.Time.Format("2006-01-02 15:04 MST")
+}
+
+// Parse parses a release given as [SERVICE:]VERSION@TIME, e.g.
+// "api:v1.4@2023-01-01 14:05" or "v1.4@14:05", where TIME is any time
+// human.ParseTime accepts relative to now
+func Parse(s string, now time.Time) (Release, error) {
+	at := strings.LastIndex(s, "@")
+	if at < 0 {
+		return Release{}, fmt.Errorf("release %q is not [SERVICE:]VERSION@TIME", s)
+	}
+	service, version, ok := strings.Cut(s[:at], ":")
+	if !ok {
+		service, version = "", s[:at]
+	}
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return Release{}, fmt.Errorf("release %q has no version", s)
+	}
+	t, err := human.ParseTime(s[at+1:], now)
+	if err != nil {
+		return Release{}, fmt.Errorf("invalid time of release %q: %w", s, err)
+	}
+	return Release{Version: version, Service: strings.TrimSpace(service), Time: t}, nil
+}
+
+// Load reads a JSON array of releases, e.g.
+// [{"version": "v1.4", "service": "api", "time": "2023-01-01T14:05:00Z"}]
+func Load(path string) ([]Release, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read releases: %w", err)
+	}
+	var releases []Release
+	if err := json.Unmarshal(data, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse releases %s: %w", path, err)
+	}
+	for _, r := range releases {
+		if r.Version == "" || r.Time.IsZero() {
+			return nil, fmt.Errorf("invalid releases %s: every release needs a version and a time", path)
+		}
+	}
+	return releases, nil
+}
+
+// Timeline is a set of releases in order of time. A nil Timeline holds no
+// releases.
+type Timeline struct {
+	releases []Release
+}
+
+// NewTimeline creates a timeline of the releases
+func NewTimeline(releases []Release) *Timeline {
+	sorted := append([]Release(nil), releases...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+	return &Timeline{releases: sorted}
+}
+
+// Releases returns the releases in order of time
+func (t *Timeline) Releases() []Release {
+	if t == nil {
+		return nil
+	}
+	return t.releases
+}
+
+// Before returns the latest release of service, or of all services, at or
+// before at, which is the release an error beginning at that time started
+// after
+func (t *Timeline) Before(service string, at time.Time) (Release, bool) {
+	if t == nil || at.IsZero() {
+		return Release{}, false
+	}
+	// Releases after at start at i
+	i := sort.Search(len(t.releases), func(i int) bool {
+		return t.releases[i].Time.After(at)
+	})
+	for i--; i >= 0; i-- {
+		if r := t.releases[i]; r.Service == "" || r.Service == service {
+			return r, true
+		}
+	}
+	return Release{}, false
+}
@@ -0,0 +1,97 @@
+package release
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2023, 1, 1, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  Release
+	}{
+		{"v1.4@14:05", Release{Version: "v1.4", Time: time.Date(2023, 1, 1, 14, 5, 0, 0, time.UTC)}},
+		{"api:v1.4@2022-12-31 09:00", Release{Version: "v1.4", Service: "api", Time: time.Date(2022, 12, 31, 9, 0, 0, 0, time.UTC)}},
+		{"db:42@2h ago", Release{Version: "42", Service: "db", Time: now.Add(-2 * time.Hour)}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input, now)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q): expected %+v, got %+v", tt.input, tt.want, got)
+		}
+	}
+	for _, input := range []string{"v1.4", "@14:05", "api:@14:05", "v1.4@someday"} {
+		if _, err := Parse(input, now); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestTimelineBefore(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2023, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+	timeline := NewTimeline([]Release{
+		{Version: "v2", Service: "api", Time: at(14, 5)},
+		{Version: "v1", Time: at(9, 0)},
+		{Version: "v7", Service: "db", Time: at(15, 0)},
+	})
+
+	tests := []struct {
+		service string
+		at      time.Time
+		want    string
+		found   bool
+	}{
+		{"api", at(8, 59), "", false},
+		{"api", at(9, 0), "v1", true},
+		{"api", at(14, 30), "v2", true},
+		{"db", at(14, 30), "v1", true},
+		{"db", at(16, 0), "v7", true},
+		{"api", at(16, 0), "v2", true},
+	}
+	for _, tt := range tests {
+		r, ok := timeline.Before(tt.service, tt.at)
+		if ok != tt.found || r.Version != tt.want {
+			t.Errorf("Before(%s, %s): expected %q, got %q (found %v)", tt.service, tt.at.Format("15:04"), tt.want, r.Version, ok)
+		}
+	}
+
+	var none *Timeline
+	if _, ok := none.Before("api", at(12, 0)); ok {
+		t.Error("Expected no release from a nil timeline")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "releases.json")
+	data := `[{"version": "v1.4", "service": "api", "time": "2023-01-01T14:05:00Z"}, {"version": "v1.3", "time": "2022-12-30T10:00:00Z"}]`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	releases, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load releases: %v", err)
+	}
+	if len(releases) != 2 || releases[0].Service != "api" || !releases[1].Time.Equal(time.Date(2022, 12, 30, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected releases: %+v", releases)
+	}
+	if got := releases[0].String(); got != "api v1.4 at 2023-01-01 14:05 UTC" {
+		t.Errorf("Unexpected description %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"version": "v1"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for a release without a time")
+	}
+}
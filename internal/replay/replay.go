@@ -0,0 +1,197 @@
+// Package replay re-emits captured log entries to sinks at the pace they
+// were originally logged at, to load-test collectors with realistic traffic.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/mergesort"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+	"github.com/interview/junior-go-challenge/internal/processor"
+	"github.com/interview/junior-go-challenge/internal/sink"
+)
+
+// Config describes a replay
+type Config struct {
+	InputDir string
+	Pattern  string
+	Parser   parser.Parser
+	// Speed multiplies the original pace: 2 replays an hour of logs in 30
+	// minutes. 0 sends entries as fast as the sinks accept them.
+	Speed float64
+	// MaxGap shortens quiet periods between entries to at most this long in
+	// log time, if set
+	MaxGap time.Duration
+	// Retime stamps entries with the time they are sent instead of their
+	// original timestamps
+	Retime bool
+	// Clock paces the replay; the system clock if nil
+	Clock clock.Clock
+}
+
+// Stats describes a finished replay
+type Stats struct {
+	Entries int
+	// Failed counts the entries a sink rejected
+	Failed int
+	// LogSpan is the time between the first and last timestamped entries
+	LogSpan time.Duration
+	Elapsed time.Duration
+	// MaxLag is how far sending fell behind the schedule at worst, e.g.
+	// because a sink was slow
+	MaxLag time.Duration
+}
+
+// Replay sorts the input by timestamp and sends it to sinks at pace
+type Replay struct {
+	cfg   Config
+	sinks []sink.Sink
+	clock clock.Clock
+	// logClock follows the timestamps of the entries sent
+	logClock *clock.Fake
+	// sleep waits for d or until ctx is cancelled; replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New creates a replay of cfg's input into sinks
+func New(cfg Config, sinks ...sink.Sink) *Replay {
+	r := &Replay{cfg: cfg, sinks: sinks, clock: cfg.Clock, logClock: clock.NewFake(time.Time{})}
+	if r.clock == nil {
+		r.clock = clock.System
+	}
+	r.sleep = r.wait
+	return r
+}
+
+// LogClock returns a clock showing the log time of the replay: it is set to
+// the timestamp of each entry before the entry is sent, so components given
+// it, such as alert monitors or tenant quotas, see time pass as it did when
+// the entries were logged
+func (r *Replay) LogClock() clock.Clock {
+	return r.logClock
+}
+
+// Run replays the input until it is exhausted or ctx is cancelled. The
+// input is first sorted into a temporary file, as entries of different
+// files must be interleaved in time.
+func (r *Replay) Run(ctx context.Context) (Stats, error) {
+	sorted, err := os.CreateTemp("", "logprocessor-replay-*.ndjson")
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to create sort file: %w", err)
+	}
+	defer os.Remove(sorted.Name())
+	defer sorted.Close()
+
+	sorter := mergesort.NewSorter(sorted, mergesort.DefaultRunSize)
+	proc := processor.NewLogProcessor(r.cfg.InputDir,
+		processor.WithParser(r.cfg.Parser),
+		processor.WithPattern(r.cfg.Pattern),
+		processor.WithSink(sorter),
+	)
+	stop := context.AfterFunc(ctx, proc.Stop)
+	err = proc.Start()
+	stop()
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to read input: %w", err)
+	}
+	if ctx.Err() != nil {
+		return Stats{}, ctx.Err()
+	}
+
+	if _, err := sorted.Seek(0, io.SeekStart); err != nil {
+		return Stats{}, fmt.Errorf("failed to read sort file: %w", err)
+	}
+	decoder := json.NewDecoder(bufio.NewReader(sorted))
+	return r.Send(ctx, func() (models.LogEntry, error) {
+		var entry models.LogEntry
+		err := decoder.Decode(&entry)
+		return entry, err
+	})
+}
+
+// Send sends the entries returned by next, in timestamp order, until it
+// returns io.EOF. Each entry is sent once the time since the first entry,
+// divided by the speed, has passed since sending started.
+func (r *Replay) Send(ctx context.Context, next func() (models.LogEntry, error)) (Stats, error) {
+	var stats Stats
+	started := r.clock.Now()
+	var first, previous time.Time
+	// skipped is the log time cut from quiet periods by MaxGap
+	var skipped time.Duration
+
+	for {
+		entry, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("failed to read sorted entries: %w", err)
+		}
+
+		due := r.clock.Now()
+		if !entry.Timestamp.IsZero() {
+			if first.IsZero() {
+				first, previous = entry.Timestamp, entry.Timestamp
+			}
+			if gap := entry.Timestamp.Sub(previous); r.cfg.MaxGap > 0 && gap > r.cfg.MaxGap {
+				skipped += gap - r.cfg.MaxGap
+			}
+			previous = entry.Timestamp
+			stats.LogSpan = entry.Timestamp.Sub(first)
+
+			if r.cfg.Speed > 0 {
+				offset := entry.Timestamp.Sub(first) - skipped
+				due = started.Add(time.Duration(float64(offset) / r.cfg.Speed))
+				if wait := due.Sub(r.clock.Now()); wait > 0 {
+					if err := r.sleep(ctx, wait); err != nil {
+						return stats, nil
+					}
+				} else if lag := -wait; lag > stats.MaxLag {
+					stats.MaxLag = lag
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return stats, nil
+		}
+		if !entry.Timestamp.IsZero() {
+			r.logClock.Set(entry.Timestamp)
+		}
+		if r.cfg.Retime {
+			entry.Timestamp = due
+		}
+
+		failed := false
+		for _, s := range r.sinks {
+			if err := s.Write(entry); err != nil {
+				fmt.Printf("Error writing entry %s to sink: %v\n", entry.ID, err)
+				failed = true
+			}
+		}
+		if failed {
+			stats.Failed++
+		}
+		stats.Entries++
+		stats.Elapsed = r.clock.Now().Sub(started)
+	}
+	stats.Elapsed = r.clock.Now().Sub(started)
+	return stats, nil
+}
+
+// wait waits for d on the replay's clock or until ctx is cancelled
+func (r *Replay) wait(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.clock.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+package replay
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/parser"
+)
+
+// recorder is a sink remembering the entries written to it and when
+type recorder struct {
+	now     func() time.Time
+	entries []models.LogEntry
+	times   []time.Time
+}
+
+func (r *recorder) Write(entry models.LogEntry) error {
+	r.entries = append(r.entries, entry)
+	r.times = append(r.times, r.now())
+	return nil
+}
+
+func (r *recorder) Close() error { return nil }
+
+// fakeClock gives r a clock that only advances when slept on
+func fakeClock(r *Replay) *clock.Fake {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	r.clock = fake
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		fake.Advance(d)
+		return nil
+	}
+	return fake
+}
+
+func TestSendPacing(t *testing.T) {
+	base := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	entries := []models.LogEntry{
+		{ID: "1", Timestamp: base},
+		{ID: "2", Timestamp: base.Add(10 * time.Second)},
+		{ID: "3", Timestamp: base.Add(time.Hour)},
+		{ID: "4", Timestamp: base.Add(time.Hour + 4*time.Second)},
+	}
+
+	r := New(Config{Speed: 2, MaxGap: time.Minute, Retime: true})
+	fake := fakeClock(r)
+	out := &recorder{now: fake.Now}
+	r.sinks = append(r.sinks, out)
+	start := fake.Now()
+
+	i := 0
+	stats, err := r.Send(context.Background(), func() (models.LogEntry, error) {
+		if i == len(entries) {
+			return models.LogEntry{}, io.EOF
+		}
+		i++
+		return entries[i-1], nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to send: %v", err)
+	}
+
+	// At double speed, with the hour-long gap cut to a minute
+	want := []time.Duration{0, 5 * time.Second, 35 * time.Second, 37 * time.Second}
+	for i, at := range out.times {
+		if got := at.Sub(start); got != want[i] {
+			t.Errorf("Expected entry %d to be sent after %v, got %v", i+1, want[i], got)
+		}
+		if !out.entries[i].Timestamp.Equal(at) {
+			t.Errorf("Expected entry %d to be retimed to %v, got %v", i+1, at, out.entries[i].Timestamp)
+		}
+	}
+	if stats.Entries != 4 || stats.LogSpan != time.Hour+4*time.Second || stats.Elapsed != 37*time.Second {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	// The log clock follows the original timestamps, not the retimed ones
+	if now := r.LogClock().Now(); !now.Equal(base.Add(time.Hour + 4*time.Second)) {
+		t.Errorf("Expected the log clock at the last entry's timestamp, got %v", now)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "b.json"), []byte(`{"id":"2","timestamp":"2023-01-01T10:00:02Z","level":"INFO","service":"api","message":"second"}`+"\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"id":"3","timestamp":"2023-01-01T10:00:03Z","level":"INFO","service":"api","message":"third"}`+"\n"+
+		`{"id":"1","timestamp":"2023-01-01T10:00:00Z","level":"INFO","service":"api","message":"first"}`+"\n"), 0644)
+
+	out := &recorder{now: time.Now}
+	r := New(Config{InputDir: dir, Pattern: "*.json", Parser: parser.JSONParser{}}, out)
+	stats, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if stats.Entries != 3 {
+		t.Fatalf("Expected 3 entries, got %d", stats.Entries)
+	}
+	for i, id := range []string{"1", "2", "3"} {
+		if out.entries[i].ID != id {
+			t.Errorf("Expected entry %s at position %d, got %s", id, i, out.entries[i].ID)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// DefaultSubject is the subject template used when none is configured
+const DefaultSubject = "Log summary for {{.Host}}: {{.Summary.TotalEntries}} entries"
+
+// Mailer delivers summaries by email over SMTP
+type Mailer struct {
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+	subject  *template.Template
+}
+
+// SubjectData is available to subject templates. ByLevel is keyed by plain
+// strings so templates can write {{index .ByLevel "ERROR"}}.
+type SubjectData struct {
+	Summary *models.LogSummary
+	ByLevel map[string]int
+	Host    string
+	Date    string
+}
+
+// NewMailer creates a mailer sending through the SMTP server at addr
+// (host:port). Authentication is used when username is not empty. The
+// subject is a text/template executed with SubjectData.
+func NewMailer(addr, username, password, from string, to []string, subject string) (*Mailer, error) {
+	if len(to) == 0 {
+		return nil, fmt.Errorf("no email recipients configured")
+	}
+	if subject == "" {
+		subject = DefaultSubject
+	}
+	tmpl, err := template.New("subject").Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+	return &Mailer{
+		addr:     addr,
+		username: username,
+		password: password,
+		from:     from,
+		to:       to,
+		subject:  tmpl,
+	}, nil
+}
+
+// Send emails the summary as a multipart message with text and HTML parts
+func (m *Mailer) Send(summary *models.LogSummary) error {
+	msg, err := m.buildMessage(summary, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		host, _, _ := net.SplitHostPort(m.addr)
+		auth = smtp.PlainAuth("", m.username, m.password, host)
+	}
+	if err := smtp.SendMail(m.addr, auth, m.from, m.to, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// buildMessage renders the full RFC 5322 message
+func (m *Mailer) buildMessage(summary *models.LogSummary, now time.Time) ([]byte, error) {
+	host, _ := os.Hostname()
+
+	byLevel := make(map[string]int, len(summary.ByLevel))
+	for level, n := range summary.ByLevel {
+		byLevel[string(level)] = n
+	}
+
+	var subject bytes.Buffer
+	err := m.subject.Execute(&subject, SubjectData{
+		Summary: summary,
+		ByLevel: byLevel,
+		Host:    host,
+		Date:    now.Format("2006-01-02"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+
+	var text, html bytes.Buffer
+	if err := WriteText(&text, summary); err != nil {
+		return nil, err
+	}
+	if err := WriteHTML(&html, summary); err != nil {
+		return nil, err
+	}
+
+	boundary := make([]byte, 12)
+	rand.Read(boundary)
+	b := "summary-" + hex.EncodeToString(boundary)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", b)
+
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"text/plain", text.Bytes()},
+		{"text/html", html.Bytes()},
+	} {
+		fmt.Fprintf(&msg, "--%s\r\n", b)
+		fmt.Fprintf(&msg, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&msg)
+		qp.Write(part.body)
+		qp.Close()
+		fmt.Fprintf(&msg, "\r\n")
+	}
+	fmt.Fprintf(&msg, "--%s--\r\n", b)
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,143 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// heatmapMaxHours is the longest time range shown hour by hour. Longer
+// ranges are shown by day.
+const heatmapMaxHours = 48
+
+// heatShades shades heatmap cells from empty to the busiest cell of a level
+var heatShades = []rune{'·', '░', '▒', '▓', '█'}
+
+// Heatmap is the volume of each level over time
+type Heatmap struct {
+	// Period is the length of each row, an hour or a day
+	Period time.Duration
+	Levels []string
+	Rows   []HeatmapRow
+	// Max holds the largest cell of each level, by column
+	Max []int
+}
+
+// HeatmapRow holds the counts of one period, by column
+type HeatmapRow struct {
+	Start  time.Time
+	Counts []int
+}
+
+// Label formats the start of the row's period
+func (h *Heatmap) Label(row HeatmapRow) string {
+	if h.Period == time.Hour {
+		return row.Start.Format("2006-01-02 15:00")
+	}
+	return row.Start.Format("2006-01-02")
+}
+
+// Shade returns the fill of a cell relative to the busiest cell of its level
+func (h *Heatmap) Shade(col, count int) rune {
+	if count == 0 || h.Max[col] == 0 {
+		return heatShades[0]
+	}
+	steps := len(heatShades) - 1
+	return heatShades[1+(count*steps-1)/h.Max[col]]
+}
+
+// Intensity returns a cell's share of the busiest cell of its level, for
+// colouring HTML cells
+func (h *Heatmap) Intensity(col, count int) string {
+	if h.Max[col] == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.2f", float64(count)/float64(h.Max[col]))
+}
+
+// NewHeatmap arranges the hourly level counts of a summary into rows, one
+// per hour, or one per day when the data spans more than two days. Periods
+// without entries are included so gaps stand out. It returns nil when there
+// are fewer than two periods of data.
+func NewHeatmap(summary *models.LogSummary) *Heatmap {
+	if len(summary.ByHour) == 0 {
+		return nil
+	}
+
+	var first, last time.Time
+	byLevel := make(map[models.LogLevel]int)
+	for hour, levels := range summary.ByHour {
+		if first.IsZero() || hour.Before(first) {
+			first = hour
+		}
+		if hour.After(last) {
+			last = hour
+		}
+		for level, n := range levels {
+			byLevel[level] += n
+		}
+	}
+
+	h := &Heatmap{Period: time.Hour}
+	if last.Sub(first) >= heatmapMaxHours*time.Hour {
+		h.Period = 24 * time.Hour
+		first, last = first.Truncate(h.Period), last.Truncate(h.Period)
+	}
+	if first.Equal(last) {
+		return nil
+	}
+
+	columns := make(map[models.LogLevel]int)
+	for _, c := range LevelCounts(&models.LogSummary{ByLevel: byLevel}) {
+		columns[models.LogLevel(c.Name)] = len(h.Levels)
+		h.Levels = append(h.Levels, c.Name)
+	}
+	h.Max = make([]int, len(h.Levels))
+
+	rows := make(map[time.Time]int)
+	for t := first; !t.After(last); t = t.Add(h.Period) {
+		rows[t] = len(h.Rows)
+		h.Rows = append(h.Rows, HeatmapRow{Start: t, Counts: make([]int, len(h.Levels))})
+	}
+	for hour, levels := range summary.ByHour {
+		row := h.Rows[rows[hour.Truncate(h.Period)]]
+		for level, n := range levels {
+			row.Counts[columns[level]] += n
+		}
+	}
+	for _, row := range h.Rows {
+		for col, n := range row.Counts {
+			if n > h.Max[col] {
+				h.Max[col] = n
+			}
+		}
+	}
+	return h
+}
+
+// writeHeatmap writes the heatmap as rows of shaded cells, one column per
+// level
+func writeHeatmap(w io.Writer, h *Heatmap) {
+	period := "Hour"
+	if h.Period != time.Hour {
+		period = "Day"
+	}
+	fmt.Fprintf(w, "\nEntries by Level and %s (UTC, shaded relative to each level's busiest %s):\n", period, strings.ToLower(period))
+
+	label := len(h.Label(h.Rows[0]))
+	fmt.Fprintf(w, "  %*s", label, "")
+	for _, level := range h.Levels {
+		fmt.Fprintf(w, " %s", level)
+	}
+	fmt.Fprintln(w)
+	for _, row := range h.Rows {
+		fmt.Fprintf(w, "  %s", h.Label(row))
+		for col, n := range row.Counts {
+			fmt.Fprintf(w, " %s", strings.Repeat(string(h.Shade(col, n)), len(h.Levels[col])))
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,283 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Count is a named count in a report section
+type Count struct {
+	Name  string
+	Count int
+}
+
+// LevelCounts returns the counts of registered levels ordered by severity,
+// followed by any unregistered levels in alphabetical order
+func LevelCounts(summary *models.LogSummary) []Count {
+	var counts []Count
+	seen := make(map[models.LogLevel]bool)
+	for _, level := range models.Levels() {
+		if n, ok := summary.ByLevel[level]; ok {
+			counts = append(counts, Count{Name: string(level), Count: n})
+			seen[level] = true
+		}
+	}
+
+	var others []Count
+	for level, n := range summary.ByLevel {
+		if !seen[level] {
+			others = append(others, Count{Name: string(level), Count: n})
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].Name < others[j].Name })
+	return append(counts, others...)
+}
+
+// ServiceCounts returns the service counts, largest first
+func ServiceCounts(summary *models.LogSummary) []Count {
+	counts := make([]Count, 0, len(summary.ByService))
+	for service, n := range summary.ByService {
+		counts = append(counts, Count{Name: service, Count: n})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	return counts
+}
+
+// CounterValues returns the message counters by name, with labeled counters
+// written as name{label="value"} and ordered by label value
+func CounterValues(summary *models.LogSummary) []Count {
+	var counts []Count
+	for _, name := range sortedKeys(summary.Counters) {
+		counter := summary.Counters[name]
+		for _, value := range sortedKeys(counter.Counts) {
+			label := name
+			if counter.Label != "" {
+				label = fmt.Sprintf("%s{%s=%q}", name, counter.Label, value)
+			}
+			counts = append(counts, Count{Name: label, Count: counter.Counts[value]})
+		}
+	}
+	return counts
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteText writes the plain text summary printed by the CLI
+func WriteText(w io.Writer, summary *models.LogSummary) error {
+	fmt.Fprintln(w, "Log Processing Summary:")
+	fmt.Fprintf(w, "Total Entries: %d\n", summary.TotalEntries)
+	if summary.InferredLevels > 0 {
+		fmt.Fprintf(w, "Levels Inferred: %d\n", summary.InferredLevels)
+	}
+
+	fmt.Fprintln(w, "\nEntries by Level:")
+	for _, c := range LevelCounts(summary) {
+		fmt.Fprintf(w, "  %s: %d\n", c.Name, c.Count)
+	}
+
+	fmt.Fprintln(w, "\nEntries by Service:")
+	for _, c := range ServiceCounts(summary) {
+		fmt.Fprintf(w, "  %s: %d\n", c.Name, c.Count)
+	}
+
+	if a := summary.Accounting; a != nil {
+		writeAccounting(w, *a)
+	}
+
+	if len(summary.OutOfOrder) > 0 {
+		fmt.Fprintln(w, "\nOut-of-Order Entries by Source:")
+		for _, source := range sortedKeys(summary.OutOfOrder) {
+			stats := summary.OutOfOrder[source]
+			fmt.Fprintf(w, "  %s: %d (max displacement %v)\n", source, stats.Count, stats.MaxDisplacement)
+		}
+	}
+
+	if len(summary.FailedFiles) > 0 {
+		fmt.Fprintln(w, "\nFailed Files:")
+		for _, file := range sortedKeys(summary.FailedFiles) {
+			fmt.Fprintf(w, "  %s: %s\n", file, summary.FailedFiles[file])
+		}
+	}
+
+	if len(summary.CorruptArchives) > 0 {
+		fmt.Fprintln(w, "\nCorrupt Archives:")
+		for _, file := range sortedKeys(summary.CorruptArchives) {
+			fmt.Fprintf(w, "  %s: %s\n", file, summary.CorruptArchives[file])
+		}
+	}
+
+	if len(summary.Counters) > 0 {
+		fmt.Fprintln(w, "\nCounters:")
+		for _, c := range CounterValues(summary) {
+			fmt.Fprintf(w, "  %s: %d\n", c.Name, c.Count)
+		}
+	}
+
+	if p := summary.Privacy; p != nil {
+		writePrivacy(w, *p)
+	}
+
+	if !summary.TimeRange.Start.IsZero() && !summary.TimeRange.End.IsZero() {
+		fmt.Fprintf(w, "\nTime Range: %s to %s\n",
+			summary.TimeRange.Start.Format("2006-01-02 15:04:05"),
+			summary.TimeRange.End.Format("2006-01-02 15:04:05"))
+	}
+
+	if h := NewHeatmap(summary); h != nil {
+		writeHeatmap(w, h)
+	}
+	return nil
+}
+
+// writePrivacy writes how the counts were anonymized
+func writePrivacy(w io.Writer, p models.Privacy) {
+	fmt.Fprintln(w, "\nAnonymized Counts:")
+	if p.Epsilon > 0 {
+		fmt.Fprintf(w, "  Noise: epsilon %g\n", p.Epsilon)
+	}
+	fmt.Fprintf(w, "  Suppressed: %d groups with fewer than %d entries\n", p.Suppressed, max(p.MinCount, 1))
+}
+
+// writeAccounting writes what became of the entries read
+func writeAccounting(w io.Writer, a models.Accounting) {
+	fmt.Fprintln(w, "\nEntry Accounting:")
+	fmt.Fprintf(w, "  Read: %d\n", a.Read)
+	if a.Skipped > 0 {
+		fmt.Fprintf(w, "  Skipped (checkpoint): %d\n", a.Skipped)
+	}
+	fmt.Fprintf(w, "  Filtered: %d\n", a.Filtered)
+	fmt.Fprintf(w, "  Duplicates: %d\n", a.Duplicates)
+	if a.HashMatches > 0 {
+		fmt.Fprintf(w, "    of which by ID hash only: %d\n", a.HashMatches)
+	}
+	for _, reason := range sortedKeys(a.Dropped) {
+		fmt.Fprintf(w, "  Dropped (%s): %d\n", reason, a.Dropped[reason])
+	}
+	fmt.Fprintf(w, "  Analyzed: %d\n", a.Analyzed)
+	if n := a.Unaccounted(); n != 0 {
+		fmt.Fprintf(w, "  Unaccounted: %d\n", n)
+	}
+}
+
+var htmlTemplate = template.Must(template.New("summary").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Log Processing Summary</title></head>
+<body style="font-family: sans-serif">
+<h2>Log Processing Summary</h2>
+<p>Total Entries: <strong>{{.Summary.TotalEntries}}</strong></p>
+{{- if .Summary.InferredLevels}}
+<p>Levels Inferred: {{.Summary.InferredLevels}}</p>
+{{- end}}
+{{- if .Summary.OutOfOrder}}
+<h3>Out-of-Order Entries by Source</h3>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Source</th><th>Count</th><th>Max Displacement</th></tr>
+{{- range $source, $stats := .Summary.OutOfOrder}}
+<tr><td>{{$source}}</td><td align="right">{{$stats.Count}}</td><td align="right">{{$stats.MaxDisplacement}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Summary.FailedFiles}}
+<h3>Failed Files</h3>
+<ul>
+{{- range $file, $reason := .Summary.FailedFiles}}
+<li>{{$file}}: {{$reason}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Summary.CorruptArchives}}
+<h3>Corrupt Archives</h3>
+<ul>
+{{- range $file, $reason := .Summary.CorruptArchives}}
+<li>{{$file}}: {{$reason}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Counters}}
+<h3>Counters</h3>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Counter</th><th>Count</th></tr>
+{{- range .Counters}}
+<tr><td>{{.Name}}</td><td align="right">{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if not .Summary.TimeRange.Start.IsZero}}
+<p>Time Range: {{.Summary.TimeRange.Start.Format "2006-01-02 15:04:05"}} to {{.Summary.TimeRange.End.Format "2006-01-02 15:04:05"}}</p>
+{{- end}}
+<h3>Entries by Level</h3>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Level</th><th>Count</th></tr>
+{{- range .Levels}}
+<tr><td>{{.Name}}</td><td align="right">{{.Count}}</td></tr>
+{{- end}}
+</table>
+<h3>Entries by Service</h3>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Service</th><th>Count</th></tr>
+{{- range .Services}}
+<tr><td>{{.Name}}</td><td align="right">{{.Count}}</td></tr>
+{{- end}}
+</table>
+{{- with .Heatmap}}
+{{- $h := .}}
+<h3>Entries by Level and {{if eq .Period.Hours 1.0}}Hour{{else}}Day{{end}} (UTC)</h3>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th></th>{{range .Levels}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr><td>{{$h.Label .}}</td>{{range $col, $n := .Counts}}<td align="right" style="background-color: rgba(200, 30, 30, {{$h.Intensity $col $n}})">{{$n}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the summary as a standalone HTML document
+func WriteHTML(w io.Writer, summary *models.LogSummary) error {
+	return htmlTemplate.Execute(w, struct {
+		Summary  *models.LogSummary
+		Levels   []Count
+		Services []Count
+		Counters []Count
+		Heatmap  *Heatmap
+	}{
+		Summary:  summary,
+		Levels:   LevelCounts(summary),
+		Services: ServiceCounts(summary),
+		Counters: CounterValues(summary),
+		Heatmap:  NewHeatmap(summary),
+	})
+}
+
+// WriteSections writes each section under its own heading, following the
+// text summary
+func WriteSections(w io.Writer, sections []analyzer.Section) error {
+	for _, s := range sections {
+		if _, err := fmt.Fprintf(w, "\n%s:\n", s.Name()); err != nil {
+			return err
+		}
+		if err := s.WriteText(w); err != nil {
+			return fmt.Errorf("failed to write %s: %w", s.Name(), err)
+		}
+	}
+	return nil
+}
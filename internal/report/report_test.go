@@ -0,0 +1,185 @@
+package report
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func testSummary() *models.LogSummary {
+	summary := models.NewLogSummary()
+	summary.TotalEntries = 6
+	summary.ByLevel[models.ERROR] = 2
+	summary.ByLevel[models.INFO] = 3
+	summary.ByLevel["TRACE"] = 1
+	summary.ByService["api"] = 4
+	summary.ByService["<db>"] = 2
+	summary.TimeRange.Start = time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	summary.TimeRange.End = time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	return summary
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteText(&buf, testSummary()); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+
+	expected := `Log Processing Summary:
+Total Entries: 6
+
+Entries by Level:
+  INFO: 3
+  ERROR: 2
+  TRACE: 1
+
+Entries by Service:
+  api: 4
+  <db>: 2
+
+Time Range: 2023-01-01 10:00:00 to 2023-01-01 12:00:00
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected text report:\n%s", buf.String())
+	}
+}
+
+func TestWriteTextAccounting(t *testing.T) {
+	summary := testSummary()
+	summary.Accounting = &models.Accounting{
+		Read:       10,
+		Filtered:   1,
+		Duplicates: 1,
+		Dropped:    map[string]int{"panic": 1},
+		Analyzed:   6,
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, summary); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+
+	expected := `
+Entry Accounting:
+  Read: 10
+  Filtered: 1
+  Duplicates: 1
+  Dropped (panic): 1
+  Analyzed: 6
+  Unaccounted: 1
+`
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected the accounting in the report, got:\n%s", buf.String())
+	}
+}
+
+func TestMailerBuildMessage(t *testing.T) {
+	mailer, err := NewMailer("smtp.example.com:587", "", "", "logs@example.com",
+		[]string{"oncall@example.com", "lead@example.com"},
+		"[{{.Date}}] {{.Summary.TotalEntries}} entries, {{index .ByLevel \"ERROR\"}} errors")
+	if err != nil {
+		t.Fatalf("Failed to create mailer: %v", err)
+	}
+
+	raw, err := mailer.buildMessage(testSummary(), time.Date(2023, 1, 2, 2, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to build message: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "[2023-01-02] 6 entries, 2 errors" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if to := msg.Header.Get("To"); to != "oncall@example.com, lead@example.com" {
+		t.Errorf("Unexpected recipients %q", to)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %s", mediaType)
+	}
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(body)
+	}
+
+	if !strings.Contains(parts["text/plain"], "Total Entries: 6") {
+		t.Errorf("Expected text part with the summary, got %q", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], "<td>&lt;db&gt;</td>") {
+		t.Errorf("Expected escaped HTML part, got %q", parts["text/html"])
+	}
+
+	if _, err := NewMailer("localhost:25", "", "", "a@b", nil, ""); err == nil {
+		t.Error("Expected an error without recipients")
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	summary := testSummary()
+	start := summary.TimeRange.Start
+	summary.AddHour(start, models.INFO, 4)
+	summary.AddHour(start, models.ERROR, 1)
+	summary.AddHour(start.Add(30*time.Minute), models.INFO, 4)
+	summary.AddHour(start.Add(2*time.Hour), models.ERROR, 3)
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, summary); err != nil {
+		t.Fatalf("Failed to write text: %v", err)
+	}
+	expected := `
+Entries by Level and Hour (UTC, shaded relative to each level's busiest hour):
+                   INFO ERROR
+  2023-01-01 10:00 ████ ▒▒▒▒▒
+  2023-01-01 11:00 ···· ·····
+  2023-01-01 12:00 ···· █████
+`
+	if !strings.HasSuffix(buf.String(), expected) {
+		t.Errorf("Unexpected heatmap:\n%s", buf.String())
+	}
+
+	// Three days are shown by day
+	summary.AddHour(start.Add(72*time.Hour), models.INFO, 1)
+	h := NewHeatmap(summary)
+	if h.Period != 24*time.Hour || len(h.Rows) != 4 {
+		t.Fatalf("Expected 4 daily rows, got %v and %d rows", h.Period, len(h.Rows))
+	}
+	if h.Rows[0].Counts[0] != 8 || h.Rows[0].Counts[1] != 4 {
+		t.Errorf("Expected the first day to have 8 INFO and 4 ERROR entries, got %v", h.Rows[0].Counts)
+	}
+
+	buf.Reset()
+	if err := WriteHTML(&buf, summary); err != nil {
+		t.Fatalf("Failed to write HTML: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<h3>Entries by Level and Day (UTC)</h3>") ||
+		!strings.Contains(buf.String(), "<td>2023-01-01</td><td align=\"right\" style=\"background-color: rgba(200, 30, 30, 1.00)\">8</td>") {
+		t.Errorf("Expected a daily heatmap table, got %s", buf.String())
+	}
+
+	if NewHeatmap(testSummary()) != nil {
+		t.Error("Expected no heatmap without hourly counts")
+	}
+}
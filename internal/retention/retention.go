@@ -0,0 +1,193 @@
+// Package retention enforces a retention policy on processed input files:
+// files older than a maximum age are deleted or moved to an archive
+// directory, with every action recorded in an audit log.
+package retention
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Actions taken on a file
+const (
+	ActionDelete  = "delete"
+	ActionArchive = "archive"
+)
+
+// Action records what the policy did, or would do in a dry run, to a file
+type Action struct {
+	Time   time.Time `json:"time"`
+	Path   string    `json:"path"`
+	Action string    `json:"action"`
+	// Target is where an archived file was moved
+	Target string `json:"target,omitempty"`
+	// ModTime is the file's modification time its age is measured from
+	ModTime time.Time `json:"mod_time"`
+	DryRun  bool      `json:"dry_run,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Policy deletes or archives files older than a maximum age
+type Policy struct {
+	maxAge     time.Duration
+	archiveDir string
+	dryRun     bool
+	audit      io.Writer
+	now        func() time.Time
+}
+
+// Option configures a Policy
+type Option func(*Policy)
+
+// WithArchive moves expired files into dir instead of deleting them
+func WithArchive(dir string) Option {
+	return func(p *Policy) {
+		p.archiveDir = dir
+	}
+}
+
+// WithDryRun only reports the actions the policy would take
+func WithDryRun() Option {
+	return func(p *Policy) {
+		p.dryRun = true
+	}
+}
+
+// WithAudit writes every action to w as a line of JSON
+func WithAudit(w io.Writer) Option {
+	return func(p *Policy) {
+		p.audit = w
+	}
+}
+
+// New creates a policy expiring files last modified more than maxAge ago
+func New(maxAge time.Duration, opts ...Option) *Policy {
+	p := &Policy{maxAge: maxAge, now: time.Now}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Enforce deletes or archives the expired files among paths and returns
+// what was done. Every file is attempted; the error joins the failures,
+// which are also recorded in their actions.
+func (p *Policy) Enforce(paths []string) ([]Action, error) {
+	now := p.now()
+	if p.archiveDir != "" && !p.dryRun {
+		if err := os.MkdirAll(p.archiveDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create retention archive: %w", err)
+		}
+	}
+
+	var actions []Action
+	var errs []error
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check retention of %s: %w", path, err))
+			continue
+		}
+		if now.Sub(info.ModTime()) <= p.maxAge {
+			continue
+		}
+
+		action := Action{Time: now.UTC(), Path: path, Action: ActionDelete, ModTime: info.ModTime().UTC(), DryRun: p.dryRun}
+		if p.archiveDir != "" {
+			action.Action = ActionArchive
+			action.Target = p.target(path)
+		}
+		if !p.dryRun {
+			if err := p.apply(action); err != nil {
+				action.Error = err.Error()
+				errs = append(errs, err)
+			}
+		}
+		actions = append(actions, action)
+		if err := p.record(action); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return actions, errors.Join(errs...)
+}
+
+// target returns a free path in the archive for path, numbering the name
+// if another file of that name was archived before
+func (p *Policy) target(path string) string {
+	base := filepath.Join(p.archiveDir, filepath.Base(path))
+	target := base
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); errors.Is(err, os.ErrNotExist) {
+			return target
+		}
+		target = base + "." + strconv.Itoa(i)
+	}
+}
+
+// apply performs an action
+func (p *Policy) apply(action Action) error {
+	if action.Action == ActionDelete {
+		if err := os.Remove(action.Path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", action.Path, err)
+		}
+		return nil
+	}
+	if err := os.Rename(action.Path, action.Target); err == nil {
+		return nil
+	}
+	// The archive may be on another file system
+	if err := copyFile(action.Path, action.Target); err != nil {
+		os.Remove(action.Target)
+		return fmt.Errorf("failed to archive %s: %w", action.Path, err)
+	}
+	if err := os.Remove(action.Path); err != nil {
+		return fmt.Errorf("failed to remove archived %s: %w", action.Path, err)
+	}
+	return nil
+}
+
+// copyFile copies src to dst and syncs dst before returning
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// record writes an action to the audit log
+func (p *Policy) record(action Action) error {
+	if p.audit == nil {
+		return nil
+	}
+	line, err := json.Marshal(action)
+	if err != nil {
+		return fmt.Errorf("failed to encode retention audit: %w", err)
+	}
+	if _, err := p.audit.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write retention audit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package retention
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeAged creates a file last modified age ago
+func writeAged(t *testing.T, dir, name string, age time.Duration) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("Failed to age %s: %v", path, err)
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestEnforceDeletesExpiredFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := writeAged(t, dir, "old.log", 48*time.Hour)
+	recent := writeAged(t, dir, "recent.log", time.Hour)
+
+	var audit bytes.Buffer
+	actions, err := New(24*time.Hour, WithAudit(&audit)).Enforce([]string{old, recent, filepath.Join(dir, "gone.log")})
+	if err != nil {
+		t.Fatalf("Enforce failed: %v", err)
+	}
+	if len(actions) != 1 || actions[0].Path != old || actions[0].Action != ActionDelete {
+		t.Fatalf("Expected only old.log to be deleted, got %+v", actions)
+	}
+	if exists(old) {
+		t.Errorf("Expected old.log to be deleted")
+	}
+	if !exists(recent) {
+		t.Errorf("Expected recent.log to be kept")
+	}
+
+	var record Action
+	if err := json.Unmarshal(audit.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode audit record %q: %v", audit.String(), err)
+	}
+	if record.Path != old || record.Action != ActionDelete || record.DryRun {
+		t.Errorf("Expected an audit record of the deletion, got %+v", record)
+	}
+}
+
+func TestEnforceArchives(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "archive")
+	old := writeAged(t, dir, "app.log", 48*time.Hour)
+	// A file of the same name archived before is not overwritten
+	if err := os.MkdirAll(archive, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(archive, "app.log"), []byte("earlier\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	actions, err := New(24*time.Hour, WithArchive(archive)).Enforce([]string{old})
+	if err != nil {
+		t.Fatalf("Enforce failed: %v", err)
+	}
+	want := filepath.Join(archive, "app.log.1")
+	if len(actions) != 1 || actions[0].Action != ActionArchive || actions[0].Target != want {
+		t.Fatalf("Expected app.log to be archived to %s, got %+v", want, actions)
+	}
+	if exists(old) {
+		t.Errorf("Expected app.log to be moved out of the input directory")
+	}
+	data, err := os.ReadFile(want)
+	if err != nil || string(data) != "{}\n" {
+		t.Errorf("Expected the archived copy to hold the file, got %q (%v)", data, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(archive, "app.log")); string(data) != "earlier\n" {
+		t.Errorf("Expected the earlier archived file to be kept, got %q", data)
+	}
+}
+
+func TestEnforceDryRun(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+	old := writeAged(t, dir, "old.log", 48*time.Hour)
+
+	var audit bytes.Buffer
+	actions, err := New(24*time.Hour, WithArchive(archive), WithDryRun(), WithAudit(&audit)).Enforce([]string{old})
+	if err != nil {
+		t.Fatalf("Enforce failed: %v", err)
+	}
+	if len(actions) != 1 || !actions[0].DryRun {
+		t.Fatalf("Expected a dry-run action, got %+v", actions)
+	}
+	if !exists(old) {
+		t.Errorf("Expected a dry run to keep the file")
+	}
+	if exists(archive) {
+		t.Errorf("Expected a dry run not to create the archive")
+	}
+	if !strings.Contains(audit.String(), `"dry_run":true`) {
+		t.Errorf("Expected the audit record to mark the dry run, got %q", audit.String())
+	}
+}
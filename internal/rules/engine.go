@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Firing is a rule reaching its threshold
+type Firing struct {
+	Rule *Rule
+	// Time is the timestamp of the entry that reached the threshold
+	Time time.Time
+	// Count is the number of matches within the window at that time
+	Count int
+	// Entry is the entry that reached the threshold
+	Entry models.LogEntry
+}
+
+// Engine evaluates rules over entries in timestamp order. A rule fires when
+// its threshold is reached and fires again only after its count within the
+// window has dropped below the threshold.
+type Engine struct {
+	rules  []*Rule
+	states []ruleState
+}
+
+// ruleState holds the recent matches of one rule
+type ruleState struct {
+	matches []time.Time
+	firing  bool
+}
+
+// NewEngine creates an engine for rules
+func NewEngine(rules []*Rule) *Engine {
+	return &Engine{rules: rules, states: make([]ruleState, len(rules))}
+}
+
+// Observe evaluates an entry and returns the rules it made fire. Entries
+// must be observed in timestamp order from a single goroutine.
+func (e *Engine) Observe(entry models.LogEntry) []Firing {
+	var firings []Firing
+	for i, rule := range e.rules {
+		state := &e.states[i]
+		if rule.Window > 0 {
+			// Forget matches that fell out of the window
+			expired := 0
+			for expired < len(state.matches) && entry.Timestamp.Sub(state.matches[expired]) >= rule.Window {
+				expired++
+			}
+			state.matches = state.matches[expired:]
+		}
+		if len(state.matches) < rule.Threshold {
+			state.firing = false
+		}
+
+		if !rule.Matches(entry) {
+			continue
+		}
+		state.matches = append(state.matches, entry.Timestamp)
+		if rule.Window == 0 && len(state.matches) > rule.Threshold {
+			// Only the count matters without a window
+			state.matches = state.matches[1:]
+		}
+		if len(state.matches) >= rule.Threshold && !state.firing {
+			state.firing = true
+			firings = append(firings, Firing{Rule: rule, Time: entry.Timestamp, Count: len(state.matches), Entry: entry})
+		}
+	}
+	return firings
+}
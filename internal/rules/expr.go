@@ -0,0 +1,280 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Matcher reports whether an entry matches a rule
+type Matcher func(entry models.LogEntry) bool
+
+// ParseMatch compiles a match expression. Expressions compare entry fields
+// with ==, != or the regular expression operators ~ and !~, and combine
+// comparisons with and, or, not and parentheses:
+//
+//	level == ERROR and (service == api or message ~ "timeout after \\d+s")
+//
+// Fields are id, level, service, message, source and fields.<name> for
+// format-specific attributes. Levels compare case-insensitively, and also
+// by severity with <, <=, > and >=, e.g. level >= WARNING; entries with an
+// unregistered level never match those. An empty expression matches every
+// entry.
+func ParseMatch(expr string) (Matcher, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return func(models.LogEntry) bool { return true }, nil
+	}
+
+	p := &exprParser{tokens: tokens}
+	m, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in match expression", p.tokens[p.pos].text)
+	}
+	return m, nil
+}
+
+// token is a word, quoted string, operator or parenthesis
+type token struct {
+	text   string
+	quoted bool
+}
+
+var operators = []string{"==", "!=", "!~", "~", ">=", "<=", ">", "<", "(", ")"}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(expr) && expr[end] != c {
+				if c == '"' && expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string in match expression")
+			}
+			text := expr[i+1 : end]
+			if c == '"' {
+				var err error
+				if text, err = strconv.Unquote(expr[i : end+1]); err != nil {
+					return nil, fmt.Errorf("invalid string %s in match expression", expr[i:end+1])
+				}
+			}
+			tokens = append(tokens, token{text: text, quoted: true})
+			i = end + 1
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op != "" {
+				tokens = append(tokens, token{text: op})
+				i += len(op)
+				continue
+			}
+			end := i
+			for end < len(expr) && !strings.ContainsRune(" \t\"'()=!~<>", rune(expr[end])) {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("unexpected %q in match expression", expr[i:i+1])
+			}
+			tokens = append(tokens, token{text: expr[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+// keyword reports whether the next token is the unquoted word kw and
+// consumes it if so
+func (p *exprParser) keyword(kw string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) next() (token, error) {
+	if p.pos == len(p.tokens) {
+		return token{}, fmt.Errorf("unexpected end of match expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *exprParser) parseOr() (Matcher, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e models.LogEntry) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (Matcher, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e models.LogEntry) bool { return l(e) && right(e) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (Matcher, error) {
+	if p.keyword("not") {
+		m, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e models.LogEntry) bool { return !m(e) }, nil
+	}
+	if p.keyword("(") {
+		m, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword(")") {
+			return nil, fmt.Errorf("missing ) in match expression")
+		}
+		return m, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (Matcher, error) {
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	get, err := fieldGetter(field.text)
+	if err != nil || field.quoted {
+		return nil, fmt.Errorf("unknown field %q in match expression (supported: id, level, service, message, source, fields.<name>)", field.text)
+	}
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if !value.quoted && strings.ContainsAny(value.text, "()") {
+		return nil, fmt.Errorf("expected a value after %s %s", field.text, op.text)
+	}
+
+	switch op.text {
+	case "==", "!=":
+		want := value.text
+		if field.text == "level" {
+			want = strings.ToUpper(want)
+		}
+		negate := op.text == "!="
+		return func(e models.LogEntry) bool { return (get(e) == want) != negate }, nil
+	case "~", "!~":
+		re, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value.text, err)
+		}
+		negate := op.text == "!~"
+		return func(e models.LogEntry) bool { return re.MatchString(get(e)) != negate }, nil
+	case "<", "<=", ">", ">=":
+		if field.text != "level" {
+			return nil, fmt.Errorf("operator %s only applies to level, not %s", op.text, field.text)
+		}
+		return levelComparison(op.text, value.text)
+	}
+	return nil, fmt.Errorf("unknown operator %q after %s (supported: ==, !=, ~, !~, <, <=, >, >=)", op.text, field.text)
+}
+
+// levelComparison matches the entries whose level compares with the level
+// named value as op says, by severity
+func levelComparison(op, value string) (Matcher, error) {
+	level, ok := models.ParseLevel(value)
+	if !ok {
+		return nil, fmt.Errorf("unknown level %q in match expression (known: %v)", value, models.Levels())
+	}
+	return func(e models.LogEntry) bool {
+		if e.Level.Severity() == 0 {
+			// Unregistered levels have no place in the order
+			return false
+		}
+		c := e.Level.Compare(level)
+		switch op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		}
+		return c >= 0
+	}, nil
+}
+
+// Field returns a function reading the named field of an entry: id, level,
+// service, message, source or fields.<name>
+func Field(name string) (func(models.LogEntry) string, error) {
+	return fieldGetter(name)
+}
+
+// fieldGetter returns a function reading the named field of an entry
+func fieldGetter(name string) (func(models.LogEntry) string, error) {
+	switch name {
+	case "id":
+		return func(e models.LogEntry) string { return e.ID }, nil
+	case "level":
+		return func(e models.LogEntry) string { return strings.ToUpper(string(e.Level)) }, nil
+	case "service":
+		return func(e models.LogEntry) string { return e.Service }, nil
+	case "message":
+		return func(e models.LogEntry) string { return e.Message }, nil
+	case "source":
+		return func(e models.LogEntry) string { return e.Source }, nil
+	}
+	if key, ok := strings.CutPrefix(name, "fields."); ok && key != "" && strings.IndexFunc(key, unicode.IsSpace) < 0 {
+		return func(e models.LogEntry) string { return e.Fields[key] }, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", name)
+}
@@ -0,0 +1,194 @@
+// Package rules implements alert rules: a match expression, a time window
+// and a threshold, written in a small YAML format:
+//
+//	rules:
+//	  - name: api-errors
+//	    match: level == ERROR and service == api
+//	    window: 5m
+//	    threshold: 10
+//	    actions: [pagerduty]
+//	    expect: fire
+//
+// A rule fires when at least threshold matching entries fall within window
+// of each other. Rules can be evaluated against sample data before they are
+// deployed, with expect declaring whether the rule should fire on it.
+package rules
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/human"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Actions a rule can take when it fires
+const (
+	ActionLog       = "log"
+	ActionPagerDuty = "pagerduty"
+	ActionOpsgenie  = "opsgenie"
+)
+
+// Expectations of a rule on sample data
+const (
+	ExpectFire  = "fire"
+	ExpectQuiet = "quiet"
+)
+
+// Rule is an alert rule
+type Rule struct {
+	Name string
+	// Match is the match expression, see ParseMatch
+	Match string
+	// Window is the period in which Threshold matches must occur. Zero
+	// counts matches over the whole data.
+	Window    time.Duration
+	Threshold int
+	Actions   []string
+	// Expect is ExpectFire or ExpectQuiet if the rule declares how it should
+	// behave on sample data
+	Expect string
+
+	matcher Matcher
+}
+
+// Matches reports whether entry matches the rule
+func (r *Rule) Matches(entry models.LogEntry) bool {
+	return r.matcher(entry)
+}
+
+// Load reads a rule file
+func Load(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Parse parses and validates the rules of a YAML document. The rules are
+// either the document itself or its "rules" key.
+func Parse(data []byte) ([]*Rule, error) {
+	doc, err := parseYAML(data)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := doc.(map[string]any); ok {
+		doc = m["rules"]
+	}
+	items, ok := doc.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("no rules found")
+	}
+
+	var rules []*Rule
+	names := make(map[string]bool)
+	for i, item := range items {
+		fields, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("rule %d: expected a mapping", i+1)
+		}
+		rule, err := parseRule(fields)
+		if err != nil {
+			if name, _ := fields["name"].(string); name != "" {
+				return nil, fmt.Errorf("rule %q: %w", name, err)
+			}
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate rule name %q", rule.Name)
+		}
+		names[rule.Name] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(fields map[string]any) (*Rule, error) {
+	rule := &Rule{Threshold: 1}
+	for key, value := range fields {
+		var s string
+		if key != "actions" {
+			var ok bool
+			if s, ok = value.(string); !ok {
+				return nil, fmt.Errorf("%s must be a single value", key)
+			}
+		}
+
+		switch key {
+		case "name":
+			rule.Name = s
+		case "match":
+			rule.Match = s
+		case "window":
+			d, err := human.ParseDuration(s)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid window %q", s)
+			}
+			rule.Window = d
+		case "threshold":
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid threshold %q: must be a positive number", s)
+			}
+			rule.Threshold = n
+		case "actions":
+			actions, err := parseActions(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Actions = actions
+		case "expect":
+			if s != ExpectFire && s != ExpectQuiet {
+				return nil, fmt.Errorf("invalid expect %q (supported: %s, %s)", s, ExpectFire, ExpectQuiet)
+			}
+			rule.Expect = s
+		default:
+			return nil, fmt.Errorf("unknown key %q (supported: name, match, window, threshold, actions, expect)", key)
+		}
+	}
+
+	if rule.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	matcher, err := ParseMatch(rule.Match)
+	if err != nil {
+		return nil, err
+	}
+	rule.matcher = matcher
+	if len(rule.Actions) == 0 {
+		rule.Actions = []string{ActionLog}
+	}
+	return rule, nil
+}
+
+// parseActions accepts a single action or a list of them
+func parseActions(value any) ([]string, error) {
+	var items []any
+	switch v := value.(type) {
+	case string:
+		items = []any{v}
+	case []any:
+		items = v
+	default:
+		return nil, fmt.Errorf("actions must be a list")
+	}
+
+	var actions []string
+	for _, item := range items {
+		action, _ := item.(string)
+		switch action {
+		case ActionLog, ActionPagerDuty, ActionOpsgenie:
+			actions = append(actions, action)
+		default:
+			return nil, fmt.Errorf("unknown action %v (supported: %s, %s, %s)", item, ActionLog, ActionPagerDuty, ActionOpsgenie)
+		}
+	}
+	return actions, nil
+}
@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const testRules = `
+# Paging rules
+rules:
+  - name: api-errors
+    match: level == error and (service == api or message ~ "timeout after \\d+s")
+    window: 5m
+    threshold: 3
+    actions:
+      - pagerduty
+      - log
+    expect: fire
+  - name: "any: fatal"   # quoted name
+    match: 'level == FATAL and not fields.env == staging'
+    actions: [opsgenie]
+`
+
+func TestParse(t *testing.T) {
+	rules, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("Expected 2 rules, got %d", len(rules))
+	}
+
+	api := rules[0]
+	if api.Name != "api-errors" || api.Window != 5*time.Minute || api.Threshold != 3 || api.Expect != ExpectFire {
+		t.Errorf("Unexpected rule %+v", api)
+	}
+	if strings.Join(api.Actions, ",") != "pagerduty,log" {
+		t.Errorf("Expected pagerduty and log actions, got %v", api.Actions)
+	}
+	if rules[1].Name != "any: fatal" || rules[1].Threshold != 1 || rules[1].Actions[0] != ActionOpsgenie {
+		t.Errorf("Unexpected rule %+v", rules[1])
+	}
+
+	invalid := map[string]string{
+		"unknown key":    "- name: a\n  matches: level == ERROR\n",
+		"bad field":      "- name: a\n  match: host == web1\n",
+		"bad regexp":     "- name: a\n  match: message ~ \"(\"\n",
+		"bad window":     "- name: a\n  window: soon\n",
+		"bad action":     "- name: a\n  actions: [email]\n",
+		"missing name":   "- match: level == ERROR\n",
+		"duplicate name": "- name: a\n- name: a\n",
+		"bad indent":     "rules:\n  - name: a\n   match: level == ERROR\n",
+		"unbalanced":     "- name: a\n  match: (level == ERROR\n",
+	}
+	for name, doc := range invalid {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestParseMatch(t *testing.T) {
+	entry := models.LogEntry{Level: models.ERROR, Service: "db", Message: "timeout after 30s", Fields: map[string]string{"env": "prod"}}
+	tests := map[string]bool{
+		"":                                     true,
+		"level == error":                       true,
+		"level != ERROR":                       false,
+		"service == api or message ~ timeout":  true,
+		"service == api and message ~ timeout": false,
+		"not (service == api)":                 true,
+		`message !~ "^timeout"`:                false,
+		"fields.env == prod":                   true,
+		"fields.region == ''":                  true,
+		"level >= warn":                        true,
+		"level>ERROR":                          false,
+		"level <= error and level > INFO":      true,
+		"level < WARNING":                      false,
+	}
+	for expr, want := range tests {
+		m, err := ParseMatch(expr)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", expr, err)
+			continue
+		}
+		if got := m(entry); got != want {
+			t.Errorf("Expected %q to be %v, got %v", expr, want, got)
+		}
+	}
+}
+
+func TestParseMatchLevelOrder(t *testing.T) {
+	if _, err := ParseMatch("level >= VERBOSE"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if _, err := ParseMatch("service > api"); err == nil {
+		t.Error("Expected an error for ordering a field other than level")
+	}
+	m, err := ParseMatch("level >= INFO")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if m(models.LogEntry{Level: "VERBOSE"}) {
+		t.Error("Expected an unregistered level not to match")
+	}
+}
+
+func TestEngine(t *testing.T) {
+	rules, err := Parse([]byte("- name: errors\n  match: level == ERROR\n  window: 1m\n  threshold: 2\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+	engine := NewEngine(rules)
+
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	var fired []time.Time
+	// Errors at 0s, 90s, 100s, 110s and 300s, 310s
+	for _, s := range []int{0, 90, 100, 110, 300, 310} {
+		for _, f := range engine.Observe(models.LogEntry{Level: models.ERROR, Timestamp: start.Add(time.Duration(s) * time.Second)}) {
+			fired = append(fired, f.Time)
+		}
+		engine.Observe(models.LogEntry{Level: models.INFO, Timestamp: start.Add(time.Duration(s) * time.Second)})
+	}
+
+	// The rule fires at 100s, stays firing at 110s, and fires again at 310s
+	if len(fired) != 2 || !fired[0].Equal(start.Add(100*time.Second)) || !fired[1].Equal(start.Add(310*time.Second)) {
+		t.Errorf("Expected firings at 100s and 310s, got %v", fired)
+	}
+}
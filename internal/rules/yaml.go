@@ -0,0 +1,267 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a non-blank line of a YAML document with its comment removed
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// yamlParser decodes the subset of YAML used by rule files: block mappings
+// and sequences, flow sequences like [a, b], plain and quoted scalars, and
+// comments. Scalars are returned as strings, mappings as map[string]any and
+// sequences as []any.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseYAML decodes a YAML document
+func parseYAML(data []byte) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		text := strings.TrimRight(stripComment(raw), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+
+	value, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return value, nil
+}
+
+// stripComment removes a comment starting with # outside quotes
+func stripComment(line string) string {
+	var (
+		quote   rune
+		escaped bool
+	)
+	for i, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseBlock parses the mapping or sequence starting at the current line
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseSeq(indent int) ([]any, error) {
+	var items []any
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || !isSeqItem(line.text) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if rest == "" {
+			// The item is the nested block on the following lines
+			p.pos++
+			if p.pos == len(p.lines) || p.lines[p.pos].indent <= indent {
+				items = append(items, "")
+				continue
+			}
+			item, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		if _, _, ok := splitKey(rest); ok || isSeqItem(rest) {
+			// A mapping or sequence starting on the item's line continues
+			// at the column of its first entry
+			p.lines[p.pos] = yamlLine{number: line.number, indent: line.indent + len(line.text) - len(rest), text: rest}
+			item, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		item, err := parseScalar(rest, line.number)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.pos++
+	}
+	return items, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || isSeqItem(line.text) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+		key, rest, ok := splitKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		p.pos++
+
+		if rest != "" {
+			value, err := parseScalar(rest, line.number)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+			continue
+		}
+
+		// The value is a nested block, which for sequences may also start
+		// at the key's own indentation
+		switch {
+		case p.pos == len(p.lines):
+			m[key] = ""
+		case p.lines[p.pos].indent > indent:
+			value, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		case p.lines[p.pos].indent == indent && isSeqItem(p.lines[p.pos].text):
+			value, err := p.parseSeq(indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		default:
+			m[key] = ""
+		}
+	}
+	return m, nil
+}
+
+// splitKey splits "key: value" or "key:" outside quotes
+func splitKey(text string) (key, rest string, ok bool) {
+	if text[0] == '"' || text[0] == '\'' || text[0] == '[' {
+		return "", "", false
+	}
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseScalar parses a plain or quoted scalar, or a flow sequence
+func parseScalar(text string, line int) (any, error) {
+	switch text[0] {
+	case '[':
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("line %d: unterminated sequence", line)
+		}
+		var items []any
+		for _, part := range splitFlow(text[1 : len(text)-1]) {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			item, err := parseScalar(part, line)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case '"':
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", line, text)
+		}
+		return s, nil
+	case '\'':
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", line, text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	return text, nil
+}
+
+// splitFlow splits the items of a flow sequence at commas outside quotes
+func splitFlow(text string) []string {
+	var (
+		parts []string
+		quote rune
+		start int
+	)
+	for i, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
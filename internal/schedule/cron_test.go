@@ -0,0 +1,47 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Sunday afternoon
+	from := time.Date(2023, 1, 1, 14, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"0 2 * * *", time.Date(2023, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, 1, 1, 14, 45, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2023, 1, 1, 15, 0, 0, 0, time.UTC)},
+		{"30 9 * * mon-fri", time.Date(2023, 1, 2, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 mar *", time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2023, 1, 8, 12, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either may match (the 13th or a Friday)
+		{"0 0 13 * 5", time.Date(2023, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"5,10 8-9 * * *", time.Date(2023, 1, 2, 8, 5, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", tt.spec, err)
+			continue
+		}
+		if next := s.Next(from); !next.Equal(tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.spec, tt.expected, next)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "* * * foo *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// GrafanaPrefix is the path under which the Grafana JSON datasource API is
+// served. Point a JSON datasource at http://host:port/grafana.
+const GrafanaPrefix = "/grafana"
+
+// grafanaTotal is the target for entries of all levels
+const grafanaTotal = "total"
+
+// grafanaQuery is the body of a Grafana /query request
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+}
+
+// grafanaSeries is one time series of a /query response. Datapoints are
+// [value, unix milliseconds] pairs.
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// registerGrafana adds the endpoints of the Grafana JSON datasource
+func (s *Server) registerGrafana() {
+	// Grafana tests the connection with a GET on the datasource URL
+	s.mux.HandleFunc(GrafanaPrefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != GrafanaPrefix+"/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	s.mux.HandleFunc(GrafanaPrefix+"/search", s.handleGrafanaSearch)
+	s.mux.HandleFunc(GrafanaPrefix+"/metrics", s.handleGrafanaMetrics)
+	s.mux.HandleFunc(GrafanaPrefix+"/query", s.handleGrafanaQuery)
+}
+
+// currentSummary returns the summary, or nil if none is available
+func (s *Server) currentSummary() *models.LogSummary {
+	s.mu.RLock()
+	summary := s.summary
+	s.mu.RUnlock()
+
+	if summary == nil {
+		return nil
+	}
+	return summary()
+}
+
+// grafanaTargets lists the queryable series: the total and one per level
+func (s *Server) grafanaTargets() []string {
+	targets := []string{grafanaTotal}
+	if summary := s.currentSummary(); summary != nil {
+		var levels []string
+		for level := range summary.ByLevel {
+			levels = append(levels, string(level))
+		}
+		sort.Strings(levels)
+		targets = append(targets, levels...)
+	}
+	return targets
+}
+
+// handleGrafanaSearch lists the targets for the original JSON datasource
+func (s *Server) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.grafanaTargets())
+}
+
+// handleGrafanaMetrics lists the targets for newer JSON datasource versions
+func (s *Server) handleGrafanaMetrics(w http.ResponseWriter, r *http.Request) {
+	var metrics []map[string]string
+	for _, target := range s.grafanaTargets() {
+		metrics = append(metrics, map[string]string{"label": target, "value": target})
+	}
+	writeJSON(w, http.StatusOK, metrics)
+}
+
+// handleGrafanaQuery returns the hourly entry counts of the requested
+// targets within the requested range
+func (s *Server) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "query must be a POST"})
+		return
+	}
+	var query grafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid query: " + err.Error()})
+		return
+	}
+
+	summary := s.currentSummary()
+	if summary == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "no summary available yet"})
+		return
+	}
+
+	hours := make([]time.Time, 0, len(summary.ByHour))
+	for hour := range summary.ByHour {
+		// An hour overlapping the range is included
+		if !query.Range.From.IsZero() && !hour.Add(time.Hour).After(query.Range.From) {
+			continue
+		}
+		if !query.Range.To.IsZero() && hour.After(query.Range.To) {
+			continue
+		}
+		hours = append(hours, hour)
+	}
+	sort.Slice(hours, func(i, j int) bool { return hours[i].Before(hours[j]) })
+
+	series := []grafanaSeries{}
+	for _, t := range query.Targets {
+		if t.Hide || t.Target == "" {
+			continue
+		}
+		points := make([][2]float64, 0, len(hours))
+		for _, hour := range hours {
+			var n int
+			if t.Target == grafanaTotal {
+				for _, count := range summary.ByHour[hour] {
+					n += count
+				}
+			} else {
+				n = summary.ByHour[hour][models.LogLevel(t.Target)]
+			}
+			points = append(points, [2]float64{float64(n), float64(hour.UnixMilli())})
+		}
+		series = append(series, grafanaSeries{Target: t.Target, Datapoints: points})
+	}
+	writeJSON(w, http.StatusOK, series)
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// HistoryFunc returns the hourly and daily summaries overlapping from to to.
+// A zero from or to leaves that side open.
+type HistoryFunc func(from, to time.Time) ([]models.PeriodSummary, error)
+
+// SetHistoryFunc sets where /history reads per-period summaries from
+func (s *Server) SetHistoryFunc(f HistoryFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = f
+}
+
+// handleHistory serves per-period summaries, optionally limited by the
+// RFC 3339 from and to query parameters
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	history := s.history
+	s.mu.RUnlock()
+	if history == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "history is not kept; enable compaction"})
+		return
+	}
+
+	var from, to time.Time
+	for name, t := range map[string]*time.Time{"from": &from, "to": &to} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid " + name + " time, expected RFC 3339"})
+			return
+		}
+		*t = parsed
+	}
+
+	periods, err := history(from, to)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, periods)
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// metricsPrefix namespaces the exported Prometheus metrics
+const metricsPrefix = "logprocessor_"
+
+// handleMetrics exposes the current summary in the Prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	summary := s.currentSummary()
+	if summary == nil {
+		http.Error(w, "no summary available yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	var b strings.Builder
+	writeMetric(&b, "entries_total", "Entries processed.", "", map[string]int{"": summary.TotalEntries})
+
+	byLevel := make(map[string]int, len(summary.ByLevel))
+	for level, n := range summary.ByLevel {
+		byLevel[string(level)] = n
+	}
+	writeMetric(&b, "entries_by_level_total", "Entries processed by level.", "level", byLevel)
+	writeMetric(&b, "entries_by_service_total", "Entries processed by service.", "service", summary.ByService)
+
+	if a := summary.Accounting; a != nil {
+		writeMetric(&b, "entries_read_total", "Entries read from the input.", "", map[string]int{"": a.Read})
+		writeMetric(&b, "entries_skipped_total", "Entries skipped as delivered by an earlier run.", "", map[string]int{"": a.Skipped})
+		writeMetric(&b, "entries_filtered_total", "Entries removed by filters.", "", map[string]int{"": a.Filtered})
+		writeMetric(&b, "entries_duplicate_total", "Entries with an already analyzed ID.", "", map[string]int{"": a.Duplicates})
+		writeMetric(&b, "entries_duplicate_hash_only_total", "Duplicates recognized only by the hash of a restored ID.", "", map[string]int{"": a.HashMatches})
+		writeMetric(&b, "entries_dropped_total", "Entries lost to errors or shutdown.", "reason", a.Dropped)
+	}
+
+	names := make([]string, 0, len(summary.Counters))
+	for name := range summary.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		counter := summary.Counters[name]
+		writeMetric(&b, name+"_total", "Entries matching the "+name+" counter.", counter.Label, counter.Counts)
+	}
+
+	s.mu.RLock()
+	extra := s.metrics
+	s.mu.RUnlock()
+	if extra != nil {
+		if err := extra(&b, metricsPrefix); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	fmt.Fprint(w, b.String())
+}
+
+// writeMetric writes a counter family with one sample per label value, or a
+// single sample if label is empty
+func writeMetric(b *strings.Builder, name, help, label string, values map[string]int) {
+	fmt.Fprintf(b, "# HELP %s%s %s\n# TYPE %s%s counter\n", metricsPrefix, name, help, metricsPrefix, name)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if label == "" {
+			fmt.Fprintf(b, "%s%s %d\n", metricsPrefix, name, values[k])
+			continue
+		}
+		fmt.Fprintf(b, "%s%s{%s=%s} %d\n", metricsPrefix, name, label, quoteLabel(k), values[k])
+	}
+}
+
+// quoteLabel quotes a label value, escaping backslashes, quotes and newlines
+func quoteLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/tenant"
+)
+
+// Service states reported by the health endpoint
+const (
+	StatusStarting  = "starting"
+	StatusRunning   = "running"
+	StatusReloading = "reloading"
+	StatusStopping  = "stopping"
+)
+
+// Server exposes health and summary endpoints over HTTP
+type Server struct {
+	mux     *http.ServeMux
+	started time.Time
+
+	mu      sync.RWMutex
+	status  string
+	summary func() *models.LogSummary
+	history HistoryFunc
+	// healthScores and corsOrigins serve the status endpoint
+	healthScores HealthFunc
+	corsOrigins  []string
+	// metrics writes additional metrics to /metrics
+	metrics func(w io.Writer, prefix string) error
+	// tenants restricts access to API key holders, if set
+	tenants *tenant.Registry
+	// wrap guards every endpoint but /healthz, if set
+	wrap func(http.Handler) http.Handler
+
+	httpServer *http.Server
+}
+
+// New creates a server; call Start to begin listening
+func New() *Server {
+	s := &Server{
+		mux:     http.NewServeMux(),
+		started: time.Now(),
+		status:  StatusStarting,
+	}
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	s.mux.HandleFunc("/summary", s.handleSummary)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	s.mux.HandleFunc("/history", s.handleHistory)
+	s.mux.HandleFunc(StatusPath, s.handleStatus)
+	s.registerGrafana()
+	return s
+}
+
+// Handler returns the HTTP handler serving all endpoints
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+// serveHTTP passes a request through wrap, if set and the path is not
+// /healthz, which stays open for load balancers
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	tenants, wrap := s.tenants, s.wrap
+	s.mu.RUnlock()
+	if wrap != nil && r.URL.Path != "/healthz" {
+		wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.serveTenant(w, r, tenants)
+		})).ServeHTTP(w, r)
+		return
+	}
+	s.serveTenant(w, r, tenants)
+}
+
+// serveTenant checks the caller's API key, if tenants are set, before
+// serving a request. Tenants only see their own summary; admins see every
+// endpoint. /healthz stays open for load balancers, Slack commands are
+// authenticated by their signature instead, and browsers ask whether they
+// may send a key to the status endpoint without one.
+func (s *Server) serveTenant(w http.ResponseWriter, r *http.Request, tenants *tenant.Registry) {
+	preflight := r.Method == http.MethodOptions && r.URL.Path == StatusPath
+	if tenants == nil || r.URL.Path == "/healthz" || r.URL.Path == SlackPath || preflight {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	t := tenants.Authenticate(tenant.KeyFromRequest(r))
+	switch {
+	case t == nil:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or unknown API key"})
+	case t.Admin:
+		s.mux.ServeHTTP(w, r)
+	case r.URL.Path == "/summary":
+		writeJSON(w, http.StatusOK, tenants.Summary(t.Name))
+	default:
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "tenants may only read their own /summary"})
+	}
+}
+
+// Handle serves handler at pattern next to the built-in endpoints
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// SetTenants requires every request but /healthz to carry the API key of
+// one of tenants, serving each tenant its own summary only
+func (s *Server) SetTenants(tenants *tenant.Registry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants = tenants
+}
+
+// Use has wrap guard every endpoint but /healthz, e.g. to require a token
+func (s *Server) Use(wrap func(http.Handler) http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wrap = wrap
+}
+
+// SetStatus updates the state reported by /healthz
+func (s *Server) SetStatus(status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+// SetSummaryFunc sets where /summary reads the current summary from
+func (s *Server) SetSummaryFunc(f func() *models.LogSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.summary = f
+}
+
+// SetMetricsFunc adds the metrics f writes, in the Prometheus text format
+// with names prefixed by prefix, to those /metrics exposes
+func (s *Server) SetMetricsFunc(f func(w io.Writer, prefix string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics = f
+}
+
+// Start listens on addr and serves requests in the background
+func (s *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s.httpServer = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go s.httpServer.Serve(listener)
+	return nil
+}
+
+// Shutdown stops the server, waiting for in-flight requests
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
+}
+
+// handleHealth reports 200 while the service can do work and 503 otherwise
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	status := s.status
+	s.mu.RUnlock()
+
+	code := http.StatusOK
+	if status == StatusStarting || status == StatusStopping {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]interface{}{
+		"status":         status,
+		"uptime_seconds": int(time.Since(s.started).Seconds()),
+	})
+}
+
+func (s *Server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	current := s.currentSummary()
+	if current == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "no summary available yet"})
+		return
+	}
+	writeJSON(w, http.StatusOK, current)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
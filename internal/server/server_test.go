@@ -0,0 +1,403 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/recent"
+	"github.com/interview/junior-go-challenge/internal/tenant"
+)
+
+func get(t *testing.T, s *Server, path string) (int, map[string]interface{}) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON from %s: %v", path, err)
+	}
+	return rec.Code, body
+}
+
+func TestHealth(t *testing.T) {
+	s := New()
+
+	if code, body := get(t, s, "/healthz"); code != http.StatusServiceUnavailable || body["status"] != StatusStarting {
+		t.Errorf("Expected 503 while starting, got %d %v", code, body)
+	}
+
+	s.SetStatus(StatusRunning)
+	if code, body := get(t, s, "/healthz"); code != http.StatusOK || body["status"] != StatusRunning {
+		t.Errorf("Expected 200 while running, got %d %v", code, body)
+	}
+
+	// Reloads keep the service healthy
+	s.SetStatus(StatusReloading)
+	if code, _ := get(t, s, "/healthz"); code != http.StatusOK {
+		t.Errorf("Expected 200 while reloading, got %d", code)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	s := New()
+	if code, _ := get(t, s, "/summary"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a summary, got %d", code)
+	}
+
+	s.SetSummaryFunc(func() *models.LogSummary {
+		summary := models.NewLogSummary()
+		summary.TotalEntries = 3
+		summary.ByLevel[models.ERROR] = 3
+		return summary
+	})
+	code, body := get(t, s, "/summary")
+	if code != http.StatusOK || body["total_entries"] != float64(3) {
+		t.Errorf("Unexpected summary response %d %v", code, body)
+	}
+	if byLevel := body["by_level"].(map[string]interface{}); byLevel["ERROR"] != float64(3) {
+		t.Errorf("Unexpected level counts %v", byLevel)
+	}
+}
+
+func TestGrafanaQuery(t *testing.T) {
+	s := New()
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.SetSummaryFunc(func() *models.LogSummary {
+		summary := models.NewLogSummary()
+		summary.ByLevel[models.ERROR] = 3
+		summary.ByLevel[models.INFO] = 4
+		summary.AddHour(start, models.INFO, 4)
+		summary.AddHour(start.Add(time.Hour), models.ERROR, 1)
+		summary.AddHour(start.Add(2*time.Hour), models.ERROR, 2)
+		return summary
+	})
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, GrafanaPrefix+"/search", strings.NewReader("{}")))
+	if body := strings.TrimSpace(rec.Body.String()); body != `["total","ERROR","INFO"]` {
+		t.Errorf("Unexpected targets %s", body)
+	}
+
+	query := `{"range": {"from": "2023-01-01T10:30:00Z", "to": "2023-01-01T11:30:00Z"},
+		"targets": [{"target": "total"}, {"target": "ERROR"}, {"target": "INFO", "hide": true}]}`
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, GrafanaPrefix+"/query", strings.NewReader(query)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var series []grafanaSeries
+	if err := json.Unmarshal(rec.Body.Bytes(), &series); err != nil {
+		t.Fatalf("Invalid query response: %v", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %+v", series)
+	}
+	// The range overlaps the 10:00 and 11:00 hours
+	ms := float64(start.UnixMilli())
+	expected := [][2]float64{{4, ms}, {1, ms + 3600e3}}
+	if !reflect.DeepEqual(series[0].Datapoints, expected) {
+		t.Errorf("Expected total datapoints %v, got %v", expected, series[0].Datapoints)
+	}
+	if series[1].Target != "ERROR" || series[1].Datapoints[0][0] != 0 || series[1].Datapoints[1][0] != 1 {
+		t.Errorf("Unexpected ERROR series %+v", series[1])
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := New()
+	s.SetSummaryFunc(func() *models.LogSummary {
+		summary := models.NewLogSummary()
+		summary.TotalEntries = 3
+		summary.ByLevel[models.ERROR] = 3
+		summary.ByService["api"] = 3
+		summary.AddCounter("cache_misses", "key", `us"ers`, 2)
+		return summary
+	})
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE logprocessor_entries_total counter\nlogprocessor_entries_total 3\n",
+		`logprocessor_entries_by_level_total{level="ERROR"} 3`,
+		`logprocessor_entries_by_service_total{service="api"} 3`,
+		`logprocessor_cache_misses_total{key="us\"ers"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsFunc(t *testing.T) {
+	s := New()
+	s.SetSummaryFunc(func() *models.LogSummary { return models.NewLogSummary() })
+	s.SetMetricsFunc(func(w io.Writer, prefix string) error {
+		_, err := fmt.Fprintf(w, "%squeue_depth 3\n", prefix)
+		return err
+	})
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "\nlogprocessor_queue_depth 3\n") {
+		t.Errorf("Expected the additional metrics, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := New()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without history, got %d", rec.Code)
+	}
+
+	var gotFrom time.Time
+	s.SetHistoryFunc(func(from, to time.Time) ([]models.PeriodSummary, error) {
+		gotFrom = from
+		summary := models.NewLogSummary()
+		summary.TotalEntries = 7
+		return []models.PeriodSummary{{Period: "2023-01-01", Start: from, Summary: summary}}, nil
+	})
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history?from=2023-01-01T00:00:00Z", nil))
+	var periods []models.PeriodSummary
+	if err := json.Unmarshal(rec.Body.Bytes(), &periods); err != nil {
+		t.Fatalf("Invalid JSON from /history: %v", err)
+	}
+	if len(periods) != 1 || periods[0].Summary.TotalEntries != 7 {
+		t.Errorf("Unexpected history %+v", periods)
+	}
+	if !gotFrom.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected from to be passed on, got %v", gotFrom)
+	}
+
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history?to=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid time, got %d", rec.Code)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+	tenants, err := tenant.New([]tenant.Definition{
+		{Name: "payments", KeySHA256: hash("pay-key")},
+		{Name: "ops", KeySHA256: hash("ops-key"), Admin: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tenants: %v", err)
+	}
+	tenants.Write(models.LogEntry{ID: "payments/1", Tenant: "payments", Level: models.INFO})
+
+	s := New()
+	s.SetStatus(StatusRunning)
+	s.SetSummaryFunc(func() *models.LogSummary {
+		summary := models.NewLogSummary()
+		summary.TotalEntries = 10
+		return summary
+	})
+	s.SetTenants(tenants)
+
+	request := func(path, key string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	if code, _ := request("/healthz", ""); code != http.StatusOK {
+		t.Errorf("Expected /healthz to stay open, got %d", code)
+	}
+	if code, _ := request("/summary", ""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a key, got %d", code)
+	}
+	if code, body := request("/summary", "pay-key"); code != http.StatusOK || body["total_entries"] != float64(1) {
+		t.Errorf("Expected the payments summary of 1 entry, got %d %v", code, body["total_entries"])
+	}
+	if code, _ := request("/history", "pay-key"); code != http.StatusForbidden {
+		t.Errorf("Expected tenants to be refused other endpoints, got %d", code)
+	}
+	if code, body := request("/summary", "ops-key"); code != http.StatusOK || body["total_entries"] != float64(10) {
+		t.Errorf("Expected admins to see the whole summary, got %d %v", code, body["total_entries"])
+	}
+}
+
+func TestParseSlackQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want SlackQuery
+	}{
+		{"errors for service api last 1h", SlackQuery{Errors: true, Service: "api", Window: time.Hour}},
+		{"errors api", SlackQuery{Errors: true, Service: "api", Window: time.Hour}},
+		{"summary last 2 hours", SlackQuery{Window: 2 * time.Hour}},
+		{"db last 30m", SlackQuery{Service: "db", Window: 30 * time.Minute}},
+		{"errors last hour for checkout", SlackQuery{Errors: true, Service: "checkout", Window: time.Hour}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSlackQuery(tt.text)
+		if err != nil {
+			t.Errorf("ParseSlackQuery(%q) failed: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSlackQuery(%q): expected %+v, got %+v", tt.text, tt.want, got)
+		}
+	}
+	for _, text := range []string{"errors for service", "errors last soon", "api db"} {
+		if _, err := ParseSlackQuery(text); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
+
+func TestSlackCommand(t *testing.T) {
+	now := time.Now()
+	activity := recent.New(24*time.Hour, clock.NewFake(now))
+	for i := 0; i < 3; i++ {
+		activity.Add(models.LogEntry{Timestamp: now.Add(-10 * time.Minute), Level: models.ERROR, Service: "api", Message: fmt.Sprintf("query %d timed out", i)})
+	}
+	activity.Add(models.LogEntry{Timestamp: now.Add(-5 * time.Minute), Level: models.INFO, Service: "api", Message: "ok"})
+	activity.Add(models.LogEntry{Timestamp: now.Add(-5 * time.Minute), Level: models.ERROR, Service: "db", Message: "disk full"})
+
+	s := New()
+	s.SetSlackCommand("secret", activity)
+	// Slack is not a tenant, so it is let through by its signature
+	tenants, err := tenant.New([]tenant.Definition{{Name: "ops", KeySHA256: strings.Repeat("0", 64), Admin: true}})
+	if err != nil {
+		t.Fatalf("Failed to create tenants: %v", err)
+	}
+	s.SetTenants(tenants)
+
+	post := func(text, secret string, sent time.Time) (int, map[string]interface{}) {
+		body := url.Values{"command": {"/logs"}, "text": {text}}.Encode()
+		ts := strconv.FormatInt(sent.Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte("v0:" + ts + ":" + body))
+
+		req := httptest.NewRequest(http.MethodPost, SlackPath, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		var reply map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &reply)
+		return rec.Code, reply
+	}
+
+	code, reply := post("errors for service api last 1h", "secret", now)
+	if code != http.StatusOK || reply["response_type"] != "in_channel" {
+		t.Fatalf("Expected a reply in the channel, got %d %v", code, reply)
+	}
+	text, _ := reply["text"].(string)
+	for _, want := range []string{"3 errors in *api* in the last hour (75.0% of 4 entries)", "3× `query <*> timed out`"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in reply:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "disk full") {
+		t.Errorf("Expected only api errors, got:\n%s", text)
+	}
+
+	if _, reply := post("summary", "secret", now); !strings.Contains(reply["text"].(string), "5 entries in all services") {
+		t.Errorf("Unexpected summary reply %v", reply)
+	}
+	if _, reply := post("errors last 2d", "secret", now); reply["response_type"] != "ephemeral" {
+		t.Errorf("Expected a private reply beyond the retention, got %v", reply)
+	}
+	if code, _ := post("errors", "wrong", now); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong signature, got %d", code)
+	}
+	if code, _ := post("errors", "secret", now.Add(-time.Hour)); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a replayed request, got %d", code)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	s := New()
+	if code, body := get(t, s, StatusPath); code != http.StatusNotFound {
+		t.Errorf("Expected 404 without health scores, got %d %v", code, body)
+	}
+
+	s.SetHealthFunc(func() []analyzer.ServiceHealth {
+		return []analyzer.ServiceHealth{
+			{Service: "db", Score: 72.345, Entries: 100, ErrorRatio: 0.3, Fatal: 1},
+			{Service: "api", Score: 99, Entries: 300, ErrorRatio: 0.01},
+		}
+	})
+	s.SetCORSOrigins([]string{"https://status.example.com"})
+
+	req := httptest.NewRequest(http.MethodGet, StatusPath, nil)
+	req.Header.Set("Origin", "https://status.example.com")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://status.example.com" {
+		t.Errorf("Expected the origin allowed, got %q", got)
+	}
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Invalid status: %v", err)
+	}
+	if status.Status != ServiceDegraded || status.Entries != 400 || status.ErrorRate != 0.0825 {
+		t.Errorf("Unexpected overall status %+v", status)
+	}
+	if len(status.Services) != 2 || status.Services[0] != (ServiceStatus{Service: "db", Status: ServiceDegraded, Score: 72.3, Entries: 100, ErrorRate: 0.3, Fatal: 1}) {
+		t.Errorf("Unexpected services %+v", status.Services)
+	}
+
+	// Other origins are not allowed
+	req = httptest.NewRequest(http.MethodGet, StatusPath, nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS header for another origin, got %q", got)
+	}
+
+	// Browsers check before sending an API key, which they cannot do with one
+	tenants, err := tenant.New([]tenant.Definition{{Name: "ops", KeySHA256: strings.Repeat("0", 64), Admin: true}})
+	if err != nil {
+		t.Fatalf("Failed to create tenants: %v", err)
+	}
+	s.SetTenants(tenants)
+	req = httptest.NewRequest(http.MethodOptions, StatusPath, nil)
+	req.Header.Set("Origin", "https://status.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Headers") == "" {
+		t.Errorf("Expected the preflight answered, got %d %v", rec.Code, rec.Header())
+	}
+	if code, _ := get(t, s, StatusPath); code != http.StatusUnauthorized {
+		t.Errorf("Expected the status to need a key, got %d", code)
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/human"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/recent"
+)
+
+// SlackPath is where Slack slash commands are served. Set it as the
+// request URL of the command, e.g. http://host:port/slack/command.
+const SlackPath = "/slack/command"
+
+// slackMaxSkew is how old a request may be before it is rejected as a replay
+const slackMaxSkew = 5 * time.Minute
+
+// slackTopErrors is the number of error patterns in a reply
+const slackTopErrors = 5
+
+// slackUsage explains the queries the command understands
+const slackUsage = "Ask for `errors` or a `summary`, optionally `for service NAME` and `last DURATION`, e.g. `errors for service api last 1h`."
+
+// SlackQuery is a question asked through the slash command
+type SlackQuery struct {
+	// Errors asks for the errors only, rather than a summary of all levels
+	Errors bool
+	// Service is empty for all services
+	Service string
+	Window  time.Duration
+}
+
+// ParseSlackQuery parses the text of a slash command such as
+// "errors for service api last 1h" or "summary last 30 minutes". Without a
+// window the last hour is described.
+func ParseSlackQuery(text string) (SlackQuery, error) {
+	q := SlackQuery{Window: time.Hour}
+	words := strings.Fields(text)
+	if len(words) > 0 {
+		switch strings.ToLower(words[0]) {
+		case "errors", "error":
+			q.Errors = true
+			words = words[1:]
+		case "summary", "status":
+			words = words[1:]
+		}
+	}
+	for len(words) > 0 {
+		switch strings.ToLower(words[0]) {
+		case "for", "of", "in":
+			words = words[1:]
+		case "service":
+			if len(words) < 2 {
+				return q, fmt.Errorf("missing service name")
+			}
+			q.Service, words = words[1], words[2:]
+		case "last", "past":
+			// The window runs up to the next keyword, e.g. "last 2 hours"
+			end := 1
+			for end < len(words) && !slackKeyword(words[end]) {
+				end++
+			}
+			window := strings.Join(words[1:end], " ")
+			d, err := human.ParseDuration(window)
+			if err != nil {
+				// "last hour"
+				d, err = human.ParseDuration("1 " + window)
+			}
+			if err != nil || d <= 0 {
+				return q, fmt.Errorf("invalid duration %q", window)
+			}
+			q.Window, words = d, words[end:]
+		default:
+			if q.Service != "" {
+				return q, fmt.Errorf("unexpected %q", words[0])
+			}
+			q.Service, words = words[0], words[1:]
+		}
+	}
+	return q, nil
+}
+
+func slackKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "for", "of", "in", "service", "last", "past":
+		return true
+	}
+	return false
+}
+
+// SetSlackCommand answers Slack slash commands at SlackPath from activity.
+// Requests must be signed with the app's signing secret.
+func (s *Server) SetSlackCommand(signingSecret string, activity *recent.Activity) {
+	s.mux.HandleFunc(SlackPath, func(w http.ResponseWriter, r *http.Request) {
+		s.handleSlackCommand(w, r, []byte(signingSecret), activity)
+	})
+}
+
+// handleSlackCommand verifies that a slash command comes from Slack and
+// replies in the channel with the entries asked for
+func (s *Server) handleSlackCommand(w http.ResponseWriter, r *http.Request, secret []byte, activity *recent.Activity) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "slash commands must be a POST"})
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read request"})
+		return
+	}
+	if !verifySlack(r.Header, body, secret, time.Now()) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid Slack signature"})
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid form"})
+		return
+	}
+
+	// Only the asker sees help and mistakes
+	text := strings.TrimSpace(form.Get("text"))
+	if text == "" || strings.EqualFold(text, "help") {
+		writeJSON(w, http.StatusOK, map[string]string{"response_type": "ephemeral", "text": slackUsage})
+		return
+	}
+	q, err := ParseSlackQuery(text)
+	if err != nil {
+		writeJSON(w, http.StatusOK, map[string]string{"response_type": "ephemeral", "text": fmt.Sprintf("Sorry, %v. %s", err, slackUsage)})
+		return
+	}
+	if q.Window > activity.Retention() {
+		writeJSON(w, http.StatusOK, map[string]string{"response_type": "ephemeral",
+			"text": fmt.Sprintf("Sorry, only the last %s are kept.", slackDuration(activity.Retention()))})
+		return
+	}
+	stats := activity.Stats(q.Service, q.Window, slackTopErrors)
+	writeJSON(w, http.StatusOK, map[string]string{"response_type": "in_channel", "text": slackReply(q, stats)})
+}
+
+// verifySlack checks the signature Slack computes over the request
+// timestamp and body with the signing secret, and that the request is
+// recent
+func verifySlack(header http.Header, body, secret []byte, now time.Time) bool {
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(sent, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(header.Get("X-Slack-Signature"), "v0="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// slackReply formats the answer to a query in Slack's markup
+func slackReply(q SlackQuery, stats recent.Stats) string {
+	var b strings.Builder
+	scope := "all services"
+	if q.Service != "" {
+		scope = "*" + q.Service + "*"
+	}
+	window := slackDuration(q.Window)
+	if q.Errors {
+		fmt.Fprintf(&b, "%d errors in %s in the last %s", stats.Errors, scope, window)
+		if stats.Total > 0 {
+			fmt.Fprintf(&b, " (%.1f%% of %d entries)", 100*float64(stats.Errors)/float64(stats.Total), stats.Total)
+		}
+		b.WriteString("\n")
+	} else {
+		fmt.Fprintf(&b, "%d entries in %s in the last %s\n", stats.Total, scope, window)
+		levels := make([]models.LogLevel, 0, len(stats.ByLevel))
+		for level := range stats.ByLevel {
+			levels = append(levels, level)
+		}
+		sort.Slice(levels, func(i, j int) bool {
+			if c := levels[i].Compare(levels[j]); c != 0 {
+				return c > 0
+			}
+			return levels[i] < levels[j]
+		})
+		for _, level := range levels {
+			fmt.Fprintf(&b, "• %s: %d\n", level, stats.ByLevel[level])
+		}
+	}
+	if len(stats.TopErrors) > 0 {
+		b.WriteString("Top errors:\n")
+		for _, p := range stats.TopErrors {
+			fmt.Fprintf(&b, "• %d× `%s`\n", p.Count, strings.ReplaceAll(p.Pattern, "`", "'"))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// slackDuration writes a window the way it would be asked for, e.g. "hour"
+// or "30 minutes"
+func slackDuration(d time.Duration) string {
+	switch {
+	case d == time.Hour:
+		return "hour"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d == time.Minute:
+		return "minute"
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	return d.String()
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+)
+
+// StatusPath serves the compact status of every service for status pages.
+// Its fields only ever grow, so pages may rely on them.
+const StatusPath = "/status"
+
+// Service states of the status endpoint, from the health score
+const (
+	ServiceHealthy   = "healthy"
+	ServiceDegraded  = "degraded"
+	ServiceUnhealthy = "unhealthy"
+)
+
+// Lowest health scores of healthy and degraded services
+const (
+	healthyScore  = 90
+	degradedScore = 70
+)
+
+// statusMaxAge lets browsers and proxies reuse a status for this long, so
+// many viewers of a status page do not each hit the processor
+const statusMaxAge = 10 * time.Second
+
+// HealthFunc returns the current health of every service
+type HealthFunc func() []analyzer.ServiceHealth
+
+// ServiceStatus is the status of one service
+type ServiceStatus struct {
+	Service string `json:"service"`
+	Status  string `json:"status"`
+	// Score is the health score from 0 to 100
+	Score   float64 `json:"score"`
+	Entries int     `json:"entries"`
+	// ErrorRate is the share of ERROR and FATAL entries
+	ErrorRate float64 `json:"error_rate"`
+	Fatal     int     `json:"fatal"`
+	Anomalies int     `json:"anomalies"`
+}
+
+// Status is the body of the status endpoint
+type Status struct {
+	// Status is the worst status of any service, healthy if there are none
+	Status    string          `json:"status"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Entries   int             `json:"entries"`
+	ErrorRate float64         `json:"error_rate"`
+	Services  []ServiceStatus `json:"services"`
+}
+
+// SetHealthFunc sets where the status endpoint reads service health from
+func (s *Server) SetHealthFunc(f HealthFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthScores = f
+}
+
+// SetCORSOrigins lets pages from origins read the status endpoint from the
+// browser. "*" allows every origin.
+func (s *Server) SetCORSOrigins(origins []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.corsOrigins = origins
+}
+
+// allowCORS adds the headers letting the request's origin read the
+// response, if it is allowed
+func (s *Server) allowCORS(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	origins := s.corsOrigins
+	s.mu.RUnlock()
+
+	origin := r.Header.Get("Origin")
+	w.Header().Add("Vary", "Origin")
+	for _, allowed := range origins {
+		if allowed == "*" {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			break
+		}
+		if origin != "" && strings.EqualFold(allowed, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			break
+		}
+	}
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, X-API-Key")
+		w.Header().Set("Access-Control-Max-Age", "3600")
+	}
+}
+
+// handleStatus serves the status of every service, worst first
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.allowCORS(w, r)
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodGet, http.MethodHead:
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "status must be a GET"})
+		return
+	}
+
+	s.mu.RLock()
+	health := s.healthScores
+	s.mu.RUnlock()
+	if health == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "services are not scored; add -section health"})
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(statusMaxAge/time.Second)))
+	writeJSON(w, http.StatusOK, NewStatus(health(), time.Now()))
+}
+
+// NewStatus summarizes the health of services, given worst first
+func NewStatus(scores []analyzer.ServiceHealth, now time.Time) Status {
+	status := Status{Status: ServiceHealthy, UpdatedAt: now.UTC(), Services: make([]ServiceStatus, 0, len(scores))}
+	errors := 0.0
+	for _, h := range scores {
+		service := ServiceStatus{
+			Service:   h.Service,
+			Status:    serviceState(h.Score),
+			Score:     round(h.Score, 1),
+			Entries:   h.Entries,
+			ErrorRate: round(h.ErrorRatio, 4),
+			Fatal:     h.Fatal,
+			Anomalies: h.Anomalies,
+		}
+		if severity(service.Status) > severity(status.Status) {
+			status.Status = service.Status
+		}
+		status.Entries += h.Entries
+		errors += h.ErrorRatio * float64(h.Entries)
+		status.Services = append(status.Services, service)
+	}
+	if status.Entries > 0 {
+		status.ErrorRate = round(errors/float64(status.Entries), 4)
+	}
+	return status
+}
+
+// serviceState maps a health score onto a state
+func serviceState(score float64) string {
+	switch {
+	case score >= healthyScore:
+		return ServiceHealthy
+	case score >= degradedScore:
+		return ServiceDegraded
+	}
+	return ServiceUnhealthy
+}
+
+// severity orders the states from healthy to unhealthy
+func severity(state string) int {
+	switch state {
+	case ServiceDegraded:
+		return 1
+	case ServiceUnhealthy:
+		return 2
+	}
+	return 0
+}
+
+func round(f float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(f*scale) / scale
+}
@@ -0,0 +1,225 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/crypt"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// annotatedFile is an output file of the annotating sink
+type annotatedFile struct {
+	file *os.File
+	// crypt encrypts what is written to the file, if enabled
+	crypt io.WriteCloser
+	w     *bufio.Writer
+	enc   *json.Encoder
+}
+
+// annotating writes entries back out with the annotations added during
+// processing, one output file per input file
+type annotating struct {
+	dir    string
+	cipher *crypt.Cipher
+	// path names the output files after entries instead of their sources
+	path *PathTemplate
+	// shards spreads the entries of each file over this many by hash of
+	// their ID, if above one
+	shards int
+	// shardField and shardValue put entries in a directory per value of a
+	// field, if set
+	shardField string
+	shardValue func(models.LogEntry) string
+	// form normalizes messages before their pattern is taken
+	form textnorm.Form
+
+	mu    sync.Mutex
+	files map[string]*annotatedFile
+}
+
+// NewAnnotating creates a sink rewriting the input into dir: every entry is
+// written as NDJSON, with its normalized level, to a file named after its
+// source, with a .gz suffix removed and .ndjson added. The message pattern
+// and its ID are added as the pattern and pattern_id fields, so consumers
+// get cleaned-up logs without repeating the normalization. The files are
+// encrypted with c unless it is nil.
+func NewAnnotating(dir string, c *crypt.Cipher, opts ...AnnotatingOption) (Sink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create annotation directory: %w", err)
+	}
+	a := &annotating{dir: dir, cipher: c, files: make(map[string]*annotatedFile)}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a, nil
+}
+
+// AnnotatingOption configures the annotating sink
+type AnnotatingOption func(*annotating)
+
+// WithPathTemplate writes each entry to the file the template names, e.g.
+// one per service and day, instead of one per input file
+func WithPathTemplate(p *PathTemplate) AnnotatingOption {
+	return func(a *annotating) {
+		a.path = p
+	}
+}
+
+// WithHashShards spreads the entries of each file over n files by hash of
+// their ID, suffixed -000 to -(n-1), so parallel consumers get balanced
+// inputs. Entries with the same ID always land in the same shard.
+func WithHashShards(n int) AnnotatingOption {
+	return func(a *annotating) {
+		a.shards = n
+	}
+}
+
+// WithFieldShards puts each file in a directory per value of a field,
+// named like name=value, with value reading it from an entry
+func WithFieldShards(name string, value func(models.LogEntry) string) AnnotatingOption {
+	return func(a *annotating) {
+		a.shardField = name
+		a.shardValue = value
+	}
+}
+
+// WithNormalization normalizes messages with form before their pattern is
+// taken. The messages are written as received.
+func WithNormalization(form textnorm.Form) AnnotatingOption {
+	return func(a *annotating) {
+		a.form = form
+	}
+}
+
+func (a *annotating) Write(entry models.LogEntry) error {
+	pattern := analyzer.NormalizedPattern(entry.Message, a.form)
+	fields := make(map[string]string, len(entry.Fields)+2)
+	for k, v := range entry.Fields {
+		fields[k] = v
+	}
+	fields["pattern"] = pattern
+	fields["pattern_id"] = analyzer.PatternID(pattern)
+	entry.Fields = fields
+
+	name := annotatedName(entry.Source)
+	if a.path != nil {
+		var err error
+		if name, err = a.path.Path(entry); err != nil {
+			return err
+		}
+	}
+
+	name = a.shard(name, entry)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, err := a.file(name)
+	if err != nil {
+		return err
+	}
+	if err := f.enc.Encode(entry); err != nil {
+		return fmt.Errorf("failed to write annotated entry: %w", err)
+	}
+	return nil
+}
+
+// shard returns the name of the shard of file name that entry belongs to
+func (a *annotating) shard(name string, entry models.LogEntry) string {
+	if a.shards > 1 {
+		key := entry.ID
+		if key == "" {
+			key = entry.Timestamp.String() + entry.Message
+		}
+		h := fnv.New32a()
+		h.Write([]byte(key))
+		ext := filepath.Ext(name)
+		name = fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(name, ext), h.Sum32()%uint32(a.shards), ext)
+	}
+	if a.shardValue != nil {
+		value := a.shardValue(entry)
+		if value == "" {
+			value = "__none__"
+		}
+		name = filepath.Join(a.shardField+"="+url.PathEscape(value), name)
+	}
+	return name
+}
+
+// file returns the output file named name, creating it on first use.
+// Callers hold mu.
+func (a *annotating) file(name string) (*annotatedFile, error) {
+	if f, ok := a.files[name]; ok {
+		return f, nil
+	}
+	path := filepath.Join(a.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create annotation directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create annotated file: %w", err)
+	}
+	encrypted := a.cipher.NewWriter(file)
+	w := bufio.NewWriter(encrypted)
+	f := &annotatedFile{file: file, crypt: encrypted, w: w, enc: json.NewEncoder(w)}
+	a.files[name] = f
+	return f, nil
+}
+
+// annotatedName returns the output path of source relative to the
+// directory. Paths inside archives are kept, but cannot leave it.
+func annotatedName(source string) string {
+	name := filepath.Clean("/" + filepath.FromSlash(source))[1:]
+	if name == "" {
+		name = "unknown"
+	}
+	name = strings.TrimSuffix(name, ".gz")
+	if !strings.HasSuffix(name, ".ndjson") {
+		name += ".ndjson"
+	}
+	return name
+}
+
+// Flush writes the buffered entries to the files. Encrypted files hold back
+// up to one chunk until they are closed.
+func (a *annotating) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, f := range a.files {
+		if err := f.w.Flush(); err != nil {
+			return fmt.Errorf("failed to write annotated file: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close flushes and closes every output file
+func (a *annotating) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var firstErr error
+	for name, f := range a.files {
+		if err := f.w.Flush(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to write annotated file: %w", err)
+		}
+		if err := f.crypt.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to write annotated file: %w", err)
+		}
+		if err := f.file.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close annotated file: %w", err)
+		}
+		delete(a.files, name)
+	}
+	return firstErr
+}
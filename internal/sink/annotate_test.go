@@ -0,0 +1,163 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestAnnotatingSink(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewAnnotating(dir, nil)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+
+	entries := []models.LogEntry{
+		{ID: "1", Level: models.WARNING, Service: "api", Message: "retry 3 failed", Source: "app.log.gz", Fields: map[string]string{"k": "v"}},
+		{ID: "2", Level: models.INFO, Service: "api", Message: "retry 4 failed", Source: "app.log.gz"},
+		{ID: "3", Level: models.ERROR, Message: "boom", Source: "logs.tar/../../escape.log"},
+	}
+	for _, entry := range entries {
+		if err := s.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	if entries[0].Fields["pattern"] != "" {
+		t.Error("Expected the written entry's fields to be left alone")
+	}
+
+	written := readNDJSON(t, filepath.Join(dir, "app.log.ndjson"))
+	if len(written) != 2 {
+		t.Fatalf("Expected 2 entries in app.log.ndjson, got %d", len(written))
+	}
+	pattern := "retry <*> failed"
+	for _, entry := range written {
+		if entry.Fields["pattern"] != pattern || entry.Fields["pattern_id"] != analyzer.PatternID(pattern) {
+			t.Errorf("Expected pattern %q and its ID, got %v", pattern, entry.Fields)
+		}
+	}
+	if written[0].Level != models.WARNING || written[0].Fields["k"] != "v" {
+		t.Errorf("Expected the level and fields to be kept, got %+v", written[0])
+	}
+
+	// Sources cannot write outside the directory
+	if got := readNDJSON(t, filepath.Join(dir, "escape.log.ndjson")); len(got) != 1 {
+		t.Errorf("Expected the escaping source to be kept inside the directory, got %d entries", len(got))
+	}
+}
+
+func TestAnnotatingPathTemplate(t *testing.T) {
+	if _, err := ParsePathTemplate("{{.Missing}}.ndjson"); err == nil {
+		t.Error("Expected a template using an unknown field to be rejected")
+	}
+	path, err := ParsePathTemplate(`{{.Service}}/{{.Timestamp.Format "2006-01-02"}}{{.Fields.region}}.ndjson`)
+	if err != nil {
+		t.Fatalf("Failed to parse template: %v", err)
+	}
+	dir := t.TempDir()
+	s, err := NewAnnotating(dir, nil, WithPathTemplate(path))
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+
+	day := time.Date(2023, 1, 1, 23, 0, 0, 0, time.FixedZone("CET", 3600))
+	entries := []models.LogEntry{
+		{ID: "1", Timestamp: day, Service: "api", Message: "a", Source: "a.log"},
+		{ID: "2", Timestamp: day.Add(2 * time.Hour), Service: "api", Message: "b", Source: "b.log"},
+		{ID: "3", Timestamp: day, Service: "../web", Message: "c", Source: "a.log"},
+	}
+	for _, entry := range entries {
+		if err := s.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	// Days are UTC days
+	for file, want := range map[string]int{"api/2023-01-01.ndjson": 1, "api/2023-01-02.ndjson": 1, "web/2023-01-01.ndjson": 1} {
+		if got := readNDJSON(t, filepath.Join(dir, file)); len(got) != want {
+			t.Errorf("Expected %d entries in %s, got %d", want, file, len(got))
+		}
+	}
+}
+
+func TestAnnotatingShards(t *testing.T) {
+	dir := t.TempDir()
+	region := func(e models.LogEntry) string { return e.Fields["region"] }
+	s, err := NewAnnotating(dir, nil, WithHashShards(4), WithFieldShards("region", region))
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		entry := models.LogEntry{ID: fmt.Sprintf("id-%d", i), Message: "m", Source: "app.log", Fields: map[string]string{"region": "eu"}}
+		if i%2 == 1 {
+			entry.Fields = nil
+		}
+		// Repeated IDs go to the same shard
+		for j := 0; j < 2; j++ {
+			if err := s.Write(entry); err != nil {
+				t.Fatalf("Failed to write entry: %v", err)
+			}
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	for _, value := range []string{"region=eu", "region=__none__"} {
+		total := 0
+		for shard := 0; shard < 4; shard++ {
+			entries := readNDJSON(t, filepath.Join(dir, value, fmt.Sprintf("app.log-%03d.ndjson", shard)))
+			if len(entries) == 0 {
+				t.Errorf("Expected entries in every shard of %s", value)
+			}
+			ids := make(map[string]int)
+			for _, entry := range entries {
+				ids[entry.ID]++
+			}
+			for id, n := range ids {
+				if n != 2 {
+					t.Errorf("Expected both copies of %s in one shard, got %d", id, n)
+				}
+			}
+			total += len(entries)
+		}
+		if total != 100 {
+			t.Errorf("Expected 100 entries under %s, got %d", value, total)
+		}
+	}
+}
+
+// readNDJSON reads the entries of an NDJSON file
+func readNDJSON(t *testing.T, path string) []models.LogEntry {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var entries []models.LogEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry models.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("Invalid entry in %s: %v", path, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
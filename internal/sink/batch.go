@@ -0,0 +1,120 @@
+package sink
+
+import (
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// BatchWriter is implemented by sinks that can send several entries at once,
+// e.g. through a bulk API
+type BatchWriter interface {
+	WriteBatch(entries []models.LogEntry) error
+}
+
+// batching buffers entries and forwards them to the wrapped sink in batches
+type batching struct {
+	inner    Sink
+	size     int
+	interval time.Duration
+
+	mu    sync.Mutex
+	buf   []models.LogEntry
+	timer *time.Timer
+	err   error
+}
+
+// NewBatching wraps inner so entries are forwarded once size entries are
+// buffered or interval has passed since the oldest buffered entry, whichever
+// comes first. A zero size or interval disables that trigger. Sinks
+// implementing BatchWriter receive each batch in a single call. Errors from
+// flushes triggered by the interval are returned by the next Write or Close.
+func NewBatching(inner Sink, size int, interval time.Duration) Sink {
+	return &batching{inner: inner, size: size, interval: interval}
+}
+
+func (b *batching) Write(entry models.LogEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.err; err != nil {
+		b.err = nil
+		return err
+	}
+	b.buf = append(b.buf, entry)
+	if b.size > 0 && len(b.buf) >= b.size {
+		return b.flush()
+	}
+	if b.interval > 0 && b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, b.flushPending)
+	}
+	return nil
+}
+
+// flushPending flushes the buffer when the interval has passed
+func (b *batching) flushPending() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timer = nil
+	if err := b.flush(); err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+// flush forwards the buffered entries. Callers hold mu.
+func (b *batching) flush() error {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.buf) == 0 {
+		return nil
+	}
+	batch := b.buf
+	b.buf = nil
+
+	if bw, ok := b.inner.(BatchWriter); ok {
+		return bw.WriteBatch(batch)
+	}
+	var firstErr error
+	for _, entry := range batch {
+		if err := b.inner.Write(entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Flush forwards the buffered entries and flushes the wrapped sink. It also
+// returns the error of an earlier flush triggered by the interval.
+func (b *batching) Flush() error {
+	b.mu.Lock()
+	err := b.flush()
+	if err == nil {
+		err = b.err
+	}
+	b.err = nil
+	b.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	return Flush(b.inner)
+}
+
+// Close flushes the remaining entries and closes the wrapped sink
+func (b *batching) Close() error {
+	b.mu.Lock()
+	err := b.flush()
+	if err == nil {
+		err = b.err
+	}
+	b.err = nil
+	b.mu.Unlock()
+
+	if closeErr := b.inner.Close(); closeErr != nil {
+		return closeErr
+	}
+	return err
+}
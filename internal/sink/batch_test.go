@@ -0,0 +1,112 @@
+package sink
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// batchRecorder keeps the size of every batch written to it
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches []int
+}
+
+func (r *batchRecorder) Write(entry models.LogEntry) error {
+	return r.WriteBatch([]models.LogEntry{entry})
+}
+
+func (r *batchRecorder) WriteBatch(entries []models.LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, len(entries))
+	return nil
+}
+
+func (r *batchRecorder) Close() error { return nil }
+
+func (r *batchRecorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.batches...)
+}
+
+func TestBatchingSinkSize(t *testing.T) {
+	inner := &batchRecorder{}
+	s := NewBatching(inner, 3, 0)
+	for i := 0; i < 7; i++ {
+		if err := s.Write(models.LogEntry{Message: "entry"}); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	if got := inner.sizes(); len(got) != 2 || got[0] != 3 || got[1] != 3 {
+		t.Errorf("Expected two batches of 3 before closing, got %v", got)
+	}
+
+	// The remainder is flushed on shutdown
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	if got := inner.sizes(); len(got) != 3 || got[2] != 1 {
+		t.Errorf("Expected a final batch of 1, got %v", got)
+	}
+}
+
+func TestBatchingSinkInterval(t *testing.T) {
+	inner := &batchRecorder{}
+	s := NewBatching(inner, 100, 20*time.Millisecond)
+	s.Write(models.LogEntry{Message: "first"})
+	s.Write(models.LogEntry{Message: "second"})
+
+	deadline := time.Now().Add(time.Second)
+	for len(inner.sizes()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := inner.sizes(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("Expected one batch of 2 after the interval, got %v", got)
+	}
+	s.Close()
+}
+
+func TestOpenBatchOptions(t *testing.T) {
+	if _, err := Open("gelf+udp://127.0.0.1:12201?batch_size=abc"); err == nil {
+		t.Error("Expected an invalid batch_size to be rejected")
+	}
+
+	s, err := Open("gelf+udp://127.0.0.1:12201?batch_interval=1s")
+	if err != nil {
+		t.Fatalf("Failed to open sink: %v", err)
+	}
+	defer s.Close()
+	b, ok := s.(*batching)
+	if !ok {
+		t.Fatalf("Expected a batching sink, got %T", s)
+	}
+	if b.size != DefaultBatchSize || b.interval != time.Second {
+		t.Errorf("Expected size %d and interval 1s, got %d and %v", DefaultBatchSize, b.size, b.interval)
+	}
+}
+
+func TestBatchingSinkFlush(t *testing.T) {
+	inner := &batchRecorder{}
+	s := NewBatching(inner, 100, 0)
+	s.Write(models.LogEntry{Message: "first"})
+	s.Write(models.LogEntry{Message: "second"})
+
+	// Flushing confirms the buffered entries without closing the sink
+	if err := Flush(s); err != nil {
+		t.Fatalf("Failed to flush sink: %v", err)
+	}
+	if got := inner.sizes(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("Expected one batch of 2 after flushing, got %v", got)
+	}
+	s.Write(models.LogEntry{Message: "third"})
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+	if got := inner.sizes(); len(got) != 2 {
+		t.Errorf("Expected the sink to keep working after a flush, got %v", got)
+	}
+}
@@ -0,0 +1,335 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/gcp"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const bigQueryEndpoint = "https://bigquery.googleapis.com"
+
+// bigQuerySchema describes the table entries are loaded into
+var bigQuerySchema = []map[string]string{
+	{"name": "id", "type": "STRING"},
+	{"name": "timestamp", "type": "TIMESTAMP"},
+	{"name": "level", "type": "STRING", "mode": "REQUIRED"},
+	{"name": "service", "type": "STRING"},
+	{"name": "message", "type": "STRING"},
+	{"name": "source", "type": "STRING"},
+	{"name": "fields", "type": "JSON"},
+	{"name": "level_inferred", "type": "BOOL"},
+}
+
+// bigQueryRow is an entry as loaded into the table
+type bigQueryRow struct {
+	ID            string            `json:"id,omitempty"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Level         string            `json:"level"`
+	Service       string            `json:"service"`
+	Message       string            `json:"message"`
+	Source        string            `json:"source"`
+	Fields        map[string]string `json:"fields,omitempty"`
+	LevelInferred bool              `json:"level_inferred"`
+}
+
+// bigQueryJob is the part of a job resource in use
+type bigQueryJob struct {
+	Status struct {
+		State       string `json:"state"`
+		ErrorResult *struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errorResult"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"status"`
+}
+
+// BigQuerySink loads entries into a BigQuery table with load jobs, one per
+// batch. The dataset and table are created on first use if missing, the
+// table partitioned by the entry timestamp.
+type BigQuerySink struct {
+	project   string
+	dataset   string
+	table     string
+	partition string
+	location  string
+	tokens    *gcp.TokenSource
+	endpoint  string
+	client    *http.Client
+	// pollInterval is how often a running load job is checked
+	pollInterval time.Duration
+	// jobTimeout bounds how long a load job may take
+	jobTimeout time.Duration
+
+	mu      sync.Mutex
+	created bool
+}
+
+// NewBigQuerySink creates a sink loading into project.dataset.table, the
+// dataset being created in location if missing. partition is the time
+// partitioning type, e.g. "DAY", or empty for an unpartitioned table. A
+// nil tokens sends unauthenticated requests, e.g. to an emulator.
+func NewBigQuerySink(project, dataset, table, partition, location string, tokens *gcp.TokenSource) *BigQuerySink {
+	return &BigQuerySink{
+		project:      project,
+		dataset:      dataset,
+		table:        table,
+		partition:    partition,
+		location:     location,
+		tokens:       tokens,
+		endpoint:     bigQueryEndpoint,
+		client:       &http.Client{Timeout: 5 * time.Minute},
+		pollInterval: time.Second,
+		jobTimeout:   10 * time.Minute,
+	}
+}
+
+// WithEndpoint overrides the API endpoint, e.g. for an emulator
+func (b *BigQuerySink) WithEndpoint(endpoint string) *BigQuerySink {
+	b.endpoint = strings.TrimSuffix(endpoint, "/")
+	return b
+}
+
+// Write loads one entry; use batching, as BigQuery limits the number of
+// load jobs per table and day
+func (b *BigQuerySink) Write(entry models.LogEntry) error {
+	return b.WriteBatch([]models.LogEntry{entry})
+}
+
+// WriteBatch loads entries with one load job and waits for it to finish
+func (b *BigQuerySink) WriteBatch(entries []models.LogEntry) error {
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	for _, entry := range entries {
+		row := bigQueryRow{
+			ID:            entry.ID,
+			Level:         string(entry.Level),
+			Service:       entry.Service,
+			Message:       entry.Message,
+			Source:        entry.Source,
+			Fields:        entry.Fields,
+			LevelInferred: entry.LevelInferred,
+		}
+		if !entry.Timestamp.IsZero() {
+			row.Timestamp = entry.Timestamp.UTC().Format(time.RFC3339Nano)
+		}
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), b.jobTimeout)
+	defer cancel()
+	if !b.created {
+		if err := b.createTable(ctx); err != nil {
+			return err
+		}
+		b.created = true
+	}
+	if err := b.load(ctx, data.Bytes()); err != nil {
+		return fmt.Errorf("failed to load entries into %s.%s.%s: %w", b.project, b.dataset, b.table, err)
+	}
+	return nil
+}
+
+// createTable creates the dataset and table unless they exist
+func (b *BigQuerySink) createTable(ctx context.Context) error {
+	dataset := map[string]interface{}{
+		"datasetReference": map[string]string{"projectId": b.project, "datasetId": b.dataset},
+	}
+	if b.location != "" {
+		dataset["location"] = b.location
+	}
+	status, body, err := b.call(ctx, http.MethodPost, b.apiURL("datasets"), "application/json", mustJSON(dataset))
+	if err != nil || (status != http.StatusOK && status != http.StatusConflict) {
+		return fmt.Errorf("failed to create dataset %s: %w", b.dataset, apiError(status, body, err))
+	}
+
+	table := map[string]interface{}{
+		"tableReference": b.tableReference(),
+		"schema":         map[string]interface{}{"fields": bigQuerySchema},
+	}
+	if b.partition != "" {
+		table["timePartitioning"] = map[string]string{"type": b.partition, "field": "timestamp"}
+	}
+	status, body, err = b.call(ctx, http.MethodPost, b.apiURL("datasets", b.dataset, "tables"), "application/json", mustJSON(table))
+	if err != nil || (status != http.StatusOK && status != http.StatusConflict) {
+		return fmt.Errorf("failed to create table %s.%s: %w", b.dataset, b.table, apiError(status, body, err))
+	}
+	return nil
+}
+
+// load runs a load job for NDJSON rows and waits for it to finish. The job
+// ID is chosen here, so an upload retried after a failure cannot load the
+// rows twice.
+func (b *BigQuerySink) load(ctx context.Context, rows []byte) error {
+	id := make([]byte, 12)
+	rand.Read(id)
+	jobID := "logprocessor_" + hex.EncodeToString(id)
+	jobRef := map[string]string{"projectId": b.project, "jobId": jobID}
+	if b.location != "" {
+		jobRef["location"] = b.location
+	}
+	config := map[string]interface{}{
+		"jobReference": jobRef,
+		"configuration": map[string]interface{}{
+			"load": map[string]interface{}{
+				"destinationTable":  b.tableReference(),
+				"sourceFormat":      "NEWLINE_DELIMITED_JSON",
+				"writeDisposition":  "WRITE_APPEND",
+				"createDisposition": "CREATE_NEVER",
+			},
+		},
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	part.Write(mustJSON(config))
+	part, _ = mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}})
+	part.Write(rows)
+	mw.Close()
+
+	uploadURL := b.endpoint + "/upload/bigquery/v2/projects/" + url.PathEscape(b.project) + "/jobs?uploadType=multipart"
+	var job bigQueryJob
+	for attempt := 1; ; attempt++ {
+		status, resp, err := b.call(ctx, http.MethodPost, uploadURL, "multipart/related; boundary="+mw.Boundary(), body.Bytes())
+		if err == nil && status == http.StatusOK {
+			if err := json.Unmarshal(resp, &job); err != nil {
+				return fmt.Errorf("failed to parse job: %w", err)
+			}
+			break
+		}
+		if err == nil && status == http.StatusConflict {
+			// An earlier attempt created the job
+			break
+		}
+		if attempt == 3 || (err == nil && status < 500) {
+			return apiError(status, resp, err)
+		}
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+
+	jobURL := b.apiURL("jobs", jobID)
+	if b.location != "" {
+		jobURL += "?location=" + url.QueryEscape(b.location)
+	}
+	for job.Status.State != "DONE" {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("job %s did not finish: %w", jobID, ctx.Err())
+		case <-time.After(b.pollInterval):
+		}
+		status, resp, err := b.call(ctx, http.MethodGet, jobURL, "", nil)
+		if err != nil || status != http.StatusOK {
+			if err == nil && status < 500 {
+				return fmt.Errorf("failed to check job %s: %w", jobID, apiError(status, resp, err))
+			}
+			// Transient; check again
+			continue
+		}
+		if err := json.Unmarshal(resp, &job); err != nil {
+			return fmt.Errorf("failed to parse job %s: %w", jobID, err)
+		}
+	}
+
+	if e := job.Status.ErrorResult; e != nil {
+		msg := e.Message
+		for i, detail := range job.Status.Errors {
+			if i == 3 {
+				break
+			}
+			if detail.Message != msg {
+				msg += "; " + detail.Message
+			}
+		}
+		return fmt.Errorf("job %s failed: %s: %s", jobID, e.Reason, msg)
+	}
+	return nil
+}
+
+func (b *BigQuerySink) tableReference() map[string]string {
+	return map[string]string{"projectId": b.project, "datasetId": b.dataset, "tableId": b.table}
+}
+
+// apiURL builds the URL of a resource of the project
+func (b *BigQuerySink) apiURL(path ...string) string {
+	for i, p := range path {
+		path[i] = url.PathEscape(p)
+	}
+	return b.endpoint + "/bigquery/v2/projects/" + url.PathEscape(b.project) + "/" + strings.Join(path, "/")
+}
+
+// call sends an authenticated request and returns the response status and
+// body
+func (b *BigQuerySink) call(ctx context.Context, method, rawURL, contentType string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if b.tokens != nil {
+		token, err := b.tokens.Token(ctx)
+		if err != nil {
+			return 0, nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, data, err
+}
+
+// Close does nothing; every batch is loaded by the time WriteBatch returns
+func (b *BigQuerySink) Close() error {
+	return nil
+}
+
+// apiError describes a failed request, preferring the message of the
+// API's error response
+func apiError(status int, body []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Error.Message != "" {
+		return fmt.Errorf("%s: %s", http.StatusText(status), resp.Error.Message)
+	}
+	return fmt.Errorf("%s: %s", http.StatusText(status), strings.TrimSpace(string(body)))
+}
+
+func mustJSON(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
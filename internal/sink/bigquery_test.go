@@ -0,0 +1,79 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestBigQuerySink(t *testing.T) {
+	var table map[string]interface{}
+	var rows []string
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/bigquery/v2/projects/proj/datasets":
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error":{"message":"Already Exists"}}`)
+		case r.URL.Path == "/bigquery/v2/projects/proj/datasets/logs/tables":
+			json.NewDecoder(r.Body).Decode(&table)
+			fmt.Fprint(w, `{}`)
+		case r.URL.Path == "/upload/bigquery/v2/projects/proj/jobs":
+			_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			mr := multipart.NewReader(r.Body, params["boundary"])
+			mr.NextPart()
+			part, _ := mr.NextPart()
+			data, _ := io.ReadAll(part)
+			rows = strings.Split(strings.TrimSpace(string(data)), "\n")
+			fmt.Fprint(w, `{"status":{"state":"RUNNING"}}`)
+		case strings.HasPrefix(r.URL.Path, "/bigquery/v2/projects/proj/jobs/logprocessor_"):
+			polls++
+			if polls == 1 {
+				fmt.Fprint(w, `{"status":{"state":"DONE"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"status":{"state":"DONE","errorResult":{"reason":"invalid","message":"bad row"}}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	if _, err := Open("bigquery://proj/logs?endpoint=" + server.URL); err == nil {
+		t.Error("Expected a BigQuery sink without a table to be rejected")
+	}
+	sink := NewBigQuerySink("proj", "logs", "entries", "DAY", "", nil).WithEndpoint(server.URL)
+	sink.pollInterval = time.Millisecond
+	entries := []models.LogEntry{
+		{ID: "1", Timestamp: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), Level: models.ERROR, Service: "api", Message: "failed", Fields: map[string]string{"user": "bob"}},
+		{Level: models.INFO, Service: "api", Message: "ok"},
+	}
+	if err := sink.WriteBatch(entries); err != nil {
+		t.Fatalf("Failed to load entries: %v", err)
+	}
+
+	partitioning, _ := table["timePartitioning"].(map[string]interface{})
+	if partitioning["type"] != "DAY" || partitioning["field"] != "timestamp" {
+		t.Errorf("Expected the table to be partitioned by day of timestamp, got %v", table["timePartitioning"])
+	}
+	want := []string{
+		`{"id":"1","timestamp":"2023-01-01T10:00:00Z","level":"ERROR","service":"api","message":"failed","source":"","fields":{"user":"bob"},"level_inferred":false}`,
+		`{"level":"INFO","service":"api","message":"ok","source":"","level_inferred":false}`,
+	}
+	if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected rows %q, got %q", want, rows)
+	}
+
+	if err := sink.Write(entries[0]); err == nil || !strings.Contains(err.Error(), "bad row") {
+		t.Errorf("Expected the failed job to be reported, got %v", err)
+	}
+}
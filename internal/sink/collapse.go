@@ -0,0 +1,152 @@
+package sink
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// repeatKey identifies entries considered repeats of each other
+type repeatKey struct {
+	service string
+	pattern string
+}
+
+// repeatRun tracks a message repeated within the window
+type repeatRun struct {
+	first    models.LogEntry
+	last     time.Time
+	repeated int
+}
+
+// collapsing forwards the first of a run of repeated messages and replaces
+// the rest with a single "last message repeated" entry
+type collapsing struct {
+	inner  Sink
+	window time.Duration
+	form   textnorm.Form
+
+	mu        sync.Mutex
+	runs      map[repeatKey]*repeatRun
+	latest    time.Time
+	nextSweep time.Time
+}
+
+// NewCollapsing wraps inner so a message repeated by the same service within
+// window of its first occurrence is forwarded once, followed by an entry
+// counting the repeats, like syslog's "last message repeated N times".
+// Messages are compared after normalizing numbers and IDs, and with form.
+func NewCollapsing(inner Sink, window time.Duration, form textnorm.Form) Sink {
+	return &collapsing{inner: inner, window: window, form: form, runs: make(map[repeatKey]*repeatRun)}
+}
+
+func (c *collapsing) Write(entry models.LogEntry) error {
+	ts := entry.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	key := repeatKey{service: entry.Service, pattern: analyzer.NormalizedPattern(entry.Message, c.form)}
+
+	c.mu.Lock()
+	if ts.After(c.latest) {
+		c.latest = ts
+	}
+	var expired []*repeatRun
+	if c.latest.After(c.nextSweep) {
+		expired = c.sweep(c.latest)
+		c.nextSweep = c.latest.Add(time.Second)
+	}
+
+	forward := false
+	run, ok := c.runs[key]
+	if ok && ts.Sub(run.first.Timestamp) > c.window {
+		// The window has passed: report the finished run and start a new one
+		delete(c.runs, key)
+		expired = append(expired, run)
+		ok = false
+	}
+	if ok {
+		run.repeated++
+		if ts.After(run.last) {
+			run.last = ts
+		}
+	} else {
+		first := entry
+		first.Timestamp = ts
+		c.runs[key] = &repeatRun{first: first, last: ts}
+		forward = true
+	}
+	c.mu.Unlock()
+
+	if err := c.writeRepeats(expired); err != nil {
+		return err
+	}
+	if forward {
+		return c.inner.Write(entry)
+	}
+	return nil
+}
+
+// sweep removes the runs whose window ended before now. Callers hold mu.
+func (c *collapsing) sweep(now time.Time) []*repeatRun {
+	var expired []*repeatRun
+	for key, run := range c.runs {
+		if now.Sub(run.first.Timestamp) > c.window {
+			delete(c.runs, key)
+			expired = append(expired, run)
+		}
+	}
+	return expired
+}
+
+// writeRepeats forwards a repeat counter for each run with suppressed entries
+func (c *collapsing) writeRepeats(runs []*repeatRun) error {
+	var firstErr error
+	for _, run := range runs {
+		if run.repeated == 0 {
+			continue
+		}
+		entry := run.first
+		entry.ID += ":repeated"
+		entry.Timestamp = run.last
+		entry.Message = fmt.Sprintf("last message repeated %d times", run.repeated)
+		entry.Fields = make(map[string]string, len(run.first.Fields)+2)
+		for k, v := range run.first.Fields {
+			entry.Fields[k] = v
+		}
+		entry.Fields["repeat_count"] = strconv.Itoa(run.repeated)
+		entry.Fields["repeated_message"] = run.first.Message
+		if err := c.inner.Write(entry); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Flush flushes the wrapped sink. Pending repeats are not reported early:
+// they only count entries whose first occurrence was already forwarded.
+func (c *collapsing) Flush() error {
+	return Flush(c.inner)
+}
+
+// Close reports all pending repeats and closes the wrapped sink
+func (c *collapsing) Close() error {
+	c.mu.Lock()
+	runs := make([]*repeatRun, 0, len(c.runs))
+	for _, run := range c.runs {
+		runs = append(runs, run)
+	}
+	c.runs = make(map[repeatKey]*repeatRun)
+	c.mu.Unlock()
+
+	err := c.writeRepeats(runs)
+	if closeErr := c.inner.Close(); closeErr != nil {
+		return closeErr
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package sink
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/textnorm"
+)
+
+// recordingSink keeps every entry written to it
+type recordingSink struct {
+	entries []models.LogEntry
+	closed  bool
+}
+
+func (r *recordingSink) Write(entry models.LogEntry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *recordingSink) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestCollapsingSink(t *testing.T) {
+	inner := &recordingSink{}
+	s := NewCollapsing(inner, time.Minute, 0)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	write := func(offset time.Duration, service, message string) {
+		t.Helper()
+		err := s.Write(models.LogEntry{ID: fmt.Sprint(offset), Timestamp: start.Add(offset), Service: service, Message: message})
+		if err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+
+	// A storm of retries differing only in the attempt number
+	for i := 0; i < 50; i++ {
+		write(time.Duration(i)*time.Second/2, "api", fmt.Sprintf("retry %d failed", i))
+	}
+	write(10*time.Second, "db", "retry 1 failed")
+	// After the window the next repeat starts a new run
+	write(2*time.Minute, "api", "retry 51 failed")
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	if !inner.closed {
+		t.Error("Expected the wrapped sink to be closed")
+	}
+	if len(inner.entries) != 4 {
+		t.Fatalf("Expected 4 forwarded entries, got %d: %v", len(inner.entries), inner.entries)
+	}
+	if inner.entries[0].Message != "retry 0 failed" || inner.entries[1].Service != "db" {
+		t.Errorf("Expected first occurrences to be forwarded, got %v", inner.entries[:2])
+	}
+
+	repeat := inner.entries[2]
+	if repeat.Message != "last message repeated 49 times" || repeat.Fields["repeat_count"] != "49" {
+		t.Errorf("Unexpected repeat entry: %+v", repeat)
+	}
+	if !repeat.Timestamp.Equal(start.Add(49 * time.Second / 2)) {
+		t.Errorf("Expected the repeat entry at the last occurrence, got %v", repeat.Timestamp)
+	}
+	if inner.entries[3].Message != "retry 51 failed" {
+		t.Errorf("Expected a new run after the window, got %+v", inner.entries[3])
+	}
+}
+
+func TestCollapsingNormalization(t *testing.T) {
+	inner := &recordingSink{}
+	s := NewCollapsing(inner, time.Minute, textnorm.NFC|textnorm.Fold)
+	start := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// The same message written with a decomposed accent and in upper case
+	for i, message := range []string{"Café order 1 failed", "Café order 2 failed", "CAFÉ ORDER 3 FAILED"} {
+		err := s.Write(models.LogEntry{ID: fmt.Sprint(i), Timestamp: start.Add(time.Duration(i) * time.Second), Service: "api", Message: message})
+		if err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	if len(inner.entries) != 2 || inner.entries[1].Fields["repeat_count"] != "2" {
+		t.Errorf("Expected one message and 2 repeats, got %v", inner.entries)
+	}
+}
@@ -0,0 +1,17 @@
+package sink
+
+// Flusher is implemented by sinks that hold on to entries before sending
+// them. Flush returns once every entry written so far has been handed to the
+// destination, or with the error that kept it from getting there.
+type Flusher interface {
+	Flush() error
+}
+
+// Flush flushes s if it buffers entries. Sinks that send each entry in Write
+// have nothing to flush.
+func Flush(s Sink) error {
+	if f, ok := s.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+const (
+	// gelfChunkSize keeps chunked datagrams below typical LAN MTUs
+	gelfChunkSize = 8192
+	// gelfMaxChunks is the protocol limit on chunks per message
+	gelfMaxChunks = 128
+)
+
+// GELFSink forwards entries to Graylog or any other GELF receiver
+type GELFSink struct {
+	network string
+	host    string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewGELFSink connects to a GELF receiver over "udp" or "tcp"
+func NewGELFSink(network, addr string) (*GELFSink, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to GELF receiver %s: %w", addr, err)
+	}
+	host, _ := os.Hostname()
+	return &GELFSink{
+		network: network,
+		host:    host,
+		conn:    conn,
+	}, nil
+}
+
+// Write sends one entry as a GELF message
+func (g *GELFSink) Write(entry models.LogEntry) error {
+	payload, err := encodeGELF(entry, g.host)
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.network == "tcp" {
+		// TCP framing is a null byte after each uncompressed message
+		_, err = g.conn.Write(append(payload, 0))
+		return err
+	}
+	return g.writeUDP(payload)
+}
+
+// WriteBatch sends several entries, using a single write over TCP
+func (g *GELFSink) WriteBatch(entries []models.LogEntry) error {
+	var buf bytes.Buffer
+	payloads := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		payload, err := encodeGELF(entry, g.host)
+		if err != nil {
+			return err
+		}
+		if g.network == "tcp" {
+			buf.Write(payload)
+			buf.WriteByte(0)
+		} else {
+			payloads = append(payloads, payload)
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.network == "tcp" {
+		_, err := g.conn.Write(buf.Bytes())
+		return err
+	}
+	for _, payload := range payloads {
+		if err := g.writeUDP(payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeUDP compresses large messages and splits them into chunks
+func (g *GELFSink) writeUDP(payload []byte) error {
+	if len(payload) <= gelfChunkSize {
+		_, err := g.conn.Write(payload)
+		return err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(payload)
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	payload = buf.Bytes()
+	if len(payload) <= gelfChunkSize {
+		_, err := g.conn.Write(payload)
+		return err
+	}
+
+	count := (len(payload) + gelfChunkSize - 1) / gelfChunkSize
+	if count > gelfMaxChunks {
+		return fmt.Errorf("GELF message of %d bytes is too large to send over UDP", len(payload))
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		end := (i + 1) * gelfChunkSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunk := make([]byte, 0, 12+end-i*gelfChunkSize)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, payload[i*gelfChunkSize:end]...)
+		if _, err := g.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the receiver
+func (g *GELFSink) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.conn.Close()
+}
+
+// encodeGELF renders an entry as a GELF 1.1 message
+func encodeGELF(entry models.LogEntry, defaultHost string) ([]byte, error) {
+	ts := entry.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	host := entry.Fields["host"]
+	if host == "" {
+		host = defaultHost
+	}
+
+	msg := map[string]interface{}{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": entry.Message,
+		"timestamp":     float64(ts.UnixMicro()) / 1e6,
+		"level":         syslogSeverity(entry.Level),
+		"_service":      entry.Service,
+		"_source":       entry.Source,
+		"_log_id":       entry.ID,
+	}
+	for k, v := range entry.Fields {
+		switch k {
+		case "host":
+		case "full_message":
+			msg["full_message"] = v
+		default:
+			// "_id" is reserved by the GELF specification
+			if k == "id" {
+				k = "field_id"
+			}
+			msg["_"+k] = v
+		}
+	}
+
+	return json.Marshal(msg)
+}
+
+// syslogSeverity maps a LogLevel onto the syslog severity GELF uses
+func syslogSeverity(level models.LogLevel) int {
+	switch level {
+	case models.FATAL:
+		return 2
+	case models.ERROR:
+		return 3
+	case models.WARNING:
+		return 4
+	case models.DEBUG:
+		return 7
+	default:
+		return 6
+	}
+}
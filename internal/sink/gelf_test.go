@@ -0,0 +1,101 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestGELFSinkUDP(t *testing.T) {
+	receiver, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer receiver.Close()
+
+	sink, err := Open("gelf+udp://" + receiver.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to open sink: %v", err)
+	}
+	defer sink.Close()
+
+	entry := models.LogEntry{
+		ID:        "1",
+		Timestamp: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC),
+		Level:     models.ERROR,
+		Service:   "api",
+		Message:   "Connection refused",
+		Source:    "logs1.json",
+		Fields:    map[string]string{"host": "web-1", "status": "502"},
+	}
+	if err := sink.Write(entry); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+
+	buf := make([]byte, 65536)
+	receiver.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := receiver.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to receive datagram: %v", err)
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(buf[:n], &msg); err != nil {
+		t.Fatalf("Invalid GELF JSON: %v", err)
+	}
+	expected := map[string]interface{}{
+		"version":       "1.1",
+		"host":          "web-1",
+		"short_message": "Connection refused",
+		"timestamp":     float64(1672567200),
+		"level":         float64(3),
+		"_service":      "api",
+		"_status":       "502",
+	}
+	for k, v := range expected {
+		if msg[k] != v {
+			t.Errorf("Expected %s to be %v, got %v", k, v, msg[k])
+		}
+	}
+
+	// Large messages are compressed and chunked
+	var large strings.Builder
+	for i := 0; large.Len() < 3*gelfChunkSize*8; i++ {
+		large.WriteString(time.Duration(i * 7919).String())
+	}
+	entry.Fields = map[string]string{"dump": large.String()}
+	if err := sink.Write(entry); err != nil {
+		t.Fatalf("Failed to write large entry: %v", err)
+	}
+
+	var compressed []byte
+	for seq := 0; ; seq++ {
+		n, _, err := receiver.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Failed to receive chunk: %v", err)
+		}
+		if !bytes.HasPrefix(buf[:n], []byte{0x1e, 0x0f}) || int(buf[10]) != seq {
+			t.Fatalf("Unexpected chunk header % x", buf[:12])
+		}
+		compressed = append(compressed, buf[12:n]...)
+		if seq == int(buf[11])-1 {
+			break
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Expected gzip payload: %v", err)
+	}
+	data, _ := io.ReadAll(gz)
+	if err := json.Unmarshal(data, &msg); err != nil || msg["_dump"] != large.String() {
+		t.Errorf("Reassembled message does not match the entry")
+	}
+}
@@ -0,0 +1,48 @@
+package sink
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// PathTemplate names output files after the entries written to them, e.g.
+// {{.Service}}/{{.Timestamp.Format "2006-01-02"}}.ndjson. The template is
+// executed on each entry, with its timestamp in UTC; missing fields, as in
+// {{.Fields.region}}, are empty.
+type PathTemplate struct {
+	tmpl *template.Template
+}
+
+// ParsePathTemplate parses text, checking it against an example entry
+func ParsePathTemplate(text string) (*PathTemplate, error) {
+	tmpl, err := template.New("path").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path template: %w", err)
+	}
+	p := &PathTemplate{tmpl: tmpl}
+	example := models.LogEntry{Timestamp: time.Now(), Level: models.INFO, Service: "service", Source: "app.log"}
+	if _, err := p.Path(example); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Path returns the path of entry's file, relative to the output directory.
+// Values such as "../x" cannot make it leave the directory.
+func (p *PathTemplate) Path(entry models.LogEntry) (string, error) {
+	entry.Timestamp = entry.Timestamp.UTC()
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, entry); err != nil {
+		return "", fmt.Errorf("invalid path template: %w", err)
+	}
+	name := filepath.Clean("/" + filepath.FromSlash(b.String()))[1:]
+	if name == "" {
+		return "", fmt.Errorf("path template %q gives an empty path", p.tmpl.Root.String())
+	}
+	return name, nil
+}
@@ -0,0 +1,260 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/postgres"
+)
+
+// Partitioning periods of a Postgres table
+const (
+	PartitionNone  = ""
+	PartitionDay   = "day"
+	PartitionMonth = "month"
+)
+
+// validTable matches table names, optionally qualified by a schema
+var validTable = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// postgresColumns are the columns entries are loaded into, in COPY order
+const postgresColumns = "id, ts, level, service, message, source, fields, level_inferred"
+
+// PostgresSink loads entries into a PostgreSQL table with COPY. The table
+// is created if missing, optionally partitioned by day or month of the
+// entry timestamp, with partitions created as entries for them arrive.
+// With upsert, an entry with the ID and timestamp of a stored row replaces
+// it instead of adding a row.
+type PostgresSink struct {
+	table     string
+	partition string
+	upsert    bool
+
+	mu   sync.Mutex
+	conn *postgres.Conn
+	// partitions holds the partitions known to exist
+	partitions map[string]bool
+}
+
+// NewPostgresSink connects to the server at rawURL, e.g.
+// "postgres://user:pass@db:5432/logs", and prepares table
+func NewPostgresSink(rawURL, table, partition string, upsert bool) (*PostgresSink, error) {
+	if !validTable.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	if partition != PartitionNone && partition != PartitionDay && partition != PartitionMonth {
+		return nil, fmt.Errorf("invalid partition %q (expected day or month)", partition)
+	}
+	conn, err := postgres.Dial(context.Background(), rawURL)
+	if err != nil {
+		return nil, err
+	}
+	p := &PostgresSink{table: table, partition: partition, upsert: upsert, conn: conn, partitions: make(map[string]bool)}
+	if err := p.createTable(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// createTable creates the table, its default partition and, for upserts,
+// the session's staging table
+func (p *PostgresSink) createTable() error {
+	table := quoteTable(p.table)
+	var sql strings.Builder
+	fmt.Fprintf(&sql, `CREATE TABLE IF NOT EXISTS %s (
+	id text,
+	ts timestamptz,
+	level text NOT NULL,
+	service text NOT NULL,
+	message text NOT NULL,
+	source text NOT NULL,
+	fields jsonb,
+	level_inferred boolean NOT NULL DEFAULT false`, table)
+	if p.upsert {
+		// Unique constraints of partitioned tables must include the
+		// partition key. Rows without an ID never conflict.
+		sql.WriteString(",\n\tUNIQUE (id, ts)")
+	}
+	sql.WriteString("\n)")
+	if p.partition != PartitionNone {
+		sql.WriteString(" PARTITION BY RANGE (ts)")
+		// Entries without a timestamp, or of a partition that could not be
+		// created, land in the default partition
+		fmt.Fprintf(&sql, ";\nCREATE TABLE IF NOT EXISTS %s PARTITION OF %s DEFAULT", quoteTable(p.table+"_default"), table)
+	}
+	if p.upsert {
+		fmt.Fprintf(&sql, ";\nCREATE TEMP TABLE IF NOT EXISTS logprocessor_staging (LIKE %s INCLUDING DEFAULTS) ON COMMIT DELETE ROWS", table)
+	}
+	if _, err := p.conn.Exec(sql.String()); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", p.table, err)
+	}
+	return nil
+}
+
+// Write loads one entry; use batching to load many at once
+func (p *PostgresSink) Write(entry models.LogEntry) error {
+	return p.WriteBatch([]models.LogEntry{entry})
+}
+
+// WriteBatch loads several entries with a single COPY
+func (p *PostgresSink) WriteBatch(entries []models.LogEntry) error {
+	var data strings.Builder
+	for _, entry := range entries {
+		if err := writeCopyRow(&data, entry); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.createPartitions(entries); err != nil {
+		return err
+	}
+	if !p.upsert {
+		if _, err := p.conn.CopyIn(fmt.Sprintf("COPY %s (%s) FROM STDIN", quoteTable(p.table), postgresColumns), []byte(data.String())); err != nil {
+			return fmt.Errorf("failed to load entries into %s: %w", p.table, err)
+		}
+		return nil
+	}
+
+	if _, err := p.conn.Exec("BEGIN"); err != nil {
+		return fmt.Errorf("failed to load entries into %s: %w", p.table, err)
+	}
+	if err := p.upsertRows(data.String()); err != nil {
+		p.conn.Exec("ROLLBACK")
+		return fmt.Errorf("failed to load entries into %s: %w", p.table, err)
+	}
+	return nil
+}
+
+// upsertRows copies rows into the staging table and merges them into the
+// table, in the transaction begun by the caller
+func (p *PostgresSink) upsertRows(data string) error {
+	if _, err := p.conn.CopyIn(fmt.Sprintf("COPY logprocessor_staging (%s) FROM STDIN", postgresColumns), []byte(data)); err != nil {
+		return err
+	}
+	table := quoteTable(p.table)
+	// The last of several rows with the same key in one batch wins
+	_, err := p.conn.Exec(fmt.Sprintf(`INSERT INTO %[1]s (%[2]s)
+	SELECT DISTINCT ON (id, ts) %[2]s FROM logprocessor_staging WHERE id IS NOT NULL ORDER BY id, ts, ctid DESC
+	ON CONFLICT (id, ts) DO UPDATE SET level = EXCLUDED.level, service = EXCLUDED.service,
+		message = EXCLUDED.message, source = EXCLUDED.source, fields = EXCLUDED.fields,
+		level_inferred = EXCLUDED.level_inferred;
+INSERT INTO %[1]s (%[2]s) SELECT %[2]s FROM logprocessor_staging WHERE id IS NULL;
+COMMIT`, table, postgresColumns))
+	return err
+}
+
+// createPartitions creates the partitions entries fall into, if missing
+func (p *PostgresSink) createPartitions(entries []models.LogEntry) error {
+	if p.partition == PartitionNone {
+		return nil
+	}
+	needed := make(map[string]time.Time)
+	for _, entry := range entries {
+		if entry.Timestamp.IsZero() {
+			continue
+		}
+		ts := entry.Timestamp.UTC()
+		start := time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
+		if p.partition == PartitionMonth {
+			start = time.Date(ts.Year(), ts.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+		if name := p.partitionName(start); !p.partitions[name] {
+			needed[name] = start
+		}
+	}
+	names := make([]string, 0, len(needed))
+	for name := range needed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		start := needed[name]
+		end := start.AddDate(0, 0, 1)
+		if p.partition == PartitionMonth {
+			end = start.AddDate(0, 1, 0)
+		}
+		sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)",
+			quoteTable(name), quoteTable(p.table),
+			postgres.QuoteLiteral(start.Format(time.RFC3339)), postgres.QuoteLiteral(end.Format(time.RFC3339)))
+		if _, err := p.conn.Exec(sql); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", name, err)
+		}
+		p.partitions[name] = true
+	}
+	return nil
+}
+
+// partitionName names the partition starting at start, e.g. logs_20230101
+func (p *PostgresSink) partitionName(start time.Time) string {
+	if p.partition == PartitionMonth {
+		return p.table + "_" + start.Format("200601")
+	}
+	return p.table + "_" + start.Format("20060102")
+}
+
+// Close closes the connection to the server
+func (p *PostgresSink) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.conn.Close()
+}
+
+// quoteTable quotes a table name, which may be qualified by a schema
+func quoteTable(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = postgres.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// writeCopyRow appends entry as a row in COPY's text format
+func writeCopyRow(b *strings.Builder, entry models.LogEntry) error {
+	nullable := func(s string) string {
+		if s == "" {
+			return `\N`
+		}
+		return copyEscape(s)
+	}
+	ts := ""
+	if !entry.Timestamp.IsZero() {
+		ts = entry.Timestamp.Format(time.RFC3339Nano)
+	}
+	fields := ""
+	if len(entry.Fields) > 0 {
+		data, err := json.Marshal(entry.Fields)
+		if err != nil {
+			return err
+		}
+		fields = string(data)
+	}
+	inferred := "f"
+	if entry.LevelInferred {
+		inferred = "t"
+	}
+	b.WriteString(strings.Join([]string{
+		nullable(entry.ID), nullable(ts), copyEscape(string(entry.Level)), copyEscape(entry.Service),
+		copyEscape(entry.Message), copyEscape(entry.Source), nullable(fields), inferred,
+	}, "\t"))
+	b.WriteByte('\n')
+	return nil
+}
+
+// copyEscaper escapes the characters COPY's text format gives a meaning.
+// PostgreSQL text cannot hold NUL, so it is dropped.
+var copyEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\x00", "")
+
+func copyEscape(s string) string {
+	return copyEscaper.Replace(s)
+}
@@ -0,0 +1,117 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// fakePostgres serves one client without authentication, reporting each
+// query and the data of each COPY on queries
+func fakePostgres(listener net.Listener, queries chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	send := func(typ byte, payload []byte) {
+		msg := binary.BigEndian.AppendUint32([]byte{typ}, uint32(len(payload)+4))
+		conn.Write(append(msg, payload...))
+	}
+	receive := func() (byte, string) {
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, ""
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[1:])-4)
+		io.ReadFull(r, payload)
+		return header[0], string(payload)
+	}
+
+	size := make([]byte, 4)
+	io.ReadFull(r, size)
+	io.ReadFull(r, make([]byte, binary.BigEndian.Uint32(size)-4))
+	send('R', []byte{0, 0, 0, 0})
+	send('Z', []byte{'I'})
+	for {
+		typ, payload := receive()
+		if typ != 'Q' {
+			return
+		}
+		sql := strings.TrimSuffix(payload, "\x00")
+		queries <- sql
+		if strings.HasPrefix(sql, "COPY") {
+			send('G', []byte{0, 0, 0})
+			var data strings.Builder
+			for {
+				typ, payload := receive()
+				if typ != 'd' {
+					break
+				}
+				data.WriteString(payload)
+			}
+			queries <- data.String()
+		}
+		send('C', []byte("OK\x00"))
+		send('Z', []byte{'I'})
+	}
+}
+
+func TestPostgresSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	queries := make(chan string, 16)
+	go fakePostgres(listener, queries)
+
+	if _, err := Open("postgres://" + listener.Addr().String() + "/logs?table=bad-name"); err == nil {
+		t.Error("Expected an invalid table name to be rejected")
+	}
+	sink, err := Open("postgres://" + listener.Addr().String() + "/logs?table=app.logs&upsert=true&batch_size=2")
+	if err != nil {
+		t.Fatalf("Failed to open sink: %v", err)
+	}
+	entries := []models.LogEntry{
+		{ID: "1", Timestamp: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), Level: models.ERROR, Service: "api", Message: "failed\tbadly"},
+		{Timestamp: time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC), Level: models.INFO, Service: "api", Message: "ok", Fields: map[string]string{"user": "bob"}},
+	}
+	for _, entry := range entries {
+		if err := sink.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	create := <-queries
+	for _, want := range []string{`CREATE TABLE IF NOT EXISTS "app"."logs"`, "UNIQUE (id, ts)", "PARTITION BY RANGE (ts)", `"app"."logs_default" PARTITION OF "app"."logs" DEFAULT`} {
+		if !strings.Contains(create, want) {
+			t.Errorf("Expected the table definition to contain %q, got %q", want, create)
+		}
+	}
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "app"."logs_20230101" PARTITION OF "app"."logs" FOR VALUES FROM ('2023-01-01T00:00:00Z') TO ('2023-01-02T00:00:00Z')`,
+		`CREATE TABLE IF NOT EXISTS "app"."logs_20230102" PARTITION OF "app"."logs" FOR VALUES FROM ('2023-01-02T00:00:00Z') TO ('2023-01-03T00:00:00Z')`,
+		"BEGIN",
+		"COPY logprocessor_staging (id, ts, level, service, message, source, fields, level_inferred) FROM STDIN",
+		"1\t2023-01-01T10:00:00Z\tERROR\tapi\tfailed\\tbadly\t\t\\N\tf\n" +
+			"\\N\t2023-01-02T10:00:00Z\tINFO\tapi\tok\t\t{\"user\":\"bob\"}\tf\n",
+	} {
+		if got := <-queries; got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+	if merge := <-queries; !strings.Contains(merge, "ON CONFLICT (id, ts) DO UPDATE") || !strings.HasSuffix(merge, "COMMIT") {
+		t.Errorf("Expected an upsert from the staging table, got %q", merge)
+	}
+}
@@ -0,0 +1,74 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/redis"
+)
+
+// RedisSink publishes entries to a Redis stream, as JSON in the "entry"
+// field of each stream entry
+type RedisSink struct {
+	stream string
+	// maxLen trims the stream to about this many entries, if set
+	maxLen int
+
+	mu   sync.Mutex
+	conn *redis.Conn
+}
+
+// NewRedisSink connects to the server at rawURL, e.g. "redis://redis:6379/0",
+// and publishes to stream, trimmed to about maxLen entries unless maxLen is 0
+func NewRedisSink(rawURL, stream string, maxLen int) (*RedisSink, error) {
+	conn, err := redis.Dial(context.Background(), rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisSink{stream: stream, maxLen: maxLen, conn: conn}, nil
+}
+
+// Write publishes one entry
+func (r *RedisSink) Write(entry models.LogEntry) error {
+	return r.WriteBatch([]models.LogEntry{entry})
+}
+
+// WriteBatch publishes several entries in one round trip
+func (r *RedisSink) WriteBatch(entries []models.LogEntry) error {
+	cmds := make([][]string, 0, len(entries))
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		cmd := []string{"XADD", r.stream}
+		if r.maxLen > 0 {
+			cmd = append(cmd, "MAXLEN", "~", strconv.Itoa(r.maxLen))
+		}
+		cmds = append(cmds, append(cmd, "*", "entry", string(data)))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	replies, err := r.conn.Pipeline(cmds, 0)
+	if err != nil {
+		return fmt.Errorf("failed to publish to Redis stream %s: %w", r.stream, err)
+	}
+	for _, reply := range replies {
+		if e, ok := reply.(redis.Error); ok {
+			return fmt.Errorf("failed to publish to Redis stream %s: %w", r.stream, e)
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the server
+func (r *RedisSink) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conn.Close()
+}
@@ -0,0 +1,78 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestRedisSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	commands := make(chan []string, 4)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 1; ; i++ {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+			args := make([]string, n)
+			for j := range args {
+				line, _ = r.ReadString('\n')
+				size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+				buf := make([]byte, size+2)
+				io.ReadFull(r, buf)
+				args[j] = string(buf[:size])
+			}
+			commands <- args
+			id := fmt.Sprintf("%d-0", i)
+			fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(id), id)
+		}
+	}()
+
+	if _, err := Open("redis://" + listener.Addr().String()); err == nil {
+		t.Error("Expected a Redis sink without a stream to be rejected")
+	}
+	sink, err := Open("redis://" + listener.Addr().String() + "?stream=processed&maxlen=1000&batch_size=2")
+	if err != nil {
+		t.Fatalf("Failed to open sink: %v", err)
+	}
+	entry := models.LogEntry{ID: "1", Timestamp: time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC), Level: models.ERROR, Service: "api", Message: "failed"}
+	for i := 0; i < 2; i++ {
+		if err := sink.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		args := <-commands
+		if got := strings.Join(args[:7], " "); got != "XADD processed MAXLEN ~ 1000 * entry" {
+			t.Fatalf("Unexpected command %q", got)
+		}
+		var decoded models.LogEntry
+		if err := json.Unmarshal([]byte(args[7]), &decoded); err != nil || decoded.Message != "failed" || decoded.Level != models.ERROR {
+			t.Errorf("Expected the entry as JSON, got %q (%v)", args[7], err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package sink
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// Route forwards the entries it matches to a sink
+type Route struct {
+	Name string
+	// Match selects the entries of the route; nil matches every entry
+	Match func(entry models.LogEntry) bool
+	// Sink receives the matched entries. A nil sink only counts them.
+	Sink Sink
+}
+
+// Router is a sink that forwards each entry to every route matching it, so
+// one run can feed several downstream consumers with different subsets
+type Router struct {
+	routes []Route
+	counts []atomic.Int64
+}
+
+// NewRouter creates a router over routes, evaluated in order
+func NewRouter(routes ...Route) *Router {
+	return &Router{routes: routes, counts: make([]atomic.Int64, len(routes))}
+}
+
+func (r *Router) Write(entry models.LogEntry) error {
+	var firstErr error
+	for i, route := range r.routes {
+		if route.Match != nil && !route.Match(entry) {
+			continue
+		}
+		r.counts[i].Add(1)
+		if route.Sink == nil {
+			continue
+		}
+		if err := route.Sink.Write(entry); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("route %s: %w", route.Name, err)
+		}
+	}
+	return firstErr
+}
+
+// Flush flushes the sinks of all routes, returning the first error
+func (r *Router) Flush() error {
+	var firstErr error
+	for _, route := range r.routes {
+		if route.Sink == nil {
+			continue
+		}
+		if err := Flush(route.Sink); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("route %s: %w", route.Name, err)
+		}
+	}
+	return firstErr
+}
+
+// Close closes the sinks of all routes, returning the first error
+func (r *Router) Close() error {
+	var firstErr error
+	for _, route := range r.routes {
+		if route.Sink == nil {
+			continue
+		}
+		if err := route.Sink.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("route %s: %w", route.Name, err)
+		}
+	}
+	return firstErr
+}
+
+// RouteCount is the number of entries a route matched
+type RouteCount struct {
+	Name  string
+	Count int64
+}
+
+// Counts returns the number of entries matched by each route, in route order
+func (r *Router) Counts() []RouteCount {
+	counts := make([]RouteCount, len(r.routes))
+	for i, route := range r.routes {
+		counts[i] = RouteCount{Name: route.Name, Count: r.counts[i].Load()}
+	}
+	return counts
+}
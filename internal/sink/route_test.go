@@ -0,0 +1,43 @@
+package sink
+
+import (
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestRouter(t *testing.T) {
+	errors, api := &batchRecorder{}, &batchRecorder{}
+	router := NewRouter(
+		Route{Name: "errors", Match: func(e models.LogEntry) bool { return e.Level == models.ERROR }, Sink: errors},
+		Route{Name: "api", Match: func(e models.LogEntry) bool { return e.Service == "api" }, Sink: api},
+		Route{Name: "all"},
+	)
+
+	entries := []models.LogEntry{
+		{Level: models.ERROR, Service: "api"},
+		{Level: models.ERROR, Service: "db"},
+		{Level: models.INFO, Service: "api"},
+		{Level: models.INFO, Service: "db"},
+	}
+	for _, e := range entries {
+		if err := router.Write(e); err != nil {
+			t.Fatalf("Failed to route entry: %v", err)
+		}
+	}
+	if err := router.Close(); err != nil {
+		t.Fatalf("Failed to close router: %v", err)
+	}
+
+	// An entry goes to every matching route
+	if n := len(errors.sizes()); n != 2 {
+		t.Errorf("Expected 2 entries routed to errors, got %d", n)
+	}
+	if n := len(api.sizes()); n != 2 {
+		t.Errorf("Expected 2 entries routed to api, got %d", n)
+	}
+	counts := router.Counts()
+	if len(counts) != 3 || counts[2].Name != "all" || counts[2].Count != 4 {
+		t.Errorf("Expected the count-only route to match all 4 entries, got %+v", counts)
+	}
+}
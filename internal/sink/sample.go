@@ -0,0 +1,113 @@
+package sink
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+	"github.com/interview/junior-go-challenge/internal/rules"
+)
+
+// SampleRule forwards a share of the entries matching an expression
+type SampleRule struct {
+	// Expr is the rule match expression selecting the entries
+	Expr string
+	// Rate is the share of matching entries forwarded, from 0 to 1
+	Rate  float64
+	match rules.Matcher
+}
+
+// ParseSampleRule parses a rule given as RATE:MATCH, where RATE is a
+// fraction or a percentage, e.g. "1%:level <= DEBUG" or
+// "0.1:message ~ \"health check\""
+func ParseSampleRule(s string) (SampleRule, error) {
+	rate, expr, ok := strings.Cut(s, ":")
+	if !ok {
+		return SampleRule{}, fmt.Errorf("sample rule %q is not RATE:MATCH", s)
+	}
+	rate = strings.TrimSpace(rate)
+	percent := strings.HasSuffix(rate, "%")
+	r, err := strconv.ParseFloat(strings.TrimSuffix(rate, "%"), 64)
+	if err != nil {
+		return SampleRule{}, fmt.Errorf("invalid rate %q of sample rule: %w", rate, err)
+	}
+	if percent {
+		r /= 100
+	}
+	if r < 0 || r > 1 {
+		return SampleRule{}, fmt.Errorf("rate %q of sample rule must be between 0 and 100%%", rate)
+	}
+	match, err := rules.ParseMatch(expr)
+	if err != nil {
+		return SampleRule{}, fmt.Errorf("invalid match of sample rule: %w", err)
+	}
+	return SampleRule{Expr: strings.TrimSpace(expr), Rate: r, match: match}, nil
+}
+
+// sampling forwards a share of the entries matching each rule
+type sampling struct {
+	inner Sink
+	rules []SampleRule
+}
+
+// NewSampling wraps inner so only a share of the entries matching a rule is
+// forwarded, e.g. 1% of DEBUG entries, to control the cost of the
+// destination. The first matching rule decides; entries matching none are
+// all forwarded. Which entries are kept depends on a hash of their ID, so
+// the same entries are kept when the input is processed again or by several
+// instances. Entries kept by a rule with a rate below 1 carry the number of
+// entries they stand for in the sample_rate field, e.g. 100 for 1%, so the
+// destination can scale its counts.
+func NewSampling(inner Sink, rules []SampleRule) Sink {
+	return &sampling{inner: inner, rules: rules}
+}
+
+func (s *sampling) Write(entry models.LogEntry) error {
+	for _, rule := range s.rules {
+		if !rule.match(entry) {
+			continue
+		}
+		if rule.Rate >= 1 {
+			break
+		}
+		if !sampled(entry.ID, rule.Rate) {
+			return nil
+		}
+		fields := make(map[string]string, len(entry.Fields)+1)
+		for k, v := range entry.Fields {
+			fields[k] = v
+		}
+		fields["sample_rate"] = strconv.FormatFloat(1/rule.Rate, 'g', 4, 64)
+		entry.Fields = fields
+		break
+	}
+	return s.inner.Write(entry)
+}
+
+// sampled reports whether the entry with id is among the share rate of
+// entries kept
+func sampled(id string, rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	// FNV-1a, finished with a mixer so similar IDs spread evenly
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(id); i++ {
+		h ^= uint64(id[i])
+		h *= 1099511628211
+	}
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return float64(h>>11)/(1<<53) < rate
+}
+
+// Flush flushes the wrapped sink
+func (s *sampling) Flush() error {
+	return Flush(s.inner)
+}
+
+func (s *sampling) Close() error {
+	return s.inner.Close()
+}
@@ -0,0 +1,67 @@
+package sink
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestSamplingSink(t *testing.T) {
+	var sampleRules []SampleRule
+	for _, s := range []string{"100%:level >= ERROR", "1%:level == DEBUG", "0.5:message ~ \"health check\""} {
+		rule, err := ParseSampleRule(s)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", s, err)
+		}
+		sampleRules = append(sampleRules, rule)
+	}
+	inner := &recordingSink{}
+	s := NewSampling(inner, sampleRules)
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		for _, entry := range []models.LogEntry{
+			{ID: fmt.Sprintf("e%d", i), Level: models.ERROR, Message: "health check failed"},
+			{ID: fmt.Sprintf("d%d", i), Level: models.DEBUG, Message: "cache miss"},
+			{ID: fmt.Sprintf("h%d", i), Level: models.INFO, Message: "health check ok"},
+			{ID: fmt.Sprintf("i%d", i), Level: models.INFO, Message: "request served"},
+		} {
+			if err := s.Write(entry); err != nil {
+				t.Fatalf("Failed to write entry: %v", err)
+			}
+		}
+	}
+	for _, entry := range inner.entries {
+		counts[entry.ID[:1]+entry.Fields["sample_rate"]]++
+	}
+
+	if counts["e"] != 10000 || counts["i"] != 10000 {
+		t.Errorf("Expected every error and unmatched entry, got %v", counts)
+	}
+	if n := counts["d100"]; n < 70 || n > 130 {
+		t.Errorf("Expected about 1%% of debug entries with sample_rate 100, got %d", n)
+	}
+	if n := counts["h2"]; n < 4700 || n > 5300 {
+		t.Errorf("Expected about half of the health checks with sample_rate 2, got %d", n)
+	}
+
+	// The same entries are kept again
+	again := &recordingSink{}
+	s = NewSampling(again, sampleRules)
+	for _, entry := range inner.entries {
+		if entry.Level == models.DEBUG {
+			entry.Fields = nil
+			s.Write(entry)
+		}
+	}
+	if len(again.entries) != counts["d100"] {
+		t.Errorf("Expected the %d sampled debug entries kept again, got %d", counts["d100"], len(again.entries))
+	}
+
+	for _, bad := range []string{"level == DEBUG", "2:level == DEBUG", "x%:level == DEBUG", "0.1:level =="} {
+		if _, err := ParseSampleRule(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
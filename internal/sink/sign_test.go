@@ -0,0 +1,66 @@
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestSigningSink(t *testing.T) {
+	key := []byte("secret")
+	inner := &recordingSink{}
+	s := NewSigning(inner, key)
+
+	entry := models.LogEntry{
+		ID:        "1",
+		Timestamp: time.Date(2023, 1, 1, 10, 0, 0, 5, time.FixedZone("CET", 3600)),
+		Level:     models.ERROR,
+		Service:   "api",
+		Message:   "payment failed",
+		Source:    "app.json",
+		Fields:    map[string]string{"status": "500", "region": "eu"},
+	}
+	if err := s.Write(entry); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+	if entry.Fields[SignatureField] != "" {
+		t.Error("Expected the written entry's fields to be left alone")
+	}
+
+	signed := inner.entries[0]
+	if len(signed.Fields[SignatureField]) != 64 {
+		t.Fatalf("Expected a hex HMAC-SHA256 signature, got %q", signed.Fields[SignatureField])
+	}
+	if !Verify(signed, key) {
+		t.Error("Expected the signature to verify")
+	}
+	if Verify(signed, []byte("other")) {
+		t.Error("Expected the signature not to verify under another key")
+	}
+
+	// The same instant in another zone is the same entry
+	moved := signed
+	moved.Timestamp = signed.Timestamp.UTC()
+	if !Verify(moved, key) {
+		t.Error("Expected the signature to verify with the timestamp in UTC")
+	}
+
+	tampered := signed
+	tampered.Message = "payment succeeded"
+	if Verify(tampered, key) {
+		t.Error("Expected a changed message to fail verification")
+	}
+	tampered = signed
+	tampered.Fields = map[string]string{"status": "200", "region": "eu", SignatureField: signed.Fields[SignatureField]}
+	if Verify(tampered, key) {
+		t.Error("Expected a changed field to fail verification")
+	}
+
+	// Lengths keep values from running into each other
+	a := models.LogEntry{Service: "ab", Message: "c"}
+	b := models.LogEntry{Service: "a", Message: "bc"}
+	if string(Canonical(a)) == string(Canonical(b)) {
+		t.Error("Expected different entries to have different canonical bytes")
+	}
+}
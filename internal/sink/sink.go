@@ -0,0 +1,203 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/gcp"
+	"github.com/interview/junior-go-challenge/internal/human"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// DefaultBatchSize caps a batch when only batch_interval is given
+const DefaultBatchSize = 1000
+
+// bigQueryBatchSize caps the batches of BigQuery sinks not given a size
+const bigQueryBatchSize = 100000
+
+// Sink receives processed log entries. Write is called concurrently by the
+// processor's workers, so implementations must be safe for concurrent use.
+type Sink interface {
+	Write(entry models.LogEntry) error
+	Close() error
+}
+
+// Open creates a sink from a URL such as "gelf+udp://graylog:12201". The
+// batch_size and batch_interval query parameters (e.g. ?batch_size=500&
+// batch_interval=5s) buffer entries and forward them in batches; PostgreSQL
+// sinks batch up to DefaultBatchSize entries per second unless told otherwise.
+func Open(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink URL %q: %w", rawURL, err)
+	}
+
+	query := u.Query()
+	batchSize, batchInterval, err := batchOptions(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sink URL %q: %w", rawURL, err)
+	}
+
+	var s Sink
+	switch u.Scheme {
+	case "gelf+udp", "gelf":
+		s, err = NewGELFSink("udp", u.Host)
+	case "gelf+tcp":
+		s, err = NewGELFSink("tcp", u.Host)
+	case "redis", "rediss":
+		s, err = openRedis(u, query)
+	case "postgres", "postgresql":
+		s, err = openPostgres(u, query)
+		if batchSize == 0 && batchInterval == 0 {
+			// A COPY per entry would be slow
+			batchSize, batchInterval = DefaultBatchSize, time.Second
+		}
+	case "bigquery":
+		s, err = openBigQuery(u, query)
+		if batchSize == 0 && batchInterval == 0 {
+			// BigQuery allows 1,500 load jobs per table and day
+			batchSize, batchInterval = bigQueryBatchSize, time.Minute
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sink %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if batchSize > 0 || batchInterval > 0 {
+		s = NewBatching(s, batchSize, batchInterval)
+	}
+	return s, nil
+}
+
+// openRedis creates a Redis sink from a URL such as
+// "redis://redis:6379/0?stream=logs&maxlen=100000"
+func openRedis(u *url.URL, query url.Values) (Sink, error) {
+	stream := query.Get("stream")
+	if stream == "" {
+		return nil, fmt.Errorf("invalid sink URL %q: the stream parameter is required", u.Redacted())
+	}
+	var maxLen int
+	if v := query.Get("maxlen"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid maxlen %q", v)
+		}
+		maxLen = n
+	}
+	server := *u
+	server.RawQuery = ""
+	return NewRedisSink(server.String(), stream, maxLen)
+}
+
+// openPostgres creates a PostgreSQL sink from a URL such as
+// "postgres://user:pass@db/logs?table=logs&partition=day&upsert=true".
+// partition is day (the default), month or none.
+func openPostgres(u *url.URL, query url.Values) (Sink, error) {
+	table := query.Get("table")
+	if table == "" {
+		table = "logs"
+	}
+	partition := query.Get("partition")
+	switch partition {
+	case "":
+		partition = PartitionDay
+	case "none":
+		partition = PartitionNone
+	}
+	var upsert bool
+	if v := query.Get("upsert"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upsert %q", v)
+		}
+		upsert = b
+	}
+	// Only sslmode and allow_cleartext are meant for the connection
+	server := *u
+	connQuery := url.Values{}
+	for _, name := range []string{"sslmode", "allow_cleartext"} {
+		if v := query.Get(name); v != "" {
+			connQuery.Set(name, v)
+		}
+	}
+	server.RawQuery = connQuery.Encode()
+	return NewPostgresSink(server.String(), table, partition, upsert)
+}
+
+// openBigQuery creates a BigQuery sink from a URL such as
+// "bigquery://project/dataset/table?credentials=key.json&partition=day".
+// Without a project, that of the credentials is used. partition is hour,
+// day (the default), month, year or none.
+func openBigQuery(u *url.URL, query url.Values) (Sink, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid sink URL %q: expected bigquery://project/dataset/table", u.Redacted())
+	}
+	var partition string
+	switch p := strings.ToUpper(query.Get("partition")); p {
+	case "":
+		partition = "DAY"
+	case "HOUR", "DAY", "MONTH", "YEAR":
+		partition = p
+	case "NONE":
+	default:
+		return nil, fmt.Errorf("invalid partition %q (expected hour, day, month, year or none)", query.Get("partition"))
+	}
+
+	endpoint := query.Get("endpoint")
+	var tokens *gcp.TokenSource
+	// Emulators take no credentials
+	if endpoint == "" || query.Get("credentials") != "" {
+		var err error
+		if tokens, err = gcp.NewTokenSource(query.Get("credentials"), gcp.CloudPlatformScope); err != nil {
+			return nil, err
+		}
+	}
+	project := u.Host
+	if project == "" {
+		if tokens == nil {
+			return nil, fmt.Errorf("invalid sink URL %q: no project given", u.Redacted())
+		}
+		var err error
+		if project, err = tokens.ProjectID(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to determine the BigQuery project: %w", err)
+		}
+	}
+
+	s := NewBigQuerySink(project, parts[0], parts[1], partition, query.Get("location"), tokens)
+	if endpoint != "" {
+		s.WithEndpoint(endpoint)
+	}
+	return s, nil
+}
+
+// batchOptions reads the batching parameters of a sink URL
+func batchOptions(query url.Values) (int, time.Duration, error) {
+	var size int
+	var interval time.Duration
+	if v := query.Get("batch_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid batch_size %q", v)
+		}
+		size = n
+	}
+	if v := query.Get("batch_interval"); v != "" {
+		d, err := human.ParseDuration(v)
+		if err != nil || d < 0 {
+			return 0, 0, fmt.Errorf("invalid batch_interval %q", v)
+		}
+		interval = d
+	}
+	if interval > 0 && size == 0 {
+		// Without a size limit the buffer could grow for the whole interval
+		size = DefaultBatchSize
+	}
+	return size, interval, nil
+}
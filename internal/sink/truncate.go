@@ -0,0 +1,48 @@
+package sink
+
+import (
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// truncating cuts long messages before forwarding them
+type truncating struct {
+	inner Sink
+	max   int
+}
+
+// NewTruncating wraps inner so messages longer than max bytes are cut to
+// max bytes, on a character boundary, before they are forwarded. Truncated
+// entries carry their original size in the message_size field.
+func NewTruncating(inner Sink, max int) Sink {
+	return &truncating{inner: inner, max: max}
+}
+
+func (t *truncating) Write(entry models.LogEntry) error {
+	if len(entry.Message) <= t.max {
+		return t.inner.Write(entry)
+	}
+	cut := t.max
+	for cut > 0 && !utf8.RuneStart(entry.Message[cut]) {
+		cut--
+	}
+	fields := make(map[string]string, len(entry.Fields)+1)
+	for k, v := range entry.Fields {
+		fields[k] = v
+	}
+	fields["message_size"] = strconv.Itoa(len(entry.Message))
+	entry.Fields = fields
+	entry.Message = entry.Message[:cut]
+	return t.inner.Write(entry)
+}
+
+// Flush flushes the wrapped sink
+func (t *truncating) Flush() error {
+	return Flush(t.inner)
+}
+
+func (t *truncating) Close() error {
+	return t.inner.Close()
+}
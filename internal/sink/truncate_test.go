@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func TestTruncatingSink(t *testing.T) {
+	inner := &recordingSink{}
+	s := NewTruncating(inner, 10)
+
+	long := models.LogEntry{Message: "payload: " + strings.Repeat("é", 10), Fields: map[string]string{"k": "v"}}
+	for _, entry := range []models.LogEntry{{Message: "short"}, long} {
+		if err := s.Write(entry); err != nil {
+			t.Fatalf("Failed to write entry: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %v", err)
+	}
+
+	if len(inner.entries) != 2 || !inner.closed {
+		t.Fatalf("Expected 2 entries and a closed sink, got %+v", inner.entries)
+	}
+	if inner.entries[0].Message != "short" || inner.entries[0].Fields != nil {
+		t.Errorf("Expected a short message to pass unchanged, got %+v", inner.entries[0])
+	}
+	// The cut must not split the two bytes of é
+	truncated := inner.entries[1]
+	if truncated.Message != "payload: " {
+		t.Errorf("Expected message to be cut to %q, got %q", "payload: ", truncated.Message)
+	}
+	if truncated.Fields["message_size"] != "29" || truncated.Fields["k"] != "v" {
+		t.Errorf("Expected the original size and fields, got %v", truncated.Fields)
+	}
+	if long.Fields["message_size"] != "" {
+		t.Errorf("Expected the written entry's fields to be left alone")
+	}
+}
@@ -0,0 +1,248 @@
+package statsd
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxPacketSize keeps datagrams within a typical MTU
+const maxPacketSize = 1432
+
+// Client aggregates counters and gauges in memory and flushes them to a
+// statsd server at a fixed interval, so hot paths never touch the network
+type Client struct {
+	conn          net.Conn
+	prefix        string
+	dogStatsD     bool
+	globalTags    []string
+	flushInterval time.Duration
+
+	mu       sync.Mutex
+	counters map[counterKey]int64
+	gauges   map[counterKey]float64
+	// samples holds the histogram values since the last flush
+	samples map[counterKey][]float64
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// counterKey identifies a metric by name and its sorted, joined tags
+type counterKey struct {
+	name string
+	tags string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithPrefix prepends prefix to every metric name, e.g. "logprocessor."
+func WithPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.prefix = prefix
+	}
+}
+
+// WithDogStatsD sends tags using the DogStatsD extension, adding tags to
+// every metric. Without it, tags are folded into metric names.
+func WithDogStatsD(tags ...string) Option {
+	return func(c *Client) {
+		c.dogStatsD = true
+		c.globalTags = tags
+	}
+}
+
+// WithFlushInterval sets how often counters are sent. Defaults to 10s.
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *Client) {
+		c.flushInterval = d
+	}
+}
+
+// New creates a client sending to the statsd server at addr
+func New(addr string, opts ...Option) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd at %s: %w", addr, err)
+	}
+
+	c := &Client{
+		conn:          conn,
+		flushInterval: 10 * time.Second,
+		counters:      make(map[counterKey]int64),
+		gauges:        make(map[counterKey]float64),
+		samples:       make(map[counterKey][]float64),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	go c.loop()
+	return c, nil
+}
+
+// Count adds value to the named counter. Tags are "key:value" pairs.
+func (c *Client) Count(name string, value int64, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range c.keys(name, tags) {
+		c.counters[key] += value
+	}
+}
+
+// Gauge sets the named gauge to value
+func (c *Client) Gauge(name string, value float64, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range c.keys(name, tags) {
+		c.gauges[key] = value
+	}
+}
+
+// Histogram records a value of the named histogram. Values are sent
+// individually, as DogStatsD histograms or plain statsd timers, for the
+// server to compute the distribution.
+func (c *Client) Histogram(name string, value float64, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range c.keys(name, tags) {
+		c.samples[key] = append(c.samples[key], value)
+	}
+}
+
+// keys returns the metrics a value of name with tags is recorded in
+func (c *Client) keys(name string, tags []string) []counterKey {
+	if c.dogStatsD {
+		// Tag values come from log entries and must not break the line
+		sorted := make([]string, 0, len(c.globalTags)+len(tags))
+		for _, tag := range append(append([]string(nil), c.globalTags...), tags...) {
+			k, v, ok := strings.Cut(tag, ":")
+			if ok {
+				sorted = append(sorted, sanitize(k)+":"+sanitize(v))
+			} else {
+				sorted = append(sorted, sanitize(k))
+			}
+		}
+		sort.Strings(sorted)
+		return []counterKey{{name: name, tags: strings.Join(sorted, ",")}}
+	}
+
+	// Plain statsd has no tags, so emit the total plus one metric per tag
+	keys := []counterKey{{name: name}}
+	for _, tag := range tags {
+		k, v, _ := strings.Cut(tag, ":")
+		keys = append(keys, counterKey{name: name + "." + sanitize(k) + "." + sanitize(v)})
+	}
+	return keys
+}
+
+func (c *Client) loop() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Flush()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Flush sends all metrics recorded since the last flush
+func (c *Client) Flush() error {
+	c.mu.Lock()
+	counters, gauges, samples := c.counters, c.gauges, c.samples
+	c.counters = make(map[counterKey]int64)
+	c.gauges = make(map[counterKey]float64)
+	c.samples = make(map[counterKey][]float64)
+	c.mu.Unlock()
+
+	var lines []string
+	for key, value := range counters {
+		lines = append(lines, c.line(key, strconv.FormatInt(value, 10), "c"))
+	}
+	for key, value := range gauges {
+		lines = append(lines, c.line(key, formatFloat(value), "g"))
+	}
+	histogramType := "ms"
+	if c.dogStatsD {
+		histogramType = "h"
+	}
+	for key, values := range samples {
+		for _, value := range values {
+			lines = append(lines, c.line(key, formatFloat(value), histogramType))
+		}
+	}
+	sort.Strings(lines)
+
+	// Pack as many lines as fit into each datagram
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > maxPacketSize {
+			if _, err := c.conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		if _, err := c.conn.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// line formats one metric value of the given statsd type
+func (c *Client) line(key counterKey, value, typ string) string {
+	line := c.prefix + key.name + ":" + value + "|" + typ
+	if key.tags != "" {
+		line += "|#" + key.tags
+	}
+	return line
+}
+
+// formatFloat renders a gauge or histogram value
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Close flushes remaining metrics and closes the connection
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		<-c.done
+		err = c.Flush()
+		if closeErr := c.conn.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// sanitize replaces characters that have meaning in the statsd protocol
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ' ', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
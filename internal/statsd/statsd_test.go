@@ -0,0 +1,142 @@
+package statsd
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receive collects all lines sent to conn until it goes quiet
+func receive(t *testing.T, conn net.PacketConn) []string {
+	t.Helper()
+	var lines []string
+	buf := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		if n > maxPacketSize {
+			t.Errorf("Packet of %d bytes exceeds the maximum size", n)
+		}
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestClientPlain(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client, err := New(server.LocalAddr().String(), WithPrefix("lp."), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	client.Count("entries", 1, "level:ERROR", "service:api")
+	client.Count("entries", 2, "level:INFO", "service:api")
+	client.Close()
+
+	expected := []string{
+		"lp.entries.level.ERROR:1|c",
+		"lp.entries.level.INFO:2|c",
+		"lp.entries.service.api:3|c",
+		"lp.entries:3|c",
+	}
+	lines := receive(t, server)
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestClientDogStatsD(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client, err := New(server.LocalAddr().String(), WithDogStatsD("env:prod"), WithFlushInterval(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer client.Close()
+
+	// Enough distinct counters to need several packets
+	for i := 0; i < 100; i++ {
+		client.Count("entries", 1, "service:svc-"+strings.Repeat("x", i%10)+string(rune('a'+i%26)), "level:INFO")
+	}
+	client.Count("parse_errors", 1, "source:bad.json")
+
+	lines := receive(t, server)
+	found := false
+	for _, line := range lines {
+		if line == "parse_errors:1|c|#env:prod,source:bad.json" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a tagged parse_errors counter, got %v", lines)
+	}
+	if len(lines) < 27 {
+		t.Errorf("Expected one line per distinct tag set, got %d", len(lines))
+	}
+}
+
+func TestClientDogStatsDTagValues(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client, err := New(server.LocalAddr().String(), WithDogStatsD(), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	// A service name from a log entry must not inject tags or metrics
+	client.Count("entries", 1, "service:api|c\nfake:1|c|#admin:true,role:root")
+	client.Close()
+
+	expected := []string{"entries:1|c|#service:api_c_fake_1_c__admin_true_role_root"}
+	if lines := receive(t, server); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
+
+func TestClientGaugesAndHistograms(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	client, err := New(server.LocalAddr().String(), WithFlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	client.Gauge("queue_depth", 7, "service:worker")
+	client.Gauge("queue_depth", 3.5, "service:worker")
+	client.Histogram("request_ms", 12)
+	client.Histogram("request_ms", 40)
+	client.Close()
+
+	expected := []string{
+		"queue_depth.service.worker:3.5|g",
+		"queue_depth:3.5|g",
+		"request_ms:12|ms",
+		"request_ms:40|ms",
+	}
+	lines := receive(t, server)
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
@@ -0,0 +1,187 @@
+// Package tenant isolates the teams sharing one serving instance: each
+// tenant authenticates with its own API key, has its entries analyzed into
+// a summary of its own and is held to a quota of entries per minute.
+package tenant
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/analyzer"
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+// validName matches tenant names
+var validName = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Definition describes a tenant
+type Definition struct {
+	Name string
+	// KeySHA256 is the hex SHA-256 of the tenant's API key, so the key
+	// itself need not be stored
+	KeySHA256 string
+	// PerMinute is the most entries the tenant may send per minute, or 0
+	// for no limit
+	PerMinute int
+	// Admin grants access to the summary of all tenants and every other
+	// endpoint
+	Admin bool
+}
+
+// Tenant is a registered tenant
+type Tenant struct {
+	Definition
+	key [sha256.Size]byte
+
+	mu       sync.Mutex
+	analyzer *analyzer.LogAnalyzer
+	window   time.Time
+	used     int
+	rejected int64
+}
+
+// Stats are the counts of one tenant
+type Stats struct {
+	Name     string
+	Entries  int
+	Rejected int64
+}
+
+// Registry holds the tenants of an instance. It is a sink: entries written
+// to it are analyzed by the tenant they belong to.
+type Registry struct {
+	tenants []*Tenant
+	byName  map[string]*Tenant
+	clock   clock.Clock
+}
+
+// New validates the definitions and creates a registry of them
+func New(defs []Definition) (*Registry, error) {
+	r := &Registry{byName: make(map[string]*Tenant), clock: clock.System}
+	keys := make(map[[sha256.Size]byte]bool)
+	for _, def := range defs {
+		if !validName.MatchString(def.Name) {
+			return nil, fmt.Errorf("invalid tenant name %q: use letters, digits, dots, dashes and underscores", def.Name)
+		}
+		if r.byName[def.Name] != nil {
+			return nil, fmt.Errorf("duplicate tenant %s", def.Name)
+		}
+		key, err := hex.DecodeString(def.KeySHA256)
+		if err != nil || len(key) != sha256.Size {
+			return nil, fmt.Errorf("invalid api_key_sha256 of tenant %s: not a hex SHA-256", def.Name)
+		}
+		if def.PerMinute < 0 {
+			return nil, fmt.Errorf("invalid quota of tenant %s: negative max_entries_per_minute", def.Name)
+		}
+		t := &Tenant{Definition: def, analyzer: analyzer.NewLogAnalyzer()}
+		copy(t.key[:], key)
+		if keys[t.key] {
+			return nil, fmt.Errorf("tenant %s shares its API key with another tenant", def.Name)
+		}
+		keys[t.key] = true
+		r.tenants = append(r.tenants, t)
+		r.byName[def.Name] = t
+	}
+	return r, nil
+}
+
+// Authenticate returns the tenant whose API key is key, or nil
+func (r *Registry) Authenticate(key string) *Tenant {
+	if key == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(key))
+	var found *Tenant
+	// Compare with every key so the time taken does not reveal a match
+	for _, t := range r.tenants {
+		if subtle.ConstantTimeCompare(sum[:], t.key[:]) == 1 {
+			found = t
+		}
+	}
+	return found
+}
+
+// WithClock sets the clock quota minutes are counted by, e.g. the log time
+// of a replay
+func (r *Registry) WithClock(c clock.Clock) *Registry {
+	r.clock = c
+	return r
+}
+
+// Allow reports whether tenant may send n more entries this minute,
+// counting them against its quota if so. Rejected entries are counted too.
+func (r *Registry) Allow(tenant string, n int) bool {
+	t := r.byName[tenant]
+	if t == nil {
+		return false
+	}
+	now := r.clock.Now().Truncate(time.Minute)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !now.Equal(t.window) {
+		t.window, t.used = now, 0
+	}
+	if t.PerMinute > 0 && t.used+n > t.PerMinute {
+		t.rejected += int64(n)
+		return false
+	}
+	t.used += n
+	return true
+}
+
+// Write analyzes entry in its tenant's analyzer. Entries of no tenant are
+// ignored.
+func (r *Registry) Write(entry models.LogEntry) error {
+	if t := r.byName[entry.Tenant]; t != nil {
+		t.analyzer.Process(entry)
+	}
+	return nil
+}
+
+// Close does nothing: the tenants outlive the processor writing to them
+func (r *Registry) Close() error {
+	return nil
+}
+
+// Summary returns the summary of tenant's entries, or nil if there is no
+// such tenant
+func (r *Registry) Summary(tenant string) *models.LogSummary {
+	t := r.byName[tenant]
+	if t == nil {
+		return nil
+	}
+	return t.analyzer.GetSummary()
+}
+
+// Stats returns the counts of every tenant, sorted by name
+func (r *Registry) Stats() []Stats {
+	stats := make([]Stats, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		t.mu.Lock()
+		rejected := t.rejected
+		t.mu.Unlock()
+		stats = append(stats, Stats{Name: t.Name, Entries: t.analyzer.GetSummary().TotalEntries, Rejected: rejected})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// KeyFromRequest returns the API key of a request, given as a bearer token
+// or in the X-API-Key header
+func KeyFromRequest(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
@@ -0,0 +1,111 @@
+package tenant
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/interview/junior-go-challenge/internal/clock"
+	"github.com/interview/junior-go-challenge/internal/models"
+)
+
+func keyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r, err := New([]Definition{
+		{Name: "payments", KeySHA256: keyHash("pay-key"), PerMinute: 3},
+		{Name: "search", KeySHA256: keyHash("search-key")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	r := newRegistry(t)
+	if tenant := r.Authenticate("pay-key"); tenant == nil || tenant.Name != "payments" {
+		t.Errorf("Expected pay-key to authenticate payments, got %+v", tenant)
+	}
+	for _, key := range []string{"", "wrong", keyHash("pay-key")} {
+		if tenant := r.Authenticate(key); tenant != nil {
+			t.Errorf("Expected key %q to be rejected, got %s", key, tenant.Name)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/summary", nil)
+	req.Header.Set("Authorization", "Bearer pay-key")
+	if key := KeyFromRequest(req); key != "pay-key" {
+		t.Errorf("Expected the bearer token, got %q", key)
+	}
+	req = httptest.NewRequest("GET", "/summary", nil)
+	req.Header.Set("X-API-Key", "search-key")
+	if key := KeyFromRequest(req); key != "search-key" {
+		t.Errorf("Expected the X-API-Key header, got %q", key)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	r := newRegistry(t)
+	fake := clock.NewFake(time.Date(2023, 1, 1, 10, 0, 10, 0, time.UTC))
+	r.WithClock(fake)
+
+	if !r.Allow("payments", 2) || !r.Allow("payments", 1) {
+		t.Fatal("Expected entries within the quota to be allowed")
+	}
+	if r.Allow("payments", 1) {
+		t.Error("Expected entries over the quota to be rejected")
+	}
+	if !r.Allow("search", 1000) {
+		t.Error("Expected a tenant without quota to be allowed")
+	}
+	fake.Advance(time.Minute)
+	if !r.Allow("payments", 3) {
+		t.Error("Expected the quota to reset in the next minute")
+	}
+	if r.Allow("unknown", 1) {
+		t.Error("Expected an unknown tenant to be rejected")
+	}
+	if stats := r.Stats(); stats[0].Name != "payments" || stats[0].Rejected != 1 {
+		t.Errorf("Expected 1 rejected payments entry, got %+v", stats)
+	}
+}
+
+func TestIsolatedSummaries(t *testing.T) {
+	r := newRegistry(t)
+	r.Write(models.LogEntry{ID: "payments/1", Tenant: "payments", Level: models.ERROR, Service: "checkout"})
+	r.Write(models.LogEntry{ID: "payments/2", Tenant: "payments", Level: models.INFO, Service: "checkout"})
+	r.Write(models.LogEntry{ID: "search/1", Tenant: "search", Level: models.INFO, Service: "indexer"})
+	r.Write(models.LogEntry{ID: "other", Level: models.INFO, Service: "untenanted"})
+
+	payments := r.Summary("payments")
+	if payments.TotalEntries != 2 || payments.ByService["indexer"] != 0 || payments.ByLevel[models.ERROR] != 1 {
+		t.Errorf("Expected only the 2 payments entries, got %+v", payments)
+	}
+	if search := r.Summary("search"); search.TotalEntries != 1 || search.ByService["checkout"] != 0 {
+		t.Errorf("Expected only the search entry, got %+v", search)
+	}
+	if r.Summary("unknown") != nil {
+		t.Error("Expected no summary for an unknown tenant")
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, defs := range [][]Definition{
+		{{Name: "a b", KeySHA256: keyHash("k")}},
+		{{Name: "a", KeySHA256: "not-hex"}},
+		{{Name: "a", KeySHA256: keyHash("k"), PerMinute: -1}},
+		{{Name: "a", KeySHA256: keyHash("k")}, {Name: "a", KeySHA256: keyHash("l")}},
+		{{Name: "a", KeySHA256: keyHash("k")}, {Name: "b", KeySHA256: keyHash("k")}},
+	} {
+		if _, err := New(defs); err == nil {
+			t.Errorf("Expected an error for %+v", defs)
+		}
+	}
+}
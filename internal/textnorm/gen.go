@@ -0,0 +1,230 @@
+//go:build ignore
+
+// gen writes tables.go from the Unicode Character Database. Run it with
+// go generate, or with -ucd DIR to read UnicodeData.txt and
+// CompositionExclusions.txt from a local copy.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	version = flag.String("version", "15.0.0", "Unicode version to generate the tables for")
+	ucd     = flag.String("ucd", "", "Directory with the UCD files (default download them from unicode.org)")
+	out     = flag.String("out", "tables.go", "File to write")
+)
+
+func main() {
+	flag.Parse()
+
+	decompositions := make(map[rune][2]rune)
+	classes := make(map[rune]uint8)
+	err := readFields("UnicodeData.txt", func(fields []string) error {
+		if len(fields) < 6 {
+			return fmt.Errorf("too few fields: %q", fields)
+		}
+		r, err := parseRune(fields[0])
+		if err != nil {
+			return err
+		}
+		class, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			return fmt.Errorf("invalid combining class of %04X: %w", r, err)
+		}
+		if class != 0 {
+			classes[r] = uint8(class)
+		}
+		// Compatibility decompositions start with a tag such as <font>
+		d := strings.Fields(fields[5])
+		if len(d) == 0 || strings.HasPrefix(d[0], "<") {
+			return nil
+		}
+		if len(d) > 2 {
+			return fmt.Errorf("canonical decomposition of %04X is longer than a pair", r)
+		}
+		var pair [2]rune
+		for i, s := range d {
+			if pair[i], err = parseRune(s); err != nil {
+				return err
+			}
+		}
+		decompositions[r] = pair
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The file only lists the script-specific and post composition version
+	// exclusions; pairs starting with or forming a non-starter are derived
+	excluded := make(map[rune]bool)
+	err = readFields("CompositionExclusions.txt", func(fields []string) error {
+		r, err := parseRune(fields[0])
+		if err != nil {
+			return err
+		}
+		excluded[r] = true
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for r, d := range decompositions {
+		if d[1] != 0 && (classes[r] != 0 || classes[d[0]] != 0) {
+			excluded[r] = true
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gen.go from the Unicode Character Database %s. DO NOT EDIT.\n\n", *version)
+	b.WriteString("package textnorm\n\n")
+	writeDecompositions(&b, decompositions)
+	writeExclusions(&b, excluded)
+	writeClasses(&b, classes)
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalf("failed to format tables: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readFields calls fn with the semicolon separated fields of every line of
+// the named UCD file that is not blank or a comment
+func readFields(name string, fn func(fields []string) error) error {
+	r, err := open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		fields := strings.Split(text, ";")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if err := fn(fields); err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return nil
+}
+
+// open opens the named UCD file in -ucd, or downloads it
+func open(name string) (io.ReadCloser, error) {
+	if *ucd != "" {
+		return os.Open(filepath.Join(*ucd, name))
+	}
+	url := "https://www.unicode.org/Public/" + *version + "/ucd/" + name
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func parseRune(s string) (rune, error) {
+	r, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid code point %q", s)
+	}
+	return rune(r), nil
+}
+
+func sortedRunes[V any](m map[rune]V) []rune {
+	runes := make([]rune, 0, len(m))
+	for r := range m {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+func writeDecompositions(b *bytes.Buffer, decompositions map[rune][2]rune) {
+	b.WriteString(`// decompositions maps characters to their canonical decomposition, which
+// is a single character or a pair. Hangul syllables are decomposed
+// algorithmically.
+var decompositions = map[rune][2]rune{
+`)
+	var values []string
+	for _, r := range sortedRunes(decompositions) {
+		d := decompositions[r]
+		values = append(values, fmt.Sprintf("0x%04X: {0x%04X, 0x%04X},", r, d[0], d[1]))
+	}
+	writeValues(b, values, 4)
+	b.WriteString("}\n\n")
+}
+
+func writeExclusions(b *bytes.Buffer, excluded map[rune]bool) {
+	b.WriteString(`// compositionExclusions are the characters with a pair decomposition that
+// NFC leaves decomposed
+var compositionExclusions = []rune{
+`)
+	var values []string
+	for _, r := range sortedRunes(excluded) {
+		values = append(values, fmt.Sprintf("0x%04X,", r))
+	}
+	writeValues(b, values, 8)
+	b.WriteString("}\n\n")
+}
+
+func writeClasses(b *bytes.Buffer, classes map[rune]uint8) {
+	b.WriteString(`// combiningClasses lists the canonical combining classes other than 0, as
+// ranges of characters sharing a class
+var combiningClasses = []classRange{
+`)
+	type classRange struct {
+		lo, hi rune
+		class  uint8
+	}
+	var ranges []classRange
+	for _, r := range sortedRunes(classes) {
+		if n := len(ranges); n > 0 && ranges[n-1].hi == r-1 && ranges[n-1].class == classes[r] {
+			ranges[n-1].hi = r
+			continue
+		}
+		ranges = append(ranges, classRange{r, r, classes[r]})
+	}
+	var values []string
+	for _, cr := range ranges {
+		values = append(values, fmt.Sprintf("{0x%04X, 0x%04X, %d},", cr.lo, cr.hi, cr.class))
+	}
+	writeValues(b, values, 4)
+	b.WriteString("}\n")
+}
+
+// writeValues writes values to b, perLine to a line
+func writeValues(b *bytes.Buffer, values []string, perLine int) {
+	for len(values) > 0 {
+		n := min(perLine, len(values))
+		b.WriteString(strings.Join(values[:n], " ") + "\n")
+		values = values[n:]
+	}
+}
@@ -0,0 +1,276 @@
+// Package textnorm normalizes text so strings that differ only in how they
+// are encoded compare equal, e.g. an é written as one character by one
+// platform and as e followed by a combining accent by another.
+package textnorm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:generate go run gen.go -version 15.0.0
+
+// Form is a set of normalizations applied to text
+type Form uint8
+
+const (
+	// NFC composes characters into Unicode Normalization Form C, so
+	// canonically equivalent strings are identical
+	NFC Form = 1 << iota
+	// Fold folds case, so strings differing only in case are identical
+	Fold
+	// Turkic has Fold follow Turkish and Azerbaijani, which tell dotted and
+	// dotless i apart: I folds to ı and İ to i
+	Turkic
+)
+
+// ParseForm parses a comma separated list of normalizations, e.g. "nfc,fold".
+// An empty list is no normalization.
+func ParseForm(list string) (Form, error) {
+	var form Form
+	for _, name := range strings.Split(list, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "nfc":
+			form |= NFC
+		case "fold":
+			form |= Fold
+		case "turkic":
+			form |= Turkic
+		default:
+			return 0, fmt.Errorf("unknown normalization %q (supported: nfc, fold, turkic)", name)
+		}
+	}
+	return form, nil
+}
+
+// LocaleForm returns the normalizations text in the language of a BCP 47
+// tag, such as tr-TR, needs on top of the default ones: Turkic for Turkish
+// and Azerbaijani, none for other languages
+func LocaleForm(tag string) (Form, error) {
+	language, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	if len(language) < 2 || len(language) > 3 || strings.IndexFunc(language, func(r rune) bool {
+		return r > unicode.MaxASCII || !unicode.IsLetter(r)
+	}) >= 0 {
+		return 0, fmt.Errorf("invalid locale %q", tag)
+	}
+	switch strings.ToLower(language) {
+	case "tr", "az":
+		return Turkic, nil
+	}
+	return 0, nil
+}
+
+// String returns the normalizations in the form ParseForm accepts
+func (f Form) String() string {
+	var names []string
+	if f&NFC != 0 {
+		names = append(names, "nfc")
+	}
+	if f&Fold != 0 {
+		names = append(names, "fold")
+	}
+	if f&Turkic != 0 {
+		names = append(names, "turkic")
+	}
+	return strings.Join(names, ",")
+}
+
+// Apply normalizes s, composing it before folding case
+func (f Form) Apply(s string) string {
+	if f&NFC != 0 {
+		s = ToNFC(s)
+	}
+	switch {
+	case f&(Fold|Turkic) == Fold|Turkic:
+		s = foldCase(s, unicode.TurkishCase)
+	case f&Fold != 0:
+		s = FoldCase(s)
+	}
+	return s
+}
+
+// FoldCase maps every character to a single case, using simple case folding:
+// characters such as the Kelvin sign or the long s fold to the letter they
+// are a variant of, but ß stays one character
+func FoldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		return unicode.ToLower(unicode.ToUpper(r))
+	}, s)
+}
+
+// foldCase folds case like FoldCase, with the special rules of a language
+func foldCase(s string, c unicode.SpecialCase) string {
+	return strings.Map(func(r rune) rune {
+		return c.ToLower(c.ToUpper(r))
+	}, s)
+}
+
+// Hangul syllables are composed of jamo algorithmically
+const (
+	hangulBase   = 0xAC00
+	hangulL      = 0x1100
+	hangulV      = 0x1161
+	hangulT      = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulCount  = hangulLCount * hangulNCount
+)
+
+// classRange is a range of characters sharing a canonical combining class
+type classRange struct {
+	lo, hi rune
+	class  uint8
+}
+
+// compositions maps pairs of characters to the character they compose to
+var compositions = func() map[[2]rune]rune {
+	excluded := make(map[rune]bool, len(compositionExclusions))
+	for _, r := range compositionExclusions {
+		excluded[r] = true
+	}
+	m := make(map[[2]rune]rune)
+	for r, d := range decompositions {
+		if d[1] != 0 && !excluded[r] {
+			m[d] = r
+		}
+	}
+	return m
+}()
+
+// composesWithPrevious holds the characters that are the second of a
+// composing pair, which a string already in NFC may end up composing
+var composesWithPrevious = func() map[rune]bool {
+	m := make(map[rune]bool)
+	for pair := range compositions {
+		m[pair[1]] = true
+	}
+	return m
+}()
+
+// combiningClass returns the canonical combining class of r, 0 for starters
+func combiningClass(r rune) uint8 {
+	if r < 0x300 {
+		return 0
+	}
+	i := sort.Search(len(combiningClasses), func(i int) bool { return combiningClasses[i].hi >= r })
+	if i < len(combiningClasses) && combiningClasses[i].lo <= r {
+		return combiningClasses[i].class
+	}
+	return 0
+}
+
+// ToNFC returns s in Unicode Normalization Form C: characters are fully
+// decomposed, combining marks put in canonical order and the result composed
+// again. Invalid UTF-8 is replaced with U+FFFD.
+func ToNFC(s string) string {
+	if isNFC(s) {
+		return s
+	}
+	var runes []rune
+	for _, r := range s {
+		runes = decompose(runes, r)
+	}
+	reorder(runes)
+	return string(compose(runes))
+}
+
+// isNFC reports whether s contains nothing NFC could change, as is the case
+// for most text and all ASCII
+func isNFC(s string) bool {
+	for _, r := range s {
+		if r < 0xC0 {
+			continue
+		}
+		if r == utf8.RuneError || combiningClass(r) != 0 || composesWithPrevious[r] ||
+			(r >= hangulV && r < hangulV+hangulVCount) || (r > hangulT && r < hangulT+hangulTCount) {
+			return false
+		}
+		if _, ok := decompositions[r]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+// decompose appends the full canonical decomposition of r to runes
+func decompose(runes []rune, r rune) []rune {
+	if s := r - hangulBase; s >= 0 && s < hangulCount {
+		runes = append(runes, hangulL+s/hangulNCount, hangulV+(s%hangulNCount)/hangulTCount)
+		if t := s % hangulTCount; t != 0 {
+			runes = append(runes, hangulT+t)
+		}
+		return runes
+	}
+	d, ok := decompositions[r]
+	if !ok {
+		return append(runes, r)
+	}
+	runes = decompose(runes, d[0])
+	if d[1] != 0 {
+		runes = decompose(runes, d[1])
+	}
+	return runes
+}
+
+// reorder sorts each run of combining marks by combining class, keeping marks
+// of the same class in order
+func reorder(runes []rune) {
+	for i := 1; i < len(runes); i++ {
+		class := combiningClass(runes[i])
+		if class == 0 {
+			continue
+		}
+		for j := i; j > 0; j-- {
+			prev := combiningClass(runes[j-1])
+			if prev <= class {
+				break
+			}
+			runes[j-1], runes[j] = runes[j], runes[j-1]
+		}
+	}
+}
+
+// compose combines each starter with the following characters it composes
+// with, unless another character of the same or no combining class comes
+// between them
+func compose(runes []rune) []rune {
+	out := runes[:0]
+	starter := -1
+	var lastClass uint8
+	for _, r := range runes {
+		class := combiningClass(r)
+		if starter >= 0 {
+			blocked := len(out)-1 > starter && (lastClass == 0 || lastClass >= class)
+			if !blocked {
+				if c, ok := composePair(out[starter], r); ok {
+					out[starter] = c
+					continue
+				}
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+	return out
+}
+
+// composePair returns the character a and b compose to
+func composePair(a, b rune) (rune, bool) {
+	if l, v := a-hangulL, b-hangulV; l >= 0 && l < hangulLCount && v >= 0 && v < hangulVCount {
+		return hangulBase + (l*hangulVCount+v)*hangulTCount, true
+	}
+	if s, t := a-hangulBase, b-hangulT; s >= 0 && s < hangulCount && s%hangulTCount == 0 && t > 0 && t < hangulTCount {
+		return a + t, true
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
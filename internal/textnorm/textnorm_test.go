@@ -0,0 +1,90 @@
+package textnorm
+
+import "testing"
+
+func TestToNFC(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"ascii", "Connection refused", "Connection refused"},
+		{"composed", "caf\u00e9", "caf\u00e9"},
+		{"decomposed", "cafe\u0301", "caf\u00e9"},
+		{"two marks", "o\u0323\u0302", "\u1ed9"},
+		{"marks out of order", "o\u0302\u0323", "\u1ed9"},
+		{"mark without composition", "x\u0301", "x\u0301"},
+		{"singleton", "\u212b", "\u00c5"},
+		{"composition exclusion", "\u0958", "\u0915\u093c"},
+		{"blocked by a starter", "ab\u0301", "ab\u0301"},
+		{"leading mark", "\u0301e", "\u0301e"},
+		{"hangul jamo", "\u1100\u1161\u11a8", "\uac01"},
+		{"hangul syllable and trailing jamo", "\uac00\u11a8", "\uac01"},
+		{"hangul syllable", "\uac01", "\uac01"},
+	}
+	for _, tt := range tests {
+		if got := ToNFC(tt.input); got != tt.expected {
+			t.Errorf("%s: Expected %+q, got %+q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestFormApply(t *testing.T) {
+	tests := []struct {
+		form     string
+		input    string
+		expected string
+	}{
+		{"", "Cafe\u0301", "Cafe\u0301"},
+		{"nfc", "Cafe\u0301", "Caf\u00e9"},
+		{"fold", "Caf\u00c9 \u212a", "caf\u00e9 k"},
+		{"nfc,fold", "CAFE\u0301", "caf\u00e9"},
+		{" Fold , NFC ", "CAFE\u0301", "caf\u00e9"},
+		{"fold", "D\u0130SK HATASI", "disk hatasi"},
+		{"fold,turkic", "D\u0130SK HATASI", "disk hatas\u0131"},
+		{"turkic", "D\u0130SK", "D\u0130SK"},
+	}
+	for _, tt := range tests {
+		form, err := ParseForm(tt.form)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", tt.form, err)
+		}
+		if got := form.Apply(tt.input); got != tt.expected {
+			t.Errorf("%q: Expected %+q, got %+q", tt.form, tt.expected, got)
+		}
+	}
+
+	if form, _ := ParseForm("turkic,fold,nfc"); form.String() != "nfc,fold,turkic" {
+		t.Errorf("Expected nfc,fold,turkic, got %q", form.String())
+	}
+	if _, err := ParseForm("nfkc"); err == nil {
+		t.Error("Expected an error for an unknown normalization")
+	}
+}
+
+func TestLocaleForm(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected Form
+	}{
+		{"tr", Turkic},
+		{"tr-TR", Turkic},
+		{"az_Latn_AZ", Turkic},
+		{"en-US", 0},
+		{"de", 0},
+	}
+	for _, tt := range tests {
+		form, err := LocaleForm(tt.tag)
+		if err != nil {
+			t.Errorf("%q: Unexpected error: %v", tt.tag, err)
+		} else if form != tt.expected {
+			t.Errorf("%q: Expected %q, got %q", tt.tag, tt.expected, form)
+		}
+	}
+
+	for _, tag := range []string{"", "t", "turkish", "1r"} {
+		if _, err := LocaleForm(tag); err == nil {
+			t.Errorf("Expected an error for locale %q", tag)
+		}
+	}
+}